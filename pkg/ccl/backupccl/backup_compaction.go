@@ -0,0 +1,634 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/build"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+)
+
+// A compaction merges a chain of backups in a collection -- a full backup and
+// the incremental backups appended to it -- into a new full backup of the same
+// data as of the end time of the last backup of the chain. It reads only the
+// files of the chain from external storage and never reads from the cluster,
+// so that restores of the compacted backup need not read every layer of the
+// chain. The compacted backup is written to its own directory in the
+// collection, which then becomes the LATEST backup of the collection.
+
+// checkBackupCompactionStatement returns an error if the BACKUP statement
+// specifies the compact option along with options or clauses which do not
+// apply to a compaction.
+func checkBackupCompactionStatement(backupStmt *annotatedBackupStatement) error {
+	if !backupStmt.Nested || (!backupStmt.AppendToLatest && backupStmt.Subdir == nil) {
+		return errors.New(
+			"the compact option requires BACKUP INTO LATEST IN or BACKUP INTO <subdir> IN a collection")
+	}
+	if backupStmt.Targets != nil {
+		return errors.New("the compact option cannot be used with backup targets")
+	}
+	if backupStmt.AsOf.Expr != nil {
+		return errors.New("the compact option cannot be used with AS OF SYSTEM TIME")
+	}
+	if backupStmt.Options.CaptureRevisionHistory {
+		return errors.New("the compact option cannot be used with the revision_history option")
+	}
+	if len(backupStmt.To) > 1 {
+		return errors.New("the compact option is not supported for partitioned backups")
+	}
+	return nil
+}
+
+// planBackupCompaction plans and runs (or, if detached, creates) a backup job
+// which compacts the chain of backups in the collection at to whose full
+// backup is the LATEST one or the one in subdir.
+func planBackupCompaction(
+	ctx context.Context,
+	p sql.PlanHookState,
+	backupStmt *annotatedBackupStatement,
+	to []string,
+	subdir string,
+	encryptionParams backupEncryptionParams,
+	pwFn func() (string, error),
+	kmsFn func() ([]string, *backupKMSEnv, error),
+	maxBandwidth int64,
+	resultsCh chan<- tree.Datums,
+) error {
+	if err := utilccl.CheckEnterpriseEnabled(
+		p.ExecCfg().Settings, p.ExecCfg().ClusterID(), p.ExecCfg().Organization(),
+		"BACKUP with compact",
+	); err != nil {
+		return err
+	}
+	hasAdmin, err := p.HasAdminRole(ctx)
+	if err != nil {
+		return err
+	}
+	if !hasAdmin {
+		return pgerror.Newf(pgcode.InsufficientPrivilege,
+			"only users with the admin role are allowed to compact backups")
+	}
+
+	switch encryptionParams.encryptMode {
+	case passphrase:
+		pw, err := pwFn()
+		if err != nil {
+			return err
+		}
+		encryptionParams.encryptionPassphrase = []byte(pw)
+	case kms:
+		encryptionParams.kmsURIs, encryptionParams.kmsEnv, err = kmsFn()
+		if err != nil {
+			return err
+		}
+	}
+
+	makeCloudStorage := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI
+	defaultURI, urisByLocalityKV, err := getURIsByLocalityKV(to, "")
+	if err != nil {
+		return err
+	}
+	collectionURI, _, chainSubdir, _, chainURIs, err := resolveDest(ctx, p.User(),
+		true /* nested */, backupStmt.AppendToLatest, defaultURI, urisByLocalityKV, makeCloudStorage,
+		hlc.Timestamp{}, to, nil /* incrementalFrom */, subdir)
+	if err != nil {
+		return err
+	}
+	if len(chainURIs) == 0 {
+		return errors.Errorf("no backup found in %s of the collection", chainSubdir)
+	}
+	chain, encryptionOptions, err := fetchPreviousBackups(ctx, p.User(), makeCloudStorage,
+		chainURIs, encryptionParams)
+	if err != nil {
+		return err
+	}
+	if len(chain) < 2 {
+		return errors.Errorf("backup %s has no incremental backups to compact", chainSubdir)
+	}
+	if err := checkBackupChainForCompaction(chain, p.User()); err != nil {
+		return err
+	}
+
+	backupManifest := makeCompactedBackupManifest(chain[len(chain)-1])
+	descBytes, err := protoutil.Marshal(&backupManifest)
+	if err != nil {
+		return err
+	}
+
+	// The compacted backup is a full backup, so it is written to a directory of
+	// the collection named after its end time, as full backups INTO a collection
+	// are.
+	destSubdir := backupManifest.EndTime.GoTime().Format(dateBasedIntoFolderName)
+	destURI, _, err := getURIsByLocalityKV(to, destSubdir)
+	if err != nil {
+		return err
+	}
+	destStore, err := makeCloudStorage(ctx, destURI, p.User())
+	if err != nil {
+		return err
+	}
+	defer destStore.Close()
+	if err := checkForPreviousBackup(ctx, destStore, destURI); err != nil {
+		return err
+	}
+	if err := verifyWriteableDestination(ctx, p.User(), makeCloudStorage, collectionURI); err != nil {
+		return err
+	}
+
+	// The files of the compacted backup are encrypted like those of the chain,
+	// so it needs a copy of the encryption info of the chain.
+	var encryptionInfo *jobspb.EncryptionInfo
+	if encryptionOptions != nil {
+		baseStore, err := makeCloudStorage(ctx, chainURIs[0], p.User())
+		if err != nil {
+			return err
+		}
+		defer baseStore.Close()
+		if encryptionInfo, err = readEncryptionOptions(ctx, baseStore); err != nil {
+			return err
+		}
+	}
+
+	description, err := backupJobDescription(p, backupStmt.Backup, to, nil, /* incrementalFrom */
+		encryptionParams.kmsURIs, chainSubdir)
+	if err != nil {
+		return err
+	}
+
+	jr := jobs.Record{
+		Description: description,
+		Username:    p.User(),
+		DescriptorIDs: func() (sqlDescIDs []descpb.ID) {
+			for i := range backupManifest.Descriptors {
+				sqlDescIDs = append(sqlDescIDs,
+					descpb.GetDescriptorID(&backupManifest.Descriptors[i]))
+			}
+			return sqlDescIDs
+		}(),
+		Details: jobspb.BackupDetails{
+			EndTime:           backupManifest.EndTime,
+			URI:               destURI,
+			BackupManifest:    descBytes,
+			EncryptionOptions: encryptionOptions,
+			EncryptionInfo:    encryptionInfo,
+			CollectionURI:     collectionURI,
+			CompactFromURIs:   chainURIs,
+			MaxBandwidth:      maxBandwidth,
+		},
+		Progress:  jobspb.BackupProgress{},
+		CreatedBy: backupStmt.CreatedByInfo,
+	}
+
+	telemetry.Count("backup.compaction.started")
+	if backupStmt.Options.Detached {
+		aj, err := p.ExecCfg().JobRegistry.CreateAdoptableJobWithTxn(
+			ctx, jr, p.ExtendedEvalContext().Txn)
+		if err != nil {
+			return err
+		}
+		resultsCh <- tree.Datums{tree.NewDInt(tree.DInt(*aj.ID()))}
+		return nil
+	}
+
+	var sj *jobs.StartableJob
+	if err := p.ExecCfg().DB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) (err error) {
+		sj, err = p.ExecCfg().JobRegistry.CreateStartableJobWithTxn(ctx, jr, txn, resultsCh)
+		return err
+	}); err != nil {
+		if sj != nil {
+			if cleanupErr := sj.CleanupOnRollback(ctx); cleanupErr != nil {
+				log.Warningf(ctx, "failed to cleanup StartableJob: %v", cleanupErr)
+			}
+		}
+		return err
+	}
+	return sj.Run(ctx)
+}
+
+// checkBackupChainForCompaction returns an error if the backups of a chain are
+// not a full backup followed by contiguous incremental backups which together
+// cover the spans of the last backup until its end time.
+func checkBackupChainForCompaction(chain []BackupManifest, user security.SQLUsername) error {
+	if !chain[0].StartTime.IsEmpty() {
+		return errors.Errorf("the first backup of the chain to compact is not a full backup")
+	}
+	for i := range chain {
+		if len(chain[i].PartitionDescriptorFilenames) > 0 {
+			return errors.New("compacting locality-aware backups is not supported")
+		}
+		if i > 0 && chain[i].StartTime != chain[i-1].EndTime {
+			return errors.Errorf(
+				"backups to compact are not contiguous: backup %d starts at %s but the previous one ends at %s",
+				i, chain[i].StartTime, chain[i-1].EndTime)
+		}
+	}
+	last := chain[len(chain)-1]
+	if _, coveredEnd, err := makeImportSpans(
+		last.Spans, chain, nil /* backupLocalityInfo */, keys.MinKey, user, errOnMissingRange,
+	); err != nil {
+		return errors.Wrap(err, "invalid backups to compact")
+	} else if coveredEnd != last.EndTime {
+		return errors.Errorf("expected backups to compact to cover to %s, not %s", last.EndTime, coveredEnd)
+	}
+	return nil
+}
+
+// makeCompactedBackupManifest returns the manifest of the full backup which
+// compacts a chain whose last backup is last, without any files.
+func makeCompactedBackupManifest(last BackupManifest) BackupManifest {
+	m := last
+	m.StartTime = hlc.Timestamp{}
+	m.MVCCFilter = MVCCFilter_Latest
+	m.RevisionStartTime = hlc.Timestamp{}
+	m.IntroducedSpans = nil
+	m.DescriptorChanges = nil
+	m.Files = nil
+	m.EntryCounts = RowCount{}
+	m.Dir = roachpb.ExternalStorage{}
+	m.FormatVersion = BackupFormatDescriptorTrackingVersion
+	m.BuildInfo = build.GetInfo()
+	m.ID = uuid.UUID{}
+	m.LocalityKVs = nil
+	m.PartitionDescriptorFilenames = nil
+	m.StatisticsFilenames = nil
+	return m
+}
+
+// resumeCompaction runs a backup job which compacts a chain of backups.
+func (b *backupResumer) resumeCompaction(
+	ctx context.Context,
+	p sql.JobExecContext,
+	destStore cloud.ExternalStorage,
+	resultsCh chan<- tree.Datums,
+) error {
+	details := b.job.Details().(jobspb.BackupDetails)
+	res, err := b.compactBackups(ctx, p, destStore)
+	if err != nil {
+		return errors.Wrap(err, "failed to compact backups")
+	}
+	b.deleteCheckpoint(ctx, p.ExecCfg(), p.User())
+
+	// The compacted backup supersedes the chain it compacts, so that RESTORE
+	// FROM LATEST and BACKUP INTO LATEST use it.
+	if err := writeLatestFile(ctx, p, details); err != nil {
+		return err
+	}
+
+	resultsCh <- tree.Datums{
+		tree.NewDInt(tree.DInt(*b.job.ID())),
+		tree.NewDString(string(jobs.StatusSucceeded)),
+		tree.NewDFloat(tree.DFloat(1.0)),
+		tree.NewDInt(tree.DInt(res.Rows)),
+		tree.NewDInt(tree.DInt(res.IndexEntries)),
+		tree.NewDInt(tree.DInt(res.DataSize)),
+	}
+	telemetry.Count("backup.compaction.succeeded")
+	b.maybeNotifyScheduledJobCompletion(ctx, jobs.StatusSucceeded, p.ExecCfg())
+	return nil
+}
+
+// compactBackups writes the files, statistics and manifest of a compacted
+// backup to its destination store. If the job is resumed, the compaction is
+// restarted from scratch.
+func (b *backupResumer) compactBackups(
+	ctx context.Context, p sql.JobExecContext, destStore cloud.ExternalStorage,
+) (RowCount, error) {
+	details := b.job.Details().(jobspb.BackupDetails)
+	execCfg := p.ExecCfg()
+
+	var backupManifest BackupManifest
+	if err := protoutil.Unmarshal(details.BackupManifest, &backupManifest); err != nil {
+		return RowCount{}, pgerror.Wrapf(err, pgcode.DataCorrupted, "unmarshal backup descriptor")
+	}
+	chain, err := getBackupManifests(ctx, p.User(), execCfg.DistSQLSrv.ExternalStorageFromURI,
+		details.CompactFromURIs, details.EncryptionOptions)
+	if err != nil {
+		return RowCount{}, err
+	}
+	entries, _, err := makeImportSpans(backupManifest.Spans, chain, nil, /* backupLocalityInfo */
+		keys.MinKey, p.User(), errOnMissingRange)
+	if err != nil {
+		return RowCount{}, errors.Wrap(err, "invalid backups to compact")
+	}
+
+	w := &compactedFileWriter{
+		store:          destStore,
+		targetSize:     storageccl.ExportRequestTargetFileSize.Get(&execCfg.Settings.SV),
+		sqlInstanceID:  execCfg.NodeID.SQLInstanceID(),
+		pkIDs:          make(map[uint64]bool),
+		makeExtStorage: execCfg.DistSQLSrv.ExternalStorage,
+		limiter:        storageccl.NewBandwidthLimiter("backup-compaction", details.MaxBandwidth),
+	}
+	for i := range backupManifest.Descriptors {
+		if t := descpb.TableFromDescriptor(&backupManifest.Descriptors[i], hlc.Timestamp{}); t != nil {
+			w.pkIDs[roachpb.BulkOpSummaryID(uint64(t.ID), uint64(t.PrimaryIndex.ID))] = true
+		}
+	}
+	if details.EncryptionOptions != nil {
+		w.encryptionKey, err = getEncryptionKey(ctx, details.EncryptionOptions, execCfg.Settings,
+			destStore.ExternalIOConf())
+		if err != nil {
+			return RowCount{}, err
+		}
+	}
+
+	progressLogger := jobs.NewChunkProgressLogger(b.job, len(entries), b.job.FractionCompleted(),
+		jobs.ProgressUpdateOnly)
+	entryFinishedCh := make(chan struct{})
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		return progressLogger.Loop(ctx, entryFinishedCh)
+	})
+	g.GoCtx(func(ctx context.Context) error {
+		defer close(entryFinishedCh)
+		for _, entry := range entries {
+			if err := w.compactSpan(ctx, entry); err != nil {
+				return err
+			}
+			select {
+			case entryFinishedCh <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return w.flush(ctx)
+	})
+	if err := g.Wait(); err != nil {
+		return RowCount{}, errors.Wrapf(err, "compacting %d spans", errors.Safe(len(entries)))
+	}
+
+	backupManifest.Files = w.files
+	backupManifest.EntryCounts = w.exported
+	backupManifest.ID = uuid.MakeV4()
+	backupManifest.ClusterID = execCfg.ClusterID()
+
+	// Carry over the statistics of the last backup of the chain.
+	last := chain[len(chain)-1]
+	statsTable := StatsTable{Statistics: last.DeprecatedStatistics}
+	backupManifest.DeprecatedStatistics = nil
+	if len(last.StatisticsFilenames) > 0 {
+		lastStore, err := execCfg.DistSQLSrv.ExternalStorage(ctx, last.Dir)
+		if err != nil {
+			return RowCount{}, err
+		}
+		defer lastStore.Close()
+		read := make(map[string]bool)
+		backupManifest.StatisticsFilenames = make(map[descpb.ID]string)
+		for id, filename := range last.StatisticsFilenames {
+			backupManifest.StatisticsFilenames[id] = backupStatisticsFileName
+			if read[filename] {
+				continue
+			}
+			read[filename] = true
+			table, err := readTableStatistics(ctx, lastStore, filename, details.EncryptionOptions)
+			if err != nil {
+				return RowCount{}, err
+			}
+			statsTable.Statistics = append(statsTable.Statistics, table.Statistics...)
+		}
+	}
+
+	if err := writeBackupManifest(ctx, execCfg.Settings, destStore, backupManifestName,
+		details.EncryptionOptions, &backupManifest); err != nil {
+		return RowCount{}, err
+	}
+	if err := writeTableStatistics(ctx, destStore, backupStatisticsFileName,
+		details.EncryptionOptions, &statsTable); err != nil {
+		return RowCount{}, err
+	}
+	return w.exported, nil
+}
+
+// compactedFileWriter writes the latest versions of the keys of the spans of
+// a compaction to SSTs in the store of the compacted backup.
+type compactedFileWriter struct {
+	store          cloud.ExternalStorage
+	targetSize     int64
+	sqlInstanceID  base.SQLInstanceID
+	encryptionKey  []byte
+	pkIDs          map[uint64]bool
+	makeExtStorage cloud.ExternalStorageFactory
+	limiter        *storageccl.BandwidthLimiter
+
+	// The SST being written, which covers span.
+	sstFile *storage.MemFile
+	sst     storage.SSTWriter
+	span    roachpb.Span
+	rows    storage.RowCounter
+	lastRow roachpb.Key
+
+	files    []BackupManifest_File
+	exported RowCount
+}
+
+// compactSpan merges the files of an entry, which cover its span in the
+// backups of the chain, keeping only the latest version of each key which has
+// not been deleted.
+func (w *compactedFileWriter) compactSpan(
+	ctx context.Context, entry execinfrapb.RestoreSpanEntry,
+) error {
+	// A file only covers adjacent spans.
+	if w.sstFile != nil && !w.span.EndKey.Equal(entry.Span.Key) {
+		if err := w.flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	var iters []storage.SimpleMVCCIterator
+	defer func() {
+		for _, iter := range iters {
+			iter.Close()
+		}
+	}()
+	for _, file := range entry.Files {
+		data, err := w.readFile(ctx, file)
+		if err != nil {
+			return err
+		}
+		iter, err := storage.NewMemSSTIterator(data, false /* verify */)
+		if err != nil {
+			return err
+		}
+		iters = append(iters, iter)
+	}
+	if w.sstFile == nil {
+		w.startFile(entry.Span.Key)
+	}
+	w.span.EndKey = entry.Span.EndKey
+	if len(iters) == 0 {
+		return nil
+	}
+
+	iter := storage.MakeMultiIterator(iters)
+	defer iter.Close()
+	endKey := storage.MVCCKey{Key: entry.Span.EndKey}
+	for iter.SeekGE(storage.MVCCKey{Key: entry.Span.Key}); ; {
+		ok, err := iter.Valid()
+		if err != nil {
+			return err
+		}
+		if !ok || !iter.UnsafeKey().Less(endKey) {
+			break
+		}
+		// The versions of a key are ordered from the latest to the earliest, so
+		// the current one is the latest.
+		if len(iter.UnsafeValue()) == 0 {
+			// Value is deleted.
+			iter.NextKey()
+			continue
+		}
+		if key := iter.UnsafeKey().Key; w.targetSize > 0 && w.sst.DataSize >= w.targetSize &&
+			!w.inLastRow(key) {
+			w.span.EndKey = key
+			if err := w.flush(ctx); err != nil {
+				return err
+			}
+			w.startFile(key)
+			w.span.EndKey = entry.Span.EndKey
+		}
+		if err := w.sst.Put(iter.UnsafeKey(), iter.UnsafeValue()); err != nil {
+			return err
+		}
+		if err := w.rows.Count(iter.UnsafeKey().Key); err != nil {
+			return err
+		}
+		if row, err := keys.EnsureSafeSplitKey(iter.UnsafeKey().Key); err == nil {
+			w.lastRow = append(w.lastRow[:0], row...)
+		}
+		iter.NextKey()
+	}
+	return nil
+}
+
+// readFile reads, decrypts and verifies the checksum of a file of a backup of
+// the chain.
+func (w *compactedFileWriter) readFile(
+	ctx context.Context, file roachpb.ImportRequest_File,
+) ([]byte, error) {
+	dir, err := w.makeExtStorage(ctx, file.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	const maxAttempts = 3
+	var data []byte
+	if err := retry.WithMaxAttempts(ctx, base.DefaultRetryOptions(), maxAttempts, func() error {
+		f, err := dir.ReadFile(ctx, file.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data, err = ioutil.ReadAll(w.limiter.Reader(ctx, f))
+		return err
+	}); err != nil {
+		return nil, errors.Wrapf(err, "fetching %q", file.Path)
+	}
+	if w.encryptionKey != nil {
+		if data, err = storageccl.DecryptFile(data, w.encryptionKey); err != nil {
+			return nil, err
+		}
+	}
+	if len(file.Sha512) > 0 {
+		checksum, err := storageccl.SHA512ChecksumData(data)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(checksum, file.Sha512) {
+			return nil, errors.Errorf("checksum mismatch for %s", file.Path)
+		}
+	}
+	return data, nil
+}
+
+// inLastRow returns whether key belongs to the same SQL row as the last key
+// added, in which case the SST may not be split before it.
+func (w *compactedFileWriter) inLastRow(key roachpb.Key) bool {
+	row, err := keys.EnsureSafeSplitKey(key)
+	return err == nil && bytes.Equal(row, w.lastRow)
+}
+
+func (w *compactedFileWriter) startFile(key roachpb.Key) {
+	w.sstFile = &storage.MemFile{}
+	w.sst = storage.MakeBackupSSTWriter(w.sstFile)
+	w.span = roachpb.Span{Key: append(roachpb.Key(nil), key...)}
+	w.rows = storage.RowCounter{}
+}
+
+// flush writes the SST being written, if it is not empty, to the store.
+func (w *compactedFileWriter) flush(ctx context.Context) error {
+	if w.sstFile == nil {
+		return nil
+	}
+	defer func() {
+		w.sst.Close()
+		w.sstFile = nil
+	}()
+	if w.sst.DataSize == 0 {
+		return nil
+	}
+	if err := w.sst.Finish(); err != nil {
+		return err
+	}
+	data := w.sstFile.Data()
+	checksum, err := storageccl.SHA512ChecksumData(data)
+	if err != nil {
+		return err
+	}
+	if w.encryptionKey != nil {
+		if data, err = storageccl.EncryptFile(data, w.encryptionKey); err != nil {
+			return err
+		}
+	}
+	path := fmt.Sprintf("%d.sst", builtins.GenerateUniqueInt(w.sqlInstanceID))
+	if err := w.store.WriteFile(ctx, path, w.limiter.ReadSeeker(ctx, bytes.NewReader(data))); err != nil {
+		return err
+	}
+
+	summary := w.rows.BulkOpSummary
+	summary.DataSize = w.sst.DataSize
+	counts := countRows(summary, w.pkIDs)
+	w.files = append(w.files, BackupManifest_File{
+		Span:        roachpb.Span{Key: w.span.Key, EndKey: append(roachpb.Key(nil), w.span.EndKey...)},
+		Path:        path,
+		Sha512:      checksum,
+		EntryCounts: counts,
+	})
+	w.exported.add(counts)
+	return nil
+}
@@ -312,6 +312,7 @@ func backup(
 		roachpb.MVCCFilter(backupManifest.MVCCFilter),
 		backupManifest.StartTime,
 		backupManifest.EndTime,
+		job.Details().(jobspb.BackupDetails).MaxBandwidth,
 		progCh,
 	); err != nil {
 		return RowCount{}, err
@@ -451,6 +452,10 @@ func (b *backupResumer) Resume(
 		return errors.Wrapf(err, "creating checkpoint to %s", redactedURI)
 	}
 
+	if len(details.CompactFromURIs) > 0 {
+		return b.resumeCompaction(ctx, p, defaultStore, resultsCh)
+	}
+
 	ptsID := details.ProtectedTimestampRecord
 	if ptsID != nil && !b.testingKnobs.ignoreProtectedTimestamps {
 		if err := p.ExecCfg().ProtectedTimestampProvider.Verify(ctx, *ptsID); err != nil {
@@ -547,32 +552,8 @@ func (b *backupResumer) Resume(
 		}
 	}
 
-	// If this is a full backup that was automatically nested in a collection of
-	// backups, record the path under which we wrote it to the LATEST file in the
-	// root of the collection. Note: this file *not* encrypted, as it only
-	// contains the name of another file that is in the same folder -- if you can
-	// get to this file to read it, you could already find its contents from the
-	// listing of the directory it is in -- it exists only to save us a
-	// potentially expensive listing of a giant backup collection to find the most
-	// recent completed entry.
 	if backupManifest.StartTime.IsEmpty() && details.CollectionURI != "" {
-		backupURI, err := url.Parse(details.URI)
-		if err != nil {
-			return err
-		}
-		collectionURI, err := url.Parse(details.CollectionURI)
-		if err != nil {
-			return err
-		}
-
-		suffix := strings.TrimPrefix(path.Clean(backupURI.Path), path.Clean(collectionURI.Path))
-
-		c, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, details.CollectionURI, p.User())
-		if err != nil {
-			return err
-		}
-		defer c.Close()
-		if err := c.WriteFile(ctx, latestFileName, strings.NewReader(suffix)); err != nil {
+		if err := writeLatestFile(ctx, p, details); err != nil {
 			return err
 		}
 	}
@@ -613,6 +594,34 @@ func (b *backupResumer) Resume(
 	return nil
 }
 
+// writeLatestFile records the path under which a full backup that was
+// automatically nested in a collection of backups was written to the LATEST
+// file in the root of the collection. Note: this file *not* encrypted, as it
+// only contains the name of another file that is in the same folder -- if you
+// can get to this file to read it, you could already find its contents from
+// the listing of the directory it is in -- it exists only to save us a
+// potentially expensive listing of a giant backup collection to find the most
+// recent completed entry.
+func writeLatestFile(ctx context.Context, p sql.JobExecContext, details jobspb.BackupDetails) error {
+	backupURI, err := url.Parse(details.URI)
+	if err != nil {
+		return err
+	}
+	collectionURI, err := url.Parse(details.CollectionURI)
+	if err != nil {
+		return err
+	}
+
+	suffix := strings.TrimPrefix(path.Clean(backupURI.Path), path.Clean(collectionURI.Path))
+
+	c, err := p.ExecCfg().DistSQLSrv.ExternalStorageFromURI(ctx, details.CollectionURI, p.User())
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.WriteFile(ctx, latestFileName, strings.NewReader(suffix))
+}
+
 func (b *backupResumer) maybeNotifyScheduledJobCompletion(
 	ctx context.Context, jobStatus jobs.Status, exec *sql.ExecutorConfig,
 ) {
@@ -58,6 +58,7 @@ const (
 	backupOptEncPassphrase   = "encryption_passphrase"
 	backupOptEncKMS          = "kms"
 	backupOptWithPrivileges  = "privileges"
+	backupOptVerify          = "verify"
 	localityURLParam         = "COCKROACH_LOCALITY"
 	defaultLocalityValue     = "default"
 )
@@ -450,6 +451,8 @@ func resolveOptionsForBackupJobDescription(
 	newOpts := tree.BackupOptions{
 		CaptureRevisionHistory: opts.CaptureRevisionHistory,
 		Detached:               opts.Detached,
+		Compact:                opts.Compact,
+		MaxBandwidth:           opts.MaxBandwidth,
 	}
 
 	if opts.EncryptionPassphrase != nil {
@@ -699,6 +702,14 @@ func backupPlanHook(
 		}
 	}
 
+	var maxBandwidthFn func() (string, error)
+	if backupStmt.Options.MaxBandwidth != nil {
+		maxBandwidthFn, err = p.TypeAsString(ctx, backupStmt.Options.MaxBandwidth, "BACKUP")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	toFn, err := p.TypeAsStringArray(ctx, tree.Exprs(backupStmt.To), "BACKUP")
 	if err != nil {
 		return nil, nil, nil, false, err
@@ -789,6 +800,28 @@ func backupPlanHook(
 			return err
 		}
 
+		var maxBandwidth int64
+		if maxBandwidthFn != nil {
+			s, err := maxBandwidthFn()
+			if err != nil {
+				return err
+			}
+			if maxBandwidth, err = parseMaxBandwidth(s); err != nil {
+				return err
+			}
+		}
+
+		if backupStmt.Options.Compact {
+			if len(incrementalFrom) > 0 {
+				return errors.New("the compact option cannot be used with INCREMENTAL FROM")
+			}
+			if err := checkBackupCompactionStatement(backupStmt); err != nil {
+				return err
+			}
+			return planBackupCompaction(ctx, p, backupStmt, to, subdir, encryptionParams, pwFn, kmsFn,
+				maxBandwidth, resultsCh)
+		}
+
 		endTime := p.ExecCfg().Clock.Now()
 		if backupStmt.AsOf.Expr != nil {
 			var err error
@@ -1123,6 +1156,7 @@ func backupPlanHook(
 			EncryptionOptions: encryptionOptions,
 			EncryptionInfo:    encryptionInfo,
 			CollectionURI:     collectionURI,
+			MaxBandwidth:      maxBandwidth,
 		}
 		if len(spans) > 0 && p.ExecCfg().Codec.ForSystemTenant() {
 			protectedtsID := uuid.MakeV4()
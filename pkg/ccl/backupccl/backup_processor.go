@@ -118,8 +118,17 @@ type spanAndTime struct {
 	start, end hlc.Timestamp
 	attempts   int
 	lastTried  time.Time
+	// overloadDelay is the total time exports of this span have been delayed
+	// because the store serving them was overloaded, and overloadBackoff is how
+	// long to delay the next one if it is overloaded again.
+	overloadDelay, overloadBackoff time.Duration
 }
 
+const (
+	initialOverloadBackoff = 50 * time.Millisecond
+	maxOverloadBackoff     = 5 * time.Second
+)
+
 func runBackupProcessor(
 	ctx context.Context,
 	flowCtx *execinfra.FlowCtx,
@@ -140,6 +149,17 @@ func runBackupProcessor(
 	//  *2). See #49798.
 	numSenders := int(kvserver.ExportRequestsLimit.Get(&settings.SV)) * 2
 	targetFileSize := storageccl.ExportRequestTargetFileSize.Get(&settings.SV)
+	// The exported files are written to external storage by the nodes serving
+	// the ExportRequests, which limit the rate at which they write them. Each
+	// sender has at most one request in flight, so splitting the limit evenly
+	// among the requests keeps the processor as a whole under it.
+	var maxBandwidthPerRequest int64
+	if spec.MaxBandwidth > 0 {
+		maxBandwidthPerRequest = spec.MaxBandwidth / int64(numSenders)
+		if maxBandwidthPerRequest == 0 {
+			maxBandwidthPerRequest = 1
+		}
+	}
 
 	// For all backups, partitioned or not, the main BACKUP manifest is stored at
 	// details.URI.
@@ -183,6 +203,14 @@ func runBackupProcessor(
 					MVCCFilter:                          spec.MVCCFilter,
 					Encryption:                          spec.Encryption,
 					TargetFileSize:                      targetFileSize,
+					MaxBandwidth:                        maxBandwidthPerRequest,
+				}
+				// While adaptive backoff is enabled, ask the store to reject the export
+				// if it is overloaded, until the span has been delayed for long enough
+				// that we export it anyway.
+				if storageccl.ExportAdaptiveBackoffEnabled.Get(&settings.SV) &&
+					span.overloadDelay < storageccl.ExportAdaptiveBackoffMaxDelay.Get(&settings.SV) {
+					req.FailOnStoreOverload = true
 				}
 
 				// If we're doing re-attempts but are not yet in the priority regime,
@@ -232,6 +260,28 @@ func runBackupProcessor(
 						// the intents being hit.
 						continue
 					}
+					if errors.Is(pErr.GoError(), storageccl.ErrExportStoreOverloaded) {
+						// Back off exponentially before sending the span again, so that the
+						// backup yields to foreground traffic on the overloaded store.
+						if span.overloadBackoff == 0 {
+							span.overloadBackoff = initialOverloadBackoff
+						}
+						log.VEventf(ctx, 1, "delaying export of span %s by %s: %v",
+							span.span, span.overloadBackoff, pErr.GoError())
+						timer.Reset(span.overloadBackoff)
+						select {
+						case <-done:
+							return ctx.Err()
+						case <-timer.C:
+							timer.Read = true
+						}
+						span.overloadDelay += span.overloadBackoff
+						if span.overloadBackoff *= 2; span.overloadBackoff > maxOverloadBackoff {
+							span.overloadBackoff = maxOverloadBackoff
+						}
+						todo <- span
+						continue
+					}
 					return errors.Wrapf(pErr.GoError(), "exporting %s", span.span)
 				}
 				res := rawRes.(*roachpb.ExportResponse)
@@ -40,6 +40,7 @@ func distBackup(
 	encryption *jobspb.BackupEncryptionOptions,
 	mvccFilter roachpb.MVCCFilter,
 	startTime, endTime hlc.Timestamp,
+	maxBandwidth int64,
 	progCh chan *execinfrapb.RemoteProducerMetadata_BulkProcessorProgress,
 ) error {
 	ctx = logtags.AddTag(ctx, "backup-distsql", nil)
@@ -66,6 +67,7 @@ func distBackup(
 		mvccFilter,
 		encryption,
 		startTime, endTime,
+		maxBandwidth,
 		execCtx.User(),
 		execCtx.ExecCfg(),
 	)
@@ -135,6 +137,7 @@ func makeBackupDataProcessorSpecs(
 	mvccFilter roachpb.MVCCFilter,
 	encryption *jobspb.BackupEncryptionOptions,
 	startTime, endTime hlc.Timestamp,
+	maxBandwidth int64,
 	user security.SQLUsername,
 	execCfg *sql.ExecutorConfig,
 ) (map[roachpb.NodeID]*execinfrapb.BackupDataSpec, error) {
@@ -191,6 +194,7 @@ func makeBackupDataProcessorSpecs(
 			BackupStartTime:  startTime,
 			BackupEndTime:    endTime,
 			UserProto:        user.EncodeProto(),
+			MaxBandwidth:     maxBandwidth,
 		}
 		nodeToSpec[partition.Node] = spec
 	}
@@ -212,6 +216,7 @@ func makeBackupDataProcessorSpecs(
 				BackupStartTime:  startTime,
 				BackupEndTime:    endTime,
 				UserProto:        user.EncodeProto(),
+				MaxBandwidth:     maxBandwidth,
 			}
 			nodeToSpec[partition.Node] = spec
 		}
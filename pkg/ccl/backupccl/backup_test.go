@@ -58,6 +58,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
 	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl/kmstest"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
@@ -669,6 +670,96 @@ func TestBackupRestoreAppend(t *testing.T) {
 
 }
 
+func TestBackupCompaction(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 10
+	_, _, sqlDB, tmpDir, cleanupFn := BackupRestoreTestSetup(t, singleNode, numAccounts, InitNone)
+	defer cleanupFn()
+
+	const collection = "nodelocal://0/collection"
+	readLatestIn := func(dir string) string {
+		latest, err := ioutil.ReadFile(path.Join(tmpDir, dir, latestFileName))
+		require.NoError(t, err)
+		return string(latest)
+	}
+	readLatest := func() string { return readLatestIn("collection") }
+
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, collection)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1 WHERE id < 3`)
+	sqlDB.Exec(t, `DELETE FROM data.bank WHERE id >= 8`)
+	sqlDB.Exec(t, `CREATE TABLE data.t (k INT PRIMARY KEY, v STRING, INDEX (v))`)
+	sqlDB.Exec(t, `INSERT INTO data.t VALUES (1, 'a'), (2, 'b'), (3, 'c')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, collection)
+	sqlDB.Exec(t, `UPDATE data.t SET v = 'z' WHERE k = 2`)
+	sqlDB.Exec(t, `DELETE FROM data.t WHERE k = 3`)
+	sqlDB.Exec(t, `INSERT INTO data.bank VALUES (100, 100, 'new')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, collection)
+	chain := readLatest()
+
+	expectedBank := sqlDB.QueryStr(t, `SELECT * FROM data.bank ORDER BY id`)
+	expectedT := sqlDB.QueryStr(t, `SELECT * FROM data.t@t_v_idx ORDER BY v`)
+
+	sqlDB.Exec(t, `BACKUP INTO LATEST IN $1 WITH compact`, collection)
+	compacted := readLatest()
+	require.NotEqual(t, chain, compacted)
+	sqlDB.CheckQueryResults(t,
+		`SELECT description FROM [SHOW JOBS] WHERE job_type = 'BACKUP' ORDER BY created DESC LIMIT 1`,
+		[][]string{{fmt.Sprintf("BACKUP INTO '%s' IN '%s' WITH compact", chain, collection)}})
+
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(
+		`SELECT object_name, start_time IS NULL, rows FROM [SHOW BACKUP '%s']
+		 WHERE object_type = 'table' ORDER BY object_name`, collection+compacted),
+		[][]string{{"bank", "true", "9"}, {"t", "true", "2"}})
+
+	// Incremental backups can be appended to the compacted backup.
+	sqlDB.Exec(t, `INSERT INTO data.t VALUES (4, 'd')`)
+	sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1`, collection)
+	require.Equal(t, compacted, readLatest())
+	expectedT = [][]string{{"1", "a"}, {"4", "d"}, {"2", "z"}}
+
+	// The compacted backup does not depend on the backups of the chain it
+	// compacts.
+	sqlDB.Exec(t, `DROP DATABASE data CASCADE`)
+	require.NoError(t, os.RemoveAll(path.Join(tmpDir, "collection", chain)))
+	sqlDB.Exec(t, `RESTORE DATABASE data FROM $1 IN $2`, compacted, collection)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM data.bank ORDER BY id`, expectedBank)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM data.t@t_v_idx ORDER BY v`, expectedT)
+
+	t.Run("errors", func(t *testing.T) {
+		sqlDB.Exec(t, `BACKUP DATABASE data INTO $1`, "nodelocal://0/full-only")
+		sqlDB.ExpectErr(t, "has no incremental backups to compact",
+			`BACKUP INTO LATEST IN $1 WITH compact`, "nodelocal://0/full-only")
+		sqlDB.ExpectErr(t, "no backup found",
+			`BACKUP INTO 'missing' IN $1 WITH compact`, collection)
+		sqlDB.ExpectErr(t, "cannot be used with backup targets",
+			`BACKUP DATABASE data INTO LATEST IN $1 WITH compact`, collection)
+		sqlDB.ExpectErr(t, "requires BACKUP INTO LATEST IN",
+			`BACKUP INTO $1 WITH compact`, collection)
+		sqlDB.ExpectErr(t, "cannot be used with the revision_history option",
+			`BACKUP INTO LATEST IN $1 WITH compact, revision_history`, collection)
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		const encrypted = "nodelocal://0/encrypted"
+		sqlDB.Exec(t, `BACKUP DATABASE data INTO $1 WITH encryption_passphrase = 'abc'`, encrypted)
+		sqlDB.Exec(t, `INSERT INTO data.t VALUES (5, 'e')`)
+		sqlDB.Exec(t, `BACKUP DATABASE data INTO LATEST IN $1 WITH encryption_passphrase = 'abc'`,
+			encrypted)
+		expected := sqlDB.QueryStr(t, `SELECT * FROM data.t ORDER BY k`)
+
+		sqlDB.ExpectErr(t, "file appears encrypted",
+			`BACKUP INTO LATEST IN $1 WITH compact`, encrypted)
+		sqlDB.Exec(t, `BACKUP INTO LATEST IN $1 WITH compact, encryption_passphrase = 'abc'`,
+			encrypted)
+		sqlDB.Exec(t, `DROP DATABASE data CASCADE`)
+		sqlDB.Exec(t, `RESTORE DATABASE data FROM $1 IN $2 WITH encryption_passphrase = 'abc'`,
+			readLatestIn("encrypted"), encrypted)
+		sqlDB.CheckQueryResults(t, `SELECT * FROM data.t ORDER BY k`, expected)
+	})
+}
+
 func TestBackupAndRestoreJobDescription(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -4326,6 +4417,51 @@ func TestRegionalKMSEncryptedBackup(t *testing.T) {
 	})
 }
 
+// This test performs an encrypted BACKUP using KMSs of different cloud
+// providers, faked in-process, and then attempts to RESTORE the BACKUP using
+// each one of them separately.
+func TestCrossCloudKMSEncryptedBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	gcpKMS, err := kmstest.NewGCPKMS()
+	require.NoError(t, err)
+	defer gcpKMS.Close()
+	azureKMS := kmstest.NewAzureKMS()
+	defer azureKMS.Close()
+
+	kmsURIs := []string{
+		gcpKMS.URI("projects/p/locations/global/keyRings/r/cryptoKeys/k"),
+		azureKMS.URI("key", "1"),
+	}
+
+	ctx, _, sqlDB, rawDir, cleanupFn := BackupRestoreTestSetup(t, MultiNode, 3, InitNone)
+	defer cleanupFn()
+
+	setupBackupEncryptedTest(ctx, t, sqlDB)
+	before := sqlDB.QueryStr(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE neverappears.neverappears`)
+
+	backupLoc := LocalFoo + "/x"
+	sqlDB.Exec(t, fmt.Sprintf(`BACKUP DATABASE neverappears TO $1 WITH %s`,
+		concatMultiRegionKMSURIs(kmsURIs)), backupLoc)
+	checkBackupFilesEncrypted(t, rawDir)
+
+	// Attempt to RESTORE using each of the KMSs independently.
+	for _, uri := range kmsURIs {
+		sqlDB.Exec(t, fmt.Sprintf(`SHOW BACKUP $1 WITH KMS='%s'`, uri), backupLoc)
+
+		sqlDB.Exec(t, `DROP DATABASE neverappears CASCADE`)
+		sqlDB.Exec(t, fmt.Sprintf(`RESTORE DATABASE neverappears FROM $1 WITH %s`,
+			concatMultiRegionKMSURIs([]string{uri})), backupLoc)
+		sqlDB.CheckQueryResults(t, `SHOW EXPERIMENTAL_FINGERPRINTS FROM TABLE neverappears.neverappears`, before)
+	}
+
+	// A KMS which was not used during the BACKUP cannot decrypt it.
+	sqlDB.ExpectErr(t, "one of the provided URIs was not used when encrypting the base BACKUP",
+		fmt.Sprintf(`RESTORE DATABASE neverappears FROM $1 WITH KMS='%s'`, azureKMS.URI("key", "2")),
+		backupLoc)
+}
+
 type testKMSEnv struct {
 	settings         *cluster.Settings
 	externalIOConfig *base.ExternalIODirConfig
@@ -4594,6 +4730,124 @@ func TestRestoreInto(t *testing.T) {
 	sqlDB.CheckQueryResults(t, `SELECT * FROM "data 2".bank`, expected)
 }
 
+func TestRestoreSubset(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 20
+	_, _, sqlDB, _, cleanupFn := BackupRestoreTestSetup(t, singleNode, numAccounts, InitNone)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `CREATE TABLE data.t (tenant INT, k INT, v STRING, PRIMARY KEY (tenant, k DESC), INDEX (tenant) STORING (v))`)
+	sqlDB.Exec(t, `INSERT INTO data.t SELECT i % 3, i, i::STRING FROM generate_series(1, 30) AS g(i)`)
+	sqlDB.Exec(t, `CREATE TABLE data.u (tenant INT, k INT, v INT UNIQUE, PRIMARY KEY (tenant, k))`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1`, LocalFoo)
+
+	for i, tc := range []struct {
+		table, where, expected string
+	}{
+		{"bank", "id = 3", "id = 3"},
+		{"bank", "id IN (1, 7, 19)", "id IN (1, 7, 19)"},
+		{"bank", "id >= 5 AND id < 8", "id >= 5 AND id < 8"},
+		{"bank", "10 < id", "id > 10"},
+		{"bank", "id BETWEEN 2 AND 4", "id BETWEEN 2 AND 4"},
+		{"t", "tenant = 1", "tenant = 1"},
+		{"t", "tenant IN (0, 2) AND k > 20", "tenant IN (0, 2) AND k > 20"},
+	} {
+		db := fmt.Sprintf("scratch%d", i)
+		sqlDB.Exec(t, fmt.Sprintf(`CREATE DATABASE %s`, db))
+		sqlDB.Exec(t, fmt.Sprintf(`RESTORE data.%s FROM $1 WITH into_db = $2, where = $3`, tc.table),
+			LocalFoo, db, tc.where)
+		sqlDB.CheckQueryResults(t,
+			fmt.Sprintf(`SELECT * FROM %s.%s ORDER BY 1, 2`, db, tc.table),
+			sqlDB.QueryStr(t, fmt.Sprintf(
+				`SELECT * FROM data.%s WHERE %s ORDER BY 1, 2`, tc.table, tc.expected)))
+		if tc.table == "t" {
+			sqlDB.CheckQueryResults(t,
+				fmt.Sprintf(`SELECT tenant, k, v FROM %s.t@t_tenant_idx ORDER BY 1, 2`, db),
+				sqlDB.QueryStr(t, fmt.Sprintf(
+					`SELECT tenant, k, v FROM data.t WHERE %s ORDER BY 1, 2`, tc.expected)))
+		}
+	}
+
+	sqlDB.CheckQueryResults(t,
+		`SELECT description FROM [SHOW JOBS] WHERE job_type = 'RESTORE' ORDER BY created DESC LIMIT 1`,
+		[][]string{{fmt.Sprintf(
+			"RESTORE TABLE data.t FROM '%s' WITH into_db='scratch6', where='tenant IN (0, 2) AND k > 20'",
+			LocalFoo)}})
+
+	t.Run("errors", func(t *testing.T) {
+		sqlDB.Exec(t, `CREATE DATABASE errors`)
+		for _, tc := range []struct {
+			stmt, err string
+		}{
+			{`RESTORE DATABASE data FROM $1 WITH where = 'id = 1'`,
+				"can only be used when restoring a table"},
+			{`RESTORE data.* FROM $1 WITH into_db = 'errors', where = 'id = 1'`,
+				"can only be used when restoring a single table"},
+			{`RESTORE data.bank FROM $1 WITH into_db = 'errors', where = 'balance = 1'`,
+				"must be a prefix of the key columns"},
+			{`RESTORE data.t FROM $1 WITH into_db = 'errors', where = 'k = 1'`,
+				"must be a prefix of the key columns"},
+			{`RESTORE data.t FROM $1 WITH into_db = 'errors', where = 'tenant > 1 AND k = 1'`,
+				"must be a prefix of the key columns"},
+			{`RESTORE data.u FROM $1 WITH into_db = 'errors', where = 'tenant = 1'`,
+				`index "u_v_key" has key columns \[v\]`},
+			{`RESTORE data.u FROM $1 WITH into_db = 'errors', where = 'tenant = 1 AND k = 2'`,
+				`index "u_v_key" has key columns \[v\]`},
+			{`RESTORE data.bank FROM $1 WITH into_db = 'errors', where = 'id = 1 OR id = 2'`,
+				"unsupported where predicate"},
+			{`RESTORE data.bank FROM $1 WITH into_db = 'errors', where = 'id = 1 AND id = 2'`,
+				"constrained more than once"},
+			{`RESTORE data.bank FROM $1 WITH into_db = 'errors', where = 'id = NULL'`,
+				"comparisons with NULL are not supported"},
+			{`RESTORE data.bank FROM $1 WITH into_db = 'errors', where = 'id = ''a'''`,
+				"could not parse"},
+		} {
+			sqlDB.ExpectErr(t, tc.err, tc.stmt, LocalFoo)
+		}
+	})
+}
+
+func TestBackupRestoreMaxBandwidth(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 100
+	_, _, sqlDB, _, cleanupFn := BackupRestoreTestSetup(t, singleNode, numAccounts, InitNone)
+	defer cleanupFn()
+
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1 WITH max_bandwidth = '1MiB'`, LocalFoo)
+	sqlDB.CheckQueryResults(t,
+		`SELECT description FROM [SHOW JOBS] WHERE job_type = 'BACKUP' ORDER BY created DESC LIMIT 1`,
+		[][]string{{fmt.Sprintf("BACKUP DATABASE data TO '%s' WITH max_bandwidth='1MiB'", LocalFoo)}})
+
+	sqlDB.Exec(t, `CREATE DATABASE scratch`)
+	sqlDB.Exec(t, `RESTORE data.bank FROM $1 WITH into_db = 'scratch', max_bandwidth = '1MiB'`,
+		LocalFoo)
+	sqlDB.CheckQueryResults(t, `SELECT * FROM scratch.bank ORDER BY id`,
+		sqlDB.QueryStr(t, `SELECT * FROM data.bank ORDER BY id`))
+	sqlDB.CheckQueryResults(t,
+		`SELECT description FROM [SHOW JOBS] WHERE job_type = 'RESTORE' ORDER BY created DESC LIMIT 1`,
+		[][]string{{fmt.Sprintf(
+			"RESTORE TABLE data.bank FROM '%s' WITH into_db='scratch', max_bandwidth='1MiB'", LocalFoo)}})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			stmt, err string
+		}{
+			{`BACKUP DATABASE data TO $1 WITH max_bandwidth = 'fast'`,
+				"invalid value for max_bandwidth option"},
+			{`BACKUP DATABASE data TO $1 WITH max_bandwidth = '0'`,
+				"max_bandwidth option must be positive"},
+			{`RESTORE data.bank FROM $1 WITH into_db = 'scratch', max_bandwidth = '-1MiB'`,
+				"max_bandwidth option must be positive"},
+		} {
+			sqlDB.ExpectErr(t, tc.err, tc.stmt, LocalFoo)
+		}
+	})
+}
+
 func TestRestoreDatabaseVersusTable(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
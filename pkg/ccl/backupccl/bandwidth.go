@@ -0,0 +1,32 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+)
+
+const backupOptMaxBandwidth = "max_bandwidth"
+
+// parseMaxBandwidth parses the value of the max_bandwidth option of BACKUP
+// and RESTORE, a size such as '200MiB', into a number of bytes per second.
+func parseMaxBandwidth(s string) (int64, error) {
+	n, err := humanizeutil.ParseBytes(s)
+	if err != nil {
+		return 0, pgerror.Wrapf(err, pgcode.InvalidParameterValue,
+			"invalid value for %s option", backupOptMaxBandwidth)
+	}
+	if n <= 0 {
+		return 0, pgerror.Newf(pgcode.InvalidParameterValue,
+			"%s option must be positive, got %q", backupOptMaxBandwidth, s)
+	}
+	return n, nil
+}
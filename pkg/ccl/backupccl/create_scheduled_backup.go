@@ -593,6 +593,9 @@ func makeScheduledBackupEval(
 	if err != nil {
 		return nil, err
 	}
+	if schedule.BackupOptions.Compact {
+		return nil, errors.New("the compact option is not supported by backup schedules")
+	}
 	if schedule.BackupOptions.EncryptionPassphrase != nil {
 		eval.encryptionPassphrase, err =
 			p.TypeAsString(ctx, schedule.BackupOptions.EncryptionPassphrase, scheduleBackupOp)
@@ -577,7 +577,7 @@ func getLocalityInfo(
 const incBackupSubdirGlob = "[0-9]*/[0-9]*.[0-9][0-9]/"
 
 // findPriorBackupNames finds "appended" incremental backups, as done by
-// findPriorBackupLocations and appends the backup manifest file name to
+// FindPriorBackupLocations and appends the backup manifest file name to
 // the URI.
 func findPriorBackupNames(ctx context.Context, store cloud.ExternalStorage) ([]string, error) {
 	prev, err := store.ListFiles(ctx, incBackupSubdirGlob+backupManifestName)
@@ -588,12 +588,12 @@ func findPriorBackupNames(ctx context.Context, store cloud.ExternalStorage) ([]s
 	return prev, nil
 }
 
-// findPriorBackupLocations finds "appended" incremental backups by searching
+// FindPriorBackupLocations finds "appended" incremental backups by searching
 // for the subdirectories matching the naming pattern (e.g. YYMMDD/HHmmss.ss).
 // Using file-system searching rather than keeping an explicit list allows
 // layers to be manually moved/removed/etc without needing to update/maintain
 // said list.
-func findPriorBackupLocations(ctx context.Context, store cloud.ExternalStorage) ([]string, error) {
+func FindPriorBackupLocations(ctx context.Context, store cloud.ExternalStorage) ([]string, error) {
 	backupManifestSuffix := backupManifestName
 	prev, err := store.ListFiles(ctx, incBackupSubdirGlob+backupManifestSuffix)
 	if err != nil {
@@ -898,6 +898,19 @@ func sanitizeLocalityKV(kv string) string {
 	return string(sanitizedKV)
 }
 
+// IsEncryptedBackup returns whether the backup in store was taken with
+// encryption, which is recorded by the encryption information stored with it.
+func IsEncryptedBackup(ctx context.Context, store cloud.ExternalStorage) (bool, error) {
+	r, err := store.ReadFile(ctx, backupEncryptionInfoFile)
+	if err != nil {
+		if errors.Is(err, cloudimpl.ErrFileDoesNotExist) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "checking for encryption information")
+	}
+	return true, r.Close()
+}
+
 func readEncryptionOptions(
 	ctx context.Context, src cloud.ExternalStorage,
 ) (*jobspb.EncryptionInfo, error) {
@@ -44,8 +44,9 @@ type restoreDataProcessor struct {
 	input   execinfra.RowSource
 	output  execinfra.RowReceiver
 
-	alloc rowenc.DatumAlloc
-	kr    *storageccl.KeyRewriter
+	alloc   rowenc.DatumAlloc
+	kr      *storageccl.KeyRewriter
+	limiter *storageccl.BandwidthLimiter
 }
 
 var _ execinfra.Processor = &restoreDataProcessor{}
@@ -66,6 +67,7 @@ func newRestoreDataProcessor(
 		input:   input,
 		spec:    spec,
 		output:  output,
+		limiter: storageccl.NewBandwidthLimiter("restore-data", spec.MaxBandwidth),
 	}
 
 	var err error
@@ -205,7 +207,7 @@ func (rd *restoreDataProcessor) processRestoreSpanEntry(
 				return err
 			}
 			defer f.Close()
-			fileContents, err = ioutil.ReadAll(f)
+			fileContents, err = ioutil.ReadAll(rd.limiter.Reader(ctx, f))
 			return err
 		}); err != nil {
 			return summary, errors.Wrapf(err, "fetching %q", file.Path)
@@ -629,6 +629,7 @@ func restore(
 		encryption,
 		rekeys,
 		endTime,
+		job.Details().(jobspb.RestoreDetails).MaxBandwidth,
 		progCh,
 	); err != nil {
 		return emptyRowCount, err
@@ -924,6 +925,9 @@ func createImportingDescriptors(
 	// We get the spans of the restoring tables _as they appear in the backup_,
 	// that is, in the 'old' keyspace, before we reassign the table IDs.
 	spans = spansForAllRestoreTableIndexes(p.ExecCfg().Codec, tables, nil)
+	if len(details.Spans) > 0 {
+		spans = details.Spans
+	}
 
 	log.Eventf(ctx, "starting restore for %d tables", len(mutableTables))
 
@@ -1178,6 +1182,11 @@ func (r *restoreResumer) Resume(
 		return err
 	}
 	latestStats := remapRelevantStatistics(backupStats, details.DescriptorRewrites)
+	if len(details.Spans) > 0 {
+		// The statistics in the backup describe all of the rows of the table, not
+		// the subset being restored.
+		latestStats = nil
+	}
 
 	if len(details.TableDescs) == 0 && len(details.Tenants) == 0 && len(details.TypeDescs) == 0 {
 		// We have no tables to restore (we are restoring an empty DB).
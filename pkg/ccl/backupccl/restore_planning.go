@@ -64,6 +64,7 @@ const (
 	restoreOptSkipMissingSequences      = "skip_missing_sequences"
 	restoreOptSkipMissingSequenceOwners = "skip_missing_sequence_owners"
 	restoreOptSkipMissingViews          = "skip_missing_views"
+	restoreOptWhere                     = "where"
 
 	// The temporary database system tables will be restored into for full
 	// cluster backups.
@@ -1197,7 +1198,7 @@ func getUserDescriptorNames(
 }
 
 func resolveOptionsForRestoreJobDescription(
-	opts tree.RestoreOptions, intoDB string, kmsURIs []string,
+	opts tree.RestoreOptions, intoDB string, kmsURIs []string, where string,
 ) (tree.RestoreOptions, error) {
 	if opts.IsDefault() {
 		return opts, nil
@@ -1209,6 +1210,7 @@ func resolveOptionsForRestoreJobDescription(
 		SkipMissingSequenceOwners: opts.SkipMissingSequenceOwners,
 		SkipMissingViews:          opts.SkipMissingViews,
 		Detached:                  opts.Detached,
+		MaxBandwidth:              opts.MaxBandwidth,
 	}
 
 	if opts.EncryptionPassphrase != nil {
@@ -1219,6 +1221,10 @@ func resolveOptionsForRestoreJobDescription(
 		newOpts.IntoDB = tree.NewDString(intoDB)
 	}
 
+	if opts.Where != nil {
+		newOpts.Where = tree.NewDString(where)
+	}
+
 	for _, uri := range kmsURIs {
 		redactedURI, err := cloudimpl.RedactKMSURI(uri)
 		if err != nil {
@@ -1237,6 +1243,7 @@ func restoreJobDescription(
 	opts tree.RestoreOptions,
 	intoDB string,
 	kmsURIs []string,
+	where string,
 ) (string, error) {
 	r := &tree.Restore{
 		DescriptorCoverage: restore.DescriptorCoverage,
@@ -1247,7 +1254,7 @@ func restoreJobDescription(
 
 	var options tree.RestoreOptions
 	var err error
-	if options, err = resolveOptionsForRestoreJobDescription(opts, intoDB, kmsURIs); err != nil {
+	if options, err = resolveOptionsForRestoreJobDescription(opts, intoDB, kmsURIs, where); err != nil {
 		return "", err
 	}
 	r.Options = options
@@ -1323,6 +1330,27 @@ func restorePlanHook(
 		}
 	}
 
+	var whereFn func() (string, error)
+	if restoreStmt.Options.Where != nil {
+		if restoreStmt.DescriptorCoverage == tree.AllDescriptors ||
+			restoreStmt.Targets.Databases != nil || restoreStmt.Targets.Tenant != (roachpb.TenantID{}) {
+			return nil, nil, nil, false, errors.Errorf(
+				"the %s option can only be used when restoring a table", restoreOptWhere)
+		}
+		whereFn, err = p.TypeAsString(ctx, restoreStmt.Options.Where, "RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
+	var maxBandwidthFn func() (string, error)
+	if restoreStmt.Options.MaxBandwidth != nil {
+		maxBandwidthFn, err = p.TypeAsString(ctx, restoreStmt.Options.MaxBandwidth, "RESTORE")
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	subdirFn := func() (string, error) { return "", nil }
 	if restoreStmt.Subdir != nil {
 		subdirFn, err = p.TypeAsString(ctx, restoreStmt.Subdir, "RESTORE")
@@ -1403,7 +1431,28 @@ func restorePlanHook(
 			}
 		}
 
-		return doRestorePlan(ctx, restoreStmt, p, from, passphrase, kms, intoDB, endTime, resultsCh)
+		var where string
+		if whereFn != nil {
+			where, err = whereFn()
+			if err != nil {
+				return err
+			}
+		}
+
+		var maxBandwidth int64
+		if maxBandwidthFn != nil {
+			s, err := maxBandwidthFn()
+			if err != nil {
+				return err
+			}
+			if maxBandwidth, err = parseMaxBandwidth(s); err != nil {
+				return err
+			}
+		}
+
+		return doRestorePlan(
+			ctx, restoreStmt, p, from, passphrase, kms, intoDB, where, maxBandwidth, endTime, resultsCh,
+		)
 	}
 
 	if restoreStmt.Options.Detached {
@@ -1477,6 +1526,8 @@ func doRestorePlan(
 	passphrase string,
 	kms []string,
 	intoDB string,
+	where string,
+	maxBandwidth int64,
 	endTime hlc.Timestamp,
 	resultsCh chan<- tree.Datums,
 ) error {
@@ -1623,7 +1674,9 @@ func doRestorePlan(
 	if err != nil {
 		return err
 	}
-	description, err := restoreJobDescription(p, restoreStmt, from, restoreStmt.Options, intoDB, kms)
+	description, err := restoreJobDescription(
+		p, restoreStmt, from, restoreStmt.Options, intoDB, kms, where,
+	)
 	if err != nil {
 		return err
 	}
@@ -1647,6 +1700,21 @@ func doRestorePlan(
 		types = append(types, desc)
 	}
 
+	// The spans to restore are those of the table as it appears in the backup,
+	// so they must be computed before the table IDs are rewritten.
+	var spans []roachpb.Span
+	if restoreStmt.Options.Where != nil {
+		if len(tables) != 1 {
+			return errors.Errorf(
+				"the %s option can only be used when restoring a single table", restoreOptWhere)
+		}
+		spans, err = spansForRestoreSubset(ctx, p.ExecCfg().Codec, p.SemaCtx(),
+			&p.ExtendedEvalContext().EvalContext, tables[0], where)
+		if err != nil {
+			return err
+		}
+	}
+
 	// We attempt to rewrite ID's in the collected type and table descriptors
 	// to catch errors during this process here, rather than in the job itself.
 	if err := RewriteTableDescs(tables, descriptorRewrites, intoDB); err != nil {
@@ -1693,6 +1761,8 @@ func doRestorePlan(
 			OverrideDB:         intoDB,
 			DescriptorCoverage: restoreStmt.DescriptorCoverage,
 			Encryption:         encryption,
+			Spans:              spans,
+			MaxBandwidth:       maxBandwidth,
 		},
 		Progress: jobspb.RestoreProgress{},
 	}
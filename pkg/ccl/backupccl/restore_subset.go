@@ -0,0 +1,399 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/errors"
+)
+
+// keyColumnConstraint is the set of values that the predicate of a RESTORE
+// ... WITH where allows for a key column: either a list of values, or a
+// range.
+type keyColumnConstraint struct {
+	col *descpb.ColumnDescriptor
+
+	// values is set if the column is constrained by = or IN.
+	values tree.Datums
+
+	// lo and hi bound the column if it is constrained by a range comparison. A
+	// nil bound is unbounded.
+	lo, hi                   tree.Datum
+	loInclusive, hiInclusive bool
+}
+
+func (c *keyColumnConstraint) isRange() bool {
+	return c.values == nil
+}
+
+// restoreSubsetBuilder builds the spans of a table to restore from the
+// predicate of a RESTORE ... WITH where.
+type restoreSubsetBuilder struct {
+	semaCtx *tree.SemaContext
+	evalCtx *tree.EvalContext
+	table   catalog.TableDescriptor
+
+	constraints map[descpb.ColumnID]*keyColumnConstraint
+}
+
+// spansForRestoreSubset returns the spans of the given table, as it appears in
+// the backup, which contain exactly the rows matching the predicate. The
+// predicate must be a conjunction of comparisons between columns and
+// constants, and the constrained columns must be a prefix of the key columns
+// of every index of the table, as the restore otherwise could not leave the
+// indexes consistent with each other. The key columns of a non-unique
+// secondary index include the primary key columns it implicitly stores, so a
+// predicate on a prefix of the primary key can be used with secondary indexes
+// that lead with the same columns.
+func spansForRestoreSubset(
+	ctx context.Context,
+	codec keys.SQLCodec,
+	semaCtx *tree.SemaContext,
+	evalCtx *tree.EvalContext,
+	table catalog.TableDescriptor,
+	predicate string,
+) ([]roachpb.Span, error) {
+	expr, err := parser.ParseExpr(predicate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s option", restoreOptWhere)
+	}
+	if !table.IsTable() {
+		return nil, errors.Errorf(
+			"cannot use %s option with %q, which is not a table", restoreOptWhere, table.GetName())
+	}
+	if table.IsInterleaved() {
+		return nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"cannot use %s option with interleaved table %q", restoreOptWhere, table.GetName())
+	}
+
+	b := restoreSubsetBuilder{
+		semaCtx:     semaCtx,
+		evalCtx:     evalCtx,
+		table:       table,
+		constraints: make(map[descpb.ColumnID]*keyColumnConstraint),
+	}
+	if err := b.addConjunct(ctx, expr); err != nil {
+		return nil, err
+	}
+
+	var spans []roachpb.Span
+	for _, index := range table.AllNonDropIndexes() {
+		indexSpans, err := b.indexSpans(codec, index)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, indexSpans...)
+	}
+	spans, _ = roachpb.MergeSpans(spans)
+	return spans, nil
+}
+
+func unsupportedRestorePredicate(expr tree.Expr) error {
+	return pgerror.Newf(pgcode.FeatureNotSupported,
+		"unsupported %s predicate %q: only conjunctions of comparisons between "+
+			"columns and constants are supported", restoreOptWhere, tree.AsString(expr))
+}
+
+// addConjunct adds the constraints of the given conjunct of the predicate.
+func (b *restoreSubsetBuilder) addConjunct(ctx context.Context, expr tree.Expr) error {
+	switch t := expr.(type) {
+	case *tree.ParenExpr:
+		return b.addConjunct(ctx, t.Expr)
+
+	case *tree.AndExpr:
+		if err := b.addConjunct(ctx, t.Left); err != nil {
+			return err
+		}
+		return b.addConjunct(ctx, t.Right)
+
+	case *tree.RangeCond:
+		if t.Not || t.Symmetric {
+			return unsupportedRestorePredicate(expr)
+		}
+		c, err := b.constraintFor(t.Left, expr)
+		if err != nil {
+			return err
+		}
+		lo, err := b.evalConstant(ctx, c.col, t.From)
+		if err != nil {
+			return err
+		}
+		hi, err := b.evalConstant(ctx, c.col, t.To)
+		if err != nil {
+			return err
+		}
+		if err := c.setLo(lo, true /* inclusive */, expr); err != nil {
+			return err
+		}
+		return c.setHi(hi, true /* inclusive */, expr)
+
+	case *tree.ComparisonExpr:
+		left, right, op := t.Left, t.Right, t.Operator
+		if _, ok := left.(*tree.UnresolvedName); !ok && op != tree.In {
+			// Normalize "constant op column" to "column op' constant".
+			left, right = right, left
+			switch op {
+			case tree.LT:
+				op = tree.GT
+			case tree.LE:
+				op = tree.GE
+			case tree.GT:
+				op = tree.LT
+			case tree.GE:
+				op = tree.LE
+			}
+		}
+		c, err := b.constraintFor(left, expr)
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case tree.EQ, tree.In:
+			var exprs tree.Exprs
+			if op == tree.EQ {
+				exprs = tree.Exprs{right}
+			} else if tuple, ok := right.(*tree.Tuple); ok {
+				exprs = tuple.Exprs
+			} else {
+				return unsupportedRestorePredicate(expr)
+			}
+			if c.values != nil || c.lo != nil || c.hi != nil {
+				return pgerror.Newf(pgcode.FeatureNotSupported,
+					"column %q is constrained more than once in %s predicate",
+					c.col.Name, restoreOptWhere)
+			}
+			c.values = make(tree.Datums, 0, len(exprs))
+			for _, e := range exprs {
+				d, err := b.evalConstant(ctx, c.col, e)
+				if err != nil {
+					return err
+				}
+				c.values = append(c.values, d)
+			}
+			return nil
+
+		case tree.LT, tree.LE, tree.GT, tree.GE:
+			d, err := b.evalConstant(ctx, c.col, right)
+			if err != nil {
+				return err
+			}
+			if op == tree.LT || op == tree.LE {
+				return c.setHi(d, op == tree.LE, expr)
+			}
+			return c.setLo(d, op == tree.GE, expr)
+		}
+	}
+	return unsupportedRestorePredicate(expr)
+}
+
+// constraintFor returns the constraint of the column referenced by the given
+// expression, which is part of the conjunct expr.
+func (b *restoreSubsetBuilder) constraintFor(
+	colExpr tree.Expr, expr tree.Expr,
+) (*keyColumnConstraint, error) {
+	name, ok := colExpr.(*tree.UnresolvedName)
+	if !ok || name.Star || name.NumParts != 1 {
+		return nil, unsupportedRestorePredicate(expr)
+	}
+	col, _, err := b.table.FindColumnByName(tree.Name(name.Parts[0]))
+	if err != nil {
+		return nil, err
+	}
+	c, ok := b.constraints[col.ID]
+	if !ok {
+		c = &keyColumnConstraint{col: col}
+		b.constraints[col.ID] = c
+	}
+	return c, nil
+}
+
+// evalConstant evaluates the given constant expression as a value of the
+// given column.
+func (b *restoreSubsetBuilder) evalConstant(
+	ctx context.Context, col *descpb.ColumnDescriptor, expr tree.Expr,
+) (tree.Datum, error) {
+	typedExpr, err := tree.TypeCheckAndRequire(ctx, expr, b.semaCtx, col.Type, restoreOptWhere)
+	if err != nil {
+		return nil, err
+	}
+	d, err := typedExpr.Eval(b.evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if d == tree.DNull {
+		return nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"comparisons with NULL are not supported in %s predicate", restoreOptWhere)
+	}
+	return d, nil
+}
+
+func (c *keyColumnConstraint) setLo(d tree.Datum, inclusive bool, expr tree.Expr) error {
+	if c.values != nil || c.lo != nil {
+		return pgerror.Newf(pgcode.FeatureNotSupported,
+			"column %q is constrained more than once in %s predicate %q",
+			c.col.Name, restoreOptWhere, tree.AsString(expr))
+	}
+	c.lo, c.loInclusive = d, inclusive
+	return nil
+}
+
+func (c *keyColumnConstraint) setHi(d tree.Datum, inclusive bool, expr tree.Expr) error {
+	if c.values != nil || c.hi != nil {
+		return pgerror.Newf(pgcode.FeatureNotSupported,
+			"column %q is constrained more than once in %s predicate %q",
+			c.col.Name, restoreOptWhere, tree.AsString(expr))
+	}
+	c.hi, c.hiInclusive = d, inclusive
+	return nil
+}
+
+// indexSpans returns the spans of the given index which contain the entries
+// of the rows matching the predicate.
+func (b *restoreSubsetBuilder) indexSpans(
+	codec keys.SQLCodec, index *descpb.IndexDescriptor,
+) ([]roachpb.Span, error) {
+	keyCols := indexKeyColumns(index)
+	prefixLen := len(b.constraints)
+	usable := prefixLen <= len(keyCols)
+	for i := 0; usable && i < prefixLen; i++ {
+		c, ok := b.constraints[keyCols[i]]
+		// Only the last constrained column of the prefix may be a range.
+		usable = ok && (!c.isRange() || i == prefixLen-1)
+	}
+	if !usable {
+		keyColNames, err := b.table.NamesForColumnIDs(keyCols)
+		if err != nil {
+			return nil, err
+		}
+		return nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"the columns constrained by the %s predicate must be a prefix of the key "+
+				"columns of every index of table %q, with only the last one constrained "+
+				"by a range, but index %q has key columns %v",
+			restoreOptWhere, b.table.GetName(), index.Name, keyColNames)
+	}
+
+	prefixes := [][]byte{rowenc.MakeIndexKeyPrefix(codec, b.table, index.ID)}
+	var spans []roachpb.Span
+	for i := 0; i < prefixLen; i++ {
+		c := b.constraints[keyCols[i]]
+		// The implicit primary key columns of a secondary index are always
+		// encoded in ascending order.
+		dir := encoding.Ascending
+		if i < len(index.ColumnDirections) {
+			var err error
+			if dir, err = index.ColumnDirections[i].ToEncodingDirection(); err != nil {
+				return nil, err
+			}
+		}
+		encode := func(prefix []byte, d tree.Datum) (roachpb.Key, error) {
+			return rowenc.EncodeTableKey(append([]byte(nil), prefix...), d, dir)
+		}
+
+		if c.isRange() {
+			for _, prefix := range prefixes {
+				span, err := c.rangeSpan(prefix, dir, encode)
+				if err != nil {
+					return nil, err
+				}
+				if span.Key.Compare(span.EndKey) < 0 {
+					spans = append(spans, span)
+				}
+			}
+			return spans, nil
+		}
+
+		next := make([][]byte, 0, len(prefixes)*len(c.values))
+		for _, prefix := range prefixes {
+			for _, d := range c.values {
+				key, err := encode(prefix, d)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, key)
+			}
+		}
+		prefixes = next
+	}
+
+	for _, prefix := range prefixes {
+		key := roachpb.Key(prefix)
+		spans = append(spans, roachpb.Span{Key: key, EndKey: key.PrefixEnd()})
+	}
+	return spans, nil
+}
+
+// indexKeyColumns returns the columns whose values make up the keys of the
+// given index, in order.
+func indexKeyColumns(index *descpb.IndexDescriptor) []descpb.ColumnID {
+	switch {
+	case index.Type == descpb.IndexDescriptor_INVERTED:
+		// The last key column of an inverted index is not encoded as its value,
+		// so neither it nor the columns following it can be constrained.
+		return index.ColumnIDs[:len(index.ColumnIDs)-1]
+	case index.Unique:
+		// The implicit primary key columns of a unique index are only part of
+		// its keys if they contain NULLs.
+		return index.ColumnIDs
+	default:
+		keyCols := make([]descpb.ColumnID, 0, len(index.ColumnIDs)+len(index.ExtraColumnIDs))
+		keyCols = append(keyCols, index.ColumnIDs...)
+		return append(keyCols, index.ExtraColumnIDs...)
+	}
+}
+
+// rangeSpan returns the span of the keys under the given prefix whose next
+// column, encoded in the given direction, is within the range.
+func (c *keyColumnConstraint) rangeSpan(
+	prefix []byte,
+	dir encoding.Direction,
+	encode func(prefix []byte, d tree.Datum) (roachpb.Key, error),
+) (roachpb.Span, error) {
+	// In descending indexes the upper bound of the values is the lower bound of
+	// the keys. NULLs, which never match the range, sort first in ascending
+	// indexes and last in descending ones.
+	startVal, startInclusive, endVal, endInclusive := c.lo, c.loInclusive, c.hi, c.hiInclusive
+	start := roachpb.Key(encoding.EncodeNullAscending(append([]byte(nil), prefix...))).PrefixEnd()
+	end := roachpb.Key(prefix).PrefixEnd()
+	if dir == encoding.Descending {
+		startVal, startInclusive, endVal, endInclusive = c.hi, c.hiInclusive, c.lo, c.loInclusive
+		start = roachpb.Key(prefix)
+		end = encoding.EncodeNullDescending(append([]byte(nil), prefix...))
+	}
+
+	var err error
+	if startVal != nil {
+		if start, err = encode(prefix, startVal); err != nil {
+			return roachpb.Span{}, err
+		}
+		if !startInclusive {
+			start = start.PrefixEnd()
+		}
+	}
+	if endVal != nil {
+		if end, err = encode(prefix, endVal); err != nil {
+			return roachpb.Span{}, err
+		}
+		if endInclusive {
+			end = end.PrefixEnd()
+		}
+	}
+	return roachpb.Span{Key: start, EndKey: end}, nil
+}
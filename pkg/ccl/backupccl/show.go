@@ -94,6 +94,7 @@ func showBackupPlanHook(
 		backupOptEncPassphrase:  sql.KVStringOptRequireValue,
 		backupOptEncKMS:         sql.KVStringOptRequireValue,
 		backupOptWithPrivileges: sql.KVStringOptRequireNoValue,
+		backupOptVerify:         sql.KVStringOptRequireNoValue,
 	}
 	optsFn, err := p.TypeAsStringOpts(ctx, backup.Options, expected)
 	if err != nil {
@@ -104,11 +105,19 @@ func showBackupPlanHook(
 		return nil, nil, nil, false, err
 	}
 
+	_, verify := opts[backupOptVerify]
+	if verify && backup.Details != tree.BackupDefaultDetails {
+		return nil, nil, nil, false, errors.New(
+			"the verify option cannot be used with SHOW BACKUP RANGES or FILES")
+	}
+
 	var shower backupShower
-	switch backup.Details {
-	case tree.BackupRangeDetails:
+	switch {
+	case verify:
+		shower = backupShower{header: backupVerificationHeader}
+	case backup.Details == tree.BackupRangeDetails:
 		shower = backupShowerRanges
-	case tree.BackupFileDetails:
+	case backup.Details == tree.BackupFileDetails:
 		shower = backupShowerFiles
 	default:
 		shower = backupShowerDefault(ctx, p, backup.ShouldIncludeSchemas, opts)
@@ -209,7 +218,20 @@ func showBackupPlanHook(
 			return err
 		}
 
-		datums, err := shower.fn(manifests)
+		var datums []tree.Datums
+		if verify {
+			// The files of the incremental backups are relative to the directories
+			// of their manifests.
+			layerDirs := make([]string, len(manifests))
+			for i := range incPaths {
+				layerDirs[i+1] = path.Dir(incPaths[i])
+			}
+			datums, err = verifyBackup(
+				ctx, p.ExecCfg().Codec, p.User(), store, layerDirs, manifests, encryption,
+			)
+		} else {
+			datums, err = shower.fn(manifests)
+		}
 		if err != nil {
 			return err
 		}
@@ -12,7 +12,10 @@ import (
 	"context"
 	gosql "database/sql"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -500,3 +503,70 @@ func TestShowBackupPrivileges(t *testing.T) {
 	_, err = testuser.Exec(`SHOW BACKUP $1`, full)
 	require.NoError(t, err)
 }
+
+func TestShowBackupVerify(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const numAccounts = 11
+	_, _, sqlDB, tempDir, cleanupFn := BackupRestoreTestSetup(t, singleNode, numAccounts, InitNone)
+	defer cleanupFn()
+
+	const backupDir = "verify"
+	dest := LocalFoo + "/" + backupDir
+	localDir := filepath.Join(tempDir, "foo", backupDir)
+
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1`, dest)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1`, dest)
+	sqlDB.Exec(t, `UPDATE data.bank SET balance = balance + 1`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO $1`, dest)
+
+	query := fmt.Sprintf(
+		`SELECT backup_path, path, status, detail FROM [SHOW BACKUP '%s' WITH verify] WHERE status != 'ok'`, dest)
+	sqlDB.CheckQueryResults(t, fmt.Sprintf(
+		`SELECT count(DISTINCT backup_path) FROM [SHOW BACKUP '%s' WITH verify]`, dest), [][]string{{"3"}})
+	sqlDB.CheckQueryResults(t, query, [][]string{})
+
+	sqlDB.ExpectErr(t, "the verify option cannot be used with SHOW BACKUP RANGES or FILES",
+		`SHOW BACKUP FILES $1 WITH verify`, dest)
+
+	files := sqlDB.QueryStr(t,
+		`SELECT backup_path, path FROM [SHOW BACKUP $1 WITH verify] ORDER BY backup_path, path`, dest)
+	require.Greater(t, len(files), 2)
+
+	t.Run("corrupt", func(t *testing.T) {
+		file := filepath.Join(localDir, files[0][0], files[0][1])
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, ioutil.WriteFile(file, data, 0644)) }()
+		corrupted := append([]byte(nil), data...)
+		corrupted[len(corrupted)/2]++
+		require.NoError(t, ioutil.WriteFile(file, corrupted, 0644))
+
+		sqlDB.CheckQueryResults(t, query, [][]string{
+			{files[0][0], files[0][1], "corrupt", "checksum mismatch"},
+		})
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		file := filepath.Join(localDir, files[1][0], files[1][1])
+		require.NoError(t, os.Remove(file))
+
+		sqlDB.CheckQueryResults(t, query, [][]string{
+			{files[1][0], files[1][1], "missing", "NULL"},
+		})
+	})
+
+	t.Run("non-contiguous", func(t *testing.T) {
+		incrementals := sqlDB.QueryStr(t,
+			`SELECT DISTINCT backup_path FROM [SHOW BACKUP $1 WITH verify] WHERE backup_path != '/' ORDER BY backup_path`,
+			dest)
+		require.Len(t, incrementals, 2)
+		require.NoError(t, os.RemoveAll(filepath.Join(localDir, incrementals[0][0])))
+
+		rows := sqlDB.QueryStr(t,
+			`SELECT backup_path, status FROM [SHOW BACKUP $1 WITH verify] WHERE path IS NULL`, dest)
+		require.Equal(t, [][]string{{incrementals[1][0], "invalid"}}, rows)
+	})
+}
@@ -0,0 +1,259 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package backupccl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/covering"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// SHOW BACKUP ... WITH verify checks that a backup, along with the incremental
+// backups appended to it, could be restored, without restoring it: it reads
+// every file referenced by the manifests, and checks the chain of backups and
+// the spans they cover. It returns a row for each file, and a row for each
+// problem which does not concern a single file.
+const (
+	verifyStatusOK        = "ok"
+	verifyStatusMissing   = "missing"
+	verifyStatusCorrupt   = "corrupt"
+	verifyStatusInvalid   = "invalid"
+	verifyStatusUnchecked = "unchecked"
+)
+
+var backupVerificationHeader = colinfo.ResultColumns{
+	{Name: "backup_path", Typ: types.String},
+	{Name: "path", Typ: types.String},
+	{Name: "size_bytes", Typ: types.Int},
+	{Name: "status", Typ: types.String},
+	{Name: "detail", Typ: types.String},
+}
+
+// backupVerifier accumulates the rows of a SHOW BACKUP ... WITH verify.
+type backupVerifier struct {
+	codec         keys.SQLCodec
+	user          security.SQLUsername
+	store         cloud.ExternalStorage
+	encryptionKey []byte
+
+	// layerDirs are the directories of the store in which the backups of
+	// manifests are, which is the root of the store for the full backup.
+	layerDirs []string
+	manifests []BackupManifest
+
+	rows []tree.Datums
+}
+
+// verifyBackup returns the rows of a SHOW BACKUP ... WITH verify of the
+// backups of manifests, the files of which are in layerDirs of store.
+func verifyBackup(
+	ctx context.Context,
+	codec keys.SQLCodec,
+	user security.SQLUsername,
+	store cloud.ExternalStorage,
+	layerDirs []string,
+	manifests []BackupManifest,
+	encryption *jobspb.BackupEncryptionOptions,
+) ([]tree.Datums, error) {
+	v := &backupVerifier{
+		codec:     codec,
+		user:      user,
+		store:     store,
+		layerDirs: layerDirs,
+		manifests: manifests,
+	}
+	if encryption != nil {
+		var err error
+		v.encryptionKey, err = getEncryptionKey(ctx, encryption, store.Settings(), store.ExternalIOConf())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	v.verifyChain()
+	v.verifyDescriptorCoverage()
+	for i := range manifests {
+		for _, file := range manifests[i].Files {
+			if err := v.verifyFile(ctx, i, file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v.rows, nil
+}
+
+func (v *backupVerifier) report(layer int, file string, size tree.Datum, status, detail string) {
+	v.rows = append(v.rows, tree.Datums{
+		tree.NewDString("/" + v.layerDirs[layer]),
+		nullIfEmpty(file),
+		size,
+		tree.NewDString(status),
+		nullIfEmpty(detail),
+	})
+}
+
+// verifyChain checks that the backups start with a full backup, that each
+// incremental backup starts when the previous backup ends, and that together
+// they cover the spans of the last backup until its end time.
+func (v *backupVerifier) verifyChain() {
+	valid := true
+	if start := v.manifests[0].StartTime; !start.IsEmpty() {
+		v.report(0, "", tree.DNull, verifyStatusInvalid, fmt.Sprintf(
+			"backup is incremental from %s, and cannot be restored without the backups it is incremental from",
+			start))
+		valid = false
+	}
+	for i := 1; i < len(v.manifests); i++ {
+		if start, prevEnd := v.manifests[i].StartTime, v.manifests[i-1].EndTime; start != prevEnd {
+			v.report(i, "", tree.DNull, verifyStatusInvalid, fmt.Sprintf(
+				"backup starts at %s but the previous backup ends at %s", start, prevEnd))
+			valid = false
+		}
+	}
+	if !valid {
+		return
+	}
+	last := len(v.manifests) - 1
+	if _, _, err := makeImportSpans(
+		v.manifests[last].Spans, v.manifests, nil /* backupLocalityInfo */, keys.MinKey, v.user,
+		func(span covering.Range, start, end hlc.Timestamp) error {
+			v.report(last, "", tree.DNull, verifyStatusInvalid, fmt.Sprintf(
+				"no backup covers time [%s,%s) for range [%s,%s)",
+				start, end, roachpb.Key(span.Start), roachpb.Key(span.End)))
+			return nil
+		},
+	); err != nil {
+		v.report(last, "", tree.DNull, verifyStatusInvalid, err.Error())
+	}
+}
+
+// verifyDescriptorCoverage checks that the spans of the last backup cover the
+// indexes of the tables it backs up.
+func (v *backupVerifier) verifyDescriptorCoverage() {
+	last := len(v.manifests) - 1
+	spans, _ := roachpb.MergeSpans(append([]roachpb.Span(nil), v.manifests[last].Spans...))
+	covered := func(sp roachpb.Span) bool {
+		i := sort.Search(len(spans), func(i int) bool {
+			return sp.Key.Compare(spans[i].EndKey) < 0
+		})
+		return i < len(spans) && spans[i].Contains(sp)
+	}
+	for i := range v.manifests[last].Descriptors {
+		raw := descpb.TableFromDescriptor(&v.manifests[last].Descriptors[i], hlc.Timestamp{})
+		if raw == nil || raw.State == descpb.DescriptorState_DROP {
+			continue
+		}
+		table := tabledesc.NewImmutable(*raw)
+		_ = table.ForeachNonDropIndex(func(idx *descpb.IndexDescriptor) error {
+			if !covered(table.IndexSpan(v.codec, idx.ID)) {
+				v.report(last, "", tree.DNull, verifyStatusInvalid, fmt.Sprintf(
+					"index %q of table %q is not covered by the spans of the backup",
+					idx.Name, table.GetName()))
+			}
+			return nil
+		})
+	}
+}
+
+// verifyFile checks that a file of a backup exists, that it matches its
+// checksum and that it is an SST of keys in its span.
+func (v *backupVerifier) verifyFile(ctx context.Context, layer int, file BackupManifest_File) error {
+	if file.Path == "" {
+		return nil
+	}
+	if file.LocalityKV != "" {
+		v.report(layer, file.Path, tree.DNull, verifyStatusUnchecked,
+			fmt.Sprintf("file is stored in the backup for locality %s", file.LocalityKV))
+		return nil
+	}
+
+	r, err := v.store.ReadFile(ctx, path.Join(v.layerDirs[layer], file.Path))
+	if err != nil {
+		if errors.Is(err, cloudimpl.ErrFileDoesNotExist) {
+			v.report(layer, file.Path, tree.DNull, verifyStatusMissing, "")
+			return nil
+		}
+		return err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", file.Path)
+	}
+	size := tree.NewDInt(tree.DInt(len(data)))
+
+	if v.encryptionKey != nil {
+		if data, err = storageccl.DecryptFile(data, v.encryptionKey); err != nil {
+			v.report(layer, file.Path, size, verifyStatusCorrupt, err.Error())
+			return nil
+		}
+	}
+	if len(file.Sha512) > 0 {
+		checksum, err := storageccl.SHA512ChecksumData(data)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(checksum, file.Sha512) {
+			v.report(layer, file.Path, size, verifyStatusCorrupt, "checksum mismatch")
+			return nil
+		}
+	}
+	if err := verifyBackupSST(data, file.Span, v.manifests[layer].EndTime); err != nil {
+		v.report(layer, file.Path, size, verifyStatusCorrupt, err.Error())
+		return nil
+	}
+	v.report(layer, file.Path, size, verifyStatusOK, "")
+	return nil
+}
+
+// verifyBackupSST checks that data is an SST whose keys are in span and were
+// written at or before endTime.
+func verifyBackupSST(data []byte, span roachpb.Span, endTime hlc.Timestamp) error {
+	iter, err := storage.NewMemSSTIterator(data, true /* verify */)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.SeekGE(storage.MVCCKey{Key: keys.MinKey}); ; iter.Next() {
+		ok, err := iter.Valid()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		key := iter.UnsafeKey()
+		if !span.ContainsKey(key.Key) {
+			return errors.Errorf("key %s is outside of the span %s of the file", key.Key, span)
+		}
+		if endTime.Less(key.Timestamp) {
+			return errors.Errorf("key %s is newer than the end time %s of the backup", key, endTime)
+		}
+	}
+}
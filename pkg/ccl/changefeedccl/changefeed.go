@@ -53,7 +53,8 @@ type emitEntry struct {
 
 // kvsToRows gets changed kvs from a closure and converts them into sql rows. It
 // returns a closure that may be repeatedly called to advance the changefeed.
-// The returned closure is not threadsafe.
+// The returned closure is not threadsafe. If filter is non-nil, rows not
+// matching it are dropped and the rest are projected by it.
 func kvsToRows(
 	ctx context.Context,
 	codec keys.SQLCodec,
@@ -62,9 +63,13 @@ func kvsToRows(
 	leaseMgr *lease.Manager,
 	hydratedTables *hydratedtables.Cache,
 	details jobspb.ChangefeedDetails,
+	filter *rowFilter,
 	inputFn func(context.Context) (kvfeed.Event, error),
 ) func(context.Context) ([]emitEntry, error) {
 	_, withDiff := details.Opts[changefeedbase.OptDiff]
+	// The previous value of changed rows is decoded for the filter even if it
+	// isn't emitted.
+	withPrev := withDiff || filter.needsPrev()
 	rfCache := newRowFetcherCache(ctx, codec, settings, leaseMgr, hydratedTables, db)
 
 	var kvs row.SpanKVFetcher
@@ -131,7 +136,7 @@ func kvsToRows(
 		}
 
 		// Get prev value, if necessary.
-		if withDiff {
+		if withPrev {
 			prevRF := rf
 			if prevSchemaTimestamp != schemaTimestamp {
 				// If the previous value is being interpreted under a different
@@ -178,6 +183,19 @@ func kvsToRows(
 			}
 		}
 
+		if filter != nil {
+			var matches bool
+			if r.row, matches, err = filter.apply(ctx, r.row); err != nil {
+				return nil, err
+			}
+			if !matches {
+				return output, nil
+			}
+		}
+		if !withDiff {
+			r.row.prevDatums, r.row.prevTableDesc, r.row.prevDeleted = nil, nil, false
+		}
+
 		output = append(output, r)
 		return output, nil
 	}
@@ -235,6 +253,7 @@ func emitEntries(
 	cursor hlc.Timestamp,
 	sf *span.Frontier,
 	encoder Encoder,
+	namer *nameTemplate,
 	sink Sink,
 	inputFn func(context.Context) ([]emitEntry, error),
 	knobs TestingKnobs,
@@ -271,8 +290,14 @@ func emitEntries(
 				return err
 			}
 		}
+		topic := topicDescriptor{table: row.tableDesc}
+		if namer != nil {
+			if topic.name, err = namer.expand(details.Targets[row.tableDesc.GetID()], row); err != nil {
+				return err
+			}
+		}
 		if err := sink.EmitRow(
-			ctx, row.tableDesc, keyCopy, valueCopy, row.updated,
+			ctx, topic, keyCopy, valueCopy, row.updated,
 		); err != nil {
 			return err
 		}
@@ -311,6 +336,12 @@ func emitEntries(
 			}
 			if input.resolved != nil {
 				boundaryReached = boundaryReached || input.resolved.BoundaryReached
+				// Nothing follows the resolved spans at the end time, so they
+				// are flushed like a boundary rather than waiting for more input.
+				if endTime := details.EndTime; !endTime.IsEmpty() &&
+					endTime.LessEq(input.resolved.Timestamp.Next()) {
+					boundaryReached = true
+				}
 				_ = sf.Forward(input.resolved.Span, input.resolved.Timestamp)
 				resolvedSpans = append(resolvedSpans, *input.resolved)
 			}
@@ -428,5 +459,6 @@ func initialScanFromOptions(opts map[string]string) bool {
 	_, cursor := opts[changefeedbase.OptCursor]
 	_, initialScan := opts[changefeedbase.OptInitialScan]
 	_, noInitialScan := opts[changefeedbase.OptNoInitialScan]
-	return (cursor && initialScan) || (!cursor && !noInitialScan)
+	_, initialScanOnly := opts[changefeedbase.OptInitialScanOnly]
+	return (cursor && (initialScan || initialScanOnly)) || (!cursor && !noInitialScan)
 }
@@ -92,11 +92,15 @@ func distChangefeedFlow(
 
 	spansTS := details.StatementTime
 	var initialHighWater hlc.Timestamp
+	var initialScanCheckpoint []roachpb.Span
 	if h := progress.GetHighWater(); h != nil && !h.IsEmpty() {
 		initialHighWater = *h
 		// If we have a high-water set, use it to compute the spans, since the
 		// ones at the statement time may have been garbage collected by now.
 		spansTS = initialHighWater
+	} else if cp := progress.GetChangefeed().Checkpoint; cp != nil {
+		// The initial scan was interrupted; resume it from where it left off.
+		initialScanCheckpoint = cp.Spans
 	}
 
 	execCfg := execCtx.ExecCfg()
@@ -141,9 +145,10 @@ func distChangefeedFlow(
 
 		corePlacement[i].NodeID = sp.Node
 		corePlacement[i].Core.ChangeAggregator = &execinfrapb.ChangeAggregatorSpec{
-			Watches:   watches,
-			Feed:      details,
-			UserProto: execCtx.User().EncodeProto(),
+			Watches:               watches,
+			Feed:                  details,
+			UserProto:             execCtx.User().EncodeProto(),
+			InitialScanCheckpoint: initialScanCheckpoint,
 		}
 	}
 	// NB: This SpanFrontier processor depends on the set of tracked spans being
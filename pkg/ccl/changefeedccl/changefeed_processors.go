@@ -57,9 +57,20 @@ type changeAggregator struct {
 
 	// encoder is the Encoder to use for key and value serialization.
 	encoder Encoder
+	// filter, if non-nil, drops and projects rows according to the SELECT
+	// clause of the changefeed before they're encoded.
+	filter *rowFilter
+	// namer, if non-nil, names the topic or path of each row according to the
+	// topic_template or path_template of the changefeed.
+	namer *nameTemplate
 	// sink is the Sink to write rows to. Resolved timestamps are never written
 	// by changeAggregator.
 	sink Sink
+	// metrics are monitoring counters shared between all changefeeds.
+	metrics *Metrics
+	// scoped are the metrics of this changefeed's metrics_label. They are
+	// released when the processor is closed.
+	scoped *scopedMetrics
 	// tickFn is the workhorse behind Next(). It pulls kv changes from the
 	// buffer that poller fills, handles table leasing, converts them to rows,
 	// and writes them to the sink.
@@ -141,6 +152,14 @@ func newChangeAggregatorProcessor(
 	if ca.encoder, err = getEncoder(ca.spec.Feed.Opts); err != nil {
 		return nil, err
 	}
+	// The filter gets its own copy of the EvalContext since it pushes
+	// IndexedVar containers onto it.
+	if ca.filter, err = makeRowFilter(flowCtx.NewEvalCtx(), ca.spec.Feed.Select); err != nil {
+		return nil, err
+	}
+	if ca.namer, err = nameTemplateFromOptions(ca.spec.Feed.Opts); err != nil {
+		return nil, err
+	}
 
 	return ca, nil
 }
@@ -181,7 +200,20 @@ func (ca *changeAggregator) Start(ctx context.Context) context.Context {
 	// runs. They're all stored as the `metric.Struct` interface because of
 	// dependency cycles.
 	metrics := ca.flowCtx.Cfg.JobRegistry.MetricsStruct().Changefeed.(*Metrics)
-	ca.sink = makeMetricsSink(metrics, ca.sink)
+	scoped, err := metrics.getOrCreateScope(
+		&ca.flowCtx.Cfg.Settings.SV, ca.spec.Feed.Opts[changefeedbase.OptMetricsLabel])
+	if err != nil {
+		// The sink was already created, so close it before draining.
+		if closeErr := ca.sink.Close(); closeErr != nil {
+			log.Warningf(ctx, `error closing sink. goroutines may have leaked: %v`, closeErr)
+		}
+		ca.sink = nil
+		ca.MoveToDraining(err)
+		ca.cancel()
+		return ctx
+	}
+	ca.metrics, ca.scoped = metrics, scoped
+	ca.sink = makeMetricsSink(metrics, scoped, ca.sink)
 	ca.sink = &errorWrapperSink{wrapped: ca.sink}
 
 	var knobs TestingKnobs
@@ -205,11 +237,12 @@ func (ca *changeAggregator) Start(ctx context.Context) context.Context {
 	leaseMgr := ca.flowCtx.Cfg.LeaseManager.(*lease.Manager)
 	_, withDiff := ca.spec.Feed.Opts[changefeedbase.OptDiff]
 	kvfeedCfg := makeKVFeedCfg(ca.flowCtx.Cfg, leaseMgr, ca.kvFeedMemMon, ca.spec,
-		spans, withDiff, buf, metrics)
+		spans, withDiff || ca.filter.needsPrev(), buf, metrics, scoped)
 	cfg := ca.flowCtx.Cfg
-	rowsFn := kvsToRows(ctx, cfg.Codec, cfg.Settings, cfg.DB, leaseMgr, cfg.HydratedTables, ca.spec.Feed, buf.Get)
+	rowsFn := kvsToRows(ctx, cfg.Codec, cfg.Settings, cfg.DB, leaseMgr, cfg.HydratedTables,
+		ca.spec.Feed, ca.filter, buf.Get)
 	ca.tickFn = emitEntries(ca.flowCtx.Cfg.Settings, ca.spec.Feed,
-		kvfeedCfg.InitialHighWater, sf, ca.encoder, ca.sink, rowsFn, knobs, metrics)
+		kvfeedCfg.InitialHighWater, sf, ca.encoder, ca.namer, ca.sink, rowsFn, knobs, metrics)
 	ca.startKVFeed(ctx, kvfeedCfg)
 
 	return ctx
@@ -245,6 +278,7 @@ func makeKVFeedCfg(
 	withDiff bool,
 	buf kvfeed.EventBuffer,
 	metrics *Metrics,
+	scoped *scopedMetrics,
 ) kvfeed.Config {
 	schemaChangeEvents := changefeedbase.SchemaChangeEventClass(
 		spec.Feed.Opts[changefeedbase.OptSchemaChangeEvents])
@@ -252,22 +286,26 @@ func makeKVFeedCfg(
 		spec.Feed.Opts[changefeedbase.OptSchemaChangePolicy])
 	initialHighWater, needsInitialScan := getKVFeedInitialParameters(spec)
 	kvfeedCfg := kvfeed.Config{
-		Sink:               buf,
-		Settings:           cfg.Settings,
-		DB:                 cfg.DB,
-		Codec:              cfg.Codec,
-		Clock:              cfg.DB.Clock(),
-		Gossip:             cfg.Gossip,
-		Spans:              spans,
-		Targets:            spec.Feed.Targets,
-		LeaseMgr:           leaseMgr,
-		Metrics:            &metrics.KVFeedMetrics,
-		MM:                 mm,
-		InitialHighWater:   initialHighWater,
-		WithDiff:           withDiff,
-		NeedsInitialScan:   needsInitialScan,
-		SchemaChangeEvents: schemaChangeEvents,
-		SchemaChangePolicy: schemaChangePolicy,
+		Sink:                  buf,
+		Settings:              cfg.Settings,
+		DB:                    cfg.DB,
+		Codec:                 cfg.Codec,
+		Clock:                 cfg.DB.Clock(),
+		Gossip:                cfg.Gossip,
+		Spans:                 spans,
+		Targets:               spec.Feed.Targets,
+		LeaseMgr:              leaseMgr,
+		Metrics:               &metrics.KVFeedMetrics,
+		MM:                    mm,
+		InitialHighWater:      initialHighWater,
+		EndTime:               spec.Feed.EndTime,
+		InitialScanCheckpoint: spec.InitialScanCheckpoint,
+		WithDiff:              withDiff,
+		NeedsInitialScan:      needsInitialScan,
+		SchemaChangeEvents:    schemaChangeEvents,
+		SchemaChangePolicy:    schemaChangePolicy,
+
+		BackfillPendingRanges: scoped.BackfillPendingRanges,
 	}
 	return kvfeedCfg
 }
@@ -337,6 +375,10 @@ func (ca *changeAggregator) close() {
 				log.Warningf(ca.Ctx, `error closing sink. goroutines may have leaked: %v`, err)
 			}
 		}
+		if ca.scoped != nil {
+			ca.metrics.releaseScope(ca.scoped)
+			ca.scoped = nil
+		}
 		ca.memAcc.Close(ca.Ctx)
 		if ca.kvFeedMemMon != nil {
 			ca.kvFeedMemMon.Stop(ca.Ctx)
@@ -431,6 +473,9 @@ type changeFrontier struct {
 	lastEmitResolved time.Time
 	// lastSlowSpanLog is the last time a slow span from `sf` was logged.
 	lastSlowSpanLog time.Time
+	// lastInitialScanCheckpoint is the last time the spans completed by the
+	// initial scan were checkpointed to the job progress.
+	lastInitialScanCheckpoint time.Time
 
 	// schemaChangeBoundary represents an hlc timestamp at which a schema change
 	// event occurred to a target watched by this frontier. If the changefeed is
@@ -464,6 +509,9 @@ type changeFrontier struct {
 	resolvedBuf *encDatumRowBuffer
 	// metrics are monitoring counters shared between all changefeeds.
 	metrics *Metrics
+	// scoped are the metrics of this changefeed's metrics_label. They are
+	// released by closeMetrics.
+	scoped *scopedMetrics
 	// metricsID is used as the unique id of this changefeed in the
 	// metrics.MaxBehindNanos map.
 	metricsID int
@@ -561,7 +609,19 @@ func (cf *changeFrontier) Start(ctx context.Context) context.Context {
 	// runs. They're all stored as the `metric.Struct` interface because of
 	// dependency cycles.
 	cf.metrics = cf.flowCtx.Cfg.JobRegistry.MetricsStruct().Changefeed.(*Metrics)
-	cf.sink = makeMetricsSink(cf.metrics, cf.sink)
+	scoped, err := cf.metrics.getOrCreateScope(
+		&cf.flowCtx.Cfg.Settings.SV, cf.spec.Feed.Opts[changefeedbase.OptMetricsLabel])
+	if err != nil {
+		// The sink was already created, so close it before draining.
+		if closeErr := cf.sink.Close(); closeErr != nil {
+			log.Warningf(ctx, `error closing sink. goroutines may have leaked: %v`, closeErr)
+		}
+		cf.sink = nil
+		cf.MoveToDraining(err)
+		return ctx
+	}
+	cf.scoped = scoped
+	cf.sink = makeMetricsSink(cf.metrics, scoped, cf.sink)
 	cf.sink = &errorWrapperSink{wrapped: cf.sink}
 
 	cf.highWaterAtStart = cf.spec.Feed.StatementTime
@@ -615,7 +675,8 @@ func (cf *changeFrontier) close() {
 }
 
 // closeMetrics de-registers from the progress registry that powers
-// `changefeed.max_behind_nanos`. This method is idempotent.
+// `changefeed.max_behind_nanos` and releases the metrics scope. This method is
+// idempotent.
 func (cf *changeFrontier) closeMetrics() {
 	// Delete this feed from the MaxBehindNanos metric so it's no longer
 	// considered by the gauge.
@@ -625,7 +686,13 @@ func (cf *changeFrontier) closeMetrics() {
 	}
 	delete(cf.metrics.mu.resolved, cf.metricsID)
 	cf.metricsID = -1
+	scoped := cf.scoped
+	cf.scoped = nil
 	cf.metrics.mu.Unlock()
+
+	if scoped != nil {
+		cf.metrics.releaseScope(scoped)
+	}
 }
 
 // schemaChangeBoundaryReached returns true if the spanFrontier is at the
@@ -634,6 +701,13 @@ func (cf *changeFrontier) schemaChangeBoundaryReached() (r bool) {
 	return !cf.schemaChangeBoundary.IsEmpty() && cf.schemaChangeBoundary.Equal(cf.sf.Frontier())
 }
 
+// endTimeReached returns true if the changefeed has an end time and the
+// spanFrontier has resolved everything before it.
+func (cf *changeFrontier) endTimeReached() bool {
+	endTime := cf.spec.Feed.EndTime
+	return !endTime.IsEmpty() && endTime.LessEq(cf.sf.Frontier().Next())
+}
+
 // shouldFailOnSchemaChange checks the job's spec to determine whether it should
 // failed on schema change events after all spans have been resolved.
 func (cf *changeFrontier) shouldFailOnSchemaChange() bool {
@@ -665,6 +739,13 @@ func (cf *changeFrontier) Next() (rowenc.EncDatumRow, *execinfrapb.ProducerMetad
 			break
 		}
 
+		if cf.endTimeReached() {
+			// Everything before the end time has been emitted, so the changefeed
+			// is done. Draining without an error completes the job successfully.
+			cf.MoveToDraining(nil /* err */)
+			break
+		}
+
 		row, meta := cf.input.Next()
 		if meta != nil {
 			if meta.Err != nil {
@@ -738,8 +819,9 @@ func (cf *changeFrontier) noteResolvedSpan(d rowenc.EncDatum) error {
 		if err := cf.handleFrontierChanged(isBehind); err != nil {
 			return err
 		}
+		return nil
 	}
-	return nil
+	return cf.maybeCheckpointInitialScan()
 }
 
 func (cf *changeFrontier) handleFrontierChanged(isBehind bool) error {
@@ -779,10 +861,66 @@ func (cf *changeFrontier) checkpointResolvedTimestamp(
 		if err := cf.manageProtectedTimestamps(ctx, progress, txn, resolved, isBehind); err != nil {
 			return hlc.Timestamp{}, err
 		}
+		// Once a resolved timestamp is checkpointed, the initial scan is
+		// complete and there is no need to keep track of its completed spans.
+		progress.Checkpoint = nil
 		return resolved, nil
 	})
 }
 
+// maybeCheckpointInitialScan periodically records the spans which have been
+// completely scanned by the initial scan in the job progress, so that they are
+// not scanned again if the changefeed is restarted before the scan finishes.
+func (cf *changeFrontier) maybeCheckpointInitialScan() error {
+	// Sinkless changefeeds cannot be restarted, so there is no point in
+	// checkpointing them. Once the frontier has advanced, the initial scan is
+	// complete and the high-water is checkpointed instead.
+	if cf.jobProgressedFn == nil || !cf.sf.Frontier().IsEmpty() {
+		return nil
+	}
+	sv := &cf.flowCtx.Cfg.Settings.SV
+	freq := changefeedbase.FrontierCheckpointFrequency.Get(sv)
+	if freq == 0 || timeutil.Since(cf.lastInitialScanCheckpoint) < freq {
+		return nil
+	}
+	cf.lastInitialScanCheckpoint = timeutil.Now()
+
+	spans := getCheckpointSpans(cf.sf, changefeedbase.FrontierCheckpointMaxBytes.Get(sv))
+	if len(spans) == 0 {
+		return nil
+	}
+	return cf.jobProgressedFn(cf.Ctx, func(
+		ctx context.Context, txn *kv.Txn, details jobspb.ProgressDetails,
+	) (hlc.Timestamp, error) {
+		progress := details.(*jobspb.Progress_Changefeed).Changefeed
+		progress.Checkpoint = &jobspb.ChangefeedProgress_Checkpoint{Spans: spans}
+		// The high-water is not advanced until the initial scan completes.
+		return hlc.Timestamp{}, nil
+	})
+}
+
+// getCheckpointSpans returns the merged spans of the frontier which have been
+// resolved. Spans are returned in key order until their total key size exceeds
+// maxBytes.
+func getCheckpointSpans(sf *span.Frontier, maxBytes int64) []roachpb.Span {
+	var resolved []roachpb.Span
+	sf.Entries(func(s roachpb.Span, ts hlc.Timestamp) {
+		if !ts.IsEmpty() {
+			resolved = append(resolved, s)
+		}
+	})
+	resolved, _ = roachpb.MergeSpans(resolved)
+
+	var used int64
+	for i, s := range resolved {
+		used += int64(len(s.Key) + len(s.EndKey))
+		if used > maxBytes {
+			return resolved[:i]
+		}
+	}
+	return resolved
+}
+
 // manageProtectedTimestamps is called when the resolved timestamp is being
 // checkpointed. The changeFrontier always checkpoints resolved timestamps
 // which occur at scan boundaries. It releases previously protected timestamps
@@ -861,7 +999,8 @@ func (cf *changeFrontier) maybeEmitResolved(newResolved hlc.Timestamp) error {
 		return nil
 	}
 	sinceEmitted := newResolved.GoTime().Sub(cf.lastEmitResolved)
-	shouldEmit := sinceEmitted >= cf.freqEmitResolved || cf.schemaChangeBoundaryReached()
+	shouldEmit := sinceEmitted >= cf.freqEmitResolved || cf.schemaChangeBoundaryReached() ||
+		cf.endTimeReached()
 	if !shouldEmit {
 		return nil
 	}
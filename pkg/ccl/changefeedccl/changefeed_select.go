@@ -0,0 +1,376 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemaexpr"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/errors"
+)
+
+// validateChangefeedSelect checks that the SELECT clause of a
+// CREATE CHANGEFEED ... AS SELECT statement can be evaluated against the
+// target table. It returns the clause in the form that is stored in the job
+// details: with the WHERE clause dequalified and type checked.
+func validateChangefeedSelect(
+	ctx context.Context,
+	semaCtx *tree.SemaContext,
+	tableDesc catalog.TableDescriptor,
+	sel *tree.SelectClause,
+) (string, error) {
+	if len(sel.From.Tables) != 1 {
+		return ``, errors.Errorf(`CHANGEFEED SELECT must reference exactly one table`)
+	}
+	tn, ok := sel.From.Tables[0].(*tree.TableName)
+	if !ok {
+		return ``, errors.Errorf(`CHANGEFEED cannot select from %s`, tree.AsString(sel.From.Tables[0]))
+	}
+	if _, _, err := projectColumns(tableDesc, sel.Exprs); err != nil {
+		return ``, err
+	}
+
+	validated := *sel
+	if sel.Where != nil {
+		where, _, err := schemaexpr.DequalifyAndValidateExpr(
+			ctx, tableDesc, sel.Where.Expr, types.Bool, `CHANGEFEED WHERE`, semaCtx,
+			tree.VolatilityImmutable, tn,
+		)
+		if err != nil {
+			return ``, err
+		}
+		whereExpr, err := parser.ParseExpr(where)
+		if err != nil {
+			return ``, err
+		}
+		validated.Where = tree.NewWhere(tree.AstWhere, whereExpr)
+	}
+	return tree.AsStringWithFlags(&validated, tree.FmtParsable), nil
+}
+
+// parseChangefeedSelect parses the SELECT clause stored in the job details by
+// validateChangefeedSelect.
+func parseChangefeedSelect(sel string) (*tree.SelectClause, error) {
+	stmt, err := parser.ParseOne(sel)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := stmt.AST.(*tree.Select); ok {
+		if clause, ok := s.Select.(*tree.SelectClause); ok {
+			return clause, nil
+		}
+	}
+	return nil, errors.AssertionFailedf(`unexpected changefeed SELECT: %s`, sel)
+}
+
+// projectColumns returns the ordinals of the public columns of tableDesc
+// named by the given select expressions, along with the names they should be
+// emitted under. Only column references (optionally renamed) and `*` are
+// supported.
+func projectColumns(
+	tableDesc catalog.TableDescriptor, exprs tree.SelectExprs,
+) (colIdxs []int, names []string, _ error) {
+	columns := tableDesc.GetPublicColumns()
+	colIdxByID := tableDesc.ColumnIdxMap()
+	var seen util.FastIntSet
+	project := func(idx int, name string) error {
+		if seen.Contains(idx) {
+			return pgerror.Newf(pgcode.DuplicateColumn,
+				`column %q projected more than once`, columns[idx].Name)
+		}
+		seen.Add(idx)
+		colIdxs = append(colIdxs, idx)
+		names = append(names, name)
+		return nil
+	}
+	for _, expr := range exprs {
+		vBase, ok := expr.Expr.(tree.VarName)
+		if !ok {
+			return nil, nil, pgerror.Newf(pgcode.FeatureNotSupported,
+				`CHANGEFEED projections must be column references: %s`, tree.AsString(expr.Expr))
+		}
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch t := v.(type) {
+		case tree.UnqualifiedStar, *tree.AllColumnsSelector:
+			if expr.As != `` {
+				return nil, nil, pgerror.Newf(pgcode.Syntax, `"*" cannot be aliased`)
+			}
+			for i := range columns {
+				if err := project(i, columns[i].Name); err != nil {
+					return nil, nil, err
+				}
+			}
+		case *tree.ColumnItem:
+			col, dropped, err := tableDesc.FindColumnByName(t.ColumnName)
+			if err != nil || dropped {
+				return nil, nil, pgerror.Newf(pgcode.UndefinedColumn,
+					`column %q does not exist`, t.ColumnName)
+			}
+			idx, ok := colIdxByID.Get(col.ID)
+			if !ok {
+				return nil, nil, pgerror.Newf(pgcode.UndefinedColumn,
+					`column %q does not exist`, t.ColumnName)
+			}
+			name := col.Name
+			if expr.As != `` {
+				name = string(expr.As)
+			}
+			if err := project(idx, name); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, pgerror.Newf(pgcode.FeatureNotSupported,
+				`CHANGEFEED projections must be column references: %s`, tree.AsString(expr.Expr))
+		}
+	}
+	return colIdxs, names, nil
+}
+
+// rowFilter applies the projection and WHERE clause of a
+// CREATE CHANGEFEED ... AS SELECT statement to decoded rows before they're
+// handed to the encoder. The clause is resolved separately for every version
+// of the table descriptor that rows are read with, so that schema changes to
+// columns the clause doesn't reference are transparent to it.
+type rowFilter struct {
+	evalCtx *tree.EvalContext
+	sel     *tree.SelectClause
+	cache   map[idVersion]*resolvedRowFilter
+	alloc   rowenc.DatumAlloc
+}
+
+// resolvedRowFilter is a rowFilter bound to one version of a table.
+type resolvedRowFilter struct {
+	// projectedDesc is a copy of the table descriptor with only the projected
+	// columns. The primary key columns are always included since they're
+	// needed to encode the key of each message.
+	projectedDesc catalog.TableDescriptor
+	// colIdxs maps each column of projectedDesc to its ordinal in the rows
+	// decoded with the full table descriptor.
+	colIdxs []int
+	// where is nil if the clause has no WHERE.
+	where tree.TypedExpr
+	// ivars evaluates the IndexedVars in where against the current row.
+	ivars rowFilterIVarContainer
+}
+
+// makeRowFilter returns a rowFilter for the given SELECT clause, which is in
+// the form returned by validateChangefeedSelect. A nil rowFilter is returned
+// if the clause is empty.
+func makeRowFilter(evalCtx *tree.EvalContext, sel string) (*rowFilter, error) {
+	if sel == `` {
+		return nil, nil
+	}
+	clause, err := parseChangefeedSelect(sel)
+	if err != nil {
+		return nil, err
+	}
+	return &rowFilter{
+		evalCtx: evalCtx,
+		sel:     clause,
+		cache:   make(map[idVersion]*resolvedRowFilter),
+	}, nil
+}
+
+func (f *rowFilter) forDesc(
+	ctx context.Context, tableDesc catalog.TableDescriptor,
+) (*resolvedRowFilter, error) {
+	key := idVersion{id: tableDesc.GetID(), version: tableDesc.GetVersion()}
+	if rf, ok := f.cache[key]; ok {
+		return rf, nil
+	}
+
+	colIdxs, names, err := projectColumns(tableDesc, f.sel.Exprs)
+	if err != nil {
+		return nil, errors.Wrapf(err, `table %s`, tableDesc.GetName())
+	}
+	columns := tableDesc.GetPublicColumns()
+	projected := make([]descpb.ColumnDescriptor, 0, len(colIdxs))
+	var projectedIDs catalog.TableColSet
+	for i, idx := range colIdxs {
+		projectedIDs.Add(columns[idx].ID)
+		col := columns[idx]
+		col.Name = names[i]
+		projected = append(projected, col)
+	}
+	colIdxByID := tableDesc.ColumnIdxMap()
+	for _, colID := range tableDesc.GetPrimaryIndex().ColumnIDs {
+		if projectedIDs.Contains(colID) {
+			continue
+		}
+		idx, ok := colIdxByID.Get(colID)
+		if !ok {
+			return nil, errors.Errorf(`unknown column id: %d`, colID)
+		}
+		colIdxs = append(colIdxs, idx)
+		projected = append(projected, columns[idx])
+	}
+	desc := *tableDesc.TableDesc()
+	desc.Columns = projected
+	rf := &resolvedRowFilter{
+		projectedDesc: tabledesc.NewImmutable(desc),
+		colIdxs:       colIdxs,
+		ivars:         rowFilterIVarContainer{columns: columns, alloc: &f.alloc},
+	}
+
+	if f.sel.Where != nil {
+		ivarHelper := tree.MakeIndexedVarHelper(&rf.ivars, len(columns))
+		expr, err := tree.SimpleVisit(f.sel.Where.Expr, func(expr tree.Expr) (bool, tree.Expr, error) {
+			vBase, ok := expr.(tree.VarName)
+			if !ok {
+				return true, expr, nil
+			}
+			v, err := vBase.NormalizeVarName()
+			if err != nil {
+				return false, nil, err
+			}
+			c, ok := v.(*tree.ColumnItem)
+			if !ok {
+				return true, expr, nil
+			}
+			col, dropped, err := tableDesc.FindColumnByName(c.ColumnName)
+			if err != nil || dropped {
+				return false, nil, pgerror.Newf(pgcode.UndefinedColumn,
+					`column %q referenced by CHANGEFEED WHERE clause does not exist in table %s`,
+					c.ColumnName, tableDesc.GetName())
+			}
+			idx, ok := colIdxByID.Get(col.ID)
+			if !ok {
+				return false, nil, pgerror.Newf(pgcode.UndefinedColumn,
+					`column %q referenced by CHANGEFEED WHERE clause is not public in table %s`,
+					c.ColumnName, tableDesc.GetName())
+			}
+			return false, ivarHelper.IndexedVar(idx), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		semaCtx := tree.MakeSemaContext()
+		semaCtx.IVarContainer = &rf.ivars
+		if rf.where, err = tree.TypeCheck(ctx, expr, &semaCtx, types.Bool); err != nil {
+			return nil, err
+		}
+	}
+
+	f.cache[key] = rf
+	return rf, nil
+}
+
+// needsPrev returns whether the previous value of changed rows is needed to
+// apply the filter, which is the case when it has a WHERE clause.
+func (f *rowFilter) needsPrev() bool {
+	return f != nil && f.sel.Where != nil
+}
+
+// apply returns the projection of the given row and whether it should be
+// emitted. Deletions always match, since only the primary key of a deleted row
+// is known. A row which no longer matches the WHERE clause is emitted as a
+// deletion if its previous value did, so that it isn't left behind downstream.
+func (f *rowFilter) apply(ctx context.Context, row encodeRow) (encodeRow, bool, error) {
+	rf, err := f.forDesc(ctx, row.tableDesc)
+	if err != nil {
+		return encodeRow{}, false, err
+	}
+	if !row.deleted {
+		pass, err := rf.matches(f.evalCtx, row.datums)
+		if err != nil {
+			return encodeRow{}, false, err
+		}
+		if !pass {
+			if row.prevDatums == nil || row.prevDeleted {
+				return encodeRow{}, false, nil
+			}
+			prev, err := f.forDesc(ctx, row.prevTableDesc)
+			if err != nil {
+				return encodeRow{}, false, err
+			}
+			if pass, err = prev.matches(f.evalCtx, row.prevDatums); err != nil || !pass {
+				return encodeRow{}, false, err
+			}
+			row.deleted = true
+		}
+	}
+	row.datums = rf.project(row.datums)
+	row.tableDesc = rf.projectedDesc
+
+	if row.prevDatums != nil {
+		prev, err := f.forDesc(ctx, row.prevTableDesc)
+		if err != nil {
+			return encodeRow{}, false, err
+		}
+		row.prevDatums = prev.project(row.prevDatums)
+		row.prevTableDesc = prev.projectedDesc
+	}
+	return row, true, nil
+}
+
+// matches returns whether the given row, decoded with the table descriptor rf
+// was resolved for, matches the WHERE clause.
+func (rf *resolvedRowFilter) matches(
+	evalCtx *tree.EvalContext, datums rowenc.EncDatumRow,
+) (bool, error) {
+	if rf.where == nil {
+		return true, nil
+	}
+	rf.ivars.row = datums
+	evalCtx.PushIVarContainer(&rf.ivars)
+	defer evalCtx.PopIVarContainer()
+	return schemaexpr.RunFilter(rf.where, evalCtx)
+}
+
+func (rf *resolvedRowFilter) project(datums rowenc.EncDatumRow) rowenc.EncDatumRow {
+	projected := make(rowenc.EncDatumRow, len(rf.colIdxs))
+	for i, idx := range rf.colIdxs {
+		projected[i] = datums[idx]
+	}
+	return projected
+}
+
+// rowFilterIVarContainer resolves the IndexedVars of a CHANGEFEED WHERE clause
+// to the columns of the row currently being filtered.
+type rowFilterIVarContainer struct {
+	columns []descpb.ColumnDescriptor
+	row     rowenc.EncDatumRow
+	alloc   *rowenc.DatumAlloc
+}
+
+var _ tree.IndexedVarContainer = &rowFilterIVarContainer{}
+
+// IndexedVarEval implements the tree.IndexedVarContainer interface.
+func (c *rowFilterIVarContainer) IndexedVarEval(
+	idx int, _ *tree.EvalContext,
+) (tree.Datum, error) {
+	if err := c.row[idx].EnsureDecoded(c.columns[idx].Type, c.alloc); err != nil {
+		return nil, err
+	}
+	return c.row[idx].Datum, nil
+}
+
+// IndexedVarResolvedType implements the tree.IndexedVarContainer interface.
+func (c *rowFilterIVarContainer) IndexedVarResolvedType(idx int) *types.T {
+	return c.columns[idx].Type
+}
+
+// IndexedVarNodeFormatter implements the tree.IndexedVarContainer interface.
+func (c *rowFilterIVarContainer) IndexedVarNodeFormatter(idx int) tree.NodeFormatter {
+	n := tree.Name(c.columns[idx].Name)
+	return &n
+}
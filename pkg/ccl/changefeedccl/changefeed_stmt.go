@@ -166,6 +166,27 @@ func changefeedPlanHook(
 			}
 			statementTime = initialHighWater
 		}
+		var endTime hlc.Timestamp
+		if e, ok := opts[changefeedbase.OptEndTime]; ok {
+			// Unlike the cursor, the end time is allowed to be in the future so
+			// p.EvalAsOfTimestamp is not used.
+			asOf := tree.AsOfClause{Expr: tree.NewStrVal(e)}
+			var err error
+			if endTime, err = tree.EvalAsOfTimestamp(
+				ctx, asOf, p.SemaCtx(), &p.ExtendedEvalContext().EvalContext,
+			); err != nil {
+				return err
+			}
+			if endTime.LessEq(statementTime) {
+				return errors.Errorf(`%s must be after the start time of the changefeed (%s)`,
+					changefeedbase.OptEndTime, statementTime.AsOfSystemTime())
+			}
+		}
+		if _, ok := opts[changefeedbase.OptInitialScanOnly]; ok {
+			// The initial scan is performed at the statement time, so nothing
+			// after it is emitted.
+			endTime = statementTime.Next()
+		}
 
 		// For now, disallow targeting a database or wildcard table selection.
 		// Getting it right as tables enter and leave the set over time is
@@ -195,11 +216,22 @@ func changefeedPlanHook(
 				return err
 			}
 		}
+		// The parent databases and user-defined schemas of the tables are
+		// resolved along with them.
+		parentNames := map[descpb.ID]string{keys.PublicSchemaID: tree.PublicSchema}
+		for _, desc := range targetDescs {
+			switch desc.(type) {
+			case catalog.DatabaseDescriptor, catalog.SchemaDescriptor:
+				parentNames[desc.GetID()] = desc.GetName()
+			}
+		}
 		targets := make(jobspb.ChangefeedTargets, len(targetDescs))
 		for _, desc := range targetDescs {
 			if table, isTable := desc.(catalog.TableDescriptor); isTable {
 				targets[table.GetID()] = jobspb.ChangefeedTarget{
-					StatementTimeName: table.GetName(),
+					StatementTimeName:         table.GetName(),
+					StatementTimeDatabaseName: parentNames[table.GetParentID()],
+					StatementTimeSchemaName:   parentNames[table.GetParentSchemaID()],
 				}
 				if err := validateChangefeedTable(targets, table); err != nil {
 					return err
@@ -207,11 +239,28 @@ func changefeedPlanHook(
 			}
 		}
 
+		var sel string
+		if changefeedStmt.Select != nil {
+			// The grammar only allows a single table in the FROM clause, which is
+			// also the only target.
+			for _, desc := range targetDescs {
+				if table, isTable := desc.(catalog.TableDescriptor); isTable {
+					if sel, err = validateChangefeedSelect(
+						ctx, p.SemaCtx(), table, changefeedStmt.Select,
+					); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
 		details := jobspb.ChangefeedDetails{
 			Targets:       targets,
 			Opts:          opts,
 			SinkURI:       sinkURI,
 			StatementTime: statementTime,
+			Select:        sel,
+			EndTime:       endTime,
 		}
 		progress := jobspb.Progress{
 			Progress: &jobspb.Progress_HighWater{},
@@ -258,6 +307,21 @@ func changefeedPlanHook(
 		}
 		if isCloudStorageSink(parsedSink) {
 			details.Opts[changefeedbase.OptKeyInValue] = ``
+		} else if changefeedbase.FormatType(details.Opts[changefeedbase.OptFormat]) == changefeedbase.OptFormatParquet {
+			// Parquet rows are assembled into files by the cloud storage sink.
+			return errors.Errorf(`%s=%s is only supported with cloud storage sinks`,
+				changefeedbase.OptFormat, changefeedbase.OptFormatParquet)
+		}
+		if err := validateNameTemplate(parsedSink, details.Opts, targetDescs); err != nil {
+			return err
+		}
+		if label, ok := details.Opts[changefeedbase.OptMetricsLabel]; ok {
+			// Fail early if this changefeed would exceed the limit on the number
+			// of metrics scopes.
+			metrics := p.ExecCfg().JobRegistry.MetricsStruct().Changefeed.(*Metrics)
+			if err := metrics.checkScope(&p.ExecCfg().Settings.SV, label); err != nil {
+				return err
+			}
 		}
 
 		// Feature telemetry
@@ -410,12 +474,15 @@ func changefeedPlanHook(
 func changefeedJobDescription(
 	p sql.PlanHookState, changefeed *tree.CreateChangefeed, sinkURI string, opts map[string]string,
 ) (string, error) {
-	cleanedSinkURI, err := cloudimpl.SanitizeExternalStorageURI(sinkURI, []string{changefeedbase.SinkParamSASLPassword})
+	cleanedSinkURI, err := cloudimpl.SanitizeExternalStorageURI(sinkURI, []string{
+		changefeedbase.SinkParamSASLPassword, changefeedbase.SinkParamWebhookAuthHeader,
+	})
 	if err != nil {
 		return "", err
 	}
 	c := &tree.CreateChangefeed{
 		Targets: changefeed.Targets,
+		Select:  changefeed.Select,
 		SinkURI: tree.NewDString(cleanedSinkURI),
 	}
 	for k, v := range opts {
@@ -482,6 +549,24 @@ func validateDetails(details jobspb.ChangefeedDetails) (jobspb.ChangefeedDetails
 				`cannot specify both %s and %s`, changefeedbase.OptInitialScan,
 				changefeedbase.OptNoInitialScan)
 		}
+		if _, initialScanOnly := details.Opts[changefeedbase.OptInitialScanOnly]; initialScanOnly {
+			if noInitialScan {
+				return jobspb.ChangefeedDetails{}, errors.Errorf(
+					`cannot specify both %s and %s`, changefeedbase.OptInitialScanOnly,
+					changefeedbase.OptNoInitialScan)
+			}
+			if _, endTime := details.Opts[changefeedbase.OptEndTime]; endTime {
+				return jobspb.ChangefeedDetails{}, errors.Errorf(
+					`cannot specify both %s and %s`, changefeedbase.OptInitialScanOnly,
+					changefeedbase.OptEndTime)
+			}
+		}
+	}
+	{
+		const opt = changefeedbase.OptMetricsLabel
+		if v, ok := details.Opts[opt]; ok && v == `` {
+			return jobspb.ChangefeedDetails{}, errors.Errorf(`%s must not be empty`, opt)
+		}
 	}
 	{
 		const opt = changefeedbase.OptEnvelope
@@ -502,7 +587,7 @@ func validateDetails(details jobspb.ChangefeedDetails) (jobspb.ChangefeedDetails
 		switch v := changefeedbase.FormatType(details.Opts[opt]); v {
 		case ``, changefeedbase.OptFormatJSON:
 			details.Opts[opt] = string(changefeedbase.OptFormatJSON)
-		case changefeedbase.OptFormatAvro:
+		case changefeedbase.OptFormatAvro, changefeedbase.OptFormatProtobuf, changefeedbase.OptFormatParquet:
 			// No-op.
 		default:
 			return jobspb.ChangefeedDetails{}, errors.Errorf(
@@ -512,6 +597,40 @@ func validateDetails(details jobspb.ChangefeedDetails) (jobspb.ChangefeedDetails
 	return details, nil
 }
 
+// validateNameTemplate checks the topic_template or path_template of a
+// changefeed, if any, against its sink and the tables it watches.
+func validateNameTemplate(
+	sinkURI *url.URL, opts map[string]string, targetDescs []catalog.Descriptor,
+) error {
+	_, topicTemplate := opts[changefeedbase.OptTopicTemplate]
+	_, pathTemplate := opts[changefeedbase.OptPathTemplate]
+	if topicTemplate && pathTemplate {
+		return errors.Errorf(`cannot specify both %s and %s`,
+			changefeedbase.OptTopicTemplate, changefeedbase.OptPathTemplate)
+	}
+	// The experimental SQL sink mirrors the kafka sink for testing.
+	if topicTemplate && sinkURI.Scheme != changefeedbase.SinkSchemeKafka &&
+		sinkURI.Scheme != changefeedbase.SinkSchemeExperimentalSQL {
+		return errors.Errorf(`%s is only supported with kafka sinks`, changefeedbase.OptTopicTemplate)
+	}
+	if pathTemplate && !isCloudStorageSink(sinkURI) {
+		return errors.Errorf(`%s is only supported with cloud storage sinks`,
+			changefeedbase.OptPathTemplate)
+	}
+	tmpl, err := nameTemplateFromOptions(opts)
+	if err != nil || tmpl == nil {
+		return err
+	}
+	for _, desc := range targetDescs {
+		if table, isTable := desc.(catalog.TableDescriptor); isTable {
+			if err := tmpl.validate(table); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func validateChangefeedTable(
 	targets jobspb.ChangefeedTargets, tableDesc catalog.TableDescriptor,
 ) error {
@@ -719,13 +838,14 @@ func (b *changefeedResumer) OnPauseRequest(
 	}
 
 	resolved := progress.GetHighWater()
-	if resolved == nil {
+	if resolved == nil || resolved.IsEmpty() {
 		// This should only happen if the job was created in a version that did not
 		// use protected timestamps but has yet to checkpoint its high water.
 		// Changefeeds from older versions didn't get protected timestamps so it's
 		// fine to not protect this one. In newer versions changefeeds which perform
 		// an initial scan at the statement time (and don't have an initial high
-		// water) will have a protected timestamp.
+		// water) will have a protected timestamp. An empty high water is written
+		// while checkpointing the progress of an initial scan.
 		return nil
 	}
 
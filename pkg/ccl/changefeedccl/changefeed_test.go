@@ -9,6 +9,7 @@
 package changefeedccl
 
 import (
+	"bytes"
 	"context"
 	gosql "database/sql"
 	"fmt"
@@ -54,6 +55,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
@@ -155,6 +157,57 @@ func TestChangefeedDiff(t *testing.T) {
 	t.Run(`cloudstorage`, cloudStorageTest(testFn))
 }
 
+func TestChangefeedSelect(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	testFn := func(t *testing.T, db *gosql.DB, f cdctest.TestFeedFactory) {
+		sqlDB := sqlutils.MakeSQLRunner(db)
+		sqlDB.Exec(t, `CREATE TABLE foo (a INT PRIMARY KEY, b STRING, c INT, d STRING)`)
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (0, 'skipped', 5, 'd0'), (1, 'initial', 20, 'd1')`)
+
+		foo := feed(t, f, `CREATE CHANGEFEED AS SELECT b, c AS cc FROM foo WHERE c > 10`)
+		defer closeFeed(t, foo)
+
+		// Only the projected columns and the primary key are emitted, and rows
+		// not matching the WHERE clause are dropped.
+		assertPayloads(t, foo, []string{
+			`foo: [1]->{"after": {"a": 1, "b": "initial", "cc": 20}}`,
+		})
+
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (2, 'skipped', 1, 'd2'), (3, 'a', 30, 'd3')`)
+		assertPayloads(t, foo, []string{
+			`foo: [3]->{"after": {"a": 3, "b": "a", "cc": 30}}`,
+		})
+
+		// Schema changes to columns outside of the clause are transparent.
+		sqlDB.Exec(t, `ALTER TABLE foo DROP COLUMN d`)
+		sqlDB.Exec(t, `UPSERT INTO foo VALUES (2, 'skipped', 2), (3, 'b', 40)`)
+		assertPayloads(t, foo, []string{
+			`foo: [3]->{"after": {"a": 3, "b": "b", "cc": 40}}`,
+		})
+
+		// A row updated out of the WHERE clause is emitted as a delete, and
+		// further updates to it are dropped until it matches again.
+		sqlDB.Exec(t, `UPDATE foo SET c = 5 WHERE a = 3`)
+		sqlDB.Exec(t, `UPDATE foo SET c = 6 WHERE a = 3`)
+		sqlDB.Exec(t, `UPDATE foo SET c = 50 WHERE a = 3`)
+		assertPayloads(t, foo, []string{
+			`foo: [3]->{"after": null}`,
+			`foo: [3]->{"after": {"a": 3, "b": "b", "cc": 50}}`,
+		})
+
+		// Deletes are always emitted since only the primary key is known.
+		sqlDB.Exec(t, `DELETE FROM foo WHERE a = 3`)
+		assertPayloads(t, foo, []string{
+			`foo: [3]->{"after": null}`,
+		})
+	}
+
+	t.Run(`sinkless`, sinklessTest(testFn))
+	t.Run(`enterprise`, enterpriseTest(testFn))
+}
+
 func TestChangefeedEnvelope(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -419,6 +472,121 @@ func TestChangefeedInitialScan(t *testing.T) {
 	t.Run(`enterprise`, enterpriseTest(testFn))
 }
 
+func TestChangefeedEndTime(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	// waitForCompletion consumes the feed until it finishes, failing if any
+	// row is emitted along the way.
+	waitForCompletion := func(t *testing.T, db *sqlutils.SQLRunner, f cdctest.TestFeed) {
+		t.Helper()
+		if e, ok := f.(*cdctest.TableFeed); ok {
+			testutils.SucceedsSoon(t, func() error {
+				var status string
+				db.QueryRow(t, `SELECT status FROM system.jobs WHERE id = $1`, e.JobID).Scan(&status)
+				if jobs.Status(status) != jobs.StatusSucceeded {
+					return errors.Errorf(`expected job to succeed, got %s`, status)
+				}
+				return nil
+			})
+			return
+		}
+		for {
+			m, err := f.Next()
+			require.NoError(t, err)
+			if m == nil {
+				return
+			}
+			require.Nil(t, m.Key, `unexpected row %s: %s->%s`, m.Topic, m.Key, m.Value)
+		}
+	}
+
+	testFn := func(t *testing.T, db *gosql.DB, f cdctest.TestFeedFactory) {
+		sqlDB := sqlutils.MakeSQLRunner(db)
+		sqlDB.Exec(t, `SET CLUSTER SETTING kv.closed_timestamp.target_duration = '10ms'`)
+		sqlDB.Exec(t, `CREATE TABLE foo (a INT PRIMARY KEY)`)
+
+		var start, end string
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (1)`)
+		sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&start)
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (2)`)
+		sqlDB.QueryRow(t, `SELECT cluster_logical_timestamp()`).Scan(&end)
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (3)`)
+
+		t.Run(`end_time`, func(t *testing.T) {
+			foo := feed(t, f, `CREATE CHANGEFEED FOR foo WITH cursor=$1, end_time=$2`, start, end)
+			defer closeFeed(t, foo)
+			assertPayloads(t, foo, []string{
+				`foo: [2]->{"after": {"a": 2}}`,
+			})
+			waitForCompletion(t, sqlDB, foo)
+		})
+
+		t.Run(`initial_scan_only`, func(t *testing.T) {
+			foo := feed(t, f, `CREATE CHANGEFEED FOR foo WITH initial_scan_only, resolved`)
+			defer closeFeed(t, foo)
+			assertPayloads(t, foo, []string{
+				`foo: [1]->{"after": {"a": 1}}`,
+				`foo: [2]->{"after": {"a": 2}}`,
+				`foo: [3]->{"after": {"a": 3}}`,
+			})
+			waitForCompletion(t, sqlDB, foo)
+		})
+
+		t.Run(`initial_scan_only with cursor`, func(t *testing.T) {
+			foo := feed(t, f, `CREATE CHANGEFEED FOR foo WITH initial_scan_only, cursor=$1`, start)
+			defer closeFeed(t, foo)
+			assertPayloads(t, foo, []string{
+				`foo: [1]->{"after": {"a": 1}}`,
+			})
+			waitForCompletion(t, sqlDB, foo)
+		})
+	}
+
+	t.Run(`sinkless`, sinklessTest(testFn))
+	t.Run(`enterprise`, enterpriseTest(testFn))
+}
+
+func TestChangefeedTopicTemplate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	testFn := func(t *testing.T, db *gosql.DB, f cdctest.TestFeedFactory) {
+		sqlDB := sqlutils.MakeSQLRunner(db)
+		sqlDB.Exec(t, `CREATE TABLE foo (a INT, b STRING, PRIMARY KEY (b, a))`)
+		sqlDB.Exec(t, `CREATE SCHEMA s`)
+		sqlDB.Exec(t, `CREATE TABLE s.bar (a INT PRIMARY KEY)`)
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (1, 'x'), (2, 'y/z')`)
+		sqlDB.Exec(t, `INSERT INTO s.bar VALUES (1)`)
+
+		t.Run(`names`, func(t *testing.T) {
+			feed := feed(t, f, `CREATE CHANGEFEED FOR foo, s.bar `+
+				`WITH topic_template='cdc.{database}.{schema}.{table}'`)
+			defer closeFeed(t, feed)
+			assertPayloads(t, feed, []string{
+				`cdc.d.public.foo: ["x", 1]->{"after": {"a": 1, "b": "x"}}`,
+				`cdc.d.public.foo: ["y/z", 2]->{"after": {"a": 2, "b": "y/z"}}`,
+				`cdc.d.s.bar: [1]->{"after": {"a": 1}}`,
+			})
+		})
+
+		t.Run(`key`, func(t *testing.T) {
+			feed := feed(t, f, `CREATE CHANGEFEED FOR foo WITH topic_template='{table}-{key.b}'`)
+			defer closeFeed(t, feed)
+			assertPayloads(t, feed, []string{
+				`foo-x: ["x", 1]->{"after": {"a": 1, "b": "x"}}`,
+				`foo-y_u002f_z: ["y/z", 2]->{"after": {"a": 2, "b": "y/z"}}`,
+			})
+			sqlDB.Exec(t, `INSERT INTO foo VALUES (3, 'x')`)
+			assertPayloads(t, feed, []string{
+				`foo-x: ["x", 3]->{"after": {"a": 3, "b": "x"}}`,
+			})
+		})
+	}
+
+	t.Run(`enterprise`, enterpriseTest(testFn))
+}
+
 func TestChangefeedUserDefinedTypes(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	testFn := func(t *testing.T, db *gosql.DB, f cdctest.TestFeedFactory) {
@@ -1627,6 +1795,84 @@ func TestChangefeedMonitoring(t *testing.T) {
 	})
 }
 
+func TestChangefeedMetricsScopes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	testFn := func(t *testing.T, db *gosql.DB, f cdctest.TestFeedFactory) {
+		sqlDB := sqlutils.MakeSQLRunner(db)
+		registry := f.Server().JobRegistry().(*jobs.Registry)
+		metrics := registry.MetricsStruct().Changefeed.(*Metrics)
+		scope := func(name string) *scopedMetrics {
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			return metrics.mu.scopes[name]
+		}
+
+		sqlDB.Exec(t, `CREATE TABLE foo (a INT PRIMARY KEY)`)
+		sqlDB.Exec(t, `INSERT INTO foo VALUES (1)`)
+
+		unlabeled := feed(t, f, `CREATE CHANGEFEED FOR foo`)
+		defer closeFeed(t, unlabeled)
+		assertPayloads(t, unlabeled, []string{`foo: [1]->{"after": {"a": 1}}`})
+		labeled := feed(t, f, `CREATE CHANGEFEED FOR foo WITH metrics_label='bar'`)
+		assertPayloads(t, labeled, []string{`foo: [1]->{"after": {"a": 1}}`})
+
+		// Each scope only counts the messages of its own changefeeds and the
+		// aggregate counts all of them.
+		bar := scope(`bar`)
+		require.NotNil(t, bar)
+		testutils.SucceedsSoon(t, func() error {
+			if c := bar.EmittedMessages.Value(); c < 1 {
+				return errors.Errorf(`expected >= 1 got %d`, c)
+			}
+			if c := bar.CommitLatency.TotalCount(); c < 1 {
+				return errors.Errorf(`expected >= 1 got %d`, c)
+			}
+			return nil
+		})
+		scoped := bar.EmittedMessages.Value() + scope(defaultMetricsScope).EmittedMessages.Value()
+		require.GreaterOrEqual(t, metrics.EmittedMessages.Count(), scoped)
+		require.Equal(t, int64(0), bar.BackfillPendingRanges.Value())
+		require.Equal(t, int64(0), bar.SinkErrors.Value())
+
+		// The scopes are exported to prometheus with a scope label.
+		r := metric.NewRegistry()
+		r.AddMetricStruct(metrics)
+		ex := metric.MakePrometheusExporter()
+		ex.ScrapeRegistry(r, true /* includeChildMetrics */)
+		var buf bytes.Buffer
+		require.NoError(t, ex.PrintAsText(&buf))
+		require.Contains(t, buf.String(), `changefeed_emitted_messages{scope="bar"}`)
+		require.Contains(t, buf.String(), `changefeed_emitted_messages{scope="default"}`)
+		require.Contains(t, buf.String(), `changefeed_commit_latency_count{scope="bar"}`)
+
+		// The number of distinct scopes is limited.
+		sqlDB.Exec(t, `SET CLUSTER SETTING changefeed.max_metrics_scopes = 2`)
+		sqlDB.ExpectErr(t, `too many distinct metrics_label values; the maximum is 2`,
+			`EXPERIMENTAL CHANGEFEED FOR foo WITH metrics_label='baz'`)
+		relabeled := feed(t, f, `CREATE CHANGEFEED FOR foo WITH metrics_label='bar'`)
+		assertPayloads(t, relabeled, []string{`foo: [1]->{"after": {"a": 1}}`})
+
+		// Once the last changefeed with a label is done, its scope is released
+		// and no longer counts towards the limit.
+		closeFeed(t, labeled)
+		closeFeed(t, relabeled)
+		testutils.SucceedsSoon(t, func() error {
+			if scope(`bar`) != nil {
+				return errors.New(`expected the bar scope to be released`)
+			}
+			return nil
+		})
+		baz := feed(t, f, `CREATE CHANGEFEED FOR foo WITH metrics_label='baz'`)
+		defer closeFeed(t, baz)
+		assertPayloads(t, baz, []string{`foo: [1]->{"after": {"a": 1}}`})
+	}
+
+	t.Run(`sinkless`, sinklessTest(testFn))
+	t.Run(`enterprise`, enterpriseTest(testFn))
+}
+
 func TestChangefeedRetryableError(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -1897,6 +2143,27 @@ func TestChangefeedErrors(t *testing.T) {
 		`EXPERIMENTAL CHANGEFEED FOR foo WITH format=nope`,
 	)
 
+	sqlDB.ExpectErr(
+		t, `CHANGEFEED projections must be column references: a \+ 1`,
+		`EXPERIMENTAL CHANGEFEED AS SELECT a + 1 FROM foo`,
+	)
+	sqlDB.ExpectErr(
+		t, `column "nope" does not exist`,
+		`EXPERIMENTAL CHANGEFEED AS SELECT nope FROM foo`,
+	)
+	sqlDB.ExpectErr(
+		t, `column "b" projected more than once`,
+		`EXPERIMENTAL CHANGEFEED AS SELECT b, * FROM foo`,
+	)
+	sqlDB.ExpectErr(
+		t, `expected CHANGEFEED WHERE expression to have type bool, but 'b' has type string`,
+		`EXPERIMENTAL CHANGEFEED AS SELECT * FROM foo WHERE b`,
+	)
+	sqlDB.ExpectErr(
+		t, `now\(\): context-dependent operators are not allowed in CHANGEFEED WHERE`,
+		`EXPERIMENTAL CHANGEFEED AS SELECT * FROM foo WHERE a > extract(epoch FROM now())`,
+	)
+
 	sqlDB.ExpectErr(
 		t, `unknown envelope: nope`,
 		`EXPERIMENTAL CHANGEFEED FOR foo WITH envelope=nope`,
@@ -1968,6 +2235,15 @@ func TestChangefeedErrors(t *testing.T) {
 		`EXPERIMENTAL CHANGEFEED FOR "oid" WITH format=$1, confluent_schema_registry=$2`,
 		changefeedbase.OptFormatAvro, `bar`,
 	)
+	sqlDB.ExpectErr(
+		t, `pq: column a: type OID not yet supported with protobuf`,
+		`EXPERIMENTAL CHANGEFEED FOR "oid" WITH format=$1, confluent_schema_registry=$2`,
+		changefeedbase.OptFormatProtobuf, `bar`,
+	)
+	sqlDB.ExpectErr(
+		t, `WITH option confluent_schema_registry is required for format=protobuf`,
+		`EXPERIMENTAL CHANGEFEED FOR foo WITH format=$1`, changefeedbase.OptFormatProtobuf,
+	)
 
 	// Check that confluent_schema_registry is only accepted if format is avro.
 	sqlDB.ExpectErr(
@@ -2091,6 +2367,17 @@ func TestChangefeedErrors(t *testing.T) {
 		`experimental-nodelocal://0/bar`,
 	)
 
+	// The parquet format only works with the cloudStorageSink.
+	sqlDB.ExpectErr(
+		t, `format=parquet is only supported with cloud storage sinks`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='parquet'`, `kafka://nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `diff is not supported with format=parquet`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH format='parquet', diff`,
+		`experimental-nodelocal://0/bar`,
+	)
+
 	// WITH key_in_value requires envelope=wrapped
 	sqlDB.ExpectErr(
 		t, `key_in_value is only usable with envelope=wrapped`,
@@ -2120,6 +2407,55 @@ func TestChangefeedErrors(t *testing.T) {
 		t, `cannot specify both initial_scan and no_initial_scan`,
 		`CREATE CHANGEFEED FOR foo INTO $1 WITH no_initial_scan, initial_scan`, `kafka://nope`,
 	)
+
+	// WITH initial_scan_only disallows no_initial_scan and end_time.
+	sqlDB.ExpectErr(
+		t, `cannot specify both initial_scan_only and no_initial_scan`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH initial_scan_only, no_initial_scan`, `kafka://nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `cannot specify both initial_scan_only and end_time`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH initial_scan_only, end_time='+1h'`, `kafka://nope`,
+	)
+
+	// Name templates are only supported by sinks with topics or paths and
+	// must be well formed.
+	sqlDB.ExpectErr(
+		t, `topic_template is only supported with kafka sinks`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH topic_template='{table}'`, `experimental-nodelocal://0/nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `path_template is only supported with cloud storage sinks`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH path_template='{table}'`, `kafka://nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `cannot specify both topic_template and path_template`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH topic_template='{table}', path_template='{table}'`,
+		`kafka://nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `unknown placeholder {tabel} in topic_template`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH topic_template='{tabel}'`, `kafka://nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `column nope is not part of the primary key of table foo`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH path_template='{key.nope}'`, `experimental-nodelocal://0/nope`,
+	)
+
+	sqlDB.ExpectErr(
+		t, `metrics_label must not be empty`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH metrics_label=''`, `kafka://nope`,
+	)
+
+	// The end time must be after the start of the changefeed.
+	sqlDB.ExpectErr(
+		t, `end_time must be after the start time of the changefeed`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH end_time='-1h'`, `kafka://nope`,
+	)
+	sqlDB.ExpectErr(
+		t, `end_time must be after the start time of the changefeed`,
+		`CREATE CHANGEFEED FOR foo INTO $1 WITH cursor='-1s', end_time='-2s'`, `kafka://nope`,
+	)
 }
 
 func TestChangefeedDescription(t *testing.T) {
@@ -2943,6 +3279,114 @@ func TestChangefeedRestartDuringBackfill(t *testing.T) {
 	t.Run(`enterprise`, enterpriseTest(testFn))
 }
 
+func TestChangefeedBackfillCheckpoint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	defer jobs.TestingSetAdoptAndCancelIntervals(10*time.Millisecond, 10*time.Millisecond)()
+
+	testFn := func(t *testing.T, db *gosql.DB, f cdctest.TestFeedFactory) {
+		knobs := f.Server().(*server.TestServer).Cfg.TestingKnobs.
+			DistSQL.(*execinfra.TestingKnobs).
+			Changefeed.(*TestingKnobs)
+		// Let the first half of the backfill through and then block, so that
+		// the changefeed is restarted in the middle of its initial scan.
+		const numRows = 10
+		var emitted int32
+		unblockCh := make(chan struct{})
+		knobs.BeforeEmitRow = func(ctx context.Context) error {
+			if atomic.AddInt32(&emitted, 1) <= numRows/2 {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-unblockCh:
+				return nil
+			}
+		}
+
+		sqlDB := sqlutils.MakeSQLRunner(db)
+		// Scan one range at a time, so that the ranges are scanned in order.
+		sqlDB.Exec(t, `SET CLUSTER SETTING changefeed.backfill.concurrent_scan_requests = 1`)
+		sqlDB.Exec(t, `SET CLUSTER SETTING changefeed.frontier_checkpoint_frequency = '1ms'`)
+		sqlDB.Exec(t, `CREATE TABLE foo (a INT PRIMARY KEY)`)
+		sqlDB.Exec(t, `INSERT INTO foo SELECT generate_series(0, $1)`, numRows-1)
+		sqlDB.Exec(t, `ALTER TABLE foo SPLIT AT SELECT generate_series(1, $1)`, numRows-1)
+		var tableID uint32
+		sqlDB.QueryRow(t, `SELECT 'foo'::regclass::int`).Scan(&tableID)
+
+		// Flush resolved spans as soon as they are produced so that the
+		// frontier learns about each scanned range right away.
+		foo := feed(t, f, `CREATE CHANGEFEED FOR foo WITH resolved = '1ns'`).(*cdctest.TableFeed)
+		defer closeFeed(t, foo)
+
+		var expected []string
+		for i := 0; i < numRows/2; i++ {
+			expected = append(expected, fmt.Sprintf(`foo: [%d]->{"after": {"a": %d}}`, i, i))
+		}
+		assertPayloads(t, foo, expected)
+
+		loadCheckpoint := func() *jobspb.ChangefeedProgress_Checkpoint {
+			var progressBytes []byte
+			sqlDB.QueryRow(t, `SELECT progress FROM system.jobs WHERE id = $1`, foo.JobID).
+				Scan(&progressBytes)
+			var progress jobspb.Progress
+			require.NoError(t, protoutil.Unmarshal(progressBytes, &progress))
+			return progress.GetChangefeed().Checkpoint
+		}
+		testutils.SucceedsSoon(t, func() error {
+			if cp := loadCheckpoint(); cp == nil || len(cp.Spans) == 0 {
+				return errors.New("waiting for the initial scan to be checkpointed")
+			}
+			return nil
+		})
+
+		sqlDB.Exec(t, `PAUSE JOB $1`, foo.JobID)
+		testutils.SucceedsSoon(t, func() error {
+			var status string
+			sqlDB.QueryRow(t, `SELECT status FROM system.jobs WHERE id = $1`, foo.JobID).Scan(&status)
+			if jobs.Status(status) != jobs.StatusPaused {
+				return errors.New("could not pause job")
+			}
+			return nil
+		})
+
+		// Only the rows which are not covered by the checkpoint are expected
+		// to be scanned again once the changefeed is resumed.
+		checkpoint := loadCheckpoint()
+		expected = expected[:0]
+		for i := 0; i < numRows; i++ {
+			key := encoding.EncodeVarintAscending(
+				keys.SystemSQLCodec.IndexPrefix(tableID, 1 /* indexID */), int64(i))
+			var covered bool
+			for _, sp := range checkpoint.Spans {
+				covered = covered || sp.ContainsKey(key)
+			}
+			if !covered {
+				expected = append(expected, fmt.Sprintf(`foo: [%d]->{"after": {"a": %d}}`, i, i))
+			}
+		}
+		require.Less(t, len(expected), numRows)
+
+		close(unblockCh)
+		emittedBeforeResume := atomic.LoadInt32(&emitted)
+		sqlDB.Exec(t, `RESUME JOB $1`, foo.JobID)
+		assertPayloads(t, foo, expected)
+		require.Equal(t, int32(len(expected)), atomic.LoadInt32(&emitted)-emittedBeforeResume)
+
+		// Once the initial scan completes, the checkpoint is cleared.
+		testutils.SucceedsSoon(t, func() error {
+			if cp := loadCheckpoint(); cp != nil {
+				return errors.New("waiting for the checkpoint to be cleared")
+			}
+			return nil
+		})
+	}
+
+	// Only the enterprise version uses jobs.
+	t.Run(`enterprise`, enterpriseTest(testFn))
+}
+
 func TestChangefeedHandlesDrainingNodes(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -28,6 +28,7 @@ type SchemaChangePolicy string
 const (
 	OptConfluentSchemaRegistry  = `confluent_schema_registry`
 	OptCursor                   = `cursor`
+	OptEndTime                  = `end_time`
 	OptEnvelope                 = `envelope`
 	OptFormat                   = `format`
 	OptKeyInValue               = `key_in_value`
@@ -38,6 +39,9 @@ const (
 	OptSchemaChangeEvents       = `schema_change_events`
 	OptSchemaChangePolicy       = `schema_change_policy`
 	OptProtectDataFromGCOnPause = `protect_data_from_gc_on_pause`
+	OptTopicTemplate            = `topic_template`
+	OptPathTemplate             = `path_template`
+	OptMetricsLabel             = `metrics_label`
 
 	// OptSchemaChangeEventClassColumnChange corresponds to all schema change
 	// events which add or remove any column.
@@ -67,14 +71,19 @@ const (
 	// cursor is specified. This option is useful to create a changefeed which
 	// subscribes only to new messages.
 	OptNoInitialScan = `no_initial_scan`
+	// OptInitialScanOnly performs the initial scan and then completes the
+	// changefeed job successfully without emitting any subsequent changes.
+	OptInitialScanOnly = `initial_scan_only`
 
 	OptEnvelopeKeyOnly       EnvelopeType = `key_only`
 	OptEnvelopeRow           EnvelopeType = `row`
 	OptEnvelopeDeprecatedRow EnvelopeType = `deprecated_row`
 	OptEnvelopeWrapped       EnvelopeType = `wrapped`
 
-	OptFormatJSON FormatType = `json`
-	OptFormatAvro FormatType = `experimental_avro`
+	OptFormatJSON     FormatType = `json`
+	OptFormatAvro     FormatType = `experimental_avro`
+	OptFormatProtobuf FormatType = `protobuf`
+	OptFormatParquet  FormatType = `parquet`
 
 	SinkParamCACert           = `ca_cert`
 	SinkParamClientCert       = `client_cert`
@@ -91,6 +100,15 @@ const (
 	SinkParamSASLHandshake    = `sasl_handshake`
 	SinkParamSASLUser         = `sasl_user`
 	SinkParamSASLPassword     = `sasl_password`
+
+	SinkSchemeWebhookHTTPS         = `webhook-https`
+	SinkParamWebhookAuthHeader     = `webhook_auth_header`
+	SinkParamWebhookBatchBytes     = `webhook_batch_bytes`
+	SinkParamWebhookBatchMessages  = `webhook_batch_messages`
+	SinkParamWebhookClientTimeout  = `webhook_client_timeout`
+	SinkParamWebhookFlushFrequency = `webhook_flush_frequency`
+	SinkParamWebhookMaxRetries     = `webhook_max_retries`
+	SinkParamWebhookRetryBackoff   = `webhook_retry_backoff`
 )
 
 // ChangefeedOptionExpectValues is used to parse changefeed options using
@@ -98,6 +116,7 @@ const (
 var ChangefeedOptionExpectValues = map[string]sql.KVStringOptValidate{
 	OptConfluentSchemaRegistry:  sql.KVStringOptRequireValue,
 	OptCursor:                   sql.KVStringOptRequireValue,
+	OptEndTime:                  sql.KVStringOptRequireValue,
 	OptEnvelope:                 sql.KVStringOptRequireValue,
 	OptFormat:                   sql.KVStringOptRequireValue,
 	OptKeyInValue:               sql.KVStringOptRequireNoValue,
@@ -109,5 +128,9 @@ var ChangefeedOptionExpectValues = map[string]sql.KVStringOptValidate{
 	OptSchemaChangePolicy:       sql.KVStringOptRequireValue,
 	OptInitialScan:              sql.KVStringOptRequireNoValue,
 	OptNoInitialScan:            sql.KVStringOptRequireNoValue,
+	OptInitialScanOnly:          sql.KVStringOptRequireNoValue,
 	OptProtectDataFromGCOnPause: sql.KVStringOptRequireNoValue,
+	OptTopicTemplate:            sql.KVStringOptRequireValue,
+	OptPathTemplate:             sql.KVStringOptRequireValue,
+	OptMetricsLabel:             sql.KVStringOptRequireValue,
 }
@@ -24,3 +24,37 @@ var TableDescriptorPollInterval = settings.RegisterNonNegativeDurationSetting(
 	"polling interval for the table descriptors",
 	1*time.Second,
 )
+
+// MaxMetricsScopes is the maximum number of distinct metrics_label values for
+// which each node tracks changefeed metrics.
+var MaxMetricsScopes = settings.RegisterPositiveIntSetting(
+	"changefeed.max_metrics_scopes",
+	"maximum number of distinct metrics_label values tracked by each node",
+	1024,
+)
+
+// ScanRequestLimit is the number of Scan requests that can run at once.
+// Scan requests are issued when changefeed performs the backfill.
+// If set to 0, a reasonable default will be chosen.
+var ScanRequestLimit = settings.RegisterNonNegativeIntSetting(
+	"changefeed.backfill.concurrent_scan_requests",
+	"number of concurrent scan requests per node issued during a backfill; "+
+		"0 uses the number of nodes times kv.bulk_io_write.concurrent_export_requests",
+	0,
+)
+
+// FrontierCheckpointFrequency controls the frequency of frontier checkpoints.
+var FrontierCheckpointFrequency = settings.RegisterNonNegativeDurationSetting(
+	"changefeed.frontier_checkpoint_frequency",
+	"controls the frequency with which the spans completed by an initial scan "+
+		"are checkpointed into the job progress; 0 disables checkpointing",
+	10*time.Second,
+)
+
+// FrontierCheckpointMaxBytes controls the maximum number of key bytes that
+// will be added to the checkpoint record.
+var FrontierCheckpointMaxBytes = settings.RegisterByteSizeSetting(
+	"changefeed.frontier_checkpoint_max_bytes",
+	"controls the maximum size of the checkpoint as a total size of key bytes",
+	1<<20,
+)
@@ -37,6 +37,9 @@ const (
 	confluentSubjectSuffixKey    = `-key`
 	confluentSubjectSuffixValue  = `-value`
 	confluentAvroWireFormatMagic = byte(0)
+
+	confluentSchemaTypeAvro     = `AVRO`
+	confluentSchemaTypeProtobuf = `PROTOBUF`
 )
 
 // encodeRow holds all the pieces necessary to encode a row change into a key or
@@ -88,6 +91,10 @@ func getEncoder(opts map[string]string) (Encoder, error) {
 		return makeJSONEncoder(opts)
 	case changefeedbase.OptFormatAvro:
 		return newConfluentAvroEncoder(opts)
+	case changefeedbase.OptFormatProtobuf:
+		return newConfluentProtobufEncoder(opts)
+	case changefeedbase.OptFormatParquet:
+		return newParquetEncoder(opts)
 	default:
 		return nil, errors.Errorf(`unknown %s: %s`, changefeedbase.OptFormat, opts[changefeedbase.OptFormat])
 	}
@@ -267,6 +274,60 @@ func (e *jsonEncoder) EncodeResolvedTimestamp(
 	return gojson.Marshal(jsonEntries)
 }
 
+// parquetEncoder encodes changefeed entries for Parquet files. Parquet is a
+// columnar format, so rows are not encoded into their final form here: values
+// are an intermediate serialization of the row (see encodeParquetRow) that the
+// cloud storage sink assembles into a Parquet file per flushed file. Keys are
+// not emitted, as the primary key columns are always part of the value.
+// Resolved timestamps are encoded as JSON, matching the wrapped json format.
+type parquetEncoder struct {
+	updatedField bool
+
+	alloc   rowenc.DatumAlloc
+	buf     []byte
+	scratch []byte
+}
+
+var _ Encoder = &parquetEncoder{}
+
+func newParquetEncoder(opts map[string]string) (*parquetEncoder, error) {
+	switch changefeedbase.EnvelopeType(opts[changefeedbase.OptEnvelope]) {
+	case changefeedbase.OptEnvelopeWrapped:
+	default:
+		return nil, errors.Errorf(`%s=%s is not supported with %s=%s`,
+			changefeedbase.OptEnvelope, opts[changefeedbase.OptEnvelope],
+			changefeedbase.OptFormat, changefeedbase.OptFormatParquet)
+	}
+	if _, ok := opts[changefeedbase.OptDiff]; ok {
+		return nil, errors.Errorf(`%s is not supported with %s=%s`,
+			changefeedbase.OptDiff, changefeedbase.OptFormat, changefeedbase.OptFormatParquet)
+	}
+	e := &parquetEncoder{}
+	_, e.updatedField = opts[changefeedbase.OptUpdatedTimestamps]
+	return e, nil
+}
+
+// EncodeKey implements the Encoder interface.
+func (e *parquetEncoder) EncodeKey(context.Context, encodeRow) ([]byte, error) {
+	return nil, nil
+}
+
+// EncodeValue implements the Encoder interface.
+func (e *parquetEncoder) EncodeValue(_ context.Context, row encodeRow) ([]byte, error) {
+	var err error
+	e.buf, err = encodeParquetRow(e.buf[:0], row, e.updatedField, &e.alloc, e.scratch)
+	return e.buf, err
+}
+
+// EncodeResolvedTimestamp implements the Encoder interface.
+func (e *parquetEncoder) EncodeResolvedTimestamp(
+	_ context.Context, _ string, resolved hlc.Timestamp,
+) ([]byte, error) {
+	return gojson.Marshal(map[string]interface{}{
+		`resolved`: tree.TimestampToDecimalDatum(resolved).Decimal.String(),
+	})
+}
+
 // confluentAvroEncoder encodes changefeed entries as Avro's binary or textual
 // JSON format. Keys are the primary key columns in a record. Values are all
 // columns in a record.
@@ -472,26 +533,39 @@ func (e *confluentAvroEncoder) EncodeResolvedTimestamp(
 
 func (e *confluentAvroEncoder) register(
 	ctx context.Context, schema *avroRecord, subject string,
+) (int32, error) {
+	return registerConfluentSchema(ctx, e.registryURL, subject, confluentSchemaTypeAvro, schema.codec.Schema())
+}
+
+// registerConfluentSchema registers the given schema under the given subject
+// with the Confluent schema registry at registryURL and returns its id.
+func registerConfluentSchema(
+	ctx context.Context, registryURL, subject, schemaType, schemaStr string,
 ) (int32, error) {
 	type confluentSchemaVersionRequest struct {
-		Schema string `json:"schema"`
+		// SchemaType is omitted for avro schemas, which keeps us compatible with
+		// registries that predate support for other schema types.
+		SchemaType string `json:"schemaType,omitempty"`
+		Schema     string `json:"schema"`
 	}
 	type confluentSchemaVersionResponse struct {
 		ID int32 `json:"id"`
 	}
 
-	url, err := url.Parse(e.registryURL)
+	url, err := url.Parse(registryURL)
 	if err != nil {
 		return 0, err
 	}
 	url.Path = filepath.Join(url.EscapedPath(), `subjects`, subject, `versions`)
 
-	schemaStr := schema.codec.Schema()
 	if log.V(1) {
-		log.Infof(ctx, "registering avro schema %s %s", url, schemaStr)
+		log.Infof(ctx, "registering %s schema %s %s", schemaType, url, schemaStr)
 	}
 
 	req := confluentSchemaVersionRequest{Schema: schemaStr}
+	if schemaType != confluentSchemaTypeAvro {
+		req.SchemaType = schemaType
+	}
 	var buf bytes.Buffer
 	if err := gojson.NewEncoder(&buf).Encode(req); err != nil {
 		return 0, err
@@ -533,3 +607,205 @@ func (e *confluentAvroEncoder) register(
 
 	return id, nil
 }
+
+// confluentProtobufEncoder encodes changefeed entries in the protobuf wire
+// format, framed as expected by Confluent's protobuf deserializers. Keys are
+// the primary key columns in a message. Values are all columns in a message
+// wrapped in an envelope message.
+type confluentProtobufEncoder struct {
+	registryURL                        string
+	updatedField, beforeField, keyOnly bool
+
+	keyCache      map[tableIDAndVersion]confluentRegisteredProtobufKeySchema
+	valueCache    map[tableIDAndVersionPair]confluentRegisteredProtobufEnvelopeSchema
+	resolvedCache map[string]confluentRegisteredProtobufEnvelopeSchema
+}
+
+type confluentRegisteredProtobufKeySchema struct {
+	message    *protobufDataMessage
+	registryID int32
+}
+
+type confluentRegisteredProtobufEnvelopeSchema struct {
+	message    *protobufEnvelopeMessage
+	registryID int32
+}
+
+var _ Encoder = &confluentProtobufEncoder{}
+
+func newConfluentProtobufEncoder(opts map[string]string) (*confluentProtobufEncoder, error) {
+	e := &confluentProtobufEncoder{registryURL: opts[changefeedbase.OptConfluentSchemaRegistry]}
+
+	switch opts[changefeedbase.OptEnvelope] {
+	case string(changefeedbase.OptEnvelopeKeyOnly):
+		e.keyOnly = true
+	case string(changefeedbase.OptEnvelopeWrapped):
+	default:
+		return nil, errors.Errorf(`%s=%s is not supported with %s=%s`,
+			changefeedbase.OptEnvelope, opts[changefeedbase.OptEnvelope], changefeedbase.OptFormat, changefeedbase.OptFormatProtobuf)
+	}
+	_, e.updatedField = opts[changefeedbase.OptUpdatedTimestamps]
+	if e.updatedField && e.keyOnly {
+		return nil, errors.Errorf(`%s is only usable with %s=%s`,
+			changefeedbase.OptUpdatedTimestamps, changefeedbase.OptEnvelope, changefeedbase.OptEnvelopeWrapped)
+	}
+	_, e.beforeField = opts[changefeedbase.OptDiff]
+	if e.beforeField && e.keyOnly {
+		return nil, errors.Errorf(`%s is only usable with %s=%s`,
+			changefeedbase.OptDiff, changefeedbase.OptEnvelope, changefeedbase.OptEnvelopeWrapped)
+	}
+
+	if _, ok := opts[changefeedbase.OptKeyInValue]; ok {
+		return nil, errors.Errorf(`%s is not supported with %s=%s`,
+			changefeedbase.OptKeyInValue, changefeedbase.OptFormat, changefeedbase.OptFormatProtobuf)
+	}
+
+	if len(e.registryURL) == 0 {
+		return nil, errors.Errorf(`WITH option %s is required for %s=%s`,
+			changefeedbase.OptConfluentSchemaRegistry, changefeedbase.OptFormat, changefeedbase.OptFormatProtobuf)
+	}
+
+	e.keyCache = make(map[tableIDAndVersion]confluentRegisteredProtobufKeySchema)
+	e.valueCache = make(map[tableIDAndVersionPair]confluentRegisteredProtobufEnvelopeSchema)
+	e.resolvedCache = make(map[string]confluentRegisteredProtobufEnvelopeSchema)
+	return e, nil
+}
+
+// EncodeKey implements the Encoder interface.
+func (e *confluentProtobufEncoder) EncodeKey(ctx context.Context, row encodeRow) ([]byte, error) {
+	cacheKey := makeTableIDAndVersion(row.tableDesc.GetID(), row.tableDesc.GetVersion())
+	registered, ok := e.keyCache[cacheKey]
+	if !ok {
+		var err error
+		registered.message, err = indexToProtobufMessage(row.tableDesc, row.tableDesc.GetPrimaryIndex())
+		if err != nil {
+			return nil, err
+		}
+		schema, err := makeProtobufSchema(registered.message.desc.GetName(), registered.message.desc)
+		if err != nil {
+			return nil, err
+		}
+
+		// NB: This uses the kafka name escaper because it has to match the name
+		// of the kafka topic.
+		subject := SQLNameToKafkaName(row.tableDesc.GetName()) + confluentSubjectSuffixKey
+		registered.registryID, err = registerConfluentSchema(
+			ctx, e.registryURL, subject, confluentSchemaTypeProtobuf, schema.text)
+		if err != nil {
+			return nil, err
+		}
+		// TODO(dan): Bound the size of this cache.
+		e.keyCache[cacheKey] = registered
+	}
+
+	header := confluentProtobufHeader(registered.registryID)
+	return registered.message.BinaryFromRow(header, row.datums)
+}
+
+// EncodeValue implements the Encoder interface.
+func (e *confluentProtobufEncoder) EncodeValue(
+	ctx context.Context, row encodeRow,
+) ([]byte, error) {
+	if e.keyOnly {
+		return nil, nil
+	}
+
+	var cacheKey tableIDAndVersionPair
+	if e.beforeField && row.prevTableDesc != nil {
+		cacheKey[0] = makeTableIDAndVersion(row.prevTableDesc.GetID(), row.prevTableDesc.GetVersion())
+	}
+	cacheKey[1] = makeTableIDAndVersion(row.tableDesc.GetID(), row.tableDesc.GetVersion())
+	registered, ok := e.valueCache[cacheKey]
+	if !ok {
+		var beforeMessage *protobufDataMessage
+		if e.beforeField && row.prevTableDesc != nil {
+			var err error
+			beforeMessage, err = tableToProtobufMessage(row.prevTableDesc, `before`)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		afterMessage, err := tableToProtobufMessage(row.tableDesc, avroSchemaNoSuffix)
+		if err != nil {
+			return nil, err
+		}
+
+		opts := protobufEnvelopeOpts{
+			afterField:   true,
+			beforeField:  e.beforeField && beforeMessage != nil,
+			updatedField: e.updatedField,
+		}
+		registered.message = envelopeToProtobufMessage(row.tableDesc.GetName(), opts, beforeMessage, afterMessage)
+		schema, err := registered.message.schema()
+		if err != nil {
+			return nil, err
+		}
+
+		// NB: This uses the kafka name escaper because it has to match the name
+		// of the kafka topic.
+		subject := SQLNameToKafkaName(row.tableDesc.GetName()) + confluentSubjectSuffixValue
+		registered.registryID, err = registerConfluentSchema(
+			ctx, e.registryURL, subject, confluentSchemaTypeProtobuf, schema.text)
+		if err != nil {
+			return nil, err
+		}
+		// TODO(dan): Bound the size of this cache.
+		e.valueCache[cacheKey] = registered
+	}
+	var beforeDatums, afterDatums rowenc.EncDatumRow
+	if row.prevDatums != nil && !row.prevDeleted {
+		beforeDatums = row.prevDatums
+	}
+	if !row.deleted {
+		afterDatums = row.datums
+	}
+	header := confluentProtobufHeader(registered.registryID)
+	return registered.message.BinaryFromRow(header, row.updated, hlc.Timestamp{}, beforeDatums, afterDatums)
+}
+
+// EncodeResolvedTimestamp implements the Encoder interface.
+func (e *confluentProtobufEncoder) EncodeResolvedTimestamp(
+	ctx context.Context, topic string, resolved hlc.Timestamp,
+) ([]byte, error) {
+	registered, ok := e.resolvedCache[topic]
+	if !ok {
+		opts := protobufEnvelopeOpts{resolvedField: true}
+		registered.message = envelopeToProtobufMessage(topic, opts, nil /* before */, nil /* after */)
+		schema, err := registered.message.schema()
+		if err != nil {
+			return nil, err
+		}
+
+		// NB: This uses the kafka name escaper because it has to match the name
+		// of the kafka topic.
+		subject := SQLNameToKafkaName(topic) + confluentSubjectSuffixValue
+		registered.registryID, err = registerConfluentSchema(
+			ctx, e.registryURL, subject, confluentSchemaTypeProtobuf, schema.text)
+		if err != nil {
+			return nil, err
+		}
+		// TODO(dan): Bound the size of this cache.
+		e.resolvedCache[topic] = registered
+	}
+	header := confluentProtobufHeader(registered.registryID)
+	return registered.message.BinaryFromRow(
+		header, hlc.Timestamp{}, resolved, nil /* beforeRow */, nil /* afterRow */)
+}
+
+// confluentProtobufHeader returns the header that precedes a protobuf message
+// encoded with the given registered schema.
+//
+// https://docs.confluent.io/platform/current/schema-registry/serdes-develop/index.html#wire-format
+func confluentProtobufHeader(registryID int32) []byte {
+	header := []byte{
+		confluentAvroWireFormatMagic,
+		0, 0, 0, 0, // Placeholder for the ID.
+		// The message indexes identifying which message in the schema is
+		// encoded. The encoded message is always the first message in the
+		// schema, which is abbreviated as a single zero.
+		0,
+	}
+	binary.BigEndian.PutUint32(header[1:5], uint32(registryID))
+	return header
+}
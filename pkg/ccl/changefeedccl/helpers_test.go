@@ -152,6 +152,49 @@ func assertPayloadsAvro(
 	}
 }
 
+func protobufToJSON(t testing.TB, reg *testSchemaRegistry, protobufBytes []byte) []byte {
+	if len(protobufBytes) == 0 {
+		return nil
+	}
+	native, err := reg.encodedProtobufToNative(protobufBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json, err := gojson.Marshal(native)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return json
+}
+
+func assertPayloadsProtobuf(
+	t testing.TB, reg *testSchemaRegistry, f cdctest.TestFeed, expected []string,
+) {
+	t.Helper()
+
+	var actual []string
+	for len(actual) < len(expected) {
+		m, err := f.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if m == nil {
+			t.Fatal(`expected message`)
+		} else if m.Key != nil {
+			key, value := protobufToJSON(t, reg, m.Key), protobufToJSON(t, reg, m.Value)
+			actual = append(actual, fmt.Sprintf(`%s: %s->%s`, m.Topic, key, value))
+		}
+	}
+
+	// The tests that use this aren't concerned with order, just that these are
+	// the next len(expected) messages.
+	sort.Strings(expected)
+	sort.Strings(actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected\n  %s\ngot\n  %s",
+			strings.Join(expected, "\n  "), strings.Join(actual, "\n  "))
+	}
+}
+
 func parseTimeToHLC(t testing.TB, s string) hlc.Timestamp {
 	t.Helper()
 	d, _, err := apd.NewFromString(s)
@@ -215,6 +258,30 @@ func expectResolvedTimestampAvro(
 	return parseTimeToHLC(t, resolved.(map[string]interface{})[`string`].(string))
 }
 
+func expectResolvedTimestampProtobuf(
+	t testing.TB, reg *testSchemaRegistry, f cdctest.TestFeed,
+) hlc.Timestamp {
+	t.Helper()
+	m, err := f.Next()
+	if err != nil {
+		t.Fatal(err)
+	} else if m == nil {
+		t.Fatal(`expected message`)
+	}
+	if m.Key != nil {
+		key, value := protobufToJSON(t, reg, m.Key), protobufToJSON(t, reg, m.Value)
+		t.Fatalf(`unexpected row %s: %s -> %s`, m.Topic, key, value)
+	}
+	if m.Resolved == nil {
+		t.Fatal(`expected a resolved timestamp notification`)
+	}
+	resolvedNative, err := reg.encodedProtobufToNative(m.Resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseTimeToHLC(t, resolvedNative[`resolved`].(string))
+}
+
 func sinklessTest(testFn func(*testing.T, *gosql.DB, cdctest.TestFeedFactory)) func(*testing.T) {
 	return func(t *testing.T) {
 		ctx := context.Background()
@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
 	"github.com/cockroachdb/cockroach/pkg/util/span"
 	"github.com/cockroachdb/errors"
@@ -60,6 +61,19 @@ type Config struct {
 	// InitialHighWater is the timestamp from which new events are guaranteed to
 	// be produced.
 	InitialHighWater hlc.Timestamp
+
+	// InitialScanCheckpoint contains the spans whose initial scan was completed
+	// by a previous run of the feed. They are resolved at the InitialHighWater
+	// and not scanned again.
+	InitialScanCheckpoint []roachpb.Span
+
+	// EndTime, if set, is the exclusive upper bound of the events produced by
+	// the feed. Once all spans have been resolved up to it, the feed stops.
+	EndTime hlc.Timestamp
+
+	// BackfillPendingRanges, if set, tracks the number of ranges which remain
+	// to be scanned by backfills.
+	BackfillPendingRanges *aggmetric.Gauge
 }
 
 // Run will run the kvfeed. The feed runs synchronously and returns an
@@ -77,9 +91,10 @@ func Run(ctx context.Context, cfg Config) error {
 	var sc kvScanner
 	{
 		sc = &scanRequestScanner{
-			settings: cfg.Settings,
-			gossip:   cfg.Gossip,
-			db:       cfg.DB,
+			settings:      cfg.Settings,
+			gossip:        cfg.Gossip,
+			db:            cfg.DB,
+			pendingRanges: cfg.BackfillPendingRanges,
 		}
 	}
 	var pff physicalFeedFactory
@@ -95,7 +110,8 @@ func Run(ctx context.Context, cfg Config) error {
 		cfg.Sink, cfg.Spans,
 		cfg.SchemaChangeEvents, cfg.SchemaChangePolicy,
 		cfg.NeedsInitialScan, cfg.WithDiff,
-		cfg.InitialHighWater,
+		cfg.InitialHighWater, cfg.EndTime,
+		cfg.InitialScanCheckpoint,
 		cfg.Codec,
 		sf, sc, pff, bf)
 	g.GoCtx(f.run)
@@ -109,6 +125,12 @@ func Run(ctx context.Context, cfg Config) error {
 		<-ctx.Done()
 		err = nil
 	}
+	var etErr *errEndTimeReached
+	if errors.As(err, &etErr) {
+		log.Infof(ctx, "stopping changefeed at end time %v", etErr.endTime)
+		<-ctx.Done()
+		err = nil
+	}
 	return err
 }
 
@@ -133,6 +155,8 @@ type kvFeed struct {
 	withDiff            bool
 	withInitialBackfill bool
 	initialHighWater    hlc.Timestamp
+	endTime             hlc.Timestamp
+	checkpoint          []roachpb.Span
 	sink                EventBufferWriter
 	codec               keys.SQLCodec
 
@@ -152,7 +176,8 @@ func newKVFeed(
 	schemaChangeEvents changefeedbase.SchemaChangeEventClass,
 	schemaChangePolicy changefeedbase.SchemaChangePolicy,
 	withInitialBackfill, withDiff bool,
-	initialHighWater hlc.Timestamp,
+	initialHighWater, endTime hlc.Timestamp,
+	checkpoint []roachpb.Span,
 	codec keys.SQLCodec,
 	tf schemaFeed,
 	sc kvScanner,
@@ -165,6 +190,8 @@ func newKVFeed(
 		withInitialBackfill: withInitialBackfill,
 		withDiff:            withDiff,
 		initialHighWater:    initialHighWater,
+		endTime:             endTime,
+		checkpoint:          checkpoint,
 		schemaChangeEvents:  schemaChangeEvents,
 		schemaChangePolicy:  schemaChangePolicy,
 		codec:               codec,
@@ -184,10 +211,16 @@ func (f *kvFeed) run(ctx context.Context) (err error) {
 		if err = f.scanIfShould(ctx, initialScan, highWater); err != nil {
 			return err
 		}
+		if f.endTimeReached(highWater) {
+			return f.resolveEndTime(ctx, highWater)
+		}
 		highWater, err = f.runUntilTableEvent(ctx, highWater)
 		if err != nil {
 			return err
 		}
+		if f.endTimeReached(highWater) {
+			return f.resolveEndTime(ctx, highWater)
+		}
 
 		// Resolve all of the spans as a boundary if the policy indicates that
 		// we should do so.
@@ -205,9 +238,32 @@ func (f *kvFeed) run(ctx context.Context) (err error) {
 	}
 }
 
+// endTimeReached returns true if the feed has an end time and all events
+// before it are known to have been seen at highWater.
+func (f *kvFeed) endTimeReached(highWater hlc.Timestamp) bool {
+	return !f.endTime.IsEmpty() && f.endTime.LessEq(highWater.Next())
+}
+
+// resolveEndTime resolves all of the spans at highWater, the last timestamp
+// before the end time, and returns the sentinel which stops the feed.
+func (f *kvFeed) resolveEndTime(ctx context.Context, highWater hlc.Timestamp) error {
+	for _, span := range f.spans {
+		if err := f.sink.AddResolved(ctx, span, highWater, false); err != nil {
+			return err
+		}
+	}
+	return &errEndTimeReached{endTime: f.endTime}
+}
+
 func (f *kvFeed) scanIfShould(
 	ctx context.Context, initialScan bool, highWater hlc.Timestamp,
 ) error {
+	// Table events at or after the end time are never emitted, so there is
+	// nothing to backfill for them. The initial scan still happens, which is
+	// what makes a feed whose end time immediately follows its start useful.
+	if !(initialScan && f.withInitialBackfill) && f.endTimeReached(highWater) {
+		return nil
+	}
 	scanTime := highWater.Next()
 	events, err := f.tableFeed.Peek(ctx, scanTime)
 	if err != nil {
@@ -222,6 +278,23 @@ func (f *kvFeed) scanIfShould(
 	if isInitialScan {
 		scanTime = highWater
 		spansToBackfill = f.spans
+		if len(f.checkpoint) > 0 {
+			// The spans in the checkpoint were scanned by a previous run of the
+			// feed, so only the remaining spans need to be scanned. The
+			// checkpointed spans are resolved right away so that the feed makes
+			// progress on them.
+			spansToBackfill = roachpb.SubtractSpans(
+				append([]roachpb.Span(nil), f.spans...),
+				append([]roachpb.Span(nil), f.checkpoint...))
+			completed := roachpb.SubtractSpans(
+				append([]roachpb.Span(nil), f.spans...),
+				append([]roachpb.Span(nil), spansToBackfill...))
+			for _, span := range completed {
+				if err := f.sink.AddResolved(ctx, span, scanTime, false); err != nil {
+					return err
+				}
+			}
+		}
 	} else if len(events) > 0 {
 		// Only backfill for the tables which have events which may not be all
 		// of the targets.
@@ -280,7 +353,8 @@ func (f *kvFeed) runUntilTableEvent(
 	g := ctxgroup.WithContext(ctx)
 	physicalCfg := physicalConfig{Spans: f.spans, Timestamp: startFrom, WithDiff: f.withDiff}
 	g.GoCtx(func(ctx context.Context) error {
-		return copyFromSourceToSinkUntilTableEvent(ctx, f.sink, memBuf, physicalCfg, f.tableFeed)
+		return copyFromSourceToSinkUntilTableEvent(
+			ctx, f.sink, memBuf, physicalCfg, f.tableFeed, f.endTime)
 	})
 	g.GoCtx(func(ctx context.Context) error {
 		return f.physicalFeed.Run(ctx, memBuf, physicalCfg)
@@ -294,7 +368,7 @@ func (f *kvFeed) runUntilTableEvent(
 	if err == nil {
 		log.Fatalf(ctx, "feed exited with no error and no scan boundary")
 		return hlc.Timestamp{}, nil // unreachable
-	} else if tErr := (*errBoundaryReached)(nil); errors.As(err, &tErr) {
+	} else if tErr := boundaryError(nil); errors.As(err, &tErr) {
 		// TODO(ajwerner): iterate the spans and add a Resolved timestamp.
 		// We'll need to do this to ensure that a resolved timestamp propagates
 		// when we're trying to exit.
@@ -304,6 +378,14 @@ func (f *kvFeed) runUntilTableEvent(
 	}
 }
 
+// boundaryError is a sentinel error returned by
+// copyFromSourceToSinkUntilTableEvent once all of the spans have been resolved
+// up to the timestamp preceding the boundary.
+type boundaryError interface {
+	error
+	Timestamp() hlc.Timestamp
+}
+
 type errBoundaryReached struct {
 	schemafeed.TableEvent
 }
@@ -312,17 +394,35 @@ func (e *errBoundaryReached) Error() string {
 	return "scan boundary reached: " + e.String()
 }
 
+// errEndTimeReached is the boundaryError corresponding to the end time of the
+// feed. It is also the sentinel which indicates to Run() that the feed has
+// stopped because it reached its end time.
+type errEndTimeReached struct {
+	endTime hlc.Timestamp
+}
+
+func (e *errEndTimeReached) Error() string {
+	return fmt.Sprintf("end time %v reached", e.endTime)
+}
+
+func (e *errEndTimeReached) Timestamp() hlc.Timestamp {
+	return e.endTime
+}
+
 // copyFromSourceToSinkUntilTableEvents will pull read entries from source and
 // publish them to sink if there is no table event from the schemaFeed. If a
 // tableEvent occurs then the function will return once all of the spans have
 // been resolved up to the event. The first such event will be returned as
-// *errBoundaryReached. A nil error will never be returned.
+// *errBoundaryReached. If endTime is set and no table event precedes it, the
+// function instead returns *errEndTimeReached once all of the spans have been
+// resolved up to the end time. A nil error will never be returned.
 func copyFromSourceToSinkUntilTableEvent(
 	ctx context.Context,
 	sink EventBufferWriter,
 	source EventBufferReader,
 	cfg physicalConfig,
 	tables schemaFeed,
+	endTime hlc.Timestamp,
 ) error {
 	// Maintain a local spanfrontier to tell when all the component rangefeeds
 	// being watched have reached the Scan boundary.
@@ -331,11 +431,17 @@ func copyFromSourceToSinkUntilTableEvent(
 		frontier.Forward(span, cfg.Timestamp)
 	}
 	var (
-		scanBoundary         *errBoundaryReached
+		scanBoundary         boundaryError
 		checkForScanBoundary = func(ts hlc.Timestamp) error {
-			if scanBoundary != nil {
+			// A table event is always the earliest boundary as they are found
+			// in timestamp order.
+			if _, ok := scanBoundary.(*errBoundaryReached); ok {
 				return nil
 			}
+			// Table events at or after the end time are never reached.
+			if !endTime.IsEmpty() && endTime.LessEq(ts) {
+				ts = endTime.Prev()
+			}
 			nextEvents, err := tables.Peek(ctx, ts)
 			if err != nil {
 				return err
@@ -386,6 +492,9 @@ func copyFromSourceToSinkUntilTableEvent(
 			}
 		}
 	)
+	if !endTime.IsEmpty() {
+		scanBoundary = &errEndTimeReached{endTime: endTime}
+	}
 	for {
 		e, err := source.Get(ctx)
 		if err != nil {
@@ -80,14 +80,17 @@ func TestKVFeed(t *testing.T) {
 		schemaChangeEvents changefeedbase.SchemaChangeEventClass
 		schemaChangePolicy changefeedbase.SchemaChangePolicy
 		initialHighWater   hlc.Timestamp
+		endTime            hlc.Timestamp
 		spans              []roachpb.Span
+		checkpoint         []roachpb.Span
 		events             []roachpb.RangeFeedEvent
 
 		descs []*tabledesc.Immutable
 
-		expScans  []hlc.Timestamp
-		expEvents int
-		expErrRE  string
+		expScans     []hlc.Timestamp
+		expScanSpans [][]roachpb.Span
+		expEvents    int
+		expErrRE     string
 	}
 	runTest := func(t *testing.T, tc testCase) {
 		settings := cluster.MakeTestingClusterSettings()
@@ -114,7 +117,8 @@ func TestKVFeed(t *testing.T) {
 		f := newKVFeed(buf, tc.spans,
 			tc.schemaChangeEvents, tc.schemaChangePolicy,
 			tc.needsInitialScan, tc.withDiff,
-			tc.initialHighWater,
+			tc.initialHighWater, tc.endTime,
+			tc.checkpoint,
 			keys.SystemSQLCodec,
 			&tf, sf, rangefeedFactory(ref.run), bufferFactory)
 		ctx, cancel := context.WithCancel(context.Background())
@@ -124,10 +128,13 @@ func TestKVFeed(t *testing.T) {
 		})
 		testG := ctxgroup.WithContext(ctx)
 		testG.GoCtx(func(ctx context.Context) error {
-			for expScans := tc.expScans; len(expScans) > 0; expScans = expScans[1:] {
+			for i, expScan := range tc.expScans {
 				scan := <-scans
-				assert.Equal(t, expScans[0], scan.Timestamp)
+				assert.Equal(t, expScan, scan.Timestamp)
 				assert.Equal(t, tc.withDiff, scan.WithDiff)
+				if i < len(tc.expScanSpans) {
+					assert.Equal(t, tc.expScanSpans[i], scan.Spans)
+				}
 			}
 			return nil
 		})
@@ -139,7 +146,7 @@ func TestKVFeed(t *testing.T) {
 			return nil
 		})
 		// Wait for the feed to fail rather than canceling it.
-		if tc.schemaChangePolicy == changefeedbase.OptSchemaChangePolicyStop {
+		if tc.schemaChangePolicy == changefeedbase.OptSchemaChangePolicyStop || !tc.endTime.IsEmpty() {
 			testG.Go(func() error {
 				_ = g.Wait()
 				return nil
@@ -173,6 +180,31 @@ func TestKVFeed(t *testing.T) {
 			},
 			expEvents: 1,
 		},
+		{
+			name:               "no events - backfill with checkpoint",
+			schemaChangeEvents: changefeedbase.OptSchemaChangeEventClassDefault,
+			schemaChangePolicy: changefeedbase.OptSchemaChangePolicyBackfill,
+			needsInitialScan:   true,
+			initialHighWater:   ts(2),
+			spans: []roachpb.Span{
+				tableSpan(42),
+				tableSpan(43),
+			},
+			checkpoint: []roachpb.Span{
+				tableSpan(42),
+			},
+			events: []roachpb.RangeFeedEvent{
+				kvEvent(43, "a", "b", ts(3)),
+			},
+			expScans: []hlc.Timestamp{
+				ts(2),
+			},
+			expScanSpans: [][]roachpb.Span{
+				{tableSpan(43)},
+			},
+			// The resolved checkpointed span and the kv.
+			expEvents: 2,
+		},
 		{
 			name:               "one table event - backfill",
 			schemaChangeEvents: changefeedbase.OptSchemaChangeEventClassDefault,
@@ -249,6 +281,55 @@ func TestKVFeed(t *testing.T) {
 			expEvents: 2,
 			expErrRE:  "schema change ...",
 		},
+		{
+			name:               "end time",
+			schemaChangeEvents: changefeedbase.OptSchemaChangeEventClassDefault,
+			schemaChangePolicy: changefeedbase.OptSchemaChangePolicyBackfill,
+			needsInitialScan:   true,
+			initialHighWater:   ts(2),
+			endTime:            ts(5),
+			spans: []roachpb.Span{
+				tableSpan(42),
+			},
+			events: []roachpb.RangeFeedEvent{
+				kvEvent(42, "a", "b", ts(3)),
+				checkpointEvent(tableSpan(42), ts(4)),
+				kvEvent(42, "a", "b", ts(5)), // at the end time, so not emitted
+				checkpointEvent(tableSpan(42), ts(6)),
+			},
+			expScans: []hlc.Timestamp{
+				ts(2),
+			},
+			descs: []*tabledesc.Immutable{
+				makeTableDesc(42, 1, ts(1), 2),
+				// A schema change at the end time does not cause a backfill.
+				addColumnDropBackfillMutation(makeTableDesc(42, 2, ts(5), 1)),
+			},
+			expEvents: 3,
+			expErrRE:  "end time .* reached",
+		},
+		{
+			name:               "end time - initial scan only",
+			schemaChangeEvents: changefeedbase.OptSchemaChangeEventClassDefault,
+			schemaChangePolicy: changefeedbase.OptSchemaChangePolicyBackfill,
+			needsInitialScan:   true,
+			initialHighWater:   ts(2),
+			endTime:            ts(2).Next(),
+			spans: []roachpb.Span{
+				tableSpan(42),
+			},
+			events: []roachpb.RangeFeedEvent{
+				kvEvent(42, "a", "b", ts(3)),
+			},
+			expScans: []hlc.Timestamp{
+				ts(2),
+			},
+			descs: []*tabledesc.Immutable{
+				makeTableDesc(42, 1, ts(1), 2),
+			},
+			expEvents: 1,
+			expErrRE:  "end time .* reached",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			runTest(t, tc)
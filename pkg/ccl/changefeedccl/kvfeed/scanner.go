@@ -13,17 +13,20 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/covering"
 	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 )
@@ -38,6 +41,9 @@ type scanRequestScanner struct {
 	settings *cluster.Settings
 	gossip   gossip.OptionalGossip
 	db       *kv.DB
+	// pendingRanges, if non-nil, tracks the number of ranges remaining to be
+	// scanned.
+	pendingRanges *aggmetric.Gauge
 }
 
 var _ kvScanner = (*scanRequestScanner)(nil)
@@ -58,30 +64,38 @@ func (p *scanRequestScanner) Scan(
 	// Export requests for the various watched spans are executed in parallel,
 	// with a semaphore-enforced limit based on a cluster setting.
 	// The spans here generally correspond with range boundaries.
-	approxNodeCount, err := clusterNodeCount(p.gossip)
+	maxConcurrentScans, err := maxConcurrentScanRequests(p.gossip, &p.settings.SV)
 	if err != nil {
 		return err
 	}
-	maxConcurrentExports := approxNodeCount *
-		int(kvserver.ExportRequestsLimit.Get(&p.settings.SV))
-	exportsSem := make(chan struct{}, maxConcurrentExports)
+	exportsSem := make(chan struct{}, maxConcurrentScans)
 	g := ctxgroup.WithContext(ctx)
 
 	// atomicFinished is used only to enhance debugging messages.
 	var atomicFinished int64
 
-	for _, span := range spans {
+	if p.pendingRanges != nil {
+		p.pendingRanges.Inc(int64(len(spans)))
+	}
+	for i, span := range spans {
 		span := span
 
 		// Wait for our semaphore.
 		select {
 		case <-ctx.Done():
+			if p.pendingRanges != nil {
+				// The spans which were not started are no longer pending.
+				p.pendingRanges.Dec(int64(len(spans) - i))
+			}
 			return ctx.Err()
 		case exportsSem <- struct{}{}:
 		}
 
 		g.GoCtx(func(ctx context.Context) error {
 			defer func() { <-exportsSem }()
+			if p.pendingRanges != nil {
+				defer p.pendingRanges.Dec(1)
+			}
 
 			err := p.exportSpan(ctx, span, cfg.Timestamp, cfg.WithDiff, sink)
 			finished := atomic.AddInt64(&atomicFinished, 1)
@@ -97,6 +111,21 @@ func (p *scanRequestScanner) Scan(
 	return g.Wait()
 }
 
+// maxConcurrentScanRequests returns the number of spans which may be scanned
+// at once by a single backfill.
+func maxConcurrentScanRequests(gossip gossip.OptionalGossip, sv *settings.Values) (int, error) {
+	if limit := changefeedbase.ScanRequestLimit.Get(sv); limit > 0 {
+		return int(limit), nil
+	}
+	// If the setting is not specified, derive the limit from the number of
+	// nodes in the cluster, as was done before the setting existed.
+	approxNodeCount, err := clusterNodeCount(gossip)
+	if err != nil {
+		return 0, err
+	}
+	return approxNodeCount * int(kvserver.ExportRequestsLimit.Get(sv)), nil
+}
+
 func (p *scanRequestScanner) exportSpan(
 	ctx context.Context, span roachpb.Span, ts hlc.Timestamp, withDiff bool, sink EventBufferWriter,
 ) error {
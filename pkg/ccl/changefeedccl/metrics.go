@@ -12,39 +12,48 @@ import (
 	"context"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/kvfeed"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 type metricsSink struct {
 	metrics *Metrics
+	scoped  *scopedMetrics
 	wrapped Sink
 }
 
-func makeMetricsSink(metrics *Metrics, s Sink) *metricsSink {
+func makeMetricsSink(metrics *Metrics, scoped *scopedMetrics, s Sink) *metricsSink {
 	m := &metricsSink{
 		metrics: metrics,
+		scoped:  scoped,
 		wrapped: s,
 	}
 	return m
 }
 
 func (s *metricsSink) EmitRow(
-	ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp,
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
 ) error {
 	start := timeutil.Now()
-	err := s.wrapped.EmitRow(ctx, table, key, value, updated)
-	if err == nil {
-		s.metrics.EmittedMessages.Inc(1)
-		s.metrics.EmittedBytes.Inc(int64(len(key) + len(value)))
-		s.metrics.EmitNanos.Inc(timeutil.Since(start).Nanoseconds())
+	err := s.wrapped.EmitRow(ctx, topic, key, value, updated)
+	if err != nil {
+		s.scoped.SinkErrors.Inc(1)
+		return err
 	}
-	return err
+	s.scoped.EmittedMessages.Inc(1)
+	s.scoped.EmittedBytes.Inc(int64(len(key) + len(value)))
+	s.scoped.CommitLatency.RecordValue(timeutil.Since(updated.GoTime()).Nanoseconds())
+	s.metrics.EmitNanos.Inc(timeutil.Since(start).Nanoseconds())
+	return nil
 }
 
 func (s *metricsSink) EmitResolvedTimestamp(
@@ -52,30 +61,38 @@ func (s *metricsSink) EmitResolvedTimestamp(
 ) error {
 	start := timeutil.Now()
 	err := s.wrapped.EmitResolvedTimestamp(ctx, encoder, resolved)
-	if err == nil {
-		s.metrics.EmittedMessages.Inc(1)
-		// TODO(dan): This wasn't correct. The wrapped sink may emit the payload
-		// any number of times.
-		// s.metrics.EmittedBytes.Inc(int64(len(payload)))
-		s.metrics.EmitNanos.Inc(timeutil.Since(start).Nanoseconds())
-	}
-	return err
+	if err != nil {
+		s.scoped.SinkErrors.Inc(1)
+		return err
+	}
+	s.scoped.EmittedMessages.Inc(1)
+	// TODO(dan): This wasn't correct. The wrapped sink may emit the payload
+	// any number of times.
+	// s.scoped.EmittedBytes.Inc(int64(len(payload)))
+	s.metrics.EmitNanos.Inc(timeutil.Since(start).Nanoseconds())
+	return nil
 }
 
 func (s *metricsSink) Flush(ctx context.Context) error {
 	start := timeutil.Now()
 	err := s.wrapped.Flush(ctx)
-	if err == nil {
-		s.metrics.Flushes.Inc(1)
-		s.metrics.FlushNanos.Inc(timeutil.Since(start).Nanoseconds())
+	if err != nil {
+		s.scoped.SinkErrors.Inc(1)
+		return err
 	}
-	return err
+	s.metrics.Flushes.Inc(1)
+	s.metrics.FlushNanos.Inc(timeutil.Since(start).Nanoseconds())
+	return nil
 }
 
 func (s *metricsSink) Close() error {
 	return s.wrapped.Close()
 }
 
+// commitLatencyMaxValue is the largest latency tracked by the commit latency
+// histogram. Larger latencies are recorded as this value.
+const commitLatencyMaxValue = 10 * time.Minute
+
 var (
 	metaChangefeedEmittedMessages = metric.Metadata{
 		Name:        "changefeed.emitted_messages",
@@ -89,6 +106,25 @@ var (
 		Measurement: "Bytes",
 		Unit:        metric.Unit_BYTES,
 	}
+	metaChangefeedSinkErrors = metric.Metadata{
+		Name:        "changefeed.sink_errors",
+		Help:        "Errors returned by the sinks of all feeds",
+		Measurement: "Errors",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaChangefeedBackfillPendingRanges = metric.Metadata{
+		Name:        "changefeed.backfill_pending_ranges",
+		Help:        "Number of ranges remaining to be scanned by the backfills of all feeds",
+		Measurement: "Ranges",
+		Unit:        metric.Unit_COUNT,
+	}
+	metaChangefeedCommitLatency = metric.Metadata{
+		Name: "changefeed.commit_latency",
+		Help: "Time between the commit of a change and its emission to the sink. " +
+			"For backfilled rows, the time since the timestamp of the backfill.",
+		Measurement: "Latency",
+		Unit:        metric.Unit_NANOSECONDS,
+	}
 	metaChangefeedFlushes = metric.Metadata{
 		Name:        "changefeed.flushes",
 		Help:        "Total flushes across all feeds",
@@ -151,14 +187,26 @@ var (
 	}
 )
 
+// defaultMetricsScope is the scope of the metrics of changefeeds without a
+// metrics_label.
+const defaultMetricsScope = `default`
+
 // Metrics are for production monitoring of changefeeds.
+//
+// The metrics of emitted messages, sink errors, backfills and commit latency
+// are additionally broken down by changefeed into scopes, named by the
+// metrics_label option. Each scope is exported to prometheus with a `scope`
+// label if server.child_metrics.enabled is set.
 type Metrics struct {
-	KVFeedMetrics   kvfeed.Metrics
-	EmittedMessages *metric.Counter
-	EmittedBytes    *metric.Counter
-	Flushes         *metric.Counter
-	ErrorRetries    *metric.Counter
-	Failures        *metric.Counter
+	KVFeedMetrics         kvfeed.Metrics
+	EmittedMessages       *aggmetric.AggCounter
+	EmittedBytes          *aggmetric.AggCounter
+	SinkErrors            *aggmetric.AggCounter
+	BackfillPendingRanges *aggmetric.AggGauge
+	CommitLatency         *aggmetric.AggHistogram
+	Flushes               *metric.Counter
+	ErrorRetries          *metric.Counter
+	Failures              *metric.Counter
 
 	ProcessingNanos    *metric.Counter
 	TableMetadataNanos *metric.Counter
@@ -171,22 +219,115 @@ type Metrics struct {
 		syncutil.Mutex
 		id       int
 		resolved map[int]hlc.Timestamp
+		scopes   map[string]*scopedMetrics
 	}
 	MaxBehindNanos *metric.Gauge
 }
 
+// scopedMetrics are the metrics of the changefeeds with the same
+// metrics_label. Recording a value in them also records it in the aggregate
+// metrics of all changefeeds.
+type scopedMetrics struct {
+	EmittedMessages       *aggmetric.Counter
+	EmittedBytes          *aggmetric.Counter
+	SinkErrors            *aggmetric.Counter
+	BackfillPendingRanges *aggmetric.Gauge
+	CommitLatency         *aggmetric.Histogram
+
+	// name and refs are protected by Metrics.mu. refs is the number of
+	// processors using the scope.
+	name string
+	refs int
+}
+
 // MetricStruct implements the metric.Struct interface.
 func (*Metrics) MetricStruct() {}
 
+// getOrCreateScope returns the metrics of the changefeeds with the given
+// metrics_label, creating them if this is the first such changefeed on this
+// node. The number of scopes is capped by changefeed.max_metrics_scopes. Every
+// call must be paired with a call to releaseScope once the caller is done with
+// the scope.
+func (m *Metrics) getOrCreateScope(sv *settings.Values, scope string) (*scopedMetrics, error) {
+	if scope == `` {
+		scope = defaultMetricsScope
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkScopeLocked(sv, scope); err != nil {
+		return nil, err
+	}
+	scoped, ok := m.mu.scopes[scope]
+	if !ok {
+		scoped = &scopedMetrics{
+			EmittedMessages:       m.EmittedMessages.AddChild(scope),
+			EmittedBytes:          m.EmittedBytes.AddChild(scope),
+			SinkErrors:            m.SinkErrors.AddChild(scope),
+			BackfillPendingRanges: m.BackfillPendingRanges.AddChild(scope),
+			CommitLatency:         m.CommitLatency.AddChild(scope),
+			name:                  scope,
+		}
+		m.mu.scopes[scope] = scoped
+	}
+	scoped.refs++
+	return scoped, nil
+}
+
+// releaseScope releases a scope returned by getOrCreateScope. Once the last
+// changefeed using a scope on this node is done, the scope is removed, so that
+// it no longer counts towards changefeed.max_metrics_scopes.
+func (m *Metrics) releaseScope(scoped *scopedMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scoped.refs--
+	if scoped.refs > 0 {
+		return
+	}
+	delete(m.mu.scopes, scoped.name)
+	scoped.EmittedMessages.Destroy()
+	scoped.EmittedBytes.Destroy()
+	scoped.SinkErrors.Destroy()
+	scoped.BackfillPendingRanges.Destroy()
+	scoped.CommitLatency.Destroy()
+}
+
+// checkScope returns an error if a changefeed with the given metrics_label
+// would exceed changefeed.max_metrics_scopes, without creating the scope.
+func (m *Metrics) checkScope(sv *settings.Values, scope string) error {
+	if scope == `` {
+		scope = defaultMetricsScope
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkScopeLocked(sv, scope)
+}
+
+func (m *Metrics) checkScopeLocked(sv *settings.Values, scope string) error {
+	if _, ok := m.mu.scopes[scope]; ok {
+		return nil
+	}
+	if max := changefeedbase.MaxMetricsScopes.Get(sv); int64(len(m.mu.scopes)) >= max {
+		return pgerror.Newf(pgcode.ConfigurationLimitExceeded,
+			`too many distinct %s values; the maximum is %d (see changefeed.max_metrics_scopes)`,
+			changefeedbase.OptMetricsLabel, max)
+	}
+	return nil
+}
+
 // MakeMetrics makes the metrics for changefeed monitoring.
 func MakeMetrics(histogramWindow time.Duration) metric.Struct {
+	b := aggmetric.MakeBuilder(`scope`)
 	m := &Metrics{
-		KVFeedMetrics:   kvfeed.MakeMetrics(histogramWindow),
-		EmittedMessages: metric.NewCounter(metaChangefeedEmittedMessages),
-		EmittedBytes:    metric.NewCounter(metaChangefeedEmittedBytes),
-		Flushes:         metric.NewCounter(metaChangefeedFlushes),
-		ErrorRetries:    metric.NewCounter(metaChangefeedErrorRetries),
-		Failures:        metric.NewCounter(metaChangefeedFailures),
+		KVFeedMetrics:         kvfeed.MakeMetrics(histogramWindow),
+		EmittedMessages:       b.Counter(metaChangefeedEmittedMessages),
+		EmittedBytes:          b.Counter(metaChangefeedEmittedBytes),
+		SinkErrors:            b.Counter(metaChangefeedSinkErrors),
+		BackfillPendingRanges: b.Gauge(metaChangefeedBackfillPendingRanges),
+		CommitLatency: b.Histogram(metaChangefeedCommitLatency, histogramWindow,
+			commitLatencyMaxValue.Nanoseconds(), 1),
+		Flushes:      metric.NewCounter(metaChangefeedFlushes),
+		ErrorRetries: metric.NewCounter(metaChangefeedErrorRetries),
+		Failures:     metric.NewCounter(metaChangefeedFailures),
 
 		ProcessingNanos:    metric.NewCounter(metaChangefeedProcessingNanos),
 		TableMetadataNanos: metric.NewCounter(metaChangefeedTableMetadataNanos),
@@ -195,6 +336,7 @@ func MakeMetrics(histogramWindow time.Duration) metric.Struct {
 		Running:            metric.NewGauge(metaChangefeedRunning),
 	}
 	m.mu.resolved = make(map[int]hlc.Timestamp)
+	m.mu.scopes = make(map[string]*scopedMetrics)
 	m.mu.id = 1 // start the first id at 1 so we can detect initialization
 	m.MaxBehindNanos = metric.NewFunctionalGauge(metaChangefeedMaxBehindNanos, func() int64 {
 		now := timeutil.Now()
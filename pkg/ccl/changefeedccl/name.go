@@ -14,6 +14,13 @@ import (
 	"strconv"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
 )
 
 var escapeRE = regexp.MustCompile(`_u[0-9a-fA-F]{2,8}_`)
@@ -115,3 +122,188 @@ func unescapeSQLName(s string) string {
 	})
 	return s
 }
+
+// topicDescriptor identifies the topic a row is emitted to.
+type topicDescriptor struct {
+	// table is the descriptor of the table containing the row.
+	table catalog.TableDescriptor
+	// name is the expansion of the topic_template or path_template for the row.
+	// It is empty if neither option is in use, in which case the topic is named
+	// after the table.
+	name string
+}
+
+// Placeholders supported by the topic_template and path_template options.
+const (
+	templateDatabase  = `database`
+	templateSchema    = `schema`
+	templateTable     = `table`
+	templateFamily    = `family`
+	templateKeyPrefix = `key.`
+)
+
+// nameTemplate is a parsed topic_template or path_template. It is literal text
+// interspersed with placeholders in braces. `{database}`, `{schema}`,
+// `{table}` and `{family}` are replaced by the names of the row's database,
+// schema, table and column family and `{key.<column>}` by the value of one of
+// the primary key columns of the row. Each substituted value is escaped with
+// SQLNameToKafkaName, so it is always a valid kafka topic name and never
+// contains a path separator.
+type nameTemplate struct {
+	// opt is the option the template was specified with, for error messages.
+	opt   string
+	parts []nameTemplatePart
+
+	alloc rowenc.DatumAlloc
+	buf   strings.Builder
+}
+
+type nameTemplatePart struct {
+	// literal is the text of a literal part. It is empty for placeholders.
+	literal string
+	// placeholder is the contents of the braces of a placeholder.
+	placeholder string
+}
+
+// nameTemplateFromOptions parses the topic_template or path_template of a
+// changefeed. It returns nil if neither was specified.
+func nameTemplateFromOptions(opts map[string]string) (*nameTemplate, error) {
+	for _, opt := range []string{changefeedbase.OptTopicTemplate, changefeedbase.OptPathTemplate} {
+		if tmpl, ok := opts[opt]; ok {
+			return parseNameTemplate(opt, tmpl)
+		}
+	}
+	return nil, nil
+}
+
+func parseNameTemplate(opt, tmpl string) (*nameTemplate, error) {
+	t := &nameTemplate{opt: opt}
+	for rest := tmpl; len(rest) > 0; {
+		start := strings.IndexAny(rest, `{}`)
+		if start == -1 {
+			t.parts = append(t.parts, nameTemplatePart{literal: rest})
+			break
+		}
+		if rest[start] == '}' {
+			return nil, errors.Errorf(`unmatched } in %s: %s`, opt, tmpl)
+		}
+		if start > 0 {
+			t.parts = append(t.parts, nameTemplatePart{literal: rest[:start]})
+		}
+		rest = rest[start+1:]
+		end := strings.IndexAny(rest, `{}`)
+		if end == -1 || rest[end] == '{' {
+			return nil, errors.Errorf(`unmatched { in %s: %s`, opt, tmpl)
+		}
+		placeholder := rest[:end]
+		switch {
+		case placeholder == templateDatabase, placeholder == templateSchema,
+			placeholder == templateTable, placeholder == templateFamily:
+		case strings.HasPrefix(placeholder, templateKeyPrefix) &&
+			len(placeholder) > len(templateKeyPrefix):
+		default:
+			return nil, errors.Errorf(`unknown placeholder {%s} in %s: %s`, placeholder, opt, tmpl)
+		}
+		t.parts = append(t.parts, nameTemplatePart{placeholder: placeholder})
+		rest = rest[end+1:]
+	}
+	if len(t.parts) == 0 {
+		return nil, errors.Errorf(`%s must not be empty`, opt)
+	}
+
+	for _, part := range t.parts {
+		if part.literal == `` {
+			continue
+		}
+		switch opt {
+		case changefeedbase.OptTopicTemplate:
+			if kafkaDisallowedRE.MatchString(part.literal) {
+				return nil, errors.Errorf(
+					`%s may only contain the characters [a-zA-Z0-9._-] outside of placeholders: %s`,
+					opt, tmpl)
+			}
+		case changefeedbase.OptPathTemplate:
+			for _, dir := range strings.Split(part.literal, `/`) {
+				if dir == `.` || dir == `..` {
+					return nil, errors.Errorf(`%s must not contain relative path elements: %s`, opt, tmpl)
+				}
+			}
+		}
+	}
+	if opt == changefeedbase.OptPathTemplate && strings.HasPrefix(tmpl, `/`) {
+		return nil, errors.Errorf(`%s must be a relative path: %s`, opt, tmpl)
+	}
+	return t, nil
+}
+
+// validate checks that every key column referenced by the template is part of
+// the primary key of the given table.
+func (t *nameTemplate) validate(table catalog.TableDescriptor) error {
+	for _, part := range t.parts {
+		if !strings.HasPrefix(part.placeholder, templateKeyPrefix) {
+			continue
+		}
+		if _, err := primaryKeyColumnIdx(table, part.placeholder); err != nil {
+			return errors.Wrapf(err, `invalid %s`, t.opt)
+		}
+	}
+	return nil
+}
+
+// primaryKeyColumnIdx returns the index in the table's columns of the primary
+// key column referenced by a `{key.<column>}` placeholder.
+func primaryKeyColumnIdx(table catalog.TableDescriptor, placeholder string) (int, error) {
+	name := strings.TrimPrefix(placeholder, templateKeyPrefix)
+	primaryIndex := table.GetPrimaryIndex()
+	colIdxByID := table.ColumnIdxMap()
+	for i, colName := range primaryIndex.ColumnNames {
+		if colName != name {
+			continue
+		}
+		if idx, ok := colIdxByID.Get(primaryIndex.ColumnIDs[i]); ok {
+			return idx, nil
+		}
+	}
+	return 0, errors.Errorf(`column %s is not part of the primary key of table %s`,
+		name, table.GetName())
+}
+
+// expand returns the name the template expands to for a row of the given
+// target.
+func (t *nameTemplate) expand(target jobspb.ChangefeedTarget, row encodeRow) (string, error) {
+	t.buf.Reset()
+	for _, part := range t.parts {
+		var value string
+		switch part.placeholder {
+		case ``:
+			t.buf.WriteString(part.literal)
+			continue
+		case templateDatabase:
+			value = target.StatementTimeDatabaseName
+		case templateSchema:
+			value = target.StatementTimeSchemaName
+		case templateTable:
+			value = row.tableDesc.GetName()
+		case templateFamily:
+			// Changefeeds only support tables with exactly one column family.
+			value = row.tableDesc.GetFamilies()[0].Name
+		default:
+			idx, err := primaryKeyColumnIdx(row.tableDesc, part.placeholder)
+			if err != nil {
+				return ``, err
+			}
+			datum := row.datums[idx]
+			if err := datum.EnsureDecoded(row.tableDesc.GetColumnAtIdx(idx).Type, &t.alloc); err != nil {
+				return ``, err
+			}
+			if s, ok := tree.AsDString(datum.Datum); ok {
+				// Formatting would quote and escape non-ASCII characters.
+				value = string(s)
+			} else {
+				value = tree.AsStringWithFlags(datum.Datum, tree.FmtBareStrings)
+			}
+		}
+		t.buf.WriteString(SQLNameToKafkaName(value))
+	}
+	return t.buf.String(), nil
+}
@@ -12,6 +12,8 @@ import (
 	"testing"
 	"unicode/utf8"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/stretchr/testify/require"
@@ -91,3 +93,107 @@ func TestSQLNameToAvroName(t *testing.T) {
 	// We don't produce capital letters in escapes but check them anyway.
 	require.Equal(t, `/`, KafkaNameToSQLName(`_u2F_`))
 }
+
+func TestNameTemplate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tableDesc, err := parseTableDesc(
+		`CREATE TABLE "fo/o" (a INT, b STRING, c STRING, PRIMARY KEY (b, a), FAMILY f (a, b, c))`)
+	require.NoError(t, err)
+	rows, err := parseValues(tableDesc, `VALUES (1, 'x/y', 'z'), (2, '☃', NULL)`)
+	require.NoError(t, err)
+	target := jobspb.ChangefeedTarget{
+		StatementTimeName:         `d.public.fo/o`,
+		StatementTimeDatabaseName: `d`,
+		StatementTimeSchemaName:   `public`,
+	}
+
+	tests := []struct {
+		opt, tmpl string
+		expected  []string
+		err       string
+	}{
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `{table}`,
+			expected: []string{`fo_u002f_o`, `fo_u002f_o`},
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `cdc.{database}.{schema}.{table}.{family}`,
+			expected: []string{`cdc.d.public.fo_u002f_o.f`, `cdc.d.public.fo_u002f_o.f`},
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `{table}-{key.a}-{key.b}`,
+			expected: []string{`fo_u002f_o-1-x_u002f_y`, `fo_u002f_o-2-_u2603_`},
+		},
+		{
+			opt: changefeedbase.OptPathTemplate, tmpl: `{database}/{table}/b={key.b}`,
+			expected: []string{`d/fo_u002f_o/b=x_u002f_y`, `d/fo_u002f_o/b=_u2603_`},
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: ``,
+			err: `topic_template must not be empty`,
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `{table`,
+			err: `unmatched { in topic_template: {table`,
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `{{table}}`,
+			err: `unmatched { in topic_template: {{table}}`,
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `table}`,
+			err: `unmatched } in topic_template: table}`,
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `{tabel}`,
+			err: `unknown placeholder {tabel} in topic_template: {tabel}`,
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `{key.}`,
+			err: `unknown placeholder {key.} in topic_template: {key.}`,
+		},
+		{
+			opt: changefeedbase.OptTopicTemplate, tmpl: `cdc/{table}`,
+			err: `topic_template may only contain the characters [a-zA-Z0-9._-] outside of placeholders`,
+		},
+		{
+			opt: changefeedbase.OptPathTemplate, tmpl: `../{table}`,
+			err: `path_template must not contain relative path elements: ../{table}`,
+		},
+		{
+			opt: changefeedbase.OptPathTemplate, tmpl: `/{table}`,
+			err: `path_template must be a relative path: /{table}`,
+		},
+		{
+			opt: changefeedbase.OptPathTemplate, tmpl: `{key.c}`,
+			err: `invalid path_template: column c is not part of the primary key of table fo/o`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.opt+`=`+test.tmpl, func(t *testing.T) {
+			tmpl, err := nameTemplateFromOptions(map[string]string{test.opt: test.tmpl})
+			if err == nil {
+				err = tmpl.validate(tableDesc)
+			}
+			if test.err != `` {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			var actual []string
+			for _, row := range rows {
+				name, err := tmpl.expand(target, encodeRow{datums: row, tableDesc: tableDesc})
+				require.NoError(t, err)
+				actual = append(actual, name)
+			}
+			require.Equal(t, test.expected, actual)
+		})
+	}
+
+	tmpl, err := nameTemplateFromOptions(map[string]string{})
+	require.NoError(t, err)
+	require.Nil(t, tmpl)
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl/parquetccl"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/errors"
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+)
+
+// The file contains the mapping between our SQL schemas and Parquet. Like
+// avro.go, it's not intended to be a general purpose Parquet utility.
+//
+// Parquet is a columnar file format, so unlike JSON and Avro a single row
+// cannot be encoded on its own: rows are buffered per file and written out as
+// a single row group when the file is flushed. The parquetEncoder therefore
+// only serializes each row into an intermediate, self-describing form (one
+// value-encoded datum per column) which the cloud storage sink hands to a
+// parquetFileWriter.
+//
+// Each SQL column maps to an optional Parquet column of the same name, whose
+// type is determined by parquetccl. Two metadata columns are prepended:
+// `__crdb__deleted`, which is true for deletions (in which case only the
+// primary key columns are set), and, when the `updated` option is used,
+// `__crdb__updated`.
+
+const (
+	parquetDeletedColumn = `__crdb__deleted`
+	parquetUpdatedColumn = `__crdb__updated`
+)
+
+// parquetColumn describes how a single SQL column is written to a Parquet
+// file.
+type parquetColumn struct {
+	name     string
+	typ      *types.T
+	def      *parquetschema.ColumnDefinition
+	encodeFn parquetccl.EncodeFn
+}
+
+// parquetTableSchema is the Parquet schema of a table (at a specific
+// descriptor version) as written by a changefeed.
+type parquetTableSchema struct {
+	updatedField bool
+	cols         []parquetColumn
+	def          *parquetschema.SchemaDefinition
+}
+
+// tableToParquetSchema returns the Parquet schema for the public columns of
+// the given table descriptor.
+func tableToParquetSchema(
+	tableDesc catalog.TableDescriptor, updatedField bool,
+) (*parquetTableSchema, error) {
+	s := &parquetTableSchema{updatedField: updatedField}
+	root := &parquetschema.ColumnDefinition{
+		SchemaElement: &parquet.SchemaElement{Name: tableDesc.GetName()},
+	}
+	root.Children = append(root.Children, &parquetschema.ColumnDefinition{
+		SchemaElement: &parquet.SchemaElement{
+			Name:           parquetDeletedColumn,
+			Type:           parquet.TypePtr(parquet.Type_BOOLEAN),
+			RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_REQUIRED),
+		},
+	})
+	if updatedField {
+		root.Children = append(root.Children, parquetccl.Leaf(parquetUpdatedColumn, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{STRING: &parquet.StringType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8)))
+	}
+	for _, col := range tableDesc.GetPublicColumns() {
+		def, encodeFn, err := parquetccl.ColumnDefinition(col.Name, col.Type)
+		if err != nil {
+			return nil, err
+		}
+		s.cols = append(s.cols, parquetColumn{
+			name:     col.Name,
+			typ:      col.Type,
+			def:      def,
+			encodeFn: encodeFn,
+		})
+		root.Children = append(root.Children, def)
+	}
+	s.def = parquetschema.SchemaDefinitionFromColumnDefinition(root)
+	if err := s.def.ValidateStrict(); err != nil {
+		return nil, errors.Wrapf(err, `table %s`, tableDesc.GetName())
+	}
+	return s, nil
+}
+
+// encodeParquetRow serializes a row into the intermediate form read back by
+// parquetFileWriter.addRow: the deleted flag, the updated timestamp (if
+// requested) and then one value-encoded datum per public column.
+func encodeParquetRow(
+	appendTo []byte, row encodeRow, updatedField bool, alloc *rowenc.DatumAlloc, scratch []byte,
+) ([]byte, error) {
+	var err error
+	appendTo, err = rowenc.EncodeTableValue(
+		appendTo, descpb.ColumnID(encoding.NoColumnID), tree.MakeDBool(tree.DBool(row.deleted)), scratch)
+	if err != nil {
+		return nil, err
+	}
+	if updatedField {
+		appendTo, err = rowenc.EncodeTableValue(
+			appendTo, descpb.ColumnID(encoding.NoColumnID), tree.NewDString(row.updated.AsOfSystemTime()), scratch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var pkCols catalog.TableColSet
+	if row.deleted {
+		pkCols = catalog.MakeTableColSet(row.tableDesc.GetPrimaryIndex().ColumnIDs...)
+	}
+	columns := row.tableDesc.GetPublicColumns()
+	for i := range columns {
+		col := &columns[i]
+		datum := tree.Datum(tree.DNull)
+		if !row.deleted || pkCols.Contains(col.ID) {
+			if err := row.datums[i].EnsureDecoded(col.Type, alloc); err != nil {
+				return nil, err
+			}
+			datum = row.datums[i].Datum
+		}
+		appendTo, err = rowenc.EncodeTableValue(appendTo, descpb.ColumnID(encoding.NoColumnID), datum, scratch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return appendTo, nil
+}
+
+// parquetFileWriter buffers the rows of a single changefeed output file and
+// writes them as one Parquet row group when closed.
+type parquetFileWriter struct {
+	schema *parquetTableSchema
+	fw     *goparquet.FileWriter
+	alloc  rowenc.DatumAlloc
+	data   map[string]interface{}
+}
+
+func newParquetFileWriter(
+	w io.Writer,
+	tableDesc catalog.TableDescriptor,
+	updatedField bool,
+	codec parquet.CompressionCodec,
+) (*parquetFileWriter, error) {
+	schema, err := tableToParquetSchema(tableDesc, updatedField)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetFileWriter{
+		schema: schema,
+		fw: goparquet.NewFileWriter(w,
+			goparquet.WithSchemaDefinition(schema.def),
+			goparquet.WithCompressionCodec(codec),
+			goparquet.WithCreator(`CockroachDB`),
+		),
+		data: make(map[string]interface{}, len(schema.cols)+2),
+	}, nil
+}
+
+// addRow buffers a row previously serialized by encodeParquetRow.
+func (w *parquetFileWriter) addRow(encoded []byte) error {
+	for k := range w.data {
+		delete(w.data, k)
+	}
+	d, encoded, err := rowenc.DecodeTableValue(&w.alloc, types.Bool, encoded)
+	if err != nil {
+		return err
+	}
+	w.data[parquetDeletedColumn] = bool(*d.(*tree.DBool))
+	if w.schema.updatedField {
+		d, encoded, err = rowenc.DecodeTableValue(&w.alloc, types.String, encoded)
+		if err != nil {
+			return err
+		}
+		w.data[parquetUpdatedColumn] = []byte(*d.(*tree.DString))
+	}
+	for _, col := range w.schema.cols {
+		d, encoded, err = rowenc.DecodeTableValue(&w.alloc, col.typ, encoded)
+		if err != nil {
+			return err
+		}
+		if d == tree.DNull {
+			continue
+		}
+		if w.data[col.name], err = col.encodeFn(d); err != nil {
+			return errors.Wrapf(err, `column %s`, col.name)
+		}
+	}
+	if len(encoded) > 0 {
+		return errors.AssertionFailedf(`%d trailing bytes after parquet row`, len(encoded))
+	}
+	return w.fw.AddData(w.data)
+}
+
+// close writes the buffered rows as a single row group followed by the file
+// footer.
+func (w *parquetFileWriter) close() error {
+	return w.fw.Close()
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetEncoder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tableDesc, err := parseTableDesc(`CREATE TABLE foo (
+		a INT PRIMARY KEY,
+		b STRING,
+		c DECIMAL(10,2),
+		d TIMESTAMPTZ,
+		e INT[],
+		f JSONB,
+		g INT4,
+		h UUID,
+		i DATE,
+		j BOOL,
+		k INTERVAL
+	)`)
+	require.NoError(t, err)
+	rows, err := parseValues(tableDesc, `VALUES
+		(1, 'a', 1.5, '1970-01-01 00:00:01.000002+00', ARRAY[1, 3], '{"x": 1}',
+		 4, '00000000-0000-0000-0000-000000000005', '1970-01-07', true, '1h'),
+		(2, NULL, -1.29, NULL, ARRAY[]:::INT[], NULL, NULL, NULL, NULL, NULL, NULL)
+	`)
+	require.NoError(t, err)
+
+	opts := map[string]string{
+		changefeedbase.OptFormat:            string(changefeedbase.OptFormatParquet),
+		changefeedbase.OptEnvelope:          string(changefeedbase.OptEnvelopeWrapped),
+		changefeedbase.OptUpdatedTimestamps: ``,
+	}
+	e, err := getEncoder(opts)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := newParquetFileWriter(&buf, tableDesc, true /* updatedField */, parquet.CompressionCodec_GZIP)
+	require.NoError(t, err)
+	ctx := context.Background()
+	ts := hlc.Timestamp{WallTime: 1, Logical: 2}
+	for _, row := range rows {
+		value, err := e.EncodeValue(ctx, encodeRow{datums: row, updated: ts, tableDesc: tableDesc})
+		require.NoError(t, err)
+		require.NoError(t, w.addRow(value))
+	}
+	// Deletions only carry the primary key.
+	value, err := e.EncodeValue(ctx, encodeRow{
+		datums: rows[0], updated: ts, deleted: true, tableDesc: tableDesc,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.addRow(value))
+	require.NoError(t, w.close())
+
+	r, err := goparquet.NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 1, r.RowGroupCount())
+	var actual []map[string]interface{}
+	for {
+		row, err := r.NextRow()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		actual = append(actual, row)
+	}
+
+	updated := []byte(ts.AsOfSystemTime())
+	expected := []map[string]interface{}{
+		{
+			`__crdb__deleted`: false,
+			`__crdb__updated`: updated,
+			`a`:               int64(1),
+			`b`:               []byte(`a`),
+			`c`:               []byte{0x00, 0x96},
+			`d`:               int64(1000002),
+			`e`: map[string]interface{}{`list`: []map[string]interface{}{
+				{`element`: int64(1)}, {`element`: int64(3)},
+			}},
+			`f`: []byte(`{"x": 1}`),
+			`g`: int32(4),
+			`h`: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5},
+			`i`: int32(6),
+			`j`: true,
+			`k`: []byte(`01:00:00`),
+		},
+		{
+			`__crdb__deleted`: false,
+			`__crdb__updated`: updated,
+			`a`:               int64(2),
+			`c`:               []byte{0xff, 0x7f},
+			`e`:               map[string]interface{}{},
+		},
+		{
+			`__crdb__deleted`: true,
+			`__crdb__updated`: updated,
+			`a`:               int64(1),
+		},
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestParquetEncoderErrors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	for _, opts := range []map[string]string{
+		{changefeedbase.OptEnvelope: string(changefeedbase.OptEnvelopeRow)},
+		{changefeedbase.OptEnvelope: string(changefeedbase.OptEnvelopeKeyOnly)},
+		{
+			changefeedbase.OptEnvelope: string(changefeedbase.OptEnvelopeWrapped),
+			changefeedbase.OptDiff:     ``,
+		},
+	} {
+		opts[changefeedbase.OptFormat] = string(changefeedbase.OptFormatParquet)
+		_, err := getEncoder(opts)
+		require.Error(t, err)
+	}
+}
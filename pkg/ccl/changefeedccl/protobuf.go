@@ -0,0 +1,461 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The file contains a very specific marriage between protobuf and our SQL
+// schemas. It's not intended to be a general purpose protobuf utility.
+//
+// We map a SQL table schema to a proto2 message with a 1:1 mapping between
+// table columns and message fields. The number of each field is the ID of the
+// column it holds, which is never reused by a table, and the type of the column
+// is mapped to a scalar protobuf type as faithfully as possible. Every field is
+// `optional` regardless of whether the sql column allows NULLs; a NULL is
+// encoded by omitting the field. Much like for avro (see avro.go), this makes
+// all adjacent messages for a given SQL table backward and forward compatible
+// with each other: adding a column adds a field with a new number and dropping
+// a column removes a field whose number is never seen again.
+//
+// Changefeed keys and values are each described by a .proto file, which is
+// registered with the Confluent schema registry in its textual form. The first
+// message in the file is the one that is encoded.
+
+// protobufField is our representation of a field in a protobuf message that
+// holds a SQL column.
+type protobufField struct {
+	desc *descriptorpb.FieldDescriptorProto
+	typ  *types.T
+
+	wireType protowire.Type
+	// encodeFn appends the encoding of the given non-NULL datum, without the
+	// field's tag, to buf.
+	encodeFn func(buf []byte, d tree.Datum) ([]byte, error)
+}
+
+// protobufDataMessage is a protobuf message that represents the schema of a SQL
+// table or index.
+type protobufDataMessage struct {
+	desc   *descriptorpb.DescriptorProto
+	fields []*protobufField
+
+	colIdxByFieldIdx []int
+	alloc            rowenc.DatumAlloc
+}
+
+// protobufEnvelopeOpts controls which fields in protobufEnvelopeMessage are
+// set.
+type protobufEnvelopeOpts struct {
+	beforeField, afterField     bool
+	updatedField, resolvedField bool
+}
+
+// The field numbers of the envelope message. These are the same regardless of
+// which fields are set, so that consumers may rely on them.
+const (
+	protobufEnvelopeAfterFieldNum    protowire.Number = 1
+	protobufEnvelopeBeforeFieldNum   protowire.Number = 2
+	protobufEnvelopeUpdatedFieldNum  protowire.Number = 3
+	protobufEnvelopeResolvedFieldNum protowire.Number = 4
+)
+
+// protobufEnvelopeMessage is a protobuf message that wraps a changed SQL row
+// and some metadata.
+type protobufEnvelopeMessage struct {
+	desc *descriptorpb.DescriptorProto
+
+	opts          protobufEnvelopeOpts
+	before, after *protobufDataMessage
+
+	scratch []byte
+}
+
+// protobufSchema is a .proto file holding the message encoded in a changefeed
+// key or value.
+type protobufSchema struct {
+	file *descriptorpb.FileDescriptorProto
+	// text is the .proto source of file, which is what the schema registry
+	// expects.
+	text string
+}
+
+func protobufScalarField(
+	typ descriptorpb.FieldDescriptorProto_Type,
+	wireType protowire.Type,
+	encodeFn func([]byte, tree.Datum) ([]byte, error),
+) *protobufField {
+	return &protobufField{
+		desc: &descriptorpb.FieldDescriptorProto{
+			Type:  typ.Enum(),
+			Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		},
+		wireType: wireType,
+		encodeFn: encodeFn,
+	}
+}
+
+func protobufStringField(toString func(tree.Datum) string) *protobufField {
+	return protobufScalarField(
+		descriptorpb.FieldDescriptorProto_TYPE_STRING, protowire.BytesType,
+		func(buf []byte, d tree.Datum) ([]byte, error) {
+			return protowire.AppendString(buf, toString(d)), nil
+		})
+}
+
+func protobufBytesField(toBytes func(tree.Datum) []byte) *protobufField {
+	return protobufScalarField(
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES, protowire.BytesType,
+		func(buf []byte, d tree.Datum) ([]byte, error) {
+			return protowire.AppendBytes(buf, toBytes(d)), nil
+		})
+}
+
+func protobufInt64Field(toInt64 func(tree.Datum) int64) *protobufField {
+	return protobufScalarField(
+		descriptorpb.FieldDescriptorProto_TYPE_INT64, protowire.VarintType,
+		func(buf []byte, d tree.Datum) ([]byte, error) {
+			return protowire.AppendVarint(buf, uint64(toInt64(d))), nil
+		})
+}
+
+// columnDescToProtobufField converts a column descriptor into its corresponding
+// protobuf message field.
+func columnDescToProtobufField(colDesc *descpb.ColumnDescriptor) (*protobufField, error) {
+	num := protowire.Number(colDesc.ID)
+	if !num.IsValid() || (num >= protowire.FirstReservedNumber && num <= protowire.LastReservedNumber) {
+		return nil, errors.Errorf(`column %s: id %d is not a valid protobuf field number`,
+			colDesc.Name, colDesc.ID)
+	}
+
+	var field *protobufField
+	switch colDesc.Type.Family() {
+	case types.IntFamily:
+		field = protobufInt64Field(func(d tree.Datum) int64 {
+			return int64(*d.(*tree.DInt))
+		})
+	case types.BoolFamily:
+		field = protobufScalarField(
+			descriptorpb.FieldDescriptorProto_TYPE_BOOL, protowire.VarintType,
+			func(buf []byte, d tree.Datum) ([]byte, error) {
+				return protowire.AppendVarint(buf, protowire.EncodeBool(bool(*d.(*tree.DBool)))), nil
+			})
+	case types.FloatFamily:
+		field = protobufScalarField(
+			descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, protowire.Fixed64Type,
+			func(buf []byte, d tree.Datum) ([]byte, error) {
+				return protowire.AppendFixed64(buf, math.Float64bits(float64(*d.(*tree.DFloat)))), nil
+			})
+	case types.Box2DFamily:
+		field = protobufStringField(func(d tree.Datum) string {
+			return d.(*tree.DBox2D).CartesianBoundingBox.Repr()
+		})
+	case types.GeographyFamily:
+		field = protobufBytesField(func(d tree.Datum) []byte {
+			return []byte(d.(*tree.DGeography).EWKB())
+		})
+	case types.GeometryFamily:
+		field = protobufBytesField(func(d tree.Datum) []byte {
+			return []byte(d.(*tree.DGeometry).EWKB())
+		})
+	case types.StringFamily:
+		field = protobufStringField(func(d tree.Datum) string {
+			return string(*d.(*tree.DString))
+		})
+	case types.BytesFamily:
+		field = protobufBytesField(func(d tree.Datum) []byte {
+			return []byte(*d.(*tree.DBytes))
+		})
+	case types.DateFamily:
+		// Dates are the number of days since the unix epoch.
+		field = protobufScalarField(
+			descriptorpb.FieldDescriptorProto_TYPE_INT32, protowire.VarintType,
+			func(buf []byte, d tree.Datum) ([]byte, error) {
+				date := *d.(*tree.DDate)
+				if !date.IsFinite() {
+					return nil, errors.Errorf(
+						`column %s: infinite date not yet supported with protobuf`, colDesc.Name)
+				}
+				return protowire.AppendVarint(buf, uint64(date.UnixEpochDays())), nil
+			})
+	case types.TimeFamily:
+		// Times are the number of microseconds since midnight.
+		field = protobufInt64Field(func(d tree.Datum) int64 {
+			return int64(*d.(*tree.DTime))
+		})
+	case types.TimeTZFamily:
+		// We cannot encode this as an integer, as it does not encode timezone
+		// correctly.
+		field = protobufStringField(func(d tree.Datum) string {
+			return d.(*tree.DTimeTZ).TimeTZ.String()
+		})
+	case types.TimestampFamily:
+		// Timestamps are the number of microseconds since the unix epoch.
+		field = protobufInt64Field(func(d tree.Datum) int64 {
+			t := d.(*tree.DTimestamp).Time
+			return t.Unix()*1e6 + int64(t.Nanosecond()/1e3)
+		})
+	case types.TimestampTZFamily:
+		field = protobufInt64Field(func(d tree.Datum) int64 {
+			t := d.(*tree.DTimestampTZ).Time
+			return t.Unix()*1e6 + int64(t.Nanosecond()/1e3)
+		})
+	case types.DecimalFamily:
+		// Protobuf has no decimal type and the textual form roundtrips
+		// regardless of the column's precision and scale.
+		field = protobufStringField(func(d tree.Datum) string {
+			return d.(*tree.DDecimal).Decimal.String()
+		})
+	case types.UuidFamily:
+		field = protobufStringField(func(d tree.Datum) string {
+			return d.(*tree.DUuid).UUID.String()
+		})
+	case types.INetFamily:
+		field = protobufStringField(func(d tree.Datum) string {
+			return d.(*tree.DIPAddr).IPAddr.String()
+		})
+	case types.JsonFamily:
+		field = protobufStringField(func(d tree.Datum) string {
+			return d.(*tree.DJSON).JSON.String()
+		})
+	default:
+		return nil, errors.Errorf(`column %s: type %s not yet supported with protobuf`,
+			colDesc.Name, colDesc.Type.SQLString())
+	}
+	// Protobuf identifiers are restricted to the same characters as avro names.
+	field.desc.Name = proto.String(SQLNameToAvroName(colDesc.Name))
+	field.desc.Number = proto.Int32(int32(num))
+	field.typ = colDesc.Type
+	return field, nil
+}
+
+// indexToProtobufMessage converts an index descriptor into its corresponding
+// protobuf message. The fields are kept in the same order as columns in the
+// index.
+func indexToProtobufMessage(
+	tableDesc catalog.TableDescriptor, indexDesc *descpb.IndexDescriptor,
+) (*protobufDataMessage, error) {
+	msg := &protobufDataMessage{
+		desc: &descriptorpb.DescriptorProto{Name: proto.String(SQLNameToAvroName(tableDesc.GetName()))},
+	}
+	colIdxByID := tableDesc.ColumnIdxMap()
+	for _, colID := range indexDesc.ColumnIDs {
+		colIdx, ok := colIdxByID.Get(colID)
+		if !ok {
+			return nil, errors.Errorf(`unknown column id: %d`, colID)
+		}
+		if err := msg.addColumn(tableDesc.GetColumnAtIdx(colIdx), colIdx); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// tableToProtobufMessage converts a table descriptor into its corresponding
+// protobuf message. The fields are kept in the same order as
+// `tableDesc.Columns`. If a name suffix is provided (as opposed to
+// avroSchemaNoSuffix), it will be appended to the end of the message's name.
+func tableToProtobufMessage(
+	tableDesc catalog.TableDescriptor, nameSuffix string,
+) (*protobufDataMessage, error) {
+	name := SQLNameToAvroName(tableDesc.GetName())
+	if nameSuffix != avroSchemaNoSuffix {
+		name = name + `_` + nameSuffix
+	}
+	msg := &protobufDataMessage{
+		desc: &descriptorpb.DescriptorProto{Name: proto.String(name)},
+	}
+	for colIdx := range tableDesc.GetPublicColumns() {
+		if err := msg.addColumn(tableDesc.GetColumnAtIdx(colIdx), colIdx); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+func (m *protobufDataMessage) addColumn(col *descpb.ColumnDescriptor, colIdx int) error {
+	field, err := columnDescToProtobufField(col)
+	if err != nil {
+		return err
+	}
+	m.desc.Field = append(m.desc.Field, field.desc)
+	m.fields = append(m.fields, field)
+	m.colIdxByFieldIdx = append(m.colIdxByFieldIdx, colIdx)
+	return nil
+}
+
+// BinaryFromRow appends the protobuf wire encoding of the given row to buf.
+func (m *protobufDataMessage) BinaryFromRow(
+	buf []byte, row rowenc.EncDatumRow,
+) ([]byte, error) {
+	for fieldIdx, field := range m.fields {
+		d := row[m.colIdxByFieldIdx[fieldIdx]]
+		if err := d.EnsureDecoded(field.typ, &m.alloc); err != nil {
+			return nil, err
+		}
+		if d.Datum == tree.DNull {
+			// NULLs are represented by the absence of the field.
+			continue
+		}
+		buf = protowire.AppendTag(buf, protowire.Number(field.desc.GetNumber()), field.wireType)
+		var err error
+		if buf, err = field.encodeFn(buf, d.Datum); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// envelopeToProtobufMessage creates a protobuf message for an envelope
+// containing before and after versions of a row change and metadata about that
+// row change.
+func envelopeToProtobufMessage(
+	topic string, opts protobufEnvelopeOpts, before, after *protobufDataMessage,
+) *protobufEnvelopeMessage {
+	msg := &protobufEnvelopeMessage{
+		desc: &descriptorpb.DescriptorProto{Name: proto.String(SQLNameToAvroName(topic) + `_envelope`)},
+		opts: opts,
+	}
+	addField := func(name string, num protowire.Number, typ descriptorpb.FieldDescriptorProto_Type, typeName string) {
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(int32(num)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if typeName != `` {
+			// The data messages are declared at the top level of the file, so
+			// they're referenced by their fully-qualified name to keep them from
+			// being shadowed by the envelope's fields.
+			field.TypeName = proto.String(`.` + typeName)
+		}
+		msg.desc.Field = append(msg.desc.Field, field)
+	}
+	if opts.afterField {
+		msg.after = after
+		addField(`after`, protobufEnvelopeAfterFieldNum,
+			descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, after.desc.GetName())
+	}
+	if opts.beforeField {
+		msg.before = before
+		addField(`before`, protobufEnvelopeBeforeFieldNum,
+			descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, before.desc.GetName())
+	}
+	if opts.updatedField {
+		addField(`updated`, protobufEnvelopeUpdatedFieldNum,
+			descriptorpb.FieldDescriptorProto_TYPE_STRING, ``)
+	}
+	if opts.resolvedField {
+		addField(`resolved`, protobufEnvelopeResolvedFieldNum,
+			descriptorpb.FieldDescriptorProto_TYPE_STRING, ``)
+	}
+	return msg
+}
+
+// schema returns the .proto file for this envelope, which holds the envelope
+// message followed by the messages of the rows it wraps.
+func (m *protobufEnvelopeMessage) schema() (protobufSchema, error) {
+	msgs := []*descriptorpb.DescriptorProto{m.desc}
+	if m.after != nil {
+		msgs = append(msgs, m.after.desc)
+	}
+	if m.before != nil {
+		msgs = append(msgs, m.before.desc)
+	}
+	return makeProtobufSchema(m.desc.GetName(), msgs...)
+}
+
+// BinaryFromRow appends the protobuf wire encoding of the given metadata and
+// row data to buf. A nil row is encoded as the absence of its field.
+func (m *protobufEnvelopeMessage) BinaryFromRow(
+	buf []byte, updated, resolved hlc.Timestamp, beforeRow, afterRow rowenc.EncDatumRow,
+) ([]byte, error) {
+	appendRow := func(
+		buf []byte, num protowire.Number, msg *protobufDataMessage, row rowenc.EncDatumRow,
+	) ([]byte, error) {
+		var err error
+		if m.scratch, err = msg.BinaryFromRow(m.scratch[:0], row); err != nil {
+			return nil, err
+		}
+		buf = protowire.AppendTag(buf, num, protowire.BytesType)
+		return protowire.AppendBytes(buf, m.scratch), nil
+	}
+	var err error
+	if m.opts.afterField && afterRow != nil {
+		if buf, err = appendRow(buf, protobufEnvelopeAfterFieldNum, m.after, afterRow); err != nil {
+			return nil, err
+		}
+	}
+	if m.opts.beforeField && beforeRow != nil {
+		if buf, err = appendRow(buf, protobufEnvelopeBeforeFieldNum, m.before, beforeRow); err != nil {
+			return nil, err
+		}
+	}
+	if m.opts.updatedField {
+		buf = protowire.AppendTag(buf, protobufEnvelopeUpdatedFieldNum, protowire.BytesType)
+		buf = protowire.AppendString(buf, updated.AsOfSystemTime())
+	}
+	if m.opts.resolvedField {
+		buf = protowire.AppendTag(buf, protobufEnvelopeResolvedFieldNum, protowire.BytesType)
+		buf = protowire.AppendString(buf, resolved.AsOfSystemTime())
+	}
+	return buf, nil
+}
+
+// makeProtobufSchema returns a .proto file with the given messages, which must
+// only reference each other.
+func makeProtobufSchema(
+	name string, msgs ...*descriptorpb.DescriptorProto,
+) (protobufSchema, error) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(name + `.proto`),
+		Syntax:      proto.String(`proto2`),
+		MessageType: msgs,
+	}
+	// Validate the file, which catches things like two columns that map to the
+	// same field name.
+	if _, err := protodesc.NewFile(file, nil /* resolver */); err != nil {
+		return protobufSchema{}, errors.Wrapf(err, `invalid protobuf schema for %s`, name)
+	}
+	return protobufSchema{file: file, text: protobufSchemaText(file)}, nil
+}
+
+// protobufSchemaText renders a .proto file as produced by makeProtobufSchema
+// in the protobuf language.
+func protobufSchemaText(file *descriptorpb.FileDescriptorProto) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "syntax = %q;\n", file.GetSyntax())
+	for _, msg := range file.MessageType {
+		fmt.Fprintf(&buf, "\nmessage %s {\n", msg.GetName())
+		for _, field := range msg.Field {
+			typeName := field.GetTypeName()
+			if typeName == `` {
+				typeName = strings.ToLower(strings.TrimPrefix(field.GetType().String(), `TYPE_`))
+			}
+			fmt.Fprintf(&buf, "  optional %s %s = %d;\n", typeName, field.GetName(), field.GetNumber())
+		}
+		buf.WriteString("}\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,225 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	gojson "encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// parseProtobufSchema parses the subset of the protobuf language produced by
+// protobufSchemaText, which lets tests decode messages using only what was
+// registered with the schema registry.
+func parseProtobufSchema(text string) (protoreflect.FileDescriptor, error) {
+	file := &descriptorpb.FileDescriptorProto{Name: proto.String(`test.proto`)}
+	var msg *descriptorpb.DescriptorProto
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == ``:
+		case strings.HasPrefix(line, `syntax = `):
+			syntax, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(line, `syntax = `), `;`))
+			if err != nil {
+				return nil, err
+			}
+			file.Syntax = proto.String(syntax)
+		case strings.HasPrefix(line, `message `):
+			name := strings.TrimSuffix(strings.TrimPrefix(line, `message `), ` {`)
+			msg = &descriptorpb.DescriptorProto{Name: proto.String(name)}
+			file.MessageType = append(file.MessageType, msg)
+		case line == `}`:
+			msg = nil
+		case strings.HasPrefix(line, `optional `) && msg != nil:
+			var typ, name string
+			var num int32
+			if _, err := fmt.Sscanf(line, `optional %s %s = %d;`, &typ, &name, &num); err != nil {
+				return nil, errors.Wrapf(err, `parsing field: %s`, line)
+			}
+			field := &descriptorpb.FieldDescriptorProto{
+				Name:   proto.String(name),
+				Number: proto.Int32(num),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}
+			if strings.HasPrefix(typ, `.`) {
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String(typ)
+			} else {
+				fieldType, ok := descriptorpb.FieldDescriptorProto_Type_value[`TYPE_`+strings.ToUpper(typ)]
+				if !ok {
+					return nil, errors.Errorf(`unknown type: %s`, typ)
+				}
+				field.Type = descriptorpb.FieldDescriptorProto_Type(fieldType).Enum()
+			}
+			msg.Field = append(msg.Field, field)
+		default:
+			return nil, errors.Errorf(`unexpected line: %s`, line)
+		}
+	}
+	return protodesc.NewFile(file, nil /* resolver */)
+}
+
+// protobufMessageToNative converts a message into a map from field name to
+// field value, omitting unset fields.
+func protobufMessageToNative(m protoreflect.Message) map[string]interface{} {
+	native := make(map[string]interface{})
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !m.Has(field) {
+			continue
+		}
+		if field.Kind() == protoreflect.MessageKind {
+			native[string(field.Name())] = protobufMessageToNative(m.Get(field).Message())
+		} else {
+			native[string(field.Name())] = m.Get(field).Interface()
+		}
+	}
+	return native
+}
+
+// decodeProtobuf decodes the first message in the given .proto schema from
+// its wire format.
+func decodeProtobuf(schema string, b []byte) (map[string]interface{}, error) {
+	file, err := parseProtobufSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(file.Messages().Get(0))
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+	return protobufMessageToNative(msg), nil
+}
+
+func TestProtobufSchema(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	t.Run("types", func(t *testing.T) {
+		tests := []struct {
+			sqlType  string
+			sql      string
+			protoTyp string
+			native   string
+		}{
+			{sqlType: `INT`, sql: `-1`, protoTyp: `int64`, native: `-1`},
+			{sqlType: `BOOL`, sql: `true`, protoTyp: `bool`, native: `true`},
+			{sqlType: `FLOAT`, sql: `1.5`, protoTyp: `double`, native: `1.5`},
+			{sqlType: `STRING`, sql: `'foo'`, protoTyp: `string`, native: `"foo"`},
+			{sqlType: `BYTES`, sql: `'\x0102'`, protoTyp: `bytes`, native: `"AQI="`},
+			{sqlType: `DATE`, sql: `'1969-12-31'`, protoTyp: `int32`, native: `-1`},
+			{sqlType: `TIME`, sql: `'00:00:01.000002'`, protoTyp: `int64`, native: `1000002`},
+			{sqlType: `TIMETZ`, sql: `'01:02:03+04'`, protoTyp: `string`, native: `"01:02:03+04:00:00"`},
+			{sqlType: `TIMESTAMP`, sql: `'1970-01-01 00:00:01.000002'`, protoTyp: `int64`, native: `1000002`},
+			{sqlType: `TIMESTAMPTZ`, sql: `'1969-12-31 23:59:59+00'`, protoTyp: `int64`, native: `-1000000`},
+			{sqlType: `DECIMAL`, sql: `1.50`, protoTyp: `string`, native: `"1.50"`},
+			{sqlType: `UUID`, sql: `'6d5e8bd2-2e9b-4b7c-8a3c-6a6e61eb0a32'`, protoTyp: `string`, native: `"6d5e8bd2-2e9b-4b7c-8a3c-6a6e61eb0a32"`},
+			{sqlType: `INET`, sql: `'127.0.0.1'`, protoTyp: `string`, native: `"127.0.0.1"`},
+			{sqlType: `JSONB`, sql: `'{"a": 1}'`, protoTyp: `string`, native: `"{\"a\": 1}"`},
+		}
+		for _, test := range tests {
+			t.Run(test.sqlType, func(t *testing.T) {
+				tableDesc, err := parseTableDesc(
+					fmt.Sprintf(`CREATE TABLE foo (rowid INT PRIMARY KEY, a %s)`, test.sqlType))
+				require.NoError(t, err)
+				rows, err := parseValues(tableDesc, fmt.Sprintf(`VALUES (1, %s), (2, NULL)`, test.sql))
+				require.NoError(t, err)
+
+				msg, err := tableToProtobufMessage(tableDesc, avroSchemaNoSuffix)
+				require.NoError(t, err)
+				schema, err := makeProtobufSchema(`foo`, msg.desc)
+				require.NoError(t, err)
+				require.Equal(t, fmt.Sprintf(`syntax = "proto2";
+
+message foo {
+  optional int64 rowid = 1;
+  optional %s a = 2;
+}
+`, test.protoTyp), schema.text)
+
+				var expected []string
+				expected = append(expected, `{"a":`+test.native+`,"rowid":1}`, `{"rowid":2}`)
+				for i, row := range rows {
+					encoded, err := msg.BinaryFromRow(nil, row)
+					require.NoError(t, err)
+					native, err := decodeProtobuf(schema.text, encoded)
+					require.NoError(t, err)
+					j, err := gojson.Marshal(native)
+					require.NoError(t, err)
+					require.Equal(t, expected[i], string(j))
+				}
+			})
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		tableDesc, err := parseTableDesc(`CREATE TABLE foo (a INT PRIMARY KEY, b OID)`)
+		require.NoError(t, err)
+		_, err = tableToProtobufMessage(tableDesc, avroSchemaNoSuffix)
+		require.EqualError(t, err, `column b: type OID not yet supported with protobuf`)
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		tableDesc, err := parseTableDesc(`CREATE TABLE "foo.bar" (a INT PRIMARY KEY, "b-c" STRING)`)
+		require.NoError(t, err)
+		before, err := tableToProtobufMessage(tableDesc, `before`)
+		require.NoError(t, err)
+		after, err := tableToProtobufMessage(tableDesc, avroSchemaNoSuffix)
+		require.NoError(t, err)
+		opts := protobufEnvelopeOpts{beforeField: true, afterField: true, updatedField: true}
+		msg := envelopeToProtobufMessage(tableDesc.GetName(), opts, before, after)
+		schema, err := msg.schema()
+		require.NoError(t, err)
+		require.Equal(t, `syntax = "proto2";
+
+message foo_u002e_bar_envelope {
+  optional .foo_u002e_bar after = 1;
+  optional .foo_u002e_bar_before before = 2;
+  optional string updated = 3;
+}
+
+message foo_u002e_bar {
+  optional int64 a = 1;
+  optional string b_u002d_c = 2;
+}
+
+message foo_u002e_bar_before {
+  optional int64 a = 1;
+  optional string b_u002d_c = 2;
+}
+`, schema.text)
+
+		rows, err := parseValues(tableDesc, `VALUES (1, 'x'), (1, 'y')`)
+		require.NoError(t, err)
+		encoded, err := msg.BinaryFromRow(
+			nil, hlc.Timestamp{WallTime: 1, Logical: 2}, hlc.Timestamp{}, rows[0], rows[1])
+		require.NoError(t, err)
+		native, err := decodeProtobuf(schema.text, encoded)
+		require.NoError(t, err)
+		j, err := gojson.Marshal(native)
+		require.NoError(t, err)
+		require.Equal(t,
+			`{"after":{"a":1,"b_u002d_c":"y"},"before":{"a":1,"b_u002d_c":"x"},"updated":"1.0000000002"}`,
+			string(j))
+	})
+}
@@ -30,7 +30,6 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
@@ -51,7 +50,7 @@ import (
 type Sink interface {
 	// EmitRow enqueues a row message for asynchronous delivery on the sink. An
 	// error may be returned if a previously enqueued message has failed.
-	EmitRow(ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp) error
+	EmitRow(ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp) error
 	// EmitResolvedTimestamp enqueues a resolved timestamp message for
 	// asynchronous delivery on every topic that has been seen by EmitRow. An
 	// error may be returned if a previously enqueued message has failed.
@@ -177,6 +176,8 @@ func getSink(
 			}
 		}
 
+		_, cfg.topicTemplate = opts[changefeedbase.OptTopicTemplate]
+
 		makeSink = func() (Sink, error) {
 			return makeKafkaSink(cfg, u.Host, targets)
 		}
@@ -200,6 +201,14 @@ func getSink(
 				opts, timestampOracle, makeExternalStorageFromURI, user,
 			)
 		}
+	case isWebhookSink(u):
+		cfg, err := makeWebhookSinkConfig(u, q)
+		if err != nil {
+			return nil, err
+		}
+		makeSink = func() (Sink, error) {
+			return makeWebhookSink(ctx, cfg, opts, targets)
+		}
 	case u.Scheme == changefeedbase.SinkSchemeExperimentalSQL:
 		// Swap the changefeed prefix for the sql connection one that sqlSink
 		// expects.
@@ -207,8 +216,9 @@ func getSink(
 		// TODO(dan): Make tableName configurable or based on the job ID or
 		// something.
 		tableName := `sqlsink`
+		_, topicTemplate := opts[changefeedbase.OptTopicTemplate]
 		makeSink = func() (Sink, error) {
-			return makeSQLSink(u.String(), tableName, targets)
+			return makeSQLSink(u.String(), tableName, targets, topicTemplate)
 		}
 		// Remove parameters we know about for the unknown parameter check.
 		q.Del(`sslcert`)
@@ -239,9 +249,9 @@ type errorWrapperSink struct {
 }
 
 func (s errorWrapperSink) EmitRow(
-	ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp,
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
 ) error {
-	if err := s.wrapped.EmitRow(ctx, table, key, value, updated); err != nil {
+	if err := s.wrapped.EmitRow(ctx, topic, key, value, updated); err != nil {
 		return MarkRetryableError(err)
 	}
 	return nil
@@ -305,6 +315,9 @@ type kafkaSinkConfig struct {
 	saslHandshake    bool
 	saslUser         string
 	saslPassword     string
+	// topicTemplate is true if topics are named by a topic_template, in which
+	// case they are looked up as rows are emitted to them.
+	topicTemplate bool
 }
 
 // kafkaSink emits to Kafka asynchronously. It is not concurrency-safe; all
@@ -335,8 +348,10 @@ func makeKafkaSink(
 ) (Sink, error) {
 	sink := &kafkaSink{cfg: cfg}
 	sink.topics = make(map[string]struct{})
-	for _, t := range targets {
-		sink.topics[cfg.kafkaTopicPrefix+SQLNameToKafkaName(t.StatementTimeName)] = struct{}{}
+	if !cfg.topicTemplate {
+		for _, t := range targets {
+			sink.topics[cfg.kafkaTopicPrefix+SQLNameToKafkaName(t.StatementTimeName)] = struct{}{}
+		}
 	}
 
 	config := sarama.NewConfig()
@@ -464,21 +479,47 @@ func (s *kafkaSink) Close() error {
 
 // EmitRow implements the Sink interface.
 func (s *kafkaSink) EmitRow(
-	ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp,
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
 ) error {
-	topic := s.cfg.kafkaTopicPrefix + SQLNameToKafkaName(table.GetName())
-	if _, ok := s.topics[topic]; !ok {
-		return errors.Errorf(`cannot emit to undeclared topic: %s`, topic)
+	topicName := s.cfg.kafkaTopicPrefix + SQLNameToKafkaName(topic.table.GetName())
+	if s.cfg.topicTemplate {
+		topicName = s.cfg.kafkaTopicPrefix + topic.name
+		if err := s.maybeAddTopic(topicName); err != nil {
+			return err
+		}
+	}
+	if _, ok := s.topics[topicName]; !ok {
+		return errors.Errorf(`cannot emit to undeclared topic: %s`, topicName)
 	}
 
 	msg := &sarama.ProducerMessage{
-		Topic: topic,
+		Topic: topicName,
 		Key:   sarama.ByteEncoder(key),
 		Value: sarama.ByteEncoder(value),
 	}
 	return s.emitMessage(ctx, msg)
 }
 
+// maybeAddTopic looks up a topic named by a topic_template the first time a row
+// is emitted to it. Looking up a topic that doesn't exist creates it if the
+// kafka cluster is configured to automatically create topics.
+func (s *kafkaSink) maybeAddTopic(topic string) error {
+	if _, ok := s.topics[topic]; ok {
+		return nil
+	}
+	// s.client is only nil in tests.
+	if s.client != nil {
+		if err := s.client.RefreshMetadata(topic); err != nil {
+			return errors.Wrapf(err, `looking up topic %s`, topic)
+		}
+		if _, err := s.client.Partitions(topic); err != nil {
+			return errors.Wrapf(err, `looking up partitions of topic %s`, topic)
+		}
+	}
+	s.topics[topic] = struct{}{}
+	return nil
+}
+
 // EmitResolvedTimestamp implements the Sink interface.
 func (s *kafkaSink) EmitResolvedTimestamp(
 	ctx context.Context, encoder Encoder, resolved hlc.Timestamp,
@@ -664,12 +705,17 @@ type sqlSink struct {
 	tableName string
 	topics    map[string]struct{}
 	hasher    hash.Hash32
+	// topicTemplate is true if topics are named by a topic_template, in which
+	// case they are added as rows are emitted to them.
+	topicTemplate bool
 
 	rowBuf  []interface{}
 	scratch bufalloc.ByteAllocator
 }
 
-func makeSQLSink(uri, tableName string, targets jobspb.ChangefeedTargets) (*sqlSink, error) {
+func makeSQLSink(
+	uri, tableName string, targets jobspb.ChangefeedTargets, topicTemplate bool,
+) (*sqlSink, error) {
 	if u, err := url.Parse(uri); err != nil {
 		return nil, err
 	} else if u.Path == `` {
@@ -689,20 +735,28 @@ func makeSQLSink(uri, tableName string, targets jobspb.ChangefeedTargets) (*sqlS
 		tableName: tableName,
 		topics:    make(map[string]struct{}),
 		hasher:    fnv.New32a(),
+
+		topicTemplate: topicTemplate,
 	}
-	for _, t := range targets {
-		s.topics[t.StatementTimeName] = struct{}{}
+	if !topicTemplate {
+		for _, t := range targets {
+			s.topics[t.StatementTimeName] = struct{}{}
+		}
 	}
 	return s, nil
 }
 
 // EmitRow implements the Sink interface.
 func (s *sqlSink) EmitRow(
-	ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp,
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
 ) error {
-	topic := table.GetName()
-	if _, ok := s.topics[topic]; !ok {
-		return errors.Errorf(`cannot emit to undeclared topic: %s`, topic)
+	topicName := topic.table.GetName()
+	if s.topicTemplate {
+		topicName = topic.name
+		s.topics[topicName] = struct{}{}
+	}
+	if _, ok := s.topics[topicName]; !ok {
+		return errors.Errorf(`cannot emit to undeclared topic: %s`, topicName)
 	}
 
 	// Hashing logic copied from sarama.HashPartitioner.
@@ -716,7 +770,7 @@ func (s *sqlSink) EmitRow(
 	}
 
 	var noResolved []byte
-	return s.emit(ctx, topic, partition, key, value, noResolved)
+	return s.emit(ctx, topicName, partition, key, value, noResolved)
 }
 
 // EmitResolvedTimestamp implements the Sink interface.
@@ -812,17 +866,16 @@ type bufferSink struct {
 
 // EmitRow implements the Sink interface.
 func (s *bufferSink) EmitRow(
-	ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp,
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
 ) error {
 	if s.closed {
 		return errors.New(`cannot EmitRow on a closed sink`)
 	}
-	topic := table.GetName()
 	s.buf.Push(rowenc.EncDatumRow{
 		{Datum: tree.DNull}, // resolved span
-		{Datum: s.alloc.NewDString(tree.DString(topic))}, // topic
-		{Datum: s.alloc.NewDBytes(tree.DBytes(key))},     // key
-		{Datum: s.alloc.NewDBytes(tree.DBytes(value))},   //value
+		{Datum: s.alloc.NewDString(tree.DString(topic.table.GetName()))}, // topic
+		{Datum: s.alloc.NewDBytes(tree.DBytes(key))},                     // key
+		{Datum: s.alloc.NewDBytes(tree.DBytes(value))},                   //value
 	})
 	return nil
 }
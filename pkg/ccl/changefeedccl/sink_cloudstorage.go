@@ -23,12 +23,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/errors"
+	"github.com/fraugster/parquet-go/parquet"
 	"github.com/google/btree"
 )
 
@@ -57,6 +57,9 @@ type cloudStorageSinkFile struct {
 	codec   io.WriteCloser
 	rawSize int
 	buf     bytes.Buffer
+	// parquet is set for format=parquet files. Rows are buffered in it and
+	// only written to buf, as a single row group, when the file is flushed.
+	parquet *parquetFileWriter
 }
 
 var _ io.Writer = &cloudStorageSinkFile{}
@@ -162,9 +165,16 @@ func (f *cloudStorageSinkFile) Write(p []byte) (int, error) {
 // by a given `<sink_id>` and <session_id> is a unique identifying string for the job
 // session running the `changeAggregator` that owns this sink.
 //
-// `<ext>` implies the format of the file: currently the only option is
-// `ndjson`, which means a text file conforming to the "Newline Delimited JSON"
-// spec.
+// If a path_template is specified, each data file is instead written to the
+// directory the template expands to for its rows, followed by the date
+// partition. The template only depends on the primary key of a row, so all
+// updates to a row are written to the same directory and the ordering
+// guarantees above hold within each directory. Resolved timestamp files are
+// always written to the date partitions at the root of the sink.
+//
+// `<ext>` implies the format of the file: either `ndjson`, which means a text
+// file conforming to the "Newline Delimited JSON" spec, or `parquet`, which
+// means an Apache Parquet file containing a single row group.
 //
 // This naming convention of data files is carefully chosen in order to preserve
 // the external ordering guarantees of CDC. Naming output files in this fashion
@@ -272,10 +282,12 @@ type cloudStorageSink struct {
 	settings          *cluster.Settings
 	partitionFormat   string
 
+	format        changefeedbase.FormatType
 	ext           string
 	recordDelimFn func(io.Writer) error
 
-	compression string
+	compression  string
+	updatedField bool
 
 	es cloud.ExternalStorage
 
@@ -330,7 +342,8 @@ func makeCloudStorageSink(
 		s.dataFilePartition = timestampOracle.inclusiveLowerBoundTS().GoTime().Format(s.partitionFormat)
 	}
 
-	switch changefeedbase.FormatType(opts[changefeedbase.OptFormat]) {
+	s.format = changefeedbase.FormatType(opts[changefeedbase.OptFormat])
+	switch s.format {
 	case changefeedbase.OptFormatJSON:
 		// TODO(dan): It seems like these should be on the encoder, but that
 		// would require a bit of refactoring.
@@ -339,6 +352,10 @@ func makeCloudStorageSink(
 			_, err := w.Write([]byte{'\n'})
 			return err
 		}
+	case changefeedbase.OptFormatParquet:
+		// Parquet files are self-delimiting, see getOrCreateFile.
+		s.ext = `.parquet`
+		_, s.updatedField = opts[changefeedbase.OptUpdatedTimestamps]
 	default:
 		return nil, errors.Errorf(`this sink is incompatible with %s=%s`,
 			changefeedbase.OptFormat, opts[changefeedbase.OptFormat])
@@ -358,7 +375,11 @@ func makeCloudStorageSink(
 	if codec, ok := opts[changefeedbase.OptCompression]; ok && codec != "" {
 		if strings.EqualFold(codec, "gzip") {
 			s.compression = sinkCompressionGzip
-			s.ext = s.ext + ".gz"
+			// Parquet compresses the pages within the file rather than the file
+			// as a whole, so the extension is unchanged.
+			if s.format != changefeedbase.OptFormatParquet {
+				s.ext = s.ext + ".gz"
+			}
 		} else {
 			return nil, errors.Errorf(`unsupported compression codec %q`, codec)
 		}
@@ -372,44 +393,69 @@ func makeCloudStorageSink(
 	return s, nil
 }
 
-func (s *cloudStorageSink) getOrCreateFile(
-	topic string, schemaID descpb.DescriptorVersion,
-) *cloudStorageSinkFile {
-	key := cloudStorageSinkKey{topic, schemaID}
+func (s *cloudStorageSink) getOrCreateFile(topic topicDescriptor) (*cloudStorageSinkFile, error) {
+	table := topic.table
+	key := cloudStorageSinkKey{table.GetName(), topic.name, table.GetVersion()}
 	if item := s.files.Get(key); item != nil {
-		return item.(*cloudStorageSinkFile)
+		return item.(*cloudStorageSinkFile), nil
 	}
 	f := &cloudStorageSinkFile{
 		cloudStorageSinkKey: key,
 	}
-	switch s.compression {
-	case sinkCompressionGzip:
-		f.codec = gzip.NewWriter(&f.buf)
+	if s.format == changefeedbase.OptFormatParquet {
+		codec := parquet.CompressionCodec_UNCOMPRESSED
+		if s.compression == sinkCompressionGzip {
+			codec = parquet.CompressionCodec_GZIP
+		}
+		var err error
+		if f.parquet, err = newParquetFileWriter(&f.buf, table, s.updatedField, codec); err != nil {
+			return nil, err
+		}
+	} else {
+		switch s.compression {
+		case sinkCompressionGzip:
+			f.codec = gzip.NewWriter(&f.buf)
+		}
 	}
 	s.files.ReplaceOrInsert(f)
-	return f
+	return f, nil
 }
 
 // EmitRow implements the Sink interface.
 func (s *cloudStorageSink) EmitRow(
-	ctx context.Context, table catalog.TableDescriptor, key, value []byte, updated hlc.Timestamp,
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
 ) error {
 	if s.files == nil {
 		return errors.New(`cannot EmitRow on a closed sink`)
 	}
 
-	file := s.getOrCreateFile(table.GetName(), table.GetVersion())
-
-	// TODO(dan): Memory monitoring for this
-	if _, err := file.Write(value); err != nil {
+	file, err := s.getOrCreateFile(topic)
+	if err != nil {
 		return err
 	}
-	if err := s.recordDelimFn(file); err != nil {
-		return err
+
+	// TODO(dan): Memory monitoring for this
+	var size int
+	if file.parquet != nil {
+		// Parquet rows are only written to buf when the file is flushed, so
+		// the size of the file is approximated by the size of its rows.
+		if err := file.parquet.addRow(value); err != nil {
+			return err
+		}
+		file.rawSize += len(value)
+		size = file.rawSize
+	} else {
+		if _, err := file.Write(value); err != nil {
+			return err
+		}
+		if err := s.recordDelimFn(file); err != nil {
+			return err
+		}
+		size = file.buf.Len()
 	}
 
-	if int64(file.buf.Len()) > s.targetMaxFileSize {
-		if err := s.flushTopicVersions(ctx, file.topic, file.schemaID); err != nil {
+	if int64(size) > s.targetMaxFileSize {
+		if err := s.flushTopicVersions(ctx, file.topic, file.dir, file.schemaID); err != nil {
 			return err
 		}
 	}
@@ -456,12 +502,12 @@ func (s *cloudStorageSink) EmitResolvedTimestamp(
 // schema 2 file, leading to a violation of our ordering guarantees (see comment
 // on cloudStorageSink)
 func (s *cloudStorageSink) flushTopicVersions(
-	ctx context.Context, topic string, maxVersionToFlush descpb.DescriptorVersion,
+	ctx context.Context, topic, dir string, maxVersionToFlush descpb.DescriptorVersion,
 ) (err error) {
 	var toRemoveAlloc [2]descpb.DescriptorVersion // generally avoid allocating
 	toRemove := toRemoveAlloc[:0]                 // schemaIDs of flushed files
-	gte := cloudStorageSinkKey{topic: topic}
-	lt := cloudStorageSinkKey{topic: topic, schemaID: maxVersionToFlush + 1}
+	gte := cloudStorageSinkKey{topic: topic, dir: dir}
+	lt := cloudStorageSinkKey{topic: topic, dir: dir, schemaID: maxVersionToFlush + 1}
 	s.files.AscendRange(gte, lt, func(i btree.Item) (wantMore bool) {
 		f := i.(*cloudStorageSinkFile)
 		if err = s.flushFile(ctx, f); err == nil {
@@ -470,7 +516,7 @@ func (s *cloudStorageSink) flushTopicVersions(
 		return err == nil
 	})
 	for _, v := range toRemove {
-		s.files.Delete(cloudStorageSinkKey{topic: topic, schemaID: v})
+		s.files.Delete(cloudStorageSinkKey{topic: topic, dir: dir, schemaID: v})
 	}
 	return err
 }
@@ -507,6 +553,13 @@ func (s *cloudStorageSink) flushFile(ctx context.Context, file *cloudStorageSink
 		return nil
 	}
 
+	// Parquet files are written out in their entirety when closed.
+	if file.parquet != nil {
+		if err := file.parquet.close(); err != nil {
+			return err
+		}
+	}
+
 	// If the file is written via compression codec, close the codec to ensure it
 	// has flushed to the underlying buffer.
 	if file.codec != nil {
@@ -530,7 +583,9 @@ func (s *cloudStorageSink) flushFile(ctx context.Context, file *cloudStorageSink
 			"precedes a file emitted before: %s", filename, s.prevFilename)
 	}
 	s.prevFilename = filename
-	return s.es.WriteFile(ctx, filepath.Join(s.dataFilePartition, filename), bytes.NewReader(file.buf.Bytes()))
+	return s.es.WriteFile(
+		ctx, filepath.Join(file.dir, s.dataFilePartition, filename), bytes.NewReader(file.buf.Bytes()),
+	)
 }
 
 // Close implements the Sink interface.
@@ -540,7 +595,10 @@ func (s *cloudStorageSink) Close() error {
 }
 
 type cloudStorageSinkKey struct {
-	topic    string
+	topic string
+	// dir is the expansion of the path_template for the rows in the file, if
+	// any.
+	dir      string
 	schemaID descpb.DescriptorVersion
 }
 
@@ -556,8 +614,11 @@ func (k cloudStorageSinkKey) Less(other btree.Item) bool {
 }
 
 func keyLess(a, b cloudStorageSinkKey) bool {
-	if a.topic == b.topic {
-		return a.schemaID < b.schemaID
+	if a.topic != b.topic {
+		return a.topic < b.topic
+	}
+	if a.dir != b.dir {
+		return a.dir < b.dir
 	}
-	return a.topic < b.topic
+	return a.schemaID < b.schemaID
 }
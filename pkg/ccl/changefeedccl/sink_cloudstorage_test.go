@@ -27,6 +27,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
@@ -36,6 +37,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/span"
+	goparquet "github.com/fraugster/parquet-go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,6 +100,9 @@ func TestCloudStorageSink(t *testing.T) {
 		changefeedbase.OptCompression: ``, // NB: overridden in single-node subtest.
 	}
 	ts := func(i int64) hlc.Timestamp { return hlc.Timestamp{WallTime: i} }
+	topic := func(table catalog.TableDescriptor) topicDescriptor {
+		return topicDescriptor{table: table}
+	}
 	e, err := makeJSONEncoder(opts)
 	require.NoError(t, err)
 
@@ -123,7 +128,7 @@ func TestCloudStorageSink(t *testing.T) {
 		require.NoError(t, err)
 		s.(*cloudStorageSink).sinkID = 7 // Force a deterministic sinkID.
 
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
 		require.NoError(t, s.Flush(ctx))
 
 		require.Equal(t, []string{
@@ -167,9 +172,9 @@ func TestCloudStorageSink(t *testing.T) {
 				// Emitting rows and flushing should write them out in one file per table. Note
 				// the ordering among these two files is non deterministic as either of them could
 				// be flushed first (and thus be assigned fileID 0).
-				require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
-				require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v2`), ts(1)))
-				require.NoError(t, s.EmitRow(ctx, t2, noKey, []byte(`w1`), ts(3)))
+				require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
+				require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v2`), ts(1)))
+				require.NoError(t, s.EmitRow(ctx, topic(t2), noKey, []byte(`w1`), ts(3)))
 				require.NoError(t, s.Flush(ctx))
 				expected := []string{
 					"v1\nv2\n",
@@ -186,7 +191,7 @@ func TestCloudStorageSink(t *testing.T) {
 				require.Equal(t, expected, actual)
 
 				// Without a flush, nothing new shows up.
-				require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v3`), ts(3)))
+				require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v3`), ts(3)))
 				actual = slurpDir(t, dir)
 				sort.Strings(actual)
 				require.Equal(t, expected, actual)
@@ -205,9 +210,9 @@ func TestCloudStorageSink(t *testing.T) {
 				// after the rows emitted above.
 				require.True(t, sf.Forward(testSpan, ts(4)))
 				require.NoError(t, s.Flush(ctx))
-				require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v4`), ts(4)))
+				require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v4`), ts(4)))
 				t1.Version = 2
-				require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v5`), ts(5)))
+				require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v5`), ts(5)))
 				require.NoError(t, s.Flush(ctx))
 				expected = []string{
 					"v4\n",
@@ -250,8 +255,8 @@ func TestCloudStorageSink(t *testing.T) {
 		// Each node writes some data at the same timestamp. When this data is
 		// written out, the files have different names and don't conflict because
 		// the sinks have different job session IDs.
-		require.NoError(t, s1.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
-		require.NoError(t, s2.EmitRow(ctx, t1, noKey, []byte(`w1`), ts(1)))
+		require.NoError(t, s1.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s2.EmitRow(ctx, topic(t1), noKey, []byte(`w1`), ts(1)))
 		require.NoError(t, s1.Flush(ctx))
 		require.NoError(t, s2.Flush(ctx))
 		require.Equal(t, []string{
@@ -283,8 +288,8 @@ func TestCloudStorageSink(t *testing.T) {
 		s1R.(*cloudStorageSink).jobSessionID = "a"
 		s2R.(*cloudStorageSink).jobSessionID = "b"
 		// Each resends the data it did before.
-		require.NoError(t, s1R.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
-		require.NoError(t, s2R.EmitRow(ctx, t1, noKey, []byte(`w1`), ts(1)))
+		require.NoError(t, s1R.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s2R.EmitRow(ctx, topic(t1), noKey, []byte(`w1`), ts(1)))
 		require.NoError(t, s1R.Flush(ctx))
 		require.NoError(t, s2R.Flush(ctx))
 		// s1 data ends up being overwritten, s2 data ends up duplicated.
@@ -324,17 +329,17 @@ func TestCloudStorageSink(t *testing.T) {
 		s2.(*cloudStorageSink).jobSessionID = "b" // Force deterministic job session ID.
 
 		// Good job writes
-		require.NoError(t, s1.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
-		require.NoError(t, s1.EmitRow(ctx, t1, noKey, []byte(`v2`), ts(2)))
+		require.NoError(t, s1.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s1.EmitRow(ctx, topic(t1), noKey, []byte(`v2`), ts(2)))
 		require.NoError(t, s1.Flush(ctx))
 
 		// Zombie job writes partial duplicate data
-		require.NoError(t, s2.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s2.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
 		require.NoError(t, s2.Flush(ctx))
 
 		// Good job continues. There are duplicates in the data but nothing was
 		// lost.
-		require.NoError(t, s1.EmitRow(ctx, t1, noKey, []byte(`v3`), ts(3)))
+		require.NoError(t, s1.EmitRow(ctx, topic(t1), noKey, []byte(`v3`), ts(3)))
 		require.NoError(t, s1.Flush(ctx))
 		require.Equal(t, []string{
 			"v1\nv2\n",
@@ -360,7 +365,7 @@ func TestCloudStorageSink(t *testing.T) {
 		// Writing more than the max file size chunks the file up and flushes it
 		// out as necessary.
 		for i := int64(1); i <= 5; i++ {
-			require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(fmt.Sprintf(`v%d`, i)), ts(i)))
+			require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(fmt.Sprintf(`v%d`, i)), ts(i)))
 		}
 		require.Equal(t, []string{
 			"v1\nv2\nv3\n",
@@ -381,7 +386,7 @@ func TestCloudStorageSink(t *testing.T) {
 		// Some more data is written. Some of it flushed out because of the max
 		// file size.
 		for i := int64(6); i < 10; i++ {
-			require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(fmt.Sprintf(`v%d`, i)), ts(i)))
+			require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(fmt.Sprintf(`v%d`, i)), ts(i)))
 		}
 		require.Equal(t, []string{
 			"v1\nv2\nv3\n",
@@ -447,8 +452,8 @@ func TestCloudStorageSink(t *testing.T) {
 
 		// Simulate initial scan, which emits data at a timestamp, then an equal
 		// resolved timestamp.
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`is1`), ts(1)))
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`is2`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`is1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`is2`), ts(1)))
 		require.NoError(t, s.Flush(ctx))
 		require.NoError(t, s.EmitResolvedTimestamp(ctx, e, ts(1)))
 
@@ -458,13 +463,13 @@ func TestCloudStorageSink(t *testing.T) {
 		// be after the resolved timestamp emitted above.
 		require.True(t, sf.Forward(testSpan, ts(2)))
 		require.NoError(t, s.Flush(ctx))
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`e2`), ts(2)))
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`e3prev`), ts(3).Prev()))
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`e3`), ts(3)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`e2`), ts(2)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`e3prev`), ts(3).Prev()))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`e3`), ts(3)))
 		require.True(t, sf.Forward(testSpan, ts(3)))
 		require.NoError(t, s.Flush(ctx))
 		require.NoError(t, s.EmitResolvedTimestamp(ctx, e, ts(3)))
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`e3next`), ts(3).Next()))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`e3next`), ts(3).Next()))
 		require.NoError(t, s.Flush(ctx))
 		require.NoError(t, s.EmitResolvedTimestamp(ctx, e, ts(4)))
 
@@ -479,7 +484,7 @@ func TestCloudStorageSink(t *testing.T) {
 
 		// Test that files with timestamp lower than the least resolved timestamp
 		// as of file creation time are ignored.
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`noemit`), ts(1).Next()))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`noemit`), ts(1).Next()))
 		require.Equal(t, []string{
 			"is1\nis2\n",
 			`{"resolved":"1.0000000000"}`,
@@ -501,22 +506,22 @@ func TestCloudStorageSink(t *testing.T) {
 			opts, timestampOracle, externalStorageFromURI, user)
 		require.NoError(t, err)
 
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v1`), ts(1)))
 		t1.Version = 1
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v3`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v3`), ts(1)))
 		// Make the first file exceed its file size threshold. This should trigger a flush
 		// for the first file but not the second one.
 		t1.Version = 0
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`trigger-flush-v1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`trigger-flush-v1`), ts(1)))
 		require.Equal(t, []string{
 			"v1\ntrigger-flush-v1\n",
 		}, slurpDir(t, dir))
 
 		// Now make the file with the newer schema exceed its file size threshold and ensure
 		// that the file with the older schema is flushed (and ordered) before.
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`v2`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`v2`), ts(1)))
 		t1.Version = 1
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`trigger-flush-v3`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`trigger-flush-v3`), ts(1)))
 		require.Equal(t, []string{
 			"v1\ntrigger-flush-v1\n",
 			"v2\n",
@@ -524,9 +529,9 @@ func TestCloudStorageSink(t *testing.T) {
 		}, slurpDir(t, dir))
 
 		// Calling `Flush()` on the sink should emit files in the order of their schema IDs.
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`w1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`w1`), ts(1)))
 		t1.Version = 0
-		require.NoError(t, s.EmitRow(ctx, t1, noKey, []byte(`x1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, []byte(`x1`), ts(1)))
 		require.NoError(t, s.Flush(ctx))
 		require.Equal(t, []string{
 			"v1\ntrigger-flush-v1\n",
@@ -536,4 +541,89 @@ func TestCloudStorageSink(t *testing.T) {
 			"w1\n",
 		}, slurpDir(t, dir))
 	})
+
+	t.Run(`path_template`, func(t *testing.T) {
+		t1 := tabledesc.NewImmutable(descpb.TableDescriptor{Name: `t1`})
+		testSpan := roachpb.Span{Key: []byte("a"), EndKey: []byte("b")}
+		sf := span.MakeFrontier(testSpan)
+		timestampOracle := &changeAggregatorLowerBoundOracle{sf: sf}
+		dir := `path-template`
+		s, err := makeCloudStorageSink(ctx, `nodelocal://0/`+dir, 1, unlimitedFileSize, settings,
+			opts, timestampOracle, externalStorageFromURI, user)
+		require.NoError(t, err)
+
+		// Rows of the same table with different expansions of the template are
+		// buffered and flushed to separate files under their own directories.
+		expanded := func(name string) topicDescriptor {
+			return topicDescriptor{table: t1, name: name}
+		}
+		require.NoError(t, s.EmitRow(ctx, expanded(`d/t1/k=1`), noKey, []byte(`v1`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, expanded(`d/t1/k=2`), noKey, []byte(`v2`), ts(1)))
+		require.NoError(t, s.EmitRow(ctx, expanded(`d/t1/k=1`), noKey, []byte(`v3`), ts(1)))
+		require.NoError(t, s.Flush(ctx))
+		require.Equal(t, []string{"v1\nv3\n"}, slurpDir(t, dir+`/d/t1/k=1`))
+		require.Equal(t, []string{"v2\n"}, slurpDir(t, dir+`/d/t1/k=2`))
+
+		// Resolved timestamps are written at the root of the sink.
+		require.NoError(t, s.EmitResolvedTimestamp(ctx, e, ts(5)))
+		var resolved []string
+		require.NoError(t, filepath.Walk(filepath.Join(settings.ExternalIODir, dir),
+			func(path string, info os.FileInfo, err error) error {
+				if err != nil || !strings.HasSuffix(path, `.RESOLVED`) {
+					return err
+				}
+				resolved = append(resolved, path)
+				return nil
+			}))
+		require.Len(t, resolved, 1)
+		require.False(t, strings.Contains(resolved[0], `/d/`), resolved[0])
+	})
+
+	t.Run(`parquet`, func(t *testing.T) {
+		t1, err := parseTableDesc(`CREATE TABLE t1 (a INT PRIMARY KEY, b STRING)`)
+		require.NoError(t, err)
+		rows, err := parseValues(t1, `VALUES (1, 'a'), (2, 'b'), (3, 'c')`)
+		require.NoError(t, err)
+		testSpan := roachpb.Span{Key: []byte("a"), EndKey: []byte("b")}
+		sf := span.MakeFrontier(testSpan)
+		timestampOracle := &changeAggregatorLowerBoundOracle{sf: sf}
+		dir := `parquet`
+		parquetOpts := map[string]string{
+			changefeedbase.OptFormat:      string(changefeedbase.OptFormatParquet),
+			changefeedbase.OptEnvelope:    string(changefeedbase.OptEnvelopeWrapped),
+			changefeedbase.OptKeyInValue:  ``,
+			changefeedbase.OptCompression: sinkCompressionGzip,
+		}
+		pe, err := newParquetEncoder(parquetOpts)
+		require.NoError(t, err)
+		s, err := makeCloudStorageSink(ctx, `nodelocal://0/`+dir, 1, unlimitedFileSize, settings,
+			parquetOpts, timestampOracle, externalStorageFromURI, user)
+		require.NoError(t, err)
+
+		for _, row := range rows {
+			value, err := pe.EncodeValue(ctx, encodeRow{datums: row, updated: ts(1), tableDesc: t1})
+			require.NoError(t, err)
+			require.NoError(t, s.EmitRow(ctx, topic(t1), noKey, value, ts(1)))
+		}
+		require.NoError(t, s.Flush(ctx))
+
+		// Every flushed file is a standalone parquet file with one row group.
+		var names []string
+		require.NoError(t, filepath.Walk(filepath.Join(settings.ExternalIODir, dir),
+			func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				names = append(names, path)
+				return nil
+			}))
+		require.Len(t, names, 1)
+		require.True(t, strings.HasSuffix(names[0], `-t1-1.parquet`), names[0])
+		file, err := ioutil.ReadFile(names[0])
+		require.NoError(t, err)
+		r, err := goparquet.NewFileReader(bytes.NewReader(file))
+		require.NoError(t, err)
+		require.Equal(t, 1, r.RowGroupCount())
+		require.Equal(t, int64(3), r.NumRows())
+	})
 }
@@ -54,8 +54,8 @@ func TestKafkaSink(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
 
-	table := func(name string) *tabledesc.Immutable {
-		return tabledesc.NewImmutable(descpb.TableDescriptor{Name: name})
+	table := func(name string) topicDescriptor {
+		return topicDescriptor{table: tabledesc.NewImmutable(descpb.TableDescriptor{Name: name})}
 	}
 
 	ctx := context.Background()
@@ -144,8 +144,8 @@ func TestKafkaSinkEscaping(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
 
-	table := func(name string) *tabledesc.Immutable {
-		return tabledesc.NewImmutable(descpb.TableDescriptor{Name: name})
+	table := func(name string) topicDescriptor {
+		return topicDescriptor{table: tabledesc.NewImmutable(descpb.TableDescriptor{Name: name})}
 	}
 
 	ctx := context.Background()
@@ -183,8 +183,8 @@ func TestSQLSink(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
 
-	table := func(name string) *tabledesc.Immutable {
-		return tabledesc.NewImmutable(descpb.TableDescriptor{Name: name})
+	table := func(name string) topicDescriptor {
+		return topicDescriptor{table: tabledesc.NewImmutable(descpb.TableDescriptor{Name: name})}
 	}
 
 	ctx := context.Background()
@@ -201,7 +201,7 @@ func TestSQLSink(t *testing.T) {
 		0: jobspb.ChangefeedTarget{StatementTimeName: `foo`},
 		1: jobspb.ChangefeedTarget{StatementTimeName: `bar`},
 	}
-	sink, err := makeSQLSink(sinkURL.String(), `sink`, targets)
+	sink, err := makeSQLSink(sinkURL.String(), `sink`, targets, false /* topicTemplate */)
 	require.NoError(t, err)
 	defer func() { require.NoError(t, sink.Close()) }()
 
@@ -0,0 +1,581 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package changefeedccl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	gojson "encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	webhookContentType = `application/json`
+
+	defaultWebhookBatchMessages  = 100
+	defaultWebhookBatchBytes     = 1 << 20 // 1MB
+	defaultWebhookFlushFrequency = time.Second
+	defaultWebhookMaxRetries     = 3
+	defaultWebhookRetryBackoff   = 500 * time.Millisecond
+	defaultWebhookClientTimeout  = 3 * time.Second
+)
+
+func isWebhookSink(u *url.URL) bool {
+	return u.Scheme == changefeedbase.SinkSchemeWebhookHTTPS
+}
+
+type webhookSinkConfig struct {
+	// url is the https endpoint every batch is POSTed to.
+	url           string
+	authHeader    string
+	tlsSkipVerify bool
+	caCert        []byte
+	clientCert    []byte
+	clientKey     []byte
+
+	// A batch is sent as soon as it holds batchMessages messages or
+	// batchBytes bytes of encoded rows, whichever comes first. Non-empty
+	// batches are also sent every flushFrequency.
+	batchMessages  int
+	batchBytes     int64
+	flushFrequency time.Duration
+
+	maxRetries    int
+	retryBackoff  time.Duration
+	clientTimeout time.Duration
+}
+
+// makeWebhookSinkConfig consumes the webhook sink parameters from q and
+// returns the resulting configuration.
+func makeWebhookSinkConfig(u *url.URL, q url.Values) (webhookSinkConfig, error) {
+	cfg := webhookSinkConfig{
+		batchMessages:  defaultWebhookBatchMessages,
+		batchBytes:     defaultWebhookBatchBytes,
+		flushFrequency: defaultWebhookFlushFrequency,
+		maxRetries:     defaultWebhookMaxRetries,
+		retryBackoff:   defaultWebhookRetryBackoff,
+		clientTimeout:  defaultWebhookClientTimeout,
+	}
+
+	cfg.authHeader = q.Get(changefeedbase.SinkParamWebhookAuthHeader)
+	q.Del(changefeedbase.SinkParamWebhookAuthHeader)
+
+	if tlsVerifyBool := q.Get(changefeedbase.SinkParamSkipTLSVerify); tlsVerifyBool != `` {
+		var err error
+		if cfg.tlsSkipVerify, err = strconv.ParseBool(tlsVerifyBool); err != nil {
+			return webhookSinkConfig{}, errors.Errorf(`param %s must be a bool: %s`, changefeedbase.SinkParamSkipTLSVerify, err)
+		}
+	}
+	q.Del(changefeedbase.SinkParamSkipTLSVerify)
+
+	for _, p := range []struct {
+		param string
+		dest  *[]byte
+	}{
+		{changefeedbase.SinkParamCACert, &cfg.caCert},
+		{changefeedbase.SinkParamClientCert, &cfg.clientCert},
+		{changefeedbase.SinkParamClientKey, &cfg.clientKey},
+	} {
+		if v := q.Get(p.param); v != `` {
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return webhookSinkConfig{}, errors.Errorf(`param %s must be base 64 encoded: %s`, p.param, err)
+			}
+			*p.dest = decoded
+		}
+		q.Del(p.param)
+	}
+	if cfg.clientCert != nil && cfg.clientKey == nil {
+		return webhookSinkConfig{}, errors.Errorf(`%s requires %s to be set`,
+			changefeedbase.SinkParamClientCert, changefeedbase.SinkParamClientKey)
+	}
+	if cfg.clientKey != nil && cfg.clientCert == nil {
+		return webhookSinkConfig{}, errors.Errorf(`%s requires %s to be set`,
+			changefeedbase.SinkParamClientKey, changefeedbase.SinkParamClientCert)
+	}
+
+	if v := q.Get(changefeedbase.SinkParamWebhookBatchMessages); v != `` {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return webhookSinkConfig{}, errors.Errorf(`param %s must be a positive integer: %s`,
+				changefeedbase.SinkParamWebhookBatchMessages, v)
+		}
+		cfg.batchMessages = n
+	}
+	q.Del(changefeedbase.SinkParamWebhookBatchMessages)
+
+	if v := q.Get(changefeedbase.SinkParamWebhookBatchBytes); v != `` {
+		n, err := humanizeutil.ParseBytes(v)
+		if err != nil {
+			return webhookSinkConfig{}, pgerror.Wrapf(err, pgcode.Syntax, `parsing %s`, v)
+		}
+		if n <= 0 {
+			return webhookSinkConfig{}, errors.Errorf(`param %s must be positive: %s`,
+				changefeedbase.SinkParamWebhookBatchBytes, v)
+		}
+		cfg.batchBytes = n
+	}
+	q.Del(changefeedbase.SinkParamWebhookBatchBytes)
+
+	if v := q.Get(changefeedbase.SinkParamWebhookMaxRetries); v != `` {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return webhookSinkConfig{}, errors.Errorf(`param %s must be a non-negative integer: %s`,
+				changefeedbase.SinkParamWebhookMaxRetries, v)
+		}
+		cfg.maxRetries = n
+	}
+	q.Del(changefeedbase.SinkParamWebhookMaxRetries)
+
+	for _, p := range []struct {
+		param string
+		dest  *time.Duration
+	}{
+		{changefeedbase.SinkParamWebhookFlushFrequency, &cfg.flushFrequency},
+		{changefeedbase.SinkParamWebhookRetryBackoff, &cfg.retryBackoff},
+		{changefeedbase.SinkParamWebhookClientTimeout, &cfg.clientTimeout},
+	} {
+		if v := q.Get(p.param); v != `` {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return webhookSinkConfig{}, errors.Errorf(`param %s must be a duration: %s`, p.param, err)
+			}
+			if d <= 0 {
+				return webhookSinkConfig{}, errors.Errorf(`param %s must be positive: %s`, p.param, v)
+			}
+			*p.dest = d
+		}
+		q.Del(p.param)
+	}
+
+	// Every remaining parameter is an error, so the endpoint itself is sent
+	// without a query string.
+	endpoint := *u
+	endpoint.Scheme = `https`
+	endpoint.RawQuery = ``
+	cfg.url = endpoint.String()
+	return cfg, nil
+}
+
+// webhookSinkMessage is a single row in the JSON body POSTed by the webhook
+// sink. The key and value are the already encoded JSON produced by the
+// changefeed's encoder and are embedded verbatim.
+type webhookSinkMessage struct {
+	Topic string            `json:"topic"`
+	Key   gojson.RawMessage `json:"key"`
+	Value gojson.RawMessage `json:"value"`
+}
+
+// webhookSinkPayload is the JSON body of a batch of rows POSTed by the
+// webhook sink.
+type webhookSinkPayload struct {
+	Payload []webhookSinkMessage `json:"payload"`
+	Length  int                  `json:"length"`
+}
+
+// webhookSinkRequest is a single POST to the webhook endpoint, either a batch
+// of rows or a resolved timestamp.
+type webhookSinkRequest struct {
+	body []byte
+}
+
+// webhookSink emits to an HTTPS endpoint. Rows are batched and every batch
+// (and every resolved timestamp) is POSTed as a JSON document by a single
+// worker goroutine, so requests are delivered in the order they were emitted.
+// Like kafkaSink, it is not concurrency-safe; all calls to Emit and Flush
+// should be from the same goroutine.
+type webhookSink struct {
+	cfg    webhookSinkConfig
+	client *http.Client
+	topics map[string]struct{}
+
+	requestCh    chan webhookSinkRequest
+	stopWorkerCh chan struct{}
+	worker       sync.WaitGroup
+
+	mu struct {
+		syncutil.Mutex
+		// batch holds the rows emitted since the last batch was taken to be
+		// sent.
+		batch      []webhookSinkMessage
+		batchBytes int64
+		// inflight counts the requests taken to be sent which the worker
+		// hasn't finished with. It is incremented in the same critical section
+		// a batch is taken in, so that the worker can tell whether a batch it
+		// takes would be sent ahead of an earlier request.
+		inflight int64
+		flushErr error
+		flushCh  chan struct{}
+	}
+}
+
+func makeWebhookSink(
+	ctx context.Context, cfg webhookSinkConfig, opts map[string]string, targets jobspb.ChangefeedTargets,
+) (Sink, error) {
+	switch changefeedbase.FormatType(opts[changefeedbase.OptFormat]) {
+	case ``, changefeedbase.OptFormatJSON:
+	default:
+		return nil, errors.Errorf(`%s=%s is not supported with the %s sink`,
+			changefeedbase.OptFormat, opts[changefeedbase.OptFormat], changefeedbase.SinkSchemeWebhookHTTPS)
+	}
+
+	client, err := makeWebhookClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sink := &webhookSink{
+		cfg:    cfg,
+		client: client,
+		topics: make(map[string]struct{}),
+	}
+	for _, t := range targets {
+		sink.topics[t.StatementTimeName] = struct{}{}
+	}
+	sink.start(ctx)
+	return sink, nil
+}
+
+func makeWebhookClient(cfg webhookSinkConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.tlsSkipVerify}
+	if cfg.caCert != nil {
+		caCertPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, errors.Wrap(err, `loading system cert pool`)
+		}
+		if caCertPool == nil {
+			caCertPool = x509.NewCertPool()
+		}
+		if !caCertPool.AppendCertsFromPEM(cfg.caCert) {
+			return nil, errors.Errorf(`failed to parse certificate data provided in %s`,
+				changefeedbase.SinkParamCACert)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	if cfg.clientCert != nil {
+		cert, err := tls.X509KeyPair(cfg.clientCert, cfg.clientKey)
+		if err != nil {
+			return nil, errors.Errorf(`invalid client certificate data provided: %s`, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{
+		Timeout: cfg.clientTimeout,
+		Transport: &http.Transport{
+			DialContext:     (&net.Dialer{Timeout: cfg.clientTimeout}).DialContext,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+func (s *webhookSink) start(ctx context.Context) {
+	s.requestCh = make(chan webhookSinkRequest, 1)
+	s.stopWorkerCh = make(chan struct{})
+	s.worker.Add(1)
+	go s.workerLoop(ctx)
+}
+
+// Close implements the Sink interface.
+func (s *webhookSink) Close() error {
+	close(s.stopWorkerCh)
+	s.worker.Wait()
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// EmitRow implements the Sink interface.
+func (s *webhookSink) EmitRow(
+	ctx context.Context, topic topicDescriptor, key, value []byte, updated hlc.Timestamp,
+) error {
+	topicName := topic.table.GetName()
+	if _, ok := s.topics[topicName]; !ok {
+		return errors.Errorf(`cannot emit to undeclared topic: %s`, topicName)
+	}
+
+	// The key and value are only valid until the next call to the encoder, so
+	// they have to be copied before being buffered.
+	msg := webhookSinkMessage{
+		Topic: topicName,
+		Key:   append(gojson.RawMessage(nil), key...),
+	}
+	if value != nil {
+		msg.Value = append(gojson.RawMessage(nil), value...)
+	}
+
+	s.mu.Lock()
+	s.mu.batch = append(s.mu.batch, msg)
+	s.mu.batchBytes += int64(len(key) + len(value))
+	full := len(s.mu.batch) >= s.cfg.batchMessages || s.mu.batchBytes >= s.cfg.batchBytes
+	s.mu.Unlock()
+
+	if full {
+		return s.sendBatch(ctx)
+	}
+	return nil
+}
+
+// EmitResolvedTimestamp implements the Sink interface.
+func (s *webhookSink) EmitResolvedTimestamp(
+	ctx context.Context, encoder Encoder, resolved hlc.Timestamp,
+) error {
+	// Any rows still batched were emitted before this resolved timestamp, so
+	// they have to go out first.
+	if err := s.sendBatch(ctx); err != nil {
+		return err
+	}
+	var noTopic string
+	payload, err := encoder.EncodeResolvedTimestamp(ctx, noTopic, resolved)
+	if err != nil {
+		return err
+	}
+	body := append([]byte(nil), payload...)
+
+	s.mu.Lock()
+	s.mu.inflight++
+	s.mu.Unlock()
+	return s.enqueue(ctx, webhookSinkRequest{body: body})
+}
+
+// Flush implements the Sink interface.
+func (s *webhookSink) Flush(ctx context.Context) error {
+	if err := s.sendBatch(ctx); err != nil {
+		return err
+	}
+
+	flushCh := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	inflight := s.mu.inflight
+	flushErr := s.mu.flushErr
+	s.mu.flushErr = nil
+	immediateFlush := inflight == 0 || flushErr != nil
+	if !immediateFlush {
+		s.mu.flushCh = flushCh
+	}
+	s.mu.Unlock()
+
+	if immediateFlush {
+		return flushErr
+	}
+
+	if log.V(1) {
+		log.Infof(ctx, "flush waiting for %d inflight webhook requests", inflight)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-flushCh:
+		s.mu.Lock()
+		flushErr := s.mu.flushErr
+		s.mu.flushErr = nil
+		s.mu.Unlock()
+		return flushErr
+	}
+}
+
+// sendBatch hands the currently buffered rows, if any, to the worker.
+func (s *webhookSink) sendBatch(ctx context.Context) error {
+	batch := s.takeBatch(false /* onlyIfIdle */)
+	if batch == nil {
+		return nil
+	}
+	body, err := gojson.Marshal(webhookSinkPayload{Payload: batch, Length: len(batch)})
+	if err != nil {
+		s.finishInflight()
+		return err
+	}
+	return s.enqueue(ctx, webhookSinkRequest{body: body})
+}
+
+// takeBatch returns the currently buffered rows, if any, and counts them as an
+// inflight request. If onlyIfIdle is set, nothing is returned while other
+// requests are inflight.
+func (s *webhookSink) takeBatch(onlyIfIdle bool) []webhookSinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.mu.batch) == 0 || (onlyIfIdle && s.mu.inflight > 0) {
+		return nil
+	}
+	batch := s.mu.batch
+	s.mu.batch = nil
+	s.mu.batchBytes = 0
+	s.mu.inflight++
+	return batch
+}
+
+// enqueue hands req, which the caller has already counted as inflight, to the
+// worker.
+func (s *webhookSink) enqueue(ctx context.Context, req webhookSinkRequest) error {
+	select {
+	case <-ctx.Done():
+		s.finishInflight()
+		return ctx.Err()
+	case <-s.stopWorkerCh:
+		s.finishInflight()
+		return errors.New(`cannot emit to a closed sink`)
+	case s.requestCh <- req:
+	}
+	return nil
+}
+
+// finishInflight marks one inflight request as done, either because the worker
+// handled it or because it was never handed to the worker, and wakes up a
+// waiting Flush once no requests remain inflight.
+func (s *webhookSink) finishInflight() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.inflight--
+	if s.mu.inflight == 0 && s.mu.flushCh != nil {
+		s.mu.flushCh <- struct{}{}
+		s.mu.flushCh = nil
+	}
+}
+
+func (s *webhookSink) workerLoop(ctx context.Context) {
+	defer s.worker.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// Abandon any in-progress request (and its retries) once the sink is
+		// closed. Close doesn't guarantee delivery of outstanding messages.
+		select {
+		case <-s.stopWorkerCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(s.cfg.flushFrequency)
+	defer ticker.Stop()
+
+	for {
+		var body []byte
+		var err error
+		select {
+		case <-s.stopWorkerCh:
+			return
+		case <-ticker.C:
+			// Time-based batching. The batch is sent directly by the worker,
+			// but only if no other request is inflight since it would
+			// otherwise overtake those still waiting in requestCh. Any rows
+			// left behind go out with the next tick, full batch or Flush.
+			batch := s.takeBatch(true /* onlyIfIdle */)
+			if batch == nil {
+				continue
+			}
+			body, err = gojson.Marshal(webhookSinkPayload{Payload: batch, Length: len(batch)})
+		case req := <-s.requestCh:
+			body = req.body
+		}
+
+		if err == nil {
+			err = s.post(ctx, body)
+		}
+		if err != nil {
+			s.recordFlushErr(err)
+		}
+		s.finishInflight()
+	}
+}
+
+func (s *webhookSink) recordFlushErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.flushErr == nil {
+		s.mu.flushErr = err
+	}
+}
+
+// post sends body to the webhook endpoint, retrying with exponential backoff
+// on connection errors and responses that may succeed when retried.
+func (s *webhookSink) post(ctx context.Context, body []byte) error {
+	opts := retry.Options{
+		InitialBackoff: s.cfg.retryBackoff,
+		MaxBackoff:     s.cfg.retryBackoff * 16,
+		Multiplier:     2,
+	}
+	var err error
+	// The attempts are counted here rather than with opts.MaxRetries, for
+	// which zero means retrying forever.
+	for r, attempt := retry.StartWithCtx(ctx, opts), 0; r.Next(); attempt++ {
+		var retryable bool
+		retryable, err = s.postOnce(ctx, body)
+		if err == nil || !retryable || attempt >= s.cfg.maxRetries {
+			return err
+		}
+	}
+	return err
+}
+
+// postOnce makes a single attempt at sending body to the webhook endpoint and,
+// if it fails, returns whether it is worth retrying.
+func (s *webhookSink) postOnce(ctx context.Context, body []byte) (retryable bool, _ error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(`Content-Type`, webhookContentType)
+	if s.cfg.authHeader != `` {
+		req.Header.Set(`Authorization`, s.cfg.authHeader)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, errors.Wrap(err, `sending to webhook sink`)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return isRetryableWebhookStatus(resp.StatusCode), errors.Errorf(`webhook sink %s returned %s: %s`,
+			redactWebhookURL(s.cfg.url), resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return false, nil
+}
+
+// isRetryableWebhookStatus returns whether a request that got a non-2xx
+// response with the given status code may succeed when retried. Client errors
+// won't, except for timeouts and rate limiting.
+func isRetryableWebhookStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return code < 400 || code >= 500
+}
+
+// redactWebhookURL strips any userinfo from the endpoint so it can be
+// included in errors.
+func redactWebhookURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return `<unparseable url>`
+	}
+	u.User = nil
+	return u.String()
+}
@@ -41,4 +41,25 @@ example:
   --enterprise-encryption=path=cockroach-data,key=/keys/aes-128.key,old-key=plain</PRE>
 `,
 	}
+
+	ExternalIODir = cliflags.FlagInfo{
+		Name: "external-io-dir",
+		Description: `
+The local file path under which the backups given as nodelocal URIs or local
+paths are found. If left empty, defaults to the "extern" subdirectory of the
+default store directory.`,
+	}
+
+	ExportTableName = cliflags.FlagInfo{
+		Name: "table",
+		Description: `
+The fully qualified name of the table to export, e.g. "db.public.t" or "db.t".`,
+	}
+
+	ExportDestination = cliflags.FlagInfo{
+		Name: "destination",
+		Description: `
+The file to which the rows are exported as CSV. If left empty, the rows are
+written to the standard output.`,
+	}
 )
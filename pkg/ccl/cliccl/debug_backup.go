@@ -0,0 +1,607 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package cliccl
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/blobs"
+	"github.com/cockroachdb/cockroach/pkg/ccl/backupccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/cliccl/cliflagsccl"
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/cli"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/server"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// Defines the `cockroach debug backup` commands, which inspect backups
+// without a running cluster.
+
+// exportBatchSize is the number of KVs after which the exported rows are
+// decoded and written out.
+const exportBatchSize = 10000
+
+var debugBackupArgs struct {
+	externalIODir   string
+	exportTableName string
+	destination     string
+}
+
+func init() {
+	showCmd := &cobra.Command{
+		Use:   "show <backup_path>",
+		Short: "show backup summary",
+		Long: `
+Shows the metadata, spans, files and descriptors of the backup at 'backup_path'.
+To show an incremental backup, use the path of its directory, as listed by
+'debug backup list-incrementals'.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.MaybeDecorateGRPCError(runDebugBackupShow),
+	}
+
+	listIncrementalsCmd := &cobra.Command{
+		Use:   "list-incrementals <backup_path>",
+		Short: "list incremental backups",
+		Long: `
+Lists the full backup at 'backup_path' and the incremental backups appended to
+it, along with the time intervals they cover.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.MaybeDecorateGRPCError(runDebugBackupListIncrementals),
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export <backup_path> --table=<table_name>",
+		Short: "export table rows from a backup",
+		Long: `
+Exports the rows of a table, as of the end of the last incremental backup
+appended to the full backup at 'backup_path', as CSV.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.MaybeDecorateGRPCError(runDebugBackupExport),
+	}
+
+	backupCmds := &cobra.Command{
+		Use:   "backup [command]",
+		Short: "inspect backups",
+		Long:  `Commands for inspecting backups without a running cluster.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	backupCmds.AddCommand(showCmd, listIncrementalsCmd, exportCmd)
+	cli.DebugCmd.AddCommand(backupCmds)
+
+	cli.StringFlag(backupCmds.PersistentFlags(), &debugBackupArgs.externalIODir, cliflagsccl.ExternalIODir)
+	f := exportCmd.Flags()
+	cli.StringFlag(f, &debugBackupArgs.exportTableName, cliflagsccl.ExportTableName)
+	cli.StringFlag(f, &debugBackupArgs.destination, cliflagsccl.ExportDestination)
+}
+
+// newBlobFactory creates blob clients for nodelocal URIs, which are resolved
+// against the --external-io-dir directory.
+func newBlobFactory(ctx context.Context, dialing roachpb.NodeID) (blobs.BlobClient, error) {
+	if dialing != 0 {
+		return nil, errors.Errorf("only nodelocal://0 or nodelocal://self URIs can be inspected offline")
+	}
+	externalIODir := debugBackupArgs.externalIODir
+	if externalIODir == "" {
+		externalIODir = filepath.Join(server.DefaultStorePath, "extern")
+	}
+	return blobs.NewLocalClient(externalIODir)
+}
+
+func externalStorageFromURI(
+	ctx context.Context, uri string, user security.SQLUsername,
+) (cloud.ExternalStorage, error) {
+	return cloudimpl.ExternalStorageFromURI(ctx, uri, base.ExternalIODirConfig{},
+		cluster.MakeClusterSettings(), newBlobFactory, user, nil, nil)
+}
+
+// backupLayer is a full backup, or an incremental backup appended to it.
+type backupLayer struct {
+	// dir is the directory of the backup, relative to the full backup.
+	dir      string
+	uri      string
+	manifest backupccl.BackupManifest
+}
+
+// backupURI returns the URI of the backup at backupPath, which may be a local
+// path.
+func backupURI(backupPath string) string {
+	if !strings.Contains(backupPath, "://") {
+		return cloudimpl.MakeLocalStorageURI(backupPath)
+	}
+	return backupPath
+}
+
+// loadBackupChain reads the manifests of the full backup at uri and of the
+// incremental backups appended to it.
+func loadBackupChain(ctx context.Context, uri string) ([]backupLayer, error) {
+	store, err := externalStorageFromURI(ctx, uri, security.RootUserName())
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	if err := checkBackupNotEncrypted(ctx, store); err != nil {
+		return nil, err
+	}
+
+	incDirs, err := backupccl.FindPriorBackupLocations(ctx, store)
+	if err != nil {
+		if !errors.Is(err, cloudimpl.ErrListingUnsupported) {
+			return nil, err
+		}
+		// Without listing, only the full backup can be found.
+		incDirs = nil
+	}
+
+	layers := make([]backupLayer, 0, len(incDirs)+1)
+	for _, dir := range append([]string{""}, incDirs...) {
+		layerURI, err := appendPath(uri, dir)
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := backupccl.ReadBackupManifestFromURI(ctx, layerURI, security.RootUserName(),
+			externalStorageFromURI, nil)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, backupLayer{dir: dir, uri: layerURI, manifest: manifest})
+	}
+	return layers, nil
+}
+
+// checkBackupNotEncrypted returns an error if the backup in store is encrypted,
+// as its files cannot be read without its key.
+func checkBackupNotEncrypted(ctx context.Context, store cloud.ExternalStorage) error {
+	encrypted, err := backupccl.IsEncryptedBackup(ctx, store)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return errors.New("the backup is encrypted, and encrypted backups cannot be inspected offline")
+	}
+	return nil
+}
+
+func appendPath(uri string, dir string) (string, error) {
+	if dir == "" {
+		return uri, nil
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	parsed.Path = path.Join(parsed.Path, dir)
+	return parsed.String(), nil
+}
+
+// codecForBackup returns the codec of the keys of the backup, which is not the
+// system codec if the backup was taken by a tenant.
+func codecForBackup(manifest *backupccl.BackupManifest) keys.SQLCodec {
+	if len(manifest.Spans) > 0 {
+		if _, tenantID, err := keys.DecodeTenantPrefix(manifest.Spans[0].Key); err == nil {
+			return keys.MakeSQLCodec(tenantID)
+		}
+	}
+	return keys.SystemSQLCodec
+}
+
+func formatBackupTime(ts hlc.Timestamp) string {
+	if ts.IsEmpty() {
+		return ""
+	}
+	return timeutil.Unix(0, ts.WallTime).Format(time.RFC3339Nano)
+}
+
+func runDebugBackupShow(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	uri := backupURI(args[0])
+	store, err := externalStorageFromURI(ctx, uri, security.RootUserName())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := checkBackupNotEncrypted(ctx, store); err != nil {
+		return err
+	}
+	desc, err := backupccl.ReadBackupManifestFromURI(ctx, uri, security.RootUserName(),
+		externalStorageFromURI, nil)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "StartTime: %s (%s)\n", formatBackupTime(desc.StartTime), desc.StartTime)
+	fmt.Fprintf(w, "EndTime: %s (%s)\n", formatBackupTime(desc.EndTime), desc.EndTime)
+	fmt.Fprintf(w, "DataSize: %d (%s)\n", desc.EntryCounts.DataSize, humanizeutil.IBytes(desc.EntryCounts.DataSize))
+	fmt.Fprintf(w, "Rows: %d\n", desc.EntryCounts.Rows)
+	fmt.Fprintf(w, "IndexEntries: %d\n", desc.EntryCounts.IndexEntries)
+	fmt.Fprintf(w, "FormatVersion: %d\n", desc.FormatVersion)
+	fmt.Fprintf(w, "ClusterID: %s\n", desc.ClusterID)
+	fmt.Fprintf(w, "NodeID: %s\n", desc.NodeID)
+	fmt.Fprintf(w, "BuildInfo: %s\n", desc.BuildInfo.Short())
+	fmt.Fprintf(w, "Spans:\n")
+	for _, s := range desc.Spans {
+		fmt.Fprintf(w, "	%s\n", s)
+	}
+	if len(desc.IntroducedSpans) > 0 {
+		fmt.Fprintf(w, "IntroducedSpans:\n")
+		for _, s := range desc.IntroducedSpans {
+			fmt.Fprintf(w, "	%s\n", s)
+		}
+	}
+
+	fmt.Fprintf(w, "Files:\n")
+	var totalSize int64
+	for _, f := range desc.Files {
+		fmt.Fprintf(w, "	%s:\n", f.Path)
+		fmt.Fprintf(w, "		Span: %s\n", f.Span)
+		if f.LocalityKV != "" {
+			fmt.Fprintf(w, "		Locality: %s\n", f.LocalityKV)
+		}
+		if f.Path != "" && f.LocalityKV == "" {
+			size, err := store.Size(ctx, f.Path)
+			if err != nil {
+				if !errors.Is(err, cloudimpl.ErrFileDoesNotExist) {
+					return err
+				}
+				fmt.Fprintf(w, "		Size: missing\n")
+			} else {
+				totalSize += size
+				fmt.Fprintf(w, "		Size: %d (%s)\n", size, humanizeutil.IBytes(size))
+			}
+		}
+		fmt.Fprintf(w, "		Sha512: %0128x\n", f.Sha512)
+		fmt.Fprintf(w, "		DataSize: %d (%s)\n", f.EntryCounts.DataSize, humanizeutil.IBytes(f.EntryCounts.DataSize))
+		fmt.Fprintf(w, "		Rows: %d\n", f.EntryCounts.Rows)
+		fmt.Fprintf(w, "		IndexEntries: %d\n", f.EntryCounts.IndexEntries)
+	}
+	fmt.Fprintf(w, "FilesSize: %d (%s)\n", totalSize, humanizeutil.IBytes(totalSize))
+
+	// Note that these descriptors could be from any past version of the cluster,
+	// in case more fields need to be added to the output.
+	codec := codecForBackup(&desc)
+	spans, _ := roachpb.MergeSpans(append([]roachpb.Span(nil), desc.Spans...))
+	fmt.Fprintf(w, "Descriptors:\n")
+	for i := range desc.Descriptors {
+		d := &desc.Descriptors[i]
+		id, name := descpb.GetDescriptorID(d), descpb.GetDescriptorName(d)
+		switch {
+		case d.GetDatabase() != nil:
+			fmt.Fprintf(w, "	%d: %s (database)\n", id, name)
+		case d.GetSchema() != nil:
+			fmt.Fprintf(w, "	%d: %s (schema)\n", id, name)
+		case d.GetType() != nil:
+			fmt.Fprintf(w, "	%d: %s (type)\n", id, name)
+		default:
+			raw := descpb.TableFromDescriptor(d, hlc.Timestamp{})
+			if raw == nil {
+				continue
+			}
+			fmt.Fprintf(w, "	%d: %s (table, %s)\n", id, name, strings.ToLower(raw.State.String()))
+			if raw.State == descpb.DescriptorState_DROP {
+				continue
+			}
+			table := tabledesc.NewImmutable(*raw)
+			if err := table.ForeachNonDropIndex(func(idx *descpb.IndexDescriptor) error {
+				sp := table.IndexSpan(codec, idx.ID)
+				coverage := "covered"
+				if !spansContain(spans, sp) {
+					coverage = "not covered"
+				}
+				fmt.Fprintf(w, "		%s: %s (%s)\n", idx.Name, sp, coverage)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spansContain returns whether sp is contained in one of the merged spans.
+func spansContain(spans []roachpb.Span, sp roachpb.Span) bool {
+	for _, s := range spans {
+		if s.Contains(sp) {
+			return true
+		}
+	}
+	return false
+}
+
+func runDebugBackupListIncrementals(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	layers, err := loadBackupChain(ctx, backupURI(args[0]))
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 2 /* minwidth */, 1 /* tabwidth */, 2 /* padding */, ' ', 0)
+	fmt.Fprintf(tw, "path\tstart time\tend time\n")
+	for _, l := range layers {
+		fmt.Fprintf(tw, "/%s\t%s\t%s\n",
+			l.dir, formatBackupTime(l.manifest.StartTime), formatBackupTime(l.manifest.EndTime))
+	}
+	return tw.Flush()
+}
+
+func runDebugBackupExport(cmd *cobra.Command, args []string) error {
+	if debugBackupArgs.exportTableName == "" {
+		return errors.Newf("the table to export must be specified with --%s",
+			cliflagsccl.ExportTableName.Name)
+	}
+
+	ctx := context.Background()
+	layers, err := loadBackupChain(ctx, backupURI(args[0]))
+	if err != nil {
+		return err
+	}
+	last := &layers[len(layers)-1].manifest
+	table, err := findTableInBackup(last.Descriptors, debugBackupArgs.exportTableName)
+	if err != nil {
+		return err
+	}
+	if table.ContainsUserDefinedTypes() {
+		return errors.Newf("exporting table %s with user-defined types is not supported",
+			debugBackupArgs.exportTableName)
+	}
+
+	w := cmd.OutOrStdout()
+	if debugBackupArgs.destination != "" {
+		f, err := os.Create(debugBackupArgs.destination)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return exportTableRows(ctx, w, codecForBackup(last), table, layers)
+}
+
+// findTableInBackup returns the descriptor of the public table named name in
+// descs, where name is qualified with its database, and optionally its schema.
+func findTableInBackup(descs []descpb.Descriptor, name string) (*tabledesc.Immutable, error) {
+	tn, err := parser.ParseQualifiedTableName(name)
+	if err != nil {
+		return nil, err
+	}
+	if !tn.ExplicitSchema {
+		return nil, errors.Newf("table name %s must be qualified with its database", name)
+	}
+	dbName, scName := tn.Catalog(), tn.Schema()
+	if !tn.ExplicitCatalog {
+		dbName, scName = scName, tree.PublicSchema
+	}
+
+	var dbID descpb.ID
+	for i := range descs {
+		if db := descs[i].GetDatabase(); db != nil && db.Name == dbName {
+			dbID = db.ID
+		}
+	}
+	if dbID == descpb.InvalidID {
+		return nil, errors.Newf("database %s not found in backup", dbName)
+	}
+	scID := descpb.ID(keys.PublicSchemaID)
+	if scName != tree.PublicSchema {
+		scID = descpb.InvalidID
+		for i := range descs {
+			if sc := descs[i].GetSchema(); sc != nil && sc.ParentID == dbID && sc.Name == scName {
+				scID = sc.ID
+			}
+		}
+		if scID == descpb.InvalidID {
+			return nil, errors.Newf("schema %s.%s not found in backup", dbName, scName)
+		}
+	}
+	for i := range descs {
+		raw := descpb.TableFromDescriptor(&descs[i], hlc.Timestamp{})
+		if raw == nil || raw.State != descpb.DescriptorState_PUBLIC {
+			continue
+		}
+		table := tabledesc.NewImmutable(*raw)
+		if table.GetParentID() == dbID && table.GetParentSchemaID() == scID && table.GetName() == tn.Table() {
+			return table, nil
+		}
+	}
+	return nil, errors.Newf("table %s not found in backup", name)
+}
+
+// exportTableRows writes the rows of table, as of the end of the last layer,
+// as CSV to w. The versions of the rows in the files of all the layers are
+// merged and only the latest one is exported, skipping the deleted rows.
+func exportTableRows(
+	ctx context.Context,
+	w io.Writer,
+	codec keys.SQLCodec,
+	table *tabledesc.Immutable,
+	layers []backupLayer,
+) error {
+	span := table.PrimaryIndexSpan(codec)
+
+	// The files are read block by block as the iterators are positioned, so
+	// their stores stay open until the rows are exported.
+	var stores []cloud.ExternalStorage
+	defer func() {
+		for _, store := range stores {
+			store.Close()
+		}
+	}()
+	var iters []storage.SimpleMVCCIterator
+	for _, l := range layers {
+		if err := func() error {
+			store, err := externalStorageFromURI(ctx, l.uri, security.RootUserName())
+			if err != nil {
+				return err
+			}
+			stores = append(stores, store)
+			for _, f := range l.manifest.Files {
+				if f.Path == "" || !f.Span.Overlaps(span) {
+					continue
+				}
+				if f.LocalityKV != "" {
+					return errors.Newf("file %s is stored in the backup for locality %s, "+
+						"which cannot be exported", f.Path, f.LocalityKV)
+				}
+				iter, err := storageccl.ExternalSSTReader(ctx, store, f.Path)
+				if err != nil {
+					return err
+				}
+				iters = append(iters, iter)
+			}
+			return nil
+		}(); err != nil {
+			for _, iter := range iters {
+				iter.Close()
+			}
+			return err
+		}
+	}
+	iter := storage.MakeMultiIterator(iters)
+	defer iter.Close()
+
+	var colIdxMap catalog.TableColMap
+	var valNeededForCol util.FastIntSet
+	for colIdx := range table.Columns {
+		colIdxMap.Set(table.Columns[colIdx].ID, colIdx)
+		valNeededForCol.Add(colIdx)
+	}
+	var rf row.Fetcher
+	if err := rf.Init(
+		ctx,
+		codec,
+		false, /* reverse */
+		descpb.ScanLockingStrength_FOR_NONE,
+		descpb.ScanLockingWaitPolicy_BLOCK,
+		false, /* isCheck */
+		&rowenc.DatumAlloc{},
+		nil, /* memMonitor */
+		row.FetcherTableArgs{
+			Spans:            roachpb.Spans{span},
+			Desc:             table,
+			Index:            &table.PrimaryIndex,
+			ColIdxMap:        colIdxMap,
+			IsSecondaryIndex: false,
+			Cols:             table.Columns,
+			ValNeededForCol:  valNeededForCol,
+		},
+	); err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(w)
+	record := make([]string, 0, len(table.Columns))
+	var kvs []roachpb.KeyValue
+	flush := func() error {
+		if len(kvs) == 0 {
+			return nil
+		}
+		if err := rf.StartScanFrom(ctx, &row.SpanKVFetcher{KVs: kvs}); err != nil {
+			return err
+		}
+		kvs = nil
+		for {
+			datums, _, _, err := rf.NextRowDecoded(ctx)
+			if err != nil {
+				return err
+			}
+			if datums == nil {
+				return nil
+			}
+			record = record[:0]
+			for i, d := range datums {
+				if table.Columns[i].Hidden {
+					continue
+				}
+				if d == tree.DNull {
+					record = append(record, "")
+				} else {
+					record = append(record, tree.AsStringWithFlags(d, tree.FmtExport))
+				}
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The KVs are decoded in batches, which must not split the column families
+	// of a row.
+	var lastRow roachpb.Key
+	for iter.SeekGE(storage.MVCCKey{Key: span.Key}); ; iter.NextKey() {
+		ok, err := iter.Valid()
+		if err != nil {
+			return err
+		}
+		if !ok || iter.UnsafeKey().Key.Compare(span.EndKey) >= 0 {
+			break
+		}
+		if len(iter.UnsafeValue()) == 0 {
+			// The row was deleted.
+			continue
+		}
+		key := iter.UnsafeKey()
+		rowKey, err := keys.EnsureSafeSplitKey(key.Key)
+		if err != nil {
+			return err
+		}
+		if len(kvs) >= exportBatchSize && !rowKey.Equal(lastRow) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		lastRow = append(lastRow[:0], rowKey...)
+		kvs = append(kvs, roachpb.KeyValue{
+			Key: append(roachpb.Key(nil), key.Key...),
+			Value: roachpb.Value{
+				RawBytes:  append([]byte(nil), iter.UnsafeValue()...),
+				Timestamp: key.Timestamp,
+			},
+		})
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
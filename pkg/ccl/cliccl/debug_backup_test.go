@@ -0,0 +1,122 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package cliccl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	dir, cleanupDir := testutils.TempDir(t)
+	defer cleanupDir()
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer srv.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE DATABASE data`)
+	sqlDB.Exec(t, `CREATE TABLE data.t (
+		id INT PRIMARY KEY, s STRING, n INT, FAMILY f1 (id, s), FAMILY f2 (n)
+	)`)
+	sqlDB.Exec(t, `INSERT INTO data.t VALUES (1, 'a', 10), (2, 'b, c', NULL), (3, 'd', 30)`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO 'nodelocal://1/foo'`)
+	sqlDB.Exec(t, `UPDATE data.t SET n = 11 WHERE id = 1`)
+	sqlDB.Exec(t, `DELETE FROM data.t WHERE id = 3`)
+	sqlDB.Exec(t, `INSERT INTO data.t VALUES (4, 'e', 40)`)
+	sqlDB.Exec(t, `BACKUP DATABASE data TO 'nodelocal://1/foo'`)
+
+	defer func(args struct {
+		externalIODir   string
+		exportTableName string
+		destination     string
+	}) {
+		debugBackupArgs = args
+	}(debugBackupArgs)
+	debugBackupArgs.externalIODir = dir
+
+	run := func(
+		t *testing.T, fn func(*cobra.Command, []string) error, args ...string,
+	) string {
+		var out bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&out)
+		require.NoError(t, fn(cmd, args))
+		return out.String()
+	}
+
+	t.Run("show", func(t *testing.T) {
+		out := run(t, runDebugBackupShow, "nodelocal://0/foo")
+		require.Contains(t, out, "StartTime:  (0,0)\n")
+		require.Contains(t, out, ": data (database)\n")
+		require.Contains(t, out, ": t (table, public)\n")
+		require.Regexp(t, `primary: /Table/\d+/\{1-2\} \(covered\)`, out)
+		require.Regexp(t, `Size: \d+ `, out)
+		require.NotContains(t, out, "missing")
+	})
+
+	t.Run("list-incrementals", func(t *testing.T) {
+		out := run(t, runDebugBackupListIncrementals, "nodelocal://0/foo")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 3)
+		require.Regexp(t, `^path\s+start time\s+end time$`, lines[0])
+		require.Regexp(t, `^/\s+\S+$`, lines[1])
+		require.Regexp(t, `^/\d+/\S+\s+\S+\s+\S+$`, lines[2])
+
+		// The incremental backup can be shown on its own.
+		inc := strings.Fields(lines[2])[0]
+		out = run(t, runDebugBackupShow, "nodelocal://0/foo"+inc)
+		require.NotContains(t, out, "StartTime:  (0,0)\n")
+	})
+
+	t.Run("export", func(t *testing.T) {
+		debugBackupArgs.exportTableName = "data.t"
+		out := run(t, runDebugBackupExport, "nodelocal://0/foo")
+		require.Equal(t, "1,a,11\n2,\"b, c\",\n4,e,40\n", out)
+
+		debugBackupArgs.exportTableName = "data.public.t"
+		debugBackupArgs.destination = filepath.Join(dir, "t.csv")
+		require.Empty(t, run(t, runDebugBackupExport, "nodelocal://0/foo"))
+		exported, err := ioutil.ReadFile(debugBackupArgs.destination)
+		require.NoError(t, err)
+		require.Equal(t, "1,a,11\n2,\"b, c\",\n4,e,40\n", string(exported))
+		debugBackupArgs.destination = ""
+
+		for _, name := range []string{"t", "data.u", "nodata.t", "data.sc.t"} {
+			debugBackupArgs.exportTableName = name
+			cmd := &cobra.Command{}
+			cmd.SetOut(ioutil.Discard)
+			require.Error(t, runDebugBackupExport(cmd, []string{"nodelocal://0/foo"}), name)
+		}
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		sqlDB.Exec(t, `BACKUP DATABASE data TO 'nodelocal://1/enc' WITH encryption_passphrase = 'abc'`)
+		cmd := &cobra.Command{}
+		cmd.SetOut(ioutil.Discard)
+		require.EqualError(t, runDebugBackupShow(cmd, []string{"nodelocal://0/enc"}),
+			"the backup is encrypted, and encrypted backups cannot be inspected offline")
+	})
+}
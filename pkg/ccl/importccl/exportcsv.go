@@ -13,6 +13,7 @@ import (
 	"compress/gzip"
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
@@ -21,7 +22,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowexec"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
 	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
 	"github.com/cockroachdb/cockroach/pkg/util/encoding/csv"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
@@ -31,6 +34,27 @@ import (
 const exportFilePatternPart = "%part%"
 const exportFilePatternDefault = exportFilePatternPart + ".csv"
 
+// exportFileWriter accumulates the rows of a single exported file in memory.
+type exportFileWriter interface {
+	// WriteRow appends a row, which excludes any partition columns, to the
+	// file.
+	WriteRow(row rowenc.EncDatumRow) error
+	// Finish completes the file, after which Bytes and Len return its
+	// contents.
+	Finish() error
+	// Bytes returns the contents of the completed file.
+	Bytes() []byte
+	// Len returns the size of the completed file.
+	Len() int
+	// MemUsage returns an estimate of the memory used by the writer, including
+	// the rows written to the current file.
+	MemUsage() int64
+	// FileName returns the name of the file for the given part.
+	FileName(spec execinfrapb.CSVWriterSpec, part string) string
+	// ResetBuffer prepares the writer for the next file.
+	ResetBuffer()
+}
+
 // csvExporter data structure to augment the compression
 // and csv writer, encapsulating the internals to make
 // exporting oblivious for the consumers
@@ -124,6 +148,63 @@ func newCSVExporter(sp execinfrapb.CSVWriterSpec) *csvExporter {
 	return exporter
 }
 
+// csvFileWriter formats rows as CSV records of an exported file.
+type csvFileWriter struct {
+	*csvExporter
+	typs    []*types.T
+	nullsAs *string
+	alloc   rowenc.DatumAlloc
+	f       *tree.FmtCtx
+	record  []string
+}
+
+var _ exportFileWriter = &csvFileWriter{}
+
+func newCSVFileWriter(spec execinfrapb.CSVWriterSpec, typs []*types.T) *csvFileWriter {
+	return &csvFileWriter{
+		csvExporter: newCSVExporter(spec),
+		typs:        typs,
+		nullsAs:     spec.Options.NullEncoding,
+		f:           tree.NewFmtCtx(tree.FmtExport),
+		record:      make([]string, len(typs)),
+	}
+}
+
+// WriteRow implements the exportFileWriter interface.
+func (w *csvFileWriter) WriteRow(row rowenc.EncDatumRow) error {
+	for i, ed := range row {
+		if ed.IsNull() {
+			if w.nullsAs != nil {
+				w.record[i] = *w.nullsAs
+				continue
+			}
+			return errors.New("NULL value encountered during EXPORT, " +
+				"use `WITH nullas` to specify the string representation of NULL")
+		}
+		if err := ed.EnsureDecoded(w.typs[i], &w.alloc); err != nil {
+			return err
+		}
+		ed.Datum.Format(w.f)
+		w.record[i] = w.f.String()
+		w.f.Reset()
+	}
+	return w.Write(w.record)
+}
+
+// MemUsage implements the exportFileWriter interface.
+func (w *csvFileWriter) MemUsage() int64 {
+	return exportWriterOverhead + int64(w.buf.Len())
+}
+
+// Finish implements the exportFileWriter interface.
+func (w *csvFileWriter) Finish() error {
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush csv writer")
+	}
+	// Close writer to ensure buffer and any compression footer is flushed.
+	return w.Close()
+}
+
 func newCSVWriterProcessor(
 	flowCtx *execinfra.FlowCtx,
 	processorID int32,
@@ -173,93 +254,55 @@ func (sp *csvWriter) Run(ctx context.Context) {
 		sp.input.Start(ctx)
 		input := execinfra.MakeNoMetadataRowSource(sp.input, sp.output)
 
-		alloc := &rowenc.DatumAlloc{}
-
-		writer := newCSVExporter(sp.spec)
-
-		var nullsAs string
-		if sp.spec.Options.NullEncoding != nil {
-			nullsAs = *sp.spec.Options.NullEncoding
+		partitioner, err := newExportPartitioner(sp.spec, typs)
+		if err != nil {
+			return err
 		}
-		f := tree.NewFmtCtx(tree.FmtExport)
-		defer f.Close()
 
-		csvRow := make([]string, len(typs))
+		// The rows of all partitions are buffered in memory until they are
+		// written out, so they are accounted for against a limited monitor.
+		memMonitor := execinfra.NewLimitedMonitor(ctx, sp.flowCtx.EvalCtx.Mon, sp.flowCtx.Cfg, "export-mem")
+		defer memMonitor.Stop(ctx)
+		memAcc := memMonitor.MakeBoundAccount()
+		defer memAcc.Close(ctx)
+
+		// The destination is only opened once there is a file to write.
+		var es cloud.ExternalStorage
+		defer func() {
+			if es != nil {
+				es.Close()
+			}
+		}()
 
-		chunk := 0
-		done := false
-		for {
-			var rows int64
-			writer.ResetBuffer()
-			for {
-				if sp.spec.ChunkRows > 0 && rows >= sp.spec.ChunkRows {
-					break
-				}
-				row, err := input.NextRow()
+		// flush writes out the buffered rows of a partition as a single file.
+		flush := func(p *exportPartition) error {
+			if es == nil {
+				conf, err := cloudimpl.ExternalStorageConfFromURI(sp.spec.Destination, sp.spec.User())
 				if err != nil {
 					return err
 				}
-				if row == nil {
-					done = true
-					break
-				}
-				rows++
-
-				for i, ed := range row {
-					if ed.IsNull() {
-						if sp.spec.Options.NullEncoding != nil {
-							csvRow[i] = nullsAs
-							continue
-						} else {
-							return errors.New("NULL value encountered during EXPORT, " +
-								"use `WITH nullas` to specify the string representation of NULL")
-						}
-					}
-					if err := ed.EnsureDecoded(typs[i], alloc); err != nil {
-						return err
-					}
-					ed.Datum.Format(f)
-					csvRow[i] = f.String()
-					f.Reset()
-				}
-				if err := writer.Write(csvRow); err != nil {
+				if es, err = sp.flowCtx.Cfg.ExternalStorage(ctx, conf); err != nil {
 					return err
 				}
 			}
-			if rows < 1 {
-				break
-			}
-			if err := writer.Flush(); err != nil {
-				return errors.Wrap(err, "failed to flush csv writer")
-			}
-
-			conf, err := cloudimpl.ExternalStorageConfFromURI(sp.spec.Destination, sp.spec.User())
-			if err != nil {
-				return err
-			}
-			es, err := sp.flowCtx.Cfg.ExternalStorage(ctx, conf)
-			if err != nil {
-				return err
-			}
-			defer es.Close()
-
 			nodeID, err := sp.flowCtx.EvalCtx.NodeID.OptionalNodeIDErr(47970)
 			if err != nil {
 				return err
 			}
 
-			part := fmt.Sprintf("n%d.%d", nodeID, chunk)
-			chunk++
-			filename := writer.FileName(sp.spec, part)
-			// Close writer to ensure buffer and any compression footer is flushed.
-			err = writer.Close()
-			if err != nil {
-				return errors.Wrapf(err, "failed to close exporting writer")
+			if err := p.writer.Finish(); err != nil {
+				return errors.Wrap(err, "failed to close exporting writer")
 			}
 
-			size := writer.Len()
+			part := fmt.Sprintf("n%d.%d", nodeID, p.chunk)
+			p.chunk++
+			filename := p.writer.FileName(sp.spec, part)
+			if p.dir != "" {
+				filename = path.Join(p.dir, filename)
+			}
+			size := p.writer.Len()
 
-			if err := es.WriteFile(ctx, filename, bytes.NewReader(writer.Bytes())); err != nil {
+			if err := es.WriteFile(ctx, filename, bytes.NewReader(p.writer.Bytes())); err != nil {
 				return err
 			}
 			res := rowenc.EncDatumRow{
@@ -269,7 +312,7 @@ func (sp *csvWriter) Run(ctx context.Context) {
 				),
 				rowenc.DatumToEncDatum(
 					types.Int,
-					tree.NewDInt(tree.DInt(rows)),
+					tree.NewDInt(tree.DInt(p.rows)),
 				),
 				rowenc.DatumToEncDatum(
 					types.Int,
@@ -286,9 +329,72 @@ func (sp *csvWriter) Run(ctx context.Context) {
 				// another error... so do we really need another one?
 				return errors.New("unexpected closure of consumer")
 			}
-			if done {
+			p.rows = 0
+			p.writer.ResetBuffer()
+			memAcc.Shrink(ctx, p.memUsed)
+			p.memUsed = 0
+			return nil
+		}
+
+		// flushAll writes out the buffered rows of every partition and releases
+		// their writers, which are recreated for the partitions of later rows.
+		flushAll := func() error {
+			for _, p := range partitioner.sorted() {
+				if p.rows > 0 {
+					if err := flush(p); err != nil {
+						return err
+					}
+				}
+				p.writer = nil
+			}
+			return nil
+		}
+
+		for {
+			row, err := input.NextRow()
+			if err != nil {
+				return err
+			}
+			if row == nil {
 				break
 			}
+			p, err := partitioner.partitionFor(row)
+			if err != nil {
+				return err
+			}
+			if err := p.writer.WriteRow(partitioner.fileRow(row)); err != nil {
+				return err
+			}
+			p.rows++
+			if sp.spec.ChunkRows > 0 && p.rows >= sp.spec.ChunkRows {
+				if err := flush(p); err != nil {
+					return err
+				}
+				continue
+			}
+			if size := p.writer.MemUsage(); size > p.memUsed {
+				if err := memAcc.Grow(ctx, size-p.memUsed); err != nil {
+					if !sqlerrors.IsOutOfMemoryError(err) {
+						return err
+					}
+					// The buffered rows no longer fit in memory, so they are
+					// written out even if their files end up smaller than
+					// chunk_rows.
+					if err := flushAll(); err != nil {
+						return err
+					}
+					continue
+				}
+				p.memUsed = size
+			}
+		}
+		for _, p := range partitioner.sorted() {
+			if p.rows < 1 {
+				continue
+			}
+			if err := flush(p); err != nil {
+				return err
+			}
 		}
 
 		return nil
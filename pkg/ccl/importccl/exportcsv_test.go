@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
@@ -486,3 +487,144 @@ func TestExportPrivileges(t *testing.T) {
 	_, err = testuser.Exec(`EXPORT INTO CSV $1 FROM TABLE privs`, dest)
 	require.NoError(t, err)
 }
+
+func TestExportParquet(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	dir, cleanupDir := testutils.TempDir(t)
+	defer cleanupDir()
+
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer srv.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	const create = `(
+		i INT PRIMARY KEY, s STRING, f FLOAT8, d DECIMAL(10, 2), ts TIMESTAMPTZ, b BOOL, arr INT[], j JSONB)`
+	sqlDB.Exec(t, `CREATE TABLE foo `+create)
+	sqlDB.Exec(t, `INSERT INTO foo VALUES
+		(1, 'a', 1.5, 12.34, '2021-01-01 00:00:00+00', true, ARRAY[1, 2], '{"k": [1, 2]}'),
+		(2, NULL, NULL, NULL, NULL, NULL, NULL, NULL),
+		(3, '✅', -2.5, -0.01, '2021-06-01 12:30:00+00', false, ARRAY[], '[]')`)
+
+	for _, compression := range []string{"", "gzip", "snappy"} {
+		t.Run("compression="+compression, func(t *testing.T) {
+			opts := ""
+			if compression != "" {
+				opts = "WITH compression = " + compression
+			}
+			var filename string
+			var rows int
+			sqlDB.QueryRow(t, fmt.Sprintf(
+				`EXPORT INTO PARQUET 'nodelocal://0/%s' %s FROM TABLE foo`, t.Name(), opts),
+			).Scan(&filename, &rows, new(int))
+			require.Regexp(t, `^export.*-n1\.0\.parquet$`, filename)
+			require.Equal(t, 3, rows)
+
+			sqlDB.Exec(t, `CREATE TABLE foo2 `+create)
+			defer sqlDB.Exec(t, `DROP TABLE foo2`)
+			sqlDB.Exec(t, fmt.Sprintf(`IMPORT INTO foo2 PARQUET DATA ('nodelocal://0/%s/%s') WITH strict_validation`,
+				t.Name(), filename))
+			sqlDB.CheckQueryResults(t,
+				`SELECT * FROM foo2 ORDER BY i`, sqlDB.QueryStr(t, `SELECT * FROM foo ORDER BY i`))
+		})
+	}
+
+	sqlDB.ExpectErr(t, `nullas option is not supported for PARQUET export`,
+		`EXPORT INTO PARQUET 'nodelocal://0/err' WITH nullas = '' FROM TABLE foo`)
+	sqlDB.ExpectErr(t, `unsupported compression codec snappy`,
+		`EXPORT INTO CSV 'nodelocal://0/err' WITH compression = snappy FROM TABLE foo`)
+	sqlDB.ExpectErr(t, `duplicate column name i in parquet export`,
+		`EXPORT INTO PARQUET 'nodelocal://0/err' FROM SELECT i, i FROM foo`)
+}
+
+func TestExportPartitionBy(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	dir, cleanupDir := testutils.TempDir(t)
+	defer cleanupDir()
+
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir})
+	defer srv.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE TABLE t (id INT PRIMARY KEY, region STRING, year INT, v INT)`)
+	sqlDB.Exec(t, `INSERT INTO t VALUES
+		(1, 'us/east', 2020, 10), (2, 'eu', 2020, 20), (3, NULL, 2021, 30), (4, 'eu', 2020, 40),
+		(5, 'eu', 2021, 50)`)
+
+	t.Run("csv", func(t *testing.T) {
+		var files []string
+		for _, row := range sqlDB.QueryStr(t, `EXPORT INTO CSV 'nodelocal://0/csv' PARTITION BY (region, year)
+			WITH chunk_rows = '1' FROM SELECT * FROM t ORDER BY id`) {
+			files = append(files, row[0])
+			require.Equal(t, "1", row[1])
+		}
+		require.Len(t, files, 5)
+		require.Regexp(t, `^region=__HIVE_DEFAULT_PARTITION__/year=2021/export.*-n1\.0\.csv$`, files[2])
+
+		expected := map[string][]string{
+			"region=eu/year=2020":                         {"2,20\n", "4,40\n"},
+			"region=eu/year=2021":                         {"5,50\n"},
+			"region=us%2Feast/year=2020":                  {"1,10\n"},
+			"region=__HIVE_DEFAULT_PARTITION__/year=2021": {"3,30\n"},
+		}
+		for partition, chunks := range expected {
+			for i, chunk := range chunks {
+				content := readFileByGlob(t,
+					filepath.Join(dir, "csv", partition, fmt.Sprintf("export*-n1.%d.csv", i)))
+				require.Equal(t, chunk, string(content), partition)
+			}
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		sqlDB.Exec(t, `EXPORT INTO PARQUET 'nodelocal://0/parquet' PARTITION BY (region)
+			FROM SELECT id, region, v FROM t`)
+
+		sqlDB.Exec(t, `CREATE TABLE eu (id INT PRIMARY KEY, v INT)`)
+		sqlDB.Exec(t, `IMPORT INTO eu PARQUET DATA ('nodelocal://0/parquet/region=eu/export*-n1.0.parquet')
+			WITH strict_validation`)
+		sqlDB.CheckQueryResults(t, `SELECT * FROM eu ORDER BY id`, [][]string{{"2", "20"}, {"4", "40"}, {"5", "50"}})
+	})
+
+	sqlDB.ExpectErr(t, `PARTITION BY column "missing" does not exist`,
+		`EXPORT INTO CSV 'nodelocal://0/err' PARTITION BY (missing) FROM TABLE t`)
+	sqlDB.ExpectErr(t, `EXPORT cannot partition by all exported columns`,
+		`EXPORT INTO CSV 'nodelocal://0/err' PARTITION BY (id) FROM SELECT id FROM t`)
+}
+
+func TestExportPartitionByMemoryLimit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	dir, cleanupDir := testutils.TempDir(t)
+	defer cleanupDir()
+
+	// With a tiny memory budget, the buffered rows never fit in memory and
+	// every row is written out as soon as it is exported.
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{
+		ExternalIODir: dir,
+		Knobs: base.TestingKnobs{
+			DistSQL: &execinfra.TestingKnobs{MemoryLimitBytes: 1},
+		},
+	})
+	defer srv.Stopper().Stop(context.Background())
+	sqlDB := sqlutils.MakeSQLRunner(db)
+
+	sqlDB.Exec(t, `CREATE TABLE t (id INT PRIMARY KEY, region STRING, v INT)`)
+	sqlDB.Exec(t, `INSERT INTO t VALUES (1, 'us', 10), (2, 'eu', 20), (3, 'us', 30), (4, 'eu', 40)`)
+
+	rows := sqlDB.QueryStr(t, `EXPORT INTO CSV 'nodelocal://0/csv' PARTITION BY (region)
+		FROM SELECT * FROM t ORDER BY id`)
+	require.Len(t, rows, 4)
+	expected := map[string][]string{
+		"region=eu": {"2,20\n", "4,40\n"},
+		"region=us": {"1,10\n", "3,30\n"},
+	}
+	for partition, chunks := range expected {
+		for i, chunk := range chunks {
+			content := readFileByGlob(t,
+				filepath.Join(dir, "csv", partition, fmt.Sprintf("export*-n1.%d.csv", i)))
+			require.Equal(t, chunk, string(content), partition)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl/parquetccl"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/errors"
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+)
+
+// exportParquetSchemaName is the name of the root of the schema of exported
+// Parquet files.
+const exportParquetSchemaName = "export"
+
+// parquetExportColumn describes how a single exported column is written.
+type parquetExportColumn struct {
+	name     string
+	typ      *types.T
+	encodeFn parquetccl.EncodeFn
+}
+
+// parquetFileWriter writes rows into an exported Parquet file. Each exported
+// column maps to an optional Parquet column of the same name, whose type is
+// determined by parquetccl. The rows of a file are buffered and written as a
+// single row group, compressed with the codec of the export.
+type parquetFileWriter struct {
+	cols   []parquetExportColumn
+	schema *parquetschema.SchemaDefinition
+	codec  parquet.CompressionCodec
+
+	buf   bytes.Buffer
+	fw    *goparquet.FileWriter
+	alloc rowenc.DatumAlloc
+	data  map[string]interface{}
+}
+
+var _ exportFileWriter = &parquetFileWriter{}
+
+func newParquetFileWriter(
+	spec execinfrapb.CSVWriterSpec, colNames []string, typs []*types.T,
+) (*parquetFileWriter, error) {
+	if len(colNames) != len(typs) {
+		return nil, errors.AssertionFailedf(
+			"expected %d column names, found %d", len(typs), len(colNames))
+	}
+	w := &parquetFileWriter{
+		cols: make([]parquetExportColumn, len(typs)),
+		data: make(map[string]interface{}, len(typs)),
+	}
+	switch spec.CompressionCodec {
+	case execinfrapb.FileCompression_Gzip:
+		w.codec = parquet.CompressionCodec_GZIP
+	case execinfrapb.FileCompression_Snappy:
+		w.codec = parquet.CompressionCodec_SNAPPY
+	default:
+		w.codec = parquet.CompressionCodec_UNCOMPRESSED
+	}
+
+	root := &parquetschema.ColumnDefinition{
+		SchemaElement: &parquet.SchemaElement{Name: exportParquetSchemaName},
+	}
+	seen := make(map[string]struct{}, len(typs))
+	for i := range typs {
+		if _, ok := seen[colNames[i]]; ok {
+			return nil, errors.Errorf(
+				"duplicate column name %s in parquet export, use aliases to rename columns", colNames[i])
+		}
+		seen[colNames[i]] = struct{}{}
+		def, encodeFn, err := parquetccl.ColumnDefinition(colNames[i], typs[i])
+		if err != nil {
+			return nil, err
+		}
+		w.cols[i] = parquetExportColumn{name: colNames[i], typ: typs[i], encodeFn: encodeFn}
+		root.Children = append(root.Children, def)
+	}
+	w.schema = parquetschema.SchemaDefinitionFromColumnDefinition(root)
+	if err := w.schema.ValidateStrict(); err != nil {
+		return nil, errors.Wrap(err, "invalid parquet schema")
+	}
+	w.ResetBuffer()
+	return w, nil
+}
+
+// WriteRow implements the exportFileWriter interface.
+func (w *parquetFileWriter) WriteRow(row rowenc.EncDatumRow) error {
+	for k := range w.data {
+		delete(w.data, k)
+	}
+	for i, ed := range row {
+		if ed.IsNull() {
+			continue
+		}
+		col := &w.cols[i]
+		if err := ed.EnsureDecoded(col.typ, &w.alloc); err != nil {
+			return err
+		}
+		v, err := col.encodeFn(ed.Datum)
+		if err != nil {
+			return errors.Wrapf(err, "column %s", col.name)
+		}
+		w.data[col.name] = v
+	}
+	return w.fw.AddData(w.data)
+}
+
+// Finish implements the exportFileWriter interface.
+func (w *parquetFileWriter) Finish() error {
+	return w.fw.Close()
+}
+
+// Bytes implements the exportFileWriter interface.
+func (w *parquetFileWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// Len implements the exportFileWriter interface.
+func (w *parquetFileWriter) Len() int {
+	return w.buf.Len()
+}
+
+// MemUsage implements the exportFileWriter interface.
+func (w *parquetFileWriter) MemUsage() int64 {
+	return exportWriterOverhead + w.fw.CurrentRowGroupSize() + int64(w.buf.Len())
+}
+
+// FileName implements the exportFileWriter interface. Parquet files are
+// compressed internally, so the name does not depend on the codec.
+func (w *parquetFileWriter) FileName(spec execinfrapb.CSVWriterSpec, part string) string {
+	pattern := exportFilePatternPart + ".parquet"
+	if spec.NamePattern != "" {
+		pattern = spec.NamePattern
+	}
+	return strings.Replace(pattern, exportFilePatternPart, part, -1)
+}
+
+// ResetBuffer implements the exportFileWriter interface.
+func (w *parquetFileWriter) ResetBuffer() {
+	w.buf.Reset()
+	w.fw = goparquet.NewFileWriter(&w.buf,
+		goparquet.WithSchemaDefinition(w.schema),
+		goparquet.WithCompressionCodec(w.codec),
+		goparquet.WithCreator(`CockroachDB`),
+	)
+}
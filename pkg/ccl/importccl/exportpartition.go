@@ -0,0 +1,199 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/errors"
+)
+
+// When EXPORT is given a PARTITION BY clause, each row is written to a file in
+// the Hive-style directory `col1=value1/col2=value2/` of the destination,
+// determined by the values of its partition columns. The partition columns
+// themselves are omitted from the files, as the directory layout already
+// records them. This is the layout expected by most data lake query engines
+// (Hive, Spark, Presto, Athena, ...).
+
+// exportHiveDefaultPartition is the directory value used for NULLs, matching
+// Hive's default.
+const exportHiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// exportWriterOverhead is an estimate of the memory used by an open file writer
+// regardless of the rows buffered in it, such as the buffer of a CSV writer.
+const exportWriterOverhead = 4 << 10
+
+// exportPartition holds the rows of a single partition which have not been
+// written out yet.
+type exportPartition struct {
+	// dir is the Hive-style directory of the partition, relative to the export
+	// destination. It is empty if the export is not partitioned.
+	dir string
+	// writer is nil if the partition has no buffered rows and its writer was
+	// released to bound memory usage.
+	writer exportFileWriter
+	// rows is the number of rows buffered in writer.
+	rows int64
+	// memUsed is the memory accounted for the rows buffered in writer.
+	memUsed int64
+	// chunk is the number of files of the partition written so far.
+	chunk int
+}
+
+// exportPartitioner routes the rows of an export to their partitions.
+type exportPartitioner struct {
+	spec     execinfrapb.CSVWriterSpec
+	typs     []*types.T
+	colNames []string
+	// fileCols are the ordinals of the input columns written to the files.
+	fileCols  []int
+	fileTypes []*types.T
+	fileNames []string
+
+	partitions map[string]*exportPartition
+	alloc      rowenc.DatumAlloc
+	f          *tree.FmtCtx
+	buf        strings.Builder
+	row        rowenc.EncDatumRow
+}
+
+func newExportPartitioner(
+	spec execinfrapb.CSVWriterSpec, typs []*types.T,
+) (*exportPartitioner, error) {
+	if len(spec.PartitionCols) > 0 && len(spec.ColNames) != len(typs) {
+		return nil, errors.AssertionFailedf(
+			"expected %d column names, found %d", len(typs), len(spec.ColNames))
+	}
+	p := &exportPartitioner{
+		spec:       spec,
+		typs:       typs,
+		colNames:   spec.ColNames,
+		partitions: make(map[string]*exportPartition),
+		f:          tree.NewFmtCtx(tree.FmtExport),
+	}
+	isPartitionCol := make([]bool, len(typs))
+	for _, ord := range spec.PartitionCols {
+		if int(ord) >= len(typs) {
+			return nil, errors.AssertionFailedf("invalid partition column ordinal %d", ord)
+		}
+		isPartitionCol[ord] = true
+	}
+	for i := range typs {
+		if isPartitionCol[i] {
+			continue
+		}
+		p.fileCols = append(p.fileCols, i)
+		p.fileTypes = append(p.fileTypes, typs[i])
+		if i < len(spec.ColNames) {
+			p.fileNames = append(p.fileNames, spec.ColNames[i])
+		}
+	}
+	p.row = make(rowenc.EncDatumRow, len(p.fileCols))
+	return p, nil
+}
+
+// newFileWriter returns a writer for the files of a partition.
+func (p *exportPartitioner) newFileWriter() (exportFileWriter, error) {
+	switch p.spec.Format {
+	// Nodes running an older version do not set the format, which was always
+	// CSV.
+	case roachpb.IOFileFormat_Unknown, roachpb.IOFileFormat_CSV:
+		return newCSVFileWriter(p.spec, p.fileTypes), nil
+	case roachpb.IOFileFormat_Parquet:
+		return newParquetFileWriter(p.spec, p.fileNames, p.fileTypes)
+	default:
+		return nil, errors.Errorf("unsupported export format: %s", p.spec.Format)
+	}
+}
+
+// partitionFor returns the partition of the given row, creating it or its
+// writer if needed.
+func (p *exportPartitioner) partitionFor(row rowenc.EncDatumRow) (*exportPartition, error) {
+	p.buf.Reset()
+	for i, ord := range p.spec.PartitionCols {
+		if i > 0 {
+			p.buf.WriteByte('/')
+		}
+		p.buf.WriteString(escapeHivePathName(p.colNames[ord]))
+		p.buf.WriteByte('=')
+		ed := row[ord]
+		if ed.IsNull() {
+			p.buf.WriteString(exportHiveDefaultPartition)
+			continue
+		}
+		if err := ed.EnsureDecoded(p.typs[ord], &p.alloc); err != nil {
+			return nil, err
+		}
+		ed.Datum.Format(p.f)
+		p.buf.WriteString(escapeHivePathName(p.f.String()))
+		p.f.Reset()
+	}
+
+	dir := p.buf.String()
+	part, ok := p.partitions[dir]
+	if !ok {
+		part = &exportPartition{dir: dir}
+		p.partitions[dir] = part
+	}
+	if part.writer == nil {
+		writer, err := p.newFileWriter()
+		if err != nil {
+			return nil, err
+		}
+		part.writer = writer
+	}
+	return part, nil
+}
+
+// fileRow returns the columns of the given row which are written to the
+// files. The returned row is only valid until the next call.
+func (p *exportPartitioner) fileRow(row rowenc.EncDatumRow) rowenc.EncDatumRow {
+	if len(p.spec.PartitionCols) == 0 {
+		return row
+	}
+	for i, ord := range p.fileCols {
+		p.row[i] = row[ord]
+	}
+	return p.row
+}
+
+// sorted returns all partitions, ordered by their directory.
+func (p *exportPartitioner) sorted() []*exportPartition {
+	res := make([]*exportPartition, 0, len(p.partitions))
+	for _, part := range p.partitions {
+		res = append(res, part)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].dir < res[j].dir })
+	return res
+}
+
+// escapeHivePathName escapes the characters which are not allowed in a
+// Hive-style partition directory name as %XX, as Hive does.
+func escapeHivePathName(s string) string {
+	if s == "" {
+		return exportHiveDefaultPartition
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7F || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,451 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverbase"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
+	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+)
+
+// By default, IMPORT INTO fails when an imported row has the same primary key
+// as an existing row, as the primary index KVs are ingested with AddSSTable
+// requests which disallow shadowing existing keys. The on_conflict option
+// instead resolves such conflicts before the KVs are ingested:
+//
+// - do_nothing drops the KVs of the imported row, keeping the existing row.
+// - do_update writes the KVs of the imported row in a transaction instead of
+//   ingesting them, along with deletions of the keys of the existing row which
+//   the imported row does not overwrite: its stale secondary index entries and
+//   column families. Since these are blind writes, do_update cannot be used
+//   with tables which have unique secondary indexes, whose violations would go
+//   undetected.
+//
+// Existing rows are read as of just before the import's write timestamp, so
+// rows ingested by the import itself, e.g. before it was resumed, are never
+// considered conflicting. Shadowing stays disallowed for the ingested KVs, so
+// an import with several rows with the same primary key fails, as it does
+// without on_conflict: the ingestion of a row collides with the KVs of an
+// earlier row with the same key, whether they were ingested or written by
+// do_update, and do_update fails when the existing row was already updated by
+// a different imported row.
+
+// importConflictResolver resolves the conflicts between the converted rows of
+// an IMPORT INTO and the existing rows of the table.
+type importConflictResolver struct {
+	db     *kv.DB
+	codec  keys.SQLCodec
+	table  *tabledesc.Immutable
+	mode   jobspb.ImportDetails_OnConflict
+	readTS hlc.Timestamp
+
+	primaryPrefix []byte
+	colIdxMap     catalog.TableColMap
+	fetcher       row.Fetcher
+	alloc         rowenc.DatumAlloc
+
+	// summary counts the KVs written by do_update, which are not counted by
+	// the bulk adders.
+	summary roachpb.BulkOpSummary
+}
+
+// existingRow is an existing row which conflicts with an imported row.
+type existingRow struct {
+	// keys are the keys of all of the primary and secondary index KVs of the
+	// row.
+	keys []roachpb.Key
+	// primary are the primary index KVs of the row, one per non-empty column
+	// family. There is at least one, as the first column family is always
+	// written.
+	primary []roachpb.KeyValue
+}
+
+// rowUpdate is an imported row which replaces an existing row.
+type rowUpdate struct {
+	// prefix is the row prefix shared by the primary index keys of the row.
+	prefix roachpb.Key
+	old    existingRow
+	kvs    []roachpb.KeyValue
+}
+
+// numPrimary returns the number of primary index KVs of the imported row,
+// which precede its secondary index KVs.
+func (u *rowUpdate) numPrimary() int {
+	n := 0
+	for n < len(u.kvs) && bytes.HasPrefix(u.kvs[n].Key, u.prefix) {
+		n++
+	}
+	return n
+}
+
+// sameKVs returns true if the two sets of KVs have the same keys and values,
+// ignoring the value checksums.
+func sameKVs(a, b []roachpb.KeyValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	values := make(map[string][]byte, len(b))
+	for i := range b {
+		values[string(b[i].Key)] = b[i].Value.TagAndDataBytes()
+	}
+	for i := range a {
+		v, ok := values[string(a[i].Key)]
+		if !ok || !bytes.Equal(a[i].Value.TagAndDataBytes(), v) {
+			return false
+		}
+	}
+	return true
+}
+
+// makeImportConflictResolver returns a resolver for the table being imported
+// into, or nil if the import does not resolve conflicts.
+func makeImportConflictResolver(
+	ctx context.Context, flowCtx *execinfra.FlowCtx, spec *execinfrapb.ReadImportDataSpec,
+) (*importConflictResolver, error) {
+	if len(spec.Tables) != 1 {
+		return nil, nil
+	}
+	var table *execinfrapb.ReadImportDataSpec_ImportTable
+	for _, t := range spec.Tables {
+		table = t
+	}
+	if table.OnConflict == jobspb.ImportDetails_ERROR {
+		return nil, nil
+	}
+	if flowCtx.Cfg.DB == nil {
+		return nil, errors.AssertionFailedf("resolving import conflicts requires a DB")
+	}
+
+	desc := tabledesc.NewImmutable(*table.Desc)
+	r := &importConflictResolver{
+		db:     flowCtx.Cfg.DB,
+		codec:  flowCtx.Codec(),
+		table:  desc,
+		mode:   table.OnConflict,
+		readTS: hlc.Timestamp{WallTime: spec.WalltimeNanos}.Prev(),
+	}
+	r.primaryPrefix = rowenc.MakeIndexKeyPrefix(r.codec, desc, desc.GetPrimaryIndexID())
+
+	cols := desc.DeletableColumns()
+	var valNeededForCol util.FastIntSet
+	for i := range cols {
+		r.colIdxMap.Set(cols[i].ID, i)
+		valNeededForCol.Add(i)
+	}
+	if err := r.fetcher.Init(
+		ctx,
+		r.codec,
+		false, /* reverse */
+		descpb.ScanLockingStrength_FOR_NONE,
+		descpb.ScanLockingWaitPolicy_BLOCK,
+		false, /* isCheck */
+		&r.alloc,
+		nil, /* memMonitor */
+		row.FetcherTableArgs{
+			Desc:            desc,
+			Index:           desc.GetPrimaryIndex(),
+			ColIdxMap:       r.colIdxMap,
+			Cols:            cols,
+			ValNeededForCol: valNeededForCol,
+		},
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// run resolves the conflicts of the batches received on in, and sends the
+// resolved batches to out.
+func (r *importConflictResolver) run(
+	ctx context.Context, in <-chan row.KVBatch, out chan<- row.KVBatch,
+) error {
+	for batch := range in {
+		resolved, err := r.resolve(ctx, batch)
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- resolved:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// importedRow is the range of the KVs of a batch which encode a single row.
+type importedRow struct {
+	prefix     roachpb.Key
+	start, end int
+}
+
+// splitRows splits the KVs of a batch into rows. The KVs of a row are emitted
+// by the row converter in a single batch, starting with its primary index KVs
+// (of which there is at least one, as the first column family is always
+// written) followed by its secondary index KVs, so a row starts at every
+// primary index KV which does not continue the previous row.
+func (r *importConflictResolver) splitRows(kvs []roachpb.KeyValue) ([]importedRow, error) {
+	var rows []importedRow
+	prevPrimary := false
+	for i := range kvs {
+		primary := bytes.HasPrefix(kvs[i].Key, r.primaryPrefix)
+		if primary {
+			prefix, err := keys.EnsureSafeSplitKey(kvs[i].Key)
+			if err != nil {
+				return nil, err
+			}
+			if !prevPrimary || !bytes.Equal(prefix, rows[len(rows)-1].prefix) {
+				if len(rows) > 0 {
+					rows[len(rows)-1].end = i
+				}
+				rows = append(rows, importedRow{prefix: prefix, start: i})
+			}
+		} else if len(rows) == 0 {
+			return nil, errors.AssertionFailedf("imported row without primary index KV: %s", kvs[i].Key)
+		}
+		prevPrimary = primary
+	}
+	if len(rows) > 0 {
+		rows[len(rows)-1].end = len(kvs)
+	}
+	return rows, nil
+}
+
+// resolve returns the batch with the conflicts with existing rows resolved.
+func (r *importConflictResolver) resolve(
+	ctx context.Context, batch row.KVBatch,
+) (row.KVBatch, error) {
+	rows, err := r.splitRows(batch.KVs)
+	if err != nil {
+		return row.KVBatch{}, err
+	}
+	if len(rows) == 0 {
+		return batch, nil
+	}
+	existing, err := r.fetchExisting(ctx, rows)
+	if err != nil {
+		return row.KVBatch{}, err
+	}
+	if len(existing) == 0 {
+		return batch, nil
+	}
+
+	kvs := make([]roachpb.KeyValue, 0, len(batch.KVs))
+	var updates []rowUpdate
+	for _, imported := range rows {
+		old, ok := existing[string(imported.prefix)]
+		if !ok {
+			kvs = append(kvs, batch.KVs[imported.start:imported.end]...)
+			continue
+		}
+		switch r.mode {
+		case jobspb.ImportDetails_DO_NOTHING:
+		case jobspb.ImportDetails_DO_UPDATE:
+			updates = append(updates, rowUpdate{
+				prefix: imported.prefix, old: old, kvs: batch.KVs[imported.start:imported.end],
+			})
+		default:
+			return row.KVBatch{}, errors.AssertionFailedf("unexpected on_conflict mode %s", r.mode)
+		}
+	}
+	if len(updates) > 0 {
+		if err := r.update(ctx, updates); err != nil {
+			return row.KVBatch{}, err
+		}
+	}
+	batch.KVs = kvs
+	return batch, nil
+}
+
+// update replaces existing rows with the imported rows in a transaction. The
+// keys of the existing rows which the imported rows do not overwrite are
+// deleted.
+//
+// An existing row is only replaced if all of its primary index KVs are
+// unchanged since the import's read timestamp. If they already hold all of the
+// primary index KVs of the imported row, in every column family, the row was
+// updated before the import was resumed and is skipped. Otherwise, it was
+// updated by a different imported row with the same primary key, which fails
+// the import.
+func (r *importConflictResolver) update(ctx context.Context, updates []rowUpdate) error {
+	var written storage.RowCounter
+	var dataSize int64
+	if err := r.db.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		written = storage.RowCounter{}
+		dataSize = 0
+
+		b := txn.NewBatch()
+		for i := range updates {
+			b.Scan(updates[i].prefix, updates[i].prefix.PrefixEnd())
+		}
+		if err := txn.Run(ctx, b); err != nil {
+			return err
+		}
+		current := b.Results
+
+		b = txn.NewBatch()
+		seen := make(map[string]struct{}, len(updates))
+		for i := range updates {
+			u := &updates[i]
+			if _, ok := seen[string(u.prefix)]; ok {
+				return duplicateImportedRowError(u.old.primary[0])
+			}
+			seen[string(u.prefix)] = struct{}{}
+
+			cur := make([]roachpb.KeyValue, len(current[i].Rows))
+			for j, res := range current[i].Rows {
+				cur[j] = roachpb.KeyValue{Key: res.Key, Value: *res.Value}
+			}
+			if sameKVs(cur, u.kvs[:u.numPrimary()]) {
+				continue
+			}
+			if !sameKVs(cur, u.old.primary) {
+				return duplicateImportedRowError(u.old.primary[0])
+			}
+
+			newKeys := make(map[string]struct{}, len(u.kvs))
+			for j := range u.kvs {
+				kv := &u.kvs[j]
+				// Consecutive imported rows with the same primary key and no
+				// secondary index KVs are split as a single row.
+				if _, ok := newKeys[string(kv.Key)]; ok {
+					return duplicateImportedRowError(*kv)
+				}
+				newKeys[string(kv.Key)] = struct{}{}
+				b.Put(kv.Key, &kv.Value)
+				if err := written.Count(kv.Key); err != nil {
+					return err
+				}
+				dataSize += int64(len(kv.Key) + len(kv.Value.RawBytes))
+			}
+			for _, key := range u.old.keys {
+				if _, ok := newKeys[string(key)]; !ok {
+					b.Del(key)
+				}
+			}
+		}
+		return txn.CommitInBatch(ctx, b)
+	}); err != nil {
+		return errors.Wrap(err, "updating existing rows")
+	}
+	r.summary.Add(written.BulkOpSummary)
+	r.summary.DataSize += dataSize
+	return nil
+}
+
+// duplicateImportedRowError returns the error for an imported row with the
+// same primary key as another imported row.
+func duplicateImportedRowError(kv roachpb.KeyValue) error {
+	return errors.Wrap(
+		&kvserverbase.DuplicateKeyError{Key: kv.Key, Value: kv.Value.RawBytes},
+		"duplicate key in primary index")
+}
+
+// fetchExisting reads the existing rows with the primary keys of the given
+// rows, keyed by their row prefix.
+func (r *importConflictResolver) fetchExisting(
+	ctx context.Context, rows []importedRow,
+) (map[string]existingRow, error) {
+	spans := make(roachpb.Spans, 0, len(rows))
+	for _, imported := range rows {
+		spans = append(spans, roachpb.Span{Key: imported.prefix, EndKey: imported.prefix.PrefixEnd()})
+	}
+	sort.Sort(spans)
+	// Rows with the same primary key may appear more than once.
+	deduped := spans[:1]
+	for _, span := range spans[1:] {
+		if !span.Key.Equal(deduped[len(deduped)-1].Key) {
+			deduped = append(deduped, span)
+		}
+	}
+
+	var existing map[string]existingRow
+	if err := r.db.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		existing = make(map[string]existingRow)
+		txn.SetFixedTimestamp(ctx, r.readTS)
+		if err := r.fetcher.StartScan(
+			ctx, txn, deduped, true /* limitBatches */, 0 /* limitHint */, false, /* traceKV */
+		); err != nil {
+			return err
+		}
+		for {
+			datums, _, _, err := r.fetcher.NextRowDecoded(ctx)
+			if err != nil {
+				return err
+			}
+			if datums == nil {
+				return nil
+			}
+			prefix, old, err := r.existingRow(datums)
+			if err != nil {
+				return err
+			}
+			existing[string(prefix)] = old
+		}
+	}); err != nil {
+		return nil, errors.Wrap(err, "reading existing rows")
+	}
+	return existing, nil
+}
+
+// existingRow returns the row prefix of an existing row along with its KVs.
+func (r *importConflictResolver) existingRow(
+	datums tree.Datums,
+) (roachpb.Key, existingRow, error) {
+	entries, err := rowenc.EncodePrimaryIndex(
+		r.codec, r.table, r.table.GetPrimaryIndex(), r.colIdxMap, datums, false, /* includeEmpty */
+	)
+	if err != nil {
+		return nil, existingRow{}, err
+	}
+	if len(entries) == 0 {
+		return nil, existingRow{}, errors.AssertionFailedf("no primary index entries for existing row")
+	}
+	prefix, err := keys.EnsureSafeSplitKey(entries[0].Key)
+	if err != nil {
+		return nil, existingRow{}, err
+	}
+	primary := make([]roachpb.KeyValue, len(entries))
+	for i := range entries {
+		primary[i] = roachpb.KeyValue{Key: entries[i].Key, Value: entries[i].Value}
+	}
+	indexes := r.table.DeletableIndexes()
+	for i := range indexes {
+		secondary, err := rowenc.EncodeSecondaryIndex(
+			r.codec, r.table, &indexes[i], r.colIdxMap, datums, false, /* includeEmpty */
+		)
+		if err != nil {
+			return nil, existingRow{}, err
+		}
+		entries = append(entries, secondary...)
+	}
+	oldKeys := make([]roachpb.Key, len(entries))
+	for i := range entries {
+		oldKeys[i] = entries[i].Key
+	}
+	return roachpb.Key(prefix), existingRow{keys: oldKeys, primary: primary}, nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+)
+
+// An online IMPORT INTO keeps the table it imports into online. Rather than
+// ingesting the KVs of the imported rows into the indexes of the table at a
+// historical timestamp, which requires the table to be offline, it ingests the
+// KVs of its primary index into a staging index: a span of the table keyed by
+// an index ID reserved from the table descriptor but not used by any index, and
+// thus invisible to SQL. Once all of the rows have been ingested, they are read
+// back from the staging index and inserted into the table, batch by batch, in
+// the transaction which publishes the tables and completes the import. The
+// secondary index entries of each row are written along with it, and the
+// transaction fails if the row collides with an existing one.
+//
+// The imported rows thus become visible all at once, when the import commits,
+// and no imported row is ever visible if the import fails. The price is a
+// single transaction which holds intents on all of the imported rows until it
+// commits: concurrent reads of the table do not see the intents, but writes to
+// the keys of imported rows wait for the import to finish.
+
+// onlineMergeBatchRows is the number of rows of a staging index read and merged
+// by each batch of the merge transaction.
+const onlineMergeBatchRows = 1000
+
+// reserveStagingIndexes reserves a staging index ID for the primary index of
+// the table. The secondary index entries of the imported rows are written when
+// the rows are merged, and thus need no staging indexes.
+func reserveStagingIndexes(desc *tabledesc.Mutable) []jobspb.ImportDetails_StagingIndex {
+	staging := []jobspb.ImportDetails_StagingIndex{{
+		IndexID:        desc.PrimaryIndex.ID,
+		StagingIndexID: desc.NextIndexID,
+	}}
+	desc.NextIndexID++
+	return staging
+}
+
+// stagingIndexIDs returns the staging index ID of each of the indexes of the
+// table which have one, or nil if it is not imported online.
+func stagingIndexIDs(table jobspb.ImportDetails_Table) map[descpb.IndexID]descpb.IndexID {
+	if !table.Online {
+		return nil
+	}
+	ids := make(map[descpb.IndexID]descpb.IndexID, len(table.StagingIndexes))
+	for _, idx := range table.StagingIndexes {
+		ids[idx.IndexID] = idx.StagingIndexID
+	}
+	return ids
+}
+
+// rewriteIndexID rewrites the key of kv from the index from to the index to of
+// the same table, and recomputes the checksum of its value for the new key.
+func rewriteIndexID(
+	codec keys.SQLCodec, kv *roachpb.KeyValue, tableID descpb.ID, from, to descpb.IndexID,
+) error {
+	oldPrefix := codec.IndexPrefix(uint32(tableID), uint32(from))
+	if !bytes.HasPrefix(kv.Key, oldPrefix) {
+		return errors.AssertionFailedf("key %s is not in index %d of table %d", kv.Key, from, tableID)
+	}
+	newPrefix := codec.IndexPrefix(uint32(tableID), uint32(to))
+	newKey := make(roachpb.Key, 0, len(newPrefix)+len(kv.Key)-len(oldPrefix))
+	newKey = append(newKey, newPrefix...)
+	newKey = append(newKey, kv.Key[len(oldPrefix):]...)
+	kv.Key = newKey
+	if len(kv.Value.RawBytes) > 0 {
+		kv.Value.ClearChecksum()
+		kv.Value.InitChecksum(newKey)
+	}
+	return nil
+}
+
+// mergeStagingIndexes inserts the rows of the staging indexes of a table
+// imported online into the table, batch by batch, in the given transaction.
+func (r *importResumer) mergeStagingIndexes(
+	ctx context.Context, txn *kv.Txn, codec keys.SQLCodec, table *jobspb.ImportDetails_Table,
+) error {
+	// Reading the descriptor in the transaction ensures that the schema of the
+	// table cannot change before it commits.
+	current, err := checkOnlineTableUnchanged(ctx, txn, codec, table.Desc)
+	if err != nil {
+		return err
+	}
+	prepared := tabledesc.NewImmutable(*table.Desc)
+	for _, staging := range table.StagingIndexes {
+		log.Infof(ctx, "merging staging index %d into table %d", staging.StagingIndexID, table.Desc.ID)
+		span := prepared.IndexSpan(codec, staging.StagingIndexID)
+		for span.Key != nil {
+			var alloc rowenc.DatumAlloc
+			ri, err := row.MakeInserter(ctx, txn, codec, current, current.Columns, &alloc)
+			if err != nil {
+				return err
+			}
+			b := txn.NewBatch()
+			span.Key, err = scanStagingRows(ctx, txn, codec, prepared, staging.StagingIndexID, span,
+				func(datums tree.Datums) error {
+					// Imports do not evaluate the predicates of partial indexes.
+					var pm row.PartialIndexUpdateHelper
+					return ri.InsertRow(ctx, b, datums, pm, false /* overwrite */, false /* traceKV */)
+				})
+			if err != nil {
+				return err
+			}
+			if err := txn.Run(ctx, b); err != nil {
+				return row.ConvertBatchError(ctx, current, b)
+			}
+			if r.testingKnobs.afterOnlineMergeBatch != nil {
+				if err := r.testingKnobs.afterOnlineMergeBatch(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scanStagingRows decodes up to onlineMergeBatchRows rows of the given span of
+// a staging index, and returns the key from which to resume, or nil if there
+// are no rows left in the span.
+func scanStagingRows(
+	ctx context.Context,
+	txn *kv.Txn,
+	codec keys.SQLCodec,
+	desc *tabledesc.Immutable,
+	stagingIndexID descpb.IndexID,
+	span roachpb.Span,
+	fn func(tree.Datums) error,
+) (roachpb.Key, error) {
+	// The staging index is encoded like the primary index.
+	stagingIndex := desc.PrimaryIndex
+	stagingIndex.ID = stagingIndexID
+	stagingIndex.EncodingType = descpb.PrimaryIndexEncoding
+
+	var rf row.Fetcher
+	if err := initFetcher(ctx, &rf, codec, desc, &stagingIndex, desc.Columns); err != nil {
+		return nil, err
+	}
+	if err := rf.StartScan(
+		ctx, txn, roachpb.Spans{span}, true /* limitBatches */, onlineMergeBatchRows, false, /* traceKV */
+	); err != nil {
+		return nil, err
+	}
+	for i := 0; i < onlineMergeBatchRows; i++ {
+		datums, _, _, err := rf.NextRowDecoded(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if datums == nil {
+			return nil, nil
+		}
+		if err := fn(datums); err != nil {
+			return nil, err
+		}
+	}
+	return rf.Key(), nil
+}
+
+// initFetcher initializes a row.Fetcher which decodes all of the given columns
+// of the rows of a primary index.
+func initFetcher(
+	ctx context.Context,
+	rf *row.Fetcher,
+	codec keys.SQLCodec,
+	desc *tabledesc.Immutable,
+	index *descpb.IndexDescriptor,
+	cols []descpb.ColumnDescriptor,
+) error {
+	var colIdxMap catalog.TableColMap
+	var valNeededForCol util.FastIntSet
+	for i := range cols {
+		colIdxMap.Set(cols[i].ID, i)
+		valNeededForCol.Add(i)
+	}
+	return rf.Init(
+		ctx,
+		codec,
+		false, /* reverse */
+		descpb.ScanLockingStrength_FOR_NONE,
+		descpb.ScanLockingWaitPolicy_BLOCK,
+		false, /* isCheck */
+		&rowenc.DatumAlloc{},
+		nil, /* memMonitor */
+		row.FetcherTableArgs{
+			Spans:            roachpb.Spans{desc.IndexSpan(codec, index.ID)},
+			Desc:             desc,
+			Index:            index,
+			ColIdxMap:        colIdxMap,
+			IsSecondaryIndex: false,
+			Cols:             cols,
+			ValNeededForCol:  valNeededForCol,
+		},
+	)
+}
+
+// checkOnlineTableUnchanged returns the current descriptor of the table, or an
+// error if its indexes or columns were changed since it was prepared for the
+// import, as its staging index is then no longer encoded like its primary
+// index.
+func checkOnlineTableUnchanged(
+	ctx context.Context, txn *kv.Txn, codec keys.SQLCodec, prepared *descpb.TableDescriptor,
+) (*tabledesc.Immutable, error) {
+	current, err := catalogkv.MustGetTableDescByID(ctx, txn, codec, prepared.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Dropped() {
+		return nil, errors.Errorf("table %q was dropped during online IMPORT", prepared.Name)
+	}
+	changed := len(current.Mutations) > 0 ||
+		current.GetPrimaryIndexID() != prepared.PrimaryIndex.ID ||
+		len(current.GetPublicNonPrimaryIndexes()) != len(prepared.Indexes) ||
+		len(current.GetPublicColumns()) != len(prepared.Columns)
+	if !changed {
+		for i, idx := range current.GetPublicNonPrimaryIndexes() {
+			if idx.ID != prepared.Indexes[i].ID {
+				changed = true
+			}
+		}
+		for i, col := range current.GetPublicColumns() {
+			if col.ID != prepared.Columns[i].ID {
+				changed = true
+			}
+		}
+	}
+	if changed {
+		return nil, errors.Errorf("the schema of table %q changed during online IMPORT", prepared.Name)
+	}
+	return current, nil
+}
+
+// clearStagingIndexes clears the KVs of the staging indexes of a table imported
+// online.
+func clearStagingIndexes(
+	ctx context.Context, execCfg *sql.ExecutorConfig, table *jobspb.ImportDetails_Table,
+) error {
+	desc := tabledesc.NewImmutable(*table.Desc)
+	// ClearRange cannot be run in a transaction, so create a
+	// non-transactional batch to send the request.
+	b := &kv.Batch{}
+	for _, staging := range table.StagingIndexes {
+		sp := desc.IndexSpan(execCfg.Codec, staging.StagingIndexID)
+		b.AddRawRequest(&roachpb.ClearRangeRequest{
+			RequestHeader: roachpb.RequestHeader{
+				Key:    sp.Key,
+				EndKey: sp.EndKey,
+			},
+		})
+	}
+	return errors.Wrapf(execCfg.DB.Run(ctx, b), "clearing staging indexes of table %q", desc.Name)
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/kvserverbase"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfrapb"
@@ -192,6 +193,14 @@ func makeInputConverter(
 		return newAvroInputReader(
 			kvCh, singleTable, spec.Format.Avro, spec.WalltimeNanos,
 			int(spec.ReaderParallelism), evalCtx)
+	case roachpb.IOFileFormat_Parquet:
+		return newParquetInputReader(
+			kvCh, singleTable, singleTableTargetCols, spec.Format.Parquet, spec.WalltimeNanos,
+			int(spec.ReaderParallelism), evalCtx)
+	case roachpb.IOFileFormat_NDJSON:
+		return newNDJSONInputReader(
+			kvCh, singleTable, singleTableTargetCols, spec.Format.Ndjson, spec.WalltimeNanos,
+			int(spec.ReaderParallelism), evalCtx)
 	default:
 		return nil, errors.Errorf(
 			"Requested IMPORT format (%d) not supported by this node", spec.Format.Format)
@@ -214,6 +223,13 @@ func ingestKvs(
 
 	flushSize := func() int64 { return storageccl.MaxImportBatchSize(flowCtx.Cfg.Settings) }
 
+	stagingIndexes := make(map[descpb.ID]map[descpb.IndexID]descpb.IndexID)
+	for _, table := range spec.Tables {
+		if len(table.StagingIndexIDs) > 0 {
+			stagingIndexes[table.Desc.ID] = table.StagingIndexIDs
+		}
+	}
+
 	// We create two bulk adders so as to combat the excessive flushing of small
 	// SSTs which was observed when using a single adder for both primary and
 	// secondary index kvs. The number of secondary index kvs are small, and so we
@@ -357,11 +373,26 @@ func ingestKvs(
 		// number of L0 (and total) files, but with a lower memory usage.
 		for kvBatch := range kvCh {
 			for _, kv := range kvBatch.KVs {
-				_, _, indexID, indexErr := flowCtx.Codec().DecodeIndexPrefix(kv.Key)
+				_, tableID, indexID, indexErr := flowCtx.Codec().DecodeIndexPrefix(kv.Key)
 				if indexErr != nil {
 					return indexErr
 				}
 
+				// Tables imported online ingest the KVs of their primary index into its
+				// staging index. Their secondary index entries are written when the
+				// rows are merged into the table.
+				if staging, ok := stagingIndexes[descpb.ID(tableID)]; ok {
+					stagingID, ok := staging[descpb.IndexID(indexID)]
+					if !ok {
+						continue
+					}
+					if err := rewriteIndexID(
+						flowCtx.Codec(), &kv, descpb.ID(tableID), descpb.IndexID(indexID), stagingID,
+					); err != nil {
+						return err
+					}
+				}
+
 				// Decide which adder to send the KV to by extracting its index id.
 				//
 				// TODO(adityamaru): There is a potential optimization of plumbing the
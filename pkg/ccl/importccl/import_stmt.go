@@ -80,13 +80,16 @@ const (
 	importOptionSkipFKs          = "skip_foreign_keys"
 	importOptionDisableGlobMatch = "disable_glob_matching"
 	importOptionSaveRejected     = "experimental_save_rejected"
+	importOptionOnConflict       = "on_conflict"
+	importOptionOnline           = "online"
 
 	pgCopyDelimiter = "delimiter"
 	pgCopyNull      = "nullif"
 
 	optMaxRowSize = "max_row_size"
 
-	// Turn on strict validation when importing avro records.
+	// Turn on strict validation when importing avro records, parquet or ndjson
+	// files.
 	avroStrict = "strict_validation"
 	// Default input format is assumed to be OCF (object container file).
 	// This default can be changed by specified either of these options.
@@ -99,6 +102,9 @@ const (
 	avroSchema    = "schema"
 	avroSchemaURI = "schema_uri"
 
+	// Import each NDJSON document as a whole into the named JSONB column.
+	ndjsonDocumentColumn = "document_column"
+
 	// RunningStatusImportBundleParseSchema indicates to the user that a bundle format
 	// schema is being parsed
 	runningStatusImportBundleParseSchema jobs.RunningStatus = "parsing schema on Import Bundle"
@@ -121,6 +127,8 @@ var importOptionExpectValues = map[string]sql.KVStringOptValidate{
 	importOptionDecompress:   sql.KVStringOptRequireValue,
 	importOptionOversample:   sql.KVStringOptRequireValue,
 	importOptionSaveRejected: sql.KVStringOptRequireNoValue,
+	importOptionOnConflict:   sql.KVStringOptRequireValue,
+	importOptionOnline:       sql.KVStringOptRequireNoValue,
 
 	importOptionSkipFKs:          sql.KVStringOptRequireNoValue,
 	importOptionDisableGlobMatch: sql.KVStringOptRequireNoValue,
@@ -133,6 +141,8 @@ var importOptionExpectValues = map[string]sql.KVStringOptValidate{
 	avroRecordsSeparatedBy: sql.KVStringOptRequireValue,
 	avroBinRecords:         sql.KVStringOptRequireNoValue,
 	avroJSONRecords:        sql.KVStringOptRequireNoValue,
+
+	ndjsonDocumentColumn: sql.KVStringOptRequireValue,
 }
 
 func makeStringSet(opts ...string) map[string]struct{} {
@@ -146,13 +156,18 @@ func makeStringSet(opts ...string) map[string]struct{} {
 // Options common to all formats.
 var allowedCommonOptions = makeStringSet(
 	importOptionSSTSize, importOptionDecompress, importOptionOversample,
-	importOptionSaveRejected, importOptionDisableGlobMatch)
+	importOptionSaveRejected, importOptionDisableGlobMatch, importOptionOnConflict,
+	importOptionOnline)
 
 // Format specific allowed options.
 var avroAllowedOptions = makeStringSet(
 	avroStrict, avroBinRecords, avroJSONRecords,
 	avroRecordsSeparatedBy, avroSchema, avroSchemaURI, optMaxRowSize, csvRowLimit,
 )
+var parquetAllowedOptions = makeStringSet(avroStrict, csvRowLimit)
+var ndjsonAllowedOptions = makeStringSet(
+	avroStrict, ndjsonDocumentColumn, optMaxRowSize, csvRowLimit,
+)
 var csvAllowedOptions = makeStringSet(
 	csvDelimiter, csvComment, csvNullIf, csvSkip, csvStrictQuotes, csvRowLimit,
 )
@@ -172,6 +187,9 @@ var importIntoRequiredPrivileges = []privilege.Kind{privilege.INSERT, privilege.
 var allowedIntoFormats = map[string]struct{}{
 	"CSV":       {},
 	"AVRO":      {},
+	"PARQUET":   {},
+	"NDJSON":    {},
+	"JSONL":     {},
 	"DELIMITED": {},
 	"PGCOPY":    {},
 }
@@ -625,6 +643,57 @@ func importPlanHook(
 			if err != nil {
 				return err
 			}
+		case "PARQUET":
+			if err = validateFormatOptions(importStmt.FileFormat, opts, parquetAllowedOptions); err != nil {
+				return err
+			}
+			format.Format = roachpb.IOFileFormat_Parquet
+			_, format.Parquet.StrictMode = opts[avroStrict]
+			if _, ok := opts[importOptionSaveRejected]; ok {
+				format.SaveRejected = true
+			}
+			if override, ok := opts[csvRowLimit]; ok {
+				rowLimit, err := strconv.Atoi(override)
+				if err != nil {
+					return pgerror.Wrapf(err, pgcode.Syntax, "invalid numeric %s value", csvRowLimit)
+				}
+				if rowLimit <= 0 {
+					return pgerror.Newf(pgcode.Syntax, "%s must be > 0", csvRowLimit)
+				}
+				format.Parquet.RowLimit = int64(rowLimit)
+			}
+		case "NDJSON", "JSONL":
+			if err = validateFormatOptions(importStmt.FileFormat, opts, ndjsonAllowedOptions); err != nil {
+				return err
+			}
+			format.Format = roachpb.IOFileFormat_NDJSON
+			_, format.Ndjson.StrictMode = opts[avroStrict]
+			format.Ndjson.DocumentColumn = opts[ndjsonDocumentColumn]
+			if _, ok := opts[importOptionSaveRejected]; ok {
+				format.SaveRejected = true
+			}
+			maxRowSize := int32(defaultScanBuffer)
+			if override, ok := opts[optMaxRowSize]; ok {
+				sz, err := humanizeutil.ParseBytes(override)
+				if err != nil {
+					return err
+				}
+				if sz < 1 || sz > math.MaxInt32 {
+					return errors.Errorf("%d out of range: %d", maxRowSize, sz)
+				}
+				maxRowSize = int32(sz)
+			}
+			format.Ndjson.MaxRowSize = maxRowSize
+			if override, ok := opts[csvRowLimit]; ok {
+				rowLimit, err := strconv.Atoi(override)
+				if err != nil {
+					return pgerror.Wrapf(err, pgcode.Syntax, "invalid numeric %s value", csvRowLimit)
+				}
+				if rowLimit <= 0 {
+					return pgerror.Newf(pgcode.Syntax, "%s must be > 0", csvRowLimit)
+				}
+				format.Ndjson.RowLimit = int64(rowLimit)
+			}
 		default:
 			return unimplemented.Newf("import.format", "unsupported import format: %q", importStmt.FileFormat)
 		}
@@ -668,6 +737,34 @@ func importPlanHook(
 			}
 		}
 
+		onConflict := jobspb.ImportDetails_ERROR
+		if override, ok := opts[importOptionOnConflict]; ok {
+			if !importStmt.Into {
+				return errors.Errorf("%s option is only supported by IMPORT INTO", importOptionOnConflict)
+			}
+			switch strings.ToLower(override) {
+			case "do_nothing":
+				onConflict = jobspb.ImportDetails_DO_NOTHING
+			case "do_update":
+				onConflict = jobspb.ImportDetails_DO_UPDATE
+			default:
+				return errors.Errorf(
+					"invalid value %q for %s option, expected do_nothing or do_update",
+					override, importOptionOnConflict)
+			}
+		}
+
+		_, online := opts[importOptionOnline]
+		if online {
+			if !importStmt.Into {
+				return errors.Errorf("%s option is only supported by IMPORT INTO", importOptionOnline)
+			}
+			if onConflict != jobspb.ImportDetails_ERROR {
+				return errors.Errorf("%s option cannot be used with the %s option",
+					importOptionOnline, importOptionOnConflict)
+			}
+		}
+
 		var tableDetails []jobspb.ImportDetails_Table
 		var tableDescs []*tabledesc.Mutable // parallel with tableDetails
 		jobDesc, err := importJobDescription(p, importStmt, nil, filenamePatterns, opts)
@@ -713,6 +810,19 @@ func importPlanHook(
 				return pgerror.New(pgcode.FeatureNotSupported, "Cannot use IMPORT INTO with interleaved tables")
 			}
 
+			// Conflicting rows are updated with blind writes, which would let
+			// violations of unique secondary indexes go undetected.
+			if onConflict == jobspb.ImportDetails_DO_UPDATE {
+				for i := range found.Indexes {
+					if found.Indexes[i].Unique {
+						return pgerror.Newf(pgcode.FeatureNotSupported,
+							"%s = 'do_update' is not supported for tables with unique secondary indexes, "+
+								"but table %q has unique index %q",
+							importOptionOnConflict, found.Name, found.Indexes[i].Name)
+					}
+				}
+			}
+
 			// Validate target columns.
 			var intoCols []string
 			var isTargetCol = make(map[string]bool)
@@ -744,7 +854,10 @@ func importPlanHook(
 				}
 			}
 			tableDescs = []*tabledesc.Mutable{found}
-			tableDetails = []jobspb.ImportDetails_Table{{Desc: &found.TableDescriptor, IsNew: false, TargetCols: intoCols}}
+			tableDetails = []jobspb.ImportDetails_Table{{
+				Desc: &found.TableDescriptor, IsNew: false, TargetCols: intoCols, OnConflict: onConflict,
+				Online: online,
+			}}
 		} else {
 			seqVals := make(map[descpb.ID]int64)
 
@@ -826,6 +939,15 @@ func importPlanHook(
 			}
 		}
 
+		// Directory-format pg_dump archives are imported from their TOC and the
+		// data files it lists, which are loaded in parallel.
+		if format.Format == roachpb.IOFileFormat_PgDump {
+			files, err = expandPgDumpDirectories(ctx, p.ExecCfg(), p.User(), files, &format.PgDump)
+			if err != nil {
+				return err
+			}
+		}
+
 		telemetry.CountBucketed("import.files", int64(len(files)))
 
 		// Record telemetry for userfile being used as the import target.
@@ -1012,6 +1134,7 @@ type importResumer struct {
 
 	testingKnobs struct {
 		afterImport               func(summary backupccl.RowCount) error
+		afterOnlineMergeBatch     func() error
 		alwaysFlushJobProgress    bool
 		ignoreProtectedTimestamps bool
 	}
@@ -1112,37 +1235,48 @@ func prepareNewTableDescsForIngestion(
 
 // Prepares descriptors for existing tables being imported into.
 func prepareExistingTableDescForIngestion(
-	ctx context.Context, txn *kv.Txn, descsCol *descs.Collection, desc *descpb.TableDescriptor,
-) (*descpb.TableDescriptor, error) {
+	ctx context.Context,
+	txn *kv.Txn,
+	descsCol *descs.Collection,
+	desc *descpb.TableDescriptor,
+	online bool,
+) (*descpb.TableDescriptor, []jobspb.ImportDetails_StagingIndex, error) {
 	if len(desc.Mutations) > 0 {
-		return nil, errors.Errorf("cannot IMPORT INTO a table with schema changes in progress -- try again later (pending mutation %s)", desc.Mutations[0].String())
+		return nil, nil, errors.Errorf("cannot IMPORT INTO a table with schema changes in progress -- try again later (pending mutation %s)", desc.Mutations[0].String())
 	}
 
 	// Note that desc is just used to verify that the version matches.
 	importing, err := descsCol.GetMutableTableVersionByID(ctx, desc.ID, txn)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// Ensure that the version of the table has not been modified since this
 	// job was created.
 	if got, exp := importing.Version, desc.Version; got != exp {
-		return nil, errors.Errorf("another operation is currently operating on the table")
+		return nil, nil, errors.Errorf("another operation is currently operating on the table")
 	}
 
-	// Take the table offline for import.
-	// TODO(dt): audit everywhere we get table descs (leases or otherwise) to
-	// ensure that filtering by state handles IMPORTING correctly.
-	importing.State = descpb.DescriptorState_OFFLINE
-	importing.OfflineReason = "importing"
+	var staging []jobspb.ImportDetails_StagingIndex
+	if online {
+		// Keep the table online, and reserve the IDs of the staging indexes into
+		// which the imported rows are ingested.
+		staging = reserveStagingIndexes(importing)
+	} else {
+		// Take the table offline for import.
+		// TODO(dt): audit everywhere we get table descs (leases or otherwise) to
+		// ensure that filtering by state handles IMPORTING correctly.
+		importing.State = descpb.DescriptorState_OFFLINE
+		importing.OfflineReason = "importing"
+	}
 
 	// TODO(dt): de-validate all the FKs.
 	if err := descsCol.WriteDesc(
 		ctx, false /* kvTrace */, importing, txn,
 	); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return importing.TableDesc(), nil
+	return importing.TableDesc(), staging, nil
 }
 
 // prepareTableDescsForIngestion prepares table descriptors for the ingestion
@@ -1166,14 +1300,18 @@ func (r *importResumer) prepareTableDescsForIngestion(
 			var desc *descpb.TableDescriptor
 			for i, table := range details.Tables {
 				if !table.IsNew {
-					desc, err = prepareExistingTableDescForIngestion(ctx, txn, descsCol, table.Desc)
+					var staging []jobspb.ImportDetails_StagingIndex
+					desc, staging, err = prepareExistingTableDescForIngestion(ctx, txn, descsCol, table.Desc, table.Online)
 					if err != nil {
 						return err
 					}
 					importDetails.Tables[i] = jobspb.ImportDetails_Table{Desc: desc, Name: table.Name,
-						SeqVal:     table.SeqVal,
-						IsNew:      table.IsNew,
-						TargetCols: table.TargetCols}
+						SeqVal:         table.SeqVal,
+						IsNew:          table.IsNew,
+						TargetCols:     table.TargetCols,
+						OnConflict:     table.OnConflict,
+						Online:         table.Online,
+						StagingIndexes: staging}
 
 					hasExistingTables = true
 				} else {
@@ -1390,9 +1528,15 @@ func (r *importResumer) Resume(
 
 		for _, i := range details.Tables {
 			if i.Name != "" {
-				tables[i.Name] = &execinfrapb.ReadImportDataSpec_ImportTable{Desc: i.Desc, TargetCols: i.TargetCols}
+				tables[i.Name] = &execinfrapb.ReadImportDataSpec_ImportTable{
+					Desc: i.Desc, TargetCols: i.TargetCols, OnConflict: i.OnConflict,
+					StagingIndexIDs: stagingIndexIDs(i),
+				}
 			} else if i.Desc != nil {
-				tables[i.Desc.Name] = &execinfrapb.ReadImportDataSpec_ImportTable{Desc: i.Desc, TargetCols: i.TargetCols}
+				tables[i.Desc.Name] = &execinfrapb.ReadImportDataSpec_ImportTable{
+					Desc: i.Desc, TargetCols: i.TargetCols, OnConflict: i.OnConflict,
+					StagingIndexIDs: stagingIndexIDs(i),
+				}
 			} else {
 				return errors.Errorf("invalid table specification")
 			}
@@ -1421,8 +1565,9 @@ func (r *importResumer) Resume(
 
 		// Check if the tables being imported into are starting empty, in which
 		// case we can cheaply clear-range instead of revert-range to cleanup.
+		// Tables imported online are never reverted.
 		for i := range details.Tables {
-			if !details.Tables[i].IsNew {
+			if !details.Tables[i].IsNew && !details.Tables[i].Online {
 				tblSpan := tabledesc.NewImmutable(*details.Tables[i].Desc).TableSpan(keys.TODOSQLCodec)
 				res, err := p.ExecCfg().DB.Scan(ctx, tblSpan.Key, tblSpan.EndKey, 1 /* maxRows */)
 				if err != nil {
@@ -1444,6 +1589,9 @@ func (r *importResumer) Resume(
 	pkIDs := make(map[uint64]struct{}, len(details.Tables))
 	for _, t := range details.Tables {
 		pkIDs[roachpb.BulkOpSummaryID(uint64(t.Desc.ID), uint64(t.Desc.PrimaryIndex.ID))] = struct{}{}
+		if staging, ok := stagingIndexIDs(t)[t.Desc.PrimaryIndex.ID]; ok {
+			pkIDs[roachpb.BulkOpSummaryID(uint64(t.Desc.ID), uint64(staging))] = struct{}{}
+		}
 	}
 	r.res.DataSize = res.DataSize
 	for id, count := range res.EntryCounts {
@@ -1462,6 +1610,16 @@ func (r *importResumer) Resume(
 	if err := r.publishTables(ctx, p.ExecCfg()); err != nil {
 		return err
 	}
+
+	// The staging indexes of tables imported online are only cleared once their
+	// rows were merged into the tables when they were published.
+	for i := range details.Tables {
+		if details.Tables[i].Online {
+			if err := clearStagingIndexes(ctx, p.ExecCfg(), &details.Tables[i]); err != nil {
+				return err
+			}
+		}
+	}
 	// TODO(ajwerner): Should this actually return the error? At this point we've
 	// successfully finished the import but failed to drop the protected
 	// timestamp. The reconciliation loop ought to pick it up.
@@ -1516,6 +1674,16 @@ func (r *importResumer) publishTables(ctx context.Context, execCfg *sql.Executor
 	err := descs.Txn(ctx, execCfg.Settings, lm, ie, db, func(
 		ctx context.Context, txn *kv.Txn, descsCol *descs.Collection,
 	) error {
+		// The rows of the tables imported online are merged in the transaction
+		// which publishes the tables, so that they become visible all at once.
+		for i := range details.Tables {
+			if tbl := &details.Tables[i]; tbl.Online {
+				if err := r.mergeStagingIndexes(ctx, txn, execCfg.Codec, tbl); err != nil {
+					return errors.Wrapf(err, "merging imported rows into table %q", tbl.Desc.Name)
+				}
+			}
+		}
+
 		b := txn.NewBatch()
 		for _, tbl := range details.Tables {
 			newTableDesc, err := descsCol.GetMutableTableVersionByID(ctx, tbl.Desc.ID, txn)
@@ -1641,7 +1809,14 @@ func (r *importResumer) dropTables(
 				return err
 			}
 			imm := desc.ImmutableCopy().(*tabledesc.Immutable)
-			if tbl.WasEmpty {
+			if tbl.Online {
+				// The imported rows of a table imported online are only merged from
+				// its staging index when the tables are published, which completes
+				// the import, so there is nothing to revert.
+				if err := clearStagingIndexes(ctx, execCfg, &tbl); err != nil {
+					return err
+				}
+			} else if tbl.WasEmpty {
 				empty = append(empty, imm)
 			} else {
 				revert = append(revert, imm)
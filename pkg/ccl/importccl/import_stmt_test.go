@@ -10,6 +10,7 @@ package importccl
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	gosql "database/sql"
 	"encoding/json"
@@ -20,6 +21,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -5417,6 +5419,116 @@ func TestImportPgDump(t *testing.T) {
 	})
 }
 
+func TestImportPgDumpArchive(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	baseDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	tc := testcluster.StartTestCluster(
+		t, 3, base.TestClusterArgs{ServerArgs: base.TestServerArgs{ExternalIODir: baseDir}})
+	defer tc.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(tc.Conns[0])
+
+	writeFile := func(t *testing.T, name string, data []byte) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(baseDir, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(baseDir, name), data, 0644))
+	}
+	check := func(t *testing.T, db string) {
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(`SELECT * FROM %s.t ORDER BY i`, db),
+			[][]string{{"1", "a"}, {"2", "NULL"}, {"3", "c"}})
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(`SELECT * FROM %s.u ORDER BY v`, db),
+			[][]string{{"x"}, {"y"}})
+		sqlDB.CheckQueryResults(t, fmt.Sprintf(`SELECT nextval('%s.seq')`, db), [][]string{{"4"}})
+		sqlDB.CheckQueryResults(t,
+			fmt.Sprintf(`SELECT index_name FROM [SHOW INDEXES FROM %s.t] WHERE seq_in_index = 1`, db),
+			[][]string{{"t_pkey"}})
+	}
+
+	// Privileges are not supported by PGDUMP imports.
+	var entries []testPgDumpArchiveEntry
+	for _, e := range testPgDumpArchiveEntries {
+		if e.desc != "ACL" {
+			entries = append(entries, e)
+		}
+	}
+
+	for _, compression := range []pgDumpArchiveCompression{
+		pgDumpArchiveCompressionNone, pgDumpArchiveCompressionGzip,
+	} {
+		t.Run(fmt.Sprintf("custom/compression=%d", compression), func(t *testing.T) {
+			archive, _ := testPgDumpArchive{
+				version: [3]byte{1, 14, 0}, format: pgDumpArchiveCustom, compression: compression,
+				entries: entries, blobs: true,
+			}.writeArchive(t)
+			name := fmt.Sprintf("custom%d.dump", compression)
+			writeFile(t, name, archive)
+			db := fmt.Sprintf("custom%d", compression)
+			sqlDB.Exec(t, fmt.Sprintf(`CREATE DATABASE %s; USE %s`, db, db))
+			sqlDB.Exec(t, `IMPORT PGDUMP ($1)`, "nodelocal://0/"+name)
+			check(t, db)
+		})
+
+		t.Run(fmt.Sprintf("directory/compression=%d", compression), func(t *testing.T) {
+			toc, dataFiles := testPgDumpArchive{
+				version: [3]byte{1, 15, 0}, format: pgDumpArchiveDirectory, compression: compression,
+				entries: entries,
+			}.writeArchive(t)
+			dir := fmt.Sprintf("directory%d", compression)
+			writeFile(t, dir+"/toc.dat", toc)
+			for file, data := range dataFiles {
+				writeFile(t, dir+"/"+file, data)
+			}
+			// The archive is imported from either its directory or its TOC.
+			for _, uri := range []string{"nodelocal://0/" + dir + "/", "nodelocal://0/" + dir + "/toc.dat"} {
+				db := fmt.Sprintf("%s_%d", dir, len(uri))
+				sqlDB.Exec(t, fmt.Sprintf(`CREATE DATABASE %s; USE %s`, db, db))
+				sqlDB.Exec(t, `IMPORT PGDUMP ($1)`, uri)
+				check(t, db)
+			}
+			// The data files of the tables are loaded as inputs of their own.
+			var payloadBytes []byte
+			sqlDB.QueryRow(t, `SELECT payload FROM system.jobs WHERE id IN (
+				SELECT job_id FROM [SHOW JOBS] WHERE job_type = 'IMPORT'
+			) ORDER BY created DESC LIMIT 1`).Scan(&payloadBytes)
+			var payload jobspb.Payload
+			require.NoError(t, protoutil.Unmarshal(payloadBytes, &payload))
+			require.Len(t, payload.GetImport().URIs, 3)
+		})
+	}
+
+	// The fixtures are archives in the formats written by pg_dump 13 of a
+	// database holding the same objects.
+	t.Run("fixture", func(t *testing.T) {
+		files := []string{"archive.dump"}
+		dirFiles, err := ioutil.ReadDir(testutils.TestDataPath("testdata", "pgdump", "archive_dir"))
+		require.NoError(t, err)
+		for _, f := range dirFiles {
+			files = append(files, "archive_dir/"+f.Name())
+		}
+		for _, file := range files {
+			data, err := ioutil.ReadFile(testutils.TestDataPath("testdata", "pgdump", file))
+			require.NoError(t, err)
+			writeFile(t, "fixture/"+file, data)
+		}
+		for db, uri := range map[string]string{
+			"fixture_custom":    "nodelocal://0/fixture/archive.dump",
+			"fixture_directory": "nodelocal://0/fixture/archive_dir/",
+		} {
+			sqlDB.Exec(t, fmt.Sprintf(`CREATE DATABASE %s; USE %s`, db, db))
+			sqlDB.Exec(t, `IMPORT PGDUMP ($1)`, uri)
+			check(t, db)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		writeFile(t, "notarchive/toc.dat", []byte("CREATE TABLE t (i INT8);"))
+		sqlDB.ExpectErr(t, "toc.dat is not the TOC of a pg_dump directory-format archive",
+			`IMPORT PGDUMP ($1)`, "nodelocal://0/notarchive/")
+	})
+}
+
 // TestImportPgDumpGeo tests that a file with SQLFn classes can be
 // imported. These are functions like AddGeometryColumn which create and
 // execute SQL when called (!). They are, for example, used by shp2pgsql
@@ -5819,6 +5931,325 @@ func TestImportAvro(t *testing.T) {
 	})
 }
 
+func TestImportNDJSON(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	baseDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	tc := testcluster.StartTestCluster(
+		t, 1, base.TestClusterArgs{ServerArgs: base.TestServerArgs{ExternalIODir: baseDir}})
+	defer tc.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(tc.Conns[0])
+
+	const data = `{"id": 1, "name": "foo", "tags": ["a", "b"], "extra": true}
+
+{"id": 2, "name": null, "attrs": {"k": [1, 2]}}
+{"id": "3", "tags": []}
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(baseDir, "data.ndjson"), []byte(data), 0644))
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, err := gw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(baseDir, "data.ndjson.gz"), gzipped.Bytes(), 0644))
+	const bad = `{"id": 1}
+{"id": 2
+{"id": "three"}
+{"id": 4}
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(baseDir, "bad.jsonl"), []byte(bad), 0644))
+
+	for _, file := range []string{"data.ndjson", "data.ndjson.gz"} {
+		t.Run(file, func(t *testing.T) {
+			sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+			sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY, name STRING, tags STRING[], attrs JSONB)`)
+			sqlDB.Exec(t, `IMPORT INTO t NDJSON DATA ($1)`, "nodelocal://0/"+file)
+			sqlDB.CheckQueryResults(t, `SELECT * FROM t ORDER BY id`, [][]string{
+				{"1", "foo", "{a,b}", "NULL"},
+				{"2", "NULL", "NULL", `{"k": [1, 2]}`},
+				{"3", "NULL", "{}", "NULL"},
+			})
+		})
+	}
+
+	t.Run("document-column", func(t *testing.T) {
+		sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+		sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY DEFAULT unique_rowid(), doc JSONB)`)
+		sqlDB.Exec(t, `IMPORT INTO t (doc) JSONL DATA ($1)`, "nodelocal://0/data.ndjson")
+		sqlDB.CheckQueryResults(t, `SELECT doc->>'id' FROM t ORDER BY 1`, [][]string{{"1"}, {"2"}, {"3"}})
+
+		sqlDB.Exec(t, `DROP TABLE t`)
+		sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY DEFAULT unique_rowid(), doc JSONB, note STRING)`)
+		sqlDB.Exec(t, `IMPORT INTO t (doc, note) NDJSON DATA ($1) WITH document_column = 'doc'`,
+			"nodelocal://0/data.ndjson")
+		sqlDB.CheckQueryResults(t, `SELECT count(*) FROM t WHERE doc IS NOT NULL AND note IS NULL`,
+			[][]string{{"3"}})
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+		sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY, name STRING, tags STRING[], attrs JSONB)`)
+		sqlDB.ExpectErr(t, `line 1: could not find column for key extra`,
+			`IMPORT INTO t NDJSON DATA ($1) WITH strict_validation`, "nodelocal://0/data.ndjson")
+	})
+
+	t.Run("line-numbers", func(t *testing.T) {
+		sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+		sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY)`)
+		sqlDB.ExpectErr(t, `error parsing row 2: line 2: .*\(row: "{\\"id\\": 2"\)`,
+			`IMPORT INTO t NDJSON DATA ($1)`, "nodelocal://0/bad.jsonl")
+	})
+
+	t.Run("save-rejected", func(t *testing.T) {
+		sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+		sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY)`)
+		sqlDB.Exec(t, `IMPORT INTO t NDJSON DATA ($1) WITH experimental_save_rejected`,
+			"nodelocal://0/bad.jsonl")
+		sqlDB.CheckQueryResults(t, `SELECT id FROM t ORDER BY id`, [][]string{{"1"}, {"4"}})
+		rejected, err := ioutil.ReadFile(filepath.Join(baseDir, "bad.jsonl.rejected"))
+		require.NoError(t, err)
+		require.Equal(t, "{\"id\": 2\n{\"id\": \"three\"}\n", string(rejected))
+	})
+}
+
+func TestImportIntoOnConflict(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	baseDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	tc := testcluster.StartTestCluster(
+		t, 1, base.TestClusterArgs{ServerArgs: base.TestServerArgs{ExternalIODir: baseDir}})
+	defer tc.Stopper().Stop(ctx)
+	sqlDB := sqlutils.MakeSQLRunner(tc.Conns[0])
+
+	const data = "2,21,\n3,30,c\n4,40,d\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(baseDir, "data.csv"), []byte(data), 0644))
+
+	createTable := func(t *testing.T) {
+		sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+		sqlDB.Exec(t, `CREATE TABLE t (
+			id INT8 PRIMARY KEY, v INT8, s STRING, INDEX v_idx (v),
+			FAMILY f1 (id, v), FAMILY f2 (s)
+		)`)
+		sqlDB.Exec(t, `INSERT INTO t VALUES (1, 10, 'a'), (2, 20, 'b'), (3, 30, NULL)`)
+	}
+	checkIndexes := func(t *testing.T) {
+		sqlDB.CheckQueryResults(t, `EXPERIMENTAL SCRUB TABLE t WITH OPTIONS INDEX ALL`, [][]string{})
+		sqlDB.CheckQueryResults(t,
+			`SELECT (SELECT count(*) FROM t@primary), (SELECT count(*) FROM t@v_idx)`,
+			[][]string{{"4", "4"}})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		createTable(t)
+		sqlDB.ExpectErr(t, `ingested key collides with an existing one`,
+			`IMPORT INTO t CSV DATA ($1) WITH nullif = ''`, "nodelocal://0/data.csv")
+	})
+
+	t.Run("do-nothing", func(t *testing.T) {
+		createTable(t)
+		sqlDB.Exec(t, `IMPORT INTO t CSV DATA ($1) WITH nullif = '', on_conflict = 'do_nothing'`,
+			"nodelocal://0/data.csv")
+		sqlDB.CheckQueryResults(t, `SELECT * FROM t ORDER BY id`, [][]string{
+			{"1", "10", "a"}, {"2", "20", "b"}, {"3", "30", "NULL"}, {"4", "40", "d"},
+		})
+		sqlDB.CheckQueryResults(t, `SELECT id FROM t@v_idx WHERE v IN (20, 21) ORDER BY id`,
+			[][]string{{"2"}})
+		checkIndexes(t)
+	})
+
+	t.Run("do-update", func(t *testing.T) {
+		createTable(t)
+		sqlDB.Exec(t, `IMPORT INTO t CSV DATA ($1) WITH nullif = '', on_conflict = 'DO_UPDATE'`,
+			"nodelocal://0/data.csv")
+		sqlDB.CheckQueryResults(t, `SELECT * FROM t ORDER BY id`, [][]string{
+			{"1", "10", "a"}, {"2", "21", "NULL"}, {"3", "30", "c"}, {"4", "40", "d"},
+		})
+		sqlDB.CheckQueryResults(t, `SELECT id, v FROM t@v_idx WHERE v IN (20, 21) ORDER BY id`,
+			[][]string{{"2", "21"}})
+		checkIndexes(t)
+	})
+
+	// Imported rows with the same primary key fail the import rather than
+	// leaving the stale index entries of all but one of them behind.
+	t.Run("do-update-duplicates", func(t *testing.T) {
+		for name, dup := range map[string]string{
+			"existing": "2,22,x\n4,40,d\n2,23,y\n",
+			"new":      "5,50,e\n4,40,d\n5,51,f\n",
+		} {
+			t.Run(name, func(t *testing.T) {
+				createTable(t)
+				require.NoError(t, ioutil.WriteFile(filepath.Join(baseDir, "dup.csv"), []byte(dup), 0644))
+				sqlDB.ExpectErr(t, `duplicate key in primary index`,
+					`IMPORT INTO t CSV DATA ($1) WITH on_conflict = 'do_update'`, "nodelocal://0/dup.csv")
+				sqlDB.CheckQueryResults(t, `SELECT * FROM t ORDER BY id`, [][]string{
+					{"1", "10", "a"}, {"2", "20", "b"}, {"3", "30", "NULL"},
+				})
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		createTable(t)
+		sqlDB.ExpectErr(t, `invalid value "replace" for on_conflict option`,
+			`IMPORT INTO t CSV DATA ($1) WITH on_conflict = 'replace'`, "nodelocal://0/data.csv")
+		sqlDB.ExpectErr(t, `on_conflict option is only supported by IMPORT INTO`,
+			`IMPORT TABLE u (id INT8 PRIMARY KEY, v INT8, s STRING) CSV DATA ($1) WITH on_conflict = 'do_nothing'`,
+			"nodelocal://0/data.csv")
+		sqlDB.Exec(t, `CREATE UNIQUE INDEX v_unique ON t (v)`)
+		sqlDB.ExpectErr(t, `not supported for tables with unique secondary indexes`,
+			`IMPORT INTO t CSV DATA ($1) WITH on_conflict = 'do_update'`, "nodelocal://0/data.csv")
+	})
+}
+
+func TestImportIntoOnline(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	baseDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	tc := testcluster.StartTestCluster(
+		t, 1, base.TestClusterArgs{ServerArgs: base.TestServerArgs{ExternalIODir: baseDir}})
+	defer tc.Stopper().Stop(ctx)
+	conn := tc.Conns[0]
+	sqlDB := sqlutils.MakeSQLRunner(conn)
+	kvDB := tc.Server(0).DB()
+
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(baseDir, "data.csv"), []byte("4,40\n5,50\n6,60\n"), 0644))
+	// The rows of conflict.csv are merged in two batches, the second of which
+	// collides with a row inserted before the import.
+	var conflictData strings.Builder
+	for i := 1000; i < 1000+2*onlineMergeBatchRows; i++ {
+		fmt.Fprintf(&conflictData, "%d,%d\n", i, i*10)
+	}
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(baseDir, "conflict.csv"), []byte(conflictData.String()), 0644))
+
+	// While the import runs, the table is read and written to before the
+	// imported rows are merged into it, and read again after each batch of rows
+	// is merged, which must not see any of them until the import commits. The
+	// reads during the merge run with a high priority, so that they push the
+	// merge transaction rather than wait for it.
+	var forceFailure bool
+	var countDuringImport int
+	var countsDuringMerge []int
+	var errDuringImport, errDuringMerge error
+	tc.Server(0).JobRegistry().(*jobs.Registry).TestingResumerCreationKnobs = map[jobspb.Type]func(raw jobs.Resumer) jobs.Resumer{
+		jobspb.TypeImport: func(raw jobs.Resumer) jobs.Resumer {
+			r := raw.(*importResumer)
+			r.testingKnobs.afterImport = func(_ backupccl.RowCount) error {
+				errDuringImport = conn.QueryRow(`SELECT count(*) FROM t`).Scan(&countDuringImport)
+				if errDuringImport == nil {
+					_, errDuringImport = conn.Exec(`INSERT INTO t VALUES (100, 1000)`)
+				}
+				if forceFailure {
+					return errors.New("testing injected failure")
+				}
+				return nil
+			}
+			r.testingKnobs.afterOnlineMergeBatch = func() error {
+				if errDuringMerge != nil {
+					return nil
+				}
+				var count int
+				errDuringMerge = func() error {
+					tx, err := conn.Begin()
+					if err != nil {
+						return err
+					}
+					defer func() { _ = tx.Rollback() }()
+					if _, err := tx.Exec(`SET TRANSACTION PRIORITY HIGH`); err != nil {
+						return err
+					}
+					if err := tx.QueryRow(`SELECT count(*) FROM t`).Scan(&count); err != nil {
+						return err
+					}
+					return tx.Commit()
+				}()
+				countsDuringMerge = append(countsDuringMerge, count)
+				return nil
+			}
+			return r
+		},
+	}
+
+	createTable := func(t *testing.T) {
+		sqlDB.Exec(t, `DROP TABLE IF EXISTS t`)
+		sqlDB.Exec(t, `CREATE TABLE t (id INT8 PRIMARY KEY, v INT8, INDEX v_idx (v))`)
+		sqlDB.Exec(t, `INSERT INTO t VALUES (1, 10), (2, 20), (3, 30)`)
+		countDuringImport, errDuringImport, errDuringMerge = 0, nil, nil
+		countsDuringMerge = nil
+	}
+	// checkTable checks the rows of the table, and that its indexes and staging
+	// indexes hold one KV per row each.
+	checkTable := func(t *testing.T, expected [][]string) {
+		sqlDB.CheckQueryResults(t, `SELECT * FROM t ORDER BY id`, expected)
+		sqlDB.CheckQueryResults(t, `SELECT state FROM crdb_internal.tables WHERE table_id = 't'::regclass::int`,
+			[][]string{{"PUBLIC"}})
+		sqlDB.CheckQueryResults(t, `EXPERIMENTAL SCRUB TABLE t WITH OPTIONS INDEX ALL`, [][]string{})
+		var tableID uint32
+		sqlDB.QueryRow(t, `SELECT 't'::regclass::int`).Scan(&tableID)
+		tablePrefix := keys.SystemSQLCodec.TablePrefix(tableID)
+		kvs, err := kvDB.Scan(ctx, tablePrefix, tablePrefix.PrefixEnd(), 0 /* maxRows */)
+		require.NoError(t, err)
+		require.Len(t, kvs, 2*len(expected))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		createTable(t)
+		sqlDB.Exec(t, `IMPORT INTO t CSV DATA ($1) WITH online`, "nodelocal://0/data.csv")
+		require.NoError(t, errDuringImport)
+		require.Equal(t, 3, countDuringImport)
+		require.NoError(t, errDuringMerge)
+		require.Equal(t, []int{4}, countsDuringMerge)
+		checkTable(t, [][]string{
+			{"1", "10"}, {"2", "20"}, {"3", "30"}, {"4", "40"}, {"5", "50"}, {"6", "60"}, {"100", "1000"},
+		})
+		sqlDB.CheckQueryResults(t, `SELECT id FROM t@v_idx WHERE v >= 50 ORDER BY v`,
+			[][]string{{"5"}, {"6"}, {"100"}})
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		createTable(t)
+		forceFailure = true
+		defer func() { forceFailure = false }()
+		sqlDB.ExpectErr(t, `testing injected failure`,
+			`IMPORT INTO t CSV DATA ($1) WITH online`, "nodelocal://0/data.csv")
+		require.NoError(t, errDuringImport)
+		checkTable(t, [][]string{{"1", "10"}, {"2", "20"}, {"3", "30"}, {"100", "1000"}})
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		createTable(t)
+		sqlDB.Exec(t, `INSERT INTO t VALUES (2500, 1)`)
+		sqlDB.ExpectErr(t, `duplicate key value violates unique constraint "primary"`,
+			`IMPORT INTO t CSV DATA ($1) WITH online`, "nodelocal://0/conflict.csv")
+		require.NoError(t, errDuringImport)
+		require.NoError(t, errDuringMerge)
+		// The rows of the first batch were never visible, and are discarded along
+		// with their index entries when the merge fails on the second batch.
+		require.Equal(t, []int{5}, countsDuringMerge)
+		checkTable(t, [][]string{{"1", "10"}, {"2", "20"}, {"3", "30"}, {"100", "1000"}, {"2500", "1"}})
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		createTable(t)
+		sqlDB.ExpectErr(t, `online option is only supported by IMPORT INTO`,
+			`IMPORT TABLE u (id INT8 PRIMARY KEY, v INT8) CSV DATA ($1) WITH online`,
+			"nodelocal://0/data.csv")
+		sqlDB.ExpectErr(t, `online option cannot be used with the on_conflict option`,
+			`IMPORT INTO t CSV DATA ($1) WITH online, on_conflict = 'do_nothing'`,
+			"nodelocal://0/data.csv")
+	})
+}
+
 // TestImportClientDisconnect ensures that an import job can complete even if
 // the client connection which started it closes. This test uses a helper
 // subprocess to force a closed client connection without needing to rely
@@ -5932,6 +6363,8 @@ func TestDisallowsInvalidFormatOptions(t *testing.T) {
 	addOpts(mysqlOutAllowedOptions)
 	addOpts(pgDumpAllowedOptions)
 	addOpts(pgCopyAllowedOptions)
+	addOpts(parquetAllowedOptions)
+	addOpts(ndjsonAllowedOptions)
 
 	// Helper to pick num options from the set of allowed and the set
 	// of all other options.  Returns generated options plus a flag indicating
@@ -5978,6 +6411,8 @@ func TestDisallowsInvalidFormatOptions(t *testing.T) {
 		{"mysqldump", mysqlDumpAllowedOptions},
 		{"pgdump", pgDumpAllowedOptions},
 		{"pgcopy", pgCopyAllowedOptions},
+		{"parquet", parquetAllowedOptions},
+		{"ndjson", ndjsonAllowedOptions},
 	}
 
 	for _, tc := range tests {
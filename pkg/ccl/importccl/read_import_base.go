@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/execinfra"
@@ -103,11 +104,29 @@ func runImport(
 			spec.User())
 	})
 
+	// When importing into a table with the on_conflict option, resolve the
+	// conflicts of the produced KVs with the existing rows before ingesting them.
+	ingestCh := kvCh
+	conflictResolver, err := makeImportConflictResolver(ctx, flowCtx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if conflictResolver != nil {
+		resolvedCh := make(chan row.KVBatch, 10)
+		ingestCh = resolvedCh
+		group.GoCtx(func(ctx context.Context) error {
+			defer close(resolvedCh)
+			ctx, span := tracing.ChildSpan(ctx, "resolveImportConflicts")
+			defer span.Finish()
+			return conflictResolver.run(ctx, kvCh, resolvedCh)
+		})
+	}
+
 	// Ingest the KVs that the producer group emitted to the chan and the row result
 	// at the end is one row containing an encoded BulkOpSummary.
 	var summary *roachpb.BulkOpSummary
 	group.GoCtx(func(ctx context.Context) error {
-		summary, err = ingestKvs(ctx, flowCtx, spec, progCh, kvCh)
+		summary, err = ingestKvs(ctx, flowCtx, spec, progCh, ingestCh)
 		if err != nil {
 			return err
 		}
@@ -129,6 +148,9 @@ func runImport(
 	if err = group.Wait(); err != nil {
 		return nil, err
 	}
+	if conflictResolver != nil {
+		summary.Add(conflictResolver.summary)
+	}
 
 	return summary, nil
 }
@@ -192,23 +214,36 @@ func readInputFiles(
 				return err
 			}
 			defer es.Close()
-			raw, err := es.ReadFile(ctx, "")
-			if err != nil {
-				return err
-			}
-			defer raw.Close()
 
-			src := &fileReader{total: fileSizes[dataFileIndex], counter: byteCounter{r: raw}}
-			decompressed, err := decompressingReader(&src.counter, dataFile, format.Compression)
-			if err != nil {
-				return err
+			src := &fileReader{total: fileSizes[dataFileIndex], storage: es}
+			if format.Format == roachpb.IOFileFormat_Parquet {
+				// Parquet files are read with random access from storage, which
+				// is not possible through a decompressing stream. Parquet
+				// compresses its pages itself.
+				if guessCompressionFromName(dataFile, format.Compression) != roachpb.IOFileFormat_None {
+					return errors.New("compressed parquet files are not supported")
+				}
+			} else {
+				raw, err := es.ReadFile(ctx, "")
+				if err != nil {
+					return err
+				}
+				defer raw.Close()
+
+				src.counter = byteCounter{r: raw}
+				decompressed, err := decompressingReader(&src.counter, dataFile, format.Compression)
+				if err != nil {
+					return err
+				}
+				defer decompressed.Close()
+				src.Reader = decompressed
 			}
-			defer decompressed.Close()
-			src.Reader = decompressed
 
 			var rejected chan string
 			if (format.Format == roachpb.IOFileFormat_CSV && format.SaveRejected) ||
-				(format.Format == roachpb.IOFileFormat_MysqlOutfile && format.SaveRejected) {
+				(format.Format == roachpb.IOFileFormat_MysqlOutfile && format.SaveRejected) ||
+				(format.Format == roachpb.IOFileFormat_Parquet && format.SaveRejected) ||
+				(format.Format == roachpb.IOFileFormat_NDJSON && format.SaveRejected) {
 				rejected = make(chan string)
 			}
 			if rejected != nil {
@@ -218,7 +253,7 @@ func readInputFiles(
 					var countRejected int64
 					for s := range rejected {
 						countRejected++
-						if countRejected > 1000 { // TODO(spaskob): turn the magic constant into an option
+						if countRejected > maxRejectedRows {
 							return pgerror.Newf(
 								pgcode.DataCorrupted,
 								"too many parsing errors (%d) encountered for file %s",
@@ -275,6 +310,11 @@ func readInputFiles(
 	return nil
 }
 
+// maxRejectedRows is the number of rows of a file which may fail to import when
+// the rejected rows are saved, before the import fails.
+// TODO(spaskob): turn the magic constant into an option.
+const maxRejectedRows = 1000
+
 func rejectedFilename(datafile string) (string, error) {
 	parsedURI, err := url.Parse(datafile)
 	if err != nil {
@@ -331,6 +371,10 @@ type fileReader struct {
 	io.Reader
 	total   int64
 	counter byteCounter
+	// storage is the storage the file is read from. Formats which need random
+	// access to the file read it directly from storage instead of through
+	// Reader.
+	storage cloud.ExternalStorage
 }
 
 func (f fileReader) ReadFraction() float32 {
@@ -351,6 +395,8 @@ type inputConverter interface {
 func formatHasNamedColumns(format roachpb.IOFileFormat_FileFormat) bool {
 	switch format {
 	case roachpb.IOFileFormat_Avro,
+		roachpb.IOFileFormat_Parquet,
+		roachpb.IOFileFormat_NDJSON,
 		roachpb.IOFileFormat_Mysqldump,
 		roachpb.IOFileFormat_PgDump:
 		return true
@@ -391,6 +437,9 @@ type importRowError struct {
 	err    error
 	row    string
 	rowNum int64
+	// record is the record of the row as read from the input, for formats whose
+	// rows cannot be saved as lines of text. See importFileContext.
+	record interface{}
 }
 
 func (e *importRowError) Error() string {
@@ -416,12 +465,33 @@ type parallelImportContext struct {
 	kvCh       chan row.KVBatch     // Channel for sending KV batches.
 }
 
+// targetColumns returns the descriptors of the columns being imported, in the
+// order in which the datum converter expects their values.
+func (c *parallelImportContext) targetColumns() ([]descpb.ColumnDescriptor, error) {
+	if len(c.targetCols) == 0 {
+		return c.tableDesc.VisibleColumns(), nil
+	}
+	cols := make([]descpb.ColumnDescriptor, 0, len(c.targetCols))
+	for _, name := range c.targetCols {
+		col, _, err := c.tableDesc.FindColumnByName(name)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, *col)
+	}
+	return cols, nil
+}
+
 // importFileContext describes state specific to a file being imported.
 type importFileContext struct {
 	source   int32       // Source is where the row data in the batch came from.
 	skip     int64       // Number of records to skip
 	rejected chan string // Channel for reporting corrupt "rows"
 	rowLimit int64       // Number of records to process before we stop importing from a file.
+	// rejectedRecords is used instead of rejected to report the records of
+	// corrupt rows, for formats which save them in a file of the same format
+	// rather than as lines of text.
+	rejectedRecords chan interface{}
 }
 
 // handleCorruptRow reports an error encountered while processing a row
@@ -429,9 +499,15 @@ type importFileContext struct {
 func handleCorruptRow(ctx context.Context, fileCtx *importFileContext, err error) error {
 	log.Errorf(ctx, "%+v", err)
 
-	if rowErr := (*importRowError)(nil); errors.As(err, &rowErr) && fileCtx.rejected != nil {
-		fileCtx.rejected <- rowErr.row + "\n"
-		return nil
+	if rowErr := (*importRowError)(nil); errors.As(err, &rowErr) {
+		if fileCtx.rejectedRecords != nil && rowErr.record != nil {
+			fileCtx.rejectedRecords <- rowErr.record
+			return nil
+		}
+		if fileCtx.rejected != nil {
+			fileCtx.rejected <- rowErr.row + "\n"
+			return nil
+		}
 	}
 
 	return err
@@ -481,6 +557,22 @@ type importRowConsumer interface {
 	FillDatums(row interface{}, rowNum int64, conv *row.DatumRowConverter) error
 }
 
+// importRecordFormatter is implemented by the importRowConsumers which can
+// format the records of their rows as they appeared in the input.
+type importRecordFormatter interface {
+	// FormatRecord returns the given record in the format of the input.
+	FormatRecord(record interface{}) string
+}
+
+// formatRecord returns the given record of a row as it appeared in the input,
+// if the consumer is able to reconstruct it.
+func formatRecord(consumer importRowConsumer, record interface{}) string {
+	if f, ok := consumer.(importRecordFormatter); ok {
+		return f.FormatRecord(record)
+	}
+	return fmt.Sprintf("%v", record)
+}
+
 // batch represents batch of data to convert.
 type batch struct {
 	data     []interface{}
@@ -672,7 +764,7 @@ func (p *parallelImporter) importWorker(
 
 			rowIndex := int64(timestamp) + rowNum
 			if err := conv.Row(ctx, conv.KvBatch.Source, rowIndex); err != nil {
-				return newImportRowError(err, fmt.Sprintf("%v", record), rowNum)
+				return newImportRowError(err, formatRecord(consumer, record), rowNum)
 			}
 		}
 	}
@@ -169,6 +169,12 @@ type csvRowConsumer struct {
 }
 
 var _ importRowConsumer = &csvRowConsumer{}
+var _ importRecordFormatter = &csvRowConsumer{}
+
+// FormatRecord implements importRecordFormatter interface.
+func (c *csvRowConsumer) FormatRecord(record interface{}) string {
+	return strRecord(record.([]string), c.opts.Comma)
+}
 
 // FillDatums() implements importRowConsumer interface
 func (c *csvRowConsumer) FillDatums(
@@ -0,0 +1,344 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+	"github.com/cockroachdb/errors"
+)
+
+// An NDJSON (newline-delimited JSON, also known as JSON Lines) file contains
+// one JSON document per line. Blank lines are ignored.
+//
+// By default, every document must be a JSON object whose top-level keys are
+// matched to the target columns by name. Alternatively, each document can be
+// imported as a whole into a single JSONB column: either the column named by
+// the document_column option, or, if the import targets exactly one column and
+// that column is of type JSONB, that column.
+
+// ndjsonLine is a single non-blank line of an NDJSON file.
+type ndjsonLine struct {
+	num  int64 // 1-based line number in the (decompressed) input.
+	data []byte
+}
+
+// String returns the contents of the line, which are reported for rows that
+// fail to import.
+func (l ndjsonLine) String() string {
+	return string(l.data)
+}
+
+// ndjsonRowProducer implements importRowProducer interface.
+type ndjsonRowProducer struct {
+	scanner *bufio.Scanner
+	input   *fileReader
+	lineNum int64
+	line    ndjsonLine
+	err     error
+}
+
+var _ importRowProducer = &ndjsonRowProducer{}
+
+// utf8BOM is the byte order mark some tools write at the start of the file.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Scan implements importRowProducer interface.
+func (p *ndjsonRowProducer) Scan() bool {
+	for p.scanner.Scan() {
+		p.lineNum++
+		data := p.scanner.Bytes()
+		if p.lineNum == 1 {
+			data = bytes.TrimPrefix(data, utf8BOM)
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		// The scanner reuses its buffer, but the line is handed to another
+		// goroutine, so it must be copied.
+		p.line = ndjsonLine{num: p.lineNum, data: append([]byte(nil), data...)}
+		return true
+	}
+	if err := p.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = wrapWithLineTooLongHint(errors.Newf("line %d too long", p.lineNum+1))
+		}
+		p.err = err
+	}
+	return false
+}
+
+// Err implements importRowProducer interface.
+func (p *ndjsonRowProducer) Err() error {
+	return p.err
+}
+
+// Skip implements importRowProducer interface.
+func (p *ndjsonRowProducer) Skip() error {
+	return nil
+}
+
+// Row implements importRowProducer interface.
+func (p *ndjsonRowProducer) Row() (interface{}, error) {
+	return p.line, nil
+}
+
+// Progress implements importRowProducer interface.
+func (p *ndjsonRowProducer) Progress() float32 {
+	return p.input.ReadFraction()
+}
+
+// ndjsonConsumer implements importRowConsumer interface.
+type ndjsonConsumer struct {
+	targetCols []*types.T
+	colNames   []string
+	// colIdxByName maps normalized key names to target column indexes.
+	colIdxByName map[string]int
+	// documentCol is the index of the column into which whole documents are
+	// imported, or -1 if top-level keys are mapped to columns.
+	documentCol int
+	strict      bool
+}
+
+var _ importRowConsumer = &ndjsonConsumer{}
+
+// FillDatums implements importRowConsumer interface.
+func (c *ndjsonConsumer) FillDatums(
+	native interface{}, rowNum int64, conv *row.DatumRowConverter,
+) error {
+	line, ok := native.(ndjsonLine)
+	if !ok {
+		return errors.Errorf("unexpected native type; expected ndjsonLine found %T instead", native)
+	}
+	if err := c.fillDatums(line, conv); err != nil {
+		return newImportRowError(errors.Wrapf(err, "line %d", line.num), line.String(), rowNum)
+	}
+	return nil
+}
+
+func (c *ndjsonConsumer) fillDatums(line ndjsonLine, conv *row.DatumRowConverter) error {
+	doc, err := json.ParseJSON(string(line.data))
+	if err != nil {
+		return err
+	}
+
+	for i := range c.targetCols {
+		conv.Datums[i] = tree.DNull
+	}
+	if c.documentCol >= 0 {
+		conv.Datums[c.documentCol] = tree.NewDJSON(doc)
+		return nil
+	}
+
+	if doc.Type() != json.ObjectJSONType {
+		return errors.Errorf("expected a JSON object, found %s", doc)
+	}
+	var set []bool
+	if c.strict {
+		set = make([]bool, len(c.targetCols))
+	}
+	it, err := doc.ObjectIter()
+	if err != nil {
+		return err
+	}
+	for it.Next() {
+		idx, ok := c.colIdxByName[lexbase.NormalizeName(it.Key())]
+		if !ok {
+			if c.strict {
+				return errors.Errorf("could not find column for key %s", it.Key())
+			}
+			continue
+		}
+		datum, err := ndjsonToDatum(it.Value(), c.targetCols[idx], conv.EvalCtx)
+		if err != nil {
+			return errors.Wrapf(err, "key %s", it.Key())
+		}
+		conv.Datums[idx] = datum
+		if set != nil {
+			set[idx] = true
+		}
+	}
+	for i := range set {
+		if !set[i] {
+			return errors.Errorf("column %s was not set in the ndjson import", c.colNames[i])
+		}
+	}
+	return nil
+}
+
+// ndjsonToDatum converts a JSON value into a datum of type t. JSON strings are
+// parsed as the string representation of t; other scalars, as well as objects
+// and arrays imported into non-JSONB columns, are parsed from their JSON text.
+func ndjsonToDatum(v json.JSON, t *types.T, evalCtx *tree.EvalContext) (tree.Datum, error) {
+	switch {
+	case v.Type() == json.NullJSONType:
+		return tree.DNull, nil
+	case t.Family() == types.JsonFamily:
+		return tree.NewDJSON(v), nil
+	case v.Type() == json.ArrayJSONType && t.Family() == types.ArrayFamily:
+		arr := tree.NewDArray(t.ArrayContents())
+		for i, n := 0, v.Len(); i < n; i++ {
+			elem, err := v.FetchValIdx(i)
+			if err != nil {
+				return nil, err
+			}
+			d, err := ndjsonToDatum(elem, t.ArrayContents(), evalCtx)
+			if err != nil {
+				return nil, err
+			}
+			if err := arr.Append(d); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case v.Type() == json.StringJSONType:
+		s, err := v.AsText()
+		if err != nil {
+			return nil, err
+		}
+		return rowenc.ParseDatumStringAs(t, *s, evalCtx)
+	default:
+		return rowenc.ParseDatumStringAs(t, v.String(), evalCtx)
+	}
+}
+
+func newNDJSONConsumer(
+	importCtx *parallelImportContext, opts roachpb.NDJSONOptions,
+) (*ndjsonConsumer, error) {
+	targetCols, err := importCtx.targetColumns()
+	if err != nil {
+		return nil, err
+	}
+	c := &ndjsonConsumer{
+		targetCols:   make([]*types.T, len(targetCols)),
+		colNames:     make([]string, len(targetCols)),
+		colIdxByName: make(map[string]int, len(targetCols)),
+		documentCol:  -1,
+		strict:       opts.StrictMode,
+	}
+	for i := range targetCols {
+		c.targetCols[i] = targetCols[i].Type
+		c.colNames[i] = targetCols[i].Name
+		c.colIdxByName[targetCols[i].Name] = i
+	}
+
+	if opts.DocumentColumn != "" {
+		idx, ok := c.colIdxByName[opts.DocumentColumn]
+		if !ok {
+			return nil, errors.Errorf("document column %s is not being imported", opts.DocumentColumn)
+		}
+		c.documentCol = idx
+	} else if len(targetCols) == 1 {
+		c.documentCol = 0
+	}
+	if c.documentCol >= 0 && c.targetCols[c.documentCol].Family() != types.JsonFamily {
+		if opts.DocumentColumn == "" {
+			// A single non-JSONB column is populated from the matching key.
+			c.documentCol = -1
+		} else {
+			return nil, errors.Errorf(
+				"document column %s must be of type JSONB, found %s",
+				opts.DocumentColumn, c.targetCols[c.documentCol].SQLString())
+		}
+	}
+	return c, nil
+}
+
+func newImportNDJSONPipeline(
+	n *ndjsonInputReader, input *fileReader,
+) (importRowProducer, importRowConsumer, error) {
+	consumer, err := newNDJSONConsumer(n.importCtx, n.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scanner := bufio.NewScanner(input)
+	maxRowSize := int(n.opts.MaxRowSize)
+	if maxRowSize == 0 {
+		maxRowSize = defaultScanBuffer
+	}
+	scanner.Buffer(nil, maxRowSize)
+	producer := &ndjsonRowProducer{
+		scanner: scanner,
+		input:   input,
+	}
+	return producer, consumer, nil
+}
+
+type ndjsonInputReader struct {
+	importCtx *parallelImportContext
+	opts      roachpb.NDJSONOptions
+}
+
+var _ inputConverter = &ndjsonInputReader{}
+
+func newNDJSONInputReader(
+	kvCh chan row.KVBatch,
+	tableDesc *tabledesc.Immutable,
+	targetCols tree.NameList,
+	opts roachpb.NDJSONOptions,
+	walltime int64,
+	parallelism int,
+	evalCtx *tree.EvalContext,
+) (*ndjsonInputReader, error) {
+	return &ndjsonInputReader{
+		importCtx: &parallelImportContext{
+			walltime:   walltime,
+			numWorkers: parallelism,
+			evalCtx:    evalCtx,
+			tableDesc:  tableDesc,
+			targetCols: targetCols,
+			kvCh:       kvCh,
+		},
+		opts: opts,
+	}, nil
+}
+
+func (n *ndjsonInputReader) start(group ctxgroup.Group) {}
+
+func (n *ndjsonInputReader) readFiles(
+	ctx context.Context,
+	dataFiles map[int32]string,
+	resumePos map[int32]int64,
+	format roachpb.IOFileFormat,
+	makeExternalStorage cloud.ExternalStorageFactory,
+	user security.SQLUsername,
+) error {
+	return readInputFiles(ctx, dataFiles, resumePos, format, n.readFile, makeExternalStorage, user)
+}
+
+func (n *ndjsonInputReader) readFile(
+	ctx context.Context, input *fileReader, inputIdx int32, resumePos int64, rejected chan string,
+) error {
+	producer, consumer, err := newImportNDJSONPipeline(n, input)
+	if err != nil {
+		return err
+	}
+
+	fileCtx := &importFileContext{
+		source:   inputIdx,
+		skip:     resumePos,
+		rejected: rejected,
+		rowLimit: n.opts.RowLimit,
+	}
+	return runParallelImport(ctx, n.importCtx, fileCtx, producer, consumer)
+}
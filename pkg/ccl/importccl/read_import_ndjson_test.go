@@ -0,0 +1,168 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+// readNDJSON runs the NDJSON producer and consumer over data and returns the
+// imported rows, or the error of the first row that failed to import.
+func readNDJSON(
+	t *testing.T, create string, targetCols tree.NameList, opts roachpb.NDJSONOptions, data string,
+) ([]string, error) {
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	evalCtx := tree.MakeTestingEvalContext(st)
+	table := descForTable(ctx, t, create, 10, 20, NoFKs).ImmutableCopy().(*tabledesc.Immutable)
+
+	reader, err := newNDJSONInputReader(nil, table, targetCols, opts, 0, 1, &evalCtx)
+	require.NoError(t, err)
+	producer, consumer, err := newImportNDJSONPipeline(
+		reader, &fileReader{Reader: strings.NewReader(data)})
+	if err != nil {
+		return nil, err
+	}
+	conv, err := row.NewDatumRowConverter(ctx, table, targetCols, evalCtx.Copy(), nil)
+	require.NoError(t, err)
+
+	var rows []string
+	var rowNum int64
+	for producer.Scan() {
+		r, err := producer.Row()
+		require.NoError(t, err)
+		rowNum++
+		if err := consumer.FillDatums(r, rowNum, conv); err != nil {
+			return rows, err
+		}
+		datums := tree.Datums(conv.Datums[:len(conv.VisibleCols)])
+		rows = append(rows, datums.String())
+	}
+	return rows, producer.Err()
+}
+
+func TestNDJSONColumnMapping(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const create = `CREATE TABLE t (
+		id INT8 PRIMARY KEY, name STRING, score DECIMAL, ok BOOL, tags INT8[], info JSONB, raw STRING)`
+	const data = "\xEF\xBB\xBF" + `{"id": 1, "name": "foo", "score": 1.50, "ok": true, "tags": [1, null, 3]}
+
+{"id": "2", "Name": "bar", "info": {"a": [1, {"b": null}]}, "raw": {"x": 1}, "unknown": 1}
+  ` + "\r" + `
+{"id": 3, "name": null, "score": "2.5", "ok": "false", "tags": []}
+`
+	rows, err := readNDJSON(t, create, nil, roachpb.NDJSONOptions{}, data)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`(1, 'foo', 1.50, true, ARRAY[1,NULL,3], NULL, NULL)`,
+		`(2, 'bar', NULL, NULL, NULL, '{"a": [1, {"b": null}]}', '{"x": 1}')`,
+		`(3, NULL, 2.5, false, ARRAY[], NULL, NULL)`,
+	}, rows)
+}
+
+func TestNDJSONDocumentColumn(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const data = `{"id": 1, "name": "foo"}
+[1, 2]
+`
+	t.Run("single-jsonb-column", func(t *testing.T) {
+		rows, err := readNDJSON(t, `CREATE TABLE t (doc JSONB)`, nil, roachpb.NDJSONOptions{}, data)
+		require.NoError(t, err)
+		require.Equal(t, []string{`('{"id": 1, "name": "foo"}')`, `('[1, 2]')`}, rows)
+	})
+
+	t.Run("document-column-option", func(t *testing.T) {
+		rows, err := readNDJSON(t, `CREATE TABLE t (id INT8, doc JSONB)`, nil,
+			roachpb.NDJSONOptions{DocumentColumn: "doc"}, data)
+		require.NoError(t, err)
+		require.Equal(t, []string{`(NULL, '{"id": 1, "name": "foo"}')`, `(NULL, '[1, 2]')`}, rows)
+	})
+
+	t.Run("single-non-jsonb-column", func(t *testing.T) {
+		rows, err := readNDJSON(t, `CREATE TABLE t (id INT8)`, nil, roachpb.NDJSONOptions{}, data)
+		require.EqualError(t, err, `error parsing row 2: line 2: expected a JSON object, found [1, 2] (row: "[1, 2]")`)
+		require.Equal(t, []string{`(1)`}, rows)
+	})
+
+	t.Run("document-column-must-be-jsonb", func(t *testing.T) {
+		_, err := readNDJSON(t, `CREATE TABLE t (id INT8, doc STRING)`, nil,
+			roachpb.NDJSONOptions{DocumentColumn: "doc"}, data)
+		require.EqualError(t, err, `document column doc must be of type JSONB, found STRING`)
+	})
+
+	t.Run("document-column-not-imported", func(t *testing.T) {
+		_, err := readNDJSON(t, `CREATE TABLE t (id INT8, doc JSONB)`, tree.NameList{"id"},
+			roachpb.NDJSONOptions{DocumentColumn: "doc"}, data)
+		require.EqualError(t, err, `document column doc is not being imported`)
+	})
+}
+
+func TestNDJSONErrors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const create = `CREATE TABLE t (a INT8, b STRING)`
+	tests := []struct {
+		name string
+		data string
+		opts roachpb.NDJSONOptions
+		err  string
+	}{
+		{
+			name: "malformed-json",
+			data: "{\"a\": 1}\n\n{\"a\": 2,\n",
+			err:  `error parsing row 2: line 3: .*\(row: "{\\"a\\": 2,"\)`,
+		},
+		{
+			name: "bad-value",
+			data: "{\"a\": 1}\n{\"a\": \"x\"}\n",
+			err:  `error parsing row 2: line 2: key a: could not parse "x" as type int`,
+		},
+		{
+			name: "strict-extra-key",
+			data: "{\"a\": 1, \"b\": \"x\", \"c\": 3}\n",
+			opts: roachpb.NDJSONOptions{StrictMode: true},
+			err:  `error parsing row 1: line 1: could not find column for key c`,
+		},
+		{
+			name: "strict-missing-key",
+			data: "{\"a\": 1, \"b\": \"x\"}\n{\"a\": 2}\n",
+			opts: roachpb.NDJSONOptions{StrictMode: true},
+			err:  `error parsing row 2: line 2: column b was not set in the ndjson import`,
+		},
+		{
+			name: "line-too-long",
+			data: "{\"a\": 1}\n{\"b\": \"" + strings.Repeat("x", 100) + "\"}\n",
+			opts: roachpb.NDJSONOptions{MaxRowSize: 64},
+			err:  `line 2 too long`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readNDJSON(t, create, nil, test.opts, test.data)
+			require.Error(t, err)
+			require.Regexp(t, test.err, err.Error())
+		})
+	}
+}
@@ -0,0 +1,1116 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/apd/v2"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
+	"github.com/cockroachdb/cockroach/pkg/util/json"
+	"github.com/cockroachdb/cockroach/pkg/util/timeofday"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil/pgdate"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+)
+
+// Parquet is a columnar format: a file is a sequence of row groups followed by
+// a footer describing the schema and the location of every row group. The
+// reader below decodes one row group at a time and hands each row, as the
+// map[string]interface{} produced by the parquet library, to the parallel
+// import workers.
+//
+// Top-level Parquet columns are matched to the target columns by name. The
+// Parquet physical and logical types of a column determine the "natural" SQL
+// type of its values (e.g. INT64 annotated as TIMESTAMP is a TIMESTAMPTZ, a
+// BYTE_ARRAY annotated as STRING is a STRING). In strict mode, the natural type
+// must map directly to the type of the target column. Otherwise, values whose
+// types differ are converted by way of their string representation.
+//
+// Nested columns (lists, maps and structs) can be imported into JSONB columns,
+// and lists can also be imported into ARRAY columns.
+
+// parquetDecoder converts a non-NULL value of a Parquet column, as returned by
+// the parquet library, into a datum of the type of the target column.
+type parquetDecoder func(v interface{}, evalCtx *tree.EvalContext) (tree.Datum, error)
+
+// parquetColumnDecoder describes how a top-level Parquet column is imported.
+type parquetColumnDecoder struct {
+	name   string // Name of the Parquet column.
+	idx    int    // Index of the target column.
+	decode parquetDecoder
+}
+
+// parquetConsumer implements importRowConsumer interface.
+type parquetConsumer struct {
+	importCtx *parallelImportContext
+	columns   []parquetColumnDecoder
+}
+
+var _ importRowConsumer = &parquetConsumer{}
+
+// FillDatums implements importRowConsumer interface.
+func (p *parquetConsumer) FillDatums(
+	native interface{}, rowNum int64, conv *row.DatumRowConverter,
+) error {
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return errors.Errorf(
+			"unexpected native type; expected map[string]interface{} found %T instead", native)
+	}
+
+	// The parquet library omits NULL values from the record, so every target
+	// column starts out as NULL.
+	for i := range conv.VisibleCols {
+		conv.Datums[i] = tree.DNull
+	}
+	for _, col := range p.columns {
+		v, ok := record[col.name]
+		if !ok || v == nil {
+			continue
+		}
+		datum, err := col.decode(v, conv.EvalCtx)
+		if err != nil {
+			return &importRowError{
+				err:    errors.Wrapf(err, "column %s", col.name),
+				row:    fmt.Sprintf("%v", record),
+				rowNum: rowNum,
+				record: record,
+			}
+		}
+		conv.Datums[col.idx] = datum
+	}
+	return nil
+}
+
+// parquetRowStream is an importRowProducer reading the rows of a Parquet file
+// one row group at a time.
+type parquetRowStream struct {
+	reader   *goparquet.FileReader
+	numRows  int64
+	rowsRead int64
+	row      map[string]interface{}
+	err      error
+}
+
+var _ importRowProducer = &parquetRowStream{}
+
+// Scan implements importRowProducer interface.
+func (p *parquetRowStream) Scan() bool {
+	p.row, p.err = p.reader.NextRow()
+	if errors.Is(p.err, io.EOF) {
+		p.err = nil
+		return false
+	}
+	if p.err != nil {
+		return false
+	}
+	p.rowsRead++
+	return true
+}
+
+// Err implements importRowProducer interface.
+func (p *parquetRowStream) Err() error {
+	return p.err
+}
+
+// Skip implements importRowProducer interface.
+func (p *parquetRowStream) Skip() error {
+	p.row = nil
+	return nil
+}
+
+// Row implements importRowProducer interface.
+func (p *parquetRowStream) Row() (interface{}, error) {
+	res := p.row
+	p.row = nil
+	return res, nil
+}
+
+// Progress implements importRowProducer interface.
+//
+// The footer of the file is read before any of its rows, so progress is
+// reported in terms of rows rather than bytes.
+func (p *parquetRowStream) Progress() float32 {
+	if p.numRows == 0 {
+		return 0
+	}
+	return float32(p.rowsRead) / float32(p.numRows)
+}
+
+func newImportParquetPipeline(
+	p *parquetInputReader, input io.ReadSeeker,
+) (importRowProducer, importRowConsumer, error) {
+	reader, err := goparquet.NewFileReader(input)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading parquet file")
+	}
+
+	consumer, err := newParquetConsumer(p.importContext, reader.GetSchemaDefinition(), p.opts.StrictMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	producer := &parquetRowStream{
+		reader:  reader,
+		numRows: reader.NumRows(),
+	}
+	return producer, consumer, nil
+}
+
+// newParquetConsumer matches the top-level columns of the Parquet schema to
+// the target columns of the import and creates the decoders for each of them.
+func newParquetConsumer(
+	importCtx *parallelImportContext, schema *parquetschema.SchemaDefinition, strict bool,
+) (*parquetConsumer, error) {
+	targetCols, err := importCtx.targetColumns()
+	if err != nil {
+		return nil, err
+	}
+	colIdxByName := make(map[string]int, len(targetCols))
+	for i := range targetCols {
+		colIdxByName[targetCols[i].Name] = i
+	}
+
+	consumer := &parquetConsumer{importCtx: importCtx}
+	found := make([]bool, len(targetCols))
+	for _, def := range schema.RootColumn.Children {
+		name := def.SchemaElement.Name
+		idx, ok := colIdxByName[lexbase.NormalizeName(name)]
+		if !ok {
+			if strict {
+				return nil, errors.Errorf("could not find column for parquet column %s", name)
+			}
+			continue
+		}
+		found[idx] = true
+		decode, err := makeParquetDecoder(def, targetCols[idx].Type, strict)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parquet column %s", name)
+		}
+		consumer.columns = append(consumer.columns, parquetColumnDecoder{
+			name:   name,
+			idx:    idx,
+			decode: decode,
+		})
+	}
+
+	if strict {
+		for i := range targetCols {
+			if !found[i] {
+				return nil, errors.Errorf("column %s was not set in the parquet import", targetCols[i].Name)
+			}
+		}
+	}
+	return consumer, nil
+}
+
+// makeParquetDecoder returns the decoder used to import values of the given
+// Parquet column into a column of type targetT.
+func makeParquetDecoder(
+	def *parquetschema.ColumnDefinition, targetT *types.T, strict bool,
+) (parquetDecoder, error) {
+	if list, ok := parquetListOf(def); ok {
+		switch targetT.Family() {
+		case types.ArrayFamily:
+			elementDecoder, err := makeParquetDecoder(list.element, targetT.ArrayContents(), strict)
+			if err != nil {
+				return nil, err
+			}
+			return func(v interface{}, evalCtx *tree.EvalContext) (tree.Datum, error) {
+				elements, err := list.values(v)
+				if err != nil {
+					return nil, err
+				}
+				arr := tree.NewDArray(targetT.ArrayContents())
+				for _, element := range elements {
+					d := tree.Datum(tree.DNull)
+					if element != nil {
+						if d, err = elementDecoder(element, evalCtx); err != nil {
+							return nil, err
+						}
+					}
+					if err := arr.Append(d); err != nil {
+						return nil, err
+					}
+				}
+				return arr, nil
+			}, nil
+		case types.JsonFamily:
+			return makeParquetJSONDecoder(def), nil
+		}
+		if strict {
+			return nil, parquetStrictTypeError(def, targetT)
+		}
+		return makeParquetJSONTextDecoder(def, targetT), nil
+	}
+
+	if len(def.Children) > 0 {
+		// Structs and maps.
+		if targetT.Family() == types.JsonFamily {
+			return makeParquetJSONDecoder(def), nil
+		}
+		if strict {
+			return nil, parquetStrictTypeError(def, targetT)
+		}
+		return makeParquetJSONTextDecoder(def, targetT), nil
+	}
+
+	decode, naturalT, err := makeParquetLeafDecoder(def.SchemaElement)
+	if err != nil {
+		return nil, err
+	}
+	if strict && !parquetTypesCompatible(naturalT, targetT) {
+		return nil, parquetStrictTypeError(def, targetT)
+	}
+	return func(v interface{}, evalCtx *tree.EvalContext) (tree.Datum, error) {
+		d, err := decode(v)
+		if err != nil {
+			return nil, err
+		}
+		return coerceParquetDatum(d, targetT, evalCtx)
+	}, nil
+}
+
+// parquetNativeFamilies are the type families which have a dedicated
+// representation in Parquet. Other types are usually written as strings.
+var parquetNativeFamilies = map[types.Family]bool{
+	types.BoolFamily:        true,
+	types.IntFamily:         true,
+	types.FloatFamily:       true,
+	types.BytesFamily:       true,
+	types.DateFamily:        true,
+	types.TimeFamily:        true,
+	types.TimestampFamily:   true,
+	types.TimestampTZFamily: true,
+	types.UuidFamily:        true,
+	types.ArrayFamily:       true,
+}
+
+// parquetTypesCompatible returns whether values whose natural type is
+// naturalT can be imported into a column of type targetT in strict mode.
+func parquetTypesCompatible(naturalT, targetT *types.T) bool {
+	if targetT.Equivalent(naturalT) {
+		return true
+	}
+	switch naturalT.Family() {
+	case types.TimestampFamily, types.TimestampTZFamily:
+		// Many writers do not record whether their timestamps are adjusted to
+		// UTC, so both timestamp types are accepted.
+		return targetT.Family() == types.TimestampFamily ||
+			targetT.Family() == types.TimestampTZFamily
+	case types.StringFamily:
+		// Types without a Parquet representation (enums, decimals without a
+		// precision, intervals, etc.) are written as strings.
+		return !parquetNativeFamilies[targetT.Family()]
+	}
+	return false
+}
+
+func parquetStrictTypeError(def *parquetschema.ColumnDefinition, targetT *types.T) error {
+	return errors.Errorf("cannot import parquet type %s into column of type %s in strict mode",
+		parquetTypeName(def.SchemaElement), targetT.SQLString())
+}
+
+// coerceParquetDatum converts a datum of the natural type of a Parquet column
+// into a datum of type targetT.
+func coerceParquetDatum(
+	d tree.Datum, targetT *types.T, evalCtx *tree.EvalContext,
+) (tree.Datum, error) {
+	if d == tree.DNull || targetT.Equivalent(d.ResolvedType()) {
+		return d, nil
+	}
+	switch t := d.(type) {
+	case *tree.DString:
+		return rowenc.ParseDatumStringAs(targetT, string(*t), evalCtx)
+	case *tree.DBytes:
+		// Some writers do not annotate string columns.
+		return rowenc.ParseDatumStringAs(targetT, string(*t), evalCtx)
+	}
+	if targetT.Family() == types.JsonFamily {
+		j, err := tree.AsJSON(d, evalCtx.GetLocation())
+		if err != nil {
+			return nil, err
+		}
+		return tree.NewDJSON(j), nil
+	}
+	return rowenc.ParseDatumStringAs(targetT, tree.AsStringWithFlags(d, tree.FmtBareStrings), evalCtx)
+}
+
+// makeParquetJSONDecoder returns a decoder converting values of a nested
+// Parquet column into JSONB.
+func makeParquetJSONDecoder(def *parquetschema.ColumnDefinition) parquetDecoder {
+	return func(v interface{}, evalCtx *tree.EvalContext) (tree.Datum, error) {
+		j, err := parquetToJSON(def, v, evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		return tree.NewDJSON(j), nil
+	}
+}
+
+// makeParquetJSONTextDecoder returns a decoder converting values of a nested
+// Parquet column into targetT by way of their JSON representation.
+func makeParquetJSONTextDecoder(
+	def *parquetschema.ColumnDefinition, targetT *types.T,
+) parquetDecoder {
+	return func(v interface{}, evalCtx *tree.EvalContext) (tree.Datum, error) {
+		j, err := parquetToJSON(def, v, evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		return rowenc.ParseDatumStringAs(targetT, j.String(), evalCtx)
+	}
+}
+
+// parquetToJSON converts a value of the given Parquet column into JSON. Lists
+// become JSON arrays, maps and structs become JSON objects.
+func parquetToJSON(
+	def *parquetschema.ColumnDefinition, v interface{}, evalCtx *tree.EvalContext,
+) (json.JSON, error) {
+	if v == nil {
+		return json.NullJSONValue, nil
+	}
+
+	if list, ok := parquetListOf(def); ok {
+		elements, err := list.values(v)
+		if err != nil {
+			return nil, err
+		}
+		b := json.NewArrayBuilder(len(elements))
+		for _, element := range elements {
+			j, err := parquetToJSON(list.element, element, evalCtx)
+			if err != nil {
+				return nil, err
+			}
+			b.Add(j)
+		}
+		return b.Build(), nil
+	}
+
+	if len(def.Children) == 0 {
+		decode, _, err := makeParquetLeafDecoder(def.SchemaElement)
+		if err != nil {
+			return nil, err
+		}
+		d, err := decode(v)
+		if err != nil {
+			return nil, err
+		}
+		if j, ok := d.(*tree.DJSON); ok {
+			return j.JSON, nil
+		}
+		return tree.AsJSON(d, evalCtx.GetLocation())
+	}
+
+	group, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected value %T for parquet group %s", v, def.SchemaElement.Name)
+	}
+
+	if keyValue, ok := parquetMapOf(def); ok {
+		entries, ok := group[keyValue.SchemaElement.Name].([]map[string]interface{})
+		if !ok && group[keyValue.SchemaElement.Name] != nil {
+			return nil, errors.Errorf("unexpected value %T for parquet map %s",
+				group[keyValue.SchemaElement.Name], def.SchemaElement.Name)
+		}
+		keyDef, valueDef := keyValue.Children[0], keyValue.Children[1]
+		b := json.NewObjectBuilder(len(entries))
+		for _, entry := range entries {
+			k, err := parquetToJSON(keyDef, entry[keyDef.SchemaElement.Name], evalCtx)
+			if err != nil {
+				return nil, err
+			}
+			key, err := k.AsText()
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				return nil, errors.Errorf("parquet map %s has a NULL key", def.SchemaElement.Name)
+			}
+			val, err := parquetToJSON(valueDef, entry[valueDef.SchemaElement.Name], evalCtx)
+			if err != nil {
+				return nil, err
+			}
+			b.Add(*key, val)
+		}
+		return b.Build(), nil
+	}
+
+	b := json.NewObjectBuilder(len(def.Children))
+	for _, child := range def.Children {
+		j, err := parquetToJSON(child, group[child.SchemaElement.Name], evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		b.Add(child.SchemaElement.Name, j)
+	}
+	return b.Build(), nil
+}
+
+// parquetList describes a Parquet column holding a list of values.
+type parquetList struct {
+	// repeated is the repeated child of a LIST group, or nil if the column is
+	// itself a repeated field.
+	repeated *parquetschema.ColumnDefinition
+	// element is the definition of the elements of the list.
+	element *parquetschema.ColumnDefinition
+	// threeLevel is set if the elements of the list are wrapped in the
+	// repeated group rather than being the repeated field itself.
+	threeLevel bool
+}
+
+// parquetListOf returns the list described by def, if any. Lists are either
+// groups annotated as LIST, using the standard three-level structure
+//
+//   optional group <name> (LIST) {
+//     repeated group list {
+//       optional <element-type> element;
+//     }
+//   }
+//
+// or one of the legacy two-level structures in which the repeated field is the
+// element itself, or repeated fields without a LIST annotation.
+func parquetListOf(def *parquetschema.ColumnDefinition) (parquetList, bool) {
+	elem := def.SchemaElement
+	if elem.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+		return parquetList{element: parquetRepeatedElement(def)}, true
+	}
+
+	isList := (elem.LogicalType != nil && elem.LogicalType.IsSetLIST()) ||
+		(elem.IsSetConvertedType() && elem.GetConvertedType() == parquet.ConvertedType_LIST)
+	if !isList || len(def.Children) != 1 {
+		return parquetList{}, false
+	}
+	repeated := def.Children[0]
+	// The backward compatibility rules of the Parquet format specification
+	// determine whether a repeated group is the element itself.
+	if len(repeated.Children) == 1 &&
+		repeated.SchemaElement.Name != "array" &&
+		repeated.SchemaElement.Name != elem.Name+"_tuple" {
+		return parquetList{repeated: repeated, element: repeated.Children[0], threeLevel: true}, true
+	}
+	return parquetList{repeated: repeated, element: parquetRepeatedElement(repeated)}, true
+}
+
+// parquetRepeatedElement returns the definition of a single value of a
+// repeated field.
+func parquetRepeatedElement(def *parquetschema.ColumnDefinition) *parquetschema.ColumnDefinition {
+	elem := *def.SchemaElement
+	elem.RepetitionType = parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL)
+	return &parquetschema.ColumnDefinition{Children: def.Children, SchemaElement: &elem}
+}
+
+// values returns the elements of a list value. NULL elements are nil.
+func (l parquetList) values(v interface{}) ([]interface{}, error) {
+	if l.repeated == nil {
+		return parquetSliceValues(v)
+	}
+	group, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected value %T for parquet list", v)
+	}
+	repeated, ok := group[l.repeated.SchemaElement.Name]
+	if !ok || repeated == nil {
+		// The list is present but empty.
+		return nil, nil
+	}
+	if !l.threeLevel {
+		return parquetSliceValues(repeated)
+	}
+	entries, ok := repeated.([]map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected value %T for parquet list", repeated)
+	}
+	res := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		res[i] = entry[l.element.SchemaElement.Name]
+	}
+	return res, nil
+}
+
+// parquetSliceValues returns the elements of the typed slice returned by the
+// parquet library for repeated fields.
+func parquetSliceValues(v interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, errors.Errorf("unexpected value %T for repeated parquet field", v)
+	}
+	res := make([]interface{}, rv.Len())
+	for i := range res {
+		res[i] = rv.Index(i).Interface()
+	}
+	return res, nil
+}
+
+// parquetMapOf returns the repeated key_value group of def if def is a map.
+func parquetMapOf(def *parquetschema.ColumnDefinition) (*parquetschema.ColumnDefinition, bool) {
+	elem := def.SchemaElement
+	isMap := (elem.LogicalType != nil && elem.LogicalType.IsSetMAP()) ||
+		(elem.IsSetConvertedType() && (elem.GetConvertedType() == parquet.ConvertedType_MAP ||
+			elem.GetConvertedType() == parquet.ConvertedType_MAP_KEY_VALUE))
+	if !isMap || len(def.Children) != 1 {
+		return nil, false
+	}
+	keyValue := def.Children[0]
+	if keyValue.SchemaElement.GetRepetitionType() != parquet.FieldRepetitionType_REPEATED ||
+		len(keyValue.Children) != 2 {
+		return nil, false
+	}
+	return keyValue, true
+}
+
+// makeParquetLeafDecoder returns a function converting values of a primitive
+// Parquet column into datums of their natural SQL type, which is also
+// returned.
+func makeParquetLeafDecoder(
+	elem *parquet.SchemaElement,
+) (func(interface{}) (tree.Datum, error), *types.T, error) {
+	if elem.Type == nil {
+		return nil, nil, errors.Errorf("parquet column %s has no type", elem.Name)
+	}
+	logical := elem.LogicalType
+	if logical == nil {
+		logical = &parquet.LogicalType{}
+	}
+	converted := elem.GetConvertedType()
+	hasConverted := elem.IsSetConvertedType()
+
+	switch *elem.Type {
+	case parquet.Type_BOOLEAN:
+		return func(v interface{}) (tree.Datum, error) {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, errors.Errorf("unexpected value %T for parquet BOOLEAN", v)
+			}
+			return tree.MakeDBool(tree.DBool(b)), nil
+		}, types.Bool, nil
+
+	case parquet.Type_INT32, parquet.Type_INT64:
+		switch {
+		case logical.IsSetDATE() || (hasConverted && converted == parquet.ConvertedType_DATE):
+			return func(v interface{}) (tree.Datum, error) {
+				days, err := parquetInt(v)
+				if err != nil {
+					return nil, err
+				}
+				d, err := pgdate.MakeDateFromUnixEpoch(days)
+				if err != nil {
+					return nil, err
+				}
+				return tree.NewDDate(d), nil
+			}, types.Date, nil
+
+		case logical.IsSetTIME() ||
+			(hasConverted && (converted == parquet.ConvertedType_TIME_MILLIS ||
+				converted == parquet.ConvertedType_TIME_MICROS)):
+			var unit *parquet.TimeUnit
+			if logical.IsSetTIME() {
+				unit = logical.TIME.Unit
+			}
+			perSecond := parquetUnitsPerSecond(unit, converted)
+			return func(v interface{}) (tree.Datum, error) {
+				t, err := parquetInt(v)
+				if err != nil {
+					return nil, err
+				}
+				// Times are stored in microseconds since midnight.
+				var micros int64
+				if perSecond > 1e6 {
+					micros = t / (perSecond / 1e6)
+				} else {
+					micros = t * (1e6 / perSecond)
+				}
+				return tree.MakeDTime(timeofday.FromInt(micros)), nil
+			}, types.Time, nil
+
+		case logical.IsSetTIMESTAMP() ||
+			(hasConverted && (converted == parquet.ConvertedType_TIMESTAMP_MILLIS ||
+				converted == parquet.ConvertedType_TIMESTAMP_MICROS)):
+			var unit *parquet.TimeUnit
+			// Converted timestamp types are always adjusted to UTC.
+			adjusted := true
+			if logical.IsSetTIMESTAMP() {
+				unit = logical.TIMESTAMP.Unit
+				adjusted = logical.TIMESTAMP.IsAdjustedToUTC
+			}
+			perSecond := parquetUnitsPerSecond(unit, converted)
+			decode := func(v interface{}) (tree.Datum, error) {
+				ts, err := parquetInt(v)
+				if err != nil {
+					return nil, err
+				}
+				t := timeutil.Unix(ts/perSecond, (ts%perSecond)*(1e9/perSecond))
+				if adjusted {
+					return tree.MakeDTimestampTZ(t, time.Microsecond)
+				}
+				return tree.MakeDTimestamp(t, time.Microsecond)
+			}
+			if adjusted {
+				return decode, types.TimestampTZ, nil
+			}
+			return decode, types.Timestamp, nil
+
+		case logical.IsSetDECIMAL() || (hasConverted && converted == parquet.ConvertedType_DECIMAL):
+			scale := parquetDecimalScale(elem)
+			return func(v interface{}) (tree.Datum, error) {
+				unscaled, err := parquetInt(v)
+				if err != nil {
+					return nil, err
+				}
+				return &tree.DDecimal{Decimal: *apd.New(unscaled, -scale)}, nil
+			}, types.Decimal, nil
+
+		case (logical.IsSetINTEGER() && !logical.GetINTEGER().IsSigned &&
+			logical.GetINTEGER().BitWidth == 64) ||
+			(hasConverted && converted == parquet.ConvertedType_UINT_64):
+			return func(v interface{}) (tree.Datum, error) {
+				i, err := parquetInt(v)
+				if err != nil {
+					return nil, err
+				}
+				if i < 0 {
+					return nil, errors.Errorf("unsigned value %d out of range for INT8", uint64(i))
+				}
+				return tree.NewDInt(tree.DInt(i)), nil
+			}, types.Int, nil
+
+		case (logical.IsSetINTEGER() && !logical.GetINTEGER().IsSigned) ||
+			(hasConverted && (converted == parquet.ConvertedType_UINT_8 ||
+				converted == parquet.ConvertedType_UINT_16 ||
+				converted == parquet.ConvertedType_UINT_32)):
+			// Unsigned 32 bit values are stored as signed integers.
+			return func(v interface{}) (tree.Datum, error) {
+				i, err := parquetInt(v)
+				if err != nil {
+					return nil, err
+				}
+				return tree.NewDInt(tree.DInt(uint32(i))), nil
+			}, types.Int, nil
+		}
+		typ := types.Int
+		if *elem.Type == parquet.Type_INT32 {
+			typ = types.Int4
+		}
+		return func(v interface{}) (tree.Datum, error) {
+			i, err := parquetInt(v)
+			if err != nil {
+				return nil, err
+			}
+			return tree.NewDInt(tree.DInt(i)), nil
+		}, typ, nil
+
+	case parquet.Type_INT96:
+		// INT96 is the legacy representation of timestamps used by Impala and
+		// Spark, which always store them in UTC.
+		return func(v interface{}) (tree.Datum, error) {
+			b, ok := v.([12]byte)
+			if !ok {
+				return nil, errors.Errorf("unexpected value %T for parquet INT96", v)
+			}
+			return tree.MakeDTimestampTZ(goparquet.Int96ToTime(b), time.Microsecond)
+		}, types.TimestampTZ, nil
+
+	case parquet.Type_FLOAT:
+		return func(v interface{}) (tree.Datum, error) {
+			f, ok := v.(float32)
+			if !ok {
+				return nil, errors.Errorf("unexpected value %T for parquet FLOAT", v)
+			}
+			return tree.NewDFloat(tree.DFloat(f)), nil
+		}, types.Float4, nil
+
+	case parquet.Type_DOUBLE:
+		return func(v interface{}) (tree.Datum, error) {
+			f, ok := v.(float64)
+			if !ok {
+				return nil, errors.Errorf("unexpected value %T for parquet DOUBLE", v)
+			}
+			return tree.NewDFloat(tree.DFloat(f)), nil
+		}, types.Float, nil
+
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		switch {
+		case logical.IsSetDECIMAL() || (hasConverted && converted == parquet.ConvertedType_DECIMAL):
+			scale := parquetDecimalScale(elem)
+			return func(v interface{}) (tree.Datum, error) {
+				b, err := parquetBytes(v)
+				if err != nil {
+					return nil, err
+				}
+				return &tree.DDecimal{Decimal: *apd.NewWithBigInt(parquetUnscaledDecimal(b), -scale)}, nil
+			}, types.Decimal, nil
+
+		case logical.IsSetUUID():
+			return func(v interface{}) (tree.Datum, error) {
+				b, err := parquetBytes(v)
+				if err != nil {
+					return nil, err
+				}
+				u, err := uuid.FromBytes(b)
+				if err != nil {
+					return nil, err
+				}
+				return tree.NewDUuid(tree.DUuid{UUID: u}), nil
+			}, types.Uuid, nil
+
+		case logical.IsSetJSON() || (hasConverted && converted == parquet.ConvertedType_JSON):
+			return func(v interface{}) (tree.Datum, error) {
+				b, err := parquetBytes(v)
+				if err != nil {
+					return nil, err
+				}
+				return tree.ParseDJSON(string(b))
+			}, types.Jsonb, nil
+
+		case logical.IsSetSTRING() || logical.IsSetENUM() ||
+			(hasConverted && (converted == parquet.ConvertedType_UTF8 ||
+				converted == parquet.ConvertedType_ENUM)):
+			return func(v interface{}) (tree.Datum, error) {
+				b, err := parquetBytes(v)
+				if err != nil {
+					return nil, err
+				}
+				return tree.NewDString(string(b)), nil
+			}, types.String, nil
+		}
+		return func(v interface{}) (tree.Datum, error) {
+			b, err := parquetBytes(v)
+			if err != nil {
+				return nil, err
+			}
+			return tree.NewDBytes(tree.DBytes(b)), nil
+		}, types.Bytes, nil
+	}
+	return nil, nil, errors.Errorf("unsupported parquet type %s", parquetTypeName(elem))
+}
+
+func parquetInt(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case int32:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	}
+	return 0, errors.Errorf("unexpected value %T for parquet integer", v)
+}
+
+func parquetBytes(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, errors.Errorf("unexpected value %T for parquet byte array", v)
+	}
+	return b, nil
+}
+
+// parquetUnitsPerSecond returns the number of units per second of a TIME or
+// TIMESTAMP value, given either its logical (if not nil) or converted time
+// unit.
+func parquetUnitsPerSecond(unit *parquet.TimeUnit, converted parquet.ConvertedType) int64 {
+	if unit != nil {
+		switch {
+		case unit.IsSetMILLIS():
+			return 1e3
+		case unit.IsSetNANOS():
+			return 1e9
+		}
+		return 1e6
+	}
+	switch converted {
+	case parquet.ConvertedType_TIME_MILLIS, parquet.ConvertedType_TIMESTAMP_MILLIS:
+		return 1e3
+	}
+	return 1e6
+}
+
+func parquetDecimalScale(elem *parquet.SchemaElement) int32 {
+	if elem.LogicalType != nil && elem.LogicalType.IsSetDECIMAL() {
+		return elem.LogicalType.DECIMAL.Scale
+	}
+	return elem.GetScale()
+}
+
+// parquetUnscaledDecimal decodes the big-endian two's complement unscaled value
+// of a Parquet DECIMAL.
+func parquetUnscaledDecimal(b []byte) *big.Int {
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return unscaled
+}
+
+// parquetTypeName returns a description of the type of a Parquet column for
+// use in error messages, e.g. "INT64 (TIMESTAMP)".
+func parquetTypeName(elem *parquet.SchemaElement) string {
+	name := "group"
+	if elem.Type != nil {
+		name = elem.Type.String()
+	}
+	var annotation string
+	if lt := elem.LogicalType; lt != nil {
+		switch {
+		case lt.IsSetSTRING():
+			annotation = "STRING"
+		case lt.IsSetMAP():
+			annotation = "MAP"
+		case lt.IsSetLIST():
+			annotation = "LIST"
+		case lt.IsSetENUM():
+			annotation = "ENUM"
+		case lt.IsSetDECIMAL():
+			annotation = "DECIMAL"
+		case lt.IsSetDATE():
+			annotation = "DATE"
+		case lt.IsSetTIME():
+			annotation = "TIME"
+		case lt.IsSetTIMESTAMP():
+			annotation = "TIMESTAMP"
+		case lt.IsSetINTEGER():
+			annotation = "INTEGER"
+		case lt.IsSetJSON():
+			annotation = "JSON"
+		case lt.IsSetBSON():
+			annotation = "BSON"
+		case lt.IsSetUUID():
+			annotation = "UUID"
+		}
+	}
+	if annotation == "" && elem.IsSetConvertedType() {
+		annotation = elem.GetConvertedType().String()
+	}
+	if elem.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+		name = "repeated " + name
+	}
+	if annotation != "" {
+		return fmt.Sprintf("%s (%s)", name, strings.ToUpper(annotation))
+	}
+	return name
+}
+
+type parquetInputReader struct {
+	importContext *parallelImportContext
+	opts          roachpb.ParquetOptions
+}
+
+var _ inputConverter = &parquetInputReader{}
+
+func newParquetInputReader(
+	kvCh chan row.KVBatch,
+	tableDesc *tabledesc.Immutable,
+	targetCols tree.NameList,
+	parquetOpts roachpb.ParquetOptions,
+	walltime int64,
+	parallelism int,
+	evalCtx *tree.EvalContext,
+) (*parquetInputReader, error) {
+	return &parquetInputReader{
+		importContext: &parallelImportContext{
+			walltime:   walltime,
+			numWorkers: parallelism,
+			evalCtx:    evalCtx,
+			tableDesc:  tableDesc,
+			targetCols: targetCols,
+			kvCh:       kvCh,
+		},
+		opts: parquetOpts,
+	}, nil
+}
+
+func (p *parquetInputReader) start(group ctxgroup.Group) {}
+
+func (p *parquetInputReader) readFiles(
+	ctx context.Context,
+	dataFiles map[int32]string,
+	resumePos map[int32]int64,
+	format roachpb.IOFileFormat,
+	makeExternalStorage cloud.ExternalStorageFactory,
+	user security.SQLUsername,
+) error {
+	return readInputFiles(ctx, dataFiles, resumePos, format, p.readFile, makeExternalStorage, user)
+}
+
+func (p *parquetInputReader) readFile(
+	ctx context.Context, input *fileReader, inputIdx int32, resumePos int64, rejected chan string,
+) error {
+	// The footer of a Parquet file is needed before any of its row groups can
+	// be decoded, so the file is read with random access directly from its
+	// storage rather than through the streaming reader.
+	if input.storage == nil {
+		return errors.AssertionFailedf("parquet file is missing its storage")
+	}
+	f, err := newExternalStorageReadSeeker(ctx, input.storage)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	producer, consumer, err := newImportParquetPipeline(p, f)
+	if err != nil {
+		return err
+	}
+
+	fileCtx := &importFileContext{
+		source:   inputIdx,
+		skip:     resumePos,
+		rowLimit: p.opts.RowLimit,
+	}
+	if rejected == nil {
+		return runParallelImport(ctx, p.importContext, fileCtx, producer, consumer)
+	}
+
+	// The rejected rows are saved in a Parquet file with the schema of the input,
+	// so that it can be imported again once they have been fixed.
+	records := make(chan interface{})
+	fileCtx.rejectedRecords = records
+	schema := producer.(*parquetRowStream).reader.GetSchemaDefinition()
+	group := ctxgroup.WithContext(ctx)
+	group.GoCtx(func(ctx context.Context) error {
+		return writeRejectedParquetRows(schema, records, rejected)
+	})
+	group.GoCtx(func(ctx context.Context) error {
+		defer close(records)
+		return runParallelImport(ctx, p.importContext, fileCtx, producer, consumer)
+	})
+	return group.Wait()
+}
+
+// writeRejectedParquetRows writes the records received from records to a
+// Parquet file with the given schema, and sends the contents of the file to
+// rejected once records is closed, if there were any.
+func writeRejectedParquetRows(
+	schema *parquetschema.SchemaDefinition, records <-chan interface{}, rejected chan<- string,
+) error {
+	var buf bytes.Buffer
+	fw := goparquet.NewFileWriter(&buf,
+		goparquet.WithSchemaDefinition(schema),
+		goparquet.WithCreator(`CockroachDB`),
+	)
+	var count int64
+	var err error
+	// Keep receiving the records after an error, so that the import workers
+	// sending them are not blocked.
+	for record := range records {
+		count++
+		if err != nil {
+			continue
+		}
+		if count > maxRejectedRows {
+			err = pgerror.Newf(pgcode.DataCorrupted, "too many parsing errors (%d) encountered", count)
+			continue
+		}
+		err = fw.AddData(record.(map[string]interface{}))
+	}
+	if err != nil || count == 0 {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+	rejected <- buf.String()
+	return nil
+}
+
+// externalStorageReadSeeker is an io.ReadSeeker over a file in external
+// storage. Seeking does not perform any I/O: the file is opened at the current
+// offset by the first Read after a seek. This lets the parquet library read the
+// footer and then one row group at a time without downloading the whole file.
+type externalStorageReadSeeker struct {
+	ctx  context.Context
+	es   cloud.ExternalStorage
+	size int64
+	pos  int64
+	// r is the stream positioned at pos, if one is open.
+	r io.ReadCloser
+}
+
+var _ io.ReadSeeker = &externalStorageReadSeeker{}
+
+func newExternalStorageReadSeeker(
+	ctx context.Context, es cloud.ExternalStorage,
+) (*externalStorageReadSeeker, error) {
+	size, err := es.Size(ctx, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching file size")
+	}
+	return &externalStorageReadSeeker{ctx: ctx, es: es, size: size}, nil
+}
+
+// Read implements the io.Reader interface.
+func (f *externalStorageReadSeeker) Read(p []byte) (int, error) {
+	if f.pos >= f.size {
+		return 0, io.EOF
+	}
+	if f.r == nil {
+		r, err := f.es.ReadFileAt(f.ctx, "", f.pos)
+		if err != nil {
+			return 0, err
+		}
+		f.r = r
+	}
+	n, err := f.r.Read(p)
+	f.pos += int64(n)
+	return n, err
+}
+
+// Seek implements the io.Seeker interface.
+func (f *externalStorageReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.pos + offset
+	case io.SeekEnd:
+		pos = f.size + offset
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return 0, errors.Errorf("negative position %d", pos)
+	}
+	if pos != f.pos {
+		if err := f.Close(); err != nil {
+			return 0, err
+		}
+		f.pos = pos
+	}
+	return pos, nil
+}
+
+// Close closes the currently open stream, if any.
+func (f *externalStorageReadSeeker) Close() error {
+	if f.r == nil {
+		return nil
+	}
+	err := f.r.Close()
+	f.r = nil
+	return err
+}
@@ -0,0 +1,337 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/row"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+// makeParquetTestData writes the given records into a Parquet file with the
+// given schema, flushing a row group every rowGroupSize records.
+func makeParquetTestData(
+	t *testing.T, schema string, rowGroupSize int, records ...map[string]interface{},
+) []byte {
+	def, err := parquetschema.ParseSchemaDefinition(schema)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	fw := goparquet.NewFileWriter(&buf, goparquet.WithSchemaDefinition(def))
+	for i, rec := range records {
+		require.NoError(t, fw.AddData(rec))
+		if rowGroupSize > 0 && (i+1)%rowGroupSize == 0 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+	return buf.Bytes()
+}
+
+type parquetTestStream struct {
+	producer importRowProducer
+	consumer importRowConsumer
+	conv     *row.DatumRowConverter
+	rowNum   int64
+}
+
+// Row combines Row() with FillDatums and returns the resulting datums.
+func (s *parquetTestStream) Row() (tree.Datums, error) {
+	r, err := s.producer.Row()
+	if err != nil {
+		return nil, err
+	}
+	s.rowNum++
+	if err := s.consumer.FillDatums(r, s.rowNum, s.conv); err != nil {
+		return nil, err
+	}
+	return append(tree.Datums(nil), s.conv.Datums[:len(s.conv.VisibleCols)]...), nil
+}
+
+func newParquetTestStream(
+	t *testing.T, create string, strict bool, data []byte,
+) (*parquetTestStream, error) {
+	ctx := context.Background()
+	st := cluster.MakeTestingClusterSettings()
+	evalCtx := tree.MakeTestingEvalContext(st)
+	table := descForTable(ctx, t, create, 10, 20, NoFKs).ImmutableCopy().(*tabledesc.Immutable)
+
+	reader, err := newParquetInputReader(
+		nil, table, nil, roachpb.ParquetOptions{StrictMode: strict}, 0, 1, &evalCtx)
+	require.NoError(t, err)
+	producer, consumer, err := newImportParquetPipeline(reader, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	conv, err := row.NewDatumRowConverter(ctx, table, nil, evalCtx.Copy(), nil)
+	require.NoError(t, err)
+	return &parquetTestStream{producer: producer, consumer: consumer, conv: conv}, nil
+}
+
+func TestParquetTypes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const schema = `message test {
+		required int64 id;
+		optional binary name (STRING);
+		optional double score;
+		optional boolean ok;
+		optional int32 day (DATE);
+		optional int64 ts (TIMESTAMP(MICROS, true));
+		optional binary amount (DECIMAL(10, 2));
+		optional group tags (LIST) {
+			repeated group list {
+				optional int64 element;
+			}
+		}
+		optional group info {
+			optional binary city (STRING);
+			optional int32 zip;
+		}
+	}`
+	const create = `CREATE TABLE t (
+		id INT8 PRIMARY KEY, name STRING, score FLOAT8, ok BOOL, day DATE,
+		ts TIMESTAMPTZ, amount DECIMAL(10, 2), tags INT8[], info JSONB)`
+
+	data := makeParquetTestData(t, schema, 0,
+		map[string]interface{}{
+			"id":     int64(1),
+			"name":   []byte("foo"),
+			"score":  1.5,
+			"ok":     true,
+			"day":    int32(18628),
+			"ts":     int64(1609459200000000),
+			"amount": []byte{0x30, 0x39},
+			"tags": map[string]interface{}{
+				"list": []map[string]interface{}{{"element": int64(1)}, {"element": int64(3)}},
+			},
+			"info": map[string]interface{}{"city": []byte("NYC"), "zip": int32(10001)},
+		},
+		map[string]interface{}{
+			"id":   int64(2),
+			"tags": map[string]interface{}{},
+		},
+	)
+
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%t", strict), func(t *testing.T) {
+			stream, err := newParquetTestStream(t, create, strict, data)
+			require.NoError(t, err)
+
+			var rows []string
+			for stream.producer.Scan() {
+				datums, err := stream.Row()
+				require.NoError(t, err)
+				rows = append(rows, datums.String())
+			}
+			require.NoError(t, stream.producer.Err())
+			require.Equal(t, []string{
+				`(1, 'foo', 1.5, true, '2021-01-01', '2021-01-01 00:00:00+00:00', 123.45, ` +
+					`ARRAY[1,3], '{"city": "NYC", "zip": 10001}')`,
+				`(2, NULL, NULL, NULL, NULL, NULL, NULL, ARRAY[], NULL)`,
+			}, rows)
+			require.Equal(t, float32(1), stream.producer.Progress())
+		})
+	}
+}
+
+func TestParquetRelaxedAndStrictImport(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const schema = `message test {
+		required int64 a;
+		optional binary b (STRING);
+	}`
+	data := makeParquetTestData(t, schema, 0,
+		map[string]interface{}{"a": int64(1), "b": []byte("12.5")})
+
+	tests := []struct {
+		name   string
+		create string
+		strict bool
+		err    string
+	}{
+		{"relaxed-tolerates-missing-columns", "CREATE TABLE t (a INT8, b STRING, c INT8)", false, ""},
+		{"relaxed-tolerates-extra-columns", "CREATE TABLE t (a INT8)", false, ""},
+		{"relaxed-converts-types", "CREATE TABLE t (a STRING, b FLOAT8)", false, ""},
+		{"strict-returns-error-missing-columns", "CREATE TABLE t (a INT8, b STRING, c INT8)", true,
+			"column c was not set in the parquet import"},
+		{"strict-returns-error-extra-columns", "CREATE TABLE t (a INT8)", true,
+			"could not find column for parquet column b"},
+		{"strict-returns-error-mismatched-types", "CREATE TABLE t (a STRING, b STRING)", true,
+			"cannot import parquet type INT64 into column of type STRING in strict mode"},
+		{"strict-allows-strings-for-non-native-types", "CREATE TABLE t (a INT8, b DECIMAL)", true, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream, err := newParquetTestStream(t, test.create, test.strict, data)
+			if test.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, stream.producer.Scan())
+			_, err = stream.Row()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParquetRowGroups(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const schema = `message test {
+		required int64 a;
+	}`
+	const numRows = 25
+	var records []map[string]interface{}
+	for i := 0; i < numRows; i++ {
+		records = append(records, map[string]interface{}{"a": int64(i)})
+	}
+	data := makeParquetTestData(t, schema, 10, records...)
+
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip=%t", skip), func(t *testing.T) {
+			stream, err := newParquetTestStream(t, "CREATE TABLE t (a INT8)", false, data)
+			require.NoError(t, err)
+
+			var rowIdx int64
+			var lastProgress float32
+			for stream.producer.Scan() {
+				if skip {
+					require.NoError(t, stream.producer.Skip())
+				} else {
+					datums, err := stream.Row()
+					require.NoError(t, err)
+					require.Equal(t, fmt.Sprintf("(%d)", rowIdx), datums.String())
+				}
+				rowIdx++
+				progress := stream.producer.Progress()
+				require.True(t, progress > lastProgress)
+				lastProgress = progress
+			}
+			require.NoError(t, stream.producer.Err())
+			require.EqualValues(t, numRows, rowIdx)
+			require.Equal(t, float32(1), lastProgress)
+		})
+	}
+}
+
+func TestParquetRejectedRows(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const schema = `message test {
+		required int64 a;
+		optional binary b (STRING);
+	}`
+	def, err := parquetschema.ParseSchemaDefinition(schema)
+	require.NoError(t, err)
+
+	records := make(chan interface{}, 2)
+	rejected := make(chan string, 1)
+	records <- map[string]interface{}{"a": int64(1), "b": []byte("x")}
+	records <- map[string]interface{}{"a": int64(2), "b": []byte("y")}
+	close(records)
+	require.NoError(t, writeRejectedParquetRows(def, records, rejected))
+
+	// The rejected rows can be imported again from the file.
+	stream, err := newParquetTestStream(
+		t, "CREATE TABLE t (a INT8, b STRING)", true, []byte(<-rejected))
+	require.NoError(t, err)
+	var rows []string
+	for stream.producer.Scan() {
+		datums, err := stream.Row()
+		require.NoError(t, err)
+		rows = append(rows, datums.String())
+	}
+	require.NoError(t, stream.producer.Err())
+	require.Equal(t, []string{"(1, 'x')", "(2, 'y')"}, rows)
+
+	// Nothing is saved if no row was rejected.
+	records = make(chan interface{})
+	close(records)
+	require.NoError(t, writeRejectedParquetRows(def, records, rejected))
+	require.Len(t, rejected, 0)
+}
+
+// rangeReadStorage is an ExternalStorage serving a single file from memory
+// which records the offset of every read.
+type rangeReadStorage struct {
+	cloud.ExternalStorage
+	data    []byte
+	offsets []int64
+}
+
+func (s *rangeReadStorage) ReadFileAt(
+	_ context.Context, _ string, offset int64,
+) (io.ReadCloser, error) {
+	s.offsets = append(s.offsets, offset)
+	return ioutil.NopCloser(bytes.NewReader(s.data[offset:])), nil
+}
+
+func (s *rangeReadStorage) Size(_ context.Context, _ string) (int64, error) {
+	return int64(len(s.data)), nil
+}
+
+func TestParquetExternalStorageReadSeeker(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	const schema = `message test {
+		required int64 a;
+	}`
+	const numRows = 25
+	var records []map[string]interface{}
+	for i := 0; i < numRows; i++ {
+		records = append(records, map[string]interface{}{"a": int64(i)})
+	}
+	es := &rangeReadStorage{data: makeParquetTestData(t, schema, 10, records...)}
+
+	f, err := newExternalStorageReadSeeker(ctx, es)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := goparquet.NewFileReader(f)
+	require.NoError(t, err)
+	for i := 0; i < numRows; i++ {
+		row, err := reader.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, int64(i), row["a"])
+	}
+	_, err = reader.NextRow()
+	require.True(t, errors.Is(err, io.EOF))
+
+	// The footer and every row group are read from their own offset rather
+	// than by reading the file from the start.
+	require.Greater(t, len(es.offsets), reader.RowGroupCount())
+	for _, offset := range es.offsets[1:] {
+		require.NotZero(t, offset)
+	}
+}
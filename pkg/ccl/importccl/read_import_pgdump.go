@@ -240,6 +240,10 @@ func readPostgresCreateTable(
 	createTbl := make(map[string]*tree.CreateTable)
 	createSeq := make(map[string]*tree.CreateSequence)
 	tableFKs := make(map[string][]*tree.ForeignKeyConstraintTableDef)
+	input, err := maybeReadPgDumpArchive(input)
+	if err != nil {
+		return nil, err
+	}
 	ps := newPostgreStream(input, max)
 	for {
 		stmt, err := ps.Next()
@@ -644,7 +648,20 @@ func (m *pgDumpReader) readFiles(
 	makeExternalStorage cloud.ExternalStorageFactory,
 	user security.SQLUsername,
 ) error {
-	return readInputFiles(ctx, dataFiles, resumePos, format, m.readFile, makeExternalStorage, user)
+	readFile := m.readFile
+	if len(m.opts.ArchiveCopyStatements) > 0 {
+		// The data files of directory-format archives only hold the COPY data of
+		// their tables, so they are read as if preceded by their COPY statements.
+		readFile = func(
+			ctx context.Context, input *fileReader, inputIdx int32, resumePos int64, rejected chan string,
+		) error {
+			if copyStmt, ok := m.opts.ArchiveCopyStatements[dataFiles[inputIdx]]; ok {
+				input.Reader = newPgDumpCopyDataReader(copyStmt, input.Reader)
+			}
+			return m.readFile(ctx, input, inputIdx, resumePos, rejected)
+		}
+	}
+	return readInputFiles(ctx, dataFiles, resumePos, format, readFile, makeExternalStorage, user)
 }
 
 func (m *pgDumpReader) readFile(
@@ -653,7 +670,11 @@ func (m *pgDumpReader) readFile(
 	tableNameToRowsProcessed := make(map[string]int64)
 	var inserts, count int64
 	rowLimit := m.opts.RowLimit
-	ps := newPostgreStream(input, int(m.opts.MaxRowSize))
+	sqlInput, err := maybeReadPgDumpArchive(input)
+	if err != nil {
+		return err
+	}
+	ps := newPostgreStream(sqlInput, int(m.opts.MaxRowSize))
 	semaCtx := tree.MakeSemaContext()
 	for _, conv := range m.tables {
 		conv.KvBatch.Source = inputIdx
@@ -0,0 +1,758 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package importccl
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/errors"
+)
+
+// pg_dump writes archives in its custom (-Fc) and directory (-Fd) formats
+// rather than plain-text SQL dumps. Both start with a header and a table of
+// contents (TOC) listing every object of the dump along with the SQL which
+// creates it. A custom-format archive is a single file in which the TOC is
+// followed by the data blocks of the dumped tables, each holding the (possibly
+// compressed) COPY data of a table. A directory-format archive is a directory
+// holding the TOC in toc.dat and the COPY data of each table in a file of its
+// own, which lets the tables be loaded in parallel.
+//
+// Archives are imported by rendering them as the plain-text dump which
+// pg_restore would produce from them, and feeding that to the PGDUMP reader.
+// The encoding of archives is defined by pg_backup_archiver.c and
+// pg_backup_custom.c in the PostgreSQL sources.
+
+// pgDumpArchiveMagic starts every pg_dump archive.
+const pgDumpArchiveMagic = "PGDMP"
+
+// pgDumpArchiveTOCFile is the file of a directory-format archive holding its
+// TOC.
+const pgDumpArchiveTOCFile = "toc.dat"
+
+// pgDumpArchiveFormat is the format of a pg_dump archive.
+type pgDumpArchiveFormat byte
+
+const (
+	pgDumpArchiveCustom    pgDumpArchiveFormat = 1
+	pgDumpArchiveTar       pgDumpArchiveFormat = 3
+	pgDumpArchiveDirectory pgDumpArchiveFormat = 5
+)
+
+// pgDumpArchiveVersion returns the archive version with the given major, minor
+// and revision numbers, ordered like those versions are.
+func pgDumpArchiveVersion(major, minor, rev byte) int {
+	return int(major)<<16 | int(minor)<<8 | int(rev)
+}
+
+var (
+	// pgDumpArchiveMinVersion is the archive version of pg_dump 9.0, the first
+	// to write a separate TOC entry per large object.
+	pgDumpArchiveMinVersion = pgDumpArchiveVersion(1, 12, 0)
+	// pgDumpArchiveVersionTableAM added the table access method to TOC entries.
+	pgDumpArchiveVersionTableAM = pgDumpArchiveVersion(1, 14, 0)
+	// pgDumpArchiveVersionCompression replaced the compression level in the
+	// header with the compression algorithm.
+	pgDumpArchiveVersionCompression = pgDumpArchiveVersion(1, 15, 0)
+	// pgDumpArchiveVersionRelKind added the relation kind to TOC entries.
+	pgDumpArchiveVersionRelKind = pgDumpArchiveVersion(1, 16, 0)
+	// pgDumpArchiveMaxVersion is the latest archive version which can be read.
+	pgDumpArchiveMaxVersion = pgDumpArchiveVersion(1, 16, 255)
+)
+
+// pgDumpArchiveCompression is the algorithm with which the data of an archive
+// is compressed.
+type pgDumpArchiveCompression byte
+
+const (
+	pgDumpArchiveCompressionNone pgDumpArchiveCompression = 0
+	pgDumpArchiveCompressionGzip pgDumpArchiveCompression = 1
+	pgDumpArchiveCompressionLZ4  pgDumpArchiveCompression = 2
+	pgDumpArchiveCompressionZstd pgDumpArchiveCompression = 3
+)
+
+// pgDumpArchiveSection is the section of a dump in which an object appears.
+type pgDumpArchiveSection int
+
+const (
+	pgDumpArchiveSectionNone pgDumpArchiveSection = iota + 1
+	pgDumpArchiveSectionPreData
+	pgDumpArchiveSectionData
+	pgDumpArchiveSectionPostData
+)
+
+// The types of the data blocks of a custom-format archive.
+const (
+	pgDumpArchiveBlockData  = 1
+	pgDumpArchiveBlockBlobs = 3
+)
+
+// pgDumpArchiveEntry is an entry of the TOC of an archive.
+type pgDumpArchiveEntry struct {
+	dumpID   int
+	desc     string
+	section  pgDumpArchiveSection
+	defn     string
+	copyStmt string
+	// filename is the name of the file holding the data of the entry in a
+	// directory-format archive, if any.
+	filename string
+}
+
+// pgDumpArchive is the header and TOC of a pg_dump archive.
+type pgDumpArchive struct {
+	version     int
+	intSize     int
+	offSize     int
+	format      pgDumpArchiveFormat
+	compression pgDumpArchiveCompression
+	entries     []pgDumpArchiveEntry
+}
+
+// pgDumpArchiveDecoder decodes the integers and strings pg_dump archives are
+// made of.
+type pgDumpArchiveDecoder struct {
+	r       *bufio.Reader
+	archive *pgDumpArchive
+}
+
+func (d *pgDumpArchiveDecoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// readInt reads an integer, which is encoded as a sign byte followed by the
+// little-endian bytes of its absolute value.
+func (d *pgDumpArchiveDecoder) readInt() (int, error) {
+	sign, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	var res int
+	for i := 0; i < d.archive.intSize; i++ {
+		b, err := d.readByte()
+		if err != nil {
+			return 0, err
+		}
+		if i < 8 {
+			res |= int(b) << (8 * i)
+		}
+	}
+	if sign != 0 {
+		res = -res
+	}
+	return res, nil
+}
+
+// readStr reads a string, which is encoded as its length followed by its
+// bytes. A negative length encodes a null string, for which ok is false.
+func (d *pgDumpArchiveDecoder) readStr() (s string, ok bool, err error) {
+	l, err := d.readInt()
+	if err != nil || l < 0 {
+		return "", false, err
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", false, err
+	}
+	return string(buf), true, nil
+}
+
+// skipOffset skips the data offset stored in a TOC entry of a custom-format
+// archive, which is encoded as a flag byte followed by the bytes of the offset.
+func (d *pgDumpArchiveDecoder) skipOffset() error {
+	_, err := d.r.Discard(1 + d.archive.offSize)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readPgDumpArchive reads the header and TOC of an archive. The magic string
+// which starts the archive must already have been consumed.
+func readPgDumpArchive(r *bufio.Reader) (*pgDumpArchive, error) {
+	a := &pgDumpArchive{}
+	d := &pgDumpArchiveDecoder{r: r, archive: a}
+	if err := d.readHeader(); err != nil {
+		return nil, errors.Wrap(err, "reading pg_dump archive header")
+	}
+	if err := d.readTOC(); err != nil {
+		return nil, errors.Wrap(err, "reading pg_dump archive TOC")
+	}
+	return a, nil
+}
+
+func (d *pgDumpArchiveDecoder) readHeader() error {
+	a := d.archive
+	var version [3]byte
+	if _, err := io.ReadFull(d.r, version[:]); err != nil {
+		return err
+	}
+	a.version = pgDumpArchiveVersion(version[0], version[1], version[2])
+	if a.version < pgDumpArchiveMinVersion || a.version > pgDumpArchiveMaxVersion {
+		return errors.Errorf("unsupported archive version %d.%d.%d", version[0], version[1], version[2])
+	}
+	intSize, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	offSize, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	format, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	a.intSize, a.offSize, a.format = int(intSize), int(offSize), pgDumpArchiveFormat(format)
+	switch a.format {
+	case pgDumpArchiveCustom, pgDumpArchiveDirectory:
+	case pgDumpArchiveTar:
+		return errors.New("tar-format archives are not supported")
+	default:
+		return errors.Errorf("unsupported archive format %d", format)
+	}
+
+	if a.version >= pgDumpArchiveVersionCompression {
+		algorithm, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		a.compression = pgDumpArchiveCompression(algorithm)
+	} else {
+		level, err := d.readInt()
+		if err != nil {
+			return err
+		}
+		// Archives were only ever compressed with zlib before the algorithm was
+		// stored, at the given level.
+		if level != 0 {
+			a.compression = pgDumpArchiveCompressionGzip
+		}
+	}
+	switch a.compression {
+	case pgDumpArchiveCompressionNone, pgDumpArchiveCompressionGzip:
+	case pgDumpArchiveCompressionLZ4:
+		return errors.New("lz4-compressed archives are not supported")
+	case pgDumpArchiveCompressionZstd:
+		return errors.New("zstd-compressed archives are not supported")
+	default:
+		return errors.Errorf("unsupported archive compression %d", a.compression)
+	}
+
+	// The creation time, as the seconds, minutes, hours, day of month, month,
+	// year and DST flag of a struct tm.
+	for i := 0; i < 7; i++ {
+		if _, err := d.readInt(); err != nil {
+			return err
+		}
+	}
+	// The names of the database, the version of the server it was dumped from
+	// and the version of pg_dump.
+	for i := 0; i < 3; i++ {
+		if _, _, err := d.readStr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *pgDumpArchiveDecoder) readTOC() error {
+	a := d.archive
+	count, err := d.readInt()
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return errors.Errorf("invalid TOC entry count %d", count)
+	}
+	a.entries = make([]pgDumpArchiveEntry, count)
+	for i := range a.entries {
+		if err := d.readTOCEntry(&a.entries[i]); err != nil {
+			return errors.Wrapf(err, "TOC entry %d", i)
+		}
+	}
+	return nil
+}
+
+func (d *pgDumpArchiveDecoder) readTOCEntry(e *pgDumpArchiveEntry) error {
+	a := d.archive
+	var err error
+	if e.dumpID, err = d.readInt(); err != nil {
+		return err
+	}
+	// Whether the entry has data.
+	if _, err := d.readInt(); err != nil {
+		return err
+	}
+	// The OIDs of the catalog and of the object, and the name of the object.
+	for i := 0; i < 3; i++ {
+		if _, _, err := d.readStr(); err != nil {
+			return err
+		}
+	}
+	if e.desc, _, err = d.readStr(); err != nil {
+		return err
+	}
+	section, err := d.readInt()
+	if err != nil {
+		return err
+	}
+	e.section = pgDumpArchiveSection(section)
+	if e.defn, _, err = d.readStr(); err != nil {
+		return err
+	}
+	// The statement which drops the object.
+	if _, _, err := d.readStr(); err != nil {
+		return err
+	}
+	if e.copyStmt, _, err = d.readStr(); err != nil {
+		return err
+	}
+	// The schema, tablespace and table access method of the object.
+	strs := 2
+	if a.version >= pgDumpArchiveVersionTableAM {
+		strs++
+	}
+	for i := 0; i < strs; i++ {
+		if _, _, err := d.readStr(); err != nil {
+			return err
+		}
+	}
+	// The relation kind of the object.
+	if a.version >= pgDumpArchiveVersionRelKind {
+		if _, err := d.readInt(); err != nil {
+			return err
+		}
+	}
+	// The owner of the object, and whether it has OIDs.
+	for i := 0; i < 2; i++ {
+		if _, _, err := d.readStr(); err != nil {
+			return err
+		}
+	}
+	// The IDs of the entries the entry depends on, terminated by a null string.
+	for {
+		_, ok, err := d.readStr()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+	}
+
+	switch a.format {
+	case pgDumpArchiveCustom:
+		return d.skipOffset()
+	case pgDumpArchiveDirectory:
+		e.filename, _, err = d.readStr()
+		return err
+	default:
+		return errors.AssertionFailedf("unexpected archive format %d", a.format)
+	}
+}
+
+// maybeReadPgDumpArchive returns a reader of the plain-text dump of r, which is
+// r itself unless it is a pg_dump archive. The plain-text dump of an archive
+// in the directory format holds none of the rows of its tables, as those are
+// read from its data files.
+func maybeReadPgDumpArchive(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(pgDumpArchiveMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(magic) != pgDumpArchiveMagic {
+		return br, nil
+	}
+	if _, err := br.Discard(len(pgDumpArchiveMagic)); err != nil {
+		return nil, err
+	}
+	archive, err := readPgDumpArchive(br)
+	if err != nil {
+		return nil, err
+	}
+	return newPgDumpArchiveRenderer(archive, br), nil
+}
+
+// skipPgDumpArchiveEntry returns whether the SQL of an entry is left out of the
+// plain-text dump, like pg_restore does unless asked to create the database.
+func skipPgDumpArchiveEntry(e *pgDumpArchiveEntry) bool {
+	return e.desc == "DATABASE" || e.desc == "DATABASE PROPERTIES"
+}
+
+// pgDumpArchiveRenderer renders an archive as a plain-text dump. It renders
+// the SQL of the entries which precede the data in the dump, then the data of
+// the tables of a custom-format archive in the order of its data blocks, and
+// then the SQL of the entries which follow the data.
+type pgDumpArchiveRenderer struct {
+	archive *pgDumpArchive
+	d       *pgDumpArchiveDecoder
+	byID    map[int]*pgDumpArchiveEntry
+
+	// pending is the rendered output which was not read yet.
+	pending []byte
+	// section is the section being rendered, and next is the index of the next
+	// entry to render in it.
+	section pgDumpArchiveSection
+	next    int
+	// data is the data of the block being rendered, if any.
+	data *pgDumpCopyDataReader
+	// chunks is the reader of the chunks of the block being rendered.
+	chunks *pgDumpArchiveChunkReader
+}
+
+func newPgDumpArchiveRenderer(archive *pgDumpArchive, r *bufio.Reader) *pgDumpArchiveRenderer {
+	byID := make(map[int]*pgDumpArchiveEntry, len(archive.entries))
+	for i := range archive.entries {
+		byID[archive.entries[i].dumpID] = &archive.entries[i]
+	}
+	return &pgDumpArchiveRenderer{
+		archive: archive,
+		d:       &pgDumpArchiveDecoder{r: r, archive: archive},
+		byID:    byID,
+		section: pgDumpArchiveSectionPreData,
+	}
+}
+
+// inSection returns whether an entry is rendered in the given section. The
+// entries of no particular section, such as privileges, are rendered with those
+// preceding the data.
+func inSection(e *pgDumpArchiveEntry, section pgDumpArchiveSection) bool {
+	if e.section == pgDumpArchiveSectionNone {
+		return section == pgDumpArchiveSectionPreData
+	}
+	return e.section == section
+}
+
+// Read implements io.Reader.
+func (p *pgDumpArchiveRenderer) Read(buf []byte) (int, error) {
+	for len(p.pending) == 0 {
+		if p.data != nil {
+			n, err := p.data.Read(buf)
+			if err == io.EOF {
+				p.data = nil
+				// The data of a block may be followed by chunks which are left over
+				// by its decompression, such as the terminating empty chunk.
+				if _, err := io.Copy(ioutil.Discard, p.chunks); err != nil {
+					return 0, err
+				}
+				p.chunks = nil
+				if n > 0 {
+					return n, nil
+				}
+				continue
+			}
+			return n, err
+		}
+		if p.section > pgDumpArchiveSectionPostData {
+			return 0, io.EOF
+		}
+		if p.next < len(p.archive.entries) {
+			e := &p.archive.entries[p.next]
+			p.next++
+			if inSection(e, p.section) && !skipPgDumpArchiveEntry(e) && e.defn != "" {
+				p.pending = append(p.pending, e.defn...)
+				p.pending = append(p.pending, '\n')
+			}
+			continue
+		}
+		if p.section == pgDumpArchiveSectionData && p.archive.format == pgDumpArchiveCustom {
+			done, err := p.startBlock()
+			if err != nil {
+				return 0, errors.Wrap(err, "reading pg_dump archive data")
+			}
+			if !done {
+				continue
+			}
+		}
+		p.section++
+		p.next = 0
+	}
+	n := copy(buf, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// startBlock starts rendering the next data block of a custom-format archive,
+// and returns true if there are no more data blocks.
+func (p *pgDumpArchiveRenderer) startBlock() (done bool, _ error) {
+	for {
+		blockType, err := p.d.r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		dumpID, err := p.d.readInt()
+		if err != nil {
+			return false, err
+		}
+		switch blockType {
+		case pgDumpArchiveBlockData:
+		case pgDumpArchiveBlockBlobs:
+			// Large objects are not supported, so their data is skipped. Their data
+			// is a sequence of OIDs, each followed by the chunks of its data, which
+			// is terminated by a zero OID.
+			for {
+				oid, err := p.d.readInt()
+				if err != nil {
+					return false, err
+				}
+				if oid == 0 {
+					break
+				}
+				if _, err := io.Copy(ioutil.Discard, &pgDumpArchiveChunkReader{d: p.d}); err != nil {
+					return false, err
+				}
+			}
+			continue
+		default:
+			return false, errors.Errorf("unexpected block type %d", blockType)
+		}
+
+		e, ok := p.byID[dumpID]
+		if !ok {
+			return false, errors.Errorf("data block of unknown TOC entry %d", dumpID)
+		}
+		p.chunks = &pgDumpArchiveChunkReader{d: p.d}
+		var data io.Reader = p.chunks
+		if p.archive.compression == pgDumpArchiveCompressionGzip {
+			if data, err = zlib.NewReader(data); err != nil {
+				return false, err
+			}
+		}
+		p.data = newPgDumpCopyDataReader(e.copyStmt, data)
+		return false, nil
+	}
+}
+
+// pgDumpArchiveChunkReader reads the data of a block of a custom-format
+// archive, which is split into chunks each encoded as its length followed by
+// its bytes, and terminated by an empty chunk.
+type pgDumpArchiveChunkReader struct {
+	d         *pgDumpArchiveDecoder
+	remaining int
+	done      bool
+}
+
+// Read implements io.Reader.
+func (c *pgDumpArchiveChunkReader) Read(buf []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		l, err := c.d.readInt()
+		if err != nil {
+			return 0, err
+		}
+		if l < 0 {
+			return 0, errors.Errorf("invalid data chunk length %d", l)
+		}
+		c.remaining = l
+		c.done = l == 0
+	}
+	if len(buf) > c.remaining {
+		buf = buf[:c.remaining]
+	}
+	n, err := c.d.r.Read(buf)
+	c.remaining -= n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// pgDumpCopyDataReader renders the data of a table as a COPY statement
+// followed by the data and the line which terminates it. Archives written by
+// some versions of pg_dump include the terminating line in the data, in which
+// case it is not added again. If the table has no COPY statement, its data is
+// made of SQL statements and is rendered as is.
+type pgDumpCopyDataReader struct {
+	r    io.Reader
+	copy bool
+	// tail holds the last bytes read, with runs of line breaks collapsed, to
+	// find whether they end with the terminating line.
+	tail []byte
+	// trailer is the rest of the output once the data was read.
+	trailer []byte
+	eof     bool
+}
+
+// pgDumpCopyDataTerminator is the line which terminates COPY data.
+const pgDumpCopyDataTerminator = "\\.\n"
+
+func newPgDumpCopyDataReader(copyStmt string, data io.Reader) *pgDumpCopyDataReader {
+	if copyStmt == "" {
+		return &pgDumpCopyDataReader{r: data}
+	}
+	return &pgDumpCopyDataReader{r: io.MultiReader(strings.NewReader(copyStmt), data), copy: true}
+}
+
+// Read implements io.Reader.
+func (c *pgDumpCopyDataReader) Read(buf []byte) (int, error) {
+	if c.eof {
+		if len(c.trailer) == 0 {
+			return 0, io.EOF
+		}
+		n := copy(buf, c.trailer)
+		c.trailer = c.trailer[n:]
+		return n, nil
+	}
+	n, err := c.r.Read(buf)
+	if c.copy && n > 0 {
+		c.updateTail(buf[:n])
+	}
+	if err == io.EOF {
+		c.eof = true
+		if c.copy && !c.terminated() {
+			c.trailer = []byte(pgDumpCopyDataTerminator)
+		}
+		if n == 0 {
+			return c.Read(buf)
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// updateTail keeps the last bytes read.
+func (c *pgDumpCopyDataReader) updateTail(data []byte) {
+	for _, b := range data {
+		if b == '\n' && len(c.tail) > 0 && c.tail[len(c.tail)-1] == '\n' {
+			continue
+		}
+		c.tail = append(c.tail, b)
+	}
+	if keep := len(pgDumpCopyDataTerminator) + 1; len(c.tail) > keep {
+		c.tail = append(c.tail[:0], c.tail[len(c.tail)-keep:]...)
+	}
+}
+
+// terminated returns whether the data read ends with the terminating line.
+func (c *pgDumpCopyDataReader) terminated() bool {
+	tail := c.tail
+	if len(tail) > 0 && tail[len(tail)-1] != '\n' {
+		tail = append(tail, '\n')
+	}
+	return bytes.HasSuffix(tail, []byte("\n"+pgDumpCopyDataTerminator))
+}
+
+// pgDumpDirectoryTOCURI returns the URI of the TOC of a directory-format archive
+// if the given URI refers to one, either by the URI of its directory ending in a
+// slash or by the URI of its TOC file.
+func pgDumpDirectoryTOCURI(uri string) (string, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case strings.HasSuffix(parsed.Path, "/"):
+		parsed.Path += pgDumpArchiveTOCFile
+	case path.Base(parsed.Path) == pgDumpArchiveTOCFile:
+	default:
+		return "", false
+	}
+	return parsed.String(), true
+}
+
+// expandPgDumpDirectories replaces each directory-format archive among files by
+// the URI of its TOC followed by those of its data files, so that its tables
+// are loaded in parallel, and records the COPY statement of each data file in
+// opts.
+func expandPgDumpDirectories(
+	ctx context.Context,
+	execCfg *sql.ExecutorConfig,
+	user security.SQLUsername,
+	files []string,
+	opts *roachpb.PgDumpOptions,
+) ([]string, error) {
+	var expanded []string
+	for _, file := range files {
+		tocURI, ok := pgDumpDirectoryTOCURI(file)
+		if !ok {
+			expanded = append(expanded, file)
+			continue
+		}
+		archive, err := readPgDumpDirectoryTOC(ctx, execCfg, user, tocURI)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, tocURI)
+		for i := range archive.entries {
+			e := &archive.entries[i]
+			if e.filename == "" || e.desc != "TABLE DATA" || e.copyStmt == "" {
+				continue
+			}
+			dataURI, err := url.Parse(tocURI)
+			if err != nil {
+				return nil, err
+			}
+			dataURI.Path = path.Join(path.Dir(dataURI.Path), e.filename)
+			if archive.compression == pgDumpArchiveCompressionGzip {
+				dataURI.Path += ".gz"
+			}
+			if opts.ArchiveCopyStatements == nil {
+				opts.ArchiveCopyStatements = make(map[string]string)
+			}
+			opts.ArchiveCopyStatements[dataURI.String()] = e.copyStmt
+			expanded = append(expanded, dataURI.String())
+		}
+	}
+	return expanded, nil
+}
+
+// readPgDumpDirectoryTOC reads the TOC of a directory-format archive.
+func readPgDumpDirectoryTOC(
+	ctx context.Context, execCfg *sql.ExecutorConfig, user security.SQLUsername, tocURI string,
+) (*pgDumpArchive, error) {
+	store, err := execCfg.DistSQLSrv.ExternalStorageFromURI(ctx, tocURI, user)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	raw, err := store.ReadFile(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	defer raw.Close()
+	r := bufio.NewReader(raw)
+	magic := make([]byte, len(pgDumpArchiveMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != pgDumpArchiveMagic {
+		return nil, errors.Newf("%s is not the TOC of a pg_dump directory-format archive",
+			pgDumpArchiveTOCFile)
+	}
+	archive, err := readPgDumpArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	if archive.format != pgDumpArchiveDirectory {
+		return nil, errors.Newf("%s is not the TOC of a pg_dump directory-format archive",
+			pgDumpArchiveTOCFile)
+	}
+	return archive, nil
+}
@@ -12,6 +12,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"strconv"
 	"testing"
@@ -255,6 +256,19 @@ func (es *generatorExternalStorage) ReadFile(
 	return es.gen.Open()
 }
 
+func (es *generatorExternalStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
+	r, err := es.gen.Open()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 func (es *generatorExternalStorage) Close() error {
 	return nil
 }
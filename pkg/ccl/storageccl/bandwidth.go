@@ -0,0 +1,94 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package storageccl
+
+import (
+	"context"
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/util/quotapool"
+)
+
+// BandwidthLimiter limits the number of bytes per second that are moved
+// between the cluster and external storage on behalf of a BACKUP or RESTORE
+// job. A nil *BandwidthLimiter does not limit anything.
+type BandwidthLimiter struct {
+	rl *quotapool.RateLimiter
+}
+
+// NewBandwidthLimiter returns a limiter allowing maxBandwidth bytes per second,
+// or nil if maxBandwidth is not positive.
+func NewBandwidthLimiter(name string, maxBandwidth int64) *BandwidthLimiter {
+	if maxBandwidth <= 0 {
+		return nil
+	}
+	// A burst of one second worth of bytes lets a single transfer larger than
+	// the burst through while still charging it to the following ones.
+	return &BandwidthLimiter{
+		rl: quotapool.NewRateLimiter(name, quotapool.Limit(maxBandwidth), maxBandwidth),
+	}
+}
+
+// Wait blocks until n bytes may be moved.
+func (l *BandwidthLimiter) Wait(ctx context.Context, n int64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	return l.rl.WaitN(ctx, n)
+}
+
+// Reader returns a reader which reads from r no faster than the limit.
+func (l *BandwidthLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, limiter: l}
+}
+
+// ReadSeeker is like Reader, for writers to external storage which may need to
+// seek back to retry a write. Bytes read again after seeking back are charged
+// again, as they are sent again.
+func (l *BandwidthLimiter) ReadSeeker(ctx context.Context, rs io.ReadSeeker) io.ReadSeeker {
+	if l == nil {
+		return rs
+	}
+	return &limitedReadSeeker{limitedReader: limitedReader{ctx: ctx, r: rs, limiter: l}, s: rs}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *BandwidthLimiter
+}
+
+// limitedReaderChunkSize bounds the size of each read of a limitedReader, so
+// that large reads are spread over time rather than done in a single burst.
+const limitedReaderChunkSize = 1 << 20
+
+// Read implements io.Reader.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > limitedReaderChunkSize {
+		p = p[:limitedReaderChunkSize]
+	}
+	n, err := r.r.Read(p)
+	if waitErr := r.limiter.Wait(r.ctx, int64(n)); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+type limitedReadSeeker struct {
+	limitedReader
+	s io.Seeker
+}
+
+// Seek implements io.Seeker.
+func (r *limitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.s.Seek(offset, whence)
+}
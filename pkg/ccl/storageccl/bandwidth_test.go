@@ -0,0 +1,53 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package storageccl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	require.Nil(t, NewBandwidthLimiter("test", 0))
+	require.NoError(t, (*BandwidthLimiter)(nil).Wait(ctx, 1<<30))
+
+	// The first second worth of bytes is read without delay, the rest at the
+	// limit.
+	const limit = 1 << 20
+	data := make([]byte, limit+limit/2)
+	l := NewBandwidthLimiter("test", limit)
+	start := timeutil.Now()
+	read, err := ioutil.ReadAll(l.Reader(ctx, bytes.NewReader(data)))
+	require.NoError(t, err)
+	require.Equal(t, len(data), len(read))
+	require.GreaterOrEqual(t, int64(timeutil.Since(start)), int64(400*time.Millisecond))
+
+	// Bytes read again after seeking back are charged again.
+	rs := l.ReadSeeker(ctx, bytes.NewReader(data[:limit/2]))
+	start = timeutil.Now()
+	for i := 0; i < 2; i++ {
+		_, err := rs.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(rs)
+		require.NoError(t, err)
+		require.Equal(t, limit/2, len(read))
+	}
+	require.GreaterOrEqual(t, int64(timeutil.Since(start)), int64(800*time.Millisecond))
+}
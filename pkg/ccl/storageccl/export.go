@@ -13,6 +13,7 @@ import (
 	"context"
 	"crypto/sha512"
 	"fmt"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -21,6 +22,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/spanset"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
@@ -48,6 +50,35 @@ var ExportRequestMaxAllowedFileSizeOverage = settings.RegisterByteSizeSetting(
 	64<<20, /* 64 MiB */
 )
 
+// ExportAdaptiveBackoffEnabled controls whether export requests back off while
+// the store serving them is overloaded, so that backups yield to foreground
+// traffic instead of adding to its latency.
+var ExportAdaptiveBackoffEnabled = settings.RegisterBoolSetting(
+	"kv.bulk_io_write.adaptive_export_backoff.enabled",
+	"if enabled, export requests are retried later while the read amplification of L0 of the store serving them is above a threshold",
+	false,
+)
+
+// exportAdaptiveBackoffL0Threshold is the read amplification of L0 above which
+// a store is considered overloaded by export requests.
+var exportAdaptiveBackoffL0Threshold = settings.RegisterIntSetting(
+	"kv.bulk_io_write.adaptive_export_backoff.l0_threshold",
+	"number of L0 sublevels (or files, if sublevels are not tracked) above which export requests back off",
+	10,
+)
+
+// ExportAdaptiveBackoffMaxDelay bounds the time an export request is delayed
+// for the store to stop being overloaded, after which it proceeds anyway.
+var ExportAdaptiveBackoffMaxDelay = settings.RegisterNonNegativeDurationSetting(
+	"kv.bulk_io_write.adaptive_export_backoff.max_delay",
+	"maximum amount of time an export request waits for its store to stop being overloaded",
+	time.Minute,
+)
+
+// ErrExportStoreOverloaded is returned by export requests which set
+// FailOnStoreOverload when the store serving them is overloaded.
+var ErrExportStoreOverloaded = errors.New("store overloaded, export request rejected")
+
 const maxUploadRetries = 5
 
 func init() {
@@ -86,6 +117,12 @@ func evalExport(
 		reply.StartTime = cArgs.EvalCtx.GetGCThreshold()
 	}
 
+	if args.FailOnStoreOverload {
+		if err := checkStoreHealth(ctx, cArgs.EvalCtx.Engine(), cArgs.EvalCtx.ClusterSettings()); err != nil {
+			return result.Result{}, err
+		}
+	}
+
 	if err := cArgs.EvalCtx.GetLimiters().ConcurrentExportRequests.Begin(ctx); err != nil {
 		return result.Result{}, err
 	}
@@ -134,6 +171,10 @@ func evalExport(
 		}
 		defer exportStore.Close()
 	}
+	// The limit applies to the files written by this request only; the sender
+	// is responsible for dividing its own limit among the requests it has in
+	// flight.
+	limiter := NewBandwidthLimiter("export", args.MaxBandwidth)
 
 	var exportAllRevisions bool
 	switch args.MVCCFilter {
@@ -217,7 +258,7 @@ func evalExport(
 			if err := retry.WithMaxAttempts(ctx, base.DefaultRetryOptions(), maxUploadRetries, func() error {
 				// We blindly retry any error here because we expect the caller to have
 				// verified the target is writable before sending ExportRequests for it.
-				if err := exportStore.WriteFile(ctx, exported.Path, bytes.NewReader(data)); err != nil {
+				if err := exportStore.WriteFile(ctx, exported.Path, limiter.ReadSeeker(ctx, bytes.NewReader(data))); err != nil {
 					log.VEventf(ctx, 1, "failed to put file: %+v", err)
 					return err
 				}
@@ -229,6 +270,7 @@ func evalExport(
 		if args.ReturnSST {
 			exported.SST = data
 		}
+		exported.SSTSize = int64(len(data))
 		reply.Files = append(reply.Files, exported)
 		start = resume
 	}
@@ -245,6 +287,33 @@ func SHA512ChecksumData(data []byte) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// storeOverloaded returns whether the read amplification of L0 of a store,
+// which is paid by every foreground read, is above threshold.
+func storeOverloaded(metrics *storage.Metrics, threshold int64) bool {
+	l0ReadAmp := metrics.L0FileCount
+	if metrics.L0SublevelCount >= 0 {
+		l0ReadAmp = metrics.L0SublevelCount
+	}
+	return l0ReadAmp > threshold
+}
+
+// checkStoreHealth returns ErrExportStoreOverloaded if eng is overloaded.
+func checkStoreHealth(ctx context.Context, eng storage.Engine, st *cluster.Settings) error {
+	if st == nil {
+		return nil
+	}
+	metrics, err := eng.GetMetrics()
+	if err != nil {
+		log.Warningf(ctx, "failed to read metrics: %+v", err)
+		return nil
+	}
+	if storeOverloaded(metrics, exportAdaptiveBackoffL0Threshold.Get(&st.SV)) {
+		return errors.Wrapf(ErrExportStoreOverloaded, "%d L0 files, %d L0 sublevels",
+			metrics.L0FileCount, metrics.L0SublevelCount)
+	}
+	return nil
+}
+
 func getMatchingStore(
 	locality *roachpb.Locality, storageByLocalityKV map[string]*roachpb.ExternalStorage,
 ) (string, roachpb.ExternalStorage, bool) {
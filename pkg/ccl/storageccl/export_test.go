@@ -32,6 +32,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -88,6 +89,9 @@ func TestExportCmd(t *testing.T) {
 			if !bytes.Equal(fileContents, file.SST) {
 				t.Fatal("Returned SST and exported SST don't match!")
 			}
+			if file.SSTSize != int64(len(fileContents)) {
+				t.Fatalf("expected SST size %d, got %d", len(fileContents), file.SSTSize)
+			}
 			sst.SeekGE(storage.MVCCKey{Key: keys.MinKey})
 			for {
 				if valid, err := sst.Valid(); !valid || err != nil {
@@ -632,3 +636,52 @@ func TestRandomKeyAndTimestampExport(t *testing.T) {
 	}
 
 }
+
+func TestExportAdaptiveBackoff(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name       string
+		metrics    storage.Metrics
+		overloaded bool
+	}{
+		{"sublevels below", storage.Metrics{L0FileCount: 20, L0SublevelCount: 5}, false},
+		{"sublevels above", storage.Metrics{L0FileCount: 20, L0SublevelCount: 11}, true},
+		{"files below", storage.Metrics{L0FileCount: 10, L0SublevelCount: -1}, false},
+		{"files above", storage.Metrics{L0FileCount: 11, L0SublevelCount: -1}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.overloaded, storeOverloaded(&tc.metrics, 10))
+		})
+	}
+
+	ctx := context.Background()
+	eng := storage.NewDefaultInMem()
+	defer eng.Close()
+	st := cluster.MakeTestingClusterSettings()
+
+	// An idle store accepts exports.
+	require.NoError(t, checkStoreHealth(ctx, eng, st))
+
+	// An overloaded store rejects exports which ask to fail on overload.
+	exportAdaptiveBackoffL0Threshold.Override(&st.SV, -1)
+	err := checkStoreHealth(ctx, eng, st)
+	require.True(t, errors.Is(err, ErrExportStoreOverloaded), "%+v", err)
+
+	// The rejection reaches the client, which backs off before retrying, and
+	// exports which do not ask to fail on overload are still served.
+	tc := testcluster.StartTestCluster(t, 1, base.TestClusterArgs{})
+	defer tc.Stopper().Stop(ctx)
+	exportAdaptiveBackoffL0Threshold.Override(&tc.Server(0).ClusterSettings().SV, -1)
+	req := &roachpb.ExportRequest{
+		RequestHeader:       roachpb.RequestHeader{Key: keys.UserTableDataMin, EndKey: keys.MaxKey},
+		ReturnSST:           true,
+		FailOnStoreOverload: true,
+	}
+	_, pErr := kv.SendWrapped(ctx, tc.Server(0).DB().NonTransactionalSender(), req)
+	require.NotNil(t, pErr)
+	require.True(t, errors.Is(pErr.GoError(), ErrExportStoreOverloaded), "%+v", pErr)
+	req.FailOnStoreOverload = false
+	_, pErr = kv.SendWrapped(ctx, tc.Server(0).DB().NonTransactionalSender(), req)
+	require.Nil(t, pErr)
+}
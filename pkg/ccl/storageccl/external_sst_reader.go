@@ -0,0 +1,135 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package storageccl
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/errors"
+)
+
+// ExternalSSTReader returns an iterator over the SST stored at basename in e.
+// Rather than downloading the SST as a whole, the iterator reads its blocks
+// from e as it is positioned, so that only the blocks which it visits are held
+// in memory.
+func ExternalSSTReader(
+	ctx context.Context, e cloud.ExternalStorage, basename string,
+) (storage.SimpleMVCCIterator, error) {
+	size, err := e.Size(ctx, basename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching size of %s", basename)
+	}
+	f := &sstReader{ctx: ctx, es: e, basename: basename, size: size}
+	iter, err := storage.NewSSTIteratorFromFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "opening %s", basename)
+	}
+	return iter, nil
+}
+
+// sstReader implements the vfs.File interface, which the sstable reader reads
+// an SST through, over a file in external storage. Reads of consecutive ranges
+// of the file, such as those of the blocks of an SST visited in order, share a
+// single stream, which is only reopened when a read does not start where the
+// previous one ended.
+type sstReader struct {
+	ctx      context.Context
+	es       cloud.ExternalStorage
+	basename string
+	size     int64
+
+	mu struct {
+		sync.Mutex
+		// r is the stream positioned at pos, if one is open.
+		r   io.ReadCloser
+		pos int64
+	}
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (f *sstReader) ReadAt(p []byte, offset int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.mu.r != nil && f.mu.pos != offset {
+		if err := f.closeStreamLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if f.mu.r == nil {
+		r, err := f.es.ReadFileAt(f.ctx, f.basename, offset)
+		if err != nil {
+			return 0, err
+		}
+		f.mu.r, f.mu.pos = r, offset
+	}
+	n, err := io.ReadFull(f.mu.r, p)
+	f.mu.pos += int64(n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *sstReader) closeStreamLocked() error {
+	if f.mu.r == nil {
+		return nil
+	}
+	err := f.mu.r.Close()
+	f.mu.r = nil
+	return err
+}
+
+// Close implements the io.Closer interface.
+func (f *sstReader) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closeStreamLocked()
+}
+
+// Stat implements the vfs.File interface.
+func (f *sstReader) Stat() (os.FileInfo, error) {
+	return sstFileInfo{name: f.basename, size: f.size}, nil
+}
+
+// Read implements the vfs.File interface. The sstable reader only reads SSTs
+// through ReadAt.
+func (f *sstReader) Read(p []byte) (int, error) {
+	return 0, errors.AssertionFailedf("unexpected sequential read of %s", f.basename)
+}
+
+// Write implements the vfs.File interface.
+func (f *sstReader) Write(p []byte) (int, error) {
+	return 0, errors.AssertionFailedf("unexpected write to %s", f.basename)
+}
+
+// Sync implements the vfs.File interface.
+func (f *sstReader) Sync() error {
+	return nil
+}
+
+// sstFileInfo implements the os.FileInfo interface for an sstReader.
+type sstFileInfo struct {
+	name string
+	size int64
+}
+
+func (i sstFileInfo) Name() string       { return i.name }
+func (i sstFileInfo) Size() int64        { return i.size }
+func (i sstFileInfo) Mode() os.FileMode  { return 0 }
+func (i sstFileInfo) ModTime() time.Time { return time.Time{} }
+func (i sstFileInfo) IsDir() bool        { return false }
+func (i sstFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,91 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package storageccl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+// memSSTStorage is an ExternalStorage serving a single file from memory which
+// records the offset of every stream opened on it.
+type memSSTStorage struct {
+	cloud.ExternalStorage
+	data    []byte
+	offsets []int64
+}
+
+func (s *memSSTStorage) ReadFileAt(
+	_ context.Context, _ string, offset int64,
+) (io.ReadCloser, error) {
+	s.offsets = append(s.offsets, offset)
+	return ioutil.NopCloser(bytes.NewReader(s.data[offset:])), nil
+}
+
+func (s *memSSTStorage) Size(_ context.Context, _ string) (int64, error) {
+	return int64(len(s.data)), nil
+}
+
+func TestExternalSSTReader(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	const numKeys = 10000
+	sstFile := &storage.MemFile{}
+	w := storage.MakeBackupSSTWriter(sstFile)
+	for i := 0; i < numKeys; i++ {
+		key := storage.MVCCKey{Key: roachpb.Key(fmt.Sprintf("key-%05d", i)), Timestamp: hlc.Timestamp{WallTime: 1}}
+		require.NoError(t, w.Put(key, roachpb.MakeValueFromString(fmt.Sprintf("value-%d", i)).RawBytes))
+	}
+	require.NoError(t, w.Finish())
+	w.Close()
+	es := &memSSTStorage{data: sstFile.Data()}
+
+	iter, err := ExternalSSTReader(ctx, es, "test.sst")
+	require.NoError(t, err)
+	defer iter.Close()
+	opened := len(es.offsets)
+
+	var i int
+	for iter.SeekGE(storage.MVCCKey{Key: roachpb.KeyMin}); ; iter.Next() {
+		ok, err := iter.Valid()
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		require.Equal(t, fmt.Sprintf("key-%05d", i), string(iter.UnsafeKey().Key))
+		i++
+	}
+	require.Equal(t, numKeys, i)
+	// The data blocks of the SST are visited in order, so apart from its index
+	// block they are all read through a single stream.
+	require.LessOrEqual(t, len(es.offsets), opened+2)
+
+	// Seeking to a key reads its block from its own offset rather than reading
+	// the SST from the start.
+	iter.SeekGE(storage.MVCCKey{Key: roachpb.Key(fmt.Sprintf("key-%05d", numKeys/2))})
+	ok, err := iter.Valid()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, fmt.Sprintf("key-%05d", numKeys/2), string(iter.UnsafeKey().Key))
+	require.NotZero(t, es.offsets[len(es.offsets)-1])
+}
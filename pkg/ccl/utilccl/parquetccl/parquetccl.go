@@ -0,0 +1,299 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+// Package parquetccl maps SQL types to Parquet column definitions. It is
+// shared by the writers of changefeeds and EXPORT, and is not intended to be
+// a general purpose Parquet utility.
+//
+// The type of a column is mapped to a Parquet physical and logical type as
+// faithfully as possible; types without a natural Parquet representation are
+// written as strings.
+package parquetccl
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/cockroachdb/apd/v2"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/errors"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+)
+
+// EncodeFn converts a non-NULL datum into the Go value expected by the
+// Parquet writer for its column.
+type EncodeFn func(tree.Datum) (interface{}, error)
+
+// Leaf returns the definition of an optional, non-nested Parquet column.
+func Leaf(
+	name string, typ parquet.Type, logical *parquet.LogicalType, converted *parquet.ConvertedType,
+) *parquetschema.ColumnDefinition {
+	return &parquetschema.ColumnDefinition{
+		SchemaElement: &parquet.SchemaElement{
+			Name:           name,
+			Type:           parquet.TypePtr(typ),
+			RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL),
+			LogicalType:    logical,
+			ConvertedType:  converted,
+		},
+	}
+}
+
+// ColumnDefinition returns the Parquet column definition for a value of the
+// given SQL type, along with the function used to convert a non-NULL datum of
+// that type into the Go value expected by the Parquet writer.
+func ColumnDefinition(
+	name string, typ *types.T,
+) (*parquetschema.ColumnDefinition, EncodeFn, error) {
+	var def *parquetschema.ColumnDefinition
+	var encodeFn EncodeFn
+	switch typ.Family() {
+	case types.BoolFamily:
+		def = Leaf(name, parquet.Type_BOOLEAN, nil, nil)
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return bool(*d.(*tree.DBool)), nil
+		}
+	case types.IntFamily:
+		if typ.Width() == 16 || typ.Width() == 32 {
+			def = Leaf(name, parquet.Type_INT32,
+				&parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: int8(typ.Width()), IsSigned: true}},
+				nil)
+			encodeFn = func(d tree.Datum) (interface{}, error) {
+				return int32(*d.(*tree.DInt)), nil
+			}
+		} else {
+			def = Leaf(name, parquet.Type_INT64,
+				&parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 64, IsSigned: true}},
+				parquet.ConvertedTypePtr(parquet.ConvertedType_INT_64))
+			encodeFn = func(d tree.Datum) (interface{}, error) {
+				return int64(*d.(*tree.DInt)), nil
+			}
+		}
+	case types.FloatFamily:
+		if typ.Width() == 32 {
+			def = Leaf(name, parquet.Type_FLOAT, nil, nil)
+			encodeFn = func(d tree.Datum) (interface{}, error) {
+				return float32(*d.(*tree.DFloat)), nil
+			}
+		} else {
+			def = Leaf(name, parquet.Type_DOUBLE, nil, nil)
+			encodeFn = func(d tree.Datum) (interface{}, error) {
+				return float64(*d.(*tree.DFloat)), nil
+			}
+		}
+	case types.DecimalFamily:
+		if typ.Precision() == 0 {
+			// Parquet decimals need a fixed precision and scale, so a DECIMAL
+			// without one is written as its string representation.
+			def = Leaf(name, parquet.Type_BYTE_ARRAY,
+				&parquet.LogicalType{STRING: &parquet.StringType{}},
+				parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8))
+			encodeFn = func(d tree.Datum) (interface{}, error) {
+				return []byte(d.(*tree.DDecimal).Decimal.String()), nil
+			}
+			break
+		}
+		precision, scale := typ.Precision(), typ.Width()
+		def = Leaf(name, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{DECIMAL: &parquet.DecimalType{Precision: precision, Scale: scale}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_DECIMAL))
+		def.SchemaElement.Precision = &precision
+		def.SchemaElement.Scale = &scale
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return EncodeDecimal(&d.(*tree.DDecimal).Decimal, scale)
+		}
+	case types.StringFamily:
+		def = Leaf(name, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{STRING: &parquet.StringType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return []byte(*d.(*tree.DString)), nil
+		}
+	case types.CollatedStringFamily:
+		def = Leaf(name, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{STRING: &parquet.StringType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return []byte(d.(*tree.DCollatedString).Contents), nil
+		}
+	case types.BytesFamily:
+		def = Leaf(name, parquet.Type_BYTE_ARRAY, nil, nil)
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return []byte(*d.(*tree.DBytes)), nil
+		}
+	case types.DateFamily:
+		def = Leaf(name, parquet.Type_INT32,
+			&parquet.LogicalType{DATE: &parquet.DateType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_DATE))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			days := d.(*tree.DDate).UnixEpochDays()
+			// Infinite dates are clamped to the bounds of the int32 range.
+			if days < math.MinInt32 {
+				days = math.MinInt32
+			} else if days > math.MaxInt32 {
+				days = math.MaxInt32
+			}
+			return int32(days), nil
+		}
+	case types.TimestampFamily:
+		def = Leaf(name, parquet.Type_INT64,
+			&parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{
+				IsAdjustedToUTC: false,
+				Unit:            &parquet.TimeUnit{MICROS: &parquet.MicroSeconds{}},
+			}},
+			nil)
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			t := d.(*tree.DTimestamp).Time
+			return t.Unix()*1e6 + int64(t.Nanosecond()/1e3), nil
+		}
+	case types.TimestampTZFamily:
+		def = Leaf(name, parquet.Type_INT64,
+			&parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{
+				IsAdjustedToUTC: true,
+				Unit:            &parquet.TimeUnit{MICROS: &parquet.MicroSeconds{}},
+			}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_TIMESTAMP_MICROS))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			t := d.(*tree.DTimestampTZ).Time
+			return t.Unix()*1e6 + int64(t.Nanosecond()/1e3), nil
+		}
+	case types.TimeFamily:
+		def = Leaf(name, parquet.Type_INT64,
+			&parquet.LogicalType{TIME: &parquet.TimeType{
+				IsAdjustedToUTC: false,
+				Unit:            &parquet.TimeUnit{MICROS: &parquet.MicroSeconds{}},
+			}},
+			nil)
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			// TimeOfDay is stored as microseconds since midnight.
+			return int64(*d.(*tree.DTime)), nil
+		}
+	case types.UuidFamily:
+		def = Leaf(name, parquet.Type_FIXED_LEN_BYTE_ARRAY,
+			&parquet.LogicalType{UUID: &parquet.UUIDType{}}, nil)
+		length := int32(16)
+		def.SchemaElement.TypeLength = &length
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return d.(*tree.DUuid).GetBytes(), nil
+		}
+	case types.JsonFamily:
+		def = Leaf(name, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{JSON: &parquet.JsonType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_JSON))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return []byte(d.(*tree.DJSON).JSON.String()), nil
+		}
+	case types.EnumFamily:
+		def = Leaf(name, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{ENUM: &parquet.EnumType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_ENUM))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return []byte(d.(*tree.DEnum).LogicalRep), nil
+		}
+	case types.ArrayFamily:
+		// Arrays use the standard three-level LIST structure:
+		//
+		//   optional group <name> (LIST) {
+		//     repeated group list {
+		//       optional <element-type> element;
+		//     }
+		//   }
+		elementDef, elementEncodeFn, err := ColumnDefinition(`element`, typ.ArrayContents())
+		if err != nil {
+			return nil, nil, err
+		}
+		def = &parquetschema.ColumnDefinition{
+			SchemaElement: &parquet.SchemaElement{
+				Name:           name,
+				RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL),
+				LogicalType:    &parquet.LogicalType{LIST: &parquet.ListType{}},
+				ConvertedType:  parquet.ConvertedTypePtr(parquet.ConvertedType_LIST),
+			},
+			Children: []*parquetschema.ColumnDefinition{{
+				SchemaElement: &parquet.SchemaElement{
+					Name:           `list`,
+					RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_REPEATED),
+				},
+				Children: []*parquetschema.ColumnDefinition{elementDef},
+			}},
+		}
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			arr := d.(*tree.DArray).Array
+			if len(arr) == 0 {
+				// An empty array is a present LIST group with no repeated
+				// entries, which the writer encodes when `list` is omitted.
+				return map[string]interface{}{}, nil
+			}
+			elements := make([]map[string]interface{}, len(arr))
+			for i, elem := range arr {
+				elements[i] = map[string]interface{}{}
+				if elem == tree.DNull {
+					continue
+				}
+				encoded, err := elementEncodeFn(elem)
+				if err != nil {
+					return nil, err
+				}
+				elements[i][`element`] = encoded
+			}
+			return map[string]interface{}{`list`: elements}, nil
+		}
+	default:
+		// Everything else is written using its textual representation.
+		def = Leaf(name, parquet.Type_BYTE_ARRAY,
+			&parquet.LogicalType{STRING: &parquet.StringType{}},
+			parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8))
+		encodeFn = func(d tree.Datum) (interface{}, error) {
+			return []byte(tree.AsStringWithFlags(d, tree.FmtBareStrings)), nil
+		}
+	}
+	return def, encodeFn, nil
+}
+
+// EncodeDecimal returns the unscaled value of a decimal at the given scale
+// as a big-endian two's complement integer, which is the representation used
+// by Parquet's DECIMAL logical type.
+func EncodeDecimal(dec *apd.Decimal, scale int32) ([]byte, error) {
+	if dec.Form != apd.Finite {
+		return nil, errors.Errorf(`cannot write %s as a parquet DECIMAL`, dec)
+	}
+	unscaled := new(big.Int).Set(&dec.Coeff)
+	if shift := int64(dec.Exponent) + int64(scale); shift > 0 {
+		unscaled.Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(shift), nil))
+	} else if shift < 0 {
+		var rem big.Int
+		unscaled.QuoRem(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(-shift), nil), &rem)
+		if rem.Sign() != 0 {
+			return nil, errors.Errorf(`cannot write %s as a parquet DECIMAL with scale %d`, dec, scale)
+		}
+	}
+	if !dec.Negative || unscaled.Sign() == 0 {
+		b := unscaled.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			// Make room for the sign bit.
+			b = append([]byte{0}, b...)
+		}
+		return b, nil
+	}
+	// For a negative value -x, the two's complement representation in n bytes
+	// is 2^(8n) - x, where n is large enough to hold x with a sign bit.
+	n := len(unscaled.Bytes())
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(8*n-1))
+	if unscaled.Cmp(bound) > 0 {
+		n++
+	}
+	twos := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	twos.Sub(twos, unscaled)
+	b := twos.Bytes()
+	for len(b) < n {
+		b = append([]byte{0xff}, b...)
+	}
+	return b, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package parquetccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/apd/v2"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecimal(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tests := []struct {
+		dec      string
+		scale    int32
+		expected []byte
+	}{
+		{`0`, 0, []byte{0x00}},
+		{`1`, 2, []byte{0x64}},
+		{`-1`, 2, []byte{0x9c}},
+		{`-1`, 0, []byte{0xff}},
+		{`1.28`, 2, []byte{0x00, 0x80}},
+		{`-1.28`, 2, []byte{0x80}},
+		{`-1.29`, 2, []byte{0xff, 0x7f}},
+		{`655.36`, 2, []byte{0x01, 0x00, 0x00}},
+		{`-655.36`, 2, []byte{0xff, 0x00, 0x00}},
+		{`1.500`, 2, []byte{0x00, 0x96}},
+	}
+	for _, test := range tests {
+		t.Run(test.dec, func(t *testing.T) {
+			dec, _, err := apd.NewFromString(test.dec)
+			require.NoError(t, err)
+			actual, err := EncodeDecimal(dec, test.scale)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, actual)
+		})
+	}
+
+	_, err := EncodeDecimal(&apd.Decimal{Form: apd.NaN}, 2)
+	require.EqualError(t, err, `cannot write NaN as a parquet DECIMAL`)
+	_, err = EncodeDecimal(apd.New(1234, -3), 2)
+	require.EqualError(t, err, `cannot write 1.234 as a parquet DECIMAL with scale 2`)
+}
@@ -1060,3 +1060,6 @@ func extraClientFlagInit() error {
 
 // VarFlag is exported for use in package cliccl.
 var VarFlag = varFlag
+
+// StringFlag is exported for use in package cliccl.
+var StringFlag = stringFlag
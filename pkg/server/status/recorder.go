@@ -549,7 +549,7 @@ func extractValue(mtr interface{}) (float64, error) {
 // recordable values.
 func eachRecordableValue(reg *metric.Registry, fn func(string, float64)) {
 	reg.Each(func(name string, mtr interface{}) {
-		if histogram, ok := mtr.(*metric.Histogram); ok {
+		if histogram, ok := mtr.(metric.WindowedHistogram); ok {
 			// TODO(mrtracy): Where should this comment go for better
 			// visibility?
 			//
@@ -57,6 +57,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/metric/aggmetric"
 	"github.com/cockroachdb/cockroach/pkg/util/netutil"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
@@ -967,6 +968,12 @@ func (ts *TestServer) MustGetSQLCounter(name string) int64 {
 			case *metric.Gauge:
 				c = t.Value()
 				found = true
+			case *aggmetric.AggCounter:
+				c = t.Count()
+				found = true
+			case *aggmetric.AggGauge:
+				c = t.Value()
+				found = true
 			}
 		}
 	})
@@ -35,6 +35,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
 	"github.com/cockroachdb/cockroach/pkg/sql/stats"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/errorutil/unimplemented"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/errors"
 )
@@ -197,6 +198,13 @@ func (n *alterTableNode) startExec(params runParams) error {
 					continue
 				}
 
+				if d.Deferrability != tree.ConstraintNotDeferrable {
+					// The index backing a deferrable unique constraint is not unique, so
+					// backfilling it would not validate the existing rows.
+					return unimplemented.NewWithIssue(31632,
+						"DEFERRABLE unique constraints can only be added in CREATE TABLE")
+				}
+
 				// Check if the columns exist on the table.
 				for _, column := range d.Columns {
 					if _, _, err := n.tableDesc.FindColumnByName(column.Column); err != nil {
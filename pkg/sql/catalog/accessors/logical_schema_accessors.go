@@ -150,6 +150,9 @@ func (l *LogicalSchemaAccessor) GetObjectDesc(
 	case tree.TableObject:
 		tableName := tree.MakeTableNameWithSchema(tree.Name(db), tree.Name(schema), tree.Name(object))
 		return l.tc.GetTableByName(ctx, txn, &tableName, flags)
+	case tree.FunctionObject:
+		funcName := tree.MakeQualifiedFunctionName(db, schema, object)
+		return l.tc.GetFunctionByName(ctx, txn, &funcName, flags)
 	default:
 		return nil, errors.AssertionFailedf("unknown desired object kind %d", flags.DesiredObjectKind)
 	}
@@ -44,14 +44,23 @@ func IndexForDisplay(
 	semaCtx *tree.SemaContext,
 ) (string, error) {
 	f := tree.NewFmtCtx(tree.FmtSimple)
-	if index.Unique {
-		f.WriteString("UNIQUE ")
-	}
-	if index.Type == descpb.IndexDescriptor_INVERTED {
-		f.WriteString("INVERTED ")
+	// An index backing a deferrable unique constraint can only be created by
+	// the constraint, so it is displayed as one in CREATE TABLE.
+	deferrableUnique := index.Deferrable && *tableName == descpb.AnonymousTable
+	if deferrableUnique {
+		f.WriteString("CONSTRAINT ")
+		f.FormatNameP(&index.Name)
+		f.WriteString(" UNIQUE")
+	} else {
+		if index.Unique {
+			f.WriteString("UNIQUE ")
+		}
+		if index.Type == descpb.IndexDescriptor_INVERTED {
+			f.WriteString("INVERTED ")
+		}
+		f.WriteString("INDEX ")
+		f.FormatNameP(&index.Name)
 	}
-	f.WriteString("INDEX ")
-	f.FormatNameP(&index.Name)
 	if *tableName != descpb.AnonymousTable {
 		f.WriteString(" ON ")
 		f.FormatNode(tableName)
@@ -152,6 +161,14 @@ func IndexForDisplay(
 		}
 	}
 
+	if deferrableUnique {
+		if index.InitiallyDeferred {
+			f.WriteString(" DEFERRABLE INITIALLY DEFERRED")
+		} else {
+			f.WriteString(" DEFERRABLE INITIALLY IMMEDIATE")
+		}
+	}
+
 	if index.IsPartial() {
 		f.WriteString(" WHERE ")
 		pred, err := schemaexpr.FormatExprForDisplay(ctx, table, index.Predicate, semaCtx, tree.FmtParsable)
@@ -117,3 +117,17 @@ type ConstraintDetail struct {
 	// Only populated for Check Constraints.
 	CheckConstraint *TableDescriptor_CheckConstraint
 }
+
+// Deferrability returns whether the checks of the constraint can be deferred
+// until the end of the transaction, and whether they are by default.
+func (c ConstraintDetail) Deferrability() (deferrable, initiallyDeferred bool) {
+	switch {
+	case c.FK != nil:
+		return c.FK.Deferrable, c.FK.InitiallyDeferred
+	case c.CheckConstraint != nil:
+		return c.CheckConstraint.Deferrable, c.CheckConstraint.InitiallyDeferred
+	case c.Index != nil:
+		return c.Index.Deferrable, c.Index.InitiallyDeferred
+	}
+	return false, false
+}
@@ -96,6 +96,14 @@ type SchemaDescriptor interface {
 	SchemaDesc() *descpb.SchemaDescriptor
 }
 
+// FunctionDescriptor will eventually be called funcdesc.Descriptor.
+// It is implemented by (Imm|M)utable in funcdesc.
+type FunctionDescriptor interface {
+	Descriptor
+	FuncDesc() *descpb.FunctionDescriptor
+	Validate(ctx context.Context, dg DescGetter) error
+}
+
 // TableDescriptor is an interface around the table descriptor types.
 type TableDescriptor interface {
 	Descriptor
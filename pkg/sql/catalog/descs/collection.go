@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/hydratedtables"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/lease"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
@@ -521,6 +522,36 @@ func (tc *Collection) GetTypeByName(
 	return typ, nil
 }
 
+// GetFunctionByName returns a function descriptor with properties according to
+// the provided lookup flags.
+func (tc *Collection) GetFunctionByName(
+	ctx context.Context, txn *kv.Txn, name tree.ObjectName, flags tree.ObjectLookupFlags,
+) (_ catalog.Descriptor, err error) {
+	desc, found, err := tc.getObjectByName(ctx, txn, name.Catalog(), name.Schema(), name.Object(), flags)
+	if err != nil {
+		return nil, err
+	} else if !found {
+		if flags.Required {
+			return nil, sqlerrors.NewUndefinedFunctionError(name)
+		}
+		return nil, nil
+	}
+	fn, ok := desc.(catalog.FunctionDescriptor)
+	if !ok {
+		if flags.Required {
+			return nil, sqlerrors.NewUndefinedFunctionError(name)
+		}
+		return nil, nil
+	}
+	if err := catalog.FilterDescriptorState(fn, flags.CommonLookupFlags); err != nil {
+		if flags.Required {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return fn, nil
+}
+
 // TODO (lucy): Should this just take a database name? We're separately
 // resolving the database name in lots of places where we (indirectly) call
 // this.
@@ -1151,6 +1182,44 @@ func (tc *Collection) GetTypeVersionByID(
 	return typ, nil
 }
 
+// GetMutableFunctionVersionByID is the equivalent of GetMutableTableVersionByID
+// but for accessing functions.
+func (tc *Collection) GetMutableFunctionVersionByID(
+	ctx context.Context, txn *kv.Txn, funcID descpb.ID,
+) (*funcdesc.Mutable, error) {
+	desc, err := tc.GetMutableDescriptorByID(ctx, funcID, txn)
+	if err != nil || desc == nil {
+		return nil, err
+	}
+	fn, ok := desc.(*funcdesc.Mutable)
+	if !ok {
+		return nil, pgerror.Newf(
+			pgcode.UndefinedFunction, "descriptor %d is a %s not a function", funcID, desc.TypeName())
+	}
+	return fn, nil
+}
+
+// GetFunctionVersionByID is the equivalent of GetTableVersionByID but for
+// accessing functions.
+func (tc *Collection) GetFunctionVersionByID(
+	ctx context.Context, txn *kv.Txn, funcID descpb.ID, flags tree.ObjectLookupFlags,
+) (*funcdesc.Immutable, error) {
+	desc, err := tc.getDescriptorVersionByID(ctx, txn, funcID, flags.CommonLookupFlags, true /* setTxnDeadline */)
+	if err != nil {
+		if errors.Is(err, catalog.ErrDescriptorNotFound) {
+			return nil, pgerror.Newf(
+				pgcode.UndefinedFunction, "function with ID %d does not exist", funcID)
+		}
+		return nil, err
+	}
+	fn, ok := desc.(*funcdesc.Immutable)
+	if !ok {
+		return nil, pgerror.Newf(
+			pgcode.UndefinedFunction, "function with ID %d does not exist", funcID)
+	}
+	return fn, nil
+}
+
 // getUncommittedDescriptor returns a descriptor for the requested name
 // if the requested name is for a descriptor modified within the transaction
 // affiliated with the Collection.
@@ -0,0 +1,396 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package funcdesc
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+)
+
+var _ catalog.FunctionDescriptor = (*Immutable)(nil)
+var _ catalog.FunctionDescriptor = (*Mutable)(nil)
+var _ catalog.MutableDescriptor = (*Mutable)(nil)
+
+// Immutable wraps a Function descriptor and provides methods on it.
+type Immutable struct {
+	descpb.FunctionDescriptor
+
+	// isUncommittedVersion is set to true if this descriptor was created from
+	// a copy of a Mutable with an uncommitted version.
+	isUncommittedVersion bool
+}
+
+// Mutable is a mutable reference to a FunctionDescriptor.
+type Mutable struct {
+	Immutable
+
+	ClusterVersion *Immutable
+}
+
+var _ redact.SafeMessager = (*Immutable)(nil)
+
+// SafeMessage makes Immutable a SafeMessager.
+func (desc *Immutable) SafeMessage() string {
+	return formatSafeMessage("funcdesc.Immutable", desc)
+}
+
+// SafeMessage makes Mutable a SafeMessager.
+func (desc *Mutable) SafeMessage() string {
+	return formatSafeMessage("funcdesc.Mutable", desc)
+}
+
+func formatSafeMessage(typeName string, desc catalog.FunctionDescriptor) string {
+	var buf redact.StringBuilder
+	buf.Printf(typeName + ": {")
+	catalog.FormatSafeDescriptorProperties(&buf, desc)
+	buf.Printf(", NumArgs: %d", len(desc.FuncDesc().Args))
+	buf.Printf("}")
+	return buf.String()
+}
+
+// NewMutableExisting returns a Mutable from the given function descriptor with
+// the cluster version also set to the descriptor. This is for functions that
+// already exist.
+func NewMutableExisting(desc descpb.FunctionDescriptor) *Mutable {
+	return &Mutable{
+		Immutable:      makeImmutable(*protoutil.Clone(&desc).(*descpb.FunctionDescriptor)),
+		ClusterVersion: NewImmutable(desc),
+	}
+}
+
+// NewImmutable makes a new Function descriptor.
+func NewImmutable(desc descpb.FunctionDescriptor) *Immutable {
+	m := makeImmutable(desc)
+	return &m
+}
+
+func makeImmutable(desc descpb.FunctionDescriptor) Immutable {
+	return Immutable{FunctionDescriptor: desc}
+}
+
+// NewCreatedMutable returns a Mutable from the given FunctionDescriptor with
+// the cluster version being the zero function. This is for a function that is
+// created within the current transaction.
+func NewCreatedMutable(desc descpb.FunctionDescriptor) *Mutable {
+	return &Mutable{
+		Immutable: makeImmutable(desc),
+	}
+}
+
+// SetDrainingNames implements the MutableDescriptor interface.
+func (desc *Mutable) SetDrainingNames(names []descpb.NameInfo) {
+	desc.DrainingNames = names
+}
+
+// IsUncommittedVersion implements the Descriptor interface.
+func (desc *Immutable) IsUncommittedVersion() bool {
+	return desc.isUncommittedVersion
+}
+
+// GetAuditMode implements the DescriptorProto interface.
+func (desc *Immutable) GetAuditMode() descpb.TableDescriptor_AuditMode {
+	return descpb.TableDescriptor_DISABLED
+}
+
+// TypeName implements the DescriptorProto interface.
+func (desc *Immutable) TypeName() string {
+	return "function"
+}
+
+// FuncDesc implements the FunctionDescriptor interface.
+func (desc *Immutable) FuncDesc() *descpb.FunctionDescriptor {
+	return &desc.FunctionDescriptor
+}
+
+// Public implements the Descriptor interface.
+func (desc *Immutable) Public() bool {
+	return desc.State == descpb.DescriptorState_PUBLIC
+}
+
+// Adding implements the Descriptor interface.
+func (desc *Immutable) Adding() bool {
+	return false
+}
+
+// Offline implements the Descriptor interface.
+func (desc *Immutable) Offline() bool {
+	return desc.State == descpb.DescriptorState_OFFLINE
+}
+
+// Dropped implements the Descriptor interface.
+func (desc *Immutable) Dropped() bool {
+	return desc.State == descpb.DescriptorState_DROP
+}
+
+// DescriptorProto wraps a FunctionDescriptor in a Descriptor.
+func (desc *Immutable) DescriptorProto() *descpb.Descriptor {
+	return &descpb.Descriptor{
+		Union: &descpb.Descriptor_Function{
+			Function: &desc.FunctionDescriptor,
+		},
+	}
+}
+
+// NameResolutionResult implements the ObjectDescriptor interface.
+func (desc *Immutable) NameResolutionResult() {}
+
+// GetVolatility returns the volatility the function was declared with.
+func (desc *Immutable) GetVolatility() tree.Volatility {
+	return VolatilityFromProto(desc.Volatility)
+}
+
+// VolatilityFromProto converts the volatility stored in a FunctionDescriptor
+// to a tree.Volatility.
+func VolatilityFromProto(v descpb.FunctionDescriptor_Volatility) tree.Volatility {
+	switch v {
+	case descpb.FunctionDescriptor_IMMUTABLE:
+		return tree.VolatilityImmutable
+	case descpb.FunctionDescriptor_STABLE:
+		return tree.VolatilityStable
+	default:
+		return tree.VolatilityVolatile
+	}
+}
+
+// VolatilityToProto converts a tree.Volatility to the volatility stored in a
+// FunctionDescriptor. Leak-proof functions are stored as immutable.
+func VolatilityToProto(v tree.Volatility) descpb.FunctionDescriptor_Volatility {
+	switch v {
+	case tree.VolatilityLeakProof, tree.VolatilityImmutable:
+		return descpb.FunctionDescriptor_IMMUTABLE
+	case tree.VolatilityStable:
+		return descpb.FunctionDescriptor_STABLE
+	default:
+		return descpb.FunctionDescriptor_VOLATILE
+	}
+}
+
+// MaybeIncrementVersion implements the MutableDescriptor interface.
+func (desc *Mutable) MaybeIncrementVersion() {
+	// Already incremented, no-op.
+	if desc.ClusterVersion == nil || desc.Version == desc.ClusterVersion.Version+1 {
+		return
+	}
+	desc.Version++
+	desc.ModificationTime = hlc.Timestamp{}
+}
+
+// OriginalName implements the MutableDescriptor interface.
+func (desc *Mutable) OriginalName() string {
+	if desc.ClusterVersion == nil {
+		return ""
+	}
+	return desc.ClusterVersion.Name
+}
+
+// OriginalID implements the MutableDescriptor interface.
+func (desc *Mutable) OriginalID() descpb.ID {
+	if desc.ClusterVersion == nil {
+		return descpb.InvalidID
+	}
+	return desc.ClusterVersion.ID
+}
+
+// OriginalVersion implements the MutableDescriptor interface.
+func (desc *Mutable) OriginalVersion() descpb.DescriptorVersion {
+	if desc.ClusterVersion == nil {
+		return 0
+	}
+	return desc.ClusterVersion.Version
+}
+
+// ImmutableCopy implements the MutableDescriptor interface.
+func (desc *Mutable) ImmutableCopy() catalog.Descriptor {
+	imm := NewImmutable(*protoutil.Clone(desc.FuncDesc()).(*descpb.FunctionDescriptor))
+	imm.isUncommittedVersion = desc.IsUncommittedVersion()
+	return imm
+}
+
+// IsNew implements the MutableDescriptor interface.
+func (desc *Mutable) IsNew() bool {
+	return desc.ClusterVersion == nil
+}
+
+// SetPublic implements the MutableDescriptor interface.
+func (desc *Mutable) SetPublic() {
+	desc.State = descpb.DescriptorState_PUBLIC
+	desc.OfflineReason = ""
+}
+
+// SetDropped implements the MutableDescriptor interface.
+func (desc *Mutable) SetDropped() {
+	desc.State = descpb.DescriptorState_DROP
+	desc.OfflineReason = ""
+}
+
+// SetOffline implements the MutableDescriptor interface.
+func (desc *Mutable) SetOffline(reason string) {
+	desc.State = descpb.DescriptorState_OFFLINE
+	desc.OfflineReason = reason
+}
+
+// IsUncommittedVersion implements the Descriptor interface.
+func (desc *Mutable) IsUncommittedVersion() bool {
+	return desc.IsNew() || desc.GetVersion() != desc.ClusterVersion.GetVersion()
+}
+
+// AddDependedOnByTrigger adds a back-reference to a table which has a trigger
+// executing the function. It ensures that duplicates are not added.
+func (desc *Mutable) AddDependedOnByTrigger(tableID descpb.ID) {
+	for _, id := range desc.DependedOnByTriggers {
+		if id == tableID {
+			return
+		}
+	}
+	desc.DependedOnByTriggers = append(desc.DependedOnByTriggers, tableID)
+}
+
+// RemoveDependedOnByTrigger removes the back-reference to the given table. It
+// has no effect if the table has no trigger executing the function.
+func (desc *Mutable) RemoveDependedOnByTrigger(tableID descpb.ID) {
+	for i, id := range desc.DependedOnByTriggers {
+		if id == tableID {
+			desc.DependedOnByTriggers = append(desc.DependedOnByTriggers[:i], desc.DependedOnByTriggers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Validate performs validation on the FunctionDescriptor.
+func (desc *Immutable) Validate(ctx context.Context, dg catalog.DescGetter) error {
+	// Validate local properties of the descriptor.
+	if err := catalog.ValidateName(desc.Name, "function"); err != nil {
+		return err
+	}
+	if desc.ID == descpb.InvalidID {
+		return errors.AssertionFailedf("invalid ID %d", errors.Safe(desc.ID))
+	}
+	if desc.ParentID == descpb.InvalidID {
+		return errors.AssertionFailedf("invalid parentID %d", errors.Safe(desc.ParentID))
+	}
+	if desc.ReturnsTrigger {
+		if desc.ReturnType != nil || len(desc.Args) != 0 || len(desc.DependsOn) != 0 {
+			return errors.AssertionFailedf(
+				"trigger function %q has a return type, arguments or dependencies", desc.Name)
+		}
+	} else {
+		if desc.ReturnType == nil {
+			return errors.AssertionFailedf("function %q has no return type", desc.Name)
+		}
+		if len(desc.DependedOnByTriggers) != 0 {
+			return errors.AssertionFailedf("function %q is used by triggers but does not return trigger", desc.Name)
+		}
+	}
+	for i := range desc.Args {
+		if desc.Args[i].Type == nil {
+			return errors.AssertionFailedf("argument %d of function %q has no type", i+1, desc.Name)
+		}
+	}
+	if desc.Body == "" {
+		return errors.AssertionFailedf("function %q has no body", desc.Name)
+	}
+	if err := desc.Privileges.Validate(desc.ID, privilege.Function); err != nil {
+		return err
+	}
+
+	// Validate all cross references on the descriptor.
+
+	// Buffer all the requested requests and error checks together to run at once.
+	var checks []func(got catalog.Descriptor) error
+	var reqs []descpb.ID
+
+	// Validate the parentID.
+	reqs = append(reqs, desc.ParentID)
+	checks = append(checks, func(got catalog.Descriptor) error {
+		if _, isDB := got.(catalog.DatabaseDescriptor); !isDB {
+			return errors.AssertionFailedf("parentID %d does not exist", errors.Safe(desc.ParentID))
+		}
+		return nil
+	})
+
+	// Validate the parentSchemaID.
+	if desc.ParentSchemaID != keys.PublicSchemaID {
+		reqs = append(reqs, desc.ParentSchemaID)
+		checks = append(checks, func(got catalog.Descriptor) error {
+			if _, isSchema := got.(catalog.SchemaDescriptor); !isSchema {
+				return errors.AssertionFailedf("parentSchemaID %d does not exist", errors.Safe(desc.ParentSchemaID))
+			}
+			return nil
+		})
+	}
+
+	// Validate that all of the relations the body depends on exist and hold a
+	// back-reference to this function.
+	if !desc.Dropped() {
+		for _, id := range desc.DependsOn {
+			id := id
+			reqs = append(reqs, id)
+			checks = append(checks, func(got catalog.Descriptor) error {
+				table, isTable := got.(catalog.TableDescriptor)
+				if !isTable {
+					return errors.AssertionFailedf("depends-on relation %d does not exist", id)
+				}
+				for _, fnID := range table.TableDesc().DependedOnByFunctions {
+					if fnID == desc.ID {
+						return nil
+					}
+				}
+				return errors.AssertionFailedf("depends-on relation %q (%d) has no corresponding depended-on-by back reference",
+					table.GetName(), id)
+			})
+		}
+	}
+
+	// Validate that all of the tables with triggers executing this function
+	// have such a trigger.
+	if !desc.Dropped() {
+		for _, id := range desc.DependedOnByTriggers {
+			id := id
+			reqs = append(reqs, id)
+			checks = append(checks, func(got catalog.Descriptor) error {
+				table, isTable := got.(catalog.TableDescriptor)
+				if !isTable {
+					return errors.AssertionFailedf("depended-on-by table %d does not exist", id)
+				}
+				for i := range table.TableDesc().Triggers {
+					if table.TableDesc().Triggers[i].FunctionID == desc.ID {
+						return nil
+					}
+				}
+				return errors.AssertionFailedf("depended-on-by table %q (%d) has no trigger executing the function",
+					table.GetName(), id)
+			})
+		}
+	}
+
+	descs, err := dg.GetDescs(ctx, reqs)
+	if err != nil {
+		return err
+	}
+
+	// For each result in the batch, apply the corresponding check.
+	for i := range checks {
+		if err := checks[i](descs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -104,10 +104,12 @@ func (b *CheckConstraintBuilder) Build(
 	}
 
 	return &descpb.TableDescriptor_CheckConstraint{
-		Expr:      expr,
-		Name:      name,
-		ColumnIDs: colIDs.Ordered(),
-		Hidden:    c.Hidden,
+		Expr:              expr,
+		Name:              name,
+		ColumnIDs:         colIDs.Ordered(),
+		Hidden:            c.Hidden,
+		Deferrable:        c.Deferrability != tree.ConstraintNotDeferrable,
+		InitiallyDeferred: c.Deferrability == tree.ConstraintInitiallyDeferred,
 	}, nil
 }
 
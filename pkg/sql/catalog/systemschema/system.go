@@ -44,6 +44,8 @@ func ShouldSplitAtDesc(rawDesc *roachpb.Value) bool {
 		return false
 	case *descpb.Descriptor_Schema:
 		return false
+	case *descpb.Descriptor_Function:
+		return false
 	default:
 		panic(errors.AssertionFailedf("unexpected descriptor type %#v", &desc))
 	}
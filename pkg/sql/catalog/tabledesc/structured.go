@@ -1891,6 +1891,10 @@ func (desc *Immutable) ValidateTable(ctx context.Context) error {
 			return err
 		}
 
+		if err := desc.validateTriggers(); err != nil {
+			return err
+		}
+
 		if err := desc.validateTableIndexes(columnNames); err != nil {
 			return err
 		}
@@ -2118,6 +2122,27 @@ func (desc *Immutable) validateCheckConstraints(columnIDs map[descpb.ColumnID]st
 	return nil
 }
 
+// validateTriggers validates that the triggers are sorted by name, that each
+// of them fires on at least one event and that they execute a function.
+func (desc *Immutable) validateTriggers() error {
+	for i := range desc.Triggers {
+		trig := &desc.Triggers[i]
+		if trig.Name == "" {
+			return errors.AssertionFailedf("empty trigger name")
+		}
+		if i > 0 && desc.Triggers[i-1].Name >= trig.Name {
+			return errors.AssertionFailedf("triggers are not sorted by name or duplicate trigger %q", trig.Name)
+		}
+		if !trig.OnInsert && !trig.OnUpdate && !trig.OnDelete {
+			return errors.AssertionFailedf("trigger %q does not fire on any event", trig.Name)
+		}
+		if trig.FunctionID == descpb.InvalidID {
+			return errors.AssertionFailedf("trigger %q has no function", trig.Name)
+		}
+	}
+	return nil
+}
+
 // validateTableIndexes validates that indexes are well formed. Checks include
 // validating the columns involved in the index, verifying the index names and
 // IDs are unique, and the family of the primary key is 0. This does not check
@@ -2516,6 +2541,59 @@ func checkColumnsValidForInvertedIndex(tableDesc *Mutable, indexColNames []strin
 	return nil
 }
 
+// AddDependedOnByFunction adds a back-reference to a function whose body
+// references the table. It ensures that duplicates are not added.
+func (desc *Mutable) AddDependedOnByFunction(fnID descpb.ID) {
+	for _, id := range desc.DependedOnByFunctions {
+		if id == fnID {
+			return
+		}
+	}
+	desc.DependedOnByFunctions = append(desc.DependedOnByFunctions, fnID)
+}
+
+// RemoveDependedOnByFunction removes the back-reference to the given function.
+// It has no effect if the function does not depend on the table.
+func (desc *Mutable) RemoveDependedOnByFunction(fnID descpb.ID) {
+	for i, id := range desc.DependedOnByFunctions {
+		if id == fnID {
+			desc.DependedOnByFunctions = append(desc.DependedOnByFunctions[:i], desc.DependedOnByFunctions[i+1:]...)
+			return
+		}
+	}
+}
+
+// FindTriggerByName returns the trigger with the given name, or nil if there
+// is no such trigger.
+func (desc *Immutable) FindTriggerByName(name string) *descpb.TableDescriptor_Trigger {
+	i := sort.Search(len(desc.Triggers), func(i int) bool { return desc.Triggers[i].Name >= name })
+	if i < len(desc.Triggers) && desc.Triggers[i].Name == name {
+		return &desc.Triggers[i]
+	}
+	return nil
+}
+
+// AddTrigger adds a trigger to the table, keeping the triggers sorted by name.
+// The caller is responsible for ensuring that no trigger with the same name
+// exists.
+func (desc *Mutable) AddTrigger(trig descpb.TableDescriptor_Trigger) {
+	i := sort.Search(len(desc.Triggers), func(i int) bool { return desc.Triggers[i].Name >= trig.Name })
+	desc.Triggers = append(desc.Triggers, descpb.TableDescriptor_Trigger{})
+	copy(desc.Triggers[i+1:], desc.Triggers[i:])
+	desc.Triggers[i] = trig
+}
+
+// RemoveTrigger removes the trigger with the given name. It has no effect if
+// there is no such trigger.
+func (desc *Mutable) RemoveTrigger(name string) {
+	for i := range desc.Triggers {
+		if desc.Triggers[i].Name == name {
+			desc.Triggers = append(desc.Triggers[:i], desc.Triggers[i+1:]...)
+			return
+		}
+	}
+}
+
 // AddColumn adds a column to the table.
 func (desc *Mutable) AddColumn(col *descpb.ColumnDescriptor) {
 	desc.Columns = append(desc.Columns, *col)
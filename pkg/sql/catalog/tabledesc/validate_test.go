@@ -147,6 +147,8 @@ var validationMap = []struct {
 			"Disabled":          {status: thisFieldReferencesNoObjects},
 			"GeoConfig":         {status: thisFieldReferencesNoObjects},
 			"Predicate":         {status: iSolemnlySwearThisFieldIsValidated},
+			"Deferrable":        {status: thisFieldReferencesNoObjects},
+			"InitiallyDeferred": {status: thisFieldReferencesNoObjects},
 		},
 	},
 	{
@@ -190,6 +192,8 @@ var validationMap = []struct {
 			"OnDelete":          {status: thisFieldReferencesNoObjects},
 			"OnUpdate":          {status: thisFieldReferencesNoObjects},
 			"Match":             {status: thisFieldReferencesNoObjects},
+			"Deferrable":        {status: thisFieldReferencesNoObjects},
+			"InitiallyDeferred": {status: thisFieldReferencesNoObjects},
 		},
 	},
 	{
@@ -23,6 +23,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemaexpr"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/cat"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/exec"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -333,12 +335,18 @@ type checkSet = util.FastIntSet
 // the entire input); checkOrds contains the set of checks for which we have
 // values, as ordinals into ActiveChecks(). There must be exactly one value in
 // checkVals for each element in checkSet.
+//
+// The violations of DEFERRABLE checks which are currently deferred are
+// recorded in dc instead of being returned, to be checked again at the end of
+// the transaction. dc is nil if the checks cannot be deferred (e.g. for
+// internal executors).
 func checkMutationInput(
 	ctx context.Context,
 	semaCtx *tree.SemaContext,
 	tabDesc catalog.TableDescriptor,
 	checkOrds checkSet,
 	checkVals tree.Datums,
+	dc *deferredConstraints,
 ) error {
 	if len(checkVals) < checkOrds.Len() {
 		return errors.AssertionFailedf(
@@ -357,15 +365,31 @@ func checkMutationInput(
 		} else if !res && checkVals[colIdx] != tree.DNull {
 			// Failed to satisfy CHECK constraint, so unwrap the serialized
 			// check expression to display to the user.
+			var checkErr error
 			expr, err := schemaexpr.FormatExprForDisplay(ctx, tabDesc, checks[i].Expr, semaCtx, tree.FmtParsable)
 			if err != nil {
 				// If we ran into an error trying to read the check constraint, wrap it
 				// and return.
-				return pgerror.WithConstraintName(errors.Wrapf(err, "failed to satisfy CHECK constraint (%s)", checks[i].Expr), checks[i].Name)
+				checkErr = pgerror.WithConstraintName(errors.Wrapf(err, "failed to satisfy CHECK constraint (%s)", checks[i].Expr), checks[i].Name)
+			} else {
+				checkErr = pgerror.WithConstraintName(pgerror.Newf(
+					pgcode.CheckViolation, "failed to satisfy CHECK constraint (%s)", expr,
+				), checks[i].Name)
+			}
+			if !checks[i].Deferrable || dc == nil {
+				return checkErr
+			}
+			deferrable := &exec.DeferrableCheck{
+				TableID:           cat.StableID(tabDesc.GetID()),
+				ConstraintName:    checks[i].Name,
+				InitiallyDeferred: checks[i].InitiallyDeferred,
+			}
+			if !dc.isDeferred(deferrable) {
+				return checkErr
 			}
-			return pgerror.WithConstraintName(pgerror.Newf(
-				pgcode.CheckViolation, "failed to satisfy CHECK constraint (%s)", expr,
-			), checks[i].Name)
+			// The whole table is checked again at the end of the transaction, so
+			// the values of the row are not recorded.
+			dc.addViolation(deferrable, nil /* keyVals */, checkErr)
 		}
 		colIdx++
 	}
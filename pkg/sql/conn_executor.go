@@ -954,6 +954,11 @@ type connExecutor struct {
 		// queued up for the given ID.
 		schemaChangeJobsCache map[descpb.ID]*jobs.Job
 
+		// deferredConstraints accumulates the violations of foreign key, unique
+		// and check constraints which are deferred until the transaction
+		// commits, along with the checking modes set by SET CONSTRAINTS.
+		deferredConstraints deferredConstraints
+
 		// autoRetryCounter keeps track of the which iteration of a transaction
 		// auto-retry we're currently in. It's 0 whenever the transaction state is not
 		// stateOpen.
@@ -1235,6 +1240,11 @@ func (ex *connExecutor) resetExtraTxnState(ctx context.Context, ev txnEvent) err
 	switch ev {
 	case txnCommit, txnRollback:
 		ex.extraTxnState.savepoints.clear()
+		// The deferred checks are kept when the transaction is restarted, since
+		// a restart can be caused by ROLLBACK TO SAVEPOINT, which doesn't undo
+		// statements before the savepoint. Checking a violation again is
+		// harmless even if the statement which caused it was rolled back.
+		ex.extraTxnState.deferredConstraints.reset()
 		// After txn is finished, we need to call onTxnFinish (if it's non-nil).
 		if ex.extraTxnState.onTxnFinish != nil {
 			ex.extraTxnState.onTxnFinish(ev)
@@ -2142,6 +2152,11 @@ func (ex *connExecutor) resetEvalCtx(evalCtx *extendedEvalContext, txn *kv.Txn,
 	evalCtx.Mon = ex.state.mon
 	evalCtx.PrepareOnly = false
 	evalCtx.SkipNormalize = false
+	// Internal executors run within statements of other executors, so they
+	// cannot defer checks until their own commit.
+	if ex.executorType != executorTypeInternal {
+		evalCtx.DeferredConstraints = &ex.extraTxnState.deferredConstraints
+	}
 }
 
 // getTransactionState retrieves a text representation of the given state.
@@ -702,6 +702,16 @@ func (ex *connExecutor) commitSQLTransactionInternal(
 		return err
 	}
 
+	if err := ex.extraTxnState.deferredConstraints.check(
+		ctx,
+		ex.planner.ExtendedEvalContext().InternalExecutor.(*InternalExecutor),
+		ex.state.mu.txn,
+		&ex.extraTxnState.descCollection,
+		nil, /* names */
+	); err != nil {
+		return err
+	}
+
 	if err := ex.checkDescriptorTwoVersionInvariant(ctx); err != nil {
 		return err
 	}
@@ -0,0 +1,254 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// createFunctionNode represents a CREATE FUNCTION statement.
+type createFunctionNode struct {
+	// n is the CREATE FUNCTION statement. Its name is fully qualified and its
+	// body has all table names fully qualified.
+	n      *tree.CreateFunction
+	dbDesc *dbdesc.Immutable
+
+	// planDeps tracks which tables and views the body of the function depends
+	// on. Only the descriptors are used; functions do not track the columns
+	// and indexes they reference.
+	planDeps planDependencies
+}
+
+// ReadingOwnWrites implements the planNodeReadingOwnWrites interface.
+// This is because CREATE FUNCTION performs multiple KV operations on
+// descriptors and expects to see its own writes.
+func (n *createFunctionNode) ReadingOwnWrites() {}
+
+func (n *createFunctionNode) startExec(params runParams) error {
+	if n.n.Replace {
+		telemetry.Inc(sqltelemetry.SchemaChangeCreateCounter("or_replace_function"))
+	} else {
+		telemetry.Inc(sqltelemetry.SchemaChangeCreateCounter("function"))
+	}
+
+	fnName := n.n.Name.ToTableName()
+	log.VEventf(params.ctx, 2, "dependencies for function %s:\n%s", fnName.Object(), n.planDeps.String())
+
+	// The back-references to the function are not cleaned up when a temporary
+	// table is dropped at the end of the session, so functions cannot depend on
+	// them.
+	for _, dep := range n.planDeps {
+		if dep.desc.Temporary {
+			return pgerror.Newf(pgcode.FeatureNotSupported,
+				"cannot create function %q because it depends on temporary relation %q",
+				fnName.Object(), dep.desc.Name,
+			)
+		}
+	}
+
+	args := make([]descpb.FunctionDescriptor_Argument, len(n.n.Args))
+	for i := range n.n.Args {
+		typ, err := tree.ResolveType(params.ctx, n.n.Args[i].Type, params.p.semaCtx.GetTypeResolver())
+		if err != nil {
+			return err
+		}
+		args[i] = descpb.FunctionDescriptor_Argument{Name: string(n.n.Args[i].Name), Type: typ}
+	}
+	var retType *types.T
+	if !n.n.ReturnsTrigger {
+		var err error
+		retType, err = tree.ResolveType(params.ctx, n.n.ReturnType, params.p.semaCtx.GetTypeResolver())
+		if err != nil {
+			return err
+		}
+	}
+
+	dbID := n.dbDesc.GetID()
+	_, resolvedSchema, err := params.p.ResolveUncachedSchemaDescriptor(
+		params.ctx, dbID, fnName.Schema(), true, /* required */
+	)
+	if err != nil {
+		return err
+	}
+	if resolvedSchema.Kind == catalog.SchemaTemporary {
+		return pgerror.Newf(pgcode.FeatureNotSupported,
+			"cannot create function %q in a temporary schema", fnName.Object())
+	}
+	schemaID, err := params.p.getSchemaIDForCreate(params.ctx, params.ExecCfg().Codec, dbID, fnName.Schema())
+	if err != nil {
+		return err
+	}
+	if err := params.p.canCreateOnSchema(
+		params.ctx, schemaID, dbID, params.p.User(), skipCheckPublicSchema); err != nil {
+		return err
+	}
+	if schemaID != keys.PublicSchemaID {
+		sqltelemetry.IncrementUserDefinedSchemaCounter(sqltelemetry.UserDefinedSchemaUsedByObject)
+	}
+
+	exists, collided, err := catalogkv.LookupObjectID(
+		params.ctx, params.p.txn, params.ExecCfg().Codec, dbID, schemaID, fnName.Object())
+	if err != nil {
+		return err
+	}
+
+	var fnDesc *funcdesc.Mutable
+	if exists {
+		desc, err := params.p.Descriptors().GetMutableDescriptorByID(params.ctx, collided, params.p.txn)
+		if err != nil {
+			return sqlerrors.WrapErrorWhileConstructingObjectAlreadyExistsErr(err)
+		}
+		existing, isFunc := desc.(*funcdesc.Mutable)
+		if !isFunc || !n.n.Replace {
+			return sqlerrors.MakeObjectAlreadyExistsError(desc.DescriptorProto(), fnName.FQString())
+		}
+		if err := params.p.canModifyFunction(params.ctx, existing); err != nil {
+			return err
+		}
+		if existing.ReturnsTrigger != n.n.ReturnsTrigger ||
+			(!existing.ReturnsTrigger && !existing.ReturnType.Identical(retType)) {
+			return pgerror.Newf(pgcode.InvalidFunctionDefinition,
+				"cannot change return type of existing function %q", fnName.Object())
+		}
+		if err := params.p.removeFunctionBackReferences(params.ctx, existing, n.planDeps); err != nil {
+			return err
+		}
+		existing.Args = args
+		existing.Volatility = funcdesc.VolatilityToProto(n.n.Volatility)
+		existing.Body = n.n.Body
+		existing.DependsOn = existing.DependsOn[:0]
+		for id := range n.planDeps {
+			existing.DependsOn = append(existing.DependsOn, id)
+		}
+		if err := params.p.writeFunctionDesc(params.ctx, existing); err != nil {
+			return err
+		}
+		fnDesc = existing
+	} else {
+		id, err := catalogkv.GenerateUniqueDescID(params.ctx, params.ExecCfg().DB, params.ExecCfg().Codec)
+		if err != nil {
+			return err
+		}
+		privs := descpb.NewDefaultPrivilegeDescriptor(params.p.User())
+		privs.Grant(params.p.User(), privilege.List{privilege.ALL})
+
+		fnDesc = funcdesc.NewCreatedMutable(descpb.FunctionDescriptor{
+			Name:           fnName.Object(),
+			ID:             id,
+			Version:        1,
+			Privileges:     privs,
+			ParentID:       dbID,
+			ParentSchemaID: schemaID,
+			Args:           args,
+			ReturnType:     retType,
+			ReturnsTrigger: n.n.ReturnsTrigger,
+			Volatility:     funcdesc.VolatilityToProto(n.n.Volatility),
+			Body:           n.n.Body,
+		})
+		for backRefID := range n.planDeps {
+			fnDesc.DependsOn = append(fnDesc.DependsOn, backRefID)
+		}
+
+		key := catalogkv.MakeObjectNameKey(params.ctx, params.ExecCfg().Settings, dbID, schemaID, fnName.Object())
+		if err := params.p.createDescriptorWithID(
+			params.ctx, key.Key(params.ExecCfg().Codec), id, fnDesc, params.EvalContext().Settings,
+			tree.AsStringWithFQNames(n.n, params.Ann()),
+		); err != nil {
+			return err
+		}
+	}
+
+	// Persist the back-references in all referenced table descriptors.
+	for id, dep := range n.planDeps {
+		backRefMutable := params.p.Descriptors().GetUncommittedTableByID(id)
+		if backRefMutable == nil {
+			backRefMutable = tabledesc.NewExistingMutable(*dep.desc.TableDesc())
+		}
+		backRefMutable.AddDependedOnByFunction(fnDesc.ID)
+		if err := params.p.writeSchemaChange(
+			params.ctx,
+			backRefMutable,
+			descpb.InvalidMutationID,
+			fmt.Sprintf("updating function reference %q in table %s(%d)", fnName.Object(),
+				backRefMutable.Name, backRefMutable.ID,
+			),
+		); err != nil {
+			return err
+		}
+	}
+
+	dg := catalogkv.NewOneLevelUncachedDescGetter(params.p.txn, params.ExecCfg().Codec)
+	return fnDesc.Validate(params.ctx, dg)
+}
+
+func (*createFunctionNode) Next(runParams) (bool, error) { return false, nil }
+func (*createFunctionNode) Values() tree.Datums          { return tree.Datums{} }
+func (*createFunctionNode) Close(context.Context)        {}
+
+// writeFunctionDesc writes out the given function descriptor in the current
+// transaction.
+func (p *planner) writeFunctionDesc(ctx context.Context, desc *funcdesc.Mutable) error {
+	b := p.txn.NewBatch()
+	if err := p.Descriptors().WriteDescToBatch(
+		ctx, p.extendedEvalCtx.Tracing.KVTracingEnabled(), desc, b,
+	); err != nil {
+		return err
+	}
+	return p.txn.Run(ctx, b)
+}
+
+// removeFunctionBackReferences removes the back-references to the given
+// function from the relations it depends on, except for the ones in keep.
+func (p *planner) removeFunctionBackReferences(
+	ctx context.Context, fnDesc *funcdesc.Mutable, keep planDependencies,
+) error {
+	for _, depID := range fnDesc.DependsOn {
+		if _, ok := keep[depID]; ok {
+			continue
+		}
+		dependencyDesc, err := p.Descriptors().GetMutableTableVersionByID(ctx, depID, p.txn)
+		if err != nil {
+			return err
+		}
+		// The dependency is also being deleted, so we don't have to remove the
+		// references.
+		if dependencyDesc.Dropped() {
+			continue
+		}
+		dependencyDesc.RemoveDependedOnByFunction(fnDesc.ID)
+		if err := p.writeSchemaChange(
+			ctx, dependencyDesc, descpb.InvalidMutationID,
+			fmt.Sprintf("removing references for function %s from table %s(%d)",
+				fnDesc.Name, dependencyDesc.Name, dependencyDesc.ID),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
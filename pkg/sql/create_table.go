@@ -831,6 +831,8 @@ func ResolveFK(
 		OnDelete:            descpb.ForeignKeyReferenceActionValue[d.Actions.Delete],
 		OnUpdate:            descpb.ForeignKeyReferenceActionValue[d.Actions.Update],
 		Match:               descpb.CompositeKeyMatchMethodValue[d.Match],
+		Deferrable:          d.Deferrability != tree.ConstraintNotDeferrable,
+		InitiallyDeferred:   d.Deferrability == tree.ConstraintInitiallyDeferred,
 	}
 
 	if ts == NewTable {
@@ -1476,6 +1478,24 @@ func NewTableDesc(
 				StoreColumnNames: d.Storing.ToStrings(),
 				Version:          indexEncodingVersion,
 			}
+			if d.Deferrability != tree.ConstraintNotDeferrable {
+				if d.PrimaryKey {
+					return nil, pgerror.New(pgcode.FeatureNotSupported,
+						"DEFERRABLE primary keys are not supported",
+					)
+				}
+				if d.Predicate != nil {
+					return nil, pgerror.New(pgcode.FeatureNotSupported,
+						"DEFERRABLE partial unique constraints are not supported",
+					)
+				}
+				// The index may hold duplicate values until the constraint is
+				// checked by the unique checks of the mutations, which can be
+				// deferred until the end of the transaction.
+				idx.Unique = false
+				idx.Deferrable = true
+				idx.InitiallyDeferred = d.Deferrability == tree.ConstraintInitiallyDeferred
+			}
 			if d.Sharded != nil {
 				if n.Interleave != nil && d.PrimaryKey {
 					return nil, pgerror.New(pgcode.FeatureNotSupported, "interleaved indexes cannot also be hash sharded")
@@ -0,0 +1,134 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+)
+
+type createTriggerNode struct {
+	n         *tree.CreateTrigger
+	tableDesc *tabledesc.Mutable
+	fnDesc    *funcdesc.Mutable
+}
+
+// CreateTrigger creates a row-level trigger.
+// Privileges: CREATE on table and USAGE on the trigger function.
+//   notes: postgres requires TRIGGER on the table and EXECUTE on the function.
+func (p *planner) CreateTrigger(ctx context.Context, n *tree.CreateTrigger) (planNode, error) {
+	if err := checkSchemaChangeEnabled(
+		ctx,
+		&p.ExecCfg().Settings.SV,
+		"CREATE TRIGGER",
+	); err != nil {
+		return nil, err
+	}
+
+	tableDesc, err := p.ResolveMutableTableDescriptor(
+		ctx, &n.Table, true /* required */, tree.ResolveRequireTableDesc,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.CheckPrivilege(ctx, tableDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+	// The back-references to the trigger function are not cleaned up when a
+	// temporary table is dropped at the end of the session.
+	if tableDesc.Temporary {
+		return nil, pgerror.Newf(pgcode.FeatureNotSupported,
+			"cannot create trigger on temporary table %q", tableDesc.Name)
+	}
+	if tableDesc.FindTriggerByName(string(n.Name)) != nil {
+		return nil, pgerror.Newf(pgcode.DuplicateObject,
+			"trigger %q for relation %q already exists", n.Name, tableDesc.Name)
+	}
+
+	fnName, fnDesc, err := p.ResolveMutableFunctionDescriptor(ctx, n.FuncName, true /* required */)
+	if err != nil {
+		return nil, err
+	}
+	if !fnDesc.ReturnsTrigger {
+		return nil, pgerror.Newf(pgcode.InvalidObjectDefinition,
+			"function %s must return type trigger", fnName.FQString())
+	}
+	if err := p.CheckPrivilege(ctx, fnDesc, privilege.USAGE); err != nil {
+		return nil, err
+	}
+
+	return &createTriggerNode{n: n, tableDesc: tableDesc, fnDesc: fnDesc}, nil
+}
+
+// ReadingOwnWrites implements the planNodeReadingOwnWrites interface.
+// This is because CREATE TRIGGER performs multiple KV operations on
+// descriptors and expects to see its own writes.
+func (n *createTriggerNode) ReadingOwnWrites() {}
+
+func (n *createTriggerNode) startExec(params runParams) error {
+	telemetry.Inc(sqltelemetry.SchemaChangeCreateCounter("trigger"))
+
+	trig := descpb.TableDescriptor_Trigger{
+		Name:       string(n.n.Name),
+		ActionTime: descpb.TableDescriptor_Trigger_BEFORE,
+		FunctionID: n.fnDesc.ID,
+	}
+	if n.n.ActionTime == tree.TriggerAfter {
+		trig.ActionTime = descpb.TableDescriptor_Trigger_AFTER
+	}
+	for _, e := range n.n.Events {
+		switch e {
+		case tree.TriggerInsert:
+			trig.OnInsert = true
+		case tree.TriggerUpdate:
+			trig.OnUpdate = true
+		case tree.TriggerDelete:
+			trig.OnDelete = true
+		}
+	}
+	n.tableDesc.AddTrigger(trig)
+	if err := n.tableDesc.ValidateTable(params.ctx); err != nil {
+		return err
+	}
+	if err := params.p.writeSchemaChange(
+		params.ctx, n.tableDesc, descpb.InvalidMutationID, tree.AsStringWithFQNames(n.n, params.Ann()),
+	); err != nil {
+		return err
+	}
+
+	// Persist the back-reference in the trigger function.
+	n.fnDesc.AddDependedOnByTrigger(n.tableDesc.ID)
+	if err := params.p.writeFunctionDescChange(
+		params.ctx, n.fnDesc,
+		fmt.Sprintf("updating trigger reference %q in function %s(%d)",
+			n.n.Name, n.fnDesc.Name, n.fnDesc.ID),
+	); err != nil {
+		return err
+	}
+
+	dg := catalogkv.NewOneLevelUncachedDescGetter(params.p.txn, params.ExecCfg().Codec)
+	return n.fnDesc.Validate(params.ctx, dg)
+}
+
+func (*createTriggerNode) Next(runParams) (bool, error) { return false, nil }
+func (*createTriggerNode) Values() tree.Datums          { return tree.Datums{} }
+func (*createTriggerNode) Close(context.Context)        {}
@@ -0,0 +1,456 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/exec"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+)
+
+// deferredCheckBatchSize is the maximum number of keys which are checked
+// again by a single query when deferred foreign key or uniqueness checks are
+// run.
+const deferredCheckBatchSize = 100
+
+// deferredConstraints tracks the foreign key, unique and check constraint
+// checks which have been deferred until the end of the transaction, along with
+// the checking modes set by SET CONSTRAINTS. It lives in the connExecutor's
+// extraTxnState.
+//
+// A deferred check does not fail the statement which performed the mutation;
+// instead, the key values which violated the constraint are accumulated and
+// checked again when the transaction commits (or when the constraint is set to
+// IMMEDIATE). The violation is only reported if it still exists at that time.
+type deferredConstraints struct {
+	// allMode is the mode set by SET CONSTRAINTS ALL, if any.
+	allMode constraintCheckMode
+
+	// modes are the modes set by SET CONSTRAINTS for individual constraints,
+	// keyed by constraint name. They take precedence over allMode.
+	modes map[string]constraintCheckMode
+
+	// pending are the deferred violations, grouped by constraint.
+	pending map[deferredConstraintKey][]deferredViolation
+}
+
+// constraintCheckMode is the checking mode of a deferrable constraint, as set
+// by SET CONSTRAINTS.
+type constraintCheckMode int8
+
+const (
+	// constraintCheckDefault means that the mode was not set, and the
+	// constraint is checked according to its INITIALLY DEFERRED or INITIALLY
+	// IMMEDIATE definition.
+	constraintCheckDefault constraintCheckMode = iota
+	constraintCheckImmediate
+	constraintCheckDeferred
+)
+
+// deferredConstraintKey identifies a constraint of a table (the referencing
+// table, for a foreign key).
+type deferredConstraintKey struct {
+	tableID descpb.ID
+	name    string
+}
+
+// deferredViolation is a key which violated a constraint when it was
+// modified.
+type deferredViolation struct {
+	// keyVals are the values of the constraint columns. They are nil for check
+	// constraints, which are checked again against the whole table.
+	keyVals tree.Datums
+	// err is the error which is returned if the violation still exists when
+	// the constraint is checked.
+	err error
+}
+
+// isDeferred returns true if the violations of the given check must be
+// deferred until the end of the transaction.
+func (dc *deferredConstraints) isDeferred(c *exec.DeferrableCheck) bool {
+	mode := dc.modes[c.ConstraintName]
+	if mode == constraintCheckDefault {
+		mode = dc.allMode
+	}
+	switch mode {
+	case constraintCheckImmediate:
+		return false
+	case constraintCheckDeferred:
+		return true
+	default:
+		return c.InitiallyDeferred
+	}
+}
+
+// addViolation records a violation of the given check, to be checked again
+// later. keyVals must not be modified by the caller afterwards. A violation
+// with nil keyVals stands for the whole table, so it is only recorded once.
+func (dc *deferredConstraints) addViolation(
+	c *exec.DeferrableCheck, keyVals tree.Datums, err error,
+) {
+	if dc.pending == nil {
+		dc.pending = make(map[deferredConstraintKey][]deferredViolation)
+	}
+	key := deferredConstraintKey{tableID: descpb.ID(c.TableID), name: c.ConstraintName}
+	if keyVals == nil && len(dc.pending[key]) > 0 {
+		return
+	}
+	dc.pending[key] = append(dc.pending[key], deferredViolation{keyVals: keyVals, err: err})
+}
+
+// setMode implements SET CONSTRAINTS. An empty list of names stands for ALL.
+func (dc *deferredConstraints) setMode(names tree.NameList, deferred bool) {
+	mode := constraintCheckImmediate
+	if deferred {
+		mode = constraintCheckDeferred
+	}
+	if len(names) == 0 {
+		dc.allMode = mode
+		dc.modes = nil
+		return
+	}
+	if dc.modes == nil {
+		dc.modes = make(map[string]constraintCheckMode)
+	}
+	for _, name := range names {
+		dc.modes[string(name)] = mode
+	}
+}
+
+// reset clears the state when the transaction finishes.
+func (dc *deferredConstraints) reset() {
+	*dc = deferredConstraints{}
+}
+
+// check checks again the pending violations of the constraints with the given
+// names (or all of them, if names is empty), and returns the error of the
+// first violation which still exists. The checked violations are removed from
+// the pending set.
+//
+// The queries are run with the given internal executor, which sees the
+// descriptors modified by the transaction in the given collection.
+func (dc *deferredConstraints) check(
+	ctx context.Context,
+	ie *InternalExecutor,
+	txn *kv.Txn,
+	descsCol *descs.Collection,
+	names tree.NameList,
+) error {
+	if len(dc.pending) == 0 {
+		return nil
+	}
+	var keys []deferredConstraintKey
+	for key := range dc.pending {
+		matches := len(names) == 0
+		for _, name := range names {
+			if string(name) == key.name {
+				matches = true
+				break
+			}
+		}
+		if matches {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	// Sort the constraints so that the reported violation is deterministic.
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].tableID != keys[j].tableID {
+			return keys[i].tableID < keys[j].tableID
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	ie.tcModifier = descsCol
+	defer func() {
+		ie.tcModifier = nil
+	}()
+
+	for _, key := range keys {
+		violations := dc.pending[key]
+		delete(dc.pending, key)
+		if err := checkDeferredConstraint(ctx, ie, txn, descsCol, key, violations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDeferredConstraint checks whether the given violations of a constraint
+// still exist. Violations of a constraint which was dropped in the meantime are
+// ignored.
+func checkDeferredConstraint(
+	ctx context.Context,
+	ie *InternalExecutor,
+	txn *kv.Txn,
+	descsCol *descs.Collection,
+	key deferredConstraintKey,
+	violations []deferredViolation,
+) error {
+	flags := tree.ObjectLookupFlags{CommonLookupFlags: tree.CommonLookupFlags{
+		AvoidCached:    true,
+		IncludeDropped: true,
+		IncludeOffline: true,
+	}}
+	table, err := descsCol.GetTableVersionByID(ctx, txn, key.tableID, flags)
+	if err != nil {
+		return err
+	}
+	if table.Dropped() {
+		return nil
+	}
+	for i := range table.OutboundFKs {
+		if fk := &table.OutboundFKs[i]; fk.Name == key.name {
+			referencedTable, err := descsCol.GetTableVersionByID(ctx, txn, fk.ReferencedTableID, flags)
+			if err != nil {
+				return err
+			}
+			return checkDeferredFK(ctx, ie, txn, table, referencedTable, fk, violations)
+		}
+	}
+	for _, idx := range table.AllNonDropIndexes() {
+		if idx.Deferrable && idx.Name == key.name {
+			return checkDeferredUnique(ctx, ie, txn, table, idx, violations)
+		}
+	}
+	checks := table.ActiveChecks()
+	for i := range checks {
+		if c := &checks[i]; c.Deferrable && c.Name == key.name {
+			return checkDeferredCheck(ctx, ie, txn, table, c, violations)
+		}
+	}
+	return nil
+}
+
+// checkDeferredFK checks whether the given violations of a foreign key
+// constraint still exist. A violation exists if there is still a row in the
+// origin table with the key values, but no row in the referenced table.
+func checkDeferredFK(
+	ctx context.Context,
+	ie *InternalExecutor,
+	txn *kv.Txn,
+	originTable, referencedTable catalog.TableDescriptor,
+	fk *descpb.ForeignKeyConstraint,
+	violations []deferredViolation,
+) error {
+	return checkDeferredKeys(ctx, ie, txn, "deferred fk check", violations,
+		func(numKeys int) (string, error) {
+			return deferredFKCheckQuery(originTable, referencedTable, fk, numKeys)
+		},
+	)
+}
+
+// checkDeferredUnique checks whether the given violations of a deferrable
+// unique constraint still exist. A violation exists if there are still
+// several rows in the table with the key values.
+func checkDeferredUnique(
+	ctx context.Context,
+	ie *InternalExecutor,
+	txn *kv.Txn,
+	table catalog.TableDescriptor,
+	idx *descpb.IndexDescriptor,
+	violations []deferredViolation,
+) error {
+	return checkDeferredKeys(ctx, ie, txn, "deferred unique check", violations,
+		func(numKeys int) (string, error) {
+			return deferredUniqueCheckQuery(table, idx, numKeys)
+		},
+	)
+}
+
+// checkDeferredCheck checks whether a deferrable check constraint is still
+// violated by a row of the table. The error of the first recorded violation is
+// returned if so.
+func checkDeferredCheck(
+	ctx context.Context,
+	ie *InternalExecutor,
+	txn *kv.Txn,
+	table catalog.TableDescriptor,
+	c *descpb.TableDescriptor_CheckConstraint,
+	violations []deferredViolation,
+) error {
+	query := fmt.Sprintf(`SELECT 1 FROM [%d AS t] WHERE NOT (%s) LIMIT 1`, table.GetID(), c.Expr)
+	row, err := ie.QueryRowEx(
+		ctx, "deferred check constraint", txn,
+		sessiondata.InternalExecutorOverride{User: security.RootUserName()},
+		query,
+	)
+	if err != nil {
+		return err
+	}
+	if row != nil {
+		return violations[0].err
+	}
+	return nil
+}
+
+// checkDeferredKeys checks the given violations in batches, using queries
+// generated by mkQuery which return the index of the first violating key.
+func checkDeferredKeys(
+	ctx context.Context,
+	ie *InternalExecutor,
+	txn *kv.Txn,
+	opName string,
+	violations []deferredViolation,
+	mkQuery func(numKeys int) (string, error),
+) error {
+	for len(violations) > 0 {
+		batch := violations
+		if len(batch) > deferredCheckBatchSize {
+			batch = batch[:deferredCheckBatchSize]
+		}
+		violations = violations[len(batch):]
+
+		query, err := mkQuery(len(batch))
+		if err != nil {
+			return err
+		}
+		args := make([]interface{}, 0, len(batch)*(len(batch[0].keyVals)+1))
+		for i := range batch {
+			args = append(args, tree.NewDInt(tree.DInt(i)))
+			for _, d := range batch[i].keyVals {
+				args = append(args, d)
+			}
+		}
+		row, err := ie.QueryRowEx(
+			ctx, opName, txn,
+			sessiondata.InternalExecutorOverride{User: security.RootUserName()},
+			query, args...,
+		)
+		if err != nil {
+			return err
+		}
+		if row != nil {
+			return batch[int(tree.MustBeDInt(row[0]))].err
+		}
+	}
+	return nil
+}
+
+// deferredKeyValues returns the VALUES clause of a deferred check query for
+// the given number of keys, each of which has nCols values preceded by its
+// index, and the names of the key columns (k1, k2, ...).
+func deferredKeyValues(numKeys, nCols int) (values string, valueCols []string) {
+	rows := make([]string, numKeys)
+	placeholder := 1
+	for i := range rows {
+		vals := make([]string, nCols+1)
+		for j := range vals {
+			vals[j] = fmt.Sprintf("$%d", placeholder)
+			placeholder++
+		}
+		rows[i] = fmt.Sprintf("(%s)", strings.Join(vals, ", "))
+	}
+	valueCols = make([]string, nCols)
+	for i := range valueCols {
+		valueCols[i] = fmt.Sprintf("k%d", i+1)
+	}
+	return strings.Join(rows, ", "), valueCols
+}
+
+// deferredUniqueCheckQuery generates a query which returns the index of the
+// first of the given number of keys which violates the unique constraint of
+// the given index. For example, a unique constraint on columns (a, b) of the
+// table "t" would require the following query for two keys:
+//
+//	SELECT v.i FROM (VALUES ($1, $2, $3), ($4, $5, $6)) AS v (i, k1, k2)
+//	WHERE (SELECT count(*) FROM t WHERE t.a = v.k1 AND t.b = v.k2) > 1
+//	ORDER BY v.i LIMIT 1
+func deferredUniqueCheckQuery(
+	table catalog.TableDescriptor, idx *descpb.IndexDescriptor, numKeys int,
+) (string, error) {
+	colNames, err := table.NamesForColumnIDs(idx.ColumnIDs)
+	if err != nil {
+		return "", err
+	}
+	values, valueCols := deferredKeyValues(numKeys, len(colNames))
+	where := make([]string, len(colNames))
+	for i := range colNames {
+		where[i] = fmt.Sprintf("t.%s = v.%s", tree.NameString(colNames[i]), valueCols[i])
+	}
+
+	return fmt.Sprintf(
+		`SELECT v.i FROM (VALUES %[1]s) AS v (i, %[2]s)
+		 WHERE (SELECT count(*) FROM [%[3]d AS t] WHERE %[4]s) > 1
+		 ORDER BY v.i LIMIT 1`,
+		values,                        // 1
+		strings.Join(valueCols, ", "), // 2
+		table.GetID(),                 // 3
+		strings.Join(where, " AND "),  // 4
+	), nil
+}
+
+// deferredFKCheckQuery generates a query which returns the index of the first
+// of the given number of keys which violates the foreign key constraint. For
+// example, a FK constraint on columns (a_id, b_id) on the table "child",
+// referencing columns (a, b) on the table "parent", would require the
+// following query for two keys:
+//
+//	SELECT v.i FROM (VALUES ($1, $2, $3), ($4, $5, $6)) AS v (i, k1, k2)
+//	WHERE EXISTS (SELECT 1 FROM child AS o
+//	              WHERE o.a_id IS NOT DISTINCT FROM v.k1 AND o.b_id IS NOT DISTINCT FROM v.k2)
+//	  AND NOT EXISTS (SELECT 1 FROM parent AS r WHERE r.a = v.k1 AND r.b = v.k2)
+//	ORDER BY v.i LIMIT 1
+//
+// Keys with NULL values can only be pending for MATCH FULL constraints, in
+// which case they are never satisfied by the referenced table.
+func deferredFKCheckQuery(
+	originTable, referencedTable catalog.TableDescriptor,
+	fk *descpb.ForeignKeyConstraint,
+	numKeys int,
+) (string, error) {
+	originColNames, err := originTable.NamesForColumnIDs(fk.OriginColumnIDs)
+	if err != nil {
+		return "", err
+	}
+	referencedColNames, err := referencedTable.NamesForColumnIDs(fk.ReferencedColumnIDs)
+	if err != nil {
+		return "", err
+	}
+	nCols := len(originColNames)
+
+	values, valueCols := deferredKeyValues(numKeys, nCols)
+	originWhere := make([]string, nCols)
+	referencedWhere := make([]string, nCols)
+	for i := 0; i < nCols; i++ {
+		originWhere[i] = fmt.Sprintf(
+			"o.%s IS NOT DISTINCT FROM v.%s", tree.NameString(originColNames[i]), valueCols[i],
+		)
+		referencedWhere[i] = fmt.Sprintf(
+			"r.%s = v.%s", tree.NameString(referencedColNames[i]), valueCols[i],
+		)
+	}
+
+	return fmt.Sprintf(
+		`SELECT v.i FROM (VALUES %[1]s) AS v (i, %[2]s)
+		 WHERE EXISTS (SELECT 1 FROM [%[3]d AS o] WHERE %[4]s)
+		   AND NOT EXISTS (SELECT 1 FROM [%[5]d AS r] WHERE %[6]s)
+		 ORDER BY v.i LIMIT 1`,
+		values,                                 // 1
+		strings.Join(valueCols, ", "),          // 2
+		originTable.GetID(),                    // 3
+		strings.Join(originWhere, " AND "),     // 4
+		referencedTable.GetID(),                // 5
+		strings.Join(referencedWhere, " AND "), // 6
+	), nil
+}
@@ -52,7 +52,7 @@ type deleteRun struct {
 
 	// partialIndexDelValsOffset is the offset of partial index delete
 	// indicators in the source values. It is equal to the number of fetched
-	// columns.
+	// columns plus the number of passthrough columns.
 	partialIndexDelValsOffset int
 
 	// rowIdxToRetIdx is the mapping from the columns returned by the deleter
@@ -61,6 +61,11 @@ type deleteRun struct {
 	// of the mutation. Otherwise, the value at the i-th index refers to the
 	// index of the resultRowBuffer where the i-th column is to be returned.
 	rowIdxToRetIdx []int
+
+	// numPassthrough is the number of columns in addition to the set of
+	// columns of the target table being returned, that we must pass through
+	// from the input node.
+	numPassthrough int
 }
 
 func (d *deleteNode) startExec(params runParams) error {
@@ -153,6 +158,11 @@ func (d *deleteNode) BatchedNext(params runParams) (bool, error) {
 // processSourceRow processes one row from the source for deletion and, if
 // result rows are needed, saves it in the result row container
 func (d *deleteNode) processSourceRow(params runParams, sourceVals tree.Datums) error {
+	// The values of the passthrough columns, if any, follow the fetched
+	// columns in the source values.
+	numFetchCols := len(d.run.td.rd.FetchCols)
+	passthroughValues := sourceVals[numFetchCols : numFetchCols+d.run.numPassthrough]
+
 	// Create a set of partial index IDs to not delete from. Indexes should not
 	// be deleted from when they are partial indexes and the row does not
 	// satisfy the predicate and therefore do not exist in the partial index.
@@ -166,12 +176,12 @@ func (d *deleteNode) processSourceRow(params runParams, sourceVals tree.Datums)
 		if err != nil {
 			return err
 		}
-
-		// Truncate sourceVals so that it no longer includes partial index
-		// predicate values.
-		sourceVals = sourceVals[:d.run.partialIndexDelValsOffset]
 	}
 
+	// Truncate sourceVals so that it no longer includes passthrough values or
+	// partial index predicate values.
+	sourceVals = sourceVals[:numFetchCols]
+
 	// Queue the deletion in the KV batch.
 	if err := d.run.td.row(params.ctx, sourceVals, pm, d.run.traceKV); err != nil {
 		return err
@@ -193,6 +203,12 @@ func (d *deleteNode) processSourceRow(params runParams, sourceVals tree.Datums)
 			}
 		}
 
+		// At this point we've extracted all the RETURNING values that are part
+		// of the target table. We must now extract the columns in the RETURNING
+		// clause that refer to other tables (from the USING clause of the
+		// delete), which are always the last result columns.
+		copy(resultValues[len(resultValues)-d.run.numPassthrough:], passthroughValues)
+
 		if _, err := d.run.td.rows.AddRow(params.ctx, resultValues); err != nil {
 			return err
 		}
@@ -3528,7 +3528,8 @@ func (dsp *DistSQLPlanner) createPlanForWindow(
 }
 
 // createPlanForExport creates a physical plan for EXPORT.
-// We add a new stage of CSVWriter processors to the input plan.
+// We add a new stage of CSVWriter processors, which write either CSV or
+// Parquet files, to the input plan.
 func (dsp *DistSQLPlanner) createPlanForExport(
 	planCtx *PlanningCtx, n *exportNode,
 ) (*PhysicalPlan, error) {
@@ -3543,6 +3544,9 @@ func (dsp *DistSQLPlanner) createPlanForExport(
 		ChunkRows:        int64(n.chunkRows),
 		CompressionCodec: n.fileCompression,
 		UserProto:        planCtx.planner.User().EncodeProto(),
+		Format:           n.fileFormat,
+		ColNames:         n.colNames,
+		PartitionCols:    n.partitionCols,
 	}}
 
 	resTypes := make([]*types.T, len(colinfo.ExportColumns))
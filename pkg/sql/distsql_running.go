@@ -1016,6 +1016,15 @@ func (dsp *DistSQLPlanner) PlanAndRunCascadesAndChecks(
 			}
 		}
 
+		if plan.cascades[i].TriggerPlanFn != nil {
+			log.VEventf(ctx, 1, "executing trigger %s", plan.cascades[i].TriggerName)
+			if err := dsp.planAndRunTrigger(ctx, planner, evalCtxFactory, plan, i, recv); err != nil {
+				recv.SetError(err)
+				return false
+			}
+			continue
+		}
+
 		log.VEventf(ctx, 1, "executing cascade for constraint %s", plan.cascades[i].FKName)
 
 		// We place a sequence point before every cascade, so
@@ -1117,6 +1126,88 @@ func (dsp *DistSQLPlanner) PlanAndRunCascadesAndChecks(
 	return true
 }
 
+// planAndRunTrigger runs the queries for the AFTER trigger plan.cascades[idx],
+// once for each row stored in the trigger's buffer. Like cascades, the trigger
+// queries can generate more cascades and checks; these are appended to
+// plan.cascades and plan.checkPlans.
+//
+// The query is planned separately for every row (see exec.Cascade), and each
+// plan is closed as soon as it has run, so that only one of them is open at a
+// time.
+func (dsp *DistSQLPlanner) planAndRunTrigger(
+	ctx context.Context,
+	planner *planner,
+	evalCtxFactory func() *extendedEvalContext,
+	plan *planComponents,
+	idx int,
+	recv *DistSQLReceiver,
+) error {
+	rows := plan.cascades[idx].Buffer.(*bufferNode).bufferedRows
+	for r, numRows := 0, rows.Len(); r < numRows; r++ {
+		// We place a sequence point before every execution of the trigger, so
+		// that it observes the writes of the previous executions.
+		_ = planner.Txn().ConfigureStepping(ctx, kv.SteppingEnabled)
+		if err := planner.Txn().Step(ctx); err != nil {
+			return err
+		}
+
+		evalCtx := evalCtxFactory()
+		execFactory := newExecFactory(planner)
+		// The trigger query is allowed to autocommit only if it is the last one
+		// to run and there are no check queries to run.
+		allowAutoCommit := planner.autoCommit
+		if len(plan.checkPlans) > 0 || idx < len(plan.cascades)-1 || r < numRows-1 {
+			allowAutoCommit = false
+		}
+		triggerPlan, err := plan.cascades[idx].TriggerPlanFn(
+			ctx, &planner.semaCtx, &evalCtx.EvalContext, execFactory, rows.At(r), allowAutoCommit,
+		)
+		if err != nil {
+			return err
+		}
+		cp := triggerPlan.(*planComponents)
+
+		// Queue any new cascades and collect any new checks; the outer plan is
+		// responsible for closing them.
+		plan.cascades = append(plan.cascades, cp.cascades...)
+		plan.checkPlans = append(plan.checkPlans, cp.checkPlans...)
+		cp.cascades, cp.checkPlans = nil, nil
+
+		if limit := evalCtx.SessionData.OptimizerFKCascadesLimit; len(plan.cascades) > limit {
+			cp.close(ctx)
+			telemetry.Inc(sqltelemetry.CascadesLimitReached)
+			return pgerror.Newf(pgcode.TriggeredActionException, "cascades limit (%d) reached", limit)
+		}
+
+		// The trigger query can contain subqueries (e.g. in the trigger function
+		// body); they need to be evaluated before the main query, and their
+		// results must be visible through the planner while it runs.
+		outerSubqueries := planner.curPlan.subqueryPlans
+		planner.curPlan.subqueryPlans = cp.subqueryPlans
+		err = func() error {
+			defer func() {
+				planner.curPlan.subqueryPlans = outerSubqueries
+				cp.close(ctx)
+			}()
+			if len(cp.subqueryPlans) > 0 {
+				if !dsp.PlanAndRunSubqueries(
+					ctx, planner, evalCtxFactory, cp.subqueryPlans, recv,
+				) {
+					if recv.commErr != nil {
+						return recv.commErr
+					}
+					return recv.resultWriter.Err()
+				}
+			}
+			return dsp.planAndRunPostquery(ctx, cp.main, planner, evalCtxFactory(), recv)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // planAndRunPostquery runs a cascade or check query.
 func (dsp *DistSQLPlanner) planAndRunPostquery(
 	ctx context.Context,
@@ -17,6 +17,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
@@ -34,6 +35,7 @@ type dropCascadeState struct {
 	td                      []toDelete
 	allTableObjectsToDelete []*tabledesc.Mutable
 	typesToDelete           []*typedesc.Mutable
+	functionsToDelete       []*funcdesc.Mutable
 
 	droppedNames []string
 }
@@ -132,9 +134,44 @@ func (d *dropCascadeState) resolveCollectedObjects(
 					return err
 				}
 			}
+			if err := p.canRemoveDependentFunctions(ctx, tbDesc, tree.DropCascade); err != nil {
+				return err
+			}
 			d.td = append(d.td, toDelete{objName, tbDesc})
+			continue
+		}
+		// If we couldn't resolve objName as a table, try a function.
+		found, desc, err = p.LookupObject(
+			ctx,
+			tree.ObjectLookupFlags{
+				CommonLookupFlags: tree.CommonLookupFlags{
+					Required:       false,
+					RequireMutable: true,
+					IncludeOffline: true,
+				},
+				DesiredObjectKind: tree.FunctionObject,
+			},
+			objName.Catalog(),
+			objName.Schema(),
+			objName.Object(),
+		)
+		if err != nil {
+			return err
+		}
+		if found {
+			fnDesc, ok := desc.(*funcdesc.Mutable)
+			if !ok {
+				return errors.AssertionFailedf(
+					"descriptor for %q is not Mutable",
+					objName.Object(),
+				)
+			}
+			if err := p.canModifyFunction(ctx, fnDesc); err != nil {
+				return err
+			}
+			d.functionsToDelete = append(d.functionsToDelete, fnDesc)
 		} else {
-			// If we couldn't resolve objName as a table, try a type.
+			// If we couldn't resolve objName as a table or a function, try a type.
 			found, desc, err := p.LookupObject(
 				ctx,
 				tree.ObjectLookupFlags{
@@ -180,6 +217,18 @@ func (d *dropCascadeState) resolveCollectedObjects(
 }
 
 func (d *dropCascadeState) dropAllCollectedObjects(ctx context.Context, p *planner) error {
+	// Delete all of the collected functions first, so that the tables they
+	// depend on no longer reference them.
+	for _, fn := range d.functionsToDelete {
+		if fn.Dropped() {
+			continue
+		}
+		if err := p.dropFunctionImpl(ctx, fn, "dropping function"); err != nil {
+			return err
+		}
+		d.droppedNames = append(d.droppedNames, fn.Name)
+	}
+
 	// Delete all of the collected tables.
 	for _, toDel := range d.td {
 		desc := toDel.desc
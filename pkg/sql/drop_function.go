@@ -0,0 +1,303 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+)
+
+type dropFunctionNode struct {
+	n   *tree.DropFunction
+	fns []*funcdesc.Mutable
+}
+
+// DropFunction drops user defined functions.
+// Privileges: ownership of the function.
+func (p *planner) DropFunction(ctx context.Context, n *tree.DropFunction) (planNode, error) {
+	if err := checkSchemaChangeEnabled(
+		ctx,
+		&p.ExecCfg().Settings.SV,
+		"DROP FUNCTION",
+	); err != nil {
+		return nil, err
+	}
+
+	// Only triggers can depend on a function; they are dropped along with it
+	// under CASCADE.
+	node := &dropFunctionNode{n: n}
+	seen := make(map[descpb.ID]struct{}, len(n.Functions))
+	for i := range n.Functions {
+		fn := &n.Functions[i]
+		fnName, fnDesc, err := p.ResolveMutableFunctionDescriptor(ctx, fn.Name, !n.IfExists)
+		if err != nil {
+			return nil, err
+		}
+		if fnDesc == nil {
+			continue
+		}
+		if fn.Args != nil {
+			matches, err := p.functionArgsMatch(ctx, fnDesc, fn.Args)
+			if err != nil {
+				return nil, err
+			}
+			if !matches {
+				if n.IfExists {
+					continue
+				}
+				return nil, sqlerrors.NewUndefinedFunctionError(fnName)
+			}
+		}
+		if _, ok := seen[fnDesc.ID]; ok {
+			continue
+		}
+		seen[fnDesc.ID] = struct{}{}
+		if err := p.canModifyFunction(ctx, fnDesc); err != nil {
+			return nil, err
+		}
+		if err := p.canRemoveDependentTriggers(ctx, fnDesc, n.DropBehavior); err != nil {
+			return nil, err
+		}
+		node.fns = append(node.fns, fnDesc)
+	}
+	if len(node.fns) == 0 {
+		return newZeroNode(nil /* columns */), nil
+	}
+	return node, nil
+}
+
+// ReadingOwnWrites implements the planNodeReadingOwnWrites interface.
+// This is because DROP FUNCTION performs multiple KV operations on descriptors
+// and expects to see its own writes.
+func (n *dropFunctionNode) ReadingOwnWrites() {}
+
+func (n *dropFunctionNode) startExec(params runParams) error {
+	telemetry.Inc(sqltelemetry.SchemaChangeDropCounter("function"))
+	for _, fnDesc := range n.fns {
+		if err := params.p.dropFunctionImpl(
+			params.ctx, fnDesc, tree.AsStringWithFQNames(n.n, params.Ann()),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*dropFunctionNode) Next(runParams) (bool, error) { return false, nil }
+func (*dropFunctionNode) Values() tree.Datums          { return tree.Datums{} }
+func (*dropFunctionNode) Close(context.Context)        {}
+
+// functionArgsMatch returns whether the argument types of the function are
+// identical to the given types.
+func (p *planner) functionArgsMatch(
+	ctx context.Context, fnDesc *funcdesc.Mutable, args []tree.ResolvableTypeReference,
+) (bool, error) {
+	if len(args) != len(fnDesc.Args) {
+		return false, nil
+	}
+	for i := range args {
+		typ, err := tree.ResolveType(ctx, args[i], p.semaCtx.GetTypeResolver())
+		if err != nil {
+			return false, err
+		}
+		if !typ.Identical(fnDesc.Args[i].Type) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// canModifyFunction returns an error if the current user is neither an admin
+// nor the owner of the function.
+func (p *planner) canModifyFunction(ctx context.Context, desc *funcdesc.Mutable) error {
+	hasAdmin, err := p.HasAdminRole(ctx)
+	if err != nil {
+		return err
+	}
+	if hasAdmin {
+		return nil
+	}
+
+	hasOwnership, err := p.HasOwnership(ctx, desc)
+	if err != nil {
+		return err
+	}
+	if !hasOwnership {
+		return pgerror.Newf(pgcode.InsufficientPrivilege,
+			"must be owner of function %s", tree.Name(desc.GetName()))
+	}
+	return nil
+}
+
+// dropFunctionImpl does the work of dropping a function. The back-references
+// to it are removed from the relations it depends on, the triggers executing
+// it are dropped, and a job is queued to drain its name and delete the
+// descriptor.
+func (p *planner) dropFunctionImpl(
+	ctx context.Context, fnDesc *funcdesc.Mutable, jobDesc string,
+) error {
+	if fnDesc.Dropped() {
+		return errors.Errorf("function %q is already being dropped", fnDesc.Name)
+	}
+	if err := p.removeFunctionBackReferences(ctx, fnDesc, nil /* keep */); err != nil {
+		return err
+	}
+	fnDesc.DependsOn = nil
+	if err := p.dropDependentTriggers(ctx, fnDesc, jobDesc); err != nil {
+		return err
+	}
+
+	fnDesc.DrainingNames = append(fnDesc.DrainingNames, descpb.NameInfo{
+		ParentID:       fnDesc.ParentID,
+		ParentSchemaID: fnDesc.ParentSchemaID,
+		Name:           fnDesc.Name,
+	})
+	fnDesc.SetDropped()
+	return p.writeFunctionDescChange(ctx, fnDesc, jobDesc)
+}
+
+// writeFunctionDescChange writes out the given function descriptor and queues
+// a job to wait for the change to propagate. Dropped functions are deleted by
+// the job.
+func (p *planner) writeFunctionDescChange(
+	ctx context.Context, desc *funcdesc.Mutable, jobDesc string,
+) error {
+	job, jobExists := p.extendedEvalCtx.SchemaChangeJobCache[desc.ID]
+	if jobExists {
+		// Update it.
+		if err := job.WithTxn(p.txn).SetDescription(ctx,
+			func(ctx context.Context, desc string) (string, error) {
+				return desc + "; " + jobDesc, nil
+			},
+		); err != nil {
+			return err
+		}
+		log.Infof(ctx, "job %d: updated with for change on function %d", *job.ID(), desc.ID)
+	} else {
+		// Or, create a new job.
+		jobRecord := jobs.Record{
+			Description:   jobDesc,
+			Username:      p.User(),
+			DescriptorIDs: descpb.IDs{desc.ID},
+			Details: jobspb.SchemaChangeDetails{
+				DescID: desc.ID,
+				// The version distinction for database jobs doesn't matter for
+				// function jobs.
+				FormatVersion: jobspb.DatabaseJobFormatVersion,
+			},
+			Progress: jobspb.SchemaChangeProgress{},
+		}
+		newJob, err := p.extendedEvalCtx.QueueJob(jobRecord)
+		if err != nil {
+			return err
+		}
+		log.Infof(ctx, "queued new schema change job %d for function %d", *newJob.ID(), desc.ID)
+	}
+
+	return p.writeFunctionDesc(ctx, desc)
+}
+
+// canRemoveDependentFunctions returns an error if functions depend on the
+// given relation and the drop behavior is not CASCADE. Otherwise, it checks
+// that the current user can drop all of the dependent functions.
+func (p *planner) canRemoveDependentFunctions(
+	ctx context.Context, tableDesc *tabledesc.Mutable, behavior tree.DropBehavior,
+) error {
+	for _, fnID := range tableDesc.DependedOnByFunctions {
+		fnDesc, err := p.Descriptors().GetMutableFunctionVersionByID(ctx, p.txn, fnID)
+		if err != nil {
+			return errors.Wrapf(err, "error resolving dependent function ID %d", fnID)
+		}
+		if behavior != tree.DropCascade {
+			return p.dependentFunctionError(ctx, tableDesc.TypeName(), tableDesc.Name, fnDesc, "drop")
+		}
+		if err := p.canModifyFunction(ctx, fnDesc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropDependentFunctions drops all of the functions which depend on the given
+// relation, assuming that we wouldn't have made it to this point if `cascade`
+// wasn't enabled. It returns the names of the dropped functions.
+func (p *planner) dropDependentFunctions(
+	ctx context.Context, tableDesc *tabledesc.Mutable,
+) ([]string, error) {
+	var droppedFunctions []string
+	for _, fnID := range append([]descpb.ID(nil), tableDesc.DependedOnByFunctions...) {
+		fnDesc, err := p.Descriptors().GetMutableFunctionVersionByID(ctx, p.txn, fnID)
+		if err != nil {
+			return droppedFunctions, errors.Wrapf(err, "error resolving dependent function ID %d", fnID)
+		}
+		// This function is already getting dropped. Don't do it twice.
+		if fnDesc.Dropped() {
+			continue
+		}
+		if err := p.dropFunctionImpl(ctx, fnDesc, "dropping dependent function"); err != nil {
+			return droppedFunctions, err
+		}
+		droppedFunctions = append(droppedFunctions, fnDesc.Name)
+	}
+	tableDesc.DependedOnByFunctions = nil
+	return droppedFunctions, nil
+}
+
+// dependentFunctionError returns an error for an operation which cannot be
+// performed on a relation because the given function depends on it.
+func (p *planner) dependentFunctionError(
+	ctx context.Context, typeName, objName string, fnDesc *funcdesc.Mutable, op string,
+) error {
+	fnName, err := p.getQualifiedFunctionName(ctx, fnDesc)
+	if err != nil {
+		log.Warningf(ctx, "unable to retrieve name of function %d: %v", fnDesc.ID, err)
+		return sqlerrors.NewDependentObjectErrorf(
+			"cannot %s %s %q because a function depends on it",
+			op, typeName, objName)
+	}
+	return errors.WithHintf(
+		sqlerrors.NewDependentObjectErrorf("cannot %s %s %q because function %q depends on it",
+			op, typeName, objName, fnName.FQString()),
+		"you can drop %s instead.", fnName.FQString())
+}
+
+// getQualifiedFunctionName returns the fully qualified name of the function.
+func (p *planner) getQualifiedFunctionName(
+	ctx context.Context, desc *funcdesc.Mutable,
+) (*tree.FunctionName, error) {
+	dbDesc, err := catalogkv.MustGetDatabaseDescByID(ctx, p.txn, p.ExecCfg().Codec, desc.GetParentID())
+	if err != nil {
+		return nil, err
+	}
+	schemaName, err := resolver.ResolveSchemaNameByID(
+		ctx, p.txn, p.ExecCfg().Codec, desc.GetParentID(), desc.GetParentSchemaID(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	fnName := tree.MakeQualifiedFunctionName(dbDesc.GetName(), schemaName, desc.GetName())
+	return &fnName, nil
+}
@@ -55,6 +55,9 @@ func (p *planner) DropSequence(ctx context.Context, n *tree.DropSequence) (planN
 		if depErr := p.sequenceDependencyError(ctx, droppedDesc); depErr != nil {
 			return nil, depErr
 		}
+		if err := p.canRemoveDependentFunctions(ctx, droppedDesc, n.DropBehavior); err != nil {
+			return nil, err
+		}
 
 		td = append(td, toDelete{tn, droppedDesc})
 	}
@@ -121,6 +124,9 @@ func (p *planner) dropSequenceImpl(
 	if err := removeSequenceOwnerIfExists(ctx, p, seqDesc.ID, seqDesc.GetSequenceOpts()); err != nil {
 		return err
 	}
+	if _, err := p.dropDependentFunctions(ctx, seqDesc); err != nil {
+		return err
+	}
 	return p.initiateDropTable(ctx, seqDesc, queueJob, jobDesc, true /* drainName */)
 }
 
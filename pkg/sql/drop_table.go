@@ -98,6 +98,9 @@ func (p *planner) DropTable(ctx context.Context, n *tree.DropTable) (planNode, e
 		if err := p.canRemoveAllTableOwnedSequences(ctx, droppedDesc, n.DropBehavior); err != nil {
 			return nil, err
 		}
+		if err := p.canRemoveDependentFunctions(ctx, droppedDesc, n.DropBehavior); err != nil {
+			return nil, err
+		}
 
 	}
 
@@ -344,10 +347,21 @@ func (p *planner) dropTableImpl(
 		droppedViews = append(droppedViews, viewDesc.Name)
 	}
 
-	err := p.removeTableComments(ctx, tableDesc)
+	// Remove the back-references from the functions executed by triggers.
+	if err := p.removeTriggerBackReferences(ctx, tableDesc); err != nil {
+		return droppedViews, err
+	}
+
+	// Drop all functions that depend on this table.
+	droppedFunctions, err := p.dropDependentFunctions(ctx, tableDesc)
 	if err != nil {
 		return droppedViews, err
 	}
+	droppedViews = append(droppedViews, droppedFunctions...)
+
+	if err := p.removeTableComments(ctx, tableDesc); err != nil {
+		return droppedViews, err
+	}
 
 	// Remove any references to types that this table has if a job is meant to be
 	// queued. If not, then the job that is handling the drop table will also
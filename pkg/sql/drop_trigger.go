@@ -0,0 +1,193 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/errors"
+)
+
+type dropTriggerNode struct {
+	n         *tree.DropTrigger
+	tableDesc *tabledesc.Mutable
+}
+
+// DropTrigger drops a trigger.
+// Privileges: CREATE on table.
+func (p *planner) DropTrigger(ctx context.Context, n *tree.DropTrigger) (planNode, error) {
+	if err := checkSchemaChangeEnabled(
+		ctx,
+		&p.ExecCfg().Settings.SV,
+		"DROP TRIGGER",
+	); err != nil {
+		return nil, err
+	}
+
+	tableDesc, err := p.ResolveMutableTableDescriptor(
+		ctx, &n.Table, !n.IfExists, tree.ResolveRequireTableDesc,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if tableDesc == nil {
+		return newZeroNode(nil /* columns */), nil
+	}
+	if err := p.CheckPrivilege(ctx, tableDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+	if tableDesc.FindTriggerByName(string(n.Name)) == nil {
+		if n.IfExists {
+			return newZeroNode(nil /* columns */), nil
+		}
+		return nil, pgerror.Newf(pgcode.UndefinedObject,
+			"trigger %q for table %q does not exist", n.Name, tableDesc.Name)
+	}
+
+	// Nothing can depend on a trigger, so RESTRICT and CASCADE behave the same
+	// way.
+	return &dropTriggerNode{n: n, tableDesc: tableDesc}, nil
+}
+
+// ReadingOwnWrites implements the planNodeReadingOwnWrites interface.
+// This is because DROP TRIGGER performs multiple KV operations on descriptors
+// and expects to see its own writes.
+func (n *dropTriggerNode) ReadingOwnWrites() {}
+
+func (n *dropTriggerNode) startExec(params runParams) error {
+	telemetry.Inc(sqltelemetry.SchemaChangeDropCounter("trigger"))
+
+	fnID := n.tableDesc.FindTriggerByName(string(n.n.Name)).FunctionID
+	n.tableDesc.RemoveTrigger(string(n.n.Name))
+	if err := params.p.writeSchemaChange(
+		params.ctx, n.tableDesc, descpb.InvalidMutationID, tree.AsStringWithFQNames(n.n, params.Ann()),
+	); err != nil {
+		return err
+	}
+	return params.p.maybeRemoveTriggerBackReference(params.ctx, n.tableDesc, fnID)
+}
+
+func (*dropTriggerNode) Next(runParams) (bool, error) { return false, nil }
+func (*dropTriggerNode) Values() tree.Datums          { return tree.Datums{} }
+func (*dropTriggerNode) Close(context.Context)        {}
+
+// maybeRemoveTriggerBackReference removes the back-reference from the given
+// trigger function to the table, unless another trigger of the table still
+// executes the function.
+func (p *planner) maybeRemoveTriggerBackReference(
+	ctx context.Context, tableDesc *tabledesc.Mutable, fnID descpb.ID,
+) error {
+	for i := range tableDesc.Triggers {
+		if tableDesc.Triggers[i].FunctionID == fnID {
+			return nil
+		}
+	}
+	fnDesc, err := p.Descriptors().GetMutableFunctionVersionByID(ctx, p.txn, fnID)
+	if err != nil {
+		return errors.Wrapf(err, "error resolving trigger function ID %d", fnID)
+	}
+	// The function is being dropped along with its back-references.
+	if fnDesc.Dropped() {
+		return nil
+	}
+	fnDesc.RemoveDependedOnByTrigger(tableDesc.ID)
+	return p.writeFunctionDescChange(ctx, fnDesc,
+		fmt.Sprintf("removing trigger reference to table %s(%d) in function %s(%d)",
+			tableDesc.Name, tableDesc.ID, fnDesc.Name, fnDesc.ID),
+	)
+}
+
+// removeTriggerBackReferences removes the back-references from the functions
+// executed by the triggers of the given table, which is being dropped.
+func (p *planner) removeTriggerBackReferences(
+	ctx context.Context, tableDesc *tabledesc.Mutable,
+) error {
+	triggers := tableDesc.Triggers
+	tableDesc.Triggers = nil
+	for i := range triggers {
+		if err := p.maybeRemoveTriggerBackReference(ctx, tableDesc, triggers[i].FunctionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canRemoveDependentTriggers returns an error if triggers execute the given
+// function and the drop behavior is not CASCADE. Otherwise, it checks that the
+// current user can drop all of the dependent triggers.
+func (p *planner) canRemoveDependentTriggers(
+	ctx context.Context, fnDesc *funcdesc.Mutable, behavior tree.DropBehavior,
+) error {
+	for _, tableID := range fnDesc.DependedOnByTriggers {
+		tableDesc, err := p.Descriptors().GetMutableTableVersionByID(ctx, tableID, p.txn)
+		if err != nil {
+			return errors.Wrapf(err, "error resolving dependent table ID %d", tableID)
+		}
+		if behavior != tree.DropCascade {
+			for i := range tableDesc.Triggers {
+				if trig := &tableDesc.Triggers[i]; trig.FunctionID == fnDesc.ID {
+					return errors.WithHintf(
+						sqlerrors.NewDependentObjectErrorf(
+							"cannot drop function %q because trigger %q on table %q depends on it",
+							fnDesc.Name, trig.Name, tableDesc.Name),
+						"you can drop trigger %s instead.", tree.ErrNameString(trig.Name))
+				}
+			}
+			return errors.AssertionFailedf(
+				"table %q has no trigger executing function %q", tableDesc.Name, fnDesc.Name)
+		}
+		if err := p.CheckPrivilege(ctx, tableDesc, privilege.CREATE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropDependentTriggers drops all of the triggers which execute the given
+// function, assuming that we wouldn't have made it to this point if `cascade`
+// wasn't enabled.
+func (p *planner) dropDependentTriggers(
+	ctx context.Context, fnDesc *funcdesc.Mutable, jobDesc string,
+) error {
+	for _, tableID := range fnDesc.DependedOnByTriggers {
+		tableDesc, err := p.Descriptors().GetMutableTableVersionByID(ctx, tableID, p.txn)
+		if err != nil {
+			return errors.Wrapf(err, "error resolving dependent table ID %d", tableID)
+		}
+		// The table is being dropped along with its triggers.
+		if tableDesc.Dropped() {
+			continue
+		}
+		triggers := tableDesc.Triggers[:0]
+		for _, trig := range tableDesc.Triggers {
+			if trig.FunctionID != fnDesc.ID {
+				triggers = append(triggers, trig)
+			}
+		}
+		tableDesc.Triggers = triggers
+		if err := p.writeSchemaChange(ctx, tableDesc, descpb.InvalidMutationID, jobDesc); err != nil {
+			return err
+		}
+	}
+	fnDesc.DependedOnByTriggers = nil
+	return nil
+}
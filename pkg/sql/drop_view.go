@@ -76,6 +76,9 @@ func (p *planner) DropView(ctx context.Context, n *tree.DropView) (planNode, err
 				return nil, err
 			}
 		}
+		if err := p.canRemoveDependentFunctions(ctx, droppedDesc, n.DropBehavior); err != nil {
+			return nil, err
+		}
 	}
 
 	if len(td) == 0 {
@@ -236,6 +239,11 @@ func (p *planner) dropViewImpl(
 			cascadeDroppedViews = append(cascadeDroppedViews, cascadedViews...)
 			cascadeDroppedViews = append(cascadeDroppedViews, dependentDesc.Name)
 		}
+		droppedFunctions, err := p.dropDependentFunctions(ctx, viewDesc)
+		if err != nil {
+			return cascadeDroppedViews, err
+		}
+		cascadeDroppedViews = append(cascadeDroppedViews, droppedFunctions...)
 	}
 
 	// Remove any references to types that this view has.
@@ -26,6 +26,11 @@ type errorIfRowsNode struct {
 	// produced.
 	mkErr exec.MkErrFn
 
+	// deferrable is set if the errors can be deferred until the end of the
+	// transaction, in which case the rows are recorded as pending violations
+	// instead (see deferredConstraints).
+	deferrable *exec.DeferrableCheck
+
 	nexted bool
 }
 
@@ -43,10 +48,34 @@ func (n *errorIfRowsNode) Next(params runParams) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if ok {
-		return false, n.mkErr(n.plan.Values())
+	if !ok {
+		return false, nil
+	}
+	if n.deferrable != nil {
+		if dc := params.extendedEvalCtx.DeferredConstraints; dc != nil && dc.isDeferred(n.deferrable) {
+			return false, n.deferViolations(params, dc)
+		}
+	}
+	return false, n.mkErr(n.plan.Values())
+}
+
+// deferViolations records all the rows produced by the wrapped node as pending
+// violations of the deferred constraint.
+func (n *errorIfRowsNode) deferViolations(params runParams, dc *deferredConstraints) error {
+	for ok := true; ok; {
+		row := n.plan.Values()
+		keyVals := make(tree.Datums, len(n.deferrable.KeyCols))
+		for i, col := range n.deferrable.KeyCols {
+			keyVals[i] = row[col]
+		}
+		dc.addViolation(n.deferrable, keyVals, n.mkErr(row))
+
+		var err error
+		if ok, err = n.plan.Next(params); err != nil {
+			return err
+		}
 	}
-	return false, nil
+	return nil
 }
 
 func (n *errorIfRowsNode) Values() tree.Datums {
@@ -40,9 +40,15 @@ type exportNode struct {
 	// fileNamePattern represents the file naming pattern for the
 	// export, typically to be appended to the destination URI
 	fileNamePattern string
+	fileFormat      roachpb.IOFileFormat_FileFormat
 	csvOpts         roachpb.CSVOptions
 	chunkRows       int
 	fileCompression execinfrapb.FileCompression
+	// colNames are the names of the columns of source.
+	colNames []string
+	// partitionCols are the ordinals of the columns of source by whose values
+	// the exported files are partitioned.
+	partitionCols []uint32
 }
 
 func (e *exportNode) startExec(params runParams) error {
@@ -77,10 +83,13 @@ var exportOptionExpectValues = map[string]KVStringOptValidate{
 	exportOptionCompression: KVStringOptRequireValue,
 }
 
+// exportCSVOnlyOptions are the options which only apply to CSV exports.
+var exportCSVOnlyOptions = []string{exportOptionDelimiter, exportOptionNullAs}
+
 const exportChunkRowsDefault = 100000
 const exportFilePatternPart = "%part%"
-const exportFilePatternDefault = exportFilePatternPart + ".csv"
 const exportCompressionCodec = "gzip"
+const exportParquetCompressionCodec = "snappy"
 
 // featureExportEnabled is used to enable and disable the EXPORT feature.
 var featureExportEnabled = settings.RegisterPublicBoolSetting(
@@ -90,7 +99,11 @@ var featureExportEnabled = settings.RegisterPublicBoolSetting(
 
 // ConstructExport is part of the exec.Factory interface.
 func (ef *execFactory) ConstructExport(
-	input exec.Node, fileName tree.TypedExpr, fileFormat string, options []exec.KVOption,
+	input exec.Node,
+	fileName tree.TypedExpr,
+	fileFormat string,
+	options []exec.KVOption,
+	partitionCols []exec.NodeColumnOrdinal,
 ) (exec.Node, error) {
 	if !featureExportEnabled.Get(&ef.planner.ExecCfg().Settings.SV) {
 		return nil, pgerror.Newf(
@@ -112,7 +125,14 @@ func (ef *execFactory) ConstructExport(
 		return nil, errors.Errorf("EXPORT cannot be used inside a transaction")
 	}
 
-	if fileFormat != "CSV" {
+	var format roachpb.IOFileFormat_FileFormat
+	var fileExtension string
+	switch fileFormat {
+	case "CSV":
+		format, fileExtension = roachpb.IOFileFormat_CSV, ".csv"
+	case "PARQUET":
+		format, fileExtension = roachpb.IOFileFormat_Parquet, ".parquet"
+	default:
 		return nil, errors.Errorf("unsupported export format: %q", fileFormat)
 	}
 
@@ -146,6 +166,15 @@ func (ef *execFactory) ConstructExport(
 		return nil, err
 	}
 
+	if format != roachpb.IOFileFormat_CSV {
+		for _, opt := range exportCSVOnlyOptions {
+			if _, ok := optVals[opt]; ok {
+				return nil, pgerror.Newf(pgcode.InvalidParameterValue,
+					"%s option is not supported for %s export", opt, fileFormat)
+			}
+		}
+	}
+
 	csvOpts := roachpb.CSVOptions{}
 
 	if override, ok := optVals[exportOptionDelimiter]; ok {
@@ -171,26 +200,48 @@ func (ef *execFactory) ConstructExport(
 	}
 
 	// Check whenever compression is expected and extract compression codec name in case
-	// of positive result
+	// of positive result. Parquet files are compressed internally, and
+	// additionally support snappy compression.
 	var codec execinfrapb.FileCompression
 	if name, ok := optVals[exportOptionCompression]; ok && len(name) != 0 {
-		if strings.EqualFold(name, exportCompressionCodec) {
+		switch {
+		case strings.EqualFold(name, exportCompressionCodec):
 			codec = execinfrapb.FileCompression_Gzip
-		} else {
+		case strings.EqualFold(name, exportParquetCompressionCodec) && format == roachpb.IOFileFormat_Parquet:
+			codec = execinfrapb.FileCompression_Snappy
+		default:
 			return nil, pgerror.Newf(pgcode.InvalidParameterValue,
 				"unsupported compression codec %s", name)
 		}
 	}
 
+	source := input.(planNode)
+	cols := planColumns(source)
+	colNames := make([]string, len(cols))
+	for i := range cols {
+		colNames[i] = cols[i].Name
+	}
+	partitionOrds := make([]uint32, len(partitionCols))
+	for i, ord := range partitionCols {
+		partitionOrds[i] = uint32(ord)
+	}
+	if len(partitionOrds) == len(cols) {
+		return nil, pgerror.New(pgcode.InvalidParameterValue,
+			"EXPORT cannot partition by all exported columns")
+	}
+
 	exportID := ef.planner.stmt.QueryID.String()
-	namePattern := fmt.Sprintf("export%s-%s", exportID, exportFilePatternDefault)
+	namePattern := fmt.Sprintf("export%s-%s%s", exportID, exportFilePatternPart, fileExtension)
 
 	return &exportNode{
-		source:          input.(planNode),
+		source:          source,
 		destination:     string(*destination),
 		fileNamePattern: namePattern,
+		fileFormat:      format,
 		csvOpts:         csvOpts,
 		chunkRows:       chunkRows,
 		fileCompression: codec,
+		colNames:        colNames,
+		partitionCols:   partitionOrds,
 	}, nil
 }
@@ -1370,6 +1370,7 @@ CREATE TABLE information_schema.table_constraints (
 				tbNameStr := tree.NewDString(table.GetName())
 
 				for conName, c := range conInfo {
+					deferrable, initiallyDeferred := c.Deferrability()
 					if err := addRow(
 						dbNameStr,                       // constraint_catalog
 						scNameStr,                       // constraint_schema
@@ -1378,8 +1379,8 @@ CREATE TABLE information_schema.table_constraints (
 						scNameStr,                       // table_schema
 						tbNameStr,                       // table_name
 						tree.NewDString(string(c.Kind)), // constraint_type
-						yesOrNoDatum(false),             // is_deferrable
-						yesOrNoDatum(false),             // initially_deferred
+						yesOrNoDatum(deferrable),        // is_deferrable
+						yesOrNoDatum(initiallyDeferred), // initially_deferred
 					); err != nil {
 						return err
 					}
@@ -149,6 +149,7 @@ func (r *insertRun) processSourceRow(params runParams, rowVals tree.Datums) erro
 		checkVals := rowVals[len(r.insertCols):]
 		if err := checkMutationInput(
 			params.ctx, &params.p.semaCtx, r.ti.tableDesc(), r.checkOrds, checkVals,
+			params.extendedEvalCtx.DeferredConstraints,
 		); err != nil {
 			return err
 		}
@@ -91,6 +91,8 @@ func buildOpaque(
 		plan, err = p.CreateIndex(ctx, n)
 	case *tree.CreateSchema:
 		plan, err = p.CreateSchema(ctx, n)
+	case *tree.CreateTrigger:
+		plan, err = p.CreateTrigger(ctx, n)
 	case *tree.CreateType:
 		plan, err = p.CreateType(ctx, n)
 	case *tree.CreateRole:
@@ -107,6 +109,8 @@ func buildOpaque(
 		plan, err = p.Discard(ctx, n)
 	case *tree.DropDatabase:
 		plan, err = p.DropDatabase(ctx, n)
+	case *tree.DropFunction:
+		plan, err = p.DropFunction(ctx, n)
 	case *tree.DropIndex:
 		plan, err = p.DropIndex(ctx, n)
 	case *tree.DropOwnedBy:
@@ -119,6 +123,8 @@ func buildOpaque(
 		plan, err = p.DropSequence(ctx, n)
 	case *tree.DropTable:
 		plan, err = p.DropTable(ctx, n)
+	case *tree.DropTrigger:
+		plan, err = p.DropTrigger(ctx, n)
 	case *tree.DropType:
 		plan, err = p.DropType(ctx, n)
 	case *tree.DropView:
@@ -151,6 +157,8 @@ func buildOpaque(
 		plan, err = p.Scrub(ctx, n)
 	case *tree.SetClusterSetting:
 		plan, err = p.SetClusterSetting(ctx, n)
+	case *tree.SetConstraints:
+		plan, err = p.SetConstraints(ctx, n)
 	case *tree.SetZoneConfig:
 		plan, err = p.SetZoneConfig(ctx, n)
 	case *tree.SetVar:
@@ -224,17 +232,20 @@ func init() {
 		&tree.CreateSchema{},
 		&tree.CreateSequence{},
 		&tree.CreateStats{},
+		&tree.CreateTrigger{},
 		&tree.CreateType{},
 		&tree.CreateRole{},
 		&tree.Deallocate{},
 		&tree.Discard{},
 		&tree.DropDatabase{},
+		&tree.DropFunction{},
 		&tree.DropIndex{},
 		&tree.DropOwnedBy{},
 		&tree.DropRole{},
 		&tree.DropSchema{},
 		&tree.DropSequence{},
 		&tree.DropTable{},
+		&tree.DropTrigger{},
 		&tree.DropType{},
 		&tree.DropView{},
 		&tree.Grant{},
@@ -251,6 +262,7 @@ func init() {
 		&tree.Scatter{},
 		&tree.Scrub{},
 		&tree.SetClusterSetting{},
+		&tree.SetConstraints{},
 		&tree.SetZoneConfig{},
 		&tree.SetVar{},
 		&tree.SetTransaction{},
@@ -117,6 +117,21 @@ type Catalog interface {
 		ctx context.Context, flags Flags, id StableID,
 	) (_ DataSource, isAdding bool, _ error)
 
+	// ResolveFunction locates a user-defined function with the given name,
+	// searching the current search path if the name is not qualified.
+	//
+	// If no such function exists, then ResolveFunction returns nil and no error.
+	//
+	// NOTE: The returned function must be immutable after construction, and so
+	// can be safely copied or used across goroutines.
+	ResolveFunction(ctx context.Context, flags Flags, name *tree.UnresolvedObjectName) (Function, error)
+
+	// ResolveFunctionByID locates the user-defined function with the given ID.
+	// It is used to resolve the functions executed by triggers. If no such
+	// function exists, then ResolveFunctionByID returns an error with code
+	// UndefinedFunction.
+	ResolveFunctionByID(ctx context.Context, id StableID) (Function, error)
+
 	// ResolveTypeByOID is used to look up a user defined type by ID.
 	ResolveTypeByOID(ctx context.Context, oid oid.Oid) (*types.T, error)
 
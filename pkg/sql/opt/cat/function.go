@@ -0,0 +1,52 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cat
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+)
+
+// Function is an interface to a user-defined function written in SQL.
+type Function interface {
+	Object
+
+	// Name returns the fully qualified name of the function.
+	Name() *tree.FunctionName
+
+	// ArgCount returns the number of arguments of the function.
+	ArgCount() int
+
+	// ArgName returns the name of the ith argument. It is empty if the argument
+	// can only be referenced positionally, as $1, $2, etc.
+	ArgName(i int) tree.Name
+
+	// ArgType returns the type of the ith argument.
+	ArgType(i int) *types.T
+
+	// ReturnType returns the type of the value returned by the function. It is
+	// nil for trigger functions.
+	ReturnType() *types.T
+
+	// IsTrigger returns true if the function was declared as RETURNS TRIGGER.
+	// Trigger functions take no arguments and can only be executed by
+	// triggers (see Table.Trigger).
+	IsTrigger() bool
+
+	// Volatility returns the volatility the function was declared with.
+	Volatility() tree.Volatility
+
+	// Body returns the SELECT statement implementing the function. All data
+	// sources referenced by it are fully qualified. The body of a trigger
+	// function can also be an INSERT, UPSERT, UPDATE or DELETE statement, and
+	// its data sources are resolved when the trigger fires.
+	Body() string
+}
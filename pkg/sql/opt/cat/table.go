@@ -131,6 +131,15 @@ type Table interface {
 	// Unique returns the ith unique constraint defined on this table, where
 	// i < UniqueCount.
 	Unique(i int) UniqueConstraint
+
+	// TriggerCount returns the number of row-level triggers defined on this
+	// table.
+	TriggerCount() int
+
+	// Trigger returns the ith trigger defined on this table, where
+	// i < TriggerCount. Triggers are ordered by name, which is the order in
+	// which they fire.
+	Trigger(i int) Trigger
 }
 
 // CheckConstraint contains the SQL text and the validity status for a check
@@ -140,9 +149,37 @@ type Table interface {
 //
 //   CREATE TABLE a (a INT CHECK (a > 0))
 //
+//
+// A DEFERRABLE check constraint is never Validated, since its violations can
+// be deferred until the end of the transaction.
 type CheckConstraint struct {
 	Constraint string
 	Validated  bool
+	Deferrable bool
+}
+
+// Trigger describes a row-level trigger on a table. A trigger executes a
+// trigger function for each row inserted, updated or deleted by a mutation,
+// either before or after the row is modified. For example, this trigger calls
+// the audit function after each row of table t is modified:
+//
+//   CREATE TRIGGER t_audit AFTER INSERT OR UPDATE OR DELETE ON t
+//     FOR EACH ROW EXECUTE FUNCTION audit()
+//
+type Trigger struct {
+	Name       string
+	ActionTime tree.TriggerActionTime
+	Events     tree.TriggerEvents
+
+	// FunctionID is the stable identifier of the trigger function executed by
+	// the trigger. See Catalog.ResolveFunctionByID.
+	FunctionID StableID
+}
+
+// FiresOn returns true if the trigger fires at the given time for the given
+// event.
+func (t *Trigger) FiresOn(time tree.TriggerActionTime, event tree.TriggerEvent) bool {
+	return t.ActionTime == time && t.Events.Contains(event)
 }
 
 // TableStatistic is an interface to a table statistic. Each statistic is
@@ -244,6 +281,14 @@ type ForeignKeyConstraint interface {
 	// UpdateReferenceAction returns the action to be performed if the foreign key
 	// constraint would be violated by an update.
 	UpdateReferenceAction() tree.ReferenceAction
+
+	// Deferrable is true if the checks of the constraint can be deferred until
+	// the end of the transaction (see SET CONSTRAINTS).
+	Deferrable() bool
+
+	// InitiallyDeferred is true if the checks of the constraint are deferred
+	// until the end of the transaction by default.
+	InitiallyDeferred() bool
 }
 
 // UniqueConstraint represents a uniqueness constraint. UniqueConstraints may
@@ -277,4 +322,12 @@ type UniqueConstraint interface {
 	// cannot make any assumptions about the data. An unvalidated constraint still
 	// needs to be enforced on new mutations.
 	Validated() bool
+
+	// Deferrable is true if the checks of the constraint can be deferred until
+	// the end of the transaction (see SET CONSTRAINTS).
+	Deferrable() bool
+
+	// InitiallyDeferred is true if the checks of the constraint are deferred
+	// until the end of the transaction by default.
+	InitiallyDeferred() bool
 }
@@ -162,20 +162,41 @@ func FormatTable(cat Catalog, tab Table, tp treeprinter.Node) {
 	}
 
 	for i := 0; i < tab.UniqueCount(); i++ {
-		var withoutIndexStr string
-		if tab.Unique(i).WithoutIndex() {
+		var withoutIndexStr, deferrableStr string
+		if tab.Unique(i).Deferrable() {
+			// A deferrable constraint is backed by a non-unique index.
+			deferrableStr = " DEFERRABLE"
+		} else if tab.Unique(i).WithoutIndex() {
 			withoutIndexStr = "WITHOUT INDEX "
 		}
 		child.Childf(
-			"UNIQUE %s%s",
+			"UNIQUE %s%s%s",
 			withoutIndexStr,
 			formatCols(tab, tab.Unique(i).ColumnCount(), tab.Unique(i).ColumnOrdinal),
+			deferrableStr,
 		)
 	}
 
+	for i := 0; i < tab.TriggerCount(); i++ {
+		formatCatalogTrigger(cat, tab.Trigger(i), child)
+	}
+
 	// TODO(radu): show stats.
 }
 
+// formatCatalogTrigger nicely formats a catalog trigger using a treeprinter
+// for debugging and testing.
+func formatCatalogTrigger(catalog Catalog, trig Trigger, tp treeprinter.Node) {
+	fn, err := catalog.ResolveFunctionByID(context.TODO(), trig.FunctionID)
+	if err != nil {
+		panic(err)
+	}
+	tp.Childf(
+		"TRIGGER %s %s %s FOR EACH ROW EXECUTE FUNCTION %s()",
+		trig.Name, trig.ActionTime, tree.AsString(&trig.Events), fn.Name().Object(),
+	)
+}
+
 // formatCatalogIndex nicely formats a catalog index using a treeprinter for
 // debugging and testing.
 func formatCatalogIndex(tab Table, ord int, tp treeprinter.Node) {
@@ -293,6 +293,90 @@ func (cb *cascadeBuilder) planCascade(
 	return plan, nil
 }
 
+// setupTrigger fills in an exec.Cascade struct for the given AFTER trigger.
+func (cb *cascadeBuilder) setupTrigger(trigger *memo.Trigger) exec.Cascade {
+	return exec.Cascade{
+		TriggerName: trigger.Name,
+		Buffer:      cb.mutationBuffer,
+		TriggerPlanFn: func(
+			ctx context.Context,
+			semaCtx *tree.SemaContext,
+			evalCtx *tree.EvalContext,
+			execFactory exec.Factory,
+			row tree.Datums,
+			allowAutoCommit bool,
+		) (exec.Plan, error) {
+			return cb.planTrigger(ctx, semaCtx, evalCtx, execFactory, trigger, row, allowAutoCommit)
+		},
+	}
+}
+
+// planTrigger is used to plan the query executed by an AFTER trigger for a
+// single row of the mutation input. Like planCascade, it is run by the
+// execution logic (through exec.Cascade.TriggerPlanFn) after the main query was
+// executed.
+//
+// The values of the row are built into the query as constants, so the query is
+// built and optimized again for every modified row. This makes statements that
+// fire triggers on many rows proportionally more expensive to plan; the
+// execution logic closes each plan as soon as it has run, so that the memory
+// they use does not also grow with the number of rows.
+func (cb *cascadeBuilder) planTrigger(
+	ctx context.Context,
+	semaCtx *tree.SemaContext,
+	evalCtx *tree.EvalContext,
+	execFactory exec.Factory,
+	trigger *memo.Trigger,
+	row tree.Datums,
+	allowAutoCommit bool,
+) (exec.Plan, error) {
+	// Look up the values of the modified row in the buffered row.
+	rowValues := func(cols opt.ColList) (tree.Datums, error) {
+		if len(cols) == 0 {
+			return nil, nil
+		}
+		res := make(tree.Datums, len(cols))
+		for i, col := range cols {
+			ord, ok := cb.mutationBufferCols.Get(int(col))
+			if !ok {
+				return nil, errors.AssertionFailedf("column %d not in mutation buffer", col)
+			}
+			res[i] = row[ord]
+		}
+		return res, nil
+	}
+	oldVals, err := rowValues(trigger.OldValues)
+	if err != nil {
+		return nil, err
+	}
+	newVals, err := rowValues(trigger.NewValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var o xform.Optimizer
+	o.Init(evalCtx, cb.b.catalog)
+	relExpr, err := trigger.Builder.Build(
+		ctx, semaCtx, evalCtx, cb.b.catalog, o.Factory(), oldVals, newVals,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while building query for trigger %s", trigger.Name)
+	}
+	o.Memo().SetRoot(relExpr, &physical.Required{})
+
+	optimizedExpr, err := o.Optimize()
+	if err != nil {
+		return nil, errors.Wrapf(err, "while optimizing query for trigger %s", trigger.Name)
+	}
+
+	eb := New(execFactory, o.Memo(), cb.b.catalog, optimizedExpr, evalCtx, allowAutoCommit)
+	plan, err := eb.Build()
+	if err != nil {
+		return nil, errors.Wrapf(err, "while building plan for trigger %s", trigger.Name)
+	}
+	return plan, nil
+}
+
 // Remap columns according to a ColMap.
 func remapColumns(cols opt.ColList, m opt.ColMap) (opt.ColList, error) {
 	res := make(opt.ColList, len(cols))
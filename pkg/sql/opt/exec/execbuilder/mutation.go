@@ -104,7 +104,8 @@ func (b *Builder) buildInsert(ins *memo.InsertExpr) (execPlan, error) {
 		returnOrds,
 		checkOrds,
 		b.allowAutoCommit && len(ins.UniqueChecks) == 0 &&
-			len(ins.FKChecks) == 0 && len(ins.FKCascades) == 0,
+			len(ins.FKChecks) == 0 && len(ins.FKCascades) == 0 && len(ins.Triggers) == 0 &&
+			!hasDeferrableChecks(tab),
 	)
 	if err != nil {
 		return execPlan{}, err
@@ -115,12 +116,18 @@ func (b *Builder) buildInsert(ins *memo.InsertExpr) (execPlan, error) {
 		ep.outputCols = mutationOutputColMap(ins)
 	}
 
-	// TODO(rytaft): build unique checks.
+	if err := b.buildUniqueChecks(ins.UniqueChecks); err != nil {
+		return execPlan{}, err
+	}
 
 	if err := b.buildFKChecks(ins.FKChecks); err != nil {
 		return execPlan{}, err
 	}
 
+	if err := b.buildTriggers(ins.WithID, ins.Triggers); err != nil {
+		return execPlan{}, err
+	}
+
 	return ep, nil
 }
 
@@ -148,14 +155,33 @@ func (b *Builder) tryBuildFastPathInsert(ins *memo.InsertExpr) (_ execPlan, ok b
 		return execPlan{}, false, nil
 	}
 
+	//  - there are no AFTER triggers (which run after the mutation);
+	if len(ins.Triggers) > 0 {
+		return execPlan{}, false, nil
+	}
+
+	//  - there are no unique checks;
+	if len(ins.UniqueChecks) > 0 {
+		return execPlan{}, false, nil
+	}
+
 	md := b.mem.Metadata()
 	tab := md.Table(ins.Table)
 
+	//  - there are no deferrable check constraints (see hasDeferrableChecks);
+	if hasDeferrableChecks(tab) {
+		return execPlan{}, false, nil
+	}
+
 	//  - there are no self-referencing foreign keys;
+	//  - there are no deferrable foreign keys;
 	//  - all FK checks can be performed using direct lookups into unique indexes.
 	fkChecks := make([]exec.InsertFastPathFKCheck, len(ins.FKChecks))
 	for i := range ins.FKChecks {
 		c := &ins.FKChecks[i]
+		if c.Deferrable {
+			return execPlan{}, false, nil
+		}
 		if md.Table(c.ReferencedTable).ID() == md.Table(ins.Table).ID() {
 			// Self-referencing FK.
 			return execPlan{}, false, nil
@@ -331,13 +357,16 @@ func (b *Builder) buildUpdate(upd *memo.UpdateExpr) (execPlan, error) {
 		checkOrds,
 		passthroughCols,
 		b.allowAutoCommit && len(upd.UniqueChecks) == 0 &&
-			len(upd.FKChecks) == 0 && len(upd.FKCascades) == 0,
+			len(upd.FKChecks) == 0 && len(upd.FKCascades) == 0 && len(upd.Triggers) == 0 &&
+			!hasDeferrableChecks(tab),
 	)
 	if err != nil {
 		return execPlan{}, err
 	}
 
-	// TODO(rytaft): build unique checks.
+	if err := b.buildUniqueChecks(upd.UniqueChecks); err != nil {
+		return execPlan{}, err
+	}
 
 	if err := b.buildFKChecks(upd.FKChecks); err != nil {
 		return execPlan{}, err
@@ -347,6 +376,10 @@ func (b *Builder) buildUpdate(upd *memo.UpdateExpr) (execPlan, error) {
 		return execPlan{}, err
 	}
 
+	if err := b.buildTriggers(upd.WithID, upd.Triggers); err != nil {
+		return execPlan{}, err
+	}
+
 	// Construct the output column map.
 	ep := execPlan{root: node}
 	if upd.NeedResults() {
@@ -415,13 +448,16 @@ func (b *Builder) buildUpsert(ups *memo.UpsertExpr) (execPlan, error) {
 		returnColOrds,
 		checkOrds,
 		b.allowAutoCommit && len(ups.UniqueChecks) == 0 &&
-			len(ups.FKChecks) == 0 && len(ups.FKCascades) == 0,
+			len(ups.FKChecks) == 0 && len(ups.FKCascades) == 0 && len(ups.Triggers) == 0 &&
+			!hasDeferrableChecks(tab),
 	)
 	if err != nil {
 		return execPlan{}, err
 	}
 
-	// TODO(rytaft): build unique checks.
+	if err := b.buildUniqueChecks(ups.UniqueChecks); err != nil {
+		return execPlan{}, err
+	}
 
 	if err := b.buildFKChecks(ups.FKChecks); err != nil {
 		return execPlan{}, err
@@ -431,6 +467,10 @@ func (b *Builder) buildUpsert(ups *memo.UpsertExpr) (execPlan, error) {
 		return execPlan{}, err
 	}
 
+	if err := b.buildTriggers(ups.WithID, ups.Triggers); err != nil {
+		return execPlan{}, err
+	}
+
 	// If UPSERT returns rows, they contain all non-mutation columns from the
 	// table, in the same order they're defined in the table. Each output column
 	// value is taken from an insert, fetch, or update column, depending on the
@@ -452,8 +492,15 @@ func (b *Builder) buildDelete(del *memo.DeleteExpr) (execPlan, error) {
 	//
 	// TODO(andyk): Using ensureColumns here can result in an extra Render.
 	// Upgrade execution engine to not require this.
-	colList := make(opt.ColList, 0, len(del.FetchCols)+len(del.PartialIndexDelCols))
+	colList := make(opt.ColList, 0, len(del.FetchCols)+len(del.PassthroughCols)+len(del.PartialIndexDelCols))
 	colList = appendColsWhenPresent(colList, del.FetchCols)
+	// The RETURNING clause of the Delete can refer to the columns
+	// in any of the USING tables. As a result, the Delete may need
+	// to passthrough those columns so the projection above can use
+	// them.
+	if del.NeedResults() {
+		colList = appendColsWhenPresent(colList, del.PassthroughCols)
+	}
 	colList = appendColsWhenPresent(colList, del.PartialIndexDelCols)
 
 	input, err := b.buildMutationInput(del, del.Input, colList, &del.MutationPrivate)
@@ -466,12 +513,24 @@ func (b *Builder) buildDelete(del *memo.DeleteExpr) (execPlan, error) {
 	tab := md.Table(del.Table)
 	fetchColOrds := ordinalSetFromColList(del.FetchCols)
 	returnColOrds := ordinalSetFromColList(del.ReturnCols)
+
+	// Construct the result columns for the passthrough set.
+	var passthroughCols colinfo.ResultColumns
+	if del.NeedResults() {
+		for _, passthroughCol := range del.PassthroughCols {
+			colMeta := b.mem.Metadata().ColumnMeta(passthroughCol)
+			passthroughCols = append(passthroughCols, colinfo.ResultColumn{Name: colMeta.Alias, Typ: colMeta.Type})
+		}
+	}
+
 	node, err := b.factory.ConstructDelete(
 		input.root,
 		tab,
 		fetchColOrds,
 		returnColOrds,
-		b.allowAutoCommit && len(del.FKChecks) == 0 && len(del.FKCascades) == 0,
+		passthroughCols,
+		b.allowAutoCommit && len(del.FKChecks) == 0 && len(del.FKCascades) == 0 &&
+			len(del.Triggers) == 0,
 	)
 	if err != nil {
 		return execPlan{}, err
@@ -485,6 +544,10 @@ func (b *Builder) buildDelete(del *memo.DeleteExpr) (execPlan, error) {
 		return execPlan{}, err
 	}
 
+	if err := b.buildTriggers(del.WithID, del.Triggers); err != nil {
+		return execPlan{}, err
+	}
+
 	// Construct the output column map.
 	ep := execPlan{root: node}
 	if del.NeedResults() {
@@ -645,6 +708,11 @@ func (b *Builder) tryBuildDeleteRangeOnInterleaving(
 		}
 	}
 
+	// AFTER triggers need the deleted rows, which DeleteRange does not provide.
+	if len(del.Triggers) > 0 {
+		return execPlan{}, false, nil
+	}
+
 	ep, err := b.buildDeleteRange(del, queue[1:])
 	if err != nil {
 		return execPlan{}, false, err
@@ -769,6 +837,109 @@ func mutationOutputColMap(mutation memo.RelExpr) opt.ColMap {
 	return colMap
 }
 
+// hasDeferrableChecks returns true if the table has a DEFERRABLE check
+// constraint. A mutation of such a table must not auto-commit, since the
+// violations of a deferred check constraint are only reported when the
+// transaction commits (after the mutation).
+func hasDeferrableChecks(tab cat.Table) bool {
+	for i, n := 0, tab.CheckCount(); i < n; i++ {
+		if tab.Check(i).Deferrable {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Builder) buildUniqueChecks(checks memo.UniqueChecksExpr) error {
+	md := b.mem.Metadata()
+	for i := range checks {
+		c := &checks[i]
+		// Construct the query that returns uniqueness violations.
+		query, err := b.buildRelational(c.Check)
+		if err != nil {
+			return err
+		}
+		// Wrap the query in an error node.
+		mkErr := func(row tree.Datums) error {
+			keyVals := make(tree.Datums, len(c.KeyCols))
+			for i, col := range c.KeyCols {
+				keyVals[i] = row[query.getNodeColumnOrdinal(col)]
+			}
+			return mkUniqueCheckErr(md, c, keyVals)
+		}
+		var deferrable *exec.DeferrableCheck
+		if c.Deferrable {
+			deferrable = makeDeferrableUniqueCheck(md, c, query)
+		}
+		node, err := b.factory.ConstructErrorIfRows(query.root, mkErr, deferrable)
+		if err != nil {
+			return err
+		}
+		b.checks = append(b.checks, node)
+	}
+	return nil
+}
+
+// makeDeferrableUniqueCheck describes the unique constraint checked by a
+// deferrable unique check, and the columns of the check query which contain
+// the values of the constrained columns.
+func makeDeferrableUniqueCheck(
+	md *opt.Metadata, c *memo.UniqueChecksItem, query execPlan,
+) *exec.DeferrableCheck {
+	u := md.Table(c.Table).Unique(c.CheckOrdinal)
+	// The KeyCols also contain the primary key columns, which are not part of
+	// the constraint.
+	keyCols := make([]exec.NodeColumnOrdinal, u.ColumnCount())
+	for i := range keyCols {
+		keyCols[i] = query.getNodeColumnOrdinal(c.KeyCols[i])
+	}
+	return &exec.DeferrableCheck{
+		TableID:           u.TableID(),
+		ConstraintName:    u.Name(),
+		InitiallyDeferred: u.InitiallyDeferred(),
+		KeyCols:           keyCols,
+	}
+}
+
+// mkUniqueCheckErr generates a user-friendly error describing a uniqueness
+// violation. The keyVals are the values that correspond to the
+// cat.UniqueConstraint columns.
+func mkUniqueCheckErr(md *opt.Metadata, c *memo.UniqueChecksItem, keyVals tree.Datums) error {
+	// Generate an error of the form:
+	//   ERROR:  duplicate key value violates unique constraint "foo"
+	//   DETAIL: Key (k)=(2) already exists.
+	tabMeta := md.TableMeta(c.Table)
+	u := tabMeta.Table.Unique(c.CheckOrdinal)
+	var msg, details bytes.Buffer
+	msg.WriteString("duplicate key value violates unique constraint ")
+	lexbase.EncodeEscapedSQLIdent(&msg, u.Name())
+
+	details.WriteString("Key (")
+	for i := 0; i < u.ColumnCount(); i++ {
+		if i > 0 {
+			details.WriteString(", ")
+		}
+		col := tabMeta.Table.Column(u.ColumnOrdinal(tabMeta.Table, i))
+		details.WriteString(string(col.ColName()))
+	}
+	details.WriteString(")=(")
+	for i := 0; i < u.ColumnCount(); i++ {
+		if i > 0 {
+			details.WriteString(", ")
+		}
+		details.WriteString(keyVals[i].String())
+	}
+	details.WriteString(") already exists.")
+
+	return errors.WithDetail(
+		pgerror.WithConstraintName(
+			pgerror.Newf(pgcode.UniqueViolation, "%s", msg.String()),
+			u.Name(),
+		),
+		details.String(),
+	)
+}
+
 func (b *Builder) buildFKChecks(checks memo.FKChecksExpr) error {
 	md := b.mem.Metadata()
 	for i := range checks {
@@ -786,7 +957,11 @@ func (b *Builder) buildFKChecks(checks memo.FKChecksExpr) error {
 			}
 			return mkFKCheckErr(md, c, keyVals)
 		}
-		node, err := b.factory.ConstructErrorIfRows(query.root, mkErr)
+		var deferrable *exec.DeferrableCheck
+		if c.Deferrable {
+			deferrable = makeDeferrableFKCheck(md, c, query)
+		}
+		node, err := b.factory.ConstructErrorIfRows(query.root, mkErr, deferrable)
 		if err != nil {
 			return err
 		}
@@ -795,6 +970,29 @@ func (b *Builder) buildFKChecks(checks memo.FKChecksExpr) error {
 	return nil
 }
 
+// makeDeferrableFKCheck describes the foreign key checked by a deferrable FK
+// check, and the columns of the check query which contain the key values.
+func makeDeferrableFKCheck(
+	md *opt.Metadata, c *memo.FKChecksItem, query execPlan,
+) *exec.DeferrableCheck {
+	var fk cat.ForeignKeyConstraint
+	if c.FKOutbound {
+		fk = md.Table(c.OriginTable).OutboundForeignKey(c.FKOrdinal)
+	} else {
+		fk = md.Table(c.ReferencedTable).InboundForeignKey(c.FKOrdinal)
+	}
+	keyCols := make([]exec.NodeColumnOrdinal, len(c.KeyCols))
+	for i, col := range c.KeyCols {
+		keyCols[i] = query.getNodeColumnOrdinal(col)
+	}
+	return &exec.DeferrableCheck{
+		TableID:           fk.OriginTableID(),
+		ConstraintName:    fk.Name(),
+		InitiallyDeferred: fk.InitiallyDeferred(),
+		KeyCols:           keyCols,
+	}
+}
+
 // mkFKCheckErr generates a user-friendly error describing a foreign key
 // violation. The keyVals are the values that correspond to the
 // cat.ForeignKeyConstraint columns.
@@ -902,6 +1100,20 @@ func (b *Builder) buildFKCascades(withID opt.WithID, cascades memo.FKCascades) e
 	return nil
 }
 
+func (b *Builder) buildTriggers(withID opt.WithID, triggers memo.Triggers) error {
+	if len(triggers) == 0 {
+		return nil
+	}
+	cb, err := makeCascadeBuilder(b, withID)
+	if err != nil {
+		return err
+	}
+	for i := range triggers {
+		b.cascades = append(b.cascades, cb.setupTrigger(&triggers[i]))
+	}
+	return nil
+}
+
 // canAutoCommit determines if it is safe to auto commit the mutation contained
 // in the expression.
 //
@@ -98,8 +98,13 @@ func Emit(plan *Plan, ob *OutputBuilder, spanFormatFn SpanFormatFn) error {
 	}
 
 	for i := range plan.Cascades {
-		ob.EnterMetaNode("fk-cascade")
-		ob.Attr("fk", plan.Cascades[i].FKName)
+		if plan.Cascades[i].TriggerPlanFn != nil {
+			ob.EnterMetaNode("trigger")
+			ob.Attr("name", plan.Cascades[i].TriggerName)
+		} else {
+			ob.EnterMetaNode("fk-cascade")
+			ob.Attr("fk", plan.Cascades[i].FKName)
+		}
 		if buffer := plan.Cascades[i].Buffer; buffer != nil {
 			ob.Attr("input", buffer.(*Node).args.(*bufferArgs).Label)
 		}
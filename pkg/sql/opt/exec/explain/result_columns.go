@@ -142,7 +142,10 @@ func getResultColumns(
 
 	case deleteOp:
 		a := args.(*deleteArgs)
-		return tableColumns(a.Table, a.ReturnCols), nil
+		return appendColumns(
+			tableColumns(a.Table, a.ReturnCols),
+			a.Passthrough...,
+		), nil
 
 	case opaqueOp:
 		return args.(*opaqueArgs).Metadata.Columns(), nil
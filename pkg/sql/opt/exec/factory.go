@@ -233,6 +233,25 @@ type Cascade struct {
 		numBufferedRows int,
 		allowAutoCommit bool,
 	) (Plan, error)
+
+	// TriggerName is the name of the AFTER trigger, if this entry runs a
+	// trigger instead of a foreign key cascade (in which case FKName is empty
+	// and PlanFn is nil).
+	TriggerName string
+
+	// TriggerPlanFn builds the query executed by the trigger for a single row of
+	// the mutation input and creates the plan for it. The row contains the
+	// values of the Buffer columns for that row. Like PlanFn, the generated Plan
+	// can contain more cascades and checks. It is called once per row, and the
+	// caller closes each plan once it has run.
+	TriggerPlanFn func(
+		ctx context.Context,
+		semaCtx *tree.SemaContext,
+		evalCtx *tree.EvalContext,
+		execFactory Factory,
+		row tree.Datums,
+		allowAutoCommit bool,
+	) (Plan, error)
 }
 
 // InsertFastPathFKCheck contains information about a foreign key check to be
@@ -259,6 +278,24 @@ type InsertFastPathFKCheck struct {
 // relevant row.
 type MkErrFn func(tree.Datums) error
 
+// DeferrableCheck describes a foreign key or uniqueness check whose violations
+// can be deferred until the end of the transaction. When the constraint is
+// deferred, the rows returned by the check query are not errors; instead, their
+// key values are checked again when the transaction commits.
+type DeferrableCheck struct {
+	// TableID is the ID of the table the constraint belongs to (the referencing
+	// table, for a foreign key).
+	TableID cat.StableID
+	// ConstraintName is the name of the constraint.
+	ConstraintName string
+	// InitiallyDeferred is true if the constraint is deferred unless it is set
+	// to IMMEDIATE with SET CONSTRAINTS.
+	InitiallyDeferred bool
+	// KeyCols are the columns of the check query which contain the key values,
+	// in the order of the constraint columns.
+	KeyCols []NodeColumnOrdinal
+}
+
 // ExplainFactory is an extension of Factory used when constructing a plan that
 // can be explained. It allows annotation of nodes with extra information.
 type ExplainFactory interface {
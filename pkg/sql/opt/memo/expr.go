@@ -981,3 +981,57 @@ type CascadeBuilder interface {
 		oldValues, newValues opt.ColList,
 	) (RelExpr, error)
 }
+
+// Triggers stores metadata necessary for running AFTER triggers.
+type Triggers []Trigger
+
+// Trigger stores metadata necessary for running an AFTER trigger. Like
+// cascading queries, the queries executed by triggers are built as needed,
+// after the original query is executed. The trigger function is executed once
+// for each row modified by the original query.
+type Trigger struct {
+	// Name is the name of the trigger.
+	Name string
+
+	// Builder is an object that can be used as the "optbuilder" for the query
+	// executed by the trigger.
+	Builder TriggerBuilder
+
+	// WithID identifies the buffer for the mutation input in the original
+	// expression tree.
+	WithID opt.WithID
+
+	// OldValues are column IDs from the mutation input that correspond to the
+	// old values of the modified rows. The list maps 1-to-1 to the columns
+	// exposed to the trigger (see TriggerBuilder). It is empty if the mutation
+	// is an insertion.
+	OldValues opt.ColList
+
+	// NewValues are column IDs from the mutation input that correspond to the
+	// new values of the modified rows. The list maps 1-to-1 to the columns
+	// exposed to the trigger. It is empty if the mutation is a deletion.
+	NewValues opt.ColList
+}
+
+// TriggerBuilder is an interface used to construct the query executed by an
+// AFTER trigger for a single modified row.
+type TriggerBuilder interface {
+	// Build constructs the query executed by the trigger function, with the
+	// references to the old and new rows replaced by the given values. The
+	// values correspond 1-to-1 to the OldValues and NewValues columns of the
+	// Trigger. For inserts, oldValues is empty; for deletes, newValues is empty.
+	//
+	// The method does not mutate any captured state; it is ok to call Build
+	// concurrently (e.g. if the plan it originates from is cached and reused).
+	//
+	// Note: factory is always *norm.Factory; it is an interface{} only to avoid
+	// circular package dependencies.
+	Build(
+		ctx context.Context,
+		semaCtx *tree.SemaContext,
+		evalCtx *tree.EvalContext,
+		catalog cat.Catalog,
+		factory interface{},
+		oldValues, newValues tree.Datums,
+	) (RelExpr, error)
+}
@@ -207,7 +207,7 @@ func (f *ExprFmtCtx) formatRelational(e RelExpr, tp treeprinter.Node) {
 		*WindowExpr, *OpaqueRelExpr, *OpaqueMutationExpr, *OpaqueDDLExpr,
 		*AlterTableSplitExpr, *AlterTableUnsplitExpr, *AlterTableUnsplitAllExpr,
 		*AlterTableRelocateExpr, *ControlJobsExpr, *CancelQueriesExpr,
-		*CancelSessionsExpr, *CreateViewExpr, *ExportExpr:
+		*CancelSessionsExpr, *CreateViewExpr, *CreateFunctionExpr, *ExportExpr:
 		fmt.Fprintf(f.Buffer, "%v", e.Op())
 		FormatPrivate(f, e.Private(), required)
 
@@ -587,29 +587,17 @@ func (f *ExprFmtCtx) formatRelational(e RelExpr, tp treeprinter.Node) {
 		}
 		tp.Child(f.Buffer.String())
 
-		n := tp.Child("dependencies")
-		for _, dep := range t.Deps {
-			f.Buffer.Reset()
-			name := dep.DataSource.Name()
-			f.Buffer.WriteString(name.String())
-			if dep.SpecificIndex {
-				fmt.Fprintf(f.Buffer, "@%s", dep.DataSource.(cat.Table).Index(dep.Index).Name())
-			}
-			colNames, isTable := dep.GetColumnNames()
-			if len(colNames) > 0 {
-				fmt.Fprintf(f.Buffer, " [columns:")
-				for _, colName := range colNames {
-					fmt.Fprintf(f.Buffer, " %s", colName)
-				}
-				fmt.Fprintf(f.Buffer, "]")
-			} else if isTable {
-				fmt.Fprintf(f.Buffer, " [no columns]")
-			}
-			n.Child(f.Buffer.String())
-		}
+		f.formatViewDeps(tp, t.Deps)
+
+	case *CreateFunctionExpr:
+		tp.Child(t.Syntax.String())
+		f.formatViewDeps(tp, t.Deps)
 
 	case *ExportExpr:
 		tp.Childf("format: %s", t.FileFormat)
+		if len(t.PartitionCols) > 0 {
+			f.formatColList(e, tp, "partition by:", t.PartitionCols)
+		}
 
 	case *ExplainExpr:
 		// ExplainPlan is the default, don't show it.
@@ -1012,6 +1000,9 @@ func (f *ExprFmtCtx) formatScalarPrivate(scalar opt.ScalarExpr) {
 			f.Buffer.WriteString(string(col.ColName()))
 		}
 		f.Buffer.WriteByte(')')
+		if t.Deferrable {
+			f.Buffer.WriteString(" deferrable")
+		}
 
 	case *FKChecksItem:
 		origin := f.Memo.metadata.TableMeta(t.OriginTable)
@@ -1044,6 +1035,9 @@ func (f *ExprFmtCtx) formatScalarPrivate(scalar opt.ScalarExpr) {
 			f.Buffer.WriteString(string(col.ColName()))
 		}
 		f.Buffer.WriteByte(')')
+		if t.Deferrable {
+			f.Buffer.WriteString(" deferrable")
+		}
 
 	default:
 		private = scalar.Private()
@@ -1195,6 +1189,37 @@ func (f *ExprFmtCtx) formatMutationCommon(tp treeprinter.Node, p *MutationPrivat
 			c.Child(p.FKCascades[i].FKName)
 		}
 	}
+	if len(p.Triggers) > 0 {
+		c := tp.Childf("triggers")
+		for i := range p.Triggers {
+			c.Child(p.Triggers[i].Name)
+		}
+	}
+}
+
+// formatViewDeps shows the data sources referenced by a view or function
+// definition.
+func (f *ExprFmtCtx) formatViewDeps(tp treeprinter.Node, deps opt.ViewDeps) {
+	n := tp.Child("dependencies")
+	for _, dep := range deps {
+		f.Buffer.Reset()
+		name := dep.DataSource.Name()
+		f.Buffer.WriteString(name.String())
+		if dep.SpecificIndex {
+			fmt.Fprintf(f.Buffer, "@%s", dep.DataSource.(cat.Table).Index(dep.Index).Name())
+		}
+		colNames, isTable := dep.GetColumnNames()
+		if len(colNames) > 0 {
+			fmt.Fprintf(f.Buffer, " [columns:")
+			for _, colName := range colNames {
+				fmt.Fprintf(f.Buffer, " %s", colName)
+			}
+			fmt.Fprintf(f.Buffer, "]")
+		} else if isTable {
+			fmt.Fprintf(f.Buffer, " [no columns]")
+		}
+		n.Child(f.Buffer.String())
+	}
 }
 
 // ColumnString returns the column in the same format as formatColSimple.
@@ -1378,6 +1403,10 @@ func FormatPrivate(f *ExprFmtCtx, private interface{}, physProps *physical.Requi
 		schema := f.Memo.Metadata().Schema(t.Schema)
 		fmt.Fprintf(f.Buffer, " %s.%s", schema.Name(), t.ViewName)
 
+	case *CreateFunctionPrivate:
+		schema := f.Memo.Metadata().Schema(t.Schema)
+		fmt.Fprintf(f.Buffer, " %s.%s", schema.Name(), tree.Name(t.Syntax.Name.Object()))
+
 	case *JoinPrivate:
 		// Nothing to show; flags are shown separately.
 
@@ -111,6 +111,10 @@ type Metadata struct {
 	// needed for EXPLAIN (opt, env).
 	views []cat.View
 
+	// udfs stores the user-defined functions called by the query, along with
+	// the names they were resolved from.
+	udfs []mdUDF
+
 	// currUniqueID is the highest UniqueID that has been assigned.
 	currUniqueID UniqueID
 
@@ -130,6 +134,14 @@ type mdDep struct {
 	privileges privilegeBitmap
 }
 
+type mdUDF struct {
+	fn cat.Function
+
+	// name is the name the function was resolved from. It is nil if the
+	// function was resolved by ID, as is the case for trigger functions.
+	name *tree.UnresolvedObjectName
+}
+
 // MDDepName stores either the unresolved DataSourceName or the StableID from
 // the query that was used to resolve a data source.
 type MDDepName struct {
@@ -180,6 +192,11 @@ func (md *Metadata) Init() {
 	}
 	md.views = md.views[:0]
 
+	for i := range md.udfs {
+		md.udfs[i] = mdUDF{}
+	}
+	md.udfs = md.udfs[:0]
+
 	md.currUniqueID = 0
 
 	md.withBindings = nil
@@ -195,7 +212,7 @@ func (md *Metadata) Init() {
 func (md *Metadata) CopyFrom(from *Metadata) {
 	if len(md.schemas) != 0 || len(md.cols) != 0 || len(md.tables) != 0 ||
 		len(md.sequences) != 0 || len(md.deps) != 0 || len(md.views) != 0 ||
-		len(md.userDefinedTypes) != 0 || len(md.userDefinedTypesSlice) != 0 {
+		len(md.udfs) != 0 || len(md.userDefinedTypes) != 0 || len(md.userDefinedTypesSlice) != 0 {
 		panic(errors.AssertionFailedf("CopyFrom requires empty destination"))
 	}
 	md.schemas = append(md.schemas, from.schemas...)
@@ -222,6 +239,7 @@ func (md *Metadata) CopyFrom(from *Metadata) {
 	md.sequences = append(md.sequences, from.sequences...)
 	md.deps = append(md.deps, from.deps...)
 	md.views = append(md.views, from.views...)
+	md.udfs = append(md.udfs, from.udfs...)
 	md.currUniqueID = from.currUniqueID
 
 	// We cannot copy the bound expressions; they must be rebuilt in the new memo.
@@ -307,6 +325,27 @@ func (md *Metadata) CheckDependencies(
 			privs &= ^(1 << priv)
 		}
 	}
+	// Check that all of the user-defined functions still resolve to the same
+	// functions, and that those have not changed.
+	for i := range md.udfs {
+		var toCheck cat.Function
+		var err error
+		if md.udfs[i].name != nil {
+			toCheck, err = catalog.ResolveFunction(ctx, cat.Flags{}, md.udfs[i].name)
+		} else {
+			toCheck, err = catalog.ResolveFunctionByID(ctx, md.udfs[i].fn.ID())
+			// Handle when the function no longer exists.
+			if pgerror.GetPGCode(err) == pgcode.UndefinedFunction {
+				return false, nil
+			}
+		}
+		if err != nil {
+			return false, err
+		}
+		if toCheck == nil || !toCheck.Equals(md.udfs[i].fn) {
+			return false, nil
+		}
+	}
 	// Check that all of the user defined types present have not changed.
 	for _, typ := range md.AllUserDefinedTypes() {
 		toCheck, err := catalog.ResolveTypeByOID(ctx, typ.Oid())
@@ -324,6 +363,26 @@ func (md *Metadata) CheckDependencies(
 	return true, nil
 }
 
+// AddUserDefinedFunction tracks a user-defined function called by the query,
+// so that CheckDependencies can detect if the name resolves to a different
+// function now, or if the function was changed. The name is nil if the
+// function was resolved by ID.
+func (md *Metadata) AddUserDefinedFunction(fn cat.Function, name *tree.UnresolvedObjectName) {
+	for i := range md.udfs {
+		if md.udfs[i].fn != fn || (md.udfs[i].name == nil) != (name == nil) {
+			continue
+		}
+		if name == nil || *md.udfs[i].name == *name {
+			return
+		}
+	}
+	if name != nil {
+		nameCopy := *name
+		name = &nameCopy
+	}
+	md.udfs = append(md.udfs, mdUDF{fn: fn, name: name})
+}
+
 // AddSchema indexes a new reference to a schema used by the query.
 func (md *Metadata) AddSchema(sch cat.Schema) SchemaID {
 	md.schemas = append(md.schemas, sch)
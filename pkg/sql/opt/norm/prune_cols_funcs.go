@@ -67,6 +67,10 @@ func (c *CustomFuncs) NeededMutationCols(
 	if private.CanaryCol != 0 {
 		cols.Add(private.CanaryCol)
 	}
+	for i := range private.Triggers {
+		addCols(private.Triggers[i].OldValues)
+		addCols(private.Triggers[i].NewValues)
+	}
 
 	if private.WithID != 0 {
 		for i := range uniqueChecks {
@@ -141,6 +145,20 @@ func neededMutationFetchCols(
 		}
 	}
 
+	// Retain any FetchCols that provide the old values of the modified rows to
+	// AFTER triggers.
+	if len(private.Triggers) > 0 {
+		var oldValues opt.ColSet
+		for i := range private.Triggers {
+			oldValues.UnionWith(private.Triggers[i].OldValues.ToSet())
+		}
+		for ord, col := range private.FetchCols {
+			if col != 0 && oldValues.Contains(col) {
+				cols.Add(tabMeta.MetaID.ColumnID(ord))
+			}
+		}
+	}
+
 	switch op {
 	case opt.UpdateOp, opt.UpsertOp:
 		// Determine set of target table columns that need to be updated.
@@ -230,6 +230,12 @@ func (c *CustomFuncs) SubqueryCmp(sub *memo.SubqueryPrivate) opt.Operator {
 	return sub.Cmp
 }
 
+// IsUDFSubquery returns true if the subquery was built for a call to a
+// user-defined function.
+func (c *CustomFuncs) IsUDFSubquery(sub *memo.SubqueryPrivate) bool {
+	return sub.FromUDF
+}
+
 // MakeArrayAggCol returns a ColumnID with the given type and an "array_agg"
 // label.
 func (c *CustomFuncs) MakeArrayAggCol(typ *types.T) opt.ColumnID {
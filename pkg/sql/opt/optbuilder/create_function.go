@@ -0,0 +1,225 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package optbuilder
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/opt"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/memo"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/errorutil/unimplemented"
+)
+
+func (b *Builder) buildCreateFunction(cf *tree.CreateFunction, inScope *scope) (outScope *scope) {
+	b.DisableMemoReuse = true
+	fnName := cf.Name.ToTableName()
+	sch, resName := b.resolveSchemaForCreate(&fnName)
+	schID := b.factory.Metadata().AddSchema(sch)
+	fnName.ObjectNamePrefix = resName
+	fnName.ExplicitCatalog = true
+	fnName.ExplicitSchema = true
+
+	if _, ok := tree.FunDefs[fnName.Object()]; ok {
+		panic(pgerror.Newf(pgcode.DuplicateFunction,
+			"function %s conflicts with a builtin function", tree.ErrString(&fnName.ObjectName)))
+	}
+
+	if cf.ReturnsTrigger {
+		return b.buildCreateTriggerFunction(cf, &fnName, schID)
+	}
+
+	argTypes := make([]*types.T, len(cf.Args))
+	for i := range cf.Args {
+		argTypes[i] = b.resolveFunctionType(cf.Args[i].Type)
+	}
+	retType := b.resolveFunctionType(cf.ReturnType)
+
+	body := b.parseFunctionBody(cf)
+
+	// Build the body to:
+	//  - check it semantically,
+	//  - get the fully resolved names into the AST, and
+	//  - collect the function dependencies in b.viewDeps.
+	// The result is not otherwise used. The arguments are made available to
+	// the body as outer columns.
+	argScope := inScope
+	if len(cf.Args) > 0 {
+		argScope = b.buildFunctionArgs(cf, fnName.Object(), argTypes, inScope)
+	}
+
+	b.insideViewDef = true
+	b.trackViewDeps = true
+	b.qualifyDataSourceNamesInAST = true
+	defer func() {
+		b.insideViewDef = false
+		b.trackViewDeps = false
+		b.viewDeps = nil
+		b.qualifyDataSourceNamesInAST = false
+	}()
+
+	b.pushWithFrame()
+	defScope := b.buildStmtAtRoot(body, []*types.T{retType}, argScope)
+	b.popWithFrame(defScope)
+
+	p := defScope.makePhysicalProps().Presentation
+	if len(p) != 1 {
+		panic(pgerror.Newf(pgcode.InvalidFunctionDefinition,
+			"body of function %s must return exactly one column, found %d",
+			tree.ErrString(&fnName.ObjectName), len(p)))
+	}
+	bodyType := b.factory.Metadata().ColumnMeta(p[0].ID).Type
+	if bodyType.Family() != types.UnknownFamily && !bodyType.Equivalent(retType) {
+		panic(pgerror.Newf(pgcode.InvalidFunctionDefinition,
+			"return type mismatch in function declared to return %s: body returns %s",
+			retType.SQLString(), bodyType.SQLString()))
+	}
+
+	vs := defScope.expr.Relational().VolatilitySet
+	if (vs.HasVolatile() && cf.Volatility < tree.VolatilityVolatile) ||
+		(vs.HasStable() && cf.Volatility < tree.VolatilityStable) {
+		panic(pgerror.Newf(pgcode.InvalidFunctionDefinition,
+			"function %s is declared %s but its body is %s",
+			tree.ErrString(&fnName.ObjectName), cf.Volatility, vs))
+	}
+
+	syntax := *cf
+	syntax.Name = fnName.ToUnresolvedObjectName()
+	syntax.Body = tree.AsStringWithFlags(body, tree.FmtParsable)
+
+	outScope = b.allocScope()
+	outScope.expr = b.factory.ConstructCreateFunction(
+		&memo.CreateFunctionPrivate{
+			Schema: schID,
+			Syntax: &syntax,
+			Deps:   b.viewDeps,
+		},
+	)
+	return outScope
+}
+
+// buildCreateTriggerFunction builds a CREATE FUNCTION statement for a
+// function declared as RETURNS TRIGGER. The body of a trigger function
+// references the row which fired the trigger as new.<colname> and
+// old.<colname>, so it cannot be built until it is executed by a trigger on a
+// particular table. It is only parsed here, and it does not have dependencies.
+func (b *Builder) buildCreateTriggerFunction(
+	cf *tree.CreateFunction, fnName *tree.TableName, schID opt.SchemaID,
+) (outScope *scope) {
+	if len(cf.Args) > 0 {
+		panic(pgerror.Newf(pgcode.InvalidFunctionDefinition,
+			"trigger functions cannot have declared arguments"))
+	}
+	body := parseTriggerFunctionBody(cf.Name, cf.Body, pgcode.InvalidFunctionDefinition)
+
+	syntax := *cf
+	syntax.Name = fnName.ToUnresolvedObjectName()
+	syntax.Body = tree.AsStringWithFlags(body, tree.FmtParsable)
+
+	outScope = b.allocScope()
+	outScope.expr = b.factory.ConstructCreateFunction(
+		&memo.CreateFunctionPrivate{
+			Schema: schID,
+			Syntax: &syntax,
+		},
+	)
+	return outScope
+}
+
+// parseTriggerFunctionBody parses the body of a trigger function, which must
+// be a SELECT, INSERT, UPSERT, UPDATE or DELETE statement. Errors are reported
+// with the given code.
+func parseTriggerFunctionBody(
+	fnName tree.NodeFormatter, sql string, code pgcode.Code,
+) tree.Statement {
+	stmt, err := parser.ParseOne(sql)
+	if err != nil {
+		panic(pgerror.Wrapf(err, code,
+			"failed to parse body of function %s", tree.ErrString(fnName)))
+	}
+	switch stmt.AST.(type) {
+	case *tree.Select, *tree.Insert, *tree.Update, *tree.Delete:
+		return stmt.AST
+	}
+	panic(pgerror.Newf(code,
+		"body of trigger function %s must be a SELECT, INSERT, UPSERT, UPDATE or DELETE statement, found %s",
+		tree.ErrString(fnName), stmt.AST.StatementTag()))
+}
+
+// resolveFunctionType resolves the type of an argument or of the result of a
+// user-defined function.
+func (b *Builder) resolveFunctionType(ref tree.ResolvableTypeReference) *types.T {
+	typ, err := tree.ResolveType(b.ctx, ref, b.semaCtx.GetTypeResolver())
+	if err != nil {
+		panic(err)
+	}
+	if typ.UserDefined() {
+		panic(unimplemented.NewWithIssuef(17511,
+			"user-defined types cannot be used in function signatures"))
+	}
+	return typ
+}
+
+// parseFunctionBody parses the body of the function and rewrites the
+// positional references to its arguments ($1, $2, etc.) into references to
+// the argument columns (see udfArgColName).
+func (b *Builder) parseFunctionBody(cf *tree.CreateFunction) *tree.Select {
+	parse := func(sql string) *tree.Select {
+		stmt, err := parser.ParseOne(sql)
+		if err != nil {
+			panic(pgerror.Wrapf(err, pgcode.InvalidFunctionDefinition,
+				"failed to parse body of function %s", tree.ErrString(cf.Name)))
+		}
+		sel, ok := stmt.AST.(*tree.Select)
+		if !ok {
+			panic(pgerror.Newf(pgcode.InvalidFunctionDefinition,
+				"body of function %s must be a SELECT statement, found %s",
+				tree.ErrString(cf.Name), stmt.AST.StatementTag()))
+		}
+		return sel
+	}
+
+	body := parse(cf.Body)
+	fmtCtx := tree.NewFmtCtx(tree.FmtParsable)
+	fmtCtx.SetPlaceholderFormat(func(ctx *tree.FmtCtx, p *tree.Placeholder) {
+		if int(p.Idx) >= len(cf.Args) {
+			panic(pgerror.Newf(pgcode.UndefinedParameter,
+				"there is no parameter %s in function %s", p, tree.ErrString(cf.Name)))
+		}
+		name := udfArgColName(cf.Args[p.Idx].Name, int(p.Idx))
+		ctx.FormatNode(&name)
+	})
+	fmtCtx.FormatNode(body)
+	return parse(fmtCtx.CloseAndGetString())
+}
+
+// buildFunctionArgs returns a scope with one column for each argument of the
+// function, which can be used as the outer scope of the function body. The
+// columns are named after the arguments and qualified by the function name.
+func (b *Builder) buildFunctionArgs(
+	cf *tree.CreateFunction, fnName string, argTypes []*types.T, inScope *scope,
+) *scope {
+	exprs := make(tree.SelectExprs, len(cf.Args))
+	cols := make(tree.NameList, len(cf.Args))
+	for i := range cf.Args {
+		exprs[i].Expr = &tree.CastExpr{Expr: tree.DNull, Type: argTypes[i], SyntaxMode: tree.CastShort}
+		cols[i] = udfArgColName(cf.Args[i].Name, i)
+	}
+	args := &tree.AliasedTableExpr{
+		Expr: &tree.Subquery{Select: &tree.ParenSelect{Select: &tree.Select{
+			Select: &tree.SelectClause{Exprs: exprs},
+		}}},
+		As: tree.AliasClause{Alias: tree.Name(fnName), Cols: cols},
+	}
+	return b.buildDataSource(args, nil /* indexFlags */, noRowLocking, inScope)
+}
@@ -55,11 +55,11 @@ func (b *Builder) buildDelete(del *tree.Delete, inScope *scope) (outScope *scope
 	// Build the input expression that selects the rows that will be deleted:
 	//
 	//   WITH <with>
-	//   SELECT <cols> FROM <table> WHERE <where>
+	//   SELECT <cols> FROM <table> [, <using>] WHERE <where>
 	//   ORDER BY <order-by> LIMIT <limit>
 	//
 	// All columns from the delete table will be projected.
-	mb.buildInputForDelete(inScope, del.Table, del.Where, del.Limit, del.OrderBy)
+	mb.buildInputForDelete(inScope, del.Table, del.Using, del.Where, del.Limit, del.OrderBy)
 
 	// Build the final delete statement, including any returned expressions.
 	if resultsNeeded(del.Returning) {
@@ -74,11 +74,16 @@ func (b *Builder) buildDelete(del *tree.Delete, inScope *scope) (outScope *scope
 // buildDelete constructs a Delete operator, possibly wrapped by a Project
 // operator that corresponds to the given RETURNING clause.
 func (mb *mutationBuilder) buildDelete(returning tree.ReturningExprs) {
+	// Run any BEFORE DELETE triggers, which can skip the deletion of rows.
+	mb.buildBeforeTriggers(tree.TriggerDelete)
+
 	mb.buildFKChecksAndCascadesForDelete()
 
 	// Project partial index DEL boolean columns.
 	mb.projectPartialIndexDelCols(mb.fetchScope)
 
+	mb.buildAfterTriggers(tree.TriggerDelete)
+
 	private := mb.makeMutationPrivate(returning != nil)
 	mb.outScope.expr = mb.b.factory.ConstructDelete(
 		mb.outScope.expr, mb.uniqueChecks, mb.fkChecks, private,
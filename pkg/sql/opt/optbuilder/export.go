@@ -12,7 +12,10 @@ package optbuilder
 
 import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt"
 	"github.com/cockroachdb/cockroach/pkg/sql/opt/memo"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
 )
@@ -31,6 +34,11 @@ func (b *Builder) buildExport(export *tree.Export, inScope *scope) (outScope *sc
 
 	options := b.buildKVOptions(export.Options, emptyScope)
 
+	var partitionCols opt.ColList
+	for _, name := range export.PartitionBy {
+		partitionCols = append(partitionCols, b.resolveExportPartitionCol(inputScope, name))
+	}
+
 	outScope = inScope.push()
 	b.synthesizeResultColumns(outScope, colinfo.ExportColumns)
 	outScope.expr = b.factory.ConstructExport(
@@ -38,14 +46,37 @@ func (b *Builder) buildExport(export *tree.Export, inScope *scope) (outScope *sc
 		fileName,
 		options,
 		&memo.ExportPrivate{
-			FileFormat: export.FileFormat,
-			Columns:    colsToColList(outScope.cols),
-			Props:      inputScope.makePhysicalProps(),
+			FileFormat:    export.FileFormat,
+			Columns:       colsToColList(outScope.cols),
+			Props:         inputScope.makePhysicalProps(),
+			PartitionCols: partitionCols,
 		},
 	)
 	return outScope
 }
 
+// resolveExportPartitionCol returns the ID of the column of the exported query
+// with the given name.
+func (b *Builder) resolveExportPartitionCol(inScope *scope, name tree.Name) opt.ColumnID {
+	var res *scopeColumn
+	for i := range inScope.cols {
+		col := &inScope.cols[i]
+		if col.hidden || col.name != name {
+			continue
+		}
+		if res != nil {
+			panic(pgerror.Newf(pgcode.AmbiguousColumn,
+				"PARTITION BY column %q is ambiguous", tree.ErrString(&name)))
+		}
+		res = col
+	}
+	if res == nil {
+		panic(pgerror.Newf(pgcode.UndefinedColumn,
+			"PARTITION BY column %q does not exist", tree.ErrString(&name)))
+	}
+	return res.id
+}
+
 func (b *Builder) buildKVOptions(opts tree.KVOptions, inScope *scope) memo.KVOptionsExpr {
 	res := make(memo.KVOptionsExpr, len(opts))
 	for i := range opts {
@@ -45,6 +45,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/errors"
 )
 
@@ -100,6 +101,15 @@ type groupby struct {
 	// It is used to ensure that the builder does not throw a grouping error
 	// prematurely.
 	buildingGroupingCols bool
+
+	// groupingSets contains the grouping sets of a GROUP BY clause with ROLLUP,
+	// CUBE or GROUPING SETS, as sets of ordinals of the grouping columns. It is
+	// nil if there is a single grouping set (see buildGroupingSets).
+	groupingSets []util.FastIntSet
+
+	// groupingFuncs contains information about GROUPING functions encountered
+	// when there are several grouping sets.
+	groupingFuncs []groupingFuncInfo
 }
 
 // groupByStrSet is a set of stringified GROUP BY expressions that map to the
@@ -134,6 +144,38 @@ func (g *groupby) groupingCols() []scopeColumn {
 	return g.aggInScope.cols[len(g.aggInScope.cols)-len(g.groupStrs):]
 }
 
+// groupingOutCols returns the columns in the aggOutScope corresponding to
+// grouping columns. They are the same as the grouping columns in the
+// aggInScope, unless there are several grouping sets.
+func (g *groupby) groupingOutCols() []scopeColumn {
+	// Grouping cols always follow the aggregates.
+	return g.aggOutScope.cols[len(g.aggs) : len(g.aggs)+len(g.groupStrs)]
+}
+
+// groupingColOrdinal returns the ordinal of the grouping column with the given
+// ID in the aggInScope.
+func (g *groupby) groupingColOrdinal(id opt.ColumnID) int {
+	groupingCols := g.groupingCols()
+	for i := range groupingCols {
+		if groupingCols[i].id == id {
+			return i
+		}
+	}
+	panic(errors.AssertionFailedf("grouping column %d not found", id))
+}
+
+// groupingOutColOrdinal returns the ordinal of the grouping column with the
+// given ID in the aggOutScope.
+func (g *groupby) groupingOutColOrdinal(id opt.ColumnID) int {
+	groupingOutCols := g.groupingOutCols()
+	for i := range groupingOutCols {
+		if groupingOutCols[i].id == id {
+			return i
+		}
+	}
+	panic(errors.AssertionFailedf("grouping column %d not found", id))
+}
+
 // getAggregateArgCols returns the columns in the aggInScope corresponding to
 // arguments to aggregate functions. If the aggregate has a filter, the column
 // corresponding to the filter's input will immediately follow the arguments.
@@ -333,6 +375,13 @@ func (b *Builder) buildGroupingColumns(sel *tree.SelectClause, projectionsScope,
 	// The "from" columns are visible to any grouping expressions.
 	b.buildGroupingList(sel.GroupBy, sel.Exprs, projectionsScope, fromScope)
 
+	if g.groupingSets != nil {
+		// The grouping columns are NULL for the grouping sets they are not part
+		// of, so they cannot be passed through.
+		b.buildGroupingSetOutputCols(g)
+		return
+	}
+
 	// Copy the grouping columns to the aggOutScope.
 	g.aggOutScope.appendColumns(g.groupingCols())
 }
@@ -353,6 +402,10 @@ func (b *Builder) buildAggregation(having opt.ScalarExpr, fromScope *scope) (out
 	// If there are any aggregates that are ordering sensitive, build the
 	// aggregations as window functions over each group.
 	if g.hasNonCommutativeAggregates() {
+		if g.groupingSets != nil {
+			panic(unimplementedWithIssueDetailf(46280, "ordered aggregate",
+				"ordered aggregates are not supported with ROLLUP, CUBE and GROUPING SETS"))
+		}
 		return b.buildAggregationAsWindow(groupingColSet, having, fromScope)
 	}
 
@@ -429,12 +482,16 @@ func (b *Builder) buildAggregation(having opt.ScalarExpr, fromScope *scope) (out
 	// aggregate arguments, as well as any additional order by columns.
 	b.constructProjectForScope(fromScope, g.aggInScope)
 
-	g.aggOutScope.expr = b.constructGroupBy(
-		g.aggInScope.expr.(memo.RelExpr),
-		groupingColSet,
-		aggCols,
-		g.aggInScope.ordering,
-	)
+	if g.groupingSets != nil {
+		g.aggOutScope.expr = b.constructGroupingSets(g.aggInScope.expr.(memo.RelExpr), g, aggCols)
+	} else {
+		g.aggOutScope.expr = b.constructGroupBy(
+			g.aggInScope.expr.(memo.RelExpr),
+			groupingColSet,
+			aggCols,
+			g.aggInScope.ordering,
+		)
+	}
 
 	// Wrap with having filter if it exists.
 	if having != nil {
@@ -506,15 +563,20 @@ func (b *Builder) buildGroupingList(
 	// used in an aggregate function`. The builder cannot know whether there is
 	// a grouping error until the grouping columns are fully built.
 	g.buildingGroupingCols = true
-	for _, e := range groupBy {
-		b.buildGrouping(e, selects, projectionsScope, fromScope, g.aggInScope)
+	if hasGroupingSets(groupBy) {
+		g.groupingSets = b.buildGroupingSets(groupBy, selects, projectionsScope, fromScope)
+	} else {
+		for _, e := range groupBy {
+			b.buildGrouping(e, selects, projectionsScope, fromScope, g.aggInScope)
+		}
 	}
 	g.buildingGroupingCols = false
 }
 
 // buildGrouping builds a set of memo groups that represent a GROUP BY
 // expression. The expression (or expressions, if we have a star) is added to
-// groupStrs and to the aggInScope.
+// groupStrs and to the aggInScope. Returns the ordinals of the grouping columns
+// for the expression.
 //
 //
 // groupBy          The given GROUP BY expression.
@@ -528,7 +590,7 @@ func (b *Builder) buildGroupingList(
 //                  as the aggregate function arguments.
 func (b *Builder) buildGrouping(
 	groupBy tree.Expr, selects tree.SelectExprs, projectionsScope, fromScope, aggInScope *scope,
-) {
+) (ords util.FastIntSet) {
 	// Unwrap parenthesized expressions like "((a))" to "a".
 	groupBy = tree.StripParens(groupBy)
 	alias := ""
@@ -622,11 +684,13 @@ func (b *Builder) buildGrouping(
 	exprs = flattenTuples(exprs)
 
 	// Finally, build each of the GROUP BY columns.
+	g := fromScope.groupby
 	for _, e := range exprs {
 		// If a grouping column has already been added, don't add it again.
 		// GROUP BY a, a is semantically equivalent to GROUP BY a.
 		exprStr := symbolicExprStr(e)
-		if _, ok := fromScope.groupby.groupStrs[exprStr]; ok {
+		if col, ok := g.groupStrs[exprStr]; ok {
+			ords.Add(g.groupingColOrdinal(col.id))
 			continue
 		}
 
@@ -635,8 +699,10 @@ func (b *Builder) buildGrouping(
 		//   SELECT x+y FROM t GROUP BY x+y
 		col := b.addColumn(aggInScope, alias, e)
 		b.buildScalar(e, fromScope, aggInScope, col, nil)
-		fromScope.groupby.groupStrs[exprStr] = col
+		ords.Add(len(g.groupStrs))
+		g.groupStrs[exprStr] = col
 	}
+	return ords
 }
 
 // buildAggArg builds a scalar expression which is used as an input in some form
@@ -900,6 +966,10 @@ func newGroupingError(name *tree.Name) error {
 // table. In that case, we can allow col as an "implicit" grouping column, even
 // if it is not specified in the query.
 func (b *Builder) allowImplicitGroupingColumn(colID opt.ColumnID, g *groupby) bool {
+	// With several grouping sets, the PK columns are not part of all of them.
+	if g.groupingSets != nil {
+		return false
+	}
 	md := b.factory.Metadata()
 	colMeta := md.ColumnMeta(colID)
 	if colMeta.Table == 0 {
@@ -0,0 +1,433 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package optbuilder
+
+// This file has builder code specific to GROUP BY clauses with ROLLUP, CUBE
+// and GROUPING SETS.
+//
+// A query with several grouping sets is built like a regular aggregation
+// (see groupby.go), except that the GroupBy operator is replaced by an
+// expansion which computes the aggregations once per grouping set:
+//
+//  - the pre-projection is hoisted into a With expression, so that its input
+//    is only scanned once;
+//
+//  - for each grouping set, a GroupBy (or ScalarGroupBy for the empty
+//    grouping set) reads the buffered pre-projection through a WithScan and
+//    groups on the columns of the set. A projection on top of it outputs NULL
+//    for the grouping columns which are not part of the set, as well as the
+//    values of the GROUPING functions for the set;
+//
+//  - the results of all grouping sets are combined with UnionAll operators.
+//
+// For example:
+//   SELECT a, b, sum(c) FROM abc GROUP BY ROLLUP (a, b)
+//
+//   pre-projection:  a, b, c (buffered)
+//   grouping sets:   (a, b), (a), ()
+//   aggregation:     group by a, b: sum(c) (as col1)
+//                    UNION ALL
+//                    group by a: sum(c) (as col2), NULL (as col3)
+//                    UNION ALL
+//                    no grouping: sum(c) (as col4), NULL (as col5), NULL (as col6)
+//
+// Since the grouping columns are not passed through, they are given new column
+// IDs in the aggOutScope, which the SELECT, HAVING and ORDER BY expressions
+// reference.
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/opt"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/memo"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// maxGroupingSets is the maximum number of grouping sets in a GROUP BY
+	// clause. It matches the limit in Postgres.
+	maxGroupingSets = 4096
+
+	// maxCubeElements is the maximum number of elements of CUBE. It matches
+	// the limit in Postgres.
+	maxCubeElements = 12
+
+	// maxGroupingFuncArgs is the maximum number of arguments of GROUPING, so
+	// that the result fits in an INT4 like in Postgres.
+	maxGroupingFuncArgs = 31
+)
+
+// groupingFuncInfo stores information about a GROUPING function call.
+type groupingFuncInfo struct {
+	// ords are the ordinals of the grouping columns of the arguments.
+	ords []int
+
+	// col is the output column of the function.
+	col scopeColumn
+}
+
+// value returns the result of the GROUPING function for the given grouping
+// set. The bit for each argument is 1 if the argument is not part of the set;
+// the last argument corresponds to the least significant bit.
+func (f *groupingFuncInfo) value(set util.FastIntSet) int {
+	v := 0
+	for _, ord := range f.ords {
+		v <<= 1
+		if !set.Contains(ord) {
+			v |= 1
+		}
+	}
+	return v
+}
+
+// hasGroupingSets returns true if the GROUP BY clause contains ROLLUP, CUBE or
+// GROUPING SETS.
+func hasGroupingSets(groupBy tree.GroupBy) bool {
+	for _, e := range groupBy {
+		if _, ok := e.(*tree.GroupingSet); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildGroupingSets builds the grouping columns for a GROUP BY clause with
+// ROLLUP, CUBE or GROUPING SETS, and returns the grouping sets as sets of
+// ordinals of the grouping columns. The grouping sets of the GROUP BY clause
+// are the cross product of the grouping sets of its items. nil is returned if
+// there is a single grouping set, which contains all the grouping columns;
+// such a query is built like a regular GROUP BY.
+//
+// See buildGroupingList for a description of the parameters.
+func (b *Builder) buildGroupingSets(
+	groupBy tree.GroupBy, selects tree.SelectExprs, projectionsScope, fromScope *scope,
+) []util.FastIntSet {
+	sets := []util.FastIntSet{{}}
+	for _, e := range groupBy {
+		itemSets := b.buildGroupingSetItem(e, selects, projectionsScope, fromScope)
+		if len(sets)*len(itemSets) > maxGroupingSets {
+			panic(errTooManyGroupingSets)
+		}
+		product := make([]util.FastIntSet, 0, len(sets)*len(itemSets))
+		for _, s := range sets {
+			for _, t := range itemSets {
+				product = append(product, s.Union(t))
+			}
+		}
+		sets = product
+	}
+	if len(sets) == 1 {
+		return nil
+	}
+	return sets
+}
+
+var errTooManyGroupingSets = pgerror.Newf(
+	pgcode.ProgramLimitExceeded, "too many grouping sets present (maximum %d)", maxGroupingSets,
+)
+
+// buildGroupingSetItem builds the grouping columns for an item of a GROUP BY
+// clause, and returns its grouping sets. An expression (or a tuple of
+// expressions) is a single grouping set.
+func (b *Builder) buildGroupingSetItem(
+	e tree.Expr, selects tree.SelectExprs, projectionsScope, fromScope *scope,
+) []util.FastIntSet {
+	gs, ok := e.(*tree.GroupingSet)
+	if !ok {
+		return []util.FastIntSet{
+			b.buildGrouping(e, selects, projectionsScope, fromScope, fromScope.groupby.aggInScope),
+		}
+	}
+
+	switch gs.Type {
+	case tree.RollupGroupingSet:
+		// ROLLUP (a, b, c) is GROUPING SETS ((a, b, c), (a, b), (a), ()).
+		elems := b.buildGroupingSetElems(gs.Exprs, selects, projectionsScope, fromScope)
+		sets := make([]util.FastIntSet, len(elems)+1)
+		for i := range elems {
+			sets[len(elems)-1-i] = sets[len(elems)-i].Union(elems[i])
+		}
+		return sets
+
+	case tree.CubeGroupingSet:
+		// CUBE (a, b) is GROUPING SETS ((a, b), (a), (b), ()).
+		if len(gs.Exprs) > maxCubeElements {
+			panic(pgerror.Newf(pgcode.ProgramLimitExceeded,
+				"CUBE is limited to %d elements", maxCubeElements))
+		}
+		elems := b.buildGroupingSetElems(gs.Exprs, selects, projectionsScope, fromScope)
+		sets := make([]util.FastIntSet, 0, 1<<len(elems))
+		for mask := (1 << len(elems)) - 1; mask >= 0; mask-- {
+			var set util.FastIntSet
+			for i := range elems {
+				if mask&(1<<(len(elems)-1-i)) != 0 {
+					set.UnionWith(elems[i])
+				}
+			}
+			sets = append(sets, set)
+		}
+		return sets
+
+	case tree.ExplicitGroupingSets:
+		var sets []util.FastIntSet
+		for _, item := range gs.Exprs {
+			sets = append(sets, b.buildGroupingSetItem(item, selects, projectionsScope, fromScope)...)
+			if len(sets) > maxGroupingSets {
+				panic(errTooManyGroupingSets)
+			}
+		}
+		return sets
+
+	default:
+		panic(errors.AssertionFailedf("unknown grouping set type %v", gs.Type))
+	}
+}
+
+// buildGroupingSetElems builds the grouping columns for the elements of ROLLUP
+// or CUBE, and returns the set of grouping columns of each element.
+func (b *Builder) buildGroupingSetElems(
+	exprs tree.Exprs, selects tree.SelectExprs, projectionsScope, fromScope *scope,
+) []util.FastIntSet {
+	elems := make([]util.FastIntSet, len(exprs))
+	for i, e := range exprs {
+		elems[i] = b.buildGrouping(e, selects, projectionsScope, fromScope, fromScope.groupby.aggInScope)
+	}
+	return elems
+}
+
+// buildGroupingSetOutputCols synthesizes the grouping columns in the
+// aggOutScope when there are several grouping sets, and makes the GROUP BY
+// expressions refer to them.
+func (b *Builder) buildGroupingSetOutputCols(g *groupby) {
+	groupingCols := g.groupingCols()
+	outOrds := make(map[opt.ColumnID]int, len(groupingCols))
+	for i := range groupingCols {
+		col := &groupingCols[i]
+		b.synthesizeColumn(g.aggOutScope, string(col.name), col.typ, col.expr, nil /* scalar */)
+		outOrds[col.id] = len(g.aggOutScope.cols) - 1
+	}
+	for exprStr, col := range g.groupStrs {
+		g.groupStrs[exprStr] = &g.aggOutScope.cols[outOrds[col.id]]
+	}
+}
+
+// buildGroupingFunc builds a call to GROUPING. The arguments must be GROUP BY
+// expressions. With several grouping sets, the function is computed by the
+// aggregation and referenced through a column of the aggOutScope; otherwise,
+// its result is always zero.
+//
+// See Builder.buildStmt for a description of the remaining input and return
+// values.
+func (b *Builder) buildGroupingFunc(
+	f *tree.FuncExpr, inScope, outScope *scope, outCol *scopeColumn, colRefs *opt.ColSet,
+) opt.ScalarExpr {
+	if inScope.inAgg {
+		panic(pgerror.New(pgcode.Grouping, "aggregate function calls cannot contain grouping operations"))
+	}
+	if !inScope.inGroupingContext() {
+		panic(sqlerrors.NewInvalidGroupingArgsError())
+	}
+	g := inScope.groupby
+	if g.buildingGroupingCols {
+		panic(pgerror.New(pgcode.Grouping, "grouping operations are not allowed in GROUP BY"))
+	}
+	if len(f.Exprs) > maxGroupingFuncArgs {
+		panic(pgerror.Newf(pgcode.TooManyArguments,
+			"GROUPING must have fewer than %d arguments", maxGroupingFuncArgs+1))
+	}
+
+	ords := make([]int, len(f.Exprs))
+	for i, e := range f.Exprs {
+		col, ok := g.groupStrs[symbolicExprStr(tree.StripParens(e).(tree.TypedExpr))]
+		if !ok {
+			panic(sqlerrors.NewInvalidGroupingArgsError())
+		}
+		ords[i] = g.groupingOutColOrdinal(col.id)
+	}
+
+	if g.groupingSets == nil {
+		// All the arguments are part of the only grouping set.
+		zero := b.factory.ConstructConstVal(tree.NewDInt(0), types.Int)
+		return b.finishBuildScalar(f, zero, inScope, outScope, outCol)
+	}
+
+	info := g.findGroupingFunc(ords)
+	if info == nil {
+		col := b.synthesizeColumn(g.aggOutScope, "grouping", types.Int, f, nil /* scalar */)
+		g.groupingFuncs = append(g.groupingFuncs, groupingFuncInfo{ords: ords, col: *col})
+		info = &g.groupingFuncs[len(g.groupingFuncs)-1]
+	}
+	return b.finishBuildScalarRef(&info.col, g.aggOutScope, outScope, outCol, colRefs)
+}
+
+// findGroupingFunc finds a GROUPING function with the given arguments. Returns
+// nil if the function is not found.
+func (g *groupby) findGroupingFunc(ords []int) *groupingFuncInfo {
+	for i := range g.groupingFuncs {
+		f := &g.groupingFuncs[i]
+		if len(f.ords) != len(ords) {
+			continue
+		}
+		match := true
+		for j := range ords {
+			if f.ords[j] != ords[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return f
+		}
+	}
+	return nil
+}
+
+// constructGroupingSets constructs the expansion of an aggregation with
+// several grouping sets; see the comment at the top of the file. input is the
+// pre-projection, and aggCols are the columns of the aggregate functions.
+func (b *Builder) constructGroupingSets(
+	input memo.RelExpr, g *groupby, aggCols []scopeColumn,
+) memo.RelExpr {
+	if !input.Relational().OuterCols.Empty() {
+		panic(unimplementedWithIssueDetailf(46280, "correlated",
+			"ROLLUP, CUBE and GROUPING SETS are not supported in correlated subqueries"))
+	}
+	md := b.factory.Metadata()
+
+	// Hoist the pre-projection into a CTE, so that it is computed once for all
+	// the grouping sets.
+	withID := b.factory.Memo().NextWithID()
+	md.AddWithBinding(withID, input)
+	b.cteStack[len(b.cteStack)-1] = append(b.cteStack[len(b.cteStack)-1], cteSource{
+		expr: input,
+		id:   withID,
+	})
+	inputCols := opt.ColSetToList(input.Relational().OutputCols)
+
+	// Deduplicate the aggregations, as in constructGroupBy.
+	var aggColSet opt.ColSet
+	aggs := make([]scopeColumn, 0, len(aggCols))
+	for i := range aggCols {
+		if !aggColSet.Contains(aggCols[i].id) {
+			aggs = append(aggs, aggCols[i])
+			aggColSet.Add(aggCols[i].id)
+		}
+	}
+	groupingCols := g.groupingCols()
+	groupingOutCols := g.groupingOutCols()
+
+	// The output columns are the aggregations, followed by the grouping
+	// columns and the GROUPING functions.
+	outCols := make(opt.ColList, 0, len(aggs)+len(groupingCols)+len(g.groupingFuncs))
+	for i := range aggs {
+		outCols = append(outCols, aggs[i].id)
+	}
+	for i := range groupingOutCols {
+		outCols = append(outCols, groupingOutCols[i].id)
+	}
+	for i := range g.groupingFuncs {
+		outCols = append(outCols, g.groupingFuncs[i].col.id)
+	}
+
+	var result memo.RelExpr
+	var resultCols opt.ColList
+	for i, set := range g.groupingSets {
+		// Read the buffered pre-projection with new column IDs.
+		var colMap opt.ColMap
+		scanCols := make(opt.ColList, len(inputCols))
+		for j, id := range inputCols {
+			colMeta := md.ColumnMeta(id)
+			scanCols[j] = md.AddColumn(colMeta.Alias, colMeta.Type)
+			colMap.Set(int(id), int(scanCols[j]))
+		}
+		scan := b.factory.ConstructWithScan(&memo.WithScanPrivate{
+			With:    withID,
+			InCols:  inputCols,
+			OutCols: scanCols,
+			ID:      md.NextUniqueID(),
+		})
+
+		// Group on the columns of the grouping set.
+		cols := make(opt.ColList, 0, len(outCols))
+		aggregations := make(memo.AggregationsExpr, len(aggs))
+		for j := range aggs {
+			colMeta := md.ColumnMeta(aggs[j].id)
+			id := md.AddColumn(colMeta.Alias, colMeta.Type)
+			aggregations[j] = b.factory.ConstructAggregationsItem(
+				b.factory.CustomFuncs().RemapCols(aggs[j].scalar, colMap), id,
+			)
+			cols = append(cols, id)
+		}
+		private := memo.GroupingPrivate{}
+		set.ForEach(func(ord int) {
+			id, _ := colMap.Get(int(groupingCols[ord].id))
+			private.GroupingCols.Add(opt.ColumnID(id))
+		})
+		var groupBy memo.RelExpr
+		if private.GroupingCols.Empty() {
+			groupBy = b.factory.ConstructScalarGroupBy(scan, aggregations, &private)
+		} else {
+			groupBy = b.factory.ConstructGroupBy(scan, aggregations, &private)
+		}
+
+		// Output NULL for the grouping columns which are not part of the set,
+		// and the values of the GROUPING functions.
+		passthrough := aggregations.OutputCols()
+		var projections memo.ProjectionsExpr
+		for ord := range groupingCols {
+			if set.Contains(ord) {
+				id, _ := colMap.Get(int(groupingCols[ord].id))
+				passthrough.Add(opt.ColumnID(id))
+				cols = append(cols, opt.ColumnID(id))
+				continue
+			}
+			typ := groupingCols[ord].typ
+			id := md.AddColumn(string(groupingCols[ord].name), typ)
+			projections = append(projections, b.factory.ConstructProjectionsItem(
+				b.factory.ConstructNull(typ), id,
+			))
+			cols = append(cols, id)
+		}
+		for j := range g.groupingFuncs {
+			f := &g.groupingFuncs[j]
+			id := md.AddColumn(string(f.col.name), types.Int)
+			projections = append(projections, b.factory.ConstructProjectionsItem(
+				b.factory.ConstructConstVal(tree.NewDInt(tree.DInt(f.value(set))), types.Int), id,
+			))
+			cols = append(cols, id)
+		}
+		branch := b.factory.ConstructProject(groupBy, projections, passthrough)
+
+		if i == 0 {
+			result, resultCols = branch, cols
+			continue
+		}
+		unionCols := outCols
+		if i < len(g.groupingSets)-1 {
+			unionCols = make(opt.ColList, len(outCols))
+			for j, id := range outCols {
+				colMeta := md.ColumnMeta(id)
+				unionCols[j] = md.AddColumn(colMeta.Alias, colMeta.Type)
+			}
+		}
+		result = b.factory.ConstructUnionAll(result, branch, &memo.SetPrivate{
+			LeftCols:  resultCols,
+			RightCols: cols,
+			OutCols:   unionCols,
+		})
+		resultCols = unionCols
+	}
+	return result
+}
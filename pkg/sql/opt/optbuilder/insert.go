@@ -210,6 +210,7 @@ func (b *Builder) buildInsert(ins *tree.Insert, inScope *scope) (outScope *scope
 			// UPSERT and INDEX ON CONFLICT DO UPDATE may modify rows if the
 			// DO NOTHING clause is not present.
 			b.checkPrivilege(depName, tab, privilege.UPDATE)
+			checkNoTriggersForUpsert(tab)
 		}
 	}
 
@@ -624,6 +625,11 @@ func (mb *mutationBuilder) addSynthesizedColsForInsert() {
 	// synthesized or not).
 	mb.roundDecimalValues(mb.insertColIDs, false /* roundComputedCols */)
 
+	// Run any BEFORE INSERT triggers, which can change the inserted values, and
+	// round the changed values.
+	mb.buildBeforeTriggers(tree.TriggerInsert)
+	mb.roundDecimalValues(mb.insertColIDs, false /* roundComputedCols */)
+
 	// Now add all computed columns.
 	mb.addSynthesizedCols(
 		mb.insertColIDs,
@@ -656,6 +662,8 @@ func (mb *mutationBuilder) buildInsert(returning tree.ReturningExprs) {
 
 	mb.buildFKChecksForInsert()
 
+	mb.buildAfterTriggers(tree.TriggerInsert)
+
 	private := mb.makeMutationPrivate(returning != nil)
 	mb.outScope.expr = mb.b.factory.ConstructInsert(
 		mb.outScope.expr, mb.uniqueChecks, mb.fkChecks, private,
@@ -1080,6 +1088,8 @@ func (mb *mutationBuilder) buildUpsert(returning tree.ReturningExprs) {
 		mb.projectPartialIndexPutCols(preCheckScope)
 	}
 
+	mb.buildUniqueChecksForUpsert()
+
 	mb.buildFKChecksForUpsert()
 
 	private := mb.makeMutationPrivate(returning != nil)
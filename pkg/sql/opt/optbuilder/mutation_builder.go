@@ -162,13 +162,17 @@ type mutationBuilder struct {
 	// cascades contains foreign key check cascades; see buildFK* methods.
 	cascades memo.FKCascades
 
-	// withID is nonzero if we need to buffer the input for FK checks.
+	// triggers contains the AFTER triggers; see buildAfterTriggers.
+	triggers memo.Triggers
+
+	// withID is nonzero if we need to buffer the input for FK checks or
+	// triggers.
 	withID opt.WithID
 
 	// extraAccessibleCols stores all the columns that are available to the
 	// mutation that are not part of the target table. This is useful for
-	// UPDATE ... FROM queries, as the columns from the FROM tables must be
-	// made accessible to the RETURNING clause.
+	// UPDATE ... FROM and DELETE ... USING queries, as the columns from the
+	// FROM and USING tables must be made accessible to the RETURNING clause.
 	extraAccessibleCols []scopeColumn
 
 	// fkCheckHelper is used to prevent allocating the helper separately.
@@ -281,21 +285,7 @@ func (mb *mutationBuilder) buildInputForUpdate(
 	// together with the table being updated.
 	fromClausePresent := len(from) > 0
 	if fromClausePresent {
-		fromScope := mb.b.buildFromTables(from, noRowLocking, inScope)
-
-		// Check that the same table name is not used multiple times.
-		mb.b.validateJoinTableNames(mb.outScope, fromScope)
-
-		// The FROM table columns can be accessed by the RETURNING clause of the
-		// query and so we have to make them accessible.
-		mb.extraAccessibleCols = fromScope.cols
-
-		// Add the columns in the FROM scope.
-		mb.outScope.appendColumnsFromScope(fromScope)
-
-		left := mb.outScope.expr.(memo.RelExpr)
-		right := fromScope.expr.(memo.RelExpr)
-		mb.outScope.expr = mb.b.factory.ConstructInnerJoin(left, right, memo.TrueFilter, memo.EmptyJoinPrivate)
+		mb.buildJoinWithTables(inScope, from)
 	}
 
 	// WHERE
@@ -318,25 +308,51 @@ func (mb *mutationBuilder) buildInputForUpdate(
 	// Build a distinct on to ensure there is at most one row in the joined output
 	// for every row in the table.
 	if fromClausePresent {
-		var pkCols opt.ColSet
-
-		// We need to ensure that the join has a maximum of one row for every row
-		// in the table and we ensure this by constructing a distinct on the primary
-		// key columns.
-		primaryIndex := mb.tab.Index(cat.PrimaryIndex)
-		for i := 0; i < primaryIndex.KeyColumnCount(); i++ {
-			// If the primary key column is hidden, then we don't need to use it
-			// for the distinct on.
-			if col := primaryIndex.Column(i); !col.IsHidden() {
-				pkCols.Add(mb.fetchColIDs[col.Ordinal()])
-			}
-		}
+		mb.buildDistinctOnPrimaryKey()
+	}
+}
 
-		if !pkCols.Empty() {
-			mb.outScope = mb.b.buildDistinctOn(
-				pkCols, mb.outScope, false /* nullsAreDistinct */, "" /* errorOnDup */)
-		}
+// buildJoinWithTables builds the given FROM (for UPDATE) or USING (for DELETE)
+// tables and inner joins them with the target table scan in mb.outScope. Any
+// join conditions are expected to be in the WHERE clause, which is built on top
+// of the join. The columns of the joined tables are stored in the mutation
+// builder so that they can be referenced by the RETURNING clause.
+func (mb *mutationBuilder) buildJoinWithTables(inScope *scope, tables tree.TableExprs) {
+	fromScope := mb.b.buildFromTables(tables, noRowLocking, inScope)
+
+	// Check that the same table name is not used multiple times.
+	mb.b.validateJoinTableNames(mb.outScope, fromScope)
+
+	// The FROM table columns can be accessed by the RETURNING clause of the
+	// query and so we have to make them accessible.
+	mb.extraAccessibleCols = fromScope.cols
+
+	// Add the columns in the FROM scope.
+	mb.outScope.appendColumnsFromScope(fromScope)
+
+	left := mb.outScope.expr.(memo.RelExpr)
+	right := fromScope.expr.(memo.RelExpr)
+	mb.outScope.expr = mb.b.factory.ConstructInnerJoin(left, right, memo.TrueFilter, memo.EmptyJoinPrivate)
+}
+
+// buildDistinctOnPrimaryKey wraps mb.outScope in a DistinctOn on the primary
+// key columns of the target table. This ensures that a join between the target
+// table and the FROM or USING tables produces at most one row for every row in
+// the target table, so that each row is updated or deleted only once. If a row
+// joins with several rows, an arbitrary one of them is chosen.
+func (mb *mutationBuilder) buildDistinctOnPrimaryKey() {
+	var pkCols opt.ColSet
+
+	// Hidden primary key columns (such as the implicit rowid column) must be
+	// included as well; otherwise a row of a table without an explicit primary
+	// key could be updated or deleted multiple times.
+	primaryIndex := mb.tab.Index(cat.PrimaryIndex)
+	for i := 0; i < primaryIndex.KeyColumnCount(); i++ {
+		pkCols.Add(mb.fetchColIDs[primaryIndex.Column(i).Ordinal()])
 	}
+
+	mb.outScope = mb.b.buildDistinctOn(
+		pkCols, mb.outScope, false /* nullsAreDistinct */, "" /* errorOnDup */)
 }
 
 // buildInputForDelete constructs a Select expression from the fields in
@@ -349,9 +365,18 @@ func (mb *mutationBuilder) buildInputForUpdate(
 //   LIMIT <limit>
 //
 // All columns from the table to update are added to fetchColList.
+// If a USING clause is defined, the USING tables are joined with the target
+// table in the same way as the FROM tables of an UPDATE; see
+// buildInputForUpdate. A row of the target table that matches multiple rows
+// of the USING tables is deleted (and returned) only once.
 // TODO(andyk): Do needed column analysis to project fewer columns if possible.
 func (mb *mutationBuilder) buildInputForDelete(
-	inScope *scope, texpr tree.TableExpr, where *tree.Where, limit *tree.Limit, orderBy tree.OrderBy,
+	inScope *scope,
+	texpr tree.TableExpr,
+	using tree.TableExprs,
+	where *tree.Where,
+	limit *tree.Limit,
+	orderBy tree.OrderBy,
 ) {
 	var indexFlags *tree.IndexFlags
 	if source, ok := texpr.(*tree.AliasedTableExpr); ok && source.IndexFlags != nil {
@@ -382,6 +407,16 @@ func (mb *mutationBuilder) buildInputForDelete(
 	)
 	mb.outScope = mb.fetchScope
 
+	// Set list of columns that will be fetched by the input expression.
+	mb.setFetchColIDs(mb.outScope.cols)
+
+	// If there is a USING clause present, we must join all the tables
+	// together with the table being deleted from.
+	usingClausePresent := len(using) > 0
+	if usingClausePresent {
+		mb.buildJoinWithTables(inScope, using)
+	}
+
 	// WHERE
 	mb.b.buildWhere(where, mb.outScope)
 
@@ -399,8 +434,11 @@ func (mb *mutationBuilder) buildInputForDelete(
 
 	mb.outScope = projectionsScope
 
-	// Set list of columns that will be fetched by the input expression.
-	mb.setFetchColIDs(mb.outScope.cols)
+	// Build a distinct on to ensure there is at most one row in the joined output
+	// for every row in the table.
+	if usingClausePresent {
+		mb.buildDistinctOnPrimaryKey()
+	}
 }
 
 // addTargetColsByName adds one target column for each of the names in the given
@@ -936,13 +974,25 @@ func (mb *mutationBuilder) makeMutationPrivate(needResults bool) *memo.MutationP
 		PartialIndexPutCols: checkEmptyList(mb.partialIndexPutColIDs),
 		PartialIndexDelCols: checkEmptyList(mb.partialIndexDelColIDs),
 		FKCascades:          mb.cascades,
+		Triggers:            mb.triggers,
 	}
 
-	// If we didn't actually plan any checks or cascades, don't buffer the input.
-	if len(mb.uniqueChecks) > 0 || len(mb.fkChecks) > 0 || len(mb.cascades) > 0 {
+	// If we didn't actually plan any checks, cascades or triggers, don't buffer
+	// the input.
+	if len(mb.uniqueChecks) > 0 || len(mb.fkChecks) > 0 || len(mb.cascades) > 0 ||
+		len(mb.triggers) > 0 {
 		private.WithID = mb.withID
 	}
 
+	// The RETURNING clause of UPDATE ... FROM and DELETE ... USING can refer to
+	// the columns of the FROM and USING tables, so the mutation must pass them
+	// through from its input.
+	for _, col := range mb.extraAccessibleCols {
+		if col.id != 0 {
+			private.PassthroughCols = append(private.PassthroughCols, col.id)
+		}
+	}
+
 	if needResults {
 		private.ReturnCols = make(opt.ColList, mb.tab.ColumnCount())
 		for i, n := 0, mb.tab.ColumnCount(); i < n; i++ {
@@ -1023,8 +1073,8 @@ func (mb *mutationBuilder) buildReturning(returning tree.ReturningExprs) {
 
 	// extraAccessibleCols contains all the columns that the RETURNING
 	// clause can refer to in addition to the table columns. This is useful for
-	// UPDATE ... FROM statements, where all columns from tables in the FROM clause
-	// are in scope for the RETURNING clause.
+	// UPDATE ... FROM and DELETE ... USING statements, where all columns from
+	// tables in the FROM or USING clause are in scope for the RETURNING clause.
 	inScope.appendColumns(mb.extraAccessibleCols)
 
 	// Construct the Project operator that projects the RETURNING expressions.
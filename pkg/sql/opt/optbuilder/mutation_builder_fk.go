@@ -172,7 +172,9 @@ func (mb *mutationBuilder) buildFKChecksAndCascadesForDelete() {
 
 		mb.ensureWithID()
 		fkInput, withScanCols, _ := mb.makeCheckInputScan(checkInputScanFetchedVals, h.tabOrdinals)
-		mb.fkChecks = append(mb.fkChecks, h.buildDeletionCheck(fkInput, withScanCols))
+		mb.fkChecks = append(mb.fkChecks, h.buildDeletionCheck(
+			fkInput, withScanCols, h.fk.DeleteReferenceAction(),
+		))
 	}
 	telemetry.Inc(sqltelemetry.ForeignKeyChecksUseCounter)
 }
@@ -352,7 +354,9 @@ func (mb *mutationBuilder) buildFKChecksForUpdate() {
 			},
 		)
 
-		mb.fkChecks = append(mb.fkChecks, h.buildDeletionCheck(deletedRows, colsForOldRow))
+		mb.fkChecks = append(mb.fkChecks, h.buildDeletionCheck(
+			deletedRows, colsForOldRow, h.fk.UpdateReferenceAction(),
+		))
 	}
 	telemetry.Inc(sqltelemetry.ForeignKeyChecksUseCounter)
 }
@@ -454,7 +458,9 @@ func (mb *mutationBuilder) buildFKChecksForUpsert() {
 				OutCols:   colsForOldRow,
 			},
 		)
-		mb.fkChecks = append(mb.fkChecks, h.buildDeletionCheck(deletedRows, colsForOldRow))
+		mb.fkChecks = append(mb.fkChecks, h.buildDeletionCheck(
+			deletedRows, colsForOldRow, h.fk.UpdateReferenceAction(),
+		))
 	}
 	telemetry.Inc(sqltelemetry.ForeignKeyChecksUseCounter)
 }
@@ -736,15 +742,18 @@ func (h *fkCheckHelper) buildInsertionCheck() memo.FKChecksItem {
 		FKOrdinal:       h.fkOrdinal,
 		KeyCols:         withScanCols,
 		OpName:          h.mb.opName,
+		Deferrable:      h.fk.Deferrable(),
 	})
 }
 
 // buildDeletionCheck creates a FK check for rows which are removed from a
 // table. deletedRows is used as the input to the deletion check, and deleteCols
 // is a list of the columns for the rows being deleted, containing values for
-// the referenced FK columns in the table we are mutating.
+// the referenced FK columns in the table we are mutating. action is the
+// reference action of the FK for the mutation; only NO ACTION checks can be
+// deferred.
 func (h *fkCheckHelper) buildDeletionCheck(
-	deletedRows memo.RelExpr, deleteCols opt.ColList,
+	deletedRows memo.RelExpr, deleteCols opt.ColList, action tree.ReferenceAction,
 ) memo.FKChecksItem {
 	// Build a semi join, with the referenced FK columns on the left and the
 	// origin columns on the right.
@@ -778,5 +787,6 @@ func (h *fkCheckHelper) buildDeletionCheck(
 		FKOrdinal:       h.fkOrdinal,
 		KeyCols:         deleteCols,
 		OpName:          h.mb.opName,
+		Deferrable:      h.fk.Deferrable() && action == tree.NoAction,
 	})
 }
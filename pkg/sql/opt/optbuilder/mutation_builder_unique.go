@@ -57,6 +57,57 @@ func (mb *mutationBuilder) buildUniqueChecksForInsert() {
 	telemetry.Inc(sqltelemetry.UniqueChecksUseCounter)
 }
 
+// buildUniqueChecksForUpdate builds uniqueness check queries for an update.
+// Only constraints without an index which involve updated columns result in
+// checks; the new values of the updated rows are checked the same way as
+// inserted rows (see buildInsertionCheck).
+func (mb *mutationBuilder) buildUniqueChecksForUpdate() {
+	uniqueCount := mb.tab.UniqueCount()
+	if uniqueCount == 0 {
+		// No relevant unique checks.
+		return
+	}
+
+	h := &mb.uniqueCheckHelper
+	built := false
+	for i := 0; i < uniqueCount; i++ {
+		// If this constraint is already enforced by an index or none of its
+		// columns are updated, we don't need to plan a check.
+		if !mb.tab.Unique(i).WithoutIndex() || !mb.uniqueColsUpdated(i) {
+			continue
+		}
+		mb.ensureWithID()
+		if h.init(mb, i) {
+			mb.uniqueChecks = append(mb.uniqueChecks, h.buildInsertionCheck())
+			built = true
+		}
+	}
+	if built {
+		telemetry.Inc(sqltelemetry.UniqueChecksUseCounter)
+	}
+}
+
+// buildUniqueChecksForUpsert builds uniqueness check queries for an upsert.
+// The checks are the same as for an insert, since any of the rows might result
+// in an insert rather than an update. The "new" values of the unique columns
+// are already projected as part of the mutation input (see
+// buildFKChecksForUpsert).
+func (mb *mutationBuilder) buildUniqueChecksForUpsert() {
+	mb.buildUniqueChecksForInsert()
+}
+
+// uniqueColsUpdated returns true if any of the columns for a unique
+// constraint are being updated (according to updateColIDs).
+func (mb *mutationBuilder) uniqueColsUpdated(uniqueOrdinal int) bool {
+	u := mb.tab.Unique(uniqueOrdinal)
+	for i, n := 0, u.ColumnCount(); i < n; i++ {
+		if ord := u.ColumnOrdinal(mb.tab, i); mb.updateColIDs[ord] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // uniqueCheckHelper is a type associated with a single unique constraint and
 // is used to build the "leaves" of a unique check expression, namely the
 // WithScan of the mutation input and the Scan of the table.
@@ -180,6 +231,7 @@ func (h *uniqueCheckHelper) buildInsertionCheck() memo.UniqueChecksItem {
 		CheckOrdinal: h.uniqueOrdinal,
 		KeyCols:      withScanCols,
 		OpName:       h.mb.opName,
+		Deferrable:   h.unique.Deferrable(),
 	})
 }
 
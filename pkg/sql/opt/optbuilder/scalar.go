@@ -497,6 +497,10 @@ func (b *Builder) buildFunction(
 		panic(errors.AssertionFailedf("window function should have been replaced"))
 	}
 
+	if def.Name == "grouping" {
+		return b.buildGroupingFunc(f, inScope, outScope, outCol, colRefs)
+	}
+
 	args := make(memo.ScalarListExpr, len(f.Exprs))
 	for i, pexpr := range f.Exprs {
 		args[i] = b.buildScalar(pexpr.(tree.TypedExpr), inScope, nil, nil, colRefs)
@@ -919,6 +919,10 @@ func (s *scope) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
 	case *tree.FuncExpr:
 		def, err := t.Func.Resolve(s.builder.semaCtx.SearchPath)
 		if err != nil {
+			if fn := s.builder.resolveUDF(t, err); fn != nil {
+				expr = s.replaceUDF(t, fn)
+				break
+			}
 			panic(err)
 		}
 
@@ -305,7 +305,8 @@ func (b *Builder) buildSubqueryProjection(
 func (b *Builder) buildSingleRowSubquery(
 	s *subquery, inScope *scope,
 ) (out opt.ScalarExpr, outScope *scope) {
-	subqueryPrivate := memo.SubqueryPrivate{OriginalExpr: s.Subquery}
+	_, fromUDF := b.udfSubqueries[s.Subquery]
+	subqueryPrivate := memo.SubqueryPrivate{OriginalExpr: s.Subquery, FromUDF: fromUDF}
 	if s.Exists {
 		return b.factory.ConstructExists(s.node, &subqueryPrivate), inScope
 	}
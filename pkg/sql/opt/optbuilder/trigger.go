@@ -0,0 +1,390 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package optbuilder
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/cat"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/memo"
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/norm"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/errorutil/unimplemented"
+	"github.com/cockroachdb/errors"
+)
+
+// Row-level triggers execute a trigger function for each row inserted, updated
+// or deleted by a mutation. The body of the trigger function references the
+// values of the row as new.<column> and old.<column>. The old row is NULL for
+// inserts and the new row is NULL for deletes.
+//
+// BEFORE triggers are built as part of the mutation input. The body of their
+// function must be a SELECT statement returning the row to be written, with one
+// column for each visible column of the table (like new.*); the row is skipped
+// if the function does not return a row. For example, given:
+//
+//   CREATE FUNCTION f() RETURNS TRIGGER AS
+//     'SELECT new.k, lower(new.v) WHERE new.v IS NOT NULL'
+//   CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION f()
+//
+// the input of an INSERT into t is built as an apply-join:
+//
+//   insert t
+//    └── inner-join-apply
+//         ├── <insert input>
+//         ├── project
+//         │    ├── select
+//         │    │    ├── values
+//         │    │    └── filters
+//         │    │         └── new.v IS NOT NULL
+//         │    └── projections
+//         │         ├── new.k
+//         │         └── lower(new.v)
+//         └── filters (true)
+//
+// The values of the computed columns are NULL in the new row seen by BEFORE
+// triggers; they are computed after all the BEFORE triggers run, and the values
+// returned for them are ignored. BEFORE DELETE triggers are built as a
+// semi-join: the row is only deleted if the function returns a row.
+//
+// AFTER triggers are run after the mutation, in the same way as foreign key
+// cascades (see memo.Trigger). The mutation input is buffered, and the query
+// executed by the trigger function is planned once for each modified row, with
+// the references to the row replaced by its values. The function body can be
+// an INSERT, UPSERT, UPDATE or DELETE statement, which in turn can fire more
+// cascades and triggers.
+
+// buildBeforeTriggers builds the BEFORE triggers defined on the target table
+// for the given event. The triggers can change the values of the new row,
+// which are stored in mb.insertColIDs (for inserts) or mb.updateColIDs (for
+// updates).
+func (mb *mutationBuilder) buildBeforeTriggers(event tree.TriggerEvent) {
+	for i, n := 0, mb.tab.TriggerCount(); i < n; i++ {
+		trigger := mb.tab.Trigger(i)
+		if trigger.FiresOn(tree.TriggerBefore, event) {
+			mb.buildBeforeTrigger(&trigger, event)
+		}
+	}
+}
+
+// buildBeforeTrigger builds a single BEFORE trigger, wrapping mb.outScope in
+// an apply-join with the body of the trigger function.
+func (mb *mutationBuilder) buildBeforeTrigger(trigger *cat.Trigger, event tree.TriggerEvent) {
+	fn := mb.b.resolveTriggerFunction(trigger)
+	body, ok := parseTriggerFunctionBody(
+		fn.Name(), fn.Body(), pgcode.InvalidFunctionDefinition,
+	).(*tree.Select)
+	if !ok {
+		panic(errors.WithHint(
+			pgerror.Newf(pgcode.FeatureNotSupported,
+				"BEFORE trigger %s cannot execute function %s, which modifies data",
+				tree.ErrNameString(trigger.Name), tree.ErrString(fn.Name())),
+			"use an AFTER trigger instead",
+		))
+	}
+	// Only the first row returned by the function is used.
+	limitToOneRow(body)
+
+	// Collect the columns holding the values of the old and new rows. A zero
+	// column ID indicates a NULL value.
+	var oldCols, newCols opt.ColList
+	for i, n := 0, mb.tab.ColumnCount(); i < n; i++ {
+		col := mb.tab.Column(i)
+		if col.Kind() != cat.Ordinary {
+			continue
+		}
+		var oldCol, newCol opt.ColumnID
+		switch event {
+		case tree.TriggerInsert:
+			newCol = mb.insertColIDs[i]
+		case tree.TriggerUpdate:
+			oldCol = mb.fetchColIDs[i]
+			if newCol = mb.updateColIDs[i]; newCol == 0 {
+				newCol = mb.fetchColIDs[i]
+			}
+		case tree.TriggerDelete:
+			oldCol = mb.fetchColIDs[i]
+		}
+		if col.IsComputed() {
+			// Computed columns are not yet computed for the new row.
+			newCol = 0
+		}
+		oldCols = append(oldCols, oldCol)
+		newCols = append(newCols, newCol)
+	}
+	mb.projectTriggerNullCols(oldCols)
+	mb.projectTriggerNullCols(newCols)
+
+	rowScope := mb.b.allocScope()
+	addTriggerRowCols(rowScope, mb.tab, "new", newCols)
+	addTriggerRowCols(rowScope, mb.tab, "old", oldCols)
+
+	if event == tree.TriggerDelete {
+		// The row is deleted only if the function returns a row.
+		bodyScope := mb.b.buildStmt(body, nil /* desiredTypes */, rowScope)
+		mb.outScope.expr = mb.b.factory.ConstructSemiJoinApply(
+			mb.outScope.expr, bodyScope.expr, memo.TrueFilter, memo.EmptyJoinPrivate,
+		)
+		return
+	}
+
+	// The function returns the new row, with one column for each visible column
+	// of the table.
+	var resultOrds []int
+	var desiredTypes []*types.T
+	for i, n := 0, mb.tab.ColumnCount(); i < n; i++ {
+		if col := mb.tab.Column(i); col.Kind() == cat.Ordinary && !col.IsHidden() {
+			resultOrds = append(resultOrds, i)
+			desiredTypes = append(desiredTypes, col.DatumType())
+		}
+	}
+	bodyScope := mb.b.buildStmt(body, desiredTypes, rowScope)
+	if len(bodyScope.cols) != len(resultOrds) {
+		panic(pgerror.Newf(pgcode.DatatypeMismatch,
+			"function %s returned row structure does not match the structure of table %s",
+			tree.ErrString(fn.Name()), tree.ErrNameString(string(mb.tab.Name()))))
+	}
+
+	// Rows for which the function returns no row are skipped.
+	mb.outScope.expr = mb.b.factory.ConstructInnerJoinApply(
+		mb.outScope.expr, bodyScope.expr, memo.TrueFilter, memo.EmptyJoinPrivate,
+	)
+	numInputCols := len(mb.outScope.cols)
+	mb.outScope.appendColumnsFromScope(bodyScope)
+
+	colIDs := mb.insertColIDs
+	if event == tree.TriggerUpdate {
+		colIDs = mb.updateColIDs
+	}
+	for i, ord := range resultOrds {
+		col := mb.tab.Column(ord)
+		scopeCol := &mb.outScope.cols[numInputCols+i]
+		scopeCol.clearName()
+		if col.IsComputed() {
+			// The values returned for computed columns are ignored.
+			continue
+		}
+		checkDatumTypeFitsColumnType(col, scopeCol.typ)
+		scopeCol.name = col.ColName()
+		colIDs[ord] = scopeCol.id
+	}
+
+	// Make sure that the names of the table columns refer to the values returned
+	// by the function (which can be referenced by computed columns).
+	mb.disambiguateColumns()
+}
+
+// projectTriggerNullCols replaces the zero column IDs in the given list with
+// new columns projecting NULL values of the corresponding column types.
+func (mb *mutationBuilder) projectTriggerNullCols(cols opt.ColList) {
+	var projectionsScope *scope
+	var j int
+	for i, n := 0, mb.tab.ColumnCount(); i < n; i++ {
+		col := mb.tab.Column(i)
+		if col.Kind() != cat.Ordinary {
+			continue
+		}
+		if cols[j] == 0 {
+			if projectionsScope == nil {
+				projectionsScope = mb.outScope.replace()
+				projectionsScope.appendColumnsFromScope(mb.outScope)
+			}
+			typ := col.DatumType()
+			scopeCol := mb.b.synthesizeColumn(
+				projectionsScope, string(col.ColName()), typ, nil /* expr */, mb.b.factory.ConstructNull(typ),
+			)
+			scopeCol.clearName()
+			cols[j] = scopeCol.id
+		}
+		j++
+	}
+
+	if projectionsScope != nil {
+		mb.b.constructProjectForScope(mb.outScope, projectionsScope)
+		mb.outScope = projectionsScope
+	}
+}
+
+// addTriggerRowCols adds the columns holding the values of the old or new row
+// to the given scope, qualified by the given table name. The columns correspond
+// 1-to-1 to the ordinary columns of the table.
+func addTriggerRowCols(rowScope *scope, tab cat.Table, tabName tree.Name, cols opt.ColList) {
+	var j int
+	for i, n := 0, tab.ColumnCount(); i < n; i++ {
+		col := tab.Column(i)
+		if col.Kind() != cat.Ordinary {
+			continue
+		}
+		rowScope.cols = append(rowScope.cols, scopeColumn{
+			name:   col.ColName(),
+			table:  tree.MakeUnqualifiedTableName(tabName),
+			typ:    col.DatumType(),
+			id:     cols[j],
+			hidden: col.IsHidden(),
+		})
+		j++
+	}
+}
+
+// buildAfterTriggers adds the AFTER triggers defined on the target table for
+// the given event to mb.triggers. The mutation input is buffered, and the
+// triggers are built and executed after the mutation.
+func (mb *mutationBuilder) buildAfterTriggers(event tree.TriggerEvent) {
+	for i, n := 0, mb.tab.TriggerCount(); i < n; i++ {
+		trigger := mb.tab.Trigger(i)
+		if !trigger.FiresOn(tree.TriggerAfter, event) {
+			continue
+		}
+		fn := mb.b.resolveTriggerFunction(&trigger)
+		mb.ensureWithID()
+
+		var oldValues, newValues opt.ColList
+		for ord, n := 0, mb.tab.ColumnCount(); ord < n; ord++ {
+			if mb.tab.Column(ord).Kind() != cat.Ordinary {
+				continue
+			}
+			if event != tree.TriggerInsert {
+				oldValues = append(oldValues, mb.fetchColIDs[ord])
+			}
+			if event != tree.TriggerDelete {
+				newValues = append(newValues, mb.mapToReturnColID(ord))
+			}
+		}
+
+		mb.triggers = append(mb.triggers, memo.Trigger{
+			Name: trigger.Name,
+			Builder: &afterTriggerBuilder{
+				tab:     mb.tab,
+				trigger: trigger,
+				fn:      fn,
+			},
+			WithID:    mb.withID,
+			OldValues: oldValues,
+			NewValues: newValues,
+		})
+	}
+}
+
+// checkNoTriggersForUpsert raises an error if the target table has triggers
+// which fire on inserts or updates; these are not supported for UPSERT and
+// INSERT ... ON CONFLICT DO UPDATE statements.
+func checkNoTriggersForUpsert(tab cat.Table) {
+	for i, n := 0, tab.TriggerCount(); i < n; i++ {
+		trigger := tab.Trigger(i)
+		if trigger.Events.Contains(tree.TriggerInsert) || trigger.Events.Contains(tree.TriggerUpdate) {
+			panic(unimplemented.NewWithIssuef(28296,
+				"UPSERT and INSERT ... ON CONFLICT DO UPDATE are not supported on table %s, "+
+					"which has trigger %s",
+				tree.ErrNameString(string(tab.Name())), tree.ErrNameString(trigger.Name)))
+		}
+	}
+}
+
+// resolveTriggerFunction returns the function executed by the given trigger,
+// and adds it to the metadata so that the query is invalidated if the function
+// changes.
+func (b *Builder) resolveTriggerFunction(trigger *cat.Trigger) cat.Function {
+	fn, err := b.catalog.ResolveFunctionByID(b.ctx, trigger.FunctionID)
+	if err != nil {
+		panic(err)
+	}
+	if !fn.IsTrigger() {
+		panic(errors.AssertionFailedf(
+			"function %s executed by trigger %s does not return trigger", fn.Name(), trigger.Name))
+	}
+	telemetry.Inc(sqltelemetry.TriggersUseCounter)
+	b.factory.Metadata().AddUserDefinedFunction(fn, nil /* name */)
+	return fn
+}
+
+// afterTriggerBuilder is a memo.TriggerBuilder implementation for AFTER
+// triggers.
+//
+// The body of the trigger function is built in a scope containing the columns
+// of the new and old rows, so that references to new.<column> and old.<column>
+// are resolved to these columns. The expression is then copied into the target
+// memo, replacing the references to the row columns with the values of the
+// modified row.
+type afterTriggerBuilder struct {
+	tab     cat.Table
+	trigger cat.Trigger
+	fn      cat.Function
+}
+
+var _ memo.TriggerBuilder = &afterTriggerBuilder{}
+
+// Build is part of the memo.TriggerBuilder interface.
+func (tb *afterTriggerBuilder) Build(
+	ctx context.Context,
+	semaCtx *tree.SemaContext,
+	evalCtx *tree.EvalContext,
+	catalog cat.Catalog,
+	factoryI interface{},
+	oldValues, newValues tree.Datums,
+) (memo.RelExpr, error) {
+	factory := factoryI.(*norm.Factory)
+
+	// The body is built in a separate memo, since CopyAndReplace requires an
+	// empty target memo.
+	var bodyFactory norm.Factory
+	bodyFactory.Init(evalCtx, catalog)
+	return buildCascadeHelper(ctx, semaCtx, evalCtx, catalog, &bodyFactory, func(b *Builder) memo.RelExpr {
+		body := parseTriggerFunctionBody(tb.fn.Name(), tb.fn.Body(), pgcode.InvalidFunctionDefinition)
+
+		// Bind the values of the row to new columns.
+		md := b.factory.Metadata()
+		rowScope := b.allocScope()
+		rowValues := make(map[opt.ColumnID]tree.Datum)
+		addRow := func(tabName tree.Name, values tree.Datums) {
+			cols := make(opt.ColList, 0, len(values))
+			var j int
+			for i, n := 0, tb.tab.ColumnCount(); i < n; i++ {
+				col := tb.tab.Column(i)
+				if col.Kind() != cat.Ordinary {
+					continue
+				}
+				id := md.AddColumn(string(col.ColName()), col.DatumType())
+				cols = append(cols, id)
+				if values != nil {
+					rowValues[id] = values[j]
+				} else {
+					rowValues[id] = tree.DNull
+				}
+				j++
+			}
+			addTriggerRowCols(rowScope, tb.tab, tabName, cols)
+		}
+		addRow("new", newValues)
+		addRow("old", oldValues)
+
+		b.pushWithFrame()
+		bodyScope := b.buildStmtAtRoot(body, nil /* desiredTypes */, rowScope)
+		b.popWithFrame(bodyScope)
+
+		var replaceFn norm.ReplaceFunc
+		replaceFn = func(e opt.Expr) opt.Expr {
+			if v, ok := e.(*memo.VariableExpr); ok {
+				if d, ok := rowValues[v.Col]; ok {
+					return factory.ConstructConstVal(d, v.Typ)
+				}
+			}
+			return factory.CopyAndReplaceDefault(e, replaceFn)
+		}
+		factory.CopyAndReplace(bodyScope.expr, bodyScope.makePhysicalProps(), replaceFn)
+		return factory.Memo().RootExpr().(memo.RelExpr)
+	})
+}
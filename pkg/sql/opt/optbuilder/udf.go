@@ -0,0 +1,176 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package optbuilder
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/cat"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/errorutil/unimplemented"
+	"github.com/cockroachdb/errors"
+)
+
+// resolveUDF attempts to resolve the function called by f as a user-defined
+// function, after its resolution as a builtin function failed with resolveErr.
+// It returns nil if there is no such user-defined function.
+//
+// Builtin functions take precedence over user-defined functions, so that
+// existing queries keep their meaning when a function is created.
+func (b *Builder) resolveUDF(f *tree.FuncExpr, resolveErr error) cat.Function {
+	if pgerror.GetPGCode(resolveErr) != pgcode.UndefinedFunction {
+		return nil
+	}
+	un, ok := f.Func.FunctionReference.(*tree.UnresolvedName)
+	if !ok {
+		return nil
+	}
+	name, err := un.ToUnresolvedObjectName(tree.NoAnnotation)
+	if err != nil {
+		return nil
+	}
+	fn, err := b.catalog.ResolveFunction(b.ctx, cat.Flags{}, name)
+	if err != nil {
+		panic(err)
+	}
+	if fn == nil {
+		return nil
+	}
+	if fn.IsTrigger() {
+		panic(pgerror.Newf(pgcode.FeatureNotSupported,
+			"trigger functions can only be called as triggers"))
+	}
+	if b.insideViewDef {
+		panic(unimplemented.NewWithIssuef(17511,
+			"user-defined function %s cannot be used inside a view or function definition",
+			tree.ErrString(fn.Name())))
+	}
+	b.factory.Metadata().AddUserDefinedFunction(fn, name)
+	return fn
+}
+
+// replaceUDF returns a subquery which computes the result of calling the
+// given user-defined function. A call to a function defined as:
+//
+//   CREATE FUNCTION f(a INT, b INT) RETURNS INT LANGUAGE SQL AS 'SELECT a + b'
+//
+// is rewritten to:
+//
+//   (SELECT (SELECT a + b LIMIT 1)::INT FROM (SELECT x::INT, y::INT) AS f(a, b))
+//
+// where x and y are the arguments of the call. The normalization rules
+// decorrelate the subquery, so that simple functions are inlined into the
+// calling query.
+func (s *scope) replaceUDF(f *tree.FuncExpr, fn cat.Function) *subquery {
+	if f.Type != 0 || f.WindowDef != nil || f.Filter != nil || len(f.OrderBy) > 0 {
+		panic(pgerror.Newf(pgcode.WrongObjectType,
+			"%s is not an aggregate function", tree.ErrString(fn.Name())))
+	}
+	if len(f.Exprs) != fn.ArgCount() {
+		panic(pgerror.Newf(pgcode.UndefinedFunction,
+			"unknown signature: %s() expects %d argument(s), got %d",
+			tree.ErrString(fn.Name()), fn.ArgCount(), len(f.Exprs)))
+	}
+
+	stmt, err := parser.ParseOne(fn.Body())
+	if err != nil {
+		panic(pgerror.Wrapf(err, pgcode.Syntax,
+			"failed to parse body of function %s", tree.ErrString(fn.Name())))
+	}
+	body, ok := stmt.AST.(*tree.Select)
+	if !ok {
+		panic(errors.AssertionFailedf("expected SELECT statement"))
+	}
+
+	// Only the first row of the body is returned, like in Postgres.
+	limitToOneRow(body)
+	bodySubquery := &tree.Subquery{Select: &tree.ParenSelect{Select: body}}
+	var result tree.Expr = &tree.CastExpr{
+		Expr:       bodySubquery,
+		Type:       fn.ReturnType(),
+		SyntaxMode: tree.CastShort,
+	}
+
+	if fn.ArgCount() > 0 {
+		// Bind the arguments to columns named after the function arguments.
+		args := make(tree.SelectExprs, len(f.Exprs))
+		cols := make(tree.NameList, len(f.Exprs))
+		for i := range f.Exprs {
+			args[i].Expr = &tree.CastExpr{
+				Expr:       f.Exprs[i],
+				Type:       fn.ArgType(i),
+				SyntaxMode: tree.CastShort,
+			}
+			cols[i] = udfArgColName(fn.ArgName(i), i)
+		}
+		result = &tree.Subquery{Select: &tree.ParenSelect{Select: &tree.Select{
+			Select: &tree.SelectClause{
+				Exprs: tree.SelectExprs{{Expr: result}},
+				From: tree.From{Tables: tree.TableExprs{&tree.AliasedTableExpr{
+					Expr: &tree.Subquery{Select: &tree.ParenSelect{Select: &tree.Select{
+						Select: &tree.SelectClause{Exprs: args},
+					}}},
+					As: tree.AliasClause{Alias: tree.Name(fn.Name().Object()), Cols: cols},
+				}}},
+			},
+		}}}
+	} else {
+		result = &tree.Subquery{Select: &tree.ParenSelect{Select: &tree.Select{
+			Select: &tree.SelectClause{Exprs: tree.SelectExprs{{Expr: result}}},
+		}}}
+	}
+
+	// Mark the subqueries so that calls with constant arguments can be folded;
+	// see the rule InlineConstValuesSubquery.
+	if s.builder.udfSubqueries == nil {
+		s.builder.udfSubqueries = make(map[*tree.Subquery]struct{})
+	}
+	s.builder.udfSubqueries[bodySubquery] = struct{}{}
+	s.builder.udfSubqueries[result.(*tree.Subquery)] = struct{}{}
+
+	return s.replaceSubquery(
+		result.(*tree.Subquery), false /* wrapInTuple */, 1 /* desiredNumColumns */, noExtraColsAllowed,
+	)
+}
+
+// udfArgColName returns the name of the column which holds the i-th argument
+// of a user-defined function inside the function body. Arguments without a
+// name can only be referenced positionally, as $1, $2, etc.; these references
+// are rewritten to the column name when the function is created.
+func udfArgColName(name tree.Name, i int) tree.Name {
+	if name != "" {
+		return name
+	}
+	return tree.Name(fmt.Sprintf("$%d", i+1))
+}
+
+// limitToOneRow limits the given SELECT statement to return at most its first
+// row, preserving any OFFSET and any smaller LIMIT.
+func limitToOneRow(sel *tree.Select) {
+	one := tree.NewDInt(1)
+	switch {
+	case sel.Limit == nil:
+		sel.Limit = &tree.Limit{Count: one}
+	case sel.Limit.LimitAll || sel.Limit.Count == nil:
+		sel.Limit = &tree.Limit{Offset: sel.Limit.Offset, Count: one}
+	default:
+		sel.Limit = &tree.Limit{
+			Offset: sel.Limit.Offset,
+			Count: &tree.FuncExpr{
+				Func:  tree.WrapFunction("least"),
+				Exprs: tree.Exprs{sel.Limit.Count, one},
+			},
+		}
+	}
+}
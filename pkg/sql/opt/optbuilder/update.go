@@ -317,6 +317,11 @@ func (mb *mutationBuilder) addSynthesizedColsForUpdate() {
 	// the inserted columns.
 	mb.roundDecimalValues(mb.updateColIDs, false /* roundComputedCols */)
 
+	// Run any BEFORE UPDATE triggers, which can change the updated values, and
+	// round the changed values.
+	mb.buildBeforeTriggers(tree.TriggerUpdate)
+	mb.roundDecimalValues(mb.updateColIDs, false /* roundComputedCols */)
+
 	// Disambiguate names so that references in the computed expression refer to
 	// the correct columns.
 	mb.disambiguateColumns()
@@ -348,14 +353,13 @@ func (mb *mutationBuilder) buildUpdate(returning tree.ReturningExprs) {
 	// Project partial index PUT and DEL boolean columns.
 	mb.projectPartialIndexPutAndDelCols(preCheckScope, mb.fetchScope)
 
+	mb.buildUniqueChecksForUpdate()
+
 	mb.buildFKChecksForUpdate()
 
+	mb.buildAfterTriggers(tree.TriggerUpdate)
+
 	private := mb.makeMutationPrivate(returning != nil)
-	for _, col := range mb.extraAccessibleCols {
-		if col.id != 0 {
-			private.PassthroughCols = append(private.PassthroughCols, col.id)
-		}
-	}
 	mb.outScope.expr = mb.b.factory.ConstructUpdate(
 		mb.outScope.expr, mb.uniqueChecks, mb.fkChecks, private,
 	)
@@ -205,6 +205,7 @@ func newMetadata(compiled *lang.CompiledExpr, pkg string) *metadata {
 		"JoinFlags":         {fullName: "memo.JoinFlags", passByVal: true},
 		"WindowFrame":       {fullName: "memo.WindowFrame", passByVal: true},
 		"FKCascades":        {fullName: "memo.FKCascades", passByVal: true},
+		"Triggers":          {fullName: "memo.Triggers", passByVal: true},
 		"ExplainOptions":    {fullName: "tree.ExplainOptions", passByVal: true},
 		"StatementType":     {fullName: "tree.StatementType", passByVal: true},
 		"ShowTraceType":     {fullName: "tree.ShowTraceType", passByVal: true},
@@ -217,6 +218,7 @@ func newMetadata(compiled *lang.CompiledExpr, pkg string) *metadata {
 		"Statement":         {fullName: "tree.Statement", isInterface: true},
 		"Subquery":          {fullName: "tree.Subquery", isPointer: true, usePointerIntern: true},
 		"CreateTable":       {fullName: "tree.CreateTable", isPointer: true, usePointerIntern: true},
+		"CreateFunction":    {fullName: "tree.CreateFunction", isPointer: true, usePointerIntern: true},
 		"TableName":         {fullName: "tree.TableName", isPointer: true, usePointerIntern: true},
 		"Constraint":        {fullName: "constraint.Constraint", isPointer: true, usePointerIntern: true},
 		"FuncProps":         {fullName: "tree.FunctionProperties", isPointer: true, usePointerIntern: true},
@@ -0,0 +1,51 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package testcat
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+)
+
+// CreateFunction creates a test function from a parsed DDL statement and adds
+// it to the catalog. This is intended for testing, and is not a complete (and
+// probably not fully correct) implementation. It just has to be "good enough".
+func (tc *Catalog) CreateFunction(stmt *tree.CreateFunction) *Function {
+	tn := stmt.Name.ToTableName()
+	tc.qualifyTableName(&tn)
+
+	fn := &Function{
+		FuncID:       tc.nextStableID(),
+		FuncName:     tree.MakeQualifiedFunctionName(tn.Catalog(), tn.Schema(), tn.Object()),
+		ArgNames:     make([]tree.Name, len(stmt.Args)),
+		ArgTypes:     make([]*types.T, len(stmt.Args)),
+		FuncVolatile: stmt.Volatility,
+		BodyText:     stmt.Body,
+		IsTrig:       stmt.ReturnsTrigger,
+	}
+	if !stmt.ReturnsTrigger {
+		fn.RetType = tree.MustBeStaticallyKnownType(stmt.ReturnType)
+	}
+	for i := range stmt.Args {
+		fn.ArgNames[i] = stmt.Args[i].Name
+		fn.ArgTypes[i] = tree.MustBeStaticallyKnownType(stmt.Args[i].Type)
+	}
+
+	fq := fn.FuncName.FQString()
+	if _, ok := tc.testSchema.functions[fq]; ok {
+		panic(pgerror.Newf(pgcode.DuplicateFunction,
+			"function %q already exists", tree.ErrString(&fn.FuncName)))
+	}
+	tc.testSchema.functions[fq] = fn
+	return fn
+}
@@ -172,9 +172,11 @@ func (tc *Catalog) CreateTable(stmt *tree.CreateTable) *Table {
 	for _, def := range stmt.Defs {
 		switch def := def.(type) {
 		case *tree.CheckConstraintTableDef:
+			deferrable := def.Deferrability != tree.ConstraintNotDeferrable
 			tab.Checks = append(tab.Checks, cat.CheckConstraint{
 				Constraint: serializeTableDefExpr(def.Expr),
-				Validated:  validatedCheckConstraint(def),
+				Validated:  validatedCheckConstraint(def) && !deferrable,
+				Deferrable: deferrable,
 			})
 		}
 	}
@@ -185,6 +187,11 @@ func (tc *Catalog) CreateTable(stmt *tree.CreateTable) *Table {
 		case *tree.UniqueConstraintTableDef:
 			if def.WithoutIndex {
 				tab.addUniqueConstraint(def.Name, def.Columns, def.WithoutIndex)
+			} else if def.Deferrability != tree.ConstraintNotDeferrable {
+				// A deferrable unique constraint is backed by a non-unique index
+				// and enforced by unique checks.
+				tab.addIndex(&def.IndexTableDef, nonUniqueIndex)
+				tab.addDeferrableUniqueConstraint(def.Name, def.Columns, def.Deferrability)
 			} else if !def.PrimaryKey {
 				tab.addIndex(&def.IndexTableDef, uniqueIndex)
 			}
@@ -446,6 +453,8 @@ func (tc *Catalog) resolveFK(tab *Table, d *tree.ForeignKeyConstraintTableDef) {
 		matchMethod:              d.Match,
 		deleteAction:             d.Actions.Delete,
 		updateAction:             d.Actions.Update,
+		deferrable:               d.Deferrability != tree.ConstraintNotDeferrable,
+		initiallyDeferred:        d.Deferrability == tree.ConstraintInitiallyDeferred,
 	}
 	tab.outboundFKs = append(tab.outboundFKs, fk)
 	targetTable.inboundFKs = append(targetTable.inboundFKs, fk)
@@ -478,6 +487,25 @@ func (tt *Table) addUniqueConstraint(
 	tt.uniqueConstraints = append(tt.uniqueConstraints, u)
 }
 
+func (tt *Table) addDeferrableUniqueConstraint(
+	name tree.Name, columns tree.IndexElemList, deferrability tree.ConstraintDeferrability,
+) {
+	cols := make([]int, len(columns))
+	for i, c := range columns {
+		cols[i] = tt.FindOrdinal(string(c.Column))
+	}
+	sort.Ints(cols)
+
+	tt.uniqueConstraints = append(tt.uniqueConstraints, UniqueConstraint{
+		name:              string(name),
+		tabID:             tt.TabID,
+		columnOrdinals:    cols,
+		withoutIndex:      true,
+		deferrable:        true,
+		initiallyDeferred: deferrability == tree.ConstraintInitiallyDeferred,
+	})
+}
+
 func (tt *Table) addColumn(def *tree.ColumnTableDef) {
 	ordinal := len(tt.Columns)
 	nullable := !def.PrimaryKey.IsPrimaryKey && def.Nullable.Nullability != tree.NotNull
@@ -0,0 +1,55 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package testcat
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/opt/cat"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+)
+
+// CreateTrigger creates a trigger from a parsed DDL statement and adds it to
+// the target table. This is intended for testing, and is not a complete (and
+// probably not fully correct) implementation. It just has to be "good enough".
+func (tc *Catalog) CreateTrigger(stmt *tree.CreateTrigger) {
+	tab := tc.Table(&stmt.Table)
+	fn, err := tc.ResolveFunction(context.Background(), cat.Flags{}, stmt.FuncName)
+	if err != nil {
+		panic(err)
+	}
+	if fn == nil {
+		panic(pgerror.Newf(pgcode.UndefinedFunction,
+			"unknown function: %s()", tree.ErrString(stmt.FuncName)))
+	}
+	if !fn.IsTrigger() {
+		panic(pgerror.Newf(pgcode.InvalidObjectDefinition,
+			"function %s must return type trigger", tree.ErrString(stmt.FuncName)))
+	}
+	for i := range tab.Triggers {
+		if tab.Triggers[i].Name == string(stmt.Name) {
+			panic(pgerror.Newf(pgcode.DuplicateObject,
+				"trigger %q for relation %q already exists", stmt.Name, tab.Name()))
+		}
+	}
+	tab.Triggers = append(tab.Triggers, cat.Trigger{
+		Name:       string(stmt.Name),
+		ActionTime: stmt.ActionTime,
+		Events:     stmt.Events,
+		FunctionID: fn.ID(),
+	})
+	sort.Slice(tab.Triggers, func(i, j int) bool {
+		return tab.Triggers[i].Name < tab.Triggers[j].Name
+	})
+}
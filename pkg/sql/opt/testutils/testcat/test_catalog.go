@@ -67,6 +67,7 @@ func New() *Catalog {
 				ExplicitCatalog: true,
 			},
 			dataSources: make(map[string]dataSource),
+			functions:   make(map[string]*Function),
 		},
 	}
 }
@@ -173,6 +174,29 @@ func (tc *Catalog) ResolveDataSourceByID(
 		"relation [%d] does not exist", id)
 }
 
+// ResolveFunction is part of the cat.Catalog interface.
+func (tc *Catalog) ResolveFunction(
+	_ context.Context, _ cat.Flags, name *tree.UnresolvedObjectName,
+) (cat.Function, error) {
+	tn := name.ToTableName()
+	tc.qualifyTableName(&tn)
+	fn, ok := tc.testSchema.functions[tn.FQString()]
+	if !ok {
+		return nil, nil
+	}
+	return fn, nil
+}
+
+// ResolveFunctionByID is part of the cat.Catalog interface.
+func (tc *Catalog) ResolveFunctionByID(_ context.Context, id cat.StableID) (cat.Function, error) {
+	for _, fn := range tc.testSchema.functions {
+		if fn.FuncID == id {
+			return fn, nil
+		}
+	}
+	return nil, pgerror.Newf(pgcode.UndefinedFunction, "function [%d] does not exist", id)
+}
+
 // ResolveTypeByOID is part of the cat.Catalog interface.
 func (tc *Catalog) ResolveTypeByOID(context.Context, oid.Oid) (*types.T, error) {
 	return nil, errors.Newf("test catalog cannot handle user defined types")
@@ -395,6 +419,14 @@ func (tc *Catalog) ExecuteDDLWithIndexVersion(
 		tc.CreateSequence(stmt)
 		return "", nil
 
+	case *tree.CreateFunction:
+		tc.CreateFunction(stmt)
+		return "", nil
+
+	case *tree.CreateTrigger:
+		tc.CreateTrigger(stmt)
+		return "", nil
+
 	case *tree.SetZoneConfig:
 		tc.SetZoneConfig(stmt)
 		return "", nil
@@ -462,6 +494,7 @@ type Schema struct {
 	Revoked bool
 
 	dataSources map[string]dataSource
+	functions   map[string]*Function
 }
 
 var _ cat.Schema = &Schema{}
@@ -570,6 +603,76 @@ func (tv *View) ColumnName(i int) tree.Name {
 	return tv.ColumnNames[i]
 }
 
+// Function implements the cat.Function interface for testing purposes.
+type Function struct {
+	FuncID       cat.StableID
+	FuncName     tree.FunctionName
+	ArgNames     []tree.Name
+	ArgTypes     []*types.T
+	RetType      *types.T
+	FuncVolatile tree.Volatility
+	BodyText     string
+	IsTrig       bool
+}
+
+var _ cat.Function = &Function{}
+
+// ID is part of the cat.Object interface.
+func (tf *Function) ID() cat.StableID {
+	return tf.FuncID
+}
+
+// PostgresDescriptorID is part of the cat.Object interface.
+func (tf *Function) PostgresDescriptorID() cat.StableID {
+	return tf.FuncID
+}
+
+// Equals is part of the cat.Object interface.
+func (tf *Function) Equals(other cat.Object) bool {
+	otherFunc, ok := other.(*Function)
+	return ok && tf.FuncID == otherFunc.FuncID
+}
+
+// Name is part of the cat.Function interface.
+func (tf *Function) Name() *tree.FunctionName {
+	return &tf.FuncName
+}
+
+// ArgCount is part of the cat.Function interface.
+func (tf *Function) ArgCount() int {
+	return len(tf.ArgTypes)
+}
+
+// ArgName is part of the cat.Function interface.
+func (tf *Function) ArgName(i int) tree.Name {
+	return tf.ArgNames[i]
+}
+
+// ArgType is part of the cat.Function interface.
+func (tf *Function) ArgType(i int) *types.T {
+	return tf.ArgTypes[i]
+}
+
+// ReturnType is part of the cat.Function interface.
+func (tf *Function) ReturnType() *types.T {
+	return tf.RetType
+}
+
+// IsTrigger is part of the cat.Function interface.
+func (tf *Function) IsTrigger() bool {
+	return tf.IsTrig
+}
+
+// Volatility is part of the cat.Function interface.
+func (tf *Function) Volatility() tree.Volatility {
+	return tf.FuncVolatile
+}
+
+// Body is part of the cat.Function interface.
+func (tf *Function) Body() string {
+	return tf.BodyText
+}
+
 // Table implements the cat.Table interface for testing purposes.
 type Table struct {
 	TabID      cat.StableID
@@ -579,6 +682,7 @@ type Table struct {
 	Indexes    []*Index
 	Stats      TableStats
 	Checks     []cat.CheckConstraint
+	Triggers   []cat.Trigger
 	Families   []*Family
 	IsVirtual  bool
 	Catalog    cat.Catalog
@@ -736,6 +840,16 @@ func (tt *Table) Unique(i int) cat.UniqueConstraint {
 	return &tt.uniqueConstraints[i]
 }
 
+// TriggerCount is part of the cat.Table interface.
+func (tt *Table) TriggerCount() int {
+	return len(tt.Triggers)
+}
+
+// Trigger is part of the cat.Table interface.
+func (tt *Table) Trigger(i int) cat.Trigger {
+	return tt.Triggers[i]
+}
+
 // FindOrdinal returns the ordinal of the column with the given name.
 func (tt *Table) FindOrdinal(name string) int {
 	for i, col := range tt.Columns {
@@ -1075,6 +1189,9 @@ type ForeignKeyConstraint struct {
 	matchMethod  tree.CompositeKeyMatchMethod
 	deleteAction tree.ReferenceAction
 	updateAction tree.ReferenceAction
+
+	deferrable        bool
+	initiallyDeferred bool
 }
 
 var _ cat.ForeignKeyConstraint = &ForeignKeyConstraint{}
@@ -1142,6 +1259,16 @@ func (fk *ForeignKeyConstraint) UpdateReferenceAction() tree.ReferenceAction {
 	return fk.updateAction
 }
 
+// Deferrable is part of the cat.ForeignKeyConstraint interface.
+func (fk *ForeignKeyConstraint) Deferrable() bool {
+	return fk.deferrable
+}
+
+// InitiallyDeferred is part of the cat.ForeignKeyConstraint interface.
+func (fk *ForeignKeyConstraint) InitiallyDeferred() bool {
+	return fk.initiallyDeferred
+}
+
 // UniqueConstraint implements cat.UniqueConstraint. See that interface
 // for more information on the fields.
 type UniqueConstraint struct {
@@ -1150,6 +1277,9 @@ type UniqueConstraint struct {
 	columnOrdinals []int
 	withoutIndex   bool
 	validated      bool
+
+	deferrable        bool
+	initiallyDeferred bool
 }
 
 var _ cat.UniqueConstraint = &UniqueConstraint{}
@@ -1190,6 +1320,16 @@ func (u *UniqueConstraint) Validated() bool {
 	return u.validated
 }
 
+// Deferrable is part of the cat.UniqueConstraint interface.
+func (u *UniqueConstraint) Deferrable() bool {
+	return u.deferrable
+}
+
+// InitiallyDeferred is part of the cat.UniqueConstraint interface.
+func (u *UniqueConstraint) InitiallyDeferred() bool {
+	return u.initiallyDeferred
+}
+
 // Sequence implements the cat.Sequence interface for testing purposes.
 type Sequence struct {
 	SeqID      cat.StableID
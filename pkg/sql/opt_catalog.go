@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/opt/cat"
@@ -247,6 +248,57 @@ func (oc *optCatalog) ResolveDataSourceByID(
 	return ds, false, err
 }
 
+// ResolveFunction is part of the cat.Catalog interface.
+func (oc *optCatalog) ResolveFunction(
+	ctx context.Context, flags cat.Flags, name *tree.UnresolvedObjectName,
+) (cat.Function, error) {
+	if flags.AvoidDescriptorCaches {
+		defer func(prev bool) {
+			oc.planner.avoidCachedDescriptors = prev
+		}(oc.planner.avoidCachedDescriptors)
+		oc.planner.avoidCachedDescriptors = true
+	}
+
+	lflags := tree.ObjectLookupFlags{DesiredObjectKind: tree.FunctionObject}
+	desc, prefix, err := resolver.ResolveExistingObject(ctx, oc.planner, name, lflags)
+	if err != nil || desc == nil {
+		return nil, err
+	}
+	fn := desc.(*funcdesc.Immutable)
+
+	// Ensure that the current user can access the target schema.
+	if err := oc.planner.canResolveDescUnderSchema(ctx, fn.GetParentSchemaID(), fn); err != nil {
+		return nil, err
+	}
+	return newOptFunction(fn, tree.MakeQualifiedFunctionName(
+		prefix.Catalog(), prefix.Schema(), name.Object(),
+	)), nil
+}
+
+// ResolveFunctionByID is part of the cat.Catalog interface.
+func (oc *optCatalog) ResolveFunctionByID(ctx context.Context, id cat.StableID) (cat.Function, error) {
+	flags := tree.ObjectLookupFlags{CommonLookupFlags: tree.CommonLookupFlags{
+		AvoidCached: oc.planner.avoidCachedDescriptors,
+	}}
+	fn, err := oc.planner.Descriptors().GetFunctionVersionByID(ctx, oc.planner.txn, descpb.ID(id), flags)
+	if err != nil {
+		return nil, err
+	}
+	dbDesc, err := oc.planner.Descriptors().GetDatabaseVersionByID(
+		ctx, oc.planner.txn, fn.GetParentID(), tree.DatabaseLookupFlags{AvoidCached: flags.AvoidCached},
+	)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := oc.planner.Descriptors().ResolveSchemaByID(ctx, oc.planner.txn, fn.GetParentSchemaID())
+	if err != nil {
+		return nil, err
+	}
+	return newOptFunction(fn, tree.MakeQualifiedFunctionName(
+		dbDesc.GetName(), sc.Name, fn.GetName(),
+	)), nil
+}
+
 // ResolveTypeByOID is part of the cat.Catalog interface.
 func (oc *optCatalog) ResolveTypeByOID(ctx context.Context, oid oid.Oid) (*types.T, error) {
 	return oc.planner.ResolveTypeByOID(ctx, oid)
@@ -556,6 +608,78 @@ func (os *optSequence) Name() tree.Name {
 // SequenceMarker is part of the cat.Sequence interface.
 func (os *optSequence) SequenceMarker() {}
 
+// optFunction is a wrapper around funcdesc.Immutable that implements the
+// cat.Object and cat.Function interfaces.
+type optFunction struct {
+	desc *funcdesc.Immutable
+	name tree.FunctionName
+}
+
+var _ cat.Function = &optFunction{}
+
+func newOptFunction(desc *funcdesc.Immutable, name tree.FunctionName) *optFunction {
+	return &optFunction{desc: desc, name: name}
+}
+
+// ID is part of the cat.Object interface.
+func (of *optFunction) ID() cat.StableID {
+	return cat.StableID(of.desc.ID)
+}
+
+// PostgresDescriptorID is part of the cat.Object interface.
+func (of *optFunction) PostgresDescriptorID() cat.StableID {
+	return cat.StableID(of.desc.ID)
+}
+
+// Equals is part of the cat.Object interface.
+func (of *optFunction) Equals(other cat.Object) bool {
+	otherFunc, ok := other.(*optFunction)
+	if !ok {
+		return false
+	}
+	return of.desc.ID == otherFunc.desc.ID && of.desc.Version == otherFunc.desc.Version
+}
+
+// Name is part of the cat.Function interface.
+func (of *optFunction) Name() *tree.FunctionName {
+	return &of.name
+}
+
+// ArgCount is part of the cat.Function interface.
+func (of *optFunction) ArgCount() int {
+	return len(of.desc.Args)
+}
+
+// ArgName is part of the cat.Function interface.
+func (of *optFunction) ArgName(i int) tree.Name {
+	return tree.Name(of.desc.Args[i].Name)
+}
+
+// ArgType is part of the cat.Function interface.
+func (of *optFunction) ArgType(i int) *types.T {
+	return of.desc.Args[i].Type
+}
+
+// ReturnType is part of the cat.Function interface.
+func (of *optFunction) ReturnType() *types.T {
+	return of.desc.ReturnType
+}
+
+// IsTrigger is part of the cat.Function interface.
+func (of *optFunction) IsTrigger() bool {
+	return of.desc.ReturnsTrigger
+}
+
+// Volatility is part of the cat.Function interface.
+func (of *optFunction) Volatility() tree.Volatility {
+	return of.desc.GetVolatility()
+}
+
+// Body is part of the cat.Function interface.
+func (of *optFunction) Body() string {
+	return of.desc.Body
+}
+
 // optTable is a wrapper around sqlbase.Immutable that caches
 // index wrappers and maintains a ColumnID => Column mapping for fast lookup.
 type optTable struct {
@@ -598,6 +722,10 @@ type optTable struct {
 	outboundFKs []optForeignKeyConstraint
 	inboundFKs  []optForeignKeyConstraint
 
+	// uniqueConstraints are the unique constraints of the table which are not
+	// enforced by unique indexes, namely the DEFERRABLE ones.
+	uniqueConstraints []optUniqueConstraint
+
 	// checkConstraints is the set of check constraints for this table. It
 	// can be different from desc's constraints because of synthesized
 	// constraints for user defined types.
@@ -762,6 +890,8 @@ func newOptTable(
 			match:             fk.Match,
 			deleteAction:      fk.OnDelete,
 			updateAction:      fk.OnUpdate,
+			deferrable:        fk.Deferrable,
+			initiallyDeferred: fk.InitiallyDeferred,
 		})
 	}
 	for i := range ot.desc.InboundFKs {
@@ -776,6 +906,19 @@ func newOptTable(
 			match:             fk.Match,
 			deleteAction:      fk.OnDelete,
 			updateAction:      fk.OnUpdate,
+			deferrable:        fk.Deferrable,
+			initiallyDeferred: fk.InitiallyDeferred,
+		})
+	}
+	for _, idx := range ot.desc.GetPublicNonPrimaryIndexes() {
+		if !idx.Deferrable {
+			continue
+		}
+		ot.uniqueConstraints = append(ot.uniqueConstraints, optUniqueConstraint{
+			name:              idx.Name,
+			table:             ot.ID(),
+			columns:           idx.ColumnIDs,
+			initiallyDeferred: idx.InitiallyDeferred,
 		})
 	}
 
@@ -816,7 +959,9 @@ func newOptTable(
 	for i := range activeChecks {
 		ot.checkConstraints = append(ot.checkConstraints, cat.CheckConstraint{
 			Constraint: activeChecks[i].Expr,
-			Validated:  activeChecks[i].Validity == descpb.ConstraintValidity_Validated,
+			Validated: activeChecks[i].Validity == descpb.ConstraintValidity_Validated &&
+				!activeChecks[i].Deferrable,
+			Deferrable: activeChecks[i].Deferrable,
 		})
 	}
 	ot.checkConstraints = append(ot.checkConstraints, synthesizedChecks...)
@@ -1042,12 +1187,40 @@ func (ot *optTable) InboundForeignKey(i int) cat.ForeignKeyConstraint {
 func (ot *optTable) UniqueCount() int {
 	// TODO(rytaft): return the number of unique constraints (both with and
 	//  without indexes).
-	return 0
+	return len(ot.uniqueConstraints)
 }
 
 // Unique is part of the cat.Table interface.
 func (ot *optTable) Unique(i int) cat.UniqueConstraint {
-	panic(errors.AssertionFailedf("unique constraint [%d] does not exist", i))
+	return &ot.uniqueConstraints[i]
+}
+
+// TriggerCount is part of the cat.Table interface.
+func (ot *optTable) TriggerCount() int {
+	return len(ot.desc.Triggers)
+}
+
+// Trigger is part of the cat.Table interface.
+func (ot *optTable) Trigger(i int) cat.Trigger {
+	trig := &ot.desc.Triggers[i]
+	res := cat.Trigger{
+		Name:       trig.Name,
+		ActionTime: tree.TriggerBefore,
+		FunctionID: cat.StableID(trig.FunctionID),
+	}
+	if trig.ActionTime == descpb.TableDescriptor_Trigger_AFTER {
+		res.ActionTime = tree.TriggerAfter
+	}
+	if trig.OnInsert {
+		res.Events = append(res.Events, tree.TriggerInsert)
+	}
+	if trig.OnUpdate {
+		res.Events = append(res.Events, tree.TriggerUpdate)
+	}
+	if trig.OnDelete {
+		res.Events = append(res.Events, tree.TriggerDelete)
+	}
+	return res
 }
 
 // lookupColumnOrdinal returns the ordinal of the column with the given ID. A
@@ -1456,6 +1629,9 @@ type optForeignKeyConstraint struct {
 	match        descpb.ForeignKeyReference_Match
 	deleteAction descpb.ForeignKeyReference_Action
 	updateAction descpb.ForeignKeyReference_Action
+
+	deferrable        bool
+	initiallyDeferred bool
 }
 
 var _ cat.ForeignKeyConstraint = &optForeignKeyConstraint{}
@@ -1527,6 +1703,79 @@ func (fk *optForeignKeyConstraint) UpdateReferenceAction() tree.ReferenceAction
 	return descpb.ForeignKeyReferenceActionType[fk.updateAction]
 }
 
+// Deferrable is part of the cat.ForeignKeyConstraint interface.
+func (fk *optForeignKeyConstraint) Deferrable() bool {
+	return fk.deferrable
+}
+
+// InitiallyDeferred is part of the cat.ForeignKeyConstraint interface.
+func (fk *optForeignKeyConstraint) InitiallyDeferred() bool {
+	return fk.initiallyDeferred
+}
+
+// optUniqueConstraint implements cat.UniqueConstraint for the DEFERRABLE
+// unique constraints of a table. The index of such a constraint is not unique,
+// so the constraint is enforced by unique checks instead.
+type optUniqueConstraint struct {
+	name    string
+	table   cat.StableID
+	columns []descpb.ColumnID
+
+	initiallyDeferred bool
+}
+
+var _ cat.UniqueConstraint = &optUniqueConstraint{}
+
+// Name is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) Name() string {
+	return u.name
+}
+
+// TableID is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) TableID() cat.StableID {
+	return u.table
+}
+
+// ColumnCount is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) ColumnCount() int {
+	return len(u.columns)
+}
+
+// ColumnOrdinal is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) ColumnOrdinal(tab cat.Table, i int) int {
+	if tab.ID() != u.table {
+		panic(errors.AssertionFailedf(
+			"invalid table %d passed to ColumnOrdinal (expected %d)",
+			tab.ID(), u.table,
+		))
+	}
+
+	ord, _ := tab.(*optTable).lookupColumnOrdinal(u.columns[i])
+	return ord
+}
+
+// WithoutIndex is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) WithoutIndex() bool {
+	return true
+}
+
+// Validated is part of the cat.UniqueConstraint interface. A deferrable
+// constraint can be violated until the end of the transaction, so the
+// existing data cannot be assumed to satisfy it.
+func (u *optUniqueConstraint) Validated() bool {
+	return false
+}
+
+// Deferrable is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) Deferrable() bool {
+	return true
+}
+
+// InitiallyDeferred is part of the cat.UniqueConstraint interface.
+func (u *optUniqueConstraint) InitiallyDeferred() bool {
+	return u.initiallyDeferred
+}
+
 // optVirtualTable is similar to optTable but is used with virtual tables.
 type optVirtualTable struct {
 	desc *tabledesc.Immutable
@@ -1777,7 +2026,8 @@ func (ot *optVirtualTable) Check(i int) cat.CheckConstraint {
 	check := ot.desc.ActiveChecks()[i]
 	return cat.CheckConstraint{
 		Constraint: check.Expr,
-		Validated:  check.Validity == descpb.ConstraintValidity_Validated,
+		Validated:  check.Validity == descpb.ConstraintValidity_Validated && !check.Deferrable,
+		Deferrable: check.Deferrable,
 	}
 }
 
@@ -1821,6 +2071,16 @@ func (ot *optVirtualTable) Unique(i int) cat.UniqueConstraint {
 	panic(errors.AssertionFailedf("no unique constraints"))
 }
 
+// TriggerCount is part of the cat.Table interface.
+func (ot *optVirtualTable) TriggerCount() int {
+	return 0
+}
+
+// Trigger is part of the cat.Table interface.
+func (ot *optVirtualTable) Trigger(i int) cat.Trigger {
+	panic(errors.AssertionFailedf("no triggers"))
+}
+
 // optVirtualIndex is a dummy implementation of cat.Index for the indexes
 // reported by a virtual table. The index assumes that table column 0 is a dummy
 // PK column.
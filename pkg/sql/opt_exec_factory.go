@@ -1506,6 +1506,7 @@ func (ef *execFactory) ConstructDelete(
 	table cat.Table,
 	fetchColOrdSet exec.TableColumnOrdinalSet,
 	returnColOrdSet exec.TableColumnOrdinalSet,
+	passthrough colinfo.ResultColumns,
 	autoCommit bool,
 ) (exec.Node, error) {
 	// Derive table and column descriptors.
@@ -1533,7 +1534,8 @@ func (ef *execFactory) ConstructDelete(
 		source: input.(planNode),
 		run: deleteRun{
 			td:                        tableDeleter{rd: rd, alloc: ef.planner.alloc},
-			partialIndexDelValsOffset: len(rd.FetchCols),
+			partialIndexDelValsOffset: len(rd.FetchCols) + len(passthrough),
+			numPassthrough:            len(passthrough),
 		},
 	}
 
@@ -1543,6 +1545,8 @@ func (ef *execFactory) ConstructDelete(
 		// Delete returns the non-mutation columns specified, in the same
 		// order they are defined in the table.
 		del.columns = colinfo.ResultColumnsFromColDescs(tabDesc.GetID(), returnColDescs)
+		// Add the passthrough columns to the returning columns.
+		del.columns = append(del.columns, passthrough...)
 
 		del.run.rowIdxToRetIdx = row.ColMapping(rd.FetchCols, returnColDescs)
 		del.run.rowsNeeded = true
@@ -1696,6 +1700,36 @@ func (ef *execFactory) ConstructCreateView(
 	}, nil
 }
 
+// ConstructCreateFunction is part of the exec.Factory interface.
+func (ef *execFactory) ConstructCreateFunction(
+	schema cat.Schema, cf *tree.CreateFunction, deps opt.ViewDeps,
+) (exec.Node, error) {
+	if err := checkSchemaChangeEnabled(
+		ef.planner.EvalContext().Context,
+		&ef.planner.ExecCfg().Settings.SV,
+		"CREATE FUNCTION",
+	); err != nil {
+		return nil, err
+	}
+
+	planDeps := make(planDependencies, len(deps))
+	for _, d := range deps {
+		desc, err := getDescForDataSource(d.DataSource)
+		if err != nil {
+			return nil, err
+		}
+		entry := planDeps[desc.ID]
+		entry.desc = desc
+		planDeps[desc.ID] = entry
+	}
+
+	return &createFunctionNode{
+		n:        cf,
+		dbDesc:   schema.(*optSchema).database,
+		planDeps: planDeps,
+	}, nil
+}
+
 // ConstructSequenceSelect is part of the exec.Factory interface.
 func (ef *execFactory) ConstructSequenceSelect(sequence cat.Sequence) (exec.Node, error) {
 	return ef.planner.SequenceSelectNode(sequence.(*optSequence).desc)
@@ -1710,11 +1744,12 @@ func (ef *execFactory) ConstructSaveTable(
 
 // ConstructErrorIfRows is part of the exec.Factory interface.
 func (ef *execFactory) ConstructErrorIfRows(
-	input exec.Node, mkErr exec.MkErrFn,
+	input exec.Node, mkErr exec.MkErrFn, deferrable *exec.DeferrableCheck,
 ) (exec.Node, error) {
 	return &errorIfRowsNode{
-		plan:  input.(planNode),
-		mkErr: mkErr,
+		plan:       input.(planNode),
+		mkErr:      mkErr,
+		deferrable: deferrable,
 	}, nil
 }
 
@@ -125,6 +125,11 @@ func TestContextualHelp(t *testing.T) {
 		{`CREATE ROLE bleh ??`, `CREATE ROLE`},
 		{`CREATE ROLE bleh ?? WITH CREATEROLE`, `CREATE ROLE`},
 
+		{`CREATE FUNCTION ??`, `CREATE FUNCTION`},
+		{`CREATE OR REPLACE FUNCTION f(??`, `CREATE FUNCTION`},
+
+		{`CREATE TRIGGER ??`, `CREATE TRIGGER`},
+
 		{`CREATE VIEW blah (??`, `CREATE VIEW`},
 		{`CREATE VIEW blah AS (SELECT c FROM x) ??`, `CREATE VIEW`},
 		{`CREATE VIEW blah AS SELECT c FROM x ??`, `SELECT`},
@@ -144,6 +149,11 @@ func TestContextualHelp(t *testing.T) {
 
 		{`CREATE TYPE blah AS ENUM ??`, `CREATE TYPE`},
 		{`DROP TYPE ??`, `DROP TYPE`},
+		{`DROP FUNCTION ??`, `DROP FUNCTION`},
+		{`DROP FUNCTION IF EXISTS f(INT) ??`, `DROP FUNCTION`},
+
+		{`DROP TRIGGER ??`, `DROP TRIGGER`},
+		{`DROP TRIGGER IF EXISTS t ON ??`, `DROP TRIGGER`},
 
 		{`CREATE SCHEMA IF ??`, `CREATE SCHEMA`},
 		{`CREATE SCHEMA IF NOT ??`, `CREATE SCHEMA`},
@@ -151,6 +161,7 @@ func TestContextualHelp(t *testing.T) {
 
 		{`DELETE FROM ??`, `DELETE`},
 		{`DELETE FROM blah ??`, `DELETE`},
+		{`DELETE FROM blah USING ??`, `DELETE`},
 		{`DELETE FROM blah WHERE ??`, `DELETE`},
 		{`DELETE FROM blah WHERE x > 3 ??`, `DELETE`},
 
@@ -387,6 +398,9 @@ func TestContextualHelp(t *testing.T) {
 		{`SET SESSION blah TO ??`, `SET SESSION`},
 		{`SET SESSION blah TO 42 ??`, `SET SESSION`},
 
+		{`SET CONSTRAINTS ??`, `SET CONSTRAINTS`},
+		{`SET CONSTRAINTS ALL ??`, `SET CONSTRAINTS`},
+
 		{`SET TRANSACTION ??`, `SET TRANSACTION`},
 		{`SET TRANSACTION ISOLATION LEVEL SNAPSHOT ??`, `SET TRANSACTION`},
 		{`SET TIME ??`, `SET SESSION`},
@@ -228,6 +228,13 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT8, c STRING, FOREIGN KEY (b, c) REFERENCES other (x, y) MATCH FULL)`},
 		{`CREATE TABLE a (b INT8, c STRING, FOREIGN KEY (b, c) REFERENCES other)`},
 		{`CREATE TABLE a (b INT8, c STRING, FOREIGN KEY (b, c) REFERENCES other (x, y))`},
+		{`CREATE TABLE a (b INT8, c STRING, FOREIGN KEY (b) REFERENCES other DEFERRABLE INITIALLY IMMEDIATE)`},
+		{`CREATE TABLE a (b INT8, c STRING, FOREIGN KEY (b) REFERENCES other DEFERRABLE INITIALLY DEFERRED)`},
+		{`CREATE TABLE a (b INT8, c STRING, FOREIGN KEY (b) REFERENCES other MATCH FULL ON DELETE RESTRICT DEFERRABLE INITIALLY DEFERRED)`},
+		{`CREATE TABLE a (b INT8, CONSTRAINT c UNIQUE (b) DEFERRABLE INITIALLY IMMEDIATE)`},
+		{`CREATE TABLE a (b INT8, c INT8, UNIQUE (b, c) DEFERRABLE INITIALLY DEFERRED WHERE c > 0)`},
+		{`CREATE TABLE a (b INT8, CHECK (b > 0) DEFERRABLE INITIALLY IMMEDIATE)`},
+		{`CREATE TABLE a (b INT8, CONSTRAINT c CHECK (b > 0) DEFERRABLE INITIALLY DEFERRED)`},
 		{`CREATE TABLE a (b INT8, c STRING, CONSTRAINT s FOREIGN KEY (b, c) REFERENCES other (x, y))`},
 		{`CREATE TABLE a (b INT8, c STRING, CONSTRAINT s FOREIGN KEY (b, c) REFERENCES other (x, y) MATCH FULL)`},
 		{`CREATE TABLE a (b INT8, c STRING, CONSTRAINT s FOREIGN KEY (b, c) REFERENCES other (x, y) MATCH FULL ON UPDATE SET NULL)`},
@@ -352,6 +359,16 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (a INT4) LOCALITY REGIONAL BY TABLE IN PRIMARY REGION`},
 		{`CREATE TABLE a (a INT4) LOCALITY REGIONAL BY ROW`},
 
+		{`CREATE FUNCTION f() RETURNS INT8 LANGUAGE SQL VOLATILE AS 'SELECT 1'`},
+		{`CREATE FUNCTION a.b.f(x INT8, INT8) RETURNS INT8 LANGUAGE SQL IMMUTABLE AS 'SELECT x + $2'`},
+		{`CREATE OR REPLACE FUNCTION f(a STRING) RETURNS STRING LANGUAGE SQL STABLE AS 'SELECT b FROM t WHERE c = a'`},
+		{`EXPLAIN CREATE FUNCTION f() RETURNS INT8 LANGUAGE SQL VOLATILE AS 'SELECT 1'`},
+		{`CREATE FUNCTION f() RETURNS TRIGGER LANGUAGE SQL VOLATILE AS 'INSERT INTO t VALUES (new.a)'`},
+		{`CREATE OR REPLACE FUNCTION f() RETURNS TRIGGER LANGUAGE SQL STABLE AS 'SELECT new.*'`},
+
+		{`CREATE TRIGGER t BEFORE INSERT ON kv FOR EACH ROW EXECUTE FUNCTION f()`},
+		{`CREATE TRIGGER t AFTER INSERT OR UPDATE OR DELETE ON db.sc.kv FOR EACH ROW EXECUTE FUNCTION sc.f()`},
+
 		{`CREATE VIEW a AS SELECT * FROM b`},
 		{`CREATE OR REPLACE VIEW a AS SELECT * FROM b`},
 		{`EXPLAIN CREATE VIEW a AS SELECT * FROM b`},
@@ -433,6 +450,13 @@ func TestParse(t *testing.T) {
 		{`DROP SCHEMA IF EXISTS a, b.b RESTRICT`},
 		{`DROP SCHEMA a.a RESTRICT`},
 
+		{`DROP FUNCTION f`},
+		{`DROP FUNCTION f(), a.b.g(INT8, STRING)`},
+		{`DROP FUNCTION IF EXISTS f, g CASCADE`},
+		{`DROP FUNCTION IF EXISTS sc.f(INT8) RESTRICT`},
+		{`DROP TRIGGER t ON kv`},
+		{`DROP TRIGGER IF EXISTS t ON db.kv CASCADE`},
+
 		{`DROP TYPE a`},
 		{`DROP TYPE a, b, c`},
 		{`DROP TYPE db.sc.a, sc.a`},
@@ -454,6 +478,10 @@ func TestParse(t *testing.T) {
 		{`DELETE FROM a WHERE a = b RETURNING a + b`},
 		{`DELETE FROM a WHERE a = b RETURNING NOTHING`},
 		{`DELETE FROM a WHERE a = b ORDER BY c LIMIT d RETURNING e`},
+		{`DELETE FROM a USING b`},
+		{`DELETE FROM a AS other USING a, b WHERE other.x = b.x`},
+		{`DELETE FROM a USING b JOIN c ON b.x = c.x WHERE a.x = b.x RETURNING a.x, c.y`},
+		{`DELETE FROM a USING b, LATERAL (SELECT * FROM c WHERE c.x = b.x) AS d WHERE a.x = d.x`},
 
 		{`DISCARD ALL`},
 
@@ -1120,6 +1148,11 @@ func TestParse(t *testing.T) {
 		{`SELECT 1 FROM t GROUP BY a`},
 		{`SELECT 1 FROM t GROUP BY a, b`},
 		{`SELECT 1 FROM t GROUP BY ()`},
+		{`SELECT a, b, count(*) FROM t GROUP BY ROLLUP (a, b)`},
+		{`SELECT a, b, count(*) FROM t GROUP BY a, ROLLUP (b)`},
+		{`SELECT a, b, count(*) FROM t GROUP BY CUBE (a, (b, c))`},
+		{`SELECT a, b, count(*) FROM t GROUP BY GROUPING SETS (a, (b, c), (), ROLLUP (d), GROUPING SETS (e))`},
+		{`SELECT grouping(a), grouping(a, b), count(*) FROM t GROUP BY ROLLUP (a, b)`},
 		{`SELECT sum(x ORDER BY y) FROM t`},
 		{`SELECT sum(x ORDER BY y, z) FROM t`},
 
@@ -1268,6 +1301,9 @@ func TestParse(t *testing.T) {
 		{`SET a = 3.0`},
 		{`SET a = $1`},
 		{`SET a = off`},
+		{`SET CONSTRAINTS ALL DEFERRED`},
+		{`SET CONSTRAINTS ALL IMMEDIATE`},
+		{`SET CONSTRAINTS a, b DEFERRED`},
 		{`SET TRANSACTION READ ONLY`},
 		{`SET TRANSACTION READ WRITE`},
 		{`SET TRANSACTION ISOLATION LEVEL SERIALIZABLE`},
@@ -1643,6 +1679,10 @@ func TestParse(t *testing.T) {
 		{`BACKUP TABLE foo INTO LATEST IN 'bar'`},
 		{`BACKUP TABLE foo INTO 'subdir' IN 'bar'`},
 		{`BACKUP TABLE foo INTO $1 IN $2`},
+		{`BACKUP INTO LATEST IN 'bar' WITH compact`},
+		{`BACKUP INTO 'subdir' IN 'bar' WITH detached, compact`},
+		{`BACKUP TABLE foo TO 'bar' WITH max_bandwidth='200MiB'`},
+		{`BACKUP INTO 'bar' WITH revision_history, max_bandwidth=$1`},
 		{`CREATE SCHEDULE FOR BACKUP TABLE foo INTO 'bar' RECURRING '@hourly'`},
 		{`CREATE SCHEDULE 'my schedule' FOR BACKUP TABLE foo INTO 'bar' RECURRING '@daily'`},
 		{`CREATE SCHEDULE FOR BACKUP TABLE foo INTO 'bar' RECURRING '@daily'`},
@@ -1703,6 +1743,10 @@ func TestParse(t *testing.T) {
 
 		{`BACKUP TABLE foo TO 'bar' WITH revision_history, detached`},
 		{`RESTORE TABLE foo FROM 'bar' WITH skip_missing_foreign_keys, skip_missing_sequences, detached`},
+		{`RESTORE TABLE foo FROM 'bar' WITH into_db='baz', where='a = 1'`},
+		{`RESTORE TABLE foo FROM $1 WITH where=$2`},
+		{`RESTORE TABLE foo FROM 'bar' WITH into_db='baz', max_bandwidth='64MiB'`},
+		{`RESTORE FROM $1 WITH max_bandwidth=$2`},
 
 		{`IMPORT TABLE foo CREATE USING 'nodelocal://0/some/file' CSV DATA ('path/to/some/file', $1) WITH temp = 'path/to/temp'`},
 		{`EXPLAIN IMPORT TABLE foo CREATE USING 'nodelocal://0/some/file' CSV DATA ('path/to/some/file', $1) WITH temp = 'path/to/temp'`},
@@ -1720,6 +1764,8 @@ func TestParse(t *testing.T) {
 		{`EXPORT INTO CSV 'a' FROM SELECT * FROM a`},
 		{`EXPORT INTO CSV 's3://my/path/%part%.csv' WITH delimiter = '|' FROM TABLE a`},
 		{`EXPORT INTO CSV 's3://my/path/%part%.csv' WITH delimiter = '|' FROM SELECT a, sum(b) FROM c WHERE d = 1 ORDER BY sum(b) DESC LIMIT 10`},
+		{`EXPORT INTO PARQUET 's3://my/path' PARTITION BY (a) FROM TABLE t`},
+		{`EXPORT INTO PARQUET 's3://my/path' PARTITION BY (a, b) WITH compression = 'snappy' FROM SELECT a, b, c FROM t`},
 
 		{`SET ROW (1, true, NULL)`},
 
@@ -1732,6 +1778,9 @@ func TestParse(t *testing.T) {
 		// {`CREATE CHANGEFEED FOR TABLE foo PARTITION bar, baz INTO 'sink'`},
 		// {`CREATE CHANGEFEED FOR DATABASE foo INTO 'sink'`},
 		{`CREATE CHANGEFEED FOR TABLE foo INTO 'sink' WITH bar = 'baz'`},
+		{`CREATE CHANGEFEED INTO 'sink' AS SELECT a, b FROM foo`},
+		{`CREATE CHANGEFEED INTO 'sink' WITH bar = 'baz' AS SELECT * FROM db.foo WHERE status = 'shipped'`},
+		{`EXPERIMENTAL CHANGEFEED AS SELECT a FROM foo WHERE a > 1`},
 
 		// Regression for #15926
 		{`SELECT * FROM ((t1 NATURAL JOIN t2 WITH ORDINALITY AS o1)) WITH ORDINALITY AS o2`},
@@ -1769,6 +1818,24 @@ func TestParse2(t *testing.T) {
 	}{
 		{`CREATE DATABASE a WITH ENCODING = 'foo'`,
 			`CREATE DATABASE a ENCODING = 'foo'`},
+		{`CREATE FUNCTION f(a INT) RETURNS INT AS 'SELECT a' LANGUAGE sql`,
+			`CREATE FUNCTION f(a INT8) RETURNS INT8 LANGUAGE SQL VOLATILE AS 'SELECT a'`},
+		{`CREATE FUNCTION f() RETURNS STRING IMMUTABLE LANGUAGE 'sql' AS 'SELECT ''a'''`,
+			`CREATE FUNCTION f() RETURNS STRING LANGUAGE SQL IMMUTABLE AS e'SELECT \'a\''`},
+		{`CREATE FUNCTION f() RETURNS trigger LANGUAGE sql AS 'SELECT new.*'`,
+			`CREATE FUNCTION f() RETURNS TRIGGER LANGUAGE SQL VOLATILE AS 'SELECT new.*'`},
+		{`CREATE TRIGGER t BEFORE UPDATE ON kv FOR EACH ROW EXECUTE PROCEDURE f()`,
+			`CREATE TRIGGER t BEFORE UPDATE ON kv FOR EACH ROW EXECUTE FUNCTION f()`},
+		{`CREATE TABLE a (b INT8, FOREIGN KEY (b) REFERENCES c (x) DEFERRABLE)`,
+			`CREATE TABLE a (b INT8, FOREIGN KEY (b) REFERENCES c (x) DEFERRABLE INITIALLY IMMEDIATE)`},
+		{`CREATE TABLE a (b INT8, FOREIGN KEY (b) REFERENCES c (x) INITIALLY DEFERRED)`,
+			`CREATE TABLE a (b INT8, FOREIGN KEY (b) REFERENCES c (x) DEFERRABLE INITIALLY DEFERRED)`},
+		{`CREATE TABLE a (b INT8, FOREIGN KEY (b) REFERENCES c (x) INITIALLY IMMEDIATE)`,
+			`CREATE TABLE a (b INT8, FOREIGN KEY (b) REFERENCES c (x))`},
+		{`CREATE TABLE a (b INT8, UNIQUE (b) DEFERRABLE)`,
+			`CREATE TABLE a (b INT8, UNIQUE (b) DEFERRABLE INITIALLY IMMEDIATE)`},
+		{`CREATE TABLE a (b INT8, CHECK (b > 0) INITIALLY DEFERRED)`,
+			`CREATE TABLE a (b INT8, CHECK (b > 0) DEFERRABLE INITIALLY DEFERRED)`},
 		{`CREATE DATABASE a TEMPLATE = template0`,
 			`CREATE DATABASE a TEMPLATE = 'template0'`},
 		{`CREATE DATABASE a TEMPLATE = invalid`,
@@ -3138,8 +3205,7 @@ func TestUnimplementedSyntax(t *testing.T) {
 		{`CREATE DEFAULT CONVERSION a`, 0, `create def conv`, ``},
 		{`CREATE FOREIGN DATA WRAPPER a`, 0, `create fdw`, ``},
 		{`CREATE FOREIGN TABLE a`, 0, `create foreign table`, ``},
-		{`CREATE FUNCTION a`, 17511, `create`, ``},
-		{`CREATE OR REPLACE FUNCTION a`, 17511, `create`, ``},
+		{`CREATE FUNCTION a() RETURNS INT LANGUAGE plpgsql AS 'x'`, 17511, `create function language plpgsql`, ``},
 		{`CREATE LANGUAGE a`, 17511, `create language a`, ``},
 		{`CREATE OPERATOR a`, 0, `create operator`, ``},
 		{`CREATE PUBLICATION a`, 0, `create publication`, ``},
@@ -3148,7 +3214,9 @@ func TestUnimplementedSyntax(t *testing.T) {
 		{`CREATE SUBSCRIPTION a`, 0, `create subscription`, ``},
 		{`CREATE TABLESPACE a`, 54113, `create tablespace`, ``},
 		{`CREATE TEXT SEARCH a`, 7821, `create text`, ``},
-		{`CREATE TRIGGER a`, 28296, `create`, ``},
+		{`CREATE TRIGGER a AFTER INSERT ON t FOR EACH STATEMENT EXECUTE FUNCTION f()`, 28296, `create trigger for each statement`, ``},
+		{`CREATE TRIGGER a BEFORE TRUNCATE ON t FOR EACH ROW EXECUTE FUNCTION f()`, 28296, `create trigger truncate`, ``},
+		{`CREATE TRIGGER a BEFORE UPDATE OF b ON t FOR EACH ROW EXECUTE FUNCTION f()`, 28296, `create trigger update of`, ``},
 
 		{`DROP ACCESS METHOD a`, 0, `drop access method`, ``},
 		{`DROP AGGREGATE a`, 0, `drop aggregate`, ``},
@@ -3159,7 +3227,6 @@ func TestUnimplementedSyntax(t *testing.T) {
 		{`DROP EXTENSION a`, 0, `drop extension a`, ``},
 		{`DROP FOREIGN TABLE a`, 0, `drop foreign table`, ``},
 		{`DROP FOREIGN DATA WRAPPER a`, 0, `drop fdw`, ``},
-		{`DROP FUNCTION a`, 17511, `drop `, ``},
 		{`DROP LANGUAGE a`, 17511, `drop language a`, ``},
 		{`DROP OPERATOR a`, 0, `drop operator`, ``},
 		{`DROP PUBLICATION a`, 0, `drop publication`, ``},
@@ -3167,14 +3234,12 @@ func TestUnimplementedSyntax(t *testing.T) {
 		{`DROP SERVER a`, 0, `drop server`, ``},
 		{`DROP SUBSCRIPTION a`, 0, `drop subscription`, ``},
 		{`DROP TEXT SEARCH a`, 7821, `drop text`, ``},
-		{`DROP TRIGGER a`, 28296, `drop`, ``},
 
 		{`DISCARD PLANS`, 0, `discard plans`, ``},
 		{`DISCARD SEQUENCES`, 0, `discard sequences`, ``},
 		{`DISCARD TEMP`, 0, `discard temp`, ``},
 		{`DISCARD TEMPORARY`, 0, `discard temp`, ``},
 
-		{`SET CONSTRAINTS foo`, 0, `set constraints`, ``},
 		{`SET LOCAL foo = bar`, 32562, ``, ``},
 		{`SET foo FROM CURRENT`, 0, `set from current`, ``},
 
@@ -3190,13 +3255,6 @@ func TestUnimplementedSyntax(t *testing.T) {
 		{`CREATE TABLE a(b INT8 REFERENCES c(x) MATCH PARTIAL`, 20305, `match partial`, ``},
 		{`CREATE TABLE a(b INT8, FOREIGN KEY (b) REFERENCES c(x) MATCH PARTIAL)`, 20305, `match partial`, ``},
 
-		{`CREATE TABLE a(b INT8, FOREIGN KEY (b) REFERENCES c(x) DEFERRABLE)`, 31632, `deferrable`, ``},
-		{`CREATE TABLE a(b INT8, FOREIGN KEY (b) REFERENCES c(x) INITIALLY DEFERRED)`, 31632, `initially deferred`, ``},
-		{`CREATE TABLE a(b INT8, FOREIGN KEY (b) REFERENCES c(x) INITIALLY IMMEDIATE)`, 31632, `initially immediate`, ``},
-		{`CREATE TABLE a(b INT8, FOREIGN KEY (b) REFERENCES c(x) DEFERRABLE INITIALLY DEFERRED)`, 31632, `initially deferred`, ``},
-		{`CREATE TABLE a(b INT8, FOREIGN KEY (b) REFERENCES c(x) DEFERRABLE INITIALLY IMMEDIATE)`, 31632, `initially immediate`, ``},
-		{`CREATE TABLE a(b INT8, UNIQUE (b) DEFERRABLE)`, 31632, `deferrable`, ``},
-		{`CREATE TABLE a(b INT8, CHECK (b > 0) DEFERRABLE)`, 31632, `deferrable`, ``},
 
 		{`CREATE TABLE a (LIKE b INCLUDING COMMENTS)`, 47071, `like table`, ``},
 		{`CREATE TABLE a (LIKE b INCLUDING IDENTITY)`, 47071, `like table`, ``},
@@ -3257,16 +3315,10 @@ func TestUnimplementedSyntax(t *testing.T) {
 		{`SELECT a(b) 'c'`, 0, `a(...) SCONST`, ``},
 		{`SELECT (a,b) OVERLAPS (c,d)`, 0, `overlaps`, ``},
 		{`SELECT UNIQUE (SELECT b)`, 0, `UNIQUE predicate`, ``},
-		{`SELECT GROUPING (a,b,c)`, 0, `d_expr grouping`, ``},
 		{`SELECT a(VARIADIC b)`, 0, `variadic`, ``},
 		{`SELECT a(b, c, VARIADIC b)`, 0, `variadic`, ``},
 		{`SELECT TREAT (a AS INT8)`, 0, `treat`, ``},
 
-		{`SELECT 1 FROM t GROUP BY ROLLUP (b)`, 46280, `rollup`, ``},
-		{`SELECT 1 FROM t GROUP BY a, ROLLUP (b)`, 46280, `rollup`, ``},
-		{`SELECT 1 FROM t GROUP BY CUBE (b)`, 46280, `cube`, ``},
-		{`SELECT 1 FROM t GROUP BY GROUPING SETS (b)`, 46280, `grouping sets`, ``},
-
 		{`SELECT a FROM t ORDER BY a NULLS LAST`, 6224, ``, ``},
 		{`SELECT a FROM t ORDER BY a ASC NULLS LAST`, 6224, ``, ``},
 		{`SELECT a FROM t ORDER BY a DESC NULLS FIRST`, 6224, ``, ``},
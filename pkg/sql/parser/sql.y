@@ -70,6 +70,58 @@ func unimplementedWithIssueDetail(sqllex sqlLexer, issue int, detail string) int
     sqllex.(*lexer).UnimplementedWithIssueDetail(issue, detail)
     return 1
 }
+
+// setCreateFunctionOptions applies the options of a CREATE FUNCTION statement
+// to the given node. The options are collected as key/value pairs, since they
+// can be given in any order.
+func setCreateFunctionOptions(sqllex sqlLexer, n *tree.CreateFunction, opts []tree.KVOption) int {
+    n.Volatility = tree.VolatilityVolatile
+    seen := make(map[tree.Name]bool, len(opts))
+    var hasBody bool
+    var language string
+    for _, opt := range opts {
+        if seen[opt.Key] {
+            return setErr(sqllex, errors.New("conflicting or redundant options"))
+        }
+        seen[opt.Key] = true
+        val := string(tree.MustBeDString(opt.Value))
+        switch opt.Key {
+        case "language":
+            language = strings.ToLower(val)
+        case "volatility":
+            switch val {
+            case "immutable":
+                n.Volatility = tree.VolatilityImmutable
+            case "stable":
+                n.Volatility = tree.VolatilityStable
+            }
+        case "as":
+            n.Body = val
+            hasBody = true
+        }
+    }
+    if language == "" {
+        return setErr(sqllex, errors.New("no language specified"))
+    }
+    if language != "sql" {
+        return unimplementedWithIssueDetail(sqllex, 17511, "create function language " + language)
+    }
+    if !hasBody {
+        return setErr(sqllex, errors.New("no function body specified"))
+    }
+    return 0
+}
+
+// setCreateFunctionReturnType sets the return type of a CREATE FUNCTION
+// statement. TRIGGER is not a type; returning it marks the function as a
+// trigger function.
+func setCreateFunctionReturnType(n *tree.CreateFunction, typ tree.ResolvableTypeReference) {
+    if name, ok := typ.(*tree.UnresolvedObjectName); ok && name.NumParts == 1 && name.Parts[0] == "trigger" {
+        n.ReturnsTrigger = true
+        return
+    }
+    n.ReturnType = typ
+}
 %}
 
 %{
@@ -494,6 +546,27 @@ func (u *sqlSymUnion) kvOptions() []tree.KVOption {
     }
     return nil
 }
+func (u *sqlSymUnion) funcArg() tree.FuncArg {
+    return u.val.(tree.FuncArg)
+}
+func (u *sqlSymUnion) funcArgs() tree.FuncArgs {
+    return u.val.(tree.FuncArgs)
+}
+func (u *sqlSymUnion) funcObj() tree.FuncObj {
+    return u.val.(tree.FuncObj)
+}
+func (u *sqlSymUnion) funcObjs() []tree.FuncObj {
+    return u.val.([]tree.FuncObj)
+}
+func (u *sqlSymUnion) triggerActionTime() tree.TriggerActionTime {
+    return u.val.(tree.TriggerActionTime)
+}
+func (u *sqlSymUnion) triggerEvent() tree.TriggerEvent {
+    return u.val.(tree.TriggerEvent)
+}
+func (u *sqlSymUnion) triggerEvents() tree.TriggerEvents {
+    return u.val.(tree.TriggerEvents)
+}
 func (u *sqlSymUnion) backupOptions() *tree.BackupOptions {
   return u.val.(*tree.BackupOptions)
 }
@@ -509,6 +582,9 @@ func (u *sqlSymUnion) transactionModes() tree.TransactionModes {
 func (u *sqlSymUnion) compositeKeyMatchMethod() tree.CompositeKeyMatchMethod {
   return u.val.(tree.CompositeKeyMatchMethod)
 }
+func (u *sqlSymUnion) constraintDeferrability() tree.ConstraintDeferrability {
+  return u.val.(tree.ConstraintDeferrability)
+}
 func (u *sqlSymUnion) referenceAction() tree.ReferenceAction {
     return u.val.(tree.ReferenceAction)
 }
@@ -616,7 +692,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %token <str> DEALLOCATE DECLARE DEFERRABLE DEFERRED DELETE DESC DESTINATION DETACHED
 %token <str> DISCARD DISTINCT DO DOMAIN DOUBLE DROP
 
-%token <str> ELSE ENCODING ENCRYPTION_PASSPHRASE END ENUM ENUMS ESCAPE EXCEPT EXCLUDE EXCLUDING
+%token <str> EACH ELSE ENCODING ENCRYPTION_PASSPHRASE END ENUM ENUMS ESCAPE EXCEPT EXCLUDE EXCLUDING
 %token <str> EXISTS EXECUTE EXECUTION EXPERIMENTAL
 %token <str> EXPERIMENTAL_FINGERPRINTS EXPERIMENTAL_REPLICA
 %token <str> EXPERIMENTAL_AUDIT
@@ -633,7 +709,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %token <str> HAVING HASH HIGH HISTOGRAM HOUR
 
 %token <str> IDENTITY
-%token <str> IF IFERROR IFNULL IGNORE_FOREIGN_KEYS ILIKE IMMEDIATE IMPORT IN INCLUDE INCLUDING INCREMENT INCREMENTAL
+%token <str> IF IFERROR IFNULL IGNORE_FOREIGN_KEYS ILIKE IMMEDIATE IMMUTABLE IMPORT IN INCLUDE INCLUDING INCREMENT INCREMENTAL
 %token <str> INET INET_CONTAINED_BY_OR_EQUALS
 %token <str> INET_CONTAINS_OR_EQUALS INDEX INDEXES INHERITS INJECT INTERLEAVE INITIALLY
 %token <str> INNER INSERT INT INTEGER
@@ -648,7 +724,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %token <str> LINESTRING LINESTRINGM LINESTRINGZ LINESTRINGZM
 %token <str> LIST LOCAL LOCALITY LOCALTIME LOCALTIMESTAMP LOCKED LOGIN LOOKUP LOW LSHIFT
 
-%token <str> MATCH MATERIALIZED MERGE MINVALUE MAXVALUE METHOD MINUTE MODIFYCLUSTERSETTING MONTH
+%token <str> MATCH MATERIALIZED MAX_BANDWIDTH MERGE MINVALUE MAXVALUE METHOD MINUTE MODIFYCLUSTERSETTING MONTH
 %token <str> MULTILINESTRING MULTILINESTRINGM MULTILINESTRINGZ MULTILINESTRINGZM
 %token <str> MULTIPOINT MULTIPOINTM MULTIPOINTZ MULTIPOINTZM
 %token <str> MULTIPOLYGON MULTIPOLYGONM MULTIPOLYGONZ MULTIPOLYGONZM
@@ -663,14 +739,14 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %token <str> PARENT PARTIAL PARTITION PARTITIONS PASSWORD PAUSE PAUSED PHYSICAL PLACING
 %token <str> PLAN PLANS POINT POINTM POINTZ POINTZM POLYGON POLYGONM POLYGONZ POLYGONZM
 %token <str> POSITION PRECEDING PRECISION PREPARE PRESERVE PRIMARY PRIORITY PRIVILEGES
-%token <str> PROCEDURAL PUBLIC PUBLICATION
+%token <str> PROCEDURAL PROCEDURE PUBLIC PUBLICATION
 
 %token <str> QUERIES QUERY
 
 %token <str> RANGE RANGES READ REAL REASSIGN RECURSIVE RECURRING REF REFERENCES REFRESH
 %token <str> REGCLASS REGION REGIONAL REGIONS REGPROC REGPROCEDURE REGNAMESPACE REGTYPE REINDEX
 %token <str> REMOVE_PATH RENAME REPEATABLE REPLACE
-%token <str> RELEASE RESET RESTORE RESTRICT RESUME RETURNING RETRY REVISION_HISTORY REVOKE RIGHT
+%token <str> RELEASE RESET RESTORE RESTRICT RESUME RETURNING RETURNS RETRY REVISION_HISTORY REVOKE RIGHT
 %token <str> ROLE ROLES ROLLBACK ROLLUP ROW ROWS RSHIFT RULE RUNNING
 
 %token <str> SAVEPOINT SCATTER SCHEDULE SCHEDULES SCHEMA SCHEMAS SCRUB SEARCH SECOND SELECT SEQUENCE SEQUENCES
@@ -678,7 +754,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %token <str> SHARE SHOW SIMILAR SIMPLE SKIP SKIP_MISSING_FOREIGN_KEYS
 %token <str> SKIP_MISSING_SEQUENCES SKIP_MISSING_SEQUENCE_OWNERS SKIP_MISSING_VIEWS SMALLINT SMALLSERIAL SNAPSHOT SOME SPLIT SQL
 
-%token <str> START STATISTICS STATUS STDIN STRICT STRING STORAGE STORE STORED STORING SUBSTRING
+%token <str> STABLE START STATEMENT STATISTICS STATUS STDIN STRICT STRING STORAGE STORE STORED STORING SUBSTRING
 %token <str> SURVIVE SURVIVAL SYMMETRIC SYNTAX SYSTEM SQRT SUBSCRIPTION
 
 %token <str> TABLE TABLES TABLESPACE TEMP TEMPLATE TEMPORARY TENANT TESTING_RELOCATE EXPERIMENTAL_RELOCATE TEXT THEN
@@ -690,7 +766,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %token <str> UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN UNLOGGED UNSPLIT
 %token <str> UPDATE UPSERT UNTIL USE USER USERS USING UUID
 
-%token <str> VALID VALIDATE VALUE VALUES VARBIT VARCHAR VARIADIC VIEW VARYING VIEWACTIVITY VIRTUAL
+%token <str> VALID VALIDATE VALUE VALUES VARBIT VARCHAR VARIADIC VIEW VARYING VIEWACTIVITY VIRTUAL VOLATILE
 
 %token <str> WHEN WHERE WINDOW WITH WITHIN WITHOUT WORK WRITE
 
@@ -812,6 +888,8 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <tree.Statement> create_table_stmt
 %type <tree.Statement> create_table_as_stmt
 %type <tree.Statement> create_view_stmt
+%type <tree.Statement> create_func_stmt
+%type <tree.Statement> create_trigger_stmt
 %type <tree.Statement> create_sequence_stmt
 
 %type <tree.Statement> create_stats_stmt
@@ -832,6 +910,8 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <tree.Statement> drop_table_stmt
 %type <tree.Statement> drop_type_stmt
 %type <tree.Statement> drop_view_stmt
+%type <tree.Statement> drop_func_stmt
+%type <tree.Statement> drop_trigger_stmt
 %type <tree.Statement> drop_sequence_stmt
 
 %type <tree.Statement> analyze_stmt
@@ -867,6 +947,8 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <tree.Statement> set_session_stmt
 %type <tree.Statement> set_csetting_stmt
 %type <tree.Statement> set_transaction_stmt
+%type <tree.Statement> set_constraints_stmt
+%type <bool> constraints_set_mode
 %type <tree.Statement> set_exprs_internal
 %type <tree.Statement> generic_set
 %type <tree.Statement> set_rest_more
@@ -926,10 +1008,20 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <[]string> opt_incremental
 %type <tree.KVOption> kv_option
 %type <[]tree.KVOption> kv_option_list opt_with_options var_set_list opt_with_schedule_options
+%type <tree.KVOption> create_func_opt_item
+%type <[]tree.KVOption> create_func_opt_list
+%type <tree.FuncArg> func_arg
+%type <tree.FuncArgs> opt_func_arg_list func_arg_list
+%type <tree.FuncObj> func_obj
+%type <[]tree.FuncObj> func_obj_list
+%type <tree.TriggerActionTime> trigger_action_time
+%type <tree.TriggerEvent> trigger_event
+%type <tree.TriggerEvents> trigger_event_list
 %type <*tree.BackupOptions> opt_with_backup_options backup_options backup_options_list
 %type <*tree.RestoreOptions> opt_with_restore_options restore_options restore_options_list
 %type <*tree.CopyOptions> opt_with_copy_options copy_options copy_options_list
 %type <str> import_format
+%type <tree.NameList> opt_export_partition_by
 %type <tree.StorageParam> storage_parameter
 %type <[]tree.StorageParam> storage_parameter_list opt_table_with opt_with_storage_parameter_list
 
@@ -1018,7 +1110,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <tree.NameList> name_list privilege_list
 %type <[]int32> opt_array_bounds
 %type <tree.From> from_clause
-%type <tree.TableExprs> from_list rowsfrom_list opt_from_list
+%type <tree.TableExprs> from_list rowsfrom_list opt_from_list opt_using_clause
 %type <tree.TablePatterns> table_pattern_list single_table_pattern_list
 %type <tree.TableNames> table_name_list opt_locked_rels
 %type <tree.Exprs> expr_list opt_expr_list tuple1_ambiguous_values tuple1_unambiguous_values
@@ -1034,7 +1126,6 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <*tree.Limit> select_limit opt_select_limit
 %type <tree.TableNames> relation_expr_list
 %type <tree.ReturningClause> returning_clause
-%type <empty> opt_using_clause
 %type <tree.RefreshDataOption> opt_clear_data
 
 %type <[]tree.SequenceOption> sequence_option_list opt_sequence_option_list
@@ -1163,6 +1254,7 @@ func (u *sqlSymUnion) objectNamePrefixList() tree.ObjectNamePrefixList {
 %type <tree.NamedColumnQualification> col_qualification create_as_col_qualification
 %type <tree.ColumnQualification> col_qualification_elem create_as_col_qualification_elem
 %type <tree.CompositeKeyMatchMethod> key_match
+%type <tree.ConstraintDeferrability> opt_deferrable
 %type <tree.ReferenceActions> reference_actions
 %type <tree.ReferenceAction> reference_action reference_on_delete reference_on_update
 
@@ -2286,6 +2378,8 @@ opt_clear_data:
 //    encryption_passphrase="secret": encrypt backups
 //    kms="[kms_provider]://[kms_host]/[master_key_identifier]?[parameters]" : encrypt backups using KMS
 //    detached: execute backup job asynchronously, without waiting for its completion
+//    compact: merge the backups of the chain INTO ... IN a collection into a new full backup
+//    max_bandwidth='<size>': limit the bytes per second exported by each node, e.g. '200MiB'
 //
 // %SeeAlso: RESTORE, WEBDOCS/backup.html
 backup_stmt:
@@ -2391,6 +2485,14 @@ backup_options:
 	{
     $$.val = &tree.BackupOptions{EncryptionKMSURI: $3.stringOrPlaceholderOptList()}
 	}
+| COMPACT
+  {
+    $$.val = &tree.BackupOptions{Compact: true}
+  }
+| MAX_BANDWIDTH '=' string_or_placeholder
+  {
+    $$.val = &tree.BackupOptions{MaxBandwidth: $3.expr()}
+  }
 // %Help: CREATE SCHEDULE FOR BACKUP - backup data periodically
 // %Category: CCL
 // %Text:
@@ -2563,6 +2665,8 @@ opt_with_schedule_options:
 //    encryption_passphrase=passphrase: decrypt BACKUP with specified passphrase
 //    kms="[kms_provider]://[kms_host]/[master_key_identifier]?[parameters]" : decrypt backups using KMS
 //    detached: execute restore job asynchronously, without waiting for its completion
+//    where='<predicate>': only restore the rows of a single table matching the predicate on its key columns
+//    max_bandwidth='<size>': limit the bytes per second read by each node, e.g. '200MiB'
 // %SeeAlso: BACKUP, WEBDOCS/restore.html
 restore_stmt:
   RESTORE FROM list_of_string_or_placeholder_opt_list opt_as_of_clause opt_with_restore_options
@@ -2677,6 +2781,14 @@ restore_options:
   {
     $$.val = &tree.RestoreOptions{Detached: true}
   }
+| WHERE '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{Where: $3.expr()}
+  }
+| MAX_BANDWIDTH '=' string_or_placeholder
+  {
+    $$.val = &tree.RestoreOptions{MaxBandwidth: $3.expr()}
+  }
 
 import_format:
   name
@@ -2761,22 +2873,34 @@ import_stmt:
 // %Help: EXPORT - export data to file in a distributed manner
 // %Category: CCL
 // %Text:
-// EXPORT INTO <format> <datafile> [WITH <option> [= value] [,...]] FROM <query>
+// EXPORT INTO <format> <datafile> [PARTITION BY ( <colname> [, ...] )]
+//        [WITH <option> [= value] [,...]] FROM <query>
 //
 // Formats:
 //    CSV
+//    PARQUET
 //
 // Options:
 //    delimiter = '...'   [CSV-specific]
 //
 // %SeeAlso: SELECT
 export_stmt:
-  EXPORT INTO import_format string_or_placeholder opt_with_options FROM select_stmt
+  EXPORT INTO import_format string_or_placeholder opt_export_partition_by opt_with_options FROM select_stmt
   {
-    $$.val = &tree.Export{Query: $7.slct(), FileFormat: $3, File: $4.expr(), Options: $5.kvOptions()}
+    $$.val = &tree.Export{Query: $8.slct(), FileFormat: $3, File: $4.expr(), PartitionBy: $5.nameList(), Options: $6.kvOptions()}
   }
 | EXPORT error // SHOW HELP: EXPORT
 
+opt_export_partition_by:
+  PARTITION BY '(' name_list ')'
+  {
+    $$.val = $4.nameList()
+  }
+| /* EMPTY */
+  {
+    $$.val = tree.NameList(nil)
+  }
+
 string_or_placeholder:
   non_reserved_word_or_sconst
   {
@@ -3057,7 +3181,7 @@ comment_text:
 // %Text:
 // CREATE DATABASE, CREATE TABLE, CREATE INDEX, CREATE TABLE AS,
 // CREATE USER, CREATE VIEW, CREATE SEQUENCE, CREATE STATISTICS,
-// CREATE ROLE, CREATE TYPE, CREATE EXTENSION
+// CREATE ROLE, CREATE TYPE, CREATE EXTENSION, CREATE FUNCTION
 create_stmt:
   create_role_stmt     // EXTEND WITH HELP: CREATE ROLE
 | create_ddl_stmt      // help texts in sub-rule
@@ -3089,8 +3213,6 @@ create_unsupported:
 | CREATE DEFAULT CONVERSION error { return unimplemented(sqllex, "create def conv") }
 | CREATE FOREIGN TABLE error { return unimplemented(sqllex, "create foreign table") }
 | CREATE FOREIGN DATA error { return unimplemented(sqllex, "create fdw") }
-| CREATE FUNCTION error { return unimplementedWithIssueDetail(sqllex, 17511, "create function") }
-| CREATE OR REPLACE FUNCTION error { return unimplementedWithIssueDetail(sqllex, 17511, "create function") }
 | CREATE opt_or_replace opt_trusted opt_procedural LANGUAGE name error { return unimplementedWithIssueDetail(sqllex, 17511, "create language " + $6) }
 | CREATE OPERATOR error { return unimplemented(sqllex, "create operator") }
 | CREATE PUBLICATION error { return unimplemented(sqllex, "create publication") }
@@ -3099,7 +3221,6 @@ create_unsupported:
 | CREATE SUBSCRIPTION error { return unimplemented(sqllex, "create subscription") }
 | CREATE TABLESPACE error { return unimplementedWithIssueDetail(sqllex, 54113, "create tablespace") }
 | CREATE TEXT error { return unimplementedWithIssueDetail(sqllex, 7821, "create text") }
-| CREATE TRIGGER error { return unimplementedWithIssueDetail(sqllex, 28296, "create") }
 
 opt_or_replace:
   OR REPLACE {}
@@ -3124,7 +3245,6 @@ drop_unsupported:
 | DROP EXTENSION name error { return unimplemented(sqllex, "drop extension " + $3) }
 | DROP FOREIGN TABLE error { return unimplemented(sqllex, "drop foreign table") }
 | DROP FOREIGN DATA error { return unimplemented(sqllex, "drop fdw") }
-| DROP FUNCTION error { return unimplementedWithIssueDetail(sqllex, 17511, "drop function") }
 | DROP opt_procedural LANGUAGE name error { return unimplementedWithIssueDetail(sqllex, 17511, "drop language " + $4) }
 | DROP OPERATOR error { return unimplemented(sqllex, "drop operator") }
 | DROP PUBLICATION error { return unimplemented(sqllex, "drop publication") }
@@ -3132,7 +3252,6 @@ drop_unsupported:
 | DROP SERVER error { return unimplemented(sqllex, "drop server") }
 | DROP SUBSCRIPTION error { return unimplemented(sqllex, "drop subscription") }
 | DROP TEXT error { return unimplementedWithIssueDetail(sqllex, 7821, "drop text") }
-| DROP TRIGGER error { return unimplementedWithIssueDetail(sqllex, 28296, "drop") }
 
 create_ddl_stmt:
   create_changefeed_stmt
@@ -3146,6 +3265,8 @@ create_ddl_stmt:
 | create_type_stmt     // EXTEND WITH HELP: CREATE TYPE
 | create_view_stmt     // EXTEND WITH HELP: CREATE VIEW
 | create_sequence_stmt // EXTEND WITH HELP: CREATE SEQUENCE
+| create_func_stmt     // EXTEND WITH HELP: CREATE FUNCTION
+| create_trigger_stmt  // EXTEND WITH HELP: CREATE TRIGGER
 
 // %Help: CREATE STATISTICS - create a new table statistic
 // %Category: Misc
@@ -3259,6 +3380,34 @@ create_changefeed_stmt:
       Options: $5.kvOptions(),
     }
   }
+| CREATE CHANGEFEED opt_changefeed_sink opt_with_options AS SELECT target_list FROM table_name opt_where_clause
+  {
+    name := $9.unresolvedObjectName().ToTableName()
+    $$.val = &tree.CreateChangefeed{
+      Targets: tree.TargetList{Tables: tree.TablePatterns{$9.unresolvedObjectName().ToUnresolvedName()}},
+      SinkURI: $3.expr(),
+      Options: $4.kvOptions(),
+      Select: &tree.SelectClause{
+        Exprs: $7.selExprs(),
+        From:  tree.From{Tables: tree.TableExprs{&name}},
+        Where: tree.NewWhere(tree.AstWhere, $10.expr()),
+      },
+    }
+  }
+| EXPERIMENTAL CHANGEFEED opt_with_options AS SELECT target_list FROM table_name opt_where_clause
+  {
+    /* SKIP DOC */
+    name := $8.unresolvedObjectName().ToTableName()
+    $$.val = &tree.CreateChangefeed{
+      Targets: tree.TargetList{Tables: tree.TablePatterns{$8.unresolvedObjectName().ToUnresolvedName()}},
+      Options: $3.kvOptions(),
+      Select: &tree.SelectClause{
+        Exprs: $6.selExprs(),
+        From:  tree.From{Tables: tree.TableExprs{&name}},
+        Where: tree.NewWhere(tree.AstWhere, $9.expr()),
+      },
+    }
+  }
 
 changefeed_targets:
   single_table_pattern_list
@@ -3294,7 +3443,9 @@ opt_changefeed_sink:
 
 // %Help: DELETE - delete rows from a table
 // %Category: DML
-// %Text: DELETE FROM <tablename> [WHERE <expr>]
+// %Text: DELETE FROM <tablename> [[AS] <name>]
+//               [USING <table_exprs...>]
+//               [WHERE <expr>]
 //               [ORDER BY <exprs...>]
 //               [LIMIT <expr>]
 //               [RETURNING <exprs...>]
@@ -3305,6 +3456,7 @@ delete_stmt:
     $$.val = &tree.Delete{
       With: $1.with(),
       Table: $4.tblExpr(),
+      Using: $5.tblExprs(),
       Where: tree.NewWhere(tree.AstWhere, $6.expr()),
       OrderBy: $7.orderBy(),
       Limit: $8.limit(),
@@ -3314,8 +3466,14 @@ delete_stmt:
 | opt_with_clause DELETE error // SHOW HELP: DELETE
 
 opt_using_clause:
-  USING from_list { return unimplementedWithIssueDetail(sqllex, 40963, "delete using") }
-| /* EMPTY */ { }
+  USING from_list
+  {
+    $$.val = $2.tblExprs()
+  }
+| /* EMPTY */
+  {
+    $$.val = tree.TableExprs{}
+  }
 
 
 // %Help: DISCARD - reset the session to its initial state
@@ -3336,7 +3494,7 @@ discard_stmt:
 // %Category: Group
 // %Text:
 // DROP DATABASE, DROP INDEX, DROP TABLE, DROP VIEW, DROP SEQUENCE,
-// DROP USER, DROP ROLE, DROP TYPE
+// DROP USER, DROP ROLE, DROP TYPE, DROP FUNCTION
 drop_stmt:
   drop_ddl_stmt      // help texts in sub-rule
 | drop_role_stmt     // EXTEND WITH HELP: DROP ROLE
@@ -3352,6 +3510,8 @@ drop_ddl_stmt:
 | drop_sequence_stmt // EXTEND WITH HELP: DROP SEQUENCE
 | drop_schema_stmt   // EXTEND WITH HELP: DROP SCHEMA
 | drop_type_stmt     // EXTEND WITH HELP: DROP TYPE
+| drop_func_stmt     // EXTEND WITH HELP: DROP FUNCTION
+| drop_trigger_stmt  // EXTEND WITH HELP: DROP TRIGGER
 
 // %Help: DROP VIEW - remove a view
 // %Category: DDL
@@ -3486,6 +3646,78 @@ drop_type_stmt:
   }
 | DROP TYPE error // SHOW HELP: DROP TYPE
 
+// %Help: DROP FUNCTION - remove a function
+// %Category: DDL
+// %Text: DROP FUNCTION [IF EXISTS] <name> [( [<argtype> [, ...]] )] [, ...] [CASCADE | RESTRICT]
+// %SeeAlso: CREATE FUNCTION
+drop_func_stmt:
+  DROP FUNCTION func_obj_list opt_drop_behavior
+  {
+    $$.val = &tree.DropFunction{
+      Functions: $3.funcObjs(),
+      IfExists: false,
+      DropBehavior: $4.dropBehavior(),
+    }
+  }
+| DROP FUNCTION IF EXISTS func_obj_list opt_drop_behavior
+  {
+    $$.val = &tree.DropFunction{
+      Functions: $5.funcObjs(),
+      IfExists: true,
+      DropBehavior: $6.dropBehavior(),
+    }
+  }
+| DROP FUNCTION error // SHOW HELP: DROP FUNCTION
+
+// %Help: DROP TRIGGER - remove a trigger
+// %Category: DDL
+// %Text: DROP TRIGGER [IF EXISTS] <name> ON <tablename> [CASCADE | RESTRICT]
+// %SeeAlso: CREATE TRIGGER
+drop_trigger_stmt:
+  DROP TRIGGER name ON table_name opt_drop_behavior
+  {
+    $$.val = &tree.DropTrigger{
+      Name: tree.Name($3),
+      Table: $5.unresolvedObjectName().ToTableName(),
+      IfExists: false,
+      DropBehavior: $6.dropBehavior(),
+    }
+  }
+| DROP TRIGGER IF EXISTS name ON table_name opt_drop_behavior
+  {
+    $$.val = &tree.DropTrigger{
+      Name: tree.Name($5),
+      Table: $7.unresolvedObjectName().ToTableName(),
+      IfExists: true,
+      DropBehavior: $8.dropBehavior(),
+    }
+  }
+| DROP TRIGGER error // SHOW HELP: DROP TRIGGER
+
+func_obj_list:
+  func_obj
+  {
+    $$.val = []tree.FuncObj{$1.funcObj()}
+  }
+| func_obj_list ',' func_obj
+  {
+    $$.val = append($1.funcObjs(), $3.funcObj())
+  }
+
+func_obj:
+  db_object_name
+  {
+    $$.val = tree.FuncObj{Name: $1.unresolvedObjectName()}
+  }
+| db_object_name '(' ')'
+  {
+    $$.val = tree.FuncObj{Name: $1.unresolvedObjectName(), Args: []tree.ResolvableTypeReference{}}
+  }
+| db_object_name '(' type_list ')'
+  {
+    $$.val = tree.FuncObj{Name: $1.unresolvedObjectName(), Args: $3.typeReferences()}
+  }
+
 target_types:
   type_name_list
   {
@@ -3968,7 +4200,7 @@ use_stmt:
 nonpreparable_set_stmt:
   set_transaction_stmt // EXTEND WITH HELP: SET TRANSACTION
 | set_exprs_internal   { /* SKIP DOC */ }
-| SET CONSTRAINTS error { return unimplemented(sqllex, "set constraints") }
+| set_constraints_stmt // EXTEND WITH HELP: SET CONSTRAINTS
 | SET LOCAL error { return unimplementedWithIssue(sqllex, 32562) }
 
 // SET SESSION / SET CLUSTER SETTING
@@ -4153,6 +4385,36 @@ set_transaction_stmt:
   }
 | SET SESSION TRANSACTION error // SHOW HELP: SET TRANSACTION
 
+// %Help: SET CONSTRAINTS - set the checking mode of deferrable constraints
+// %Category: Txn
+// %Text:
+// SET CONSTRAINTS { ALL | <constraintname> [, ...] } { DEFERRED | IMMEDIATE }
+//
+// Deferred constraints are checked when the transaction commits. Setting a
+// constraint to IMMEDIATE checks the changes made so far in the transaction.
+//
+// %SeeAlso: SET TRANSACTION
+set_constraints_stmt:
+  SET CONSTRAINTS ALL constraints_set_mode
+  {
+    $$.val = &tree.SetConstraints{Deferred: $4.bool()}
+  }
+| SET CONSTRAINTS name_list constraints_set_mode
+  {
+    $$.val = &tree.SetConstraints{Names: $3.nameList(), Deferred: $4.bool()}
+  }
+| SET CONSTRAINTS error // SHOW HELP: SET CONSTRAINTS
+
+constraints_set_mode:
+  DEFERRED
+  {
+    $$.val = true
+  }
+| IMMEDIATE
+  {
+    $$.val = false
+  }
+
 generic_set:
   var_name to_or_eq var_list
   {
@@ -6200,6 +6462,7 @@ constraint_elem:
   {
     $$.val = &tree.CheckConstraintTableDef{
       Expr: $3.expr(),
+      Deferrability: $5.constraintDeferrability(),
     }
   }
 | UNIQUE opt_without_index '(' index_params ')'
@@ -6214,6 +6477,7 @@ constraint_elem:
         PartitionBy: $8.partitionBy(),
         Predicate: $10.expr(),
       },
+      Deferrability: $9.constraintDeferrability(),
     }
   }
 | PRIMARY KEY '(' index_params ')' opt_hash_sharded opt_interleave
@@ -6237,6 +6501,7 @@ constraint_elem:
       ToCols: $8.nameList(),
       Match: $9.compositeKeyMatchMethod(),
       Actions: $10.referenceActions(),
+      Deferrability: $11.constraintDeferrability(),
     }
   }
 | EXCLUDE USING error
@@ -6346,13 +6611,33 @@ create_as_col_qualification_elem:
     $$.val = tree.PrimaryKeyConstraint{}
   }
 
+// INITIALLY DEFERRED implies DEFERRABLE, and INITIALLY IMMEDIATE alone is the
+// default.
 opt_deferrable:
-  /* EMPTY */ { /* no error */ }
-| DEFERRABLE { return unimplementedWithIssueDetail(sqllex, 31632, "deferrable") }
-| DEFERRABLE INITIALLY DEFERRED { return unimplementedWithIssueDetail(sqllex, 31632, "def initially deferred") }
-| DEFERRABLE INITIALLY IMMEDIATE { return unimplementedWithIssueDetail(sqllex, 31632, "def initially immediate") }
-| INITIALLY DEFERRED { return unimplementedWithIssueDetail(sqllex, 31632, "initially deferred") }
-| INITIALLY IMMEDIATE { return unimplementedWithIssueDetail(sqllex, 31632, "initially immediate") }
+  /* EMPTY */
+  {
+    $$.val = tree.ConstraintNotDeferrable
+  }
+| DEFERRABLE
+  {
+    $$.val = tree.ConstraintInitiallyImmediate
+  }
+| DEFERRABLE INITIALLY DEFERRED
+  {
+    $$.val = tree.ConstraintInitiallyDeferred
+  }
+| DEFERRABLE INITIALLY IMMEDIATE
+  {
+    $$.val = tree.ConstraintInitiallyImmediate
+  }
+| INITIALLY DEFERRED
+  {
+    $$.val = tree.ConstraintInitiallyDeferred
+  }
+| INITIALLY IMMEDIATE
+  {
+    $$.val = tree.ConstraintNotDeferrable
+  }
 
 storing:
   COVERING
@@ -6638,6 +6923,187 @@ role_or_group_or_user:
     $$.val = false
   }
 
+// %Help: CREATE FUNCTION - create a new function
+// %Category: DDL
+// %Text:
+// CREATE [OR REPLACE] FUNCTION <name> ( [ [<argname>] <argtype> [, ...] ] )
+//   RETURNS <rettype>
+//   LANGUAGE SQL
+//   [IMMUTABLE | STABLE | VOLATILE]
+//   AS '<select statement>'
+//
+// The options after RETURNS can be given in any order. The arguments can be
+// referenced in the function body by name or as $1, $2, etc.
+//
+// A function declared as RETURNS TRIGGER takes no arguments and can only be
+// executed by a trigger. Its body can also be an INSERT, UPSERT, UPDATE or
+// DELETE statement, and can reference the row that fired the trigger as
+// new.<colname> and old.<colname>.
+// %SeeAlso: DROP FUNCTION, CREATE TRIGGER
+create_func_stmt:
+  CREATE FUNCTION db_object_name '(' opt_func_arg_list ')' RETURNS typename create_func_opt_list
+  {
+    n := &tree.CreateFunction{
+      Name: $3.unresolvedObjectName(),
+      Args: $5.funcArgs(),
+    }
+    setCreateFunctionReturnType(n, $8.typeReference())
+    if ret := setCreateFunctionOptions(sqllex, n, $9.kvOptions()); ret != 0 {
+      return ret
+    }
+    $$.val = n
+  }
+| CREATE OR REPLACE FUNCTION db_object_name '(' opt_func_arg_list ')' RETURNS typename create_func_opt_list
+  {
+    n := &tree.CreateFunction{
+      Name: $5.unresolvedObjectName(),
+      Replace: true,
+      Args: $7.funcArgs(),
+    }
+    setCreateFunctionReturnType(n, $10.typeReference())
+    if ret := setCreateFunctionOptions(sqllex, n, $11.kvOptions()); ret != 0 {
+      return ret
+    }
+    $$.val = n
+  }
+| CREATE FUNCTION error            // SHOW HELP: CREATE FUNCTION
+| CREATE OR REPLACE FUNCTION error // SHOW HELP: CREATE FUNCTION
+
+opt_func_arg_list:
+  func_arg_list
+| /* EMPTY */
+  {
+    $$.val = tree.FuncArgs(nil)
+  }
+
+func_arg_list:
+  func_arg
+  {
+    $$.val = tree.FuncArgs{$1.funcArg()}
+  }
+| func_arg_list ',' func_arg
+  {
+    $$.val = append($1.funcArgs(), $3.funcArg())
+  }
+
+func_arg:
+  typename
+  {
+    $$.val = tree.FuncArg{Type: $1.typeReference()}
+  }
+| type_function_name typename
+  {
+    $$.val = tree.FuncArg{Name: tree.Name($1), Type: $2.typeReference()}
+  }
+
+create_func_opt_list:
+  create_func_opt_item
+  {
+    $$.val = []tree.KVOption{$1.kvOption()}
+  }
+| create_func_opt_list create_func_opt_item
+  {
+    $$.val = append($1.kvOptions(), $2.kvOption())
+  }
+
+create_func_opt_item:
+  LANGUAGE non_reserved_word_or_sconst
+  {
+    $$.val = tree.KVOption{Key: "language", Value: tree.NewDString($2)}
+  }
+| IMMUTABLE
+  {
+    $$.val = tree.KVOption{Key: "volatility", Value: tree.NewDString("immutable")}
+  }
+| STABLE
+  {
+    $$.val = tree.KVOption{Key: "volatility", Value: tree.NewDString("stable")}
+  }
+| VOLATILE
+  {
+    $$.val = tree.KVOption{Key: "volatility", Value: tree.NewDString("volatile")}
+  }
+| AS SCONST
+  {
+    $$.val = tree.KVOption{Key: "as", Value: tree.NewDString($2)}
+  }
+
+// %Help: CREATE TRIGGER - create a new trigger
+// %Category: DDL
+// %Text:
+// CREATE TRIGGER <name> { BEFORE | AFTER } <event> [OR ...]
+//   ON <tablename>
+//   FOR EACH ROW EXECUTE FUNCTION <funcname> ()
+//
+// Events:
+//   INSERT, UPDATE, DELETE
+//
+// The function must be declared as RETURNS TRIGGER.
+// %SeeAlso: DROP TRIGGER, CREATE FUNCTION
+create_trigger_stmt:
+  CREATE TRIGGER name trigger_action_time trigger_event_list ON table_name FOR EACH ROW EXECUTE function_or_procedure db_object_name '(' ')'
+  {
+    $$.val = &tree.CreateTrigger{
+      Name: tree.Name($3),
+      ActionTime: $4.triggerActionTime(),
+      Events: $5.triggerEvents(),
+      Table: $7.unresolvedObjectName().ToTableName(),
+      FuncName: $13.unresolvedObjectName(),
+    }
+  }
+| CREATE TRIGGER name trigger_action_time trigger_event_list ON table_name FOR EACH STATEMENT error
+  {
+    return unimplementedWithIssueDetail(sqllex, 28296, "create trigger for each statement")
+  }
+| CREATE TRIGGER error // SHOW HELP: CREATE TRIGGER
+
+trigger_action_time:
+  BEFORE
+  {
+    $$.val = tree.TriggerBefore
+  }
+| AFTER
+  {
+    $$.val = tree.TriggerAfter
+  }
+
+trigger_event_list:
+  trigger_event
+  {
+    $$.val = tree.TriggerEvents{$1.triggerEvent()}
+  }
+| trigger_event_list OR trigger_event
+  {
+    $$.val = append($1.triggerEvents(), $3.triggerEvent())
+  }
+
+trigger_event:
+  INSERT
+  {
+    $$.val = tree.TriggerInsert
+  }
+| UPDATE
+  {
+    $$.val = tree.TriggerUpdate
+  }
+| DELETE
+  {
+    $$.val = tree.TriggerDelete
+  }
+| TRUNCATE error
+  {
+    return unimplementedWithIssueDetail(sqllex, 28296, "create trigger truncate")
+  }
+| UPDATE OF error
+  {
+    return unimplementedWithIssueDetail(sqllex, 28296, "create trigger update of")
+  }
+
+// EXECUTE PROCEDURE is a deprecated synonym of EXECUTE FUNCTION in Postgres.
+function_or_procedure:
+  FUNCTION {}
+| PROCEDURE {}
+
 // %Help: CREATE VIEW - create a new view
 // %Category: DDL
 // %Text: CREATE [TEMPORARY | TEMP] [MATERIALIZED] VIEW [IF NOT EXISTS] <viewname> [( <colnames...> )] AS <source>
@@ -8215,7 +8681,7 @@ simple_select:
 //        { <expr> [[AS] <name>] | [ [<dbname>.] <tablename>. ] * } [, ...]
 //        [ FROM <source> ]
 //        [ WHERE <expr> ]
-//        [ GROUP BY <expr> [ , ... ] ]
+//        [ GROUP BY { <expr> | ROLLUP ( ... ) | CUBE ( ... ) | GROUPING SETS ( ... ) } [ , ... ] ]
 //        [ HAVING <expr> ]
 //        [ WINDOW <name> AS ( <definition> ) ]
 //        [ { UNION | INTERSECT | EXCEPT } [ ALL | DISTINCT ] <selectclause> ]
@@ -8651,9 +9117,18 @@ group_by_list:
 // rather than reducing the conflicting unreserved_keyword rule.
 group_by_item:
   a_expr { $$.val = $1.expr() }
-| ROLLUP '(' error { return unimplementedWithIssueDetail(sqllex, 46280, "rollup") }
-| CUBE '(' error { return unimplementedWithIssueDetail(sqllex, 46280, "cube") }
-| GROUPING SETS error { return unimplementedWithIssueDetail(sqllex, 46280, "grouping sets") }
+| ROLLUP '(' expr_list ')'
+  {
+    $$.val = &tree.GroupingSet{Type: tree.RollupGroupingSet, Exprs: $3.exprs()}
+  }
+| CUBE '(' expr_list ')'
+  {
+    $$.val = &tree.GroupingSet{Type: tree.CubeGroupingSet, Exprs: $3.exprs()}
+  }
+| GROUPING SETS '(' group_by_list ')'
+  {
+    $$.val = &tree.GroupingSet{Type: tree.ExplicitGroupingSets, Exprs: $4.exprs()}
+  }
 
 having_clause:
   HAVING a_expr
@@ -10495,7 +10970,11 @@ d_expr:
   {
     $$.val = $2.expr()
   }
-| GROUPING '(' expr_list ')' { return unimplemented(sqllex, "d_expr grouping") }
+| GROUPING '(' expr_list ')'
+  {
+    $$.val = &tree.FuncExpr{Func: tree.WrapFunction($1), Exprs: $3.exprs()}
+  }
+| GROUPING '(' error { return helpWithFunctionByName(sqllex, $1) }
 
 func_application:
   func_name '(' ')'
@@ -12030,6 +12509,7 @@ unreserved_keyword:
 | DOMAIN
 | DOUBLE
 | DROP
+| EACH
 | ENCODING
 | ENCRYPTION_PASSPHRASE
 | ENUM
@@ -12073,6 +12553,7 @@ unreserved_keyword:
 | HOUR
 | IDENTITY
 | IMMEDIATE
+| IMMUTABLE
 | IMPORT
 | INCLUDE
 | INCLUDING
@@ -12111,6 +12592,7 @@ unreserved_keyword:
 | LOW
 | MATCH
 | MATERIALIZED
+| MAX_BANDWIDTH
 | MAXVALUE
 | MERGE
 | METHOD
@@ -12182,6 +12664,7 @@ unreserved_keyword:
 | PRESERVE
 | PRIORITY
 | PRIVILEGES
+| PROCEDURE
 | PUBLIC
 | PUBLICATION
 | QUERIES
@@ -12207,6 +12690,7 @@ unreserved_keyword:
 | RESTRICT
 | RESUME
 | RETRY
+| RETURNS
 | REVISION_HISTORY
 | REVOKE
 | ROLE
@@ -12247,7 +12731,9 @@ unreserved_keyword:
 | SNAPSHOT
 | SPLIT
 | SQL
+| STABLE
 | START
+| STATEMENT
 | STATISTICS
 | STDIN
 | STORAGE
@@ -12294,6 +12780,7 @@ unreserved_keyword:
 | VARYING
 | VIEW
 | VIEWACTIVITY
+| VOLATILE
 | WITHIN
 | WITHOUT
 | WRITE
@@ -762,6 +762,9 @@ func populateTableConstraints(
 		consrc := tree.DNull
 		conbin := tree.DNull
 		condef := tree.DNull
+		deferrable, initiallyDeferred := con.Deferrability()
+		condeferrable := tree.MakeDBool(tree.DBool(deferrable))
+		condeferred := tree.MakeDBool(tree.DBool(initiallyDeferred))
 
 		// Determine constraint kind-specific fields.
 		var err error
@@ -864,8 +867,8 @@ func populateTableConstraints(
 			dNameOrNull(conName), // conname
 			namespaceOid,         // connamespace
 			contype,              // contype
-			tree.DBoolFalse,      // condeferrable
-			tree.DBoolFalse,      // condeferred
+			condeferrable,        // condeferrable
+			condeferred,          // condeferred
 			tree.MakeDBool(tree.DBool(!con.Unvalidated)), // convalidated
 			tblOid,         // conrelid
 			oidZero,        // contypid
@@ -89,6 +89,12 @@ type extendedEvalContext struct {
 	// SchemaChangeJobCache refers to schemaChangeJobsCache in extraTxnState.
 	SchemaChangeJobCache map[descpb.ID]*jobs.Job
 
+	// DeferredConstraints refers to deferredConstraints in extraTxnState. It is
+	// nil if the checks of deferrable constraints cannot be deferred (for
+	// example, in internal executors), in which case all constraints are
+	// checked immediately.
+	DeferredConstraints *deferredConstraints
+
 	schemaAccessors *schemaInterface
 
 	sqlStatsCollector *sqlStatsCollector
@@ -56,16 +56,19 @@ const (
 	Table ObjectType = "table"
 	// Type represents a type object.
 	Type ObjectType = "type"
+	// Function represents a user-defined function object.
+	Function ObjectType = "function"
 )
 
 // Predefined sets of privileges.
 var (
-	AllPrivileges     = List{ALL, CREATE, DROP, GRANT, SELECT, INSERT, DELETE, UPDATE, USAGE, ZONECONFIG}
-	ReadData          = List{GRANT, SELECT}
-	ReadWriteData     = List{GRANT, SELECT, INSERT, DELETE, UPDATE}
-	DBTablePrivileges = List{ALL, CREATE, DROP, GRANT, SELECT, INSERT, DELETE, UPDATE, ZONECONFIG}
-	SchemaPrivileges  = List{ALL, GRANT, CREATE, USAGE}
-	TypePrivileges    = List{ALL, GRANT, USAGE}
+	AllPrivileges      = List{ALL, CREATE, DROP, GRANT, SELECT, INSERT, DELETE, UPDATE, USAGE, ZONECONFIG}
+	ReadData           = List{GRANT, SELECT}
+	ReadWriteData      = List{GRANT, SELECT, INSERT, DELETE, UPDATE}
+	DBTablePrivileges  = List{ALL, CREATE, DROP, GRANT, SELECT, INSERT, DELETE, UPDATE, ZONECONFIG}
+	SchemaPrivileges   = List{ALL, GRANT, CREATE, USAGE}
+	TypePrivileges     = List{ALL, GRANT, USAGE}
+	FunctionPrivileges = List{ALL, GRANT, USAGE}
 )
 
 // Mask returns the bitmask for a given privilege.
@@ -216,6 +219,8 @@ func GetValidPrivilegesForObject(objectType ObjectType) List {
 		return SchemaPrivileges
 	case Type:
 		return TypePrivileges
+	case Function:
+		return FunctionPrivileges
 	case Any:
 		return AllPrivileges
 	default:
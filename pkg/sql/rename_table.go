@@ -89,6 +89,16 @@ func (p *planner) RenameTable(ctx context.Context, n *tree.RenameTable) (planNod
 			tableDesc.ParentID, tableDesc.DependedOnBy[0].ID, "rename",
 		)
 	}
+	// The same applies to the bodies of functions.
+	if len(tableDesc.DependedOnByFunctions) > 0 {
+		fnDesc, err := p.Descriptors().GetMutableFunctionVersionByID(
+			ctx, p.txn, tableDesc.DependedOnByFunctions[0],
+		)
+		if err != nil {
+			return nil, err
+		}
+		return nil, p.dependentFunctionError(ctx, tableDesc.TypeName(), oldTn.String(), fnDesc, "rename")
+	}
 
 	return &renameTableNode{n: n, oldTn: &oldTn, newTn: &newTn, tableDesc: tableDesc}, nil
 }
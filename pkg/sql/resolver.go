@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descs"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/funcdesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
@@ -723,6 +724,8 @@ func newInternalLookupCtxFromDescriptors(
 			descriptors[i] = typedesc.NewImmutable(*t.Type)
 		case *descpb.Descriptor_Schema:
 			descriptors[i] = schemadesc.NewImmutable(*t.Schema)
+		case *descpb.Descriptor_Function:
+			descriptors[i] = funcdesc.NewImmutable(*t.Function)
 		}
 	}
 	lCtx := newInternalLookupCtx(ctx, descriptors, prefix, nil /* fallback */)
@@ -932,6 +935,22 @@ func (p *planner) ResolveMutableTypeDescriptor(
 	return desc, nil
 }
 
+// ResolveMutableFunctionDescriptor resolves a function descriptor for mutable
+// access. It returns the resolved name of the function along with it.
+func (p *planner) ResolveMutableFunctionDescriptor(
+	ctx context.Context, name *tree.UnresolvedObjectName, required bool,
+) (*tree.FunctionName, *funcdesc.Mutable, error) {
+	fn, desc, err := resolver.ResolveMutableFunction(ctx, p, name, required)
+	if err != nil || desc == nil {
+		return nil, nil, err
+	}
+	// Ensure that the user can access the target schema.
+	if err := p.canResolveDescUnderSchema(ctx, desc.GetParentSchemaID(), desc); err != nil {
+		return nil, nil, err
+	}
+	return fn, desc, nil
+}
+
 // The versions below are part of the work for #34240.
 // TODO(radu): clean these up when everything is switched over.
 
@@ -55,6 +55,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondatapb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlerrors"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlliveness"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
@@ -4502,6 +4503,29 @@ may increase either contention or retry errors, or both.`,
 			Volatility: tree.VolatilityImmutable,
 		},
 	),
+
+	// grouping is only meaningful in a query with GROUP BY, where it is
+	// replaced by the optimizer; see optbuilder.buildGroupingFunc.
+	"grouping": makeBuiltin(
+		tree.FunctionProperties{
+			NullableArgs: true,
+		},
+		tree.Overload{
+			Types: tree.VariadicType{
+				VarType: types.Any,
+			},
+			ReturnType: tree.FixedReturnType(types.Int),
+			Fn: func(ctx *tree.EvalContext, args tree.Datums) (tree.Datum, error) {
+				return nil, sqlerrors.NewInvalidGroupingArgsError()
+			},
+			Info: "Returns a bit mask indicating which of the arguments are not included " +
+				"in the current grouping set. Bits are assigned with the rightmost " +
+				"argument being the least-significant bit; each bit is 0 if the " +
+				"corresponding expression is included in the grouping set, and 1 " +
+				"if it is not.",
+			Volatility: tree.VolatilityImmutable,
+		},
+	),
 }
 
 var lengthImpls = func(incBitOverload bool) builtinDefinition {
@@ -40,6 +40,8 @@ type BackupOptions struct {
 	EncryptionPassphrase   Expr
 	Detached               bool
 	EncryptionKMSURI       StringOrPlaceholderOptList
+	Compact                bool
+	MaxBandwidth           Expr
 }
 
 var _ NodeFormatter = &BackupOptions{}
@@ -114,6 +116,8 @@ type RestoreOptions struct {
 	SkipMissingSequenceOwners bool
 	SkipMissingViews          bool
 	Detached                  bool
+	Where                     Expr
+	MaxBandwidth              Expr
 }
 
 var _ NodeFormatter = &RestoreOptions{}
@@ -226,6 +230,17 @@ func (o *BackupOptions) Format(ctx *FmtCtx) {
 		ctx.WriteString("kms=")
 		o.EncryptionKMSURI.Format(ctx)
 	}
+
+	if o.Compact {
+		maybeAddSep()
+		ctx.WriteString("compact")
+	}
+
+	if o.MaxBandwidth != nil {
+		maybeAddSep()
+		ctx.WriteString("max_bandwidth=")
+		o.MaxBandwidth.Format(ctx)
+	}
 }
 
 // CombineWith merges other backup options into this backup options struct.
@@ -259,6 +274,20 @@ func (o *BackupOptions) CombineWith(other *BackupOptions) error {
 		return errors.New("kms specified multiple times")
 	}
 
+	if o.Compact {
+		if other.Compact {
+			return errors.New("compact option specified multiple times")
+		}
+	} else {
+		o.Compact = other.Compact
+	}
+
+	if o.MaxBandwidth == nil {
+		o.MaxBandwidth = other.MaxBandwidth
+	} else if other.MaxBandwidth != nil {
+		return errors.New("max_bandwidth specified multiple times")
+	}
+
 	return nil
 }
 
@@ -267,7 +296,9 @@ func (o BackupOptions) IsDefault() bool {
 	options := BackupOptions{}
 	return o.CaptureRevisionHistory == options.CaptureRevisionHistory &&
 		o.Detached == options.Detached && cmp.Equal(o.EncryptionKMSURI, options.EncryptionKMSURI) &&
-		o.EncryptionPassphrase == options.EncryptionPassphrase
+		o.EncryptionPassphrase == options.EncryptionPassphrase &&
+		o.Compact == options.Compact &&
+		o.MaxBandwidth == options.MaxBandwidth
 }
 
 // Format implements the NodeFormatter interface.
@@ -321,6 +352,18 @@ func (o *RestoreOptions) Format(ctx *FmtCtx) {
 		maybeAddSep()
 		ctx.WriteString("detached")
 	}
+
+	if o.Where != nil {
+		maybeAddSep()
+		ctx.WriteString("where=")
+		o.Where.Format(ctx)
+	}
+
+	if o.MaxBandwidth != nil {
+		maybeAddSep()
+		ctx.WriteString("max_bandwidth=")
+		o.MaxBandwidth.Format(ctx)
+	}
 }
 
 // CombineWith merges other backup options into this backup options struct.
@@ -384,6 +427,18 @@ func (o *RestoreOptions) CombineWith(other *RestoreOptions) error {
 		o.Detached = other.Detached
 	}
 
+	if o.Where == nil {
+		o.Where = other.Where
+	} else if other.Where != nil {
+		return errors.New("where specified multiple times")
+	}
+
+	if o.MaxBandwidth == nil {
+		o.MaxBandwidth = other.MaxBandwidth
+	} else if other.MaxBandwidth != nil {
+		return errors.New("max_bandwidth specified multiple times")
+	}
+
 	return nil
 }
 
@@ -397,5 +452,7 @@ func (o RestoreOptions) IsDefault() bool {
 		cmp.Equal(o.DecryptionKMSURI, options.DecryptionKMSURI) &&
 		o.EncryptionPassphrase == options.EncryptionPassphrase &&
 		o.IntoDB == options.IntoDB &&
-		o.Detached == options.Detached
+		o.Detached == options.Detached &&
+		o.Where == options.Where &&
+		o.MaxBandwidth == options.MaxBandwidth
 }
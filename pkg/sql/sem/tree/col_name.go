@@ -11,6 +11,8 @@
 package tree
 
 import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/sql/types"
 )
@@ -67,6 +69,12 @@ func ComputeColNameInternal(sp sessiondata.SearchPath, target Expr) (int, string
 	case *FuncExpr:
 		fd, err := e.Func.Resolve(sp)
 		if err != nil {
+			// The function may be a user-defined function, which is resolved by
+			// the optimizer. Name the column after the unqualified function name.
+			if un, ok := e.Func.FunctionReference.(*UnresolvedName); ok &&
+				pgerror.GetPGCode(err) == pgcode.UndefinedFunction {
+				return 2, un.Parts[0], nil
+			}
 			return 0, "", err
 		}
 		return 2, fd.Name, nil
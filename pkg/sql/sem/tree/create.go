@@ -330,6 +330,175 @@ func (node *CreateType) String() string {
 	return AsString(node)
 }
 
+// FuncArg is an argument in the signature of a CREATE FUNCTION statement.
+type FuncArg struct {
+	// Name is empty if the argument can only be referenced positionally, as
+	// $1, $2, etc.
+	Name Name
+	Type ResolvableTypeReference
+}
+
+// Format implements the NodeFormatter interface.
+func (node *FuncArg) Format(ctx *FmtCtx) {
+	if node.Name != "" {
+		ctx.FormatNode(&node.Name)
+		ctx.WriteByte(' ')
+	}
+	ctx.FormatTypeReference(node.Type)
+}
+
+// FuncArgs is the list of arguments of a CREATE FUNCTION statement.
+type FuncArgs []FuncArg
+
+// Format implements the NodeFormatter interface.
+func (node *FuncArgs) Format(ctx *FmtCtx) {
+	for i := range *node {
+		if i > 0 {
+			ctx.WriteString(", ")
+		}
+		ctx.FormatNode(&(*node)[i])
+	}
+}
+
+// CreateFunction represents a CREATE FUNCTION statement. Only functions
+// written in SQL are supported.
+type CreateFunction struct {
+	Name       *UnresolvedObjectName
+	Replace    bool
+	Args       FuncArgs
+	ReturnType ResolvableTypeReference
+	// ReturnsTrigger is true if the function was declared as RETURNS TRIGGER,
+	// in which case ReturnType is nil. Such functions can only be executed by
+	// triggers.
+	ReturnsTrigger bool
+	// Volatility is the volatility marker of the function. It is
+	// VolatilityVolatile unless IMMUTABLE or STABLE was specified.
+	Volatility Volatility
+	// Body is the SELECT statement implementing the function. The body of a
+	// trigger function can also be an INSERT, UPSERT, UPDATE or DELETE
+	// statement.
+	Body string
+}
+
+var _ Statement = &CreateFunction{}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateFunction) Format(ctx *FmtCtx) {
+	ctx.WriteString("CREATE ")
+	if node.Replace {
+		ctx.WriteString("OR REPLACE ")
+	}
+	ctx.WriteString("FUNCTION ")
+	ctx.FormatNode(node.Name)
+	ctx.WriteByte('(')
+	ctx.FormatNode(&node.Args)
+	ctx.WriteString(") RETURNS ")
+	if node.ReturnsTrigger {
+		ctx.WriteString("TRIGGER")
+	} else {
+		ctx.FormatTypeReference(node.ReturnType)
+	}
+	ctx.WriteString(" LANGUAGE SQL ")
+	ctx.WriteString(strings.ToUpper(node.Volatility.String()))
+	ctx.WriteString(" AS ")
+	if ctx.flags.HasFlags(FmtAnonymize) {
+		ctx.WriteByte('_')
+	} else {
+		lex.EncodeSQLStringWithFlags(&ctx.Buffer, node.Body, ctx.flags.EncodeFlags())
+	}
+}
+
+// TriggerActionTime specifies whether a trigger fires before or after the
+// row it is fired for is modified.
+type TriggerActionTime int
+
+// TriggerActionTime values.
+const (
+	TriggerBefore TriggerActionTime = iota
+	TriggerAfter
+)
+
+var triggerActionTimeName = [...]string{
+	TriggerBefore: "BEFORE",
+	TriggerAfter:  "AFTER",
+}
+
+func (t TriggerActionTime) String() string {
+	return triggerActionTimeName[t]
+}
+
+// TriggerEvent is a kind of mutation which fires a trigger.
+type TriggerEvent int
+
+// TriggerEvent values.
+const (
+	TriggerInsert TriggerEvent = iota
+	TriggerUpdate
+	TriggerDelete
+)
+
+var triggerEventName = [...]string{
+	TriggerInsert: "INSERT",
+	TriggerUpdate: "UPDATE",
+	TriggerDelete: "DELETE",
+}
+
+func (e TriggerEvent) String() string {
+	return triggerEventName[e]
+}
+
+// TriggerEvents is a list of events which fire a trigger.
+type TriggerEvents []TriggerEvent
+
+// Format implements the NodeFormatter interface.
+func (node *TriggerEvents) Format(ctx *FmtCtx) {
+	for i, e := range *node {
+		if i > 0 {
+			ctx.WriteString(" OR ")
+		}
+		ctx.WriteString(e.String())
+	}
+}
+
+// Contains returns true if the list contains the given event.
+func (node TriggerEvents) Contains(e TriggerEvent) bool {
+	for _, ev := range node {
+		if ev == e {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTrigger represents a CREATE TRIGGER statement. Only row-level
+// triggers are supported.
+type CreateTrigger struct {
+	Name       Name
+	ActionTime TriggerActionTime
+	Events     TriggerEvents
+	Table      TableName
+	// FuncName is the name of the function executed by the trigger. It must
+	// be declared as RETURNS TRIGGER.
+	FuncName *UnresolvedObjectName
+}
+
+var _ Statement = &CreateTrigger{}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateTrigger) Format(ctx *FmtCtx) {
+	ctx.WriteString("CREATE TRIGGER ")
+	ctx.FormatNode(&node.Name)
+	ctx.WriteByte(' ')
+	ctx.WriteString(node.ActionTime.String())
+	ctx.WriteByte(' ')
+	ctx.FormatNode(&node.Events)
+	ctx.WriteString(" ON ")
+	ctx.FormatNode(&node.Table)
+	ctx.WriteString(" FOR EACH ROW EXECUTE FUNCTION ")
+	ctx.FormatNode(node.FuncName)
+	ctx.WriteString("()")
+}
+
 // TableDef represents a column, index or constraint definition within a CREATE
 // TABLE statement.
 type TableDef interface {
@@ -832,6 +1001,8 @@ type UniqueConstraintTableDef struct {
 	IndexTableDef
 	PrimaryKey   bool
 	WithoutIndex bool
+
+	Deferrability ConstraintDeferrability
 }
 
 // SetName implements the TableDef interface.
@@ -871,6 +1042,10 @@ func (node *UniqueConstraintTableDef) Format(ctx *FmtCtx) {
 	if node.PartitionBy != nil {
 		ctx.FormatNode(node.PartitionBy)
 	}
+	if node.Deferrability != ConstraintNotDeferrable {
+		ctx.WriteByte(' ')
+		ctx.WriteString(node.Deferrability.String())
+	}
 	if node.Predicate != nil {
 		ctx.WriteString(" WHERE ")
 		ctx.FormatNode(node.Predicate)
@@ -944,6 +1119,34 @@ func (c CompositeKeyMatchMethod) String() string {
 	return compositeKeyMatchMethodName[c]
 }
 
+// ConstraintDeferrability specifies whether the checking of a constraint can
+// be deferred until the end of the transaction, and whether it is deferred by
+// default. See SET CONSTRAINTS.
+type ConstraintDeferrability int
+
+// The values for ConstraintDeferrability.
+const (
+	// ConstraintNotDeferrable constraints are checked at the end of each
+	// statement.
+	ConstraintNotDeferrable ConstraintDeferrability = iota
+	// ConstraintInitiallyImmediate constraints are checked at the end of each
+	// statement, unless SET CONSTRAINTS defers them.
+	ConstraintInitiallyImmediate
+	// ConstraintInitiallyDeferred constraints are checked when the transaction
+	// commits, unless SET CONSTRAINTS makes them immediate.
+	ConstraintInitiallyDeferred
+)
+
+var constraintDeferrabilityName = [...]string{
+	ConstraintNotDeferrable:      "NOT DEFERRABLE",
+	ConstraintInitiallyImmediate: "DEFERRABLE INITIALLY IMMEDIATE",
+	ConstraintInitiallyDeferred:  "DEFERRABLE INITIALLY DEFERRED",
+}
+
+func (c ConstraintDeferrability) String() string {
+	return constraintDeferrabilityName[c]
+}
+
 // ForeignKeyConstraintTableDef represents a FOREIGN KEY constraint in the AST.
 type ForeignKeyConstraintTableDef struct {
 	Name     Name
@@ -952,6 +1155,8 @@ type ForeignKeyConstraintTableDef struct {
 	ToCols   NameList
 	Actions  ReferenceActions
 	Match    CompositeKeyMatchMethod
+
+	Deferrability ConstraintDeferrability
 }
 
 // Format implements the NodeFormatter interface.
@@ -979,6 +1184,11 @@ func (node *ForeignKeyConstraintTableDef) Format(ctx *FmtCtx) {
 	}
 
 	ctx.FormatNode(&node.Actions)
+
+	if node.Deferrability != ConstraintNotDeferrable {
+		ctx.WriteByte(' ')
+		ctx.WriteString(node.Deferrability.String())
+	}
 }
 
 // SetName implements the ConstraintTableDef interface.
@@ -992,6 +1202,8 @@ type CheckConstraintTableDef struct {
 	Name   Name
 	Expr   Expr
 	Hidden bool
+
+	Deferrability ConstraintDeferrability
 }
 
 // SetName implements the ConstraintTableDef interface.
@@ -1009,6 +1221,10 @@ func (node *CheckConstraintTableDef) Format(ctx *FmtCtx) {
 	ctx.WriteString("CHECK (")
 	ctx.FormatNode(node.Expr)
 	ctx.WriteByte(')')
+	if node.Deferrability != ConstraintNotDeferrable {
+		ctx.WriteByte(' ')
+		ctx.WriteString(node.Deferrability.String())
+	}
 }
 
 // FamilyTableDef represents a family definition within a CREATE TABLE
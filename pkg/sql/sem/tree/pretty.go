@@ -1132,10 +1132,15 @@ func (node *Update) doc(p *PrettyCfg) pretty.Doc {
 }
 
 func (node *Delete) doc(p *PrettyCfg) pretty.Doc {
-	items := make([]pretty.TableRow, 6)
+	items := make([]pretty.TableRow, 7)
 	items = append(items,
 		node.With.docRow(p),
-		p.row("DELETE FROM", p.Doc(node.Table)),
+		p.row("DELETE FROM", p.Doc(node.Table)))
+	if len(node.Using) > 0 {
+		items = append(items,
+			p.row("USING", p.Doc(&node.Using)))
+	}
+	items = append(items,
 		node.Where.docRow(p),
 		node.OrderBy.docRow(p))
 	items = append(items, node.Limit.docTable(p)...)
@@ -1686,6 +1691,7 @@ func (node *UniqueConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 	//    [STORING ( ... )]
 	//    [INTERLEAVE ...]
 	//    [PARTITION BY ...]
+	//    [DEFERRABLE ...]
 	//    [WHERE ...]
 	//
 	// or (no constraint name):
@@ -1694,9 +1700,10 @@ func (node *UniqueConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 	//    [STORING ( ... )]
 	//    [INTERLEAVE ...]
 	//    [PARTITION BY ...]
+	//    [DEFERRABLE ...]
 	//    [WHERE ...]
 	//
-	clauses := make([]pretty.Doc, 0, 5)
+	clauses := make([]pretty.Doc, 0, 6)
 	var title pretty.Doc
 	if node.PrimaryKey {
 		title = pretty.Keyword("PRIMARY KEY")
@@ -1726,6 +1733,9 @@ func (node *UniqueConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 	if node.PartitionBy != nil {
 		clauses = append(clauses, p.Doc(node.PartitionBy))
 	}
+	if node.Deferrability != ConstraintNotDeferrable {
+		clauses = append(clauses, pretty.Keyword(node.Deferrability.String()))
+	}
 	if node.Predicate != nil {
 		clauses = append(clauses, p.nestUnder(pretty.Keyword("WHERE"), p.Doc(node.Predicate)))
 	}
@@ -1743,6 +1753,7 @@ func (node *ForeignKeyConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 	//    REFERENCES tbl (...)
 	//    [MATCH ...]
 	//    [ACTIONS ...]
+	//    [DEFERRABLE ...]
 	//
 	// or (no constraint name):
 	//
@@ -1750,8 +1761,9 @@ func (node *ForeignKeyConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 	//    REFERENCES tbl [(...)]
 	//    [MATCH ...]
 	//    [ACTIONS ...]
+	//    [DEFERRABLE ...]
 	//
-	clauses := make([]pretty.Doc, 0, 4)
+	clauses := make([]pretty.Doc, 0, 5)
 	title := pretty.ConcatSpace(
 		pretty.Keyword("FOREIGN KEY"),
 		p.bracket("(", p.Doc(&node.FromCols), ")"))
@@ -1776,6 +1788,10 @@ func (node *ForeignKeyConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 		clauses = append(clauses, actions)
 	}
 
+	if node.Deferrability != ConstraintNotDeferrable {
+		clauses = append(clauses, pretty.Keyword(node.Deferrability.String()))
+	}
+
 	return p.nestUnder(title, pretty.Group(pretty.Stack(clauses...)))
 }
 
@@ -1929,14 +1945,17 @@ func (node *CheckConstraintTableDef) doc(p *PrettyCfg) pretty.Doc {
 	// Final layout:
 	//
 	// CONSTRAINT name
-	//    CHECK (...)
+	//    CHECK (...) [DEFERRABLE ...]
 	//
 	// or (no constraint name):
 	//
-	// CHECK (...)
+	// CHECK (...) [DEFERRABLE ...]
 	//
 	d := pretty.ConcatSpace(pretty.Keyword("CHECK"),
 		p.bracket("(", p.Doc(node.Expr), ")"))
+	if node.Deferrability != ConstraintNotDeferrable {
+		d = pretty.ConcatSpace(d, pretty.Keyword(node.Deferrability.String()))
+	}
 
 	if node.Name != "" {
 		d = p.nestUnder(
@@ -2112,6 +2131,9 @@ func (node *Export) doc(p *PrettyCfg) pretty.Doc {
 	items := make([]pretty.TableRow, 0, 5)
 	items = append(items, p.row("EXPORT", pretty.Nil))
 	items = append(items, p.row("INTO "+node.FileFormat, p.Doc(node.File)))
+	if node.PartitionBy != nil {
+		items = append(items, p.row("PARTITION BY", p.bracket("(", p.Doc(&node.PartitionBy), ")")))
+	}
 	if node.Options != nil {
 		items = append(items, p.row("WITH", p.Doc(&node.Options)))
 	}
@@ -570,6 +570,49 @@ func (node *GroupBy) Format(ctx *FmtCtx) {
 	}
 }
 
+// GroupingSetType represents the type of a GroupingSet.
+type GroupingSetType int8
+
+// GroupingSetType values.
+const (
+	// RollupGroupingSet is ROLLUP (<exprs>), which is equivalent to the
+	// grouping sets made of each prefix of the expressions.
+	RollupGroupingSet GroupingSetType = iota
+	// CubeGroupingSet is CUBE (<exprs>), which is equivalent to the grouping
+	// sets made of each subset of the expressions.
+	CubeGroupingSet
+	// ExplicitGroupingSets is GROUPING SETS (<items>).
+	ExplicitGroupingSets
+)
+
+var groupingSetTypeName = [...]string{
+	RollupGroupingSet:    "ROLLUP",
+	CubeGroupingSet:      "CUBE",
+	ExplicitGroupingSets: "GROUPING SETS",
+}
+
+func (t GroupingSetType) String() string {
+	return groupingSetTypeName[t]
+}
+
+// GroupingSet represents a ROLLUP, CUBE or GROUPING SETS item in a GROUP BY
+// clause. The elements of ROLLUP and CUBE are expressions, where a Tuple
+// stands for a group of expressions that are added to or removed from the
+// grouping sets together. The elements of GROUPING SETS are themselves GROUP
+// BY items; an empty Tuple stands for the empty grouping set.
+type GroupingSet struct {
+	Type  GroupingSetType
+	Exprs Exprs
+}
+
+// Format implements the NodeFormatter interface.
+func (node *GroupingSet) Format(ctx *FmtCtx) {
+	ctx.WriteString(node.Type.String())
+	ctx.WriteString(" (")
+	ctx.FormatNode(&node.Exprs)
+	ctx.WriteByte(')')
+}
+
 // DistinctOn represents a DISTINCT ON clause.
 type DistinctOn []Expr
 
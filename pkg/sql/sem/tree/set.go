@@ -74,6 +74,29 @@ func (node *SetTransaction) Format(ctx *FmtCtx) {
 	node.Modes.Format(ctx)
 }
 
+// SetConstraints represents a SET CONSTRAINTS statement.
+type SetConstraints struct {
+	// Names are the names of the constraints whose mode is set. If it is
+	// empty, the mode of ALL constraints is set.
+	Names    NameList
+	Deferred bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *SetConstraints) Format(ctx *FmtCtx) {
+	ctx.WriteString("SET CONSTRAINTS ")
+	if len(node.Names) == 0 {
+		ctx.WriteString("ALL")
+	} else {
+		ctx.FormatNode(&node.Names)
+	}
+	if node.Deferred {
+		ctx.WriteString(" DEFERRED")
+	} else {
+		ctx.WriteString(" IMMEDIATE")
+	}
+}
+
 // SetSessionAuthorizationDefault represents a SET SESSION AUTHORIZATION DEFAULT
 // statement. This can be extended (and renamed) if we ever support names in the
 // last position.
@@ -448,6 +448,22 @@ func (*CreateType) StatementTag() string { return "CREATE TYPE" }
 
 func (*CreateType) modifiesSchema() bool { return true }
 
+// StatementType implements the Statement interface.
+func (*CreateFunction) StatementType() StatementType { return DDL }
+
+// StatementTag implements the Statement interface.
+func (*CreateFunction) StatementTag() string { return "CREATE FUNCTION" }
+
+func (*CreateFunction) modifiesSchema() bool { return true }
+
+// StatementType implements the Statement interface.
+func (*CreateTrigger) StatementType() StatementType { return DDL }
+
+// StatementTag implements the Statement interface.
+func (*CreateTrigger) StatementTag() string { return "CREATE TRIGGER" }
+
+func (*CreateTrigger) modifiesSchema() bool { return true }
+
 // StatementType implements the Statement interface.
 func (*CreateRole) StatementType() StatementType { return Ack }
 
@@ -544,6 +560,18 @@ func (*DropType) StatementType() StatementType { return DDL }
 // StatementTag returns a short string identifying the type of statement.
 func (*DropType) StatementTag() string { return "DROP TYPE" }
 
+// StatementType implements the Statement interface.
+func (*DropFunction) StatementType() StatementType { return DDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*DropFunction) StatementTag() string { return "DROP FUNCTION" }
+
+// StatementType implements the Statement interface.
+func (*DropTrigger) StatementType() StatementType { return DDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*DropTrigger) StatementTag() string { return "DROP TRIGGER" }
+
 // StatementType implements the Statement interface.
 func (*DropSchema) StatementType() StatementType { return DDL }
 
@@ -762,6 +790,12 @@ func (*SetClusterSetting) StatementType() StatementType { return Ack }
 // StatementTag returns a short string identifying the type of statement.
 func (*SetClusterSetting) StatementTag() string { return "SET CLUSTER SETTING" }
 
+// StatementType implements the Statement interface.
+func (*SetConstraints) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*SetConstraints) StatementTag() string { return "SET CONSTRAINTS" }
+
 // StatementType implements the Statement interface.
 func (*SetTransaction) StatementType() StatementType { return Ack }
 
@@ -1113,21 +1147,25 @@ func (n *CopyFrom) String() string                       { return AsString(n) }
 func (n *CreateChangefeed) String() string               { return AsString(n) }
 func (n *CreateDatabase) String() string                 { return AsString(n) }
 func (n *CreateExtension) String() string                { return AsString(n) }
+func (n *CreateFunction) String() string                 { return AsString(n) }
 func (n *CreateIndex) String() string                    { return AsString(n) }
 func (n *CreateRole) String() string                     { return AsString(n) }
 func (n *CreateTable) String() string                    { return AsString(n) }
 func (n *CreateSchema) String() string                   { return AsString(n) }
 func (n *CreateSequence) String() string                 { return AsString(n) }
 func (n *CreateStats) String() string                    { return AsString(n) }
+func (n *CreateTrigger) String() string                  { return AsString(n) }
 func (n *CreateView) String() string                     { return AsString(n) }
 func (n *Deallocate) String() string                     { return AsString(n) }
 func (n *Delete) String() string                         { return AsString(n) }
 func (n *DropDatabase) String() string                   { return AsString(n) }
+func (n *DropFunction) String() string                   { return AsString(n) }
 func (n *DropIndex) String() string                      { return AsString(n) }
 func (n *DropOwnedBy) String() string                    { return AsString(n) }
 func (n *DropSchema) String() string                     { return AsString(n) }
 func (n *DropSequence) String() string                   { return AsString(n) }
 func (n *DropTable) String() string                      { return AsString(n) }
+func (n *DropTrigger) String() string                    { return AsString(n) }
 func (n *DropType) String() string                       { return AsString(n) }
 func (n *DropView) String() string                       { return AsString(n) }
 func (n *DropRole) String() string                       { return AsString(n) }
@@ -1162,6 +1200,7 @@ func (n *Scrub) String() string                          { return AsString(n) }
 func (n *Select) String() string                         { return AsString(n) }
 func (n *SelectClause) String() string                   { return AsString(n) }
 func (n *SetClusterSetting) String() string              { return AsString(n) }
+func (n *SetConstraints) String() string                 { return AsString(n) }
 func (n *SetZoneConfig) String() string                  { return AsString(n) }
 func (n *SetSessionAuthorizationDefault) String() string { return AsString(n) }
 func (n *SetSessionCharacteristics) String() string      { return AsString(n) }
@@ -755,6 +755,7 @@ var (
 	errInvalidDefaultUsage = pgerror.New(pgcode.Syntax, "DEFAULT can only appear in a VALUES list within INSERT or on the right side of a SET")
 	errInvalidMaxUsage     = pgerror.New(pgcode.Syntax, "MAXVALUE can only appear within a range partition expression")
 	errInvalidMinUsage     = pgerror.New(pgcode.Syntax, "MINVALUE can only appear within a range partition expression")
+	errInvalidGroupingSet  = pgerror.New(pgcode.Syntax, "ROLLUP, CUBE and GROUPING SETS can only appear in GROUP BY")
 	errPrivateFunction     = pgerror.New(pgcode.ReservedName, "function reserved for internal use")
 )
 
@@ -1370,6 +1371,13 @@ func (expr DefaultVal) TypeCheck(
 	return nil, errInvalidDefaultUsage
 }
 
+// TypeCheck implements the Expr interface.
+func (expr *GroupingSet) TypeCheck(
+	_ context.Context, _ *SemaContext, desired *types.T,
+) (TypedExpr, error) {
+	return nil, errInvalidGroupingSet
+}
+
 // TypeCheck implements the Expr interface.
 func (expr PartitionMinVal) TypeCheck(
 	_ context.Context, _ *SemaContext, desired *types.T,
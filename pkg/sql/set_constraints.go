@@ -0,0 +1,112 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+)
+
+type setConstraintsNode struct {
+	n *tree.SetConstraints
+}
+
+// SetConstraints sets the checking mode of deferrable constraints in the
+// current transaction. The named constraints must exist on a table of the
+// current database, and must be deferrable.
+// Privileges: None.
+func (p *planner) SetConstraints(ctx context.Context, n *tree.SetConstraints) (planNode, error) {
+	if err := p.checkDeferrableConstraintNames(ctx, n.Names); err != nil {
+		return nil, err
+	}
+	return &setConstraintsNode{n: n}, nil
+}
+
+// checkDeferrableConstraintNames verifies that each of the given names refers
+// to a deferrable constraint of a table in the current database.
+func (p *planner) checkDeferrableConstraintNames(ctx context.Context, names tree.NameList) error {
+	if len(names) == 0 {
+		return nil
+	}
+	var db catalog.DatabaseDescriptor
+	if dbName := p.CurrentDatabase(); dbName != "" {
+		var err error
+		db, err = p.Descriptors().GetDatabaseByName(ctx, p.txn, dbName, tree.DatabaseLookupFlags{})
+		if err != nil {
+			return err
+		}
+	}
+	all, err := p.Descriptors().GetAllDescriptors(ctx, p.txn, false /* validate */)
+	if err != nil {
+		return err
+	}
+	// deferrable maps the names of the constraints of the current database to
+	// whether any constraint with that name is deferrable.
+	deferrable := make(map[string]bool)
+	for _, desc := range all {
+		table, ok := desc.(catalog.TableDescriptor)
+		if !ok || table.Dropped() || (db != nil && table.GetParentID() != db.GetID()) {
+			continue
+		}
+		info, err := table.GetConstraintInfoWithLookup(nil /* tableLookup */)
+		if err != nil {
+			return err
+		}
+		for name, detail := range info {
+			d, _ := detail.Deferrability()
+			deferrable[name] = deferrable[name] || d
+		}
+	}
+	for _, name := range names {
+		d, ok := deferrable[string(name)]
+		if !ok {
+			return pgerror.Newf(pgcode.UndefinedObject, "constraint %q does not exist", name)
+		}
+		if !d {
+			return pgerror.Newf(pgcode.WrongObjectType, "constraint %q is not deferrable", name)
+		}
+	}
+	return nil
+}
+
+// ReadingOwnWrites implements the planNodeReadingOwnWrites interface.
+// This is because SET CONSTRAINTS ... IMMEDIATE runs queries which must see the
+// writes of the transaction.
+func (n *setConstraintsNode) ReadingOwnWrites() {}
+
+func (n *setConstraintsNode) startExec(params runParams) error {
+	dc := params.extendedEvalCtx.DeferredConstraints
+	if dc == nil {
+		// All constraints are checked immediately.
+		return nil
+	}
+	dc.setMode(n.n.Names, n.n.Deferred)
+	if n.n.Deferred {
+		return nil
+	}
+	// The pending violations of the constraints which are now immediate are
+	// checked at the end of the statement.
+	return dc.check(
+		params.ctx,
+		params.EvalContext().InternalExecutor.(*InternalExecutor),
+		params.p.txn,
+		params.p.Descriptors(),
+		n.n.Names,
+	)
+}
+
+func (*setConstraintsNode) Next(runParams) (bool, error) { return false, nil }
+func (*setConstraintsNode) Values() tree.Datums          { return tree.Datums{} }
+func (*setConstraintsNode) Close(context.Context)        {}
@@ -214,6 +214,13 @@ func showForeignKeyConstraint(
 		buf.WriteString(" ON UPDATE ")
 		buf.WriteString(fk.OnUpdate.String())
 	}
+	if fk.Deferrable {
+		if fk.InitiallyDeferred {
+			buf.WriteString(" DEFERRABLE INITIALLY DEFERRED")
+		} else {
+			buf.WriteString(" DEFERRABLE INITIALLY IMMEDIATE")
+		}
+	}
 	if fk.Validity != descpb.ConstraintValidity_Validated {
 		buf.WriteString(" NOT VALID")
 	}
@@ -427,6 +434,13 @@ func showConstraintClause(
 		}
 		f.WriteString(expr)
 		f.WriteString(")")
+		if e.Deferrable {
+			if e.InitiallyDeferred {
+				f.WriteString(" DEFERRABLE INITIALLY DEFERRED")
+			} else {
+				f.WriteString(" DEFERRABLE INITIALLY IMMEDIATE")
+			}
+		}
 		if e.Validity != descpb.ConstraintValidity_Validated {
 			f.WriteString(" NOT VALID")
 		}
@@ -106,6 +106,8 @@ func NewUndefinedObjectError(name tree.NodeFormatter, kind tree.DesiredObjectKin
 		return NewUndefinedRelationError(name)
 	case tree.TypeObject:
 		return NewUndefinedTypeError(name)
+	case tree.FunctionObject:
+		return NewUndefinedFunctionError(name)
 	default:
 		return errors.AssertionFailedf("unknown object kind %d", kind)
 	}
@@ -116,6 +118,12 @@ func NewUndefinedTypeError(name tree.NodeFormatter) error {
 	return pgerror.Newf(pgcode.UndefinedObject, "type %q does not exist", tree.ErrString(name))
 }
 
+// NewUndefinedFunctionError creates an error that represents a missing
+// user-defined function.
+func NewUndefinedFunctionError(name tree.NodeFormatter) error {
+	return pgerror.Newf(pgcode.UndefinedFunction, "function %q does not exist", tree.ErrString(name))
+}
+
 // NewUndefinedRelationError creates an error that represents a missing database table or view.
 func NewUndefinedRelationError(name tree.NodeFormatter) error {
 	return pgerror.Newf(pgcode.UndefinedTable,
@@ -147,6 +155,8 @@ func MakeObjectAlreadyExistsError(collidingObject *descpb.Descriptor, name strin
 		return NewRelationAlreadyExistsError(name)
 	case *descpb.Descriptor_Type:
 		return NewTypeAlreadyExistsError(name)
+	case *descpb.Descriptor_Function:
+		return NewFunctionAlreadyExistsError(name)
 	case *descpb.Descriptor_Database:
 		return NewDatabaseAlreadyExistsError(name)
 	case *descpb.Descriptor_Schema:
@@ -167,6 +177,11 @@ func NewTypeAlreadyExistsError(name string) error {
 	return pgerror.Newf(pgcode.DuplicateObject, "type %q already exists", name)
 }
 
+// NewFunctionAlreadyExistsError creates an error for a preexisting function.
+func NewFunctionAlreadyExistsError(name string) error {
+	return pgerror.Newf(pgcode.DuplicateFunction, "function %q already exists", name)
+}
+
 // IsRelationAlreadyExistsError checks whether this is an error for a preexisting relation.
 func IsRelationAlreadyExistsError(err error) bool {
 	return errHasCode(err, pgcode.DuplicateRelation)
@@ -210,6 +225,13 @@ func NewAggInAggError() error {
 	return pgerror.New(pgcode.Grouping, "aggregate function calls cannot be nested")
 }
 
+// NewInvalidGroupingArgsError creates an error for the case when an argument
+// of GROUPING is not a grouping expression of the enclosing query.
+func NewInvalidGroupingArgsError() error {
+	return pgerror.New(pgcode.Grouping,
+		"arguments to GROUPING must be grouping expressions of the associated query level")
+}
+
 // QueryTimeoutError is an error representing a query timeout.
 var QueryTimeoutError = pgerror.New(
 	pgcode.QueryCanceled, "query execution canceled due to statement timeout")
@@ -45,6 +45,10 @@ var ForeignKeyChecksUseCounter = telemetry.GetCounterOnce("sql.plan.fk.checks")
 // involves a cascade.
 var ForeignKeyCascadesUseCounter = telemetry.GetCounterOnce("sql.plan.fk.cascades")
 
+// TriggersUseCounter is to be incremented every time a mutation fires
+// row-level triggers and the triggers are planned by the optimizer.
+var TriggersUseCounter = telemetry.GetCounterOnce("sql.plan.triggers")
+
 // LateralJoinUseCounter is to be incremented whenever a query uses the
 // LATERAL keyword.
 var LateralJoinUseCounter = telemetry.GetCounterOnce("sql.plan.lateral-join")
@@ -291,6 +291,7 @@ func (u *updateNode) processSourceRow(params runParams, sourceVals tree.Datums)
 		checkVals := sourceVals[len(u.run.tu.ru.FetchCols)+len(u.run.tu.ru.UpdateCols)+u.run.numPassthrough:]
 		if err := checkMutationInput(
 			params.ctx, &params.p.semaCtx, u.run.tu.tableDesc(), u.run.checkOrds, checkVals,
+			params.extendedEvalCtx.DeferredConstraints,
 		); err != nil {
 			return err
 		}
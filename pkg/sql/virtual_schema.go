@@ -367,6 +367,9 @@ func (v *virtualSchemaEntry) GetObjectByName(
 			desc:    typedesc.MakeSimpleAlias(typ, catconstants.PgCatalogID),
 			mutable: flags.RequireMutable,
 		}, nil
+	case tree.FunctionObject:
+		// User-defined functions cannot be created in virtual schemas.
+		return nil, nil
 	default:
 		return nil, errors.AssertionFailedf("unknown desired object kind %d", flags.DesiredObjectKind)
 	}
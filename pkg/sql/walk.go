@@ -217,6 +217,7 @@ func (v *planVisitor) visitInternal(plan planNode, name string) {
 		}
 
 	case *createViewNode:
+	case *createFunctionNode:
 	case *setVarNode:
 	case *setClusterSettingNode:
 
@@ -354,11 +355,13 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&controlSchedulesNode{}):        "control schedules",
 	reflect.TypeOf(&createDatabaseNode{}):          "create database",
 	reflect.TypeOf(&createExtensionNode{}):         "create extension",
+	reflect.TypeOf(&createFunctionNode{}):          "create function",
 	reflect.TypeOf(&createIndexNode{}):             "create index",
 	reflect.TypeOf(&createSequenceNode{}):          "create sequence",
 	reflect.TypeOf(&createSchemaNode{}):            "create schema",
 	reflect.TypeOf(&createStatsNode{}):             "create statistics",
 	reflect.TypeOf(&createTableNode{}):             "create table",
+	reflect.TypeOf(&createTriggerNode{}):           "create trigger",
 	reflect.TypeOf(&createTypeNode{}):              "create type",
 	reflect.TypeOf(&CreateRoleNode{}):              "create user/role",
 	reflect.TypeOf(&createViewNode{}):              "create view",
@@ -367,10 +370,12 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&deleteRangeNode{}):             "delete range",
 	reflect.TypeOf(&distinctNode{}):                "distinct",
 	reflect.TypeOf(&dropDatabaseNode{}):            "drop database",
+	reflect.TypeOf(&dropFunctionNode{}):            "drop function",
 	reflect.TypeOf(&dropIndexNode{}):               "drop index",
 	reflect.TypeOf(&dropSequenceNode{}):            "drop sequence",
 	reflect.TypeOf(&dropSchemaNode{}):              "drop schema",
 	reflect.TypeOf(&dropTableNode{}):               "drop table",
+	reflect.TypeOf(&dropTriggerNode{}):             "drop trigger",
 	reflect.TypeOf(&dropTypeNode{}):                "drop type",
 	reflect.TypeOf(&DropRoleNode{}):                "drop user/role",
 	reflect.TypeOf(&dropViewNode{}):                "drop view",
@@ -416,6 +421,7 @@ var planNodeNames = map[reflect.Type]string{
 	reflect.TypeOf(&sequenceSelectNode{}):          "sequence select",
 	reflect.TypeOf(&serializeNode{}):               "run",
 	reflect.TypeOf(&setClusterSettingNode{}):       "set cluster setting",
+	reflect.TypeOf(&setConstraintsNode{}):          "set constraints",
 	reflect.TypeOf(&setVarNode{}):                  "set",
 	reflect.TypeOf(&setZoneConfigNode{}):           "configure zone",
 	reflect.TypeOf(&showFingerprintsNode{}):        "show fingerprints",
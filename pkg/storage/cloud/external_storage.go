@@ -55,6 +55,11 @@ type ExternalStorage interface {
 	// This can be leveraged for an existence check.
 	ReadFile(ctx context.Context, basename string) (io.ReadCloser, error)
 
+	// ReadFileAt is like ReadFile but returns a Reader which starts at the
+	// given byte offset of the requested file. It allows formats which need
+	// random access to a file to read only the parts of it that they need.
+	ReadFileAt(ctx context.Context, basename string, offset int64) (io.ReadCloser, error)
+
 	// WriteFile should write the content to requested name.
 	WriteFile(ctx context.Context, basename string, content io.ReadSeeker) error
 
@@ -0,0 +1,166 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cloudimpl
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/errors"
+)
+
+const azureKMSScheme = "azure-kms"
+
+type azureKMS struct {
+	client   keyvault.BaseClient
+	vaultURL string
+	keyName  string
+	// keyVersion is required, as data can only be decrypted with the version of
+	// the key with which it was encrypted.
+	keyVersion string
+	vaultName  string
+}
+
+var _ cloud.KMS = &azureKMS{}
+
+func init() {
+	cloud.RegisterKMSFromURIFactory(MakeAzureKMS, azureKMSScheme)
+}
+
+// MakeAzureKMS is the factory method which returns a configured, ready-to-use
+// Azure Key Vault KMS object. The URI is of the form
+// azure-kms:///<key name>/<key version>?AZURE_VAULT_NAME=<vault name>&...
+func MakeAzureKMS(uri string, env cloud.KMSEnv) (cloud.KMS, error) {
+	kmsURI, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	q := kmsURI.Query()
+
+	key := strings.Split(strings.TrimPrefix(kmsURI.Path, "/"), "/")
+	if len(key) != 2 || key[0] == "" || key[1] == "" {
+		return nil, errors.Errorf(
+			"azure kms uri must specify the key as %s:///<key name>/<key version>", azureKMSScheme)
+	}
+	vaultName := q.Get(AzureVaultNameParam)
+	if vaultName == "" {
+		return nil, errors.Errorf("azure kms uri missing %q parameter", AzureVaultNameParam)
+	}
+	vaultURL := fmt.Sprintf("https://%s.%s", vaultName, azure.PublicCloud.KeyVaultDNSSuffix)
+	aadEndpoint := azure.PublicCloud.ActiveDirectoryEndpoint
+	if endpoint, adEndpoint := q.Get(KMSEndpointParam), q.Get(AzureADEndpointParam); endpoint != "" ||
+		adEndpoint != "" {
+		if env.KMSConfig().DisableHTTP {
+			return nil, errors.New(
+				"custom endpoints disallowed for azure kms due to --external-io-disable-http flag")
+		}
+		if endpoint != "" {
+			vaultURL = endpoint
+		}
+		if adEndpoint != "" {
+			aadEndpoint = adEndpoint
+		}
+	}
+
+	// "specified": use the service principal given by the URI params; error if
+	//              not present.
+	// "implicit": use the credentials of the environment.
+	//             Detailed in https://docs.microsoft.com/en-us/azure/developer/go/azure-sdk-authorization
+	// "": default to `specified`.
+	resource := azure.PublicCloud.ResourceIdentifiers.KeyVault
+	var authorizer autorest.Authorizer
+	switch authParam := q.Get(AuthParam); authParam {
+	case "", AuthParamSpecified:
+		for _, param := range []string{AzureTenantIDParam, AzureClientIDParam, AzureClientSecretParam} {
+			if q.Get(param) == "" {
+				return nil, errors.Errorf(
+					"%s is set to '%s', but %s is not set",
+					AuthParam,
+					AuthParamSpecified,
+					param,
+				)
+			}
+		}
+		config := auth.NewClientCredentialsConfig(
+			q.Get(AzureClientIDParam), q.Get(AzureClientSecretParam), q.Get(AzureTenantIDParam))
+		config.AADEndpoint = aadEndpoint
+		config.Resource = resource
+		authorizer, err = config.Authorizer()
+	case AuthParamImplicit:
+		if env.KMSConfig().DisableImplicitCredentials {
+			return nil, errors.New(
+				"implicit credentials disallowed for azure kms due to --external-io-disable-implicit-credentials flag")
+		}
+		authorizer, err = auth.NewAuthorizerFromEnvironmentWithResource(resource)
+	default:
+		return nil, errors.Errorf("unsupported value %s for %s", authParam, AuthParam)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "azure kms authorizer")
+	}
+
+	client := keyvault.New()
+	client.Authorizer = authorizer
+	return &azureKMS{
+		client:     client,
+		vaultURL:   vaultURL,
+		keyName:    key[0],
+		keyVersion: key[1],
+		vaultName:  vaultName,
+	}, nil
+}
+
+// MasterKeyID implements the KMS interface.
+func (k *azureKMS) MasterKeyID() (string, error) {
+	return fmt.Sprintf("%s/%s/%s", k.vaultName, k.keyName, k.keyVersion), nil
+}
+
+// Encrypt implements the KMS interface.
+func (k *azureKMS) Encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	value := base64.RawURLEncoding.EncodeToString(data)
+	result, err := k.client.Encrypt(ctx, k.vaultURL, k.keyName, k.keyVersion,
+		keyvault.KeyOperationsParameters{Algorithm: keyvault.RSAOAEP256, Value: &value})
+	if err != nil {
+		return nil, err
+	}
+	return decodeAzureKeyOperationResult(result)
+}
+
+// Decrypt implements the KMS interface.
+func (k *azureKMS) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	value := base64.RawURLEncoding.EncodeToString(data)
+	result, err := k.client.Decrypt(ctx, k.vaultURL, k.keyName, k.keyVersion,
+		keyvault.KeyOperationsParameters{Algorithm: keyvault.RSAOAEP256, Value: &value})
+	if err != nil {
+		return nil, err
+	}
+	return decodeAzureKeyOperationResult(result)
+}
+
+func decodeAzureKeyOperationResult(result keyvault.KeyOperationResult) ([]byte, error) {
+	if result.Result == nil {
+		return nil, errors.New("azure kms returned no result")
+	}
+	return base64.RawURLEncoding.DecodeString(*result.Result)
+}
+
+// Close implements the KMS interface.
+func (k *azureKMS) Close() error {
+	return nil
+}
@@ -108,9 +108,15 @@ func (s *azureStorage) WriteFile(
 }
 
 func (s *azureStorage) ReadFile(ctx context.Context, basename string) (io.ReadCloser, error) {
+	return s.ReadFileAt(ctx, basename, 0)
+}
+
+func (s *azureStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
 	// https://github.com/cockroachdb/cockroach/issues/23859
 	blob := s.getBlob(basename)
-	get, err := blob.Download(ctx, 0, 0, azblob.BlobAccessConditions{}, false)
+	get, err := blob.Download(ctx, offset, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
 	if err != nil {
 		if azerr := (azblob.StorageError)(nil); errors.As(err, &azerr) {
 			switch azerr.ServiceCode() {
@@ -0,0 +1,106 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cloudimpltests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl/kmstest"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecryptAzure(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	fake := kmstest.NewAzureKMS()
+	defer fake.Close()
+
+	env := testKMSEnv{cluster.MakeTestingClusterSettings(), &base.ExternalIODirConfig{}}
+	testEncryptDecrypt(t, fake.URI("key", "1"), env)
+
+	ctx := context.Background()
+	kms, err := cloud.KMSFromURI(fake.URI("key", "1"), &env)
+	require.NoError(t, err)
+	id, err := kms.MasterKeyID()
+	require.NoError(t, err)
+	require.Equal(t, "fake-vault/key/1", id)
+	encrypted, err := kms.Encrypt(ctx, []byte("hello world"))
+	require.NoError(t, err)
+
+	// Data can only be decrypted with the version of the key which encrypted it.
+	other, err := cloud.KMSFromURI(fake.URI("key", "2"), &env)
+	require.NoError(t, err)
+	_, err = other.Decrypt(ctx, encrypted)
+	require.Error(t, err)
+
+	// The client secret is redacted from the URI.
+	redacted, err := cloudimpl.RedactKMSURI(fake.URI("key", "1"))
+	require.NoError(t, err)
+	require.NotContains(t, redacted, "fake-secret")
+}
+
+func TestAzureKMSURIErrors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	env := testKMSEnv{cluster.NoSettings, &base.ExternalIODirConfig{}}
+	vault := fmt.Sprintf("%s=vault", cloudimpl.AzureVaultNameParam)
+	for _, tc := range []struct {
+		name   string
+		uri    string
+		env    testKMSEnv
+		expErr string
+	}{
+		{
+			name:   "no-version",
+			uri:    fmt.Sprintf("azure-kms:///key?%s", vault),
+			env:    env,
+			expErr: "must specify the key as azure-kms:///<key name>/<key version>",
+		},
+		{
+			name:   "no-vault",
+			uri:    "azure-kms:///key/1",
+			env:    env,
+			expErr: fmt.Sprintf("missing %q parameter", cloudimpl.AzureVaultNameParam),
+		},
+		{
+			name:   "auth-specified-no-cred",
+			uri:    fmt.Sprintf("azure-kms:///key/1?%s&%s=tenant", vault, cloudimpl.AzureTenantIDParam),
+			env:    env,
+			expErr: fmt.Sprintf("%s is not set", cloudimpl.AzureClientIDParam),
+		},
+		{
+			name: "implicit-disallowed",
+			uri:  fmt.Sprintf("azure-kms:///key/1?%s&%s=%s", vault, cloudimpl.AuthParam, cloudimpl.AuthParamImplicit),
+			env: testKMSEnv{cluster.NoSettings,
+				&base.ExternalIODirConfig{DisableImplicitCredentials: true}},
+			expErr: "implicit credentials disallowed",
+		},
+		{
+			name:   "endpoint-disallowed",
+			uri:    fmt.Sprintf("azure-kms:///key/1?%s&%s=localhost", vault, cloudimpl.KMSEndpointParam),
+			env:    testKMSEnv{cluster.NoSettings, &base.ExternalIODirConfig{DisableHTTP: true}},
+			expErr: "custom endpoints disallowed",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cloud.KMSFromURI(tc.uri, &tc.env)
+			require.True(t, testutils.IsError(err, tc.expErr), "%v", err)
+		})
+	}
+}
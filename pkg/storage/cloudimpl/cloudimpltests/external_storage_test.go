@@ -188,6 +188,18 @@ func testExportStoreWithExternalIOConfig(
 		if !bytes.Equal(content, testingContent) {
 			t.Fatalf("wrong content")
 		}
+
+		// Reading from an offset returns the rest of the file.
+		for _, offset := range []int64{0, 1, size / 2, size - 1} {
+			res, err := s.ReadFileAt(ctx, testingFilename, offset)
+			require.NoError(t, err)
+			content, err := ioutil.ReadAll(res)
+			require.NoError(t, res.Close())
+			require.NoError(t, err)
+			if !bytes.Equal(content, testingContent[offset:]) {
+				t.Fatalf("wrong content at offset %d", offset)
+			}
+		}
 		require.NoError(t, s.Delete(ctx, testingFilename))
 	})
 	if skipSingleFile {
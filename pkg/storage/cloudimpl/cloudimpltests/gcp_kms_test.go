@@ -0,0 +1,99 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cloudimpltests
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl/kmstest"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+const gcpTestKeyID = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+
+func TestEncryptDecryptGCP(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	fake, err := kmstest.NewGCPKMS()
+	require.NoError(t, err)
+	defer fake.Close()
+
+	env := testKMSEnv{cluster.MakeTestingClusterSettings(), &base.ExternalIODirConfig{}}
+	testEncryptDecrypt(t, fake.URI(gcpTestKeyID), env)
+
+	ctx := context.Background()
+	kms, err := cloud.KMSFromURI(fake.URI(gcpTestKeyID), &env)
+	require.NoError(t, err)
+	id, err := kms.MasterKeyID()
+	require.NoError(t, err)
+	require.Equal(t, gcpTestKeyID, id)
+	encrypted, err := kms.Encrypt(ctx, []byte("hello world"))
+	require.NoError(t, err)
+
+	// Data can only be decrypted with the key which encrypted it.
+	other, err := cloud.KMSFromURI(fake.URI(gcpTestKeyID+"-other"), &env)
+	require.NoError(t, err)
+	_, err = other.Decrypt(ctx, encrypted)
+	require.Error(t, err)
+}
+
+func TestGCPKMSURIErrors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	env := testKMSEnv{cluster.NoSettings, &base.ExternalIODirConfig{}}
+	for _, tc := range []struct {
+		name   string
+		uri    string
+		env    testKMSEnv
+		expErr string
+	}{
+		{
+			name:   "no-key",
+			uri:    "gs:///?AUTH=implicit",
+			env:    env,
+			expErr: "must specify the resource ID of the key",
+		},
+		{
+			name:   "auth-specified-no-cred",
+			uri:    fmt.Sprintf("gs:///%s?%s=%s", gcpTestKeyID, cloudimpl.AuthParam, cloudimpl.AuthParamSpecified),
+			env:    env,
+			expErr: fmt.Sprintf("%s is not set", cloudimpl.CredentialsParam),
+		},
+		{
+			name: "implicit-disallowed",
+			uri:  fmt.Sprintf("gs:///%s?%s=%s", gcpTestKeyID, cloudimpl.AuthParam, cloudimpl.AuthParamImplicit),
+			env: testKMSEnv{cluster.NoSettings,
+				&base.ExternalIODirConfig{DisableImplicitCredentials: true}},
+			expErr: "implicit credentials disallowed",
+		},
+		{
+			name: "endpoint-disallowed",
+			uri: fmt.Sprintf("gs:///%s?%s=%s&%s=%s", gcpTestKeyID, cloudimpl.AuthParam, cloudimpl.AuthParamImplicit,
+				cloudimpl.KMSEndpointParam, url.QueryEscape("http://localhost:1234")),
+			env:    testKMSEnv{cluster.NoSettings, &base.ExternalIODirConfig{DisableHTTP: true}},
+			expErr: "custom endpoints disallowed",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cloud.KMSFromURI(tc.uri, &tc.env)
+			require.True(t, testutils.IsError(err, tc.expErr), "%v", err)
+		})
+	}
+}
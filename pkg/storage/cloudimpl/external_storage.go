@@ -13,6 +13,7 @@ package cloudimpl
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"path"
 	"strconv"
@@ -59,11 +60,30 @@ const (
 
 	// KMSRegionParam is the query parameter for the 'region' in every KMS URI.
 	KMSRegionParam = "REGION"
+	// KMSEndpointParam is the query parameter for a custom endpoint of the KMS
+	// service in a gs or azure-kms KMS URI.
+	KMSEndpointParam = "KMS_ENDPOINT"
 
 	// AzureAccountNameParam is the query parameter for account_name in an azure URI.
 	AzureAccountNameParam = "AZURE_ACCOUNT_NAME"
 	// AzureAccountKeyParam is the query parameter for account_key in an azure URI.
 	AzureAccountKeyParam = "AZURE_ACCOUNT_KEY"
+	// AzureVaultNameParam is the query parameter for the name of the key vault
+	// in an azure-kms URI.
+	AzureVaultNameParam = "AZURE_VAULT_NAME"
+	// AzureTenantIDParam is the query parameter for the tenant ID of the service
+	// principal in an azure-kms URI.
+	AzureTenantIDParam = "AZURE_TENANT_ID"
+	// AzureClientIDParam is the query parameter for the client ID of the service
+	// principal in an azure-kms URI.
+	AzureClientIDParam = "AZURE_CLIENT_ID"
+	// AzureClientSecretParam is the query parameter for the client secret of the
+	// service principal in an azure-kms URI.
+	AzureClientSecretParam = "AZURE_CLIENT_SECRET"
+	// AzureADEndpointParam is the query parameter for a custom Azure Active
+	// Directory endpoint, e.g. of an Azure Stack deployment, in an azure-kms
+	// URI.
+	AzureADEndpointParam = "AZURE_AD_ENDPOINT"
 
 	// GoogleBillingProjectParam is the query parameter for the billing project
 	// in a gs URI.
@@ -108,10 +128,11 @@ const (
 
 // See SanitizeExternalStorageURI.
 var redactedQueryParams = map[string]struct{}{
-	AWSSecretParam:       {},
-	AWSTempTokenParam:    {},
-	AzureAccountKeyParam: {},
-	CredentialsParam:     {},
+	AWSSecretParam:         {},
+	AWSTempTokenParam:      {},
+	AzureAccountKeyParam:   {},
+	AzureClientSecretParam: {},
+	CredentialsParam:       {},
 }
 
 // ErrListingUnsupported is a marker for indicating listing is unsupported.
@@ -476,6 +497,27 @@ func isResumableHTTPError(err error) bool {
 		sysutil.IsErrConnectionRefused(err)
 }
 
+// skipToOffset advances r to the given offset, for storage which has no native
+// support for reading from an offset. If r is seekable, it is seeked to the
+// offset. Otherwise the bytes before it are read and discarded. r is closed if
+// this fails.
+func skipToOffset(r io.ReadCloser, offset int64) (io.ReadCloser, error) {
+	if offset == 0 {
+		return r, nil
+	}
+	var err error
+	if s, ok := r.(io.Seeker); ok {
+		_, err = s.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(ioutil.Discard, r, offset)
+	}
+	if err != nil {
+		_ = r.Close()
+		return nil, errors.Wrapf(err, "seeking to offset %d", offset)
+	}
+	return r, nil
+}
+
 func getPrefixBeforeWildcard(p string) string {
 	globIndex := strings.IndexAny(p, "*?[")
 	if globIndex < 0 {
@@ -201,6 +201,19 @@ func (f *fileTableStorage) ReadFile(ctx context.Context, basename string) (io.Re
 	return reader, err
 }
 
+// ReadFileAt implements the ExternalStorage interface and returns the contents
+// of the file stored in the user scoped FileToTableSystem, starting at the
+// given offset.
+func (f *fileTableStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
+	reader, err := f.ReadFile(ctx, basename)
+	if err != nil {
+		return nil, err
+	}
+	return skipToOffset(reader, offset)
+}
+
 // WriteFile implements the ExternalStorage interface and writes the file to the
 // user scoped FileToTableSystem.
 func (f *fileTableStorage) WriteFile(
@@ -0,0 +1,121 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package cloudimpl
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/storage/cloud"
+	"github.com/cockroachdb/errors"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const gcpScheme = "gs"
+
+type gcpKMS struct {
+	kms *cloudkms.ProjectsLocationsKeyRingsCryptoKeysService
+	// customerMasterKeyID is the resource ID of the key, of the form
+	// projects/<project>/locations/<location>/keyRings/<key ring>/cryptoKeys/<key>.
+	customerMasterKeyID string
+}
+
+var _ cloud.KMS = &gcpKMS{}
+
+func init() {
+	cloud.RegisterKMSFromURIFactory(MakeGCPKMS, gcpScheme)
+}
+
+// MakeGCPKMS is the factory method which returns a configured, ready-to-use
+// GCP Cloud KMS object.
+func MakeGCPKMS(uri string, env cloud.KMSEnv) (cloud.KMS, error) {
+	kmsURI, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	keyID := strings.TrimPrefix(kmsURI.Path, "/")
+	if keyID == "" {
+		return nil, errors.New("gs kms uri must specify the resource ID of the key")
+	}
+
+	ctx := context.Background()
+	opts, err := googleCloudClientOptions(ctx, cloudkms.CloudPlatformScope,
+		kmsURI.Query().Get(AuthParam), kmsURI.Query().Get(CredentialsParam),
+		*env.KMSConfig(), env.ClusterSettings())
+	if err != nil {
+		return nil, err
+	}
+	if endpoint := kmsURI.Query().Get(KMSEndpointParam); endpoint != "" {
+		if env.KMSConfig().DisableHTTP {
+			return nil, errors.New(
+				"custom endpoints disallowed for gs kms due to --external-io-disable-http flag")
+		}
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	client, endpoint, err := htransport.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create google cloud kms client")
+	}
+	service, err := cloudkms.New(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create google cloud kms client")
+	}
+	if endpoint != "" {
+		service.BasePath = endpoint
+	}
+	return &gcpKMS{
+		kms:                 cloudkms.NewProjectsLocationsKeyRingsCryptoKeysService(service),
+		customerMasterKeyID: keyID,
+	}, nil
+}
+
+// MasterKeyID implements the KMS interface.
+func (k *gcpKMS) MasterKeyID() (string, error) {
+	return k.customerMasterKeyID, nil
+}
+
+// Encrypt implements the KMS interface.
+func (k *gcpKMS) Encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	encryptRequest := &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(data),
+	}
+
+	encryptResponse, err := k.kms.Encrypt(k.customerMasterKeyID, encryptRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(encryptResponse.Ciphertext)
+}
+
+// Decrypt implements the KMS interface.
+func (k *gcpKMS) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	decryptRequest := &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(data),
+	}
+
+	decryptResponse, err := k.kms.Decrypt(k.customerMasterKeyID, decryptRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(decryptResponse.Plaintext)
+}
+
+// Close implements the KMS interface.
+func (k *gcpKMS) Close() error {
+	return nil
+}
@@ -81,26 +81,59 @@ func makeGCSStorage(
 	if conf == nil {
 		return nil, errors.Errorf("google cloud storage upload requested but info missing")
 	}
-	const scope = gcs.ScopeReadWrite
+	opts, err := googleCloudClientOptions(ctx, gcs.ScopeReadWrite, conf.Auth, conf.Credentials,
+		ioConf, settings)
+	if err != nil {
+		return nil, err
+	}
+	g, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create google cloud client")
+	}
+	bucket := g.Bucket(conf.Bucket)
+	if conf.BillingProject != `` {
+		bucket = bucket.UserProject(conf.BillingProject)
+	}
+	return &gcsStorage{
+		bucket:   bucket,
+		client:   g,
+		conf:     conf,
+		ioConf:   ioConf,
+		prefix:   conf.Prefix,
+		settings: settings,
+	}, nil
+}
+
+// googleCloudClientOptions returns the options of a Google Cloud client with
+// the given scope, which authenticates as specified by the AUTH and
+// CREDENTIALS params of a URI.
+func googleCloudClientOptions(
+	ctx context.Context,
+	scope string,
+	auth string,
+	credentials string,
+	ioConf base.ExternalIODirConfig,
+	settings *cluster.Settings,
+) ([]option.ClientOption, error) {
 	opts := []option.ClientOption{option.WithScopes(scope)}
 
 	// "default": only use the key in the settings; error if not present.
 	// "specified": the JSON object for authentication is given by the CREDENTIALS param.
 	// "implicit": only use the environment data.
 	// "": if default key is in the settings use it; otherwise use environment data.
-	if ioConf.DisableImplicitCredentials && conf.Auth != AuthParamSpecified {
+	if ioConf.DisableImplicitCredentials && auth != AuthParamSpecified {
 		return nil, errors.New(
 			"implicit credentials disallowed for gs due to --external-io-disable-implicit-credentials flag")
 	}
 
-	switch conf.Auth {
+	switch auth {
 	case "", AuthParamDefault:
 		var key string
 		if settings != nil {
 			key = GcsDefault.Get(&settings.SV)
 		}
 		// We expect a key to be present if default is specified.
-		if conf.Auth == AuthParamDefault && key == "" {
+		if auth == AuthParamDefault && key == "" {
 			return nil, errors.Errorf("expected settings value for %s", CloudstorageGSDefaultKey)
 		}
 		if key != "" {
@@ -111,7 +144,7 @@ func makeGCSStorage(
 			opts = append(opts, option.WithTokenSource(source.TokenSource(ctx)))
 		}
 	case AuthParamSpecified:
-		if conf.Credentials == "" {
+		if credentials == "" {
 			return nil, errors.Errorf(
 				"%s is set to '%s', but %s is not set",
 				AuthParam,
@@ -119,7 +152,7 @@ func makeGCSStorage(
 				CredentialsParam,
 			)
 		}
-		decodedKey, err := base64.StdEncoding.DecodeString(conf.Credentials)
+		decodedKey, err := base64.StdEncoding.DecodeString(credentials)
 		if err != nil {
 			return nil, errors.Wrapf(err, "decoding value of %s", CredentialsParam)
 		}
@@ -132,24 +165,9 @@ func makeGCSStorage(
 		// Do nothing; use implicit params:
 		// https://godoc.org/golang.org/x/oauth2/google#FindDefaultCredentials
 	default:
-		return nil, errors.Errorf("unsupported value %s for %s", conf.Auth, AuthParam)
-	}
-	g, err := gcs.NewClient(ctx, opts...)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create google cloud client")
-	}
-	bucket := g.Bucket(conf.Bucket)
-	if conf.BillingProject != `` {
-		bucket = bucket.UserProject(conf.BillingProject)
+		return nil, errors.Errorf("unsupported value %s for %s", auth, AuthParam)
 	}
-	return &gcsStorage{
-		bucket:   bucket,
-		client:   g,
-		conf:     conf,
-		ioConf:   ioConf,
-		prefix:   conf.Prefix,
-		settings: settings,
-	}, nil
+	return opts, nil
 }
 
 func (g *gcsStorage) WriteFile(ctx context.Context, basename string, content io.ReadSeeker) error {
@@ -233,11 +251,18 @@ func (r *resumingGoogleStorageReader) Close() error {
 }
 
 func (g *gcsStorage) ReadFile(ctx context.Context, basename string) (io.ReadCloser, error) {
+	return g.ReadFileAt(ctx, basename, 0)
+}
+
+func (g *gcsStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
 	// https://github.com/cockroachdb/cockroach/issues/23859
 	reader := &resumingGoogleStorageReader{
 		ctx:    ctx,
 		bucket: g.bucket,
 		object: path.Join(g.prefix, basename),
+		pos:    offset,
 	}
 	if err := reader.openStream(); err != nil {
 		// The Google SDK has a specialized ErrBucketDoesNotExist error, but
@@ -137,7 +137,7 @@ func (h *httpStorage) Settings() *cluster.Settings {
 type resumingHTTPReader struct {
 	body      io.ReadCloser
 	canResume bool  // Can we resume if download aborts prematurely?
-	pos       int64 // How much data was received so far.
+	pos       int64 // Offset in the file of the next byte to receive.
 	ctx       context.Context
 	url       string
 	client    *httpStorage
@@ -146,20 +146,33 @@ type resumingHTTPReader struct {
 var _ io.ReadCloser = &resumingHTTPReader{}
 
 func newResumingHTTPReader(
-	ctx context.Context, client *httpStorage, url string,
+	ctx context.Context, client *httpStorage, url string, offset int64,
 ) (*resumingHTTPReader, error) {
 	r := &resumingHTTPReader{
 		ctx:    ctx,
 		client: client,
 		url:    url,
+		pos:    offset,
 	}
 
-	resp, err := r.sendRequest(nil)
+	var reqHeaders map[string]string
+	if offset != 0 {
+		reqHeaders = map[string]string{"Range": fmt.Sprintf("bytes=%d-", offset)}
+	}
+	resp, err := r.sendRequest(reqHeaders)
 	if err != nil {
 		return nil, err
 	}
 
 	r.canResume = resp.Header.Get("Accept-Ranges") == "bytes"
+	if offset != 0 {
+		if err := checkHTTPContentRangeHeader(resp.Header.Get("Content-Range"), offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		// The server honored the range request, so it can resume downloads.
+		r.canResume = true
+	}
 	r.body = resp.Body
 	return r, nil
 }
@@ -267,8 +280,14 @@ func (r *resumingHTTPReader) Read(p []byte) (n int, err error) {
 }
 
 func (h *httpStorage) ReadFile(ctx context.Context, basename string) (io.ReadCloser, error) {
+	return h.ReadFileAt(ctx, basename, 0)
+}
+
+func (h *httpStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
 	// https://github.com/cockroachdb/cockroach/issues/23859
-	return newResumingHTTPReader(ctx, h, basename)
+	return newResumingHTTPReader(ctx, h, basename, offset)
 }
 
 func (h *httpStorage) WriteFile(ctx context.Context, basename string, content io.ReadSeeker) error {
@@ -0,0 +1,303 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+// Package kmstest provides in-process fakes of the KMS services of GCP and
+// Azure, for use in tests of the gs and azure-kms KMS implementations.
+package kmstest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/storage/cloudimpl"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/errors"
+)
+
+// fakeAccessToken is the OAuth access token handed out by the fakes, which
+// they expect in the requests to their KMS APIs.
+const fakeAccessToken = "fake-access-token"
+
+// keyring encrypts data with AES-GCM keys generated on first use.
+type keyring struct {
+	mu   syncutil.Mutex
+	keys map[string]cipher.AEAD
+}
+
+func (k *keyring) aead(keyID string) (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if aead, ok := k.keys[keyID]; ok {
+		return aead, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if k.keys == nil {
+		k.keys = make(map[string]cipher.AEAD)
+	}
+	k.keys[keyID] = aead
+	return aead, nil
+}
+
+func (k *keyring) encrypt(keyID string, plaintext []byte) ([]byte, error) {
+	aead, err := k.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(keyID)), nil
+}
+
+func (k *keyring) decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	aead, err := k.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(keyID))
+}
+
+func authorized(r *http.Request) bool {
+	return r.Header.Get("Authorization") == "Bearer "+fakeAccessToken
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// GCPKMS is a fake of the REST API of GCP Cloud KMS, along with the OAuth
+// token endpoint of the service account whose credentials are in its URIs.
+type GCPKMS struct {
+	server      *httptest.Server
+	credentials string
+	keys        keyring
+}
+
+// NewGCPKMS starts a fake GCP Cloud KMS, which must be closed.
+func NewGCPKMS() (*GCPKMS, error) {
+	f := &GCPKMS{}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		f.server.Close()
+		return nil, err
+	}
+	credentials, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "fake-project",
+		"private_key_id": "fake-key",
+		"private_key": string(pem.EncodeToMemory(&pem.Block{
+			Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		})),
+		"client_email": "fake@fake-project.iam.gserviceaccount.com",
+		"token_uri":    f.server.URL + "/token",
+	})
+	if err != nil {
+		f.server.Close()
+		return nil, err
+	}
+	f.credentials = base64.StdEncoding.EncodeToString(credentials)
+	return f, nil
+}
+
+// URI returns a gs KMS URI for the key with the given resource ID, of the
+// form projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>.
+func (f *GCPKMS) URI(keyID string) string {
+	q := make(url.Values)
+	q.Set(cloudimpl.AuthParam, cloudimpl.AuthParamSpecified)
+	q.Set(cloudimpl.CredentialsParam, f.credentials)
+	q.Set(cloudimpl.KMSEndpointParam, f.server.URL+"/")
+	return fmt.Sprintf("gs:///%s?%s", keyID, q.Encode())
+}
+
+// Close shuts down the fake.
+func (f *GCPKMS) Close() {
+	f.server.Close()
+}
+
+func (f *GCPKMS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		writeJSON(w, map[string]interface{}{
+			"access_token": fakeAccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+		return
+	}
+	if !authorized(r) {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	// The requests are POST /v1/<key resource ID>:{encrypt,decrypt}.
+	name := strings.TrimPrefix(r.URL.Path, "/v1/")
+	var req struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch {
+	case strings.HasSuffix(name, ":encrypt"):
+		keyID := strings.TrimSuffix(name, ":encrypt")
+		plaintext, err := base64.StdEncoding.DecodeString(req.Plaintext)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ciphertext, err := f.keys.encrypt(keyID, plaintext)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]string{
+			"name":       keyID + "/cryptoKeyVersions/1",
+			"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+		})
+	case strings.HasSuffix(name, ":decrypt"):
+		keyID := strings.TrimSuffix(name, ":decrypt")
+		ciphertext, err := base64.StdEncoding.DecodeString(req.Ciphertext)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		plaintext, err := f.keys.decrypt(keyID, ciphertext)
+		if err != nil {
+			http.Error(w, "decryption failed", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]string{
+			"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// AzureKMS is a fake of the REST API of an Azure key vault, along with the
+// Azure Active Directory token endpoint of the service principal in its URIs.
+type AzureKMS struct {
+	server *httptest.Server
+	keys   keyring
+}
+
+// NewAzureKMS starts a fake Azure key vault, which must be closed.
+func NewAzureKMS() *AzureKMS {
+	f := &AzureKMS{}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// URI returns an azure-kms URI for the given version of the given key.
+func (f *AzureKMS) URI(keyName, keyVersion string) string {
+	q := make(url.Values)
+	q.Set(cloudimpl.AzureVaultNameParam, "fake-vault")
+	q.Set(cloudimpl.AzureTenantIDParam, "fake-tenant")
+	q.Set(cloudimpl.AzureClientIDParam, "fake-client")
+	q.Set(cloudimpl.AzureClientSecretParam, "fake-secret")
+	q.Set(cloudimpl.KMSEndpointParam, f.server.URL)
+	q.Set(cloudimpl.AzureADEndpointParam, f.server.URL+"/")
+	return fmt.Sprintf("azure-kms:///%s/%s?%s", keyName, keyVersion, q.Encode())
+}
+
+// Close shuts down the fake.
+func (f *AzureKMS) Close() {
+	f.server.Close()
+}
+
+func (f *AzureKMS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/oauth2/token") {
+		expiresOn := time.Now().Add(time.Hour).Unix()
+		writeJSON(w, map[string]string{
+			"access_token": fakeAccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   "3600",
+			"expires_on":   fmt.Sprint(expiresOn),
+			"not_before":   fmt.Sprint(expiresOn - 3600),
+			"resource":     r.FormValue("resource"),
+		})
+		return
+	}
+	if !authorized(r) {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	// The requests are POST /keys/<name>/<version>/{encrypt,decrypt}.
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "keys" {
+		http.NotFound(w, r)
+		return
+	}
+	keyID := parts[1] + "/" + parts[2]
+	var req struct {
+		Algorithm string `json:"alg"`
+		Value     string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	value, err := base64.RawURLEncoding.DecodeString(req.Value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var result []byte
+	switch parts[3] {
+	case "encrypt":
+		result, err = f.keys.encrypt(keyID, value)
+	case "decrypt":
+		result, err = f.keys.decrypt(keyID, value)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]string{
+		"kid":   fmt.Sprintf("%s/keys/%s", f.server.URL, keyID),
+		"value": base64.RawURLEncoding.EncodeToString(result),
+	})
+}
@@ -121,6 +121,16 @@ func (l *localFileStorage) ReadFile(ctx context.Context, basename string) (io.Re
 	return reader, nil
 }
 
+func (l *localFileStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
+	reader, err := l.ReadFile(ctx, basename)
+	if err != nil {
+		return nil, err
+	}
+	return skipToOffset(reader, offset)
+}
+
 func (l *localFileStorage) ListFiles(ctx context.Context, patternSuffix string) ([]string, error) {
 
 	pattern := l.base
@@ -12,6 +12,7 @@ package cloudimpl
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/url"
 	"path"
@@ -236,16 +237,26 @@ func (s *s3Storage) WriteFile(ctx context.Context, basename string, content io.R
 }
 
 func (s *s3Storage) ReadFile(ctx context.Context, basename string) (io.ReadCloser, error) {
+	return s.ReadFileAt(ctx, basename, 0)
+}
+
+func (s *s3Storage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
 	// https://github.com/cockroachdb/cockroach/issues/23859
 	client, err := s.newS3Client(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+	input := &s3.GetObjectInput{
 		Bucket: s.bucket,
 		Key:    aws.String(path.Join(s.prefix, basename)),
-	})
+	}
+	if offset != 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	out, err := client.GetObjectWithContext(ctx, input)
 	if err != nil {
 		if aerr := (awserr.Error)(nil); errors.As(err, &aerr) {
 			switch aerr.Code() {
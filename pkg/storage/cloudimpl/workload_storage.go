@@ -104,6 +104,15 @@ func (s *workloadStorage) ReadFile(_ context.Context, basename string) (io.ReadC
 	return ioutil.NopCloser(r), nil
 }
 
+func (s *workloadStorage) ReadFileAt(
+	ctx context.Context, basename string, offset int64,
+) (io.ReadCloser, error) {
+	if offset != 0 {
+		return nil, errors.Errorf(`workload storage does not support reading from an offset`)
+	}
+	return s.ReadFile(ctx, basename)
+}
+
 func (s *workloadStorage) WriteFile(_ context.Context, _ string, _ io.ReadSeeker) error {
 	return errors.Errorf(`workload storage does not support writes`)
 }
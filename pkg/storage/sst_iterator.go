@@ -44,6 +44,13 @@ func NewSSTIterator(path string) (SimpleMVCCIterator, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewSSTIteratorFromFile(file)
+}
+
+// NewSSTIteratorFromFile returns a `SimpleMVCCIterator` for the sstable in
+// file, which is read block by block as the iterator is positioned. It takes
+// ownership of file, which is closed when the iterator is.
+func NewSSTIteratorFromFile(file vfs.File) (SimpleMVCCIterator, error) {
 	sst, err := sstable.NewReader(file, sstable.ReaderOptions{
 		Comparer: EngineComparer,
 	})
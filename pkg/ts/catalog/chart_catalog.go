@@ -1029,6 +1029,19 @@ var charts = []sectionDescription{
 				Metrics: []string{
 					"changefeed.error_retries",
 					"changefeed.failures",
+					"changefeed.sink_errors",
+				},
+			},
+			{
+				Title: "Backfill Pending Ranges",
+				Metrics: []string{
+					"changefeed.backfill_pending_ranges",
+				},
+			},
+			{
+				Title: "Commit Latency",
+				Metrics: []string{
+					"changefeed.commit_latency",
 				},
 			},
 			{
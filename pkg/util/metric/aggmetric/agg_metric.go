@@ -15,6 +15,7 @@ package aggmetric
 
 import (
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
@@ -43,6 +44,13 @@ func (b Builder) Counter(metadata metric.Metadata) *AggCounter {
 	return NewCounter(metadata, b.labels...)
 }
 
+// Histogram constructs a new AggHistogram with the Builder's labels.
+func (b Builder) Histogram(
+	metadata metric.Metadata, duration time.Duration, maxVal int64, sigFigs int,
+) *AggHistogram {
+	return NewHistogram(metadata, duration, maxVal, sigFigs, b.labels...)
+}
+
 type childSet struct {
 	labels []string
 	mu     struct {
@@ -16,6 +16,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
@@ -113,3 +114,56 @@ foo_counter{tenant_id="3"} 4`,
 		require.Panics(t, func() { g.AddChild("", "") })
 	})
 }
+
+func TestAggHistogram(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	r := metric.NewRegistry()
+	h := aggmetric.NewHistogram(metric.Metadata{
+		Name: "baz_histogram",
+	}, time.Minute, 100, 1, "tenant_id")
+	r.AddMetric(h)
+
+	// writePrometheusCounts returns the sample counts of the histogram and its
+	// children as exported to prometheus.
+	writePrometheusCounts := func(t *testing.T) string {
+		var in bytes.Buffer
+		ex := metric.MakePrometheusExporter()
+		ex.ScrapeRegistry(r, true /* includeChildMetrics */)
+		require.NoError(t, ex.PrintAsText(&in))
+		var lines []string
+		for sc := bufio.NewScanner(&in); sc.Scan(); {
+			if strings.HasPrefix(sc.Text(), "baz_histogram_count") {
+				lines = append(lines, sc.Text())
+			}
+		}
+		sort.Strings(lines)
+		return strings.Join(lines, "\n")
+	}
+
+	h2 := h.AddChild(roachpb.MakeTenantID(2).String())
+	h3 := h.AddChild(roachpb.MakeTenantID(3).String())
+	h2.RecordValue(10)
+	h3.RecordValue(20)
+	h3.RecordValue(30)
+	require.Equal(t, int64(1), h2.TotalCount())
+	require.Equal(t, int64(2), h3.TotalCount())
+	require.Equal(t, int64(3), h.TotalCount())
+	require.Equal(t,
+		`baz_histogram_count 3
+baz_histogram_count{tenant_id="2"} 1
+baz_histogram_count{tenant_id="3"} 2`,
+		writePrometheusCounts(t))
+
+	// The windowed histogram of the parent includes the values recorded by all
+	// of its children.
+	windowed, _ := h.Windowed()
+	require.Equal(t, int64(3), windowed.TotalCount())
+
+	h2.Destroy()
+	require.Equal(t,
+		`baz_histogram_count 3
+baz_histogram_count{tenant_id="3"} 2`,
+		writePrometheusCounts(t))
+	require.Panics(t, func() { h.AddChild(roachpb.MakeTenantID(3).String()) })
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package aggmetric
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/codahale/hdrhistogram"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+)
+
+// AggHistogram maintains a histogram of the values recorded by all of its
+// children. The histogram will report to crdb-internal time series only the
+// aggregate of all of its children, while its children are additionally
+// exported to prometheus via the PrometheusIterable interface.
+type AggHistogram struct {
+	h      *metric.Histogram
+	create func() *metric.Histogram
+	childSet
+}
+
+var _ metric.Iterable = (*AggHistogram)(nil)
+var _ metric.PrometheusIterable = (*AggHistogram)(nil)
+var _ metric.PrometheusExportable = (*AggHistogram)(nil)
+var _ metric.WindowedHistogram = (*AggHistogram)(nil)
+
+// NewHistogram constructs a new AggHistogram. The arguments are those of
+// metric.NewHistogram and are used for the parent and each of its children.
+func NewHistogram(
+	metadata metric.Metadata,
+	duration time.Duration,
+	maxVal int64,
+	sigFigs int,
+	childLabels ...string,
+) *AggHistogram {
+	create := func() *metric.Histogram {
+		return metric.NewHistogram(metadata, duration, maxVal, sigFigs)
+	}
+	h := &AggHistogram{h: create(), create: create}
+	h.init(childLabels)
+	return h
+}
+
+// GetName is part of the metric.Iterable interface.
+func (h *AggHistogram) GetName() string { return h.h.GetName() }
+
+// GetHelp is part of the metric.Iterable interface.
+func (h *AggHistogram) GetHelp() string { return h.h.GetHelp() }
+
+// GetMeasurement is part of the metric.Iterable interface.
+func (h *AggHistogram) GetMeasurement() string { return h.h.GetMeasurement() }
+
+// GetUnit is part of the metric.Iterable interface.
+func (h *AggHistogram) GetUnit() metric.Unit { return h.h.GetUnit() }
+
+// GetMetadata is part of the metric.Iterable interface.
+func (h *AggHistogram) GetMetadata() metric.Metadata { return h.h.GetMetadata() }
+
+// Inspect is part of the metric.Iterable interface.
+func (h *AggHistogram) Inspect(f func(interface{})) {
+	// Inspecting the parent rotates its window if necessary.
+	h.h.Inspect(func(interface{}) {})
+	f(h)
+}
+
+// GetType is part of the metric.PrometheusExportable interface.
+func (h *AggHistogram) GetType() *io_prometheus_client.MetricType {
+	return h.h.GetType()
+}
+
+// GetLabels is part of the metric.PrometheusExportable interface.
+func (h *AggHistogram) GetLabels() []*io_prometheus_client.LabelPair {
+	return h.h.GetLabels()
+}
+
+// ToPrometheusMetric is part of the metric.PrometheusExportable interface.
+func (h *AggHistogram) ToPrometheusMetric() *io_prometheus_client.Metric {
+	return h.h.ToPrometheusMetric()
+}
+
+// Windowed is part of the metric.WindowedHistogram interface.
+func (h *AggHistogram) Windowed() (*hdrhistogram.Histogram, time.Duration) {
+	return h.h.Windowed()
+}
+
+// TotalCount returns the number of values recorded by all of its current and
+// past children.
+func (h *AggHistogram) TotalCount() int64 {
+	return h.h.TotalCount()
+}
+
+// AddChild adds a Histogram to this AggHistogram. This method panics if a
+// Histogram already exists for this set of labelVals.
+func (h *AggHistogram) AddChild(labelVals ...string) *Histogram {
+	child := &Histogram{
+		parent:           h,
+		labelValuesSlice: labelValuesSlice(labelVals),
+		h:                h.create(),
+	}
+	h.add(child)
+	return child
+}
+
+// Histogram is a child of a AggHistogram. When a value is recorded, so too is
+// it recorded by the parent. When metrics are collected by prometheus, each of
+// the children will appear with a distinct label, however, when cockroach
+// internally collects metrics, only the parent is collected.
+type Histogram struct {
+	parent *AggHistogram
+	labelValuesSlice
+	h *metric.Histogram
+}
+
+// ToPrometheusMetric constructs a prometheus metric for this Histogram.
+func (h *Histogram) ToPrometheusMetric() *io_prometheus_client.Metric {
+	return h.h.ToPrometheusMetric()
+}
+
+// Destroy disconnects this Histogram from its parent. The values it recorded
+// remain recorded by the parent.
+func (h *Histogram) Destroy() {
+	h.parent.remove(h)
+}
+
+// RecordValue adds the given value to the histogram and its parent.
+func (h *Histogram) RecordValue(v int64) {
+	h.h.RecordValue(v)
+	h.parent.h.RecordValue(v)
+}
+
+// TotalCount returns the number of values recorded by this Histogram.
+func (h *Histogram) TotalCount() int64 {
+	return h.h.TotalCount()
+}
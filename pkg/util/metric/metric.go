@@ -90,6 +90,15 @@ type PrometheusIterable interface {
 	Each([]*prometheusgo.LabelPair, func(metric *prometheusgo.Metric))
 }
 
+// WindowedHistogram is implemented by histograms which retain a window of
+// recent samples. The time series recorder records fixed quantiles of the
+// window of any metric implementing it.
+type WindowedHistogram interface {
+	// Windowed returns a copy of the current windowed histogram data and its
+	// rotation interval.
+	Windowed() (*hdrhistogram.Histogram, time.Duration)
+}
+
 // GetName returns the metric's name.
 func (m *Metadata) GetName() string {
 	return m.Name
@@ -224,6 +233,8 @@ func NewLatency(metadata Metadata, histogramWindow time.Duration) *Histogram {
 	)
 }
 
+var _ WindowedHistogram = (*Histogram)(nil)
+
 // Windowed returns a copy of the current windowed histogram data and its
 // rotation interval.
 func (h *Histogram) Windowed() (*hdrhistogram.Histogram, time.Duration) {